@@ -4295,7 +4295,18 @@ var doc = `{
             }
         },
         "types.SchedulerClusterConfig": {
-            "type": "object"
+            "type": "object",
+            "properties": {
+                "candidate_parent_limit": {
+                    "type": "integer"
+                },
+                "retry_back_source_limit": {
+                    "type": "integer"
+                },
+                "retry_limit": {
+                    "type": "integer"
+                }
+            }
         },
         "types.SchedulerClusterScopes": {
             "type": "object",
@@ -19,7 +19,8 @@ package rpcserver
 import (
 	"context"
 	"encoding/json"
-	"fmt"
+	"net"
+	"strconv"
 	"time"
 
 	"go.opentelemetry.io/otel"
@@ -230,7 +231,7 @@ func (css *Server) GetPieceTasks(ctx context.Context, req *base.PieceTaskRequest
 	pp := &base.PiecePacket{
 		TaskId:        req.TaskId,
 		DstPid:        req.DstPid,
-		DstAddr:       fmt.Sprintf("%s:%d", css.config.AdvertiseIP, css.config.DownloadPort),
+		DstAddr:       net.JoinHostPort(css.config.AdvertiseIP, strconv.Itoa(css.config.DownloadPort)),
 		PieceInfos:    pieceInfos,
 		TotalPiece:    seedTask.TotalPieceCount,
 		ContentLength: seedTask.SourceFileLength,
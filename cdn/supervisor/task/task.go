@@ -17,8 +17,6 @@
 package task
 
 import (
-	"strings"
-
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 
@@ -108,7 +106,7 @@ func NewSeedTask(taskID string, rawURL string, urlMeta *base.UrlMeta) *SeedTask
 	return &SeedTask{
 		ID:               taskID,
 		RawURL:           rawURL,
-		TaskURL:          urlutils.FilterURLParam(rawURL, strings.Split(urlMeta.Filter, "&")),
+		TaskURL:          urlutils.FilterURLParam(rawURL, urlutils.SplitFilter(urlMeta.Filter)),
 		SourceFileLength: source.UnknownSourceFileLen,
 		CdnFileLength:    0,
 		PieceSize:        0,
@@ -76,4 +76,6 @@ const (
 const (
 	SimpleLocalTaskStoreStrategy  = StoreStrategy("io.d7y.storage.v2.simple")
 	AdvanceLocalTaskStoreStrategy = StoreStrategy("io.d7y.storage.v2.advance")
+	// ObjectStorageTaskStoreStrategy stores task data in object storage, only metadata is kept in local disk
+	ObjectStorageTaskStoreStrategy = StoreStrategy("io.d7y.storage.v2.object")
 )
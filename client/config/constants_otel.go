@@ -44,6 +44,8 @@ const (
 	AttributeGetPieceCount     = attribute.Key("d7y.peer.piece.count")
 	AttributeGetPieceRetry     = attribute.Key("d7y.peer.piece.retry")
 	AttributeWritePieceSuccess = attribute.Key("d7y.peer.piece.write.success")
+	AttributeFilter            = attribute.Key("d7y.peer.task.filter")
+	AttributeTag               = attribute.Key("d7y.peer.task.tag")
 
 	SpanFileTask          = "file-task"
 	SpanStreamTask        = "stream-task"
@@ -61,4 +63,5 @@ const (
 	SpanWriteBackPiece    = "write-back-piece"
 	SpanWaitPieceLimit    = "wait-limit"
 	SpanPeerGC            = "peer-gc"
+	SpanTransportDownload = "transport.download"
 )
@@ -64,6 +64,8 @@ type DaemonOption struct {
 	Upload       UploadOption    `mapstructure:"upload" yaml:"upload"`
 	Storage      StorageOption   `mapstructure:"storage" yaml:"storage"`
 	ConfigServer string          `mapstructure:"configServer" yaml:"configServer"`
+	// TrafficMark marks the sockets of piece downloading and uploading for QoS classification
+	TrafficMark dfnet.TrafficMark `mapstructure:"trafficMark" yaml:"trafficMark"`
 }
 
 func NewDaemonConfig() *DaemonOption {
@@ -126,6 +128,30 @@ func (p *DaemonOption) Validate() error {
 		}
 	}
 
+	if p.Storage.DiskHighWatermarkPercent > 0 {
+		if p.Storage.DiskHighWatermarkPercent > 100 {
+			return errors.New("storage diskHighWatermarkPercent must be less than or equal to 100")
+		}
+
+		if p.Storage.DiskLowWatermarkPercent <= 0 || p.Storage.DiskLowWatermarkPercent > p.Storage.DiskHighWatermarkPercent {
+			return errors.New("storage diskLowWatermarkPercent must be greater than 0 and less than or equal to diskHighWatermarkPercent")
+		}
+	}
+
+	if p.Storage.StoreStrategy == ObjectStorageTaskStoreStrategy {
+		if p.Storage.ObjectStorage.Endpoint == "" || p.Storage.ObjectStorage.Bucket == "" {
+			return errors.New("storage objectStorage endpoint and bucket are required for object storage strategy")
+		}
+	}
+
+	if p.TrafficMark.DSCP < 0 || p.TrafficMark.DSCP > 63 {
+		return errors.New("trafficMark dscp must be between 0 and 63")
+	}
+
+	if p.TrafficMark.Mark < 0 {
+		return errors.New("trafficMark mark must be greater than or equal to 0")
+	}
+
 	return nil
 }
 
@@ -138,6 +164,15 @@ type SchedulerOption struct {
 	ScheduleTimeout clientutil.Duration `mapstructure:"scheduleTimeout" yaml:"scheduleTimeout"`
 	// DisableAutoBackSource indicates not back source normally, only scheduler says back source
 	DisableAutoBackSource bool `mapstructure:"disableAutoBackSource" yaml:"disableAutoBackSource"`
+	// PieceResultBatch coalesces the successful piece results reported to scheduler
+	PieceResultBatch PieceResultBatchOption `mapstructure:"pieceResultBatch" yaml:"pieceResultBatch"`
+}
+
+type PieceResultBatchOption struct {
+	// Count is the max count of the buffered piece results, 0 means no limit
+	Count int `mapstructure:"count" yaml:"count"`
+	// Interval is the max duration the piece results are buffered, 0 means no limit
+	Interval clientutil.Duration `mapstructure:"interval" yaml:"interval"`
 }
 
 type ManagerOption struct {
@@ -167,15 +202,18 @@ type HostOption struct {
 }
 
 type DownloadOption struct {
-	TotalRateLimit       clientutil.RateLimit `mapstructure:"totalRateLimit" yaml:"totalRateLimit"`
-	PerPeerRateLimit     clientutil.RateLimit `mapstructure:"perPeerRateLimit" yaml:"perPeerRateLimit"`
-	PieceDownloadTimeout time.Duration        `mapstructure:"pieceDownloadTimeout" yaml:"pieceDownloadTimeout"`
-	DownloadGRPC         ListenOption         `mapstructure:"downloadGRPC" yaml:"downloadGRPC"`
-	PeerGRPC             ListenOption         `mapstructure:"peerGRPC" yaml:"peerGRPC"`
-	CalculateDigest      bool                 `mapstructure:"calculateDigest" yaml:"calculateDigest"`
-	TransportOption      *TransportOption     `mapstructure:"transportOption" yaml:"transportOption"`
-	GetPiecesMaxRetry    int                  `mapstructure:"getPiecesMaxRetry" yaml:"getPiecesMaxRetry"`
-	Prefetch             bool                 `mapstructure:"prefetch" yaml:"prefetch"`
+	TotalRateLimit        clientutil.RateLimit `mapstructure:"totalRateLimit" yaml:"totalRateLimit"`
+	PerPeerRateLimit      clientutil.RateLimit `mapstructure:"perPeerRateLimit" yaml:"perPeerRateLimit"`
+	PieceDownloadTimeout  time.Duration        `mapstructure:"pieceDownloadTimeout" yaml:"pieceDownloadTimeout"`
+	DownloadGRPC          ListenOption         `mapstructure:"downloadGRPC" yaml:"downloadGRPC"`
+	PeerGRPC              ListenOption         `mapstructure:"peerGRPC" yaml:"peerGRPC"`
+	CalculateDigest       bool                 `mapstructure:"calculateDigest" yaml:"calculateDigest"`
+	TransportOption       *TransportOption     `mapstructure:"transportOption" yaml:"transportOption"`
+	GetPiecesMaxRetry     int                  `mapstructure:"getPiecesMaxRetry" yaml:"getPiecesMaxRetry"`
+	Prefetch              bool                 `mapstructure:"prefetch" yaml:"prefetch"`
+	BackSourceConcurrency int                  `mapstructure:"backSourceConcurrency" yaml:"backSourceConcurrency"`
+	PieceNotFoundMaxRetry int                  `mapstructure:"pieceNotFoundMaxRetry" yaml:"pieceNotFoundMaxRetry"`
+	BackSourceLimit       int                  `mapstructure:"backSourceLimit" yaml:"backSourceLimit"`
 }
 
 type TransportOption struct {
@@ -190,15 +228,17 @@ type TransportOption struct {
 
 type ProxyOption struct {
 	// WARNING: when add more option, please update ProxyOption.unmarshal function
-	ListenOption    `mapstructure:",squash" yaml:",inline"`
-	BasicAuth       *BasicAuth      `mapstructure:"basicAuth" yaml:"basicAuth"`
-	DefaultFilter   string          `mapstructure:"defaultFilter" yaml:"defaultFilter"`
-	MaxConcurrency  int64           `mapstructure:"maxConcurrency" yaml:"maxConcurrency"`
-	RegistryMirror  *RegistryMirror `mapstructure:"registryMirror" yaml:"registryMirror"`
-	WhiteList       []*WhiteList    `mapstructure:"whiteList" yaml:"whiteList"`
-	Proxies         []*Proxy        `mapstructure:"proxies" yaml:"proxies"`
-	HijackHTTPS     *HijackConfig   `mapstructure:"hijackHTTPS" yaml:"hijackHTTPS"`
-	DumpHTTPContent bool            `mapstructure:"dumpHTTPContent" yaml:"dumpHTTPContent"`
+	ListenOption      `mapstructure:",squash" yaml:",inline"`
+	BasicAuth         *BasicAuth      `mapstructure:"basicAuth" yaml:"basicAuth"`
+	DefaultFilter     string          `mapstructure:"defaultFilter" yaml:"defaultFilter"`
+	MaxConcurrency    int64           `mapstructure:"maxConcurrency" yaml:"maxConcurrency"`
+	RegistryMirror    *RegistryMirror `mapstructure:"registryMirror" yaml:"registryMirror"`
+	WhiteList         []*WhiteList    `mapstructure:"whiteList" yaml:"whiteList"`
+	Proxies           []*Proxy        `mapstructure:"proxies" yaml:"proxies"`
+	HijackHTTPS       *HijackConfig   `mapstructure:"hijackHTTPS" yaml:"hijackHTTPS"`
+	DumpHTTPContent   bool            `mapstructure:"dumpHTTPContent" yaml:"dumpHTTPContent"`
+	DumpHTTPBodyLimit int             `mapstructure:"dumpHTTPBodyLimit" yaml:"dumpHTTPBodyLimit"`
+	DumpRedactHeaders []string        `mapstructure:"dumpRedactHeaders" yaml:"dumpRedactHeaders"`
 }
 
 func (p *ProxyOption) UnmarshalJSON(b []byte) error {
@@ -275,15 +315,17 @@ func (p *ProxyOption) UnmarshalYAML(node *yaml.Node) error {
 
 func (p *ProxyOption) unmarshal(unmarshal func(in []byte, out interface{}) (err error), b []byte) error {
 	pt := struct {
-		ListenOption    `mapstructure:",squash" yaml:",inline"`
-		BasicAuth       *BasicAuth      `mapstructure:"basicAuth" yaml:"basicAuth"`
-		DefaultFilter   string          `mapstructure:"defaultFilter" yaml:"defaultFilter"`
-		MaxConcurrency  int64           `mapstructure:"maxConcurrency" yaml:"maxConcurrency"`
-		RegistryMirror  *RegistryMirror `mapstructure:"registryMirror" yaml:"registryMirror"`
-		WhiteList       []*WhiteList    `mapstructure:"whiteList" yaml:"whiteList"`
-		Proxies         []*Proxy        `mapstructure:"proxies" yaml:"proxies"`
-		HijackHTTPS     *HijackConfig   `mapstructure:"hijackHTTPS" yaml:"hijackHTTPS"`
-		DumpHTTPContent bool            `mapstructure:"dumpHTTPContent" yaml:"dumpHTTPContent"`
+		ListenOption      `mapstructure:",squash" yaml:",inline"`
+		BasicAuth         *BasicAuth      `mapstructure:"basicAuth" yaml:"basicAuth"`
+		DefaultFilter     string          `mapstructure:"defaultFilter" yaml:"defaultFilter"`
+		MaxConcurrency    int64           `mapstructure:"maxConcurrency" yaml:"maxConcurrency"`
+		RegistryMirror    *RegistryMirror `mapstructure:"registryMirror" yaml:"registryMirror"`
+		WhiteList         []*WhiteList    `mapstructure:"whiteList" yaml:"whiteList"`
+		Proxies           []*Proxy        `mapstructure:"proxies" yaml:"proxies"`
+		HijackHTTPS       *HijackConfig   `mapstructure:"hijackHTTPS" yaml:"hijackHTTPS"`
+		DumpHTTPContent   bool            `mapstructure:"dumpHTTPContent" yaml:"dumpHTTPContent"`
+		DumpHTTPBodyLimit int             `mapstructure:"dumpHTTPBodyLimit" yaml:"dumpHTTPBodyLimit"`
+		DumpRedactHeaders []string        `mapstructure:"dumpRedactHeaders" yaml:"dumpRedactHeaders"`
 	}{}
 
 	if err := unmarshal(b, &pt); err != nil {
@@ -299,6 +341,8 @@ func (p *ProxyOption) unmarshal(unmarshal func(in []byte, out interface{}) (err
 	p.DefaultFilter = pt.DefaultFilter
 	p.BasicAuth = pt.BasicAuth
 	p.DumpHTTPContent = pt.DumpHTTPContent
+	p.DumpHTTPBodyLimit = pt.DumpHTTPBodyLimit
+	p.DumpRedactHeaders = pt.DumpRedactHeaders
 
 	return nil
 }
@@ -435,9 +479,34 @@ type StorageOption struct {
 	// DiskGCThresholdPercent indicates the threshold to gc the oldest tasks according the disk usage
 	// Eg, DiskGCThresholdPercent=80, when the disk usage is above 80%, start to gc the oldest tasks
 	DiskGCThresholdPercent float64 `mapstructure:"diskGCThresholdPercent" yaml:"diskGCThresholdPercent"`
+	// DiskHighWatermarkPercent indicates the disk usage to reclaim the least recently used completed tasks in background
+	// Eg, DiskHighWatermarkPercent=90, when the disk usage is above 90%, reclaim tasks until below DiskLowWatermarkPercent
+	DiskHighWatermarkPercent float64 `mapstructure:"diskHighWatermarkPercent" yaml:"diskHighWatermarkPercent"`
+	// DiskLowWatermarkPercent indicates the disk usage to stop reclaiming tasks in background
+	DiskLowWatermarkPercent float64 `mapstructure:"diskLowWatermarkPercent" yaml:"diskLowWatermarkPercent"`
+	// DiskWatermarkInterval indicates the interval to check the disk usage against the watermarks
+	DiskWatermarkInterval clientutil.Duration `mapstructure:"diskWatermarkInterval" yaml:"diskWatermarkInterval"`
+	// ValidateDigestConcurrency indicates the count of pieces hashed concurrently when validating the data
+	// of reloaded tasks, default is the count of cpus
+	ValidateDigestConcurrency int `mapstructure:"validateDigestConcurrency" yaml:"validateDigestConcurrency"`
 	// Multiplex indicates reusing underlying storage for same task id
 	Multiplex     bool          `mapstructure:"multiplex" yaml:"multiplex"`
 	StoreStrategy StoreStrategy `mapstructure:"strategy" yaml:"strategy"`
+	// ObjectStorage is the object storage to store task data when StoreStrategy is ObjectStorageTaskStoreStrategy
+	ObjectStorage ObjectStorageOption `mapstructure:"objectStorage" yaml:"objectStorage"`
+}
+
+type ObjectStorageOption struct {
+	// Endpoint is the endpoint of object storage
+	Endpoint string `mapstructure:"endpoint" yaml:"endpoint"`
+	// AccessKeyID is the access key id of object storage
+	AccessKeyID string `mapstructure:"accessKeyID" yaml:"accessKeyID"`
+	// AccessKeySecret is the access key secret of object storage
+	AccessKeySecret string `mapstructure:"accessKeySecret" yaml:"accessKeySecret"`
+	// Bucket is the bucket to store task data
+	Bucket string `mapstructure:"bucket" yaml:"bucket"`
+	// Prefix is the prefix of object keys in the bucket
+	Prefix string `mapstructure:"prefix" yaml:"prefix"`
 }
 
 type StoreStrategy string
@@ -58,9 +58,10 @@ var peerHostConfig = DaemonOption{
 		NetTopology:    "",
 	},
 	Download: DownloadOption{
-		CalculateDigest:      true,
-		PieceDownloadTimeout: 30 * time.Second,
-		GetPiecesMaxRetry:    100,
+		CalculateDigest:       true,
+		PieceDownloadTimeout:  30 * time.Second,
+		GetPiecesMaxRetry:     100,
+		PieceNotFoundMaxRetry: 3,
 		TotalRateLimit: clientutil.RateLimit{
 			Limit: rate.Limit(DefaultTotalDownloadLimit),
 		},
@@ -249,6 +249,12 @@ func TestPeerHostOption_Load(t *testing.T) {
 			ScheduleTimeout: clientutil.Duration{
 				Duration: 0,
 			},
+			PieceResultBatch: PieceResultBatchOption{
+				Count: 16,
+				Interval: clientutil.Duration{
+					Duration: 100 * time.Millisecond,
+				},
+			},
 		},
 		Host: HostOption{
 			Hostname:       "d7y.io",
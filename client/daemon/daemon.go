@@ -95,6 +95,15 @@ func New(opt *config.DaemonOption, d dfpath.Dfpath) (Daemon, error) {
 	// update plugin directory
 	source.UpdatePluginDir(d.PluginDir())
 
+	// load source plugins at start to fail fast with broken plugins
+	schemes, err := source.LoadAllPlugins(d.PluginDir())
+	if err != nil {
+		return nil, err
+	}
+	if len(schemes) > 0 {
+		logger.Infof("loaded source plugins: %v", schemes)
+	}
+
 	host := &scheduler.PeerHost{
 		Uuid:           idgen.UUIDString(),
 		Ip:             opt.Host.AdvertiseIP,
@@ -164,12 +173,16 @@ func New(opt *config.DaemonOption, d dfpath.Dfpath) (Daemon, error) {
 		opt.Download.PieceDownloadTimeout,
 		peer.WithLimiter(rate.NewLimiter(opt.Download.TotalRateLimit.Limit, int(opt.Download.TotalRateLimit.Limit))),
 		peer.WithCalculateDigest(opt.Download.CalculateDigest), peer.WithTransportOption(opt.Download.TransportOption),
+		peer.WithBackSourceConcurrency(opt.Download.BackSourceConcurrency),
+		peer.WithBackSourceLimit(opt.Download.BackSourceLimit),
+		peer.WithPieceDownloaderOptions(peer.WithTrafficMark(opt.TrafficMark)),
 	)
 	if err != nil {
 		return nil, err
 	}
 	peerTaskManager, err := peer.NewPeerTaskManager(host, pieceManager, storageManager, sched, opt.Scheduler,
-		opt.Download.PerPeerRateLimit.Limit, opt.Storage.Multiplex, opt.Download.Prefetch, opt.Download.CalculateDigest, opt.Download.GetPiecesMaxRetry)
+		opt.Download.PerPeerRateLimit.Limit, opt.Storage.Multiplex, opt.Download.Prefetch, opt.Download.CalculateDigest, opt.Download.GetPiecesMaxRetry,
+		opt.Download.PieceNotFoundMaxRetry)
 	if err != nil {
 		return nil, err
 	}
@@ -262,7 +275,8 @@ func loadGPRCTLSCredentials(opt config.SecurityOption) (credentials.TransportCre
 	return credentials.NewTLS(opt.TLSConfig), nil
 }
 
-func (*clientDaemon) prepareTCPListener(opt config.ListenOption, withTLS bool) (net.Listener, int, error) {
+// prepareTCPListener listens with opt, the accepted connections are marked with trafficMark before tls handshake
+func (*clientDaemon) prepareTCPListener(opt config.ListenOption, withTLS bool, trafficMark dfnet.TrafficMark) (net.Listener, int, error) {
 	if len(opt.TCPListen.Namespace) > 0 {
 		runtime.LockOSThread()
 		defer runtime.UnlockOSThread()
@@ -290,6 +304,7 @@ func (*clientDaemon) prepareTCPListener(opt config.ListenOption, withTLS bool) (
 	if err != nil {
 		return nil, -1, err
 	}
+	ln = trafficMark.Listener(ln)
 	// when use grpc, tls config is in server option
 	if !withTLS || opt.Security.Insecure {
 		return ln, port, err
@@ -345,7 +360,7 @@ func (cd *clientDaemon) Serve() error {
 	if cd.Option.Download.PeerGRPC.TCPListen == nil {
 		return errors.New("peer grpc tcp listen option is empty")
 	}
-	peerListener, peerPort, err := cd.prepareTCPListener(cd.Option.Download.PeerGRPC, false)
+	peerListener, peerPort, err := cd.prepareTCPListener(cd.Option.Download.PeerGRPC, false, dfnet.TrafficMark{})
 	if err != nil {
 		logger.Errorf("failed to listen for peer grpc service: %v", err)
 		return err
@@ -356,7 +371,7 @@ func (cd *clientDaemon) Serve() error {
 	if cd.Option.Upload.TCPListen == nil {
 		return errors.New("upload tcp listen option is empty")
 	}
-	uploadListener, uploadPort, err := cd.prepareTCPListener(cd.Option.Upload.ListenOption, true)
+	uploadListener, uploadPort, err := cd.prepareTCPListener(cd.Option.Upload.ListenOption, true, cd.Option.TrafficMark)
 	if err != nil {
 		logger.Errorf("failed to listen for upload service: %v", err)
 		return err
@@ -391,7 +406,7 @@ func (cd *clientDaemon) Serve() error {
 		if cd.Option.Proxy.TCPListen == nil {
 			return errors.New("proxy tcp listen option is empty")
 		}
-		proxyListener, proxyPort, err := cd.prepareTCPListener(cd.Option.Proxy.ListenOption, true)
+		proxyListener, proxyPort, err := cd.prepareTCPListener(cd.Option.Proxy.ListenOption, true, dfnet.TrafficMark{})
 		if err != nil {
 			logger.Errorf("failed to listen for proxy service: %v", err)
 			return err
@@ -414,7 +429,7 @@ func (cd *clientDaemon) Serve() error {
 
 				listener, port, err := cd.prepareTCPListener(config.ListenOption{
 					TCPListen: opt,
-				}, false)
+				}, false, dfnet.TrafficMark{})
 				if err != nil {
 					logger.Errorf("failed to listen for proxy sni service: %v", err)
 					return err
@@ -110,6 +110,27 @@ var (
 		Name:      "peer_task_cache_hit_total",
 		Help:      "Counter of the total cache hit peer tasks.",
 	})
+
+	BackSourceRunningCount = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: constants.MetricsNamespace,
+		Subsystem: constants.DfdaemonMetricsName,
+		Name:      "back_source_running",
+		Help:      "Current running count of back source downloads.",
+	})
+
+	BackSourceWaitingCount = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: constants.MetricsNamespace,
+		Subsystem: constants.DfdaemonMetricsName,
+		Name:      "back_source_waiting",
+		Help:      "Current count of back source downloads waiting for a slot.",
+	})
+
+	StorageReclaimedBytesCount = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: constants.MetricsNamespace,
+		Subsystem: constants.DfdaemonMetricsName,
+		Name:      "storage_reclaimed_bytes_total",
+		Help:      "Counter of the total bytes reclaimed by disk watermarks.",
+	})
 )
 
 func New(addr string) *http.Server {
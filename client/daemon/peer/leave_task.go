@@ -0,0 +1,44 @@
+/*
+ *     Copyright 2022 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package peer
+
+import (
+	"context"
+
+	"d7y.io/dragonfly/v2/client/daemon/storage"
+	logger "d7y.io/dragonfly/v2/internal/dflog"
+	"d7y.io/dragonfly/v2/pkg/rpc/scheduler"
+	schedulerclient "d7y.io/dragonfly/v2/pkg/rpc/scheduler/client"
+)
+
+// LeaveAndCleanup leaves the task of target in scheduler, then reclaims the local storage of the task.
+// The storage is reclaimed even when leaving fails, and the error of leaving is returned after it.
+// The failure of reclaiming is logged without failing the leave, the storage is reclaimed by gc later.
+func LeaveAndCleanup(ctx context.Context, sched schedulerclient.SchedulerClient, target *scheduler.PeerTarget, sm storage.Manager) error {
+	leaveErr := sched.LeaveTask(ctx, target)
+	if leaveErr != nil {
+		logger.WithTaskAndPeerID(target.TaskId, target.PeerId).Warnf("leave task error: %s", leaveErr)
+	}
+
+	if err := sm.UnregisterTask(ctx, storage.CommonTaskRequest{
+		PeerID: target.PeerId,
+		TaskID: target.TaskId,
+	}); err != nil {
+		logger.WithTaskAndPeerID(target.TaskId, target.PeerId).Warnf("clean up storage after leaving task error: %s", err)
+	}
+	return leaveErr
+}
@@ -0,0 +1,94 @@
+/*
+ *     Copyright 2022 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package peer
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	testifyassert "github.com/stretchr/testify/assert"
+
+	"d7y.io/dragonfly/v2/client/daemon/storage"
+	mock_scheduler "d7y.io/dragonfly/v2/client/daemon/test/mock/scheduler"
+	mock_storage "d7y.io/dragonfly/v2/client/daemon/test/mock/storage"
+	"d7y.io/dragonfly/v2/pkg/rpc/scheduler"
+)
+
+func TestLeaveAndCleanup(t *testing.T) {
+	var (
+		target   = &scheduler.PeerTarget{TaskId: "task", PeerId: "peer"}
+		unregReq = storage.CommonTaskRequest{TaskID: "task", PeerID: "peer"}
+	)
+	tests := []struct {
+		name   string
+		mock   func(sched *mock_scheduler.MockSchedulerClientMockRecorder, sm *mock_storage.MockManagerMockRecorder)
+		expect func(t *testing.T, err error)
+	}{
+		{
+			name: "leave and clean up",
+			mock: func(sched *mock_scheduler.MockSchedulerClientMockRecorder, sm *mock_storage.MockManagerMockRecorder) {
+				gomock.InOrder(
+					sched.LeaveTask(gomock.Any(), target).Return(nil),
+					sm.UnregisterTask(gomock.Any(), unregReq).Return(nil),
+				)
+			},
+			expect: func(t *testing.T, err error) {
+				assert := testifyassert.New(t)
+				assert.Nil(err)
+			},
+		},
+		{
+			name: "clean up failure does not fail the leave",
+			mock: func(sched *mock_scheduler.MockSchedulerClientMockRecorder, sm *mock_storage.MockManagerMockRecorder) {
+				gomock.InOrder(
+					sched.LeaveTask(gomock.Any(), target).Return(nil),
+					sm.UnregisterTask(gomock.Any(), unregReq).Return(storage.ErrTaskNotFound),
+				)
+			},
+			expect: func(t *testing.T, err error) {
+				assert := testifyassert.New(t)
+				assert.Nil(err)
+			},
+		},
+		{
+			name: "storage is cleaned up when leave failed",
+			mock: func(sched *mock_scheduler.MockSchedulerClientMockRecorder, sm *mock_storage.MockManagerMockRecorder) {
+				gomock.InOrder(
+					sched.LeaveTask(gomock.Any(), target).Return(errors.New("scheduler is gone")),
+					sm.UnregisterTask(gomock.Any(), unregReq).Return(nil),
+				)
+			},
+			expect: func(t *testing.T, err error) {
+				assert := testifyassert.New(t)
+				assert.EqualError(err, "scheduler is gone")
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			sched := mock_scheduler.NewMockSchedulerClient(ctrl)
+			sm := mock_storage.NewMockManager(ctrl)
+			tc.mock(sched.EXPECT(), sm.EXPECT())
+			tc.expect(t, LeaveAndCleanup(context.Background(), sched, target, sm))
+		})
+	}
+}
@@ -30,6 +30,7 @@ import (
 	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/atomic"
 	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
 
 	"d7y.io/dragonfly/v2/client/clientutil"
 	"d7y.io/dragonfly/v2/client/config"
@@ -39,6 +40,7 @@ import (
 	logger "d7y.io/dragonfly/v2/internal/dflog"
 	"d7y.io/dragonfly/v2/pkg/idgen"
 	"d7y.io/dragonfly/v2/pkg/rpc/base"
+	dfclient "d7y.io/dragonfly/v2/pkg/rpc/dfdaemon/client"
 	"d7y.io/dragonfly/v2/pkg/rpc/scheduler"
 	schedulerclient "d7y.io/dragonfly/v2/pkg/rpc/scheduler/client"
 	"d7y.io/dragonfly/v2/pkg/util/digestutils"
@@ -52,6 +54,9 @@ const (
 	reasonBackSourceDisabled    = "download from source disabled"
 
 	failedReasonNotSet = "unknown"
+
+	// maxStealPeerFailedCount stands max failed count of a steal peer, the steal peer will be skipped after it
+	maxStealPeerFailedCount = 3
 )
 
 var errPeerPacketChanged = errors.New("peer packet changed")
@@ -128,6 +133,23 @@ type peerTaskConductor struct {
 	failedReason string
 	// failedReason will be set when peer task failed
 	failedCode base.Code
+	// scheduleError will be set when peer task failed due to the code from scheduler
+	scheduleError *ScheduleError
+
+	// pieceNotFoundMaxRetry stands max retry with replacement peers when peers return piece not found
+	pieceNotFoundMaxRetry int
+	// notFoundPeers holds the peers which returned piece not found by piece number,
+	// the failed piece will be retried after the scheduler replaces the peer
+	notFoundPeers map[int32]string
+	// notFoundCounts stands the count of piece not found by piece number
+	notFoundCounts map[int32]int
+	// notFoundLock protects notFoundPeers and notFoundCounts
+	notFoundLock sync.Mutex
+
+	// stealPeerFailedCounts stands the failed count of steal peers by peer id
+	stealPeerFailedCounts map[string]int
+	// stealPeerLock protects stealPeerFailedCounts
+	stealPeerLock sync.Mutex
 
 	// readyPieces stands all pieces download status
 	readyPieces *Bitmap
@@ -173,34 +195,38 @@ func (ptm *peerTaskManager) newPeerTaskConductor(ctx context.Context, request *s
 	span.SetAttributes(config.AttributeTaskID.String(taskID))
 
 	ptc := &peerTaskConductor{
-		startTime:           time.Now(),
-		ctx:                 ctx,
-		broker:              newPieceBroker(),
-		host:                ptm.host,
-		request:             request,
-		pieceManager:        ptm.pieceManager,
-		storageManager:      ptm.storageManager,
-		peerTaskManager:     ptm,
-		peerPacketReady:     make(chan bool, 1),
-		peerID:              request.PeerId,
-		taskID:              taskID,
-		successCh:           make(chan struct{}),
-		failCh:              make(chan struct{}),
-		span:                span,
-		readyPieces:         NewBitmap(),
-		requestedPieces:     NewBitmap(),
-		failedPieceCh:       make(chan int32, config.DefaultPieceChanSize),
-		failedReason:        failedReasonNotSet,
-		failedCode:          base.Code_UnknownError,
-		contentLength:       atomic.NewInt64(-1),
-		pieceParallelCount:  atomic.NewInt32(0),
-		totalPiece:          -1,
-		schedulerOption:     ptm.schedulerOption,
-		limiter:             rate.NewLimiter(limit, int(limit)),
-		completedLength:     atomic.NewInt64(0),
-		usedTraffic:         atomic.NewUint64(0),
-		SugaredLoggerOnWith: log,
+		startTime:             time.Now(),
+		ctx:                   ctx,
+		broker:                newPieceBroker(),
+		host:                  ptm.host,
+		request:               request,
+		pieceManager:          ptm.pieceManager,
+		storageManager:        ptm.storageManager,
+		peerTaskManager:       ptm,
+		peerPacketReady:       make(chan bool, 1),
+		peerID:                request.PeerId,
+		taskID:                taskID,
+		successCh:             make(chan struct{}),
+		failCh:                make(chan struct{}),
+		span:                  span,
+		readyPieces:           NewBitmap(),
+		requestedPieces:       NewBitmap(),
+		failedPieceCh:         make(chan int32, config.DefaultPieceChanSize),
+		failedReason:          failedReasonNotSet,
+		failedCode:            base.Code_UnknownError,
+		notFoundPeers:         map[int32]string{},
+		notFoundCounts:        map[int32]int{},
+		stealPeerFailedCounts: map[string]int{},
+		contentLength:         atomic.NewInt64(-1),
+		pieceParallelCount:    atomic.NewInt32(0),
+		totalPiece:            -1,
+		schedulerOption:       ptm.schedulerOption,
+		limiter:               rate.NewLimiter(limit, int(limit)),
+		completedLength:       atomic.NewInt64(0),
+		usedTraffic:           atomic.NewUint64(0),
+		SugaredLoggerOnWith:   log,
 	}
+	ptc.pieceNotFoundMaxRetry = ptm.pieceNotFoundMaxRetry
 	ptc.pieceTaskPoller = &pieceTaskPoller{
 		getPiecesMaxRetry: ptm.getPiecesMaxRetry,
 		peerTaskConductor: ptc,
@@ -227,11 +253,19 @@ func (pt *peerTaskConductor) register() error {
 	logger.Infof("step 1: peer %s start to register", pt.request.PeerId)
 	schedulerClient := pt.peerTaskManager.schedulerClient
 
-	result, err := schedulerClient.RegisterPeerTask(regCtx, pt.request)
+	result, err := schedulerclient.CheckRegisterResult(schedulerClient.RegisterPeerTask(regCtx, pt.request))
 	regSpan.RecordError(err)
 	regSpan.End()
 
-	if err != nil {
+	if errors.Is(err, schedulerclient.ErrNeedBackSource) {
+		// the scheduler asks to download from source, it's not a failure even auto back source is disabled,
+		// the piece result stream is not established, the dummy scheduler client is used instead
+		pt.Infof("step 1: scheduler asks to download from source at registration")
+		pt.span.AddEvent("back source due to scheduler says need back source at registration")
+		needBackSource = true
+		schedulerClient = &dummySchedulerClient{}
+		result = &scheduler.RegisterResult{TaskId: pt.taskID}
+	} else if err != nil {
 		if err == context.DeadlineExceeded {
 			logger.Errorf("scheduler did not response in %s", pt.peerTaskManager.schedulerOption.ScheduleTimeout.Duration)
 		}
@@ -258,8 +292,19 @@ func (pt *peerTaskConductor) register() error {
 			pt.span.SetAttributes(config.AttributePeerTaskSizeScope.String("normal"))
 		case base.SizeScope_SMALL:
 			pt.span.SetAttributes(config.AttributePeerTaskSizeScope.String("small"))
-			if piece, ok := result.DirectPiece.(*scheduler.RegisterResult_SinglePiece); ok {
+			switch piece := result.DirectPiece.(type) {
+			case *scheduler.RegisterResult_SinglePiece:
 				singlePiece = piece.SinglePiece
+			case *scheduler.RegisterResult_PieceContent:
+				// the content is returned inline, store it directly without contacting any peer
+				tinyData = &TinyData{
+					TaskID:  result.TaskId,
+					PeerID:  pt.request.PeerId,
+					Content: piece.PieceContent,
+				}
+			default:
+				pt.Warnf("scheduler return small piece without direct piece, fall back to normal size scope")
+				sizeScope = base.SizeScope_NORMAL
 			}
 		case base.SizeScope_TINY:
 			pt.span.SetAttributes(config.AttributePeerTaskSizeScope.String("tiny"))
@@ -279,7 +324,7 @@ func (pt *peerTaskConductor) register() error {
 		}
 	}
 
-	peerPacketStream, err := schedulerClient.ReportPieceResult(pt.ctx, result.TaskId, pt.request)
+	peerPacketStream, err := schedulerClient.ReportPieceResult(pt.ctx, result.TaskId, pt.request, pt.reportPieceResultOptions()...)
 	pt.Infof("step 2: start report piece result")
 	if err != nil {
 		pt.span.RecordError(err)
@@ -367,6 +412,24 @@ func (pt *peerTaskConductor) cancel(code base.Code, reason string) {
 	})
 }
 
+func (pt *peerTaskConductor) cancelWithScheduleError(scheduleError *ScheduleError) {
+	pt.cancelOnce.Do(func() {
+		pt.scheduleError = scheduleError
+		pt.failedCode = scheduleError.Code
+		pt.failedReason = scheduleError.Reason
+		pt.Fail()
+	})
+}
+
+// failedError returns the error of the failed peer task,
+// it is a *ScheduleError when the peer task failed due to the code from scheduler
+func (pt *peerTaskConductor) failedError() error {
+	if pt.scheduleError != nil {
+		return pt.scheduleError
+	}
+	return fmt.Errorf("peer task failed: %d/%s", pt.failedCode, pt.failedReason)
+}
+
 func (pt *peerTaskConductor) backSource() {
 	backSourceCtx, backSourceSpan := tracer.Start(pt.ctx, config.SpanBackSource)
 	defer backSourceSpan.End()
@@ -398,6 +461,10 @@ func (pt *peerTaskConductor) pullPieces() {
 	case base.SizeScope_TINY:
 		pt.storeTinyPeerTask()
 	case base.SizeScope_SMALL:
+		if pt.tinyData != nil {
+			pt.storeTinyPeerTask()
+			return
+		}
 		go pt.pullSinglePiece()
 	case base.SizeScope_NORMAL:
 		go pt.receivePeerPacket()
@@ -422,6 +489,8 @@ func (pt *peerTaskConductor) storeTinyPeerTask() {
 			},
 			ContentLength: l,
 			TotalPieces:   1,
+			URL:           pt.request.Url,
+			Digest:        pt.request.UrlMeta.GetDigest(),
 			// TODO check digest
 		})
 	pt.storage = storageDriver
@@ -487,6 +556,9 @@ func (pt *peerTaskConductor) receivePeerPacket() {
 		if !firstSpanDone {
 			firstPeerSpan.End()
 		}
+		if reconnects := schedulerclient.Reconnects(pt.peerPacketStream); reconnects > 0 {
+			pt.Infof("reconnect scheduler %d times while receiving peer packets", reconnects)
+		}
 		if pt.needBackSource.Load() {
 			return
 		}
@@ -516,37 +588,26 @@ loop:
 			break loop
 		}
 		if err != nil {
-			pt.confirmReceivePeerPacketError(err)
 			if !firstSpanDone {
 				firstPeerSpan.RecordError(err)
 			}
+			if pt.confirmReceivePeerPacketError(err) {
+				continue
+			}
 			break loop
 		}
 
 		pt.Debugf("receive peerPacket %v for peer %s", peerPacket, pt.peerID)
 		if peerPacket.Code != base.Code_Success {
-			if peerPacket.Code == base.Code_SchedNeedBackSource {
-				pt.needBackSource.Store(true)
-				close(pt.peerPacketReady)
-				pt.Infof("receive back source code")
-				return
-			}
 			pt.Errorf("receive peer packet with error: %d", peerPacket.Code)
-			if pt.isExitPeerPacketCode(peerPacket) {
-				pt.Errorf(pt.failedReason)
-				pt.cancel(pt.failedCode, pt.failedReason)
-				if !firstSpanDone {
-					firstPeerSpan.RecordError(fmt.Errorf(pt.failedReason))
-				}
-				pt.span.AddEvent("receive exit peer packet",
-					trace.WithAttributes(config.AttributePeerPacketCode.Int(int(peerPacket.Code))))
-				pt.span.RecordError(fmt.Errorf(pt.failedReason))
-				break
-			} else {
-				pt.span.AddEvent("receive not success peer packet",
-					trace.WithAttributes(config.AttributePeerPacketCode.Int(int(peerPacket.Code))))
+			if pt.handleScheduleCode(peerPacket.Code,
+				fmt.Sprintf("receive exit peer packet with code %d", peerPacket.Code), false) {
+				continue
 			}
-			continue
+			if !firstSpanDone && pt.scheduleError != nil {
+				firstPeerSpan.RecordError(pt.scheduleError)
+			}
+			break loop
 		}
 
 		if peerPacket.MainPeer == nil && peerPacket.StealPeers == nil {
@@ -580,61 +641,66 @@ loop:
 	}
 }
 
-func (pt *peerTaskConductor) confirmReceivePeerPacketError(err error) {
+// confirmReceivePeerPacketError handles the error of receiving peer packet,
+// returns true when the peer task should keep receiving peer packets
+func (pt *peerTaskConductor) confirmReceivePeerPacketError(err error) bool {
 	select {
 	case <-pt.successCh:
-		return
+		return false
 	case <-pt.failCh:
-		return
+		return false
 	default:
 	}
-	var (
-		failedCode   = base.Code_UnknownError
-		failedReason string
-	)
-	de, ok := err.(*dferrors.DfError)
-	if ok && de.Code == base.Code_SchedNeedBackSource {
+	if de, ok := err.(*dferrors.DfError); ok {
+		pt.Errorf("receive peer packet failed: %s", de)
+		return pt.handleScheduleCode(de.Code, de.Message, true)
+	}
+	pt.Errorf("receive peer packet failed: %s", err)
+	pt.cancel(base.Code_UnknownError, err.Error())
+	return false
+}
+
+// handleScheduleCode takes the action of the not success code from scheduler,
+// returns true when the peer task should keep receiving peer packets.
+// when the stream is broken, the codes to wait fail the peer task, as no more peer packet will be received
+func (pt *peerTaskConductor) handleScheduleCode(code base.Code, reason string, streamBroken bool) bool {
+	action := scheduleActionOf(code)
+	if streamBroken && action == scheduleActionWait {
+		action = scheduleActionFail
+	}
+	pt.Warnf("receive scheduler code %d, reason: %s, action: %s", code, reason, action)
+	pt.span.AddEvent("receive not success peer packet",
+		trace.WithAttributes(config.AttributePeerPacketCode.Int(int(code))))
+
+	switch action {
+	case scheduleActionWait:
+		return true
+	case scheduleActionBackSource:
 		pt.needBackSource.Store(true)
 		close(pt.peerPacketReady)
 		pt.Infof("receive back source code")
-		return
-	} else if ok && de.Code != base.Code_SchedNeedBackSource {
-		failedCode = de.Code
-		failedReason = de.Message
-		pt.Errorf("receive peer packet failed: %s", pt.failedReason)
-	} else {
-		pt.Errorf("receive peer packet failed: %s", err)
+		return false
 	}
-	pt.cancel(failedCode, failedReason)
-	return
-}
 
-func (pt *peerTaskConductor) isExitPeerPacketCode(pp *scheduler.PeerPacket) bool {
-	switch pp.Code {
-	case base.Code_ResourceLacked, base.Code_BadRequest,
-		base.Code_PeerTaskNotFound, base.Code_UnknownError, base.Code_RequestTimeOut:
-		// 1xxx
-		pt.failedCode = pp.Code
-		pt.failedReason = fmt.Sprintf("receive exit peer packet with code %d", pp.Code)
-		return true
-	case base.Code_SchedError, base.Code_SchedTaskStatusError, base.Code_SchedPeerNotFound:
-		// 5xxx
-		pt.failedCode = pp.Code
-		pt.failedReason = fmt.Sprintf("receive exit peer packet with code %d", pp.Code)
-		return true
-	case base.Code_SchedPeerGone:
-		pt.failedReason = reasonPeerGoneFromScheduler
-		pt.failedCode = base.Code_SchedPeerGone
-		return true
-	case base.Code_CDNError, base.Code_CDNTaskRegistryFail, base.Code_CDNTaskDownloadFail:
-		// 6xxx
-		pt.failedCode = pp.Code
-		pt.failedReason = fmt.Sprintf("receive exit peer packet with code %d", pp.Code)
-		return true
+	if code == base.Code_SchedPeerGone {
+		reason = reasonPeerGoneFromScheduler
 	}
+	scheduleError := newScheduleError(code, reason)
+	pt.Errorf(scheduleError.Error())
+	pt.span.RecordError(scheduleError)
+	pt.cancelWithScheduleError(scheduleError)
 	return false
 }
 
+// reportPieceResultOptions returns the call options of reporting piece results by the scheduler option
+func (pt *peerTaskConductor) reportPieceResultOptions() []grpc.CallOption {
+	batch := pt.schedulerOption.PieceResultBatch
+	if batch.Count <= 0 && batch.Interval.Duration <= 0 {
+		return nil
+	}
+	return []grpc.CallOption{schedulerclient.WithPieceResultBatch(batch.Count, batch.Interval.Duration)}
+}
+
 func (pt *peerTaskConductor) pullSinglePiece() {
 	pt.Infof("single piece, dest peer id: %s, piece num: %d, size: %d",
 		pt.singlePiece.DstPid, pt.singlePiece.PieceInfo.PieceNum, pt.singlePiece.PieceInfo.RangeSize)
@@ -714,6 +780,9 @@ loop:
 			break loop
 		case failed := <-pt.failedPieceCh:
 			pt.Warnf("download piece %d failed, retry", failed)
+			if !pt.waitReplacementPeer(failed) {
+				break loop
+			}
 			num = failed
 			limit = 1
 		default:
@@ -783,6 +852,9 @@ loop:
 			// when ok == false, indicates than need break loop
 			break loop
 		}
+		if !pt.waitReplacementPeer(num) {
+			break loop
+		}
 		// just need one piece
 		limit = 1
 	}
@@ -929,6 +1001,55 @@ func (pt *peerTaskConductor) waitFailedPiece() (int32, bool) {
 	}
 }
 
+// markPieceNotFound records the peer which returned piece not found,
+// the piece will be retried with a replacement peer
+func (pt *peerTaskConductor) markPieceNotFound(request *DownloadPieceRequest) {
+	if pt.pieceNotFoundMaxRetry <= 0 {
+		return
+	}
+	pt.notFoundLock.Lock()
+	defer pt.notFoundLock.Unlock()
+	pt.notFoundPeers[request.piece.PieceNum] = request.DstPid
+	pt.notFoundCounts[request.piece.PieceNum]++
+}
+
+// waitReplacementPeer waits the scheduler to replace the peer which returned piece not found,
+// when the retry exceeds pieceNotFoundMaxRetry, the peer task downloads from source.
+// when it returns false, indicates that need break loop
+func (pt *peerTaskConductor) waitReplacementPeer(pieceNum int32) bool {
+	pt.notFoundLock.Lock()
+	dstPid, ok := pt.notFoundPeers[pieceNum]
+	delete(pt.notFoundPeers, pieceNum)
+	count := pt.notFoundCounts[pieceNum]
+	pt.notFoundLock.Unlock()
+	if !ok {
+		return true
+	}
+
+	if count > pt.pieceNotFoundMaxRetry {
+		pt.Warnf("piece %d not found %d times, exceed max retry %d", pieceNum, count, pt.pieceNotFoundMaxRetry)
+		if pt.schedulerOption.DisableAutoBackSource {
+			pt.cancel(base.Code_ClientPieceNotFound, reasonBackSourceDisabled)
+			err := fmt.Errorf("%s, auto back source disabled", pt.failedReason)
+			pt.span.RecordError(err)
+			pt.Errorf(err.Error())
+			return false
+		}
+		pt.span.AddEvent("back source due to piece not found")
+		pt.needBackSource.Store(true)
+		pt.backSource()
+		return false
+	}
+
+	pt.Infof("piece %d not found in peer %s, wait replacement peer from scheduler", pieceNum, dstPid)
+	for pt.peerPacket.Load().(*scheduler.PeerPacket).MainPeer.PeerId == dstPid {
+		if _, ok = pt.waitAvailablePeerPacket(); !ok {
+			return false
+		}
+	}
+	return true
+}
+
 func (pt *peerTaskConductor) downloadPieceWorker(id int32, requests chan *DownloadPieceRequest) {
 	for {
 		select {
@@ -958,7 +1079,16 @@ func (pt *peerTaskConductor) downloadPieceWorker(id int32, requests chan *Downlo
 			// download piece
 			// result is always not nil, pieceManager will report begin and end time
 			result, err := pt.pieceManager.DownloadPiece(ctx, request)
+			if err != nil && (isConnectionError(err) || isPieceNotFound(err)) {
+				// try steal peers before reporting to scheduler
+				if stealRequest, stealResult, ok := pt.downloadPieceFromStealPeers(ctx, request, err); ok {
+					request, result, err = stealRequest, stealResult, nil
+				}
+			}
 			if err != nil {
+				if isPieceNotFound(err) {
+					pt.markPieceNotFound(request)
+				}
 				// send to fail chan and retry
 				pt.failedPieceCh <- request.piece.PieceNum
 				pt.ReportPieceResult(request, result, err)
@@ -983,6 +1113,79 @@ func (pt *peerTaskConductor) downloadPieceWorker(id int32, requests chan *Downlo
 	}
 }
 
+// downloadPieceFromStealPeers tries the steal peers of the latest peer packet in order when the piece
+// failed to download from the main peer, the steal peers failed repeatedly are skipped
+func (pt *peerTaskConductor) downloadPieceFromStealPeers(ctx context.Context, request *DownloadPieceRequest, cause error) (
+	*DownloadPieceRequest, *DownloadPieceResult, bool) {
+	peerPacket := pt.peerPacket.Load().(*scheduler.PeerPacket)
+	for _, peer := range peerPacket.StealPeers {
+		if peer == nil || peer.PeerId == request.DstPid {
+			continue
+		}
+		if pt.stealPeerFailedCount(peer.PeerId) >= maxStealPeerFailedCount {
+			pt.Debugf("steal peer %s failed too many times, skip it", peer.PeerId)
+			continue
+		}
+
+		pt.Infof("download piece %d from peer %s error: %s, try steal peer %s",
+			request.piece.PieceNum, request.DstPid, cause, peer.PeerId)
+		piecePacket, err := dfclient.GetPieceTasks(ctx, peer, &base.PieceTaskRequest{
+			TaskId:   pt.taskID,
+			SrcPid:   pt.peerID,
+			DstPid:   peer.PeerId,
+			StartNum: uint32(request.piece.PieceNum),
+			Limit:    1,
+		})
+		if err != nil {
+			pt.Warnf("get piece %d task from steal peer %s error: %s", request.piece.PieceNum, peer.PeerId, err)
+			pt.markStealPeerFailed(peer.PeerId)
+			continue
+		}
+		if len(piecePacket.PieceInfos) == 0 || piecePacket.PieceInfos[0].PieceNum != request.piece.PieceNum {
+			pt.Warnf("steal peer %s does not have piece %d", peer.PeerId, request.piece.PieceNum)
+			pt.markStealPeerFailed(peer.PeerId)
+			continue
+		}
+
+		stealRequest := &DownloadPieceRequest{
+			storage: request.storage,
+			piece:   request.piece,
+			log:     request.log,
+			TaskID:  request.TaskID,
+			PeerID:  request.PeerID,
+			DstPid:  peer.PeerId,
+			DstAddr: piecePacket.DstAddr,
+		}
+		result, err := pt.pieceManager.DownloadPiece(ctx, stealRequest)
+		if err != nil {
+			pt.Warnf("download piece %d from steal peer %s error: %s", request.piece.PieceNum, peer.PeerId, err)
+			pt.markStealPeerFailed(peer.PeerId)
+			continue
+		}
+		pt.resetStealPeerFailed(peer.PeerId)
+		return stealRequest, result, true
+	}
+	return nil, nil, false
+}
+
+func (pt *peerTaskConductor) stealPeerFailedCount(peerID string) int {
+	pt.stealPeerLock.Lock()
+	defer pt.stealPeerLock.Unlock()
+	return pt.stealPeerFailedCounts[peerID]
+}
+
+func (pt *peerTaskConductor) markStealPeerFailed(peerID string) {
+	pt.stealPeerLock.Lock()
+	defer pt.stealPeerLock.Unlock()
+	pt.stealPeerFailedCounts[peerID]++
+}
+
+func (pt *peerTaskConductor) resetStealPeerFailed(peerID string) {
+	pt.stealPeerLock.Lock()
+	defer pt.stealPeerLock.Unlock()
+	delete(pt.stealPeerFailedCounts, peerID)
+}
+
 func (pt *peerTaskConductor) waitLimit(ctx context.Context, request *DownloadPieceRequest) bool {
 	_, waitSpan := tracer.Start(ctx, config.SpanWaitPieceLimit)
 	err := pt.limiter.WaitN(pt.ctx, int(request.piece.RangeSize))
@@ -1048,6 +1251,7 @@ func (pt *peerTaskConductor) ReportPieceResult(request *DownloadPieceRequest, re
 		pt.reportSuccessResult(request, result)
 		return
 	}
+	// corrupt pieces are reported as download failures too, the scheduler blocks the parent and chooses another one
 	code := base.Code_ClientPieceDownloadFail
 	if isConnectionError(err) {
 		code = base.Code_ClientConnectionError
@@ -1123,6 +1327,8 @@ func (pt *peerTaskConductor) InitStorage() (err error) {
 			ContentLength: pt.GetContentLength(),
 			TotalPieces:   pt.GetTotalPieces(),
 			PieceMd5Sign:  pt.GetPieceMd5Sign(),
+			URL:           pt.request.Url,
+			Digest:        pt.request.UrlMeta.GetDigest(),
 		})
 	if err != nil {
 		pt.Log().Errorf("register task to storage manager failed: %s", err)
@@ -0,0 +1,121 @@
+/*
+ *     Copyright 2022 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package peer
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	testifyassert "github.com/stretchr/testify/assert"
+	"go.uber.org/atomic"
+
+	"d7y.io/dragonfly/v2/client/clientutil"
+	"d7y.io/dragonfly/v2/client/config"
+	"d7y.io/dragonfly/v2/client/daemon/storage"
+	logger "d7y.io/dragonfly/v2/internal/dflog"
+	"d7y.io/dragonfly/v2/pkg/util/digestutils"
+)
+
+// readCountingBackend discards the written data and counts the reads
+type readCountingBackend struct {
+	reads *atomic.Int32
+}
+
+func (b *readCountingBackend) WritePiece(ctx context.Context, key string, offset int64, reader io.Reader) (int64, error) {
+	return io.Copy(io.Discard, reader)
+}
+
+func (b *readCountingBackend) ReadPiece(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	b.reads.Inc()
+	return nil, errors.New("data is discarded")
+}
+
+func (b *readCountingBackend) Stat(ctx context.Context, key string) (*storage.BackendStat, error) {
+	return &storage.BackendStat{}, nil
+}
+
+func (b *readCountingBackend) Delete(ctx context.Context, key string) error {
+	return nil
+}
+
+func TestPeerTaskConductor_ValidateWithoutReadingData(t *testing.T) {
+	assert := testifyassert.New(t)
+	backend := &readCountingBackend{reads: atomic.NewInt32(0)}
+	sm, err := storage.NewStorageManager(config.SimpleLocalTaskStoreStrategy,
+		&config.StorageOption{
+			DataPath: t.TempDir(),
+			TaskExpireTime: clientutil.Duration{
+				Duration: time.Minute,
+			},
+		}, func(request storage.CommonTaskRequest) {
+		}, storage.WithStorageBackend(backend))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sm.CleanUp()
+
+	var (
+		meta   = storage.PeerTaskMetadata{PeerID: "peer-validate", TaskID: "task-validate"}
+		pieces = [][]byte{[]byte("hello "), []byte("dragonfly")}
+		md5s   []string
+	)
+	for _, piece := range pieces {
+		sum := md5.Sum(piece)
+		md5s = append(md5s, hex.EncodeToString(sum[:]))
+	}
+	ts, err := sm.RegisterTask(context.Background(), storage.RegisterTaskRequest{
+		CommonTaskRequest: storage.CommonTaskRequest{PeerID: meta.PeerID, TaskID: meta.TaskID},
+		ContentLength:     int64(len(pieces[0]) + len(pieces[1])),
+		TotalPieces:       int32(len(pieces)),
+		PieceMd5Sign:      digestutils.Sha256(md5s...),
+	})
+	assert.Nil(err)
+
+	var offset int
+	for i, piece := range pieces {
+		_, err = ts.WritePiece(context.Background(), &storage.WritePieceRequest{
+			PeerTaskMetadata: meta,
+			PieceMetadata: storage.PieceMetadata{
+				Num:    int32(i),
+				Md5:    md5s[i],
+				Offset: uint64(offset),
+				Range:  clientutil.Range{Start: int64(offset), Length: int64(len(piece))},
+			},
+			Reader: bytes.NewBuffer(piece),
+		})
+		assert.Nil(err)
+		offset += len(piece)
+	}
+
+	pt := &peerTaskConductor{
+		SugaredLoggerOnWith: logger.With("peer", meta.PeerID, "task", meta.TaskID),
+		ctx:                 context.Background(),
+		peerTaskManager:     &peerTaskManager{calculateDigest: true},
+		storage:             ts,
+		peerID:              meta.PeerID,
+		taskID:              meta.TaskID,
+		totalPiece:          int32(len(pieces)),
+	}
+	assert.Nil(pt.Validate())
+	assert.Equal(int32(0), backend.reads.Load())
+}
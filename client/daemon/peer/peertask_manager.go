@@ -119,6 +119,9 @@ type peerTaskManager struct {
 	calculateDigest bool
 
 	getPiecesMaxRetry int
+
+	// pieceNotFoundMaxRetry stands max retry with replacement peers when peers return piece not found
+	pieceNotFoundMaxRetry int
 }
 
 func NewPeerTaskManager(
@@ -131,21 +134,23 @@ func NewPeerTaskManager(
 	multiplex bool,
 	prefetch bool,
 	calculateDigest bool,
-	getPiecesMaxRetry int) (TaskManager, error) {
+	getPiecesMaxRetry int,
+	pieceNotFoundMaxRetry int) (TaskManager, error) {
 
 	ptm := &peerTaskManager{
-		host:              host,
-		runningPeerTasks:  sync.Map{},
-		conductorLock:     &sync.Mutex{},
-		pieceManager:      pieceManager,
-		storageManager:    storageManager,
-		schedulerClient:   schedulerClient,
-		schedulerOption:   schedulerOption,
-		perPeerRateLimit:  perPeerRateLimit,
-		enableMultiplex:   multiplex,
-		enablePrefetch:    prefetch,
-		calculateDigest:   calculateDigest,
-		getPiecesMaxRetry: getPiecesMaxRetry,
+		host:                  host,
+		runningPeerTasks:      sync.Map{},
+		conductorLock:         &sync.Mutex{},
+		pieceManager:          pieceManager,
+		storageManager:        storageManager,
+		schedulerClient:       schedulerClient,
+		schedulerOption:       schedulerOption,
+		perPeerRateLimit:      perPeerRateLimit,
+		enableMultiplex:       multiplex,
+		enablePrefetch:        prefetch,
+		calculateDigest:       calculateDigest,
+		getPiecesMaxRetry:     getPiecesMaxRetry,
+		pieceNotFoundMaxRetry: pieceNotFoundMaxRetry,
 	}
 	return ptm, nil
 }
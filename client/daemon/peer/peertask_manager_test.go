@@ -19,6 +19,7 @@ package peer
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"math"
@@ -69,8 +70,17 @@ type componentsOption struct {
 	sourceClient       source.ResourceClient
 	peerPacketDelay    []time.Duration
 	backSource         bool
+	// registerBackSource returns base.Code_SchedNeedBackSource in RegisterPeerTask
+	registerBackSource bool
 	scope              base.SizeScope
 	content            []byte
+	// directContent returns the content in RegisterResult for small size scope
+	directContent bool
+	// mainPeers are the main peers of peer packets in order, the last one is kept after exhausted,
+	// a new peer packet is sent when a piece not found result is reported
+	mainPeers []string
+	// stealPeers are the steal peers of peer packets
+	stealPeers []string
 }
 
 func setupPeerTaskManagerComponents(ctrl *gomock.Controller, opt componentsOption) (
@@ -110,7 +120,7 @@ func setupPeerTaskManagerComponents(ctrl *gomock.Controller, opt componentsOptio
 			}
 			return &base.PiecePacket{
 				TaskId:        request.TaskId,
-				DstPid:        "peer-x",
+				DstPid:        request.DstPid,
 				PieceInfos:    tasks,
 				ContentLength: opt.contentLength,
 				TotalPiece:    int32(math.Ceil(float64(opt.contentLength) / float64(opt.pieceSize))),
@@ -132,15 +142,23 @@ func setupPeerTaskManagerComponents(ctrl *gomock.Controller, opt componentsOptio
 
 	// 2. setup a scheduler
 	pps := mock_scheduler.NewMockPeerPacketStream(ctrl)
+	var (
+		delayCount  int
+		packetCount int
+		sent        = make(chan struct{}, 1)
+	)
+	sent <- struct{}{}
 	pps.EXPECT().Send(gomock.Any()).AnyTimes().DoAndReturn(
 		func(pr *scheduler.PieceResult) error {
+			// reschedule when piece not found
+			if pr.Code == base.Code_ClientPieceNotFound {
+				select {
+				case sent <- struct{}{}:
+				default:
+				}
+			}
 			return nil
 		})
-	var (
-		delayCount int
-		sent       = make(chan struct{}, 1)
-	)
-	sent <- struct{}{}
 	pps.EXPECT().Recv().AnyTimes().DoAndReturn(
 		func() (*scheduler.PeerPacket, error) {
 			if len(opt.peerPacketDelay) > delayCount {
@@ -153,6 +171,22 @@ func setupPeerTaskManagerComponents(ctrl *gomock.Controller, opt componentsOptio
 			if opt.backSource {
 				return nil, dferrors.Newf(base.Code_SchedNeedBackSource, "fake back source error")
 			}
+			mainPeer := "peer-x"
+			if len(opt.mainPeers) > 0 {
+				mainPeer = opt.mainPeers[len(opt.mainPeers)-1]
+				if packetCount < len(opt.mainPeers) {
+					mainPeer = opt.mainPeers[packetCount]
+				}
+			}
+			packetCount++
+			var stealPeers []*scheduler.PeerPacket_DestPeer
+			for _, peerID := range opt.stealPeers {
+				stealPeers = append(stealPeers, &scheduler.PeerPacket_DestPeer{
+					Ip:      "127.0.0.1",
+					RpcPort: port,
+					PeerId:  peerID,
+				})
+			}
 			return &scheduler.PeerPacket{
 				Code:          base.Code_Success,
 				TaskId:        opt.taskID,
@@ -161,14 +195,17 @@ func setupPeerTaskManagerComponents(ctrl *gomock.Controller, opt componentsOptio
 				MainPeer: &scheduler.PeerPacket_DestPeer{
 					Ip:      "127.0.0.1",
 					RpcPort: port,
-					PeerId:  "peer-x",
+					PeerId:  mainPeer,
 				},
-				StealPeers: nil,
+				StealPeers: stealPeers,
 			}, nil
 		})
 	sched := mock_scheduler.NewMockSchedulerClient(ctrl)
 	sched.EXPECT().RegisterPeerTask(gomock.Any(), gomock.Any()).AnyTimes().DoAndReturn(
 		func(ctx context.Context, ptr *scheduler.PeerTaskRequest, opts ...grpc.CallOption) (*scheduler.RegisterResult, error) {
+			if opt.registerBackSource {
+				return nil, dferrors.New(base.Code_SchedNeedBackSource, "fake back source at registration")
+			}
 			switch opt.scope {
 			case base.SizeScope_TINY:
 				return &scheduler.RegisterResult{
@@ -179,6 +216,15 @@ func setupPeerTaskManagerComponents(ctrl *gomock.Controller, opt componentsOptio
 					},
 				}, nil
 			case base.SizeScope_SMALL:
+				if opt.directContent {
+					return &scheduler.RegisterResult{
+						TaskId:    opt.taskID,
+						SizeScope: base.SizeScope_SMALL,
+						DirectPiece: &scheduler.RegisterResult_PieceContent{
+							PieceContent: opt.content,
+						},
+					}, nil
+				}
 				return &scheduler.RegisterResult{
 					TaskId:    opt.taskID,
 					SizeScope: base.SizeScope_SMALL,
@@ -207,6 +253,9 @@ func setupPeerTaskManagerComponents(ctrl *gomock.Controller, opt componentsOptio
 	sched.EXPECT().ReportPieceResult(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes().DoAndReturn(
 		func(ctx context.Context, taskId string, ptr *scheduler.PeerTaskRequest, opts ...grpc.CallOption) (
 			schedulerclient.PeerPacketStream, error) {
+			if opt.registerBackSource {
+				return nil, errors.New("piece result stream should not be established")
+			}
 			return pps, nil
 		})
 	sched.EXPECT().ReportPeerResult(gomock.Any(), gomock.Any()).AnyTimes().DoAndReturn(
@@ -263,7 +312,10 @@ func setupMockManager(ctrl *gomock.Controller, ts *testSpec, opt componentsOptio
 		schedulerClient: schedulerClient,
 		schedulerOption: config.SchedulerOption{
 			ScheduleTimeout: scheduleTimeout,
+			// back source is asked by the scheduler, it works even auto back source is disabled
+			DisableAutoBackSource: ts.registerBackSource,
 		},
+		pieceNotFoundMaxRetry: ts.pieceNotFoundMaxRetry,
 	}
 	return &mockManager{
 		testSpec:        ts,
@@ -287,6 +339,7 @@ type testSpec struct {
 	pieceParallelCount int32
 	pieceSize          int
 	sizeScope          base.SizeScope
+	directContent      bool
 	peerID             string
 	url                string
 	// when urlGenerator is not nil, use urlGenerator instead url
@@ -297,6 +350,13 @@ type testSpec struct {
 	peerPacketDelay []time.Duration
 	scheduleTimeout time.Duration
 	backSource      bool
+	// mock back source at registration
+	registerBackSource bool
+
+	// mock piece not found with replacement peers or steal peers
+	mainPeers             []string
+	stealPeers            []string
+	pieceNotFoundMaxRetry int
 
 	mockPieceDownloader  func(ctrl *gomock.Controller, taskData []byte, pieceSize int) PieceDownloader
 	mockHTTPSourceClient func(t *testing.T, ctrl *gomock.Controller, rg *clientutil.Range, taskData []byte, url string) source.ResourceClient
@@ -349,6 +409,94 @@ func TestPeerTaskManager_TaskSuite(t *testing.T) {
 			mockPieceDownloader:  commonPieceDownloader,
 			mockHTTPSourceClient: nil,
 		},
+		{
+			name:               "small size scope - direct content",
+			taskData:           testBytes[:4096],
+			pieceParallelCount: 4,
+			pieceSize:          16384,
+			peerID:             "small-size-direct-content-peer",
+			url:                "http://localhost/test/data",
+			sizeScope:          base.SizeScope_SMALL,
+			directContent:      true,
+			mockPieceDownloader: func(ctrl *gomock.Controller, taskData []byte, pieceSize int) PieceDownloader {
+				downloader := NewMockPieceDownloader(ctrl)
+				downloader.EXPECT().DownloadPiece(gomock.Any(), gomock.Any()).Times(0)
+				return downloader
+			},
+			mockHTTPSourceClient: nil,
+		},
+		{
+			name:                  "normal size scope - p2p - piece not found",
+			taskData:              testBytes,
+			pieceParallelCount:    4,
+			pieceSize:             1024,
+			peerID:                "normal-size-piece-not-found-peer",
+			url:                   "http://localhost/test/data",
+			sizeScope:             base.SizeScope_NORMAL,
+			mainPeers:             []string{"peer-a", "peer-b"},
+			pieceNotFoundMaxRetry: 3,
+			mockPieceDownloader: func(ctrl *gomock.Controller, taskData []byte, pieceSize int) PieceDownloader {
+				downloader := NewMockPieceDownloader(ctrl)
+				// peer-a returns not found for piece 0, and peer-b serves it
+				downloader.EXPECT().DownloadPiece(gomock.Any(), gomock.Any()).Times(
+					int(math.Ceil(float64(len(taskData))/float64(pieceSize))) + 1).DoAndReturn(
+					func(ctx context.Context, task *DownloadPieceRequest) (io.Reader, io.Closer, error) {
+						if task.DstPid == "peer-a" && task.piece.PieceNum == 0 {
+							return nil, nil, &pieceDownloadError{
+								target:     "peer-a",
+								status:     http.StatusText(http.StatusNotFound),
+								statusCode: http.StatusNotFound,
+							}
+						}
+						rc := io.NopCloser(
+							bytes.NewBuffer(
+								taskData[task.piece.RangeStart : task.piece.RangeStart+uint64(task.piece.RangeSize)],
+							))
+						return rc, rc, nil
+					})
+				return downloader
+			},
+			mockHTTPSourceClient: nil,
+		},
+		{
+			name:               "normal size scope - p2p - steal peers",
+			taskData:           testBytes,
+			pieceParallelCount: 4,
+			pieceSize:          1024,
+			peerID:             "normal-size-steal-peers-peer",
+			url:                "http://localhost/test/data",
+			sizeScope:          base.SizeScope_NORMAL,
+			stealPeers:         []string{"peer-bad", "peer-s"},
+			mockPieceDownloader: func(ctrl *gomock.Controller, taskData []byte, pieceSize int) PieceDownloader {
+				downloader := NewMockPieceDownloader(ctrl)
+				// main peer fails to connect for piece 0, peer-bad returns not found and peer-s serves it
+				downloader.EXPECT().DownloadPiece(gomock.Any(), gomock.Any()).Times(
+					int(math.Ceil(float64(len(taskData))/float64(pieceSize))) + 2).DoAndReturn(
+					func(ctx context.Context, task *DownloadPieceRequest) (io.Reader, io.Closer, error) {
+						if task.DstPid == "peer-x" && task.piece.PieceNum == 0 {
+							return nil, nil, &pieceDownloadError{
+								connectionError: true,
+								target:          "peer-x",
+								err:             fmt.Errorf("connection refused"),
+							}
+						}
+						if task.DstPid == "peer-bad" {
+							return nil, nil, &pieceDownloadError{
+								target:     "peer-bad",
+								status:     http.StatusText(http.StatusNotFound),
+								statusCode: http.StatusNotFound,
+							}
+						}
+						rc := io.NopCloser(
+							bytes.NewBuffer(
+								taskData[task.piece.RangeStart : task.piece.RangeStart+uint64(task.piece.RangeSize)],
+							))
+						return rc, rc, nil
+					})
+				return downloader
+			},
+			mockHTTPSourceClient: nil,
+		},
 		{
 			name:                 "tidy size scope - p2p",
 			taskData:             testBytes[:64],
@@ -383,6 +531,29 @@ func TestPeerTaskManager_TaskSuite(t *testing.T) {
 				return sourceClient
 			},
 		},
+		{
+			name:                "normal size scope - back source at registration",
+			taskData:            testBytes,
+			pieceParallelCount:  4,
+			pieceSize:           1024,
+			peerID:              "normal-size-peer-register-back-source",
+			registerBackSource:  true,
+			url:                 "http://localhost/test/data",
+			sizeScope:           base.SizeScope_NORMAL,
+			mockPieceDownloader: nil,
+			mockHTTPSourceClient: func(t *testing.T, ctrl *gomock.Controller, rg *clientutil.Range, taskData []byte, url string) source.ResourceClient {
+				sourceClient := sourceMock.NewMockResourceClient(ctrl)
+				sourceClient.EXPECT().GetContentLength(source.RequestEq(url)).AnyTimes().DoAndReturn(
+					func(request *source.Request) (int64, error) {
+						return int64(len(taskData)), nil
+					})
+				sourceClient.EXPECT().Download(source.RequestEq(url)).AnyTimes().DoAndReturn(
+					func(request *source.Request) (*source.Response, error) {
+						return source.NewResponse(io.NopCloser(bytes.NewBuffer(taskData))), nil
+					})
+				return sourceClient
+			},
+		},
 		{
 			name:     "normal size scope - range - back source - content length",
 			taskData: testBytes[0:4096],
@@ -553,8 +724,12 @@ func TestPeerTaskManager_TaskSuite(t *testing.T) {
 						sourceClient:       sourceClient,
 						content:            tc.taskData,
 						scope:              tc.sizeScope,
+						directContent:      tc.directContent,
+						mainPeers:          tc.mainPeers,
+						stealPeers:         tc.stealPeers,
 						peerPacketDelay:    tc.peerPacketDelay,
 						backSource:         tc.backSource,
+						registerBackSource: tc.registerBackSource,
 					}
 					// keep peer task running in enough time to check "getOrCreatePeerTaskConductor" always return same
 					if tc.taskType == taskTypeConductor {
@@ -708,7 +883,12 @@ func (ts *testSpec) runConductorTest(assert *testifyassert.Assertions, require *
 	case base.SizeScope_TINY:
 		require.NotNil(ptc.tinyData)
 	case base.SizeScope_SMALL:
-		require.NotNil(ptc.singlePiece)
+		if ts.directContent {
+			require.NotNil(ptc.tinyData)
+			require.Nil(ptc.singlePiece)
+		} else {
+			require.NotNil(ptc.singlePiece)
+		}
 	}
 
 	wg.Wait()
@@ -786,3 +966,71 @@ func (ts *testSpec) runConductorTest(assert *testifyassert.Assertions, require *
 	assert.Nil(err, "load output file should be ok")
 	assert.Equal(ts.taskData, outputBytes, "file output and desired output must match")
 }
+
+func TestPeerTaskManager_StreamTask_EmptyFile(t *testing.T) {
+	require := testifyrequire.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	tc := testSpec{
+		taskType:           taskTypeStream,
+		taskData:           []byte{},
+		pieceParallelCount: 4,
+		pieceSize:          1024,
+		peerID:             "empty-file-peer-back-source",
+		backSource:         true,
+		url:                "http://localhost/test/empty",
+		sizeScope:          base.SizeScope_NORMAL,
+	}
+	urlMeta := &base.UrlMeta{
+		Tag: "d7y-test",
+	}
+
+	// empty source file is completed without downloading
+	sourceClient := sourceMock.NewMockResourceClient(ctrl)
+	sourceClient.EXPECT().GetContentLength(source.RequestEq(tc.url)).AnyTimes().DoAndReturn(
+		func(request *source.Request) (int64, error) {
+			return 0, nil
+		})
+	sourceClient.EXPECT().Download(source.RequestEq(tc.url)).Times(0)
+	source.UnRegister("http")
+	defer func() {
+		// reset source client
+		source.UnRegister("http")
+		require.Nil(source.Register("http", httpprotocol.NewHTTPSourceClient(), httpprotocol.Adapter))
+	}()
+	require.Nil(source.Register("http", sourceClient, httpprotocol.Adapter))
+
+	taskID := idgen.TaskID(tc.url, urlMeta)
+	mm := setupMockManager(ctrl, &tc, componentsOption{
+		taskID:             taskID,
+		contentLength:      0,
+		pieceSize:          uint32(tc.pieceSize),
+		pieceParallelCount: tc.pieceParallelCount,
+		sourceClient:       sourceClient,
+		content:            tc.taskData,
+		scope:              tc.sizeScope,
+		backSource:         tc.backSource,
+	})
+	defer mm.CleanUp()
+
+	r, attr, err := mm.peerTaskManager.StartStreamTask(
+		context.Background(),
+		&StreamTaskRequest{
+			URL:     tc.url,
+			URLMeta: urlMeta,
+			PeerID:  tc.peerID,
+		})
+	require.Nil(err, "start stream peer task")
+	require.Equal("0", attr[headers.ContentLength])
+
+	outputBytes, err := io.ReadAll(r)
+	require.Nil(err, "load read data")
+	require.Nil(r.Close())
+	require.Empty(outputBytes)
+
+	reuse := mm.storageManager.FindCompletedTask(taskID)
+	require.NotNil(reuse, "empty task should be completed")
+	require.Equal(int64(0), reuse.ContentLength)
+	require.Equal(int32(0), reuse.TotalPieces)
+}
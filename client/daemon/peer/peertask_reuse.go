@@ -49,6 +49,13 @@ func (ptm *peerTaskManager) tryReuseFilePeerTask(ctx context.Context,
 		length int64
 		err    error
 	)
+	if reuse == nil && request.UrlMeta.GetRange() == "" {
+		// try to reuse the completed task with the same content from other tasks
+		reuse = ptm.storageManager.FindReusableTask(request.Url, request.UrlMeta.GetDigest())
+		if reuse != nil {
+			taskID = reuse.TaskID
+		}
+	}
 	if reuse == nil {
 		taskID = idgen.ParentTaskID(request.Url, request.UrlMeta)
 		reuse = ptm.storageManager.FindCompletedTask(taskID)
@@ -178,6 +185,13 @@ func (ptm *peerTaskManager) tryReuseStreamPeerTask(ctx context.Context,
 		rg  *clientutil.Range // the range of parent peer task data to read
 		log *logger.SugaredLoggerOnWith
 	)
+	if reuse == nil && request.Range == nil {
+		// try to reuse the completed task with the same content from other tasks
+		reuse = ptm.storageManager.FindReusableTask(request.URL, request.URLMeta.GetDigest())
+		if reuse != nil {
+			taskID = reuse.TaskID
+		}
+	}
 	if reuse == nil {
 		// for ranged request, check the parent task
 		if request.Range == nil {
@@ -188,7 +202,12 @@ func (ptm *peerTaskManager) tryReuseStreamPeerTask(ctx context.Context,
 		if reuse == nil {
 			return nil, nil, false
 		}
-		rg = request.Range
+		// the open-ended range is resolved against the length of parent task
+		rgs, err := clientutil.ParseRange("bytes="+request.URLMeta.Range, reuse.ContentLength)
+		if err != nil || len(rgs) != 1 {
+			return nil, nil, false
+		}
+		rg = &rgs[0]
 	}
 
 	if rg == nil {
@@ -212,8 +231,16 @@ func (ptm *peerTaskManager) tryReuseStreamPeerTask(ctx context.Context,
 	}
 	defer span.End()
 
-	rc, err := ptm.storageManager.ReadAllPieces(ctx,
-		&storage.ReadAllPiecesRequest{PeerTaskMetadata: reuse.PeerTaskMetadata, Range: rg})
+	var (
+		rc  io.ReadCloser
+		err error
+	)
+	if rg == nil {
+		rc, err = ptm.storageManager.ReadAllPieces(ctx,
+			&storage.ReadAllPiecesRequest{PeerTaskMetadata: reuse.PeerTaskMetadata})
+	} else {
+		rc, err = ptm.storageManager.ReadPiecesRange(ctx, &reuse.PeerTaskMetadata, rg.Start, rg.Length)
+	}
 	if err != nil {
 		log.Errorf("read pieces error when reuse peer task: %s", err)
 		span.SetAttributes(config.AttributePeerTaskSuccess.Bool(false))
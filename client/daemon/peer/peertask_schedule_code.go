@@ -0,0 +1,103 @@
+/*
+ *     Copyright 2022 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package peer
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"d7y.io/dragonfly/v2/pkg/rpc/base"
+)
+
+// scheduleAction is the action of peer task when receiving a not success code from scheduler
+type scheduleAction int
+
+const (
+	// scheduleActionWait ignores the code and waits next peer packet
+	scheduleActionWait scheduleAction = iota
+	// scheduleActionBackSource downloads from source
+	scheduleActionBackSource
+	// scheduleActionFail fails the peer task
+	scheduleActionFail
+)
+
+func (a scheduleAction) String() string {
+	switch a {
+	case scheduleActionWait:
+		return "wait"
+	case scheduleActionBackSource:
+		return "back-source"
+	case scheduleActionFail:
+		return "fail"
+	}
+	return fmt.Sprintf("unknown(%d)", int(a))
+}
+
+// scheduleActionOf maps the code from scheduler to the action of peer task
+func scheduleActionOf(code base.Code) scheduleAction {
+	switch code {
+	case base.Code_SchedNeedBackSource:
+		return scheduleActionBackSource
+	case base.Code_ResourceLacked, base.Code_BadRequest, base.Code_UnknownError, base.Code_RequestTimeOut,
+		base.Code_SchedError, base.Code_SchedTaskStatusError, base.Code_SchedPeerGone,
+		base.Code_CDNError, base.Code_CDNTaskRegistryFail, base.Code_CDNTaskDownloadFail,
+		// the piece result stream registers the peer task again when scheduler lost the peer,
+		// the codes are received only after registering again failed
+		base.Code_SchedPeerNotFound, base.Code_PeerTaskNotFound:
+		return scheduleActionFail
+	}
+	// like base.Code_SchedPeerPieceResultReportFail, scheduler will send new peer packet later
+	return scheduleActionWait
+}
+
+// isRetriableScheduleCode returns whether a new peer task may succeed after the peer task failed with the code
+func isRetriableScheduleCode(code base.Code) bool {
+	switch code {
+	case base.Code_ResourceLacked, base.Code_RequestTimeOut, base.Code_PeerTaskNotFound,
+		base.Code_SchedError, base.Code_SchedPeerGone, base.Code_SchedPeerNotFound,
+		base.Code_CDNError, base.Code_CDNTaskDownloadFail:
+		return true
+	}
+	return false
+}
+
+// ScheduleError is the error of peer task failed due to the code from scheduler
+type ScheduleError struct {
+	Code   base.Code
+	Reason string
+	// Retriable indicates a new peer task may succeed later
+	Retriable bool
+}
+
+func newScheduleError(code base.Code, reason string) *ScheduleError {
+	return &ScheduleError{
+		Code:      code,
+		Reason:    reason,
+		Retriable: isRetriableScheduleCode(code),
+	}
+}
+
+func (e *ScheduleError) Error() string {
+	return fmt.Sprintf("peer task failed due to scheduler code %d: %s", e.Code, e.Reason)
+}
+
+// IsRetriableScheduleError returns whether the err is a retriable ScheduleError
+func IsRetriableScheduleError(err error) bool {
+	var e *ScheduleError
+	return errors.As(err, &e) && e.Retriable
+}
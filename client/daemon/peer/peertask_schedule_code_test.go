@@ -0,0 +1,69 @@
+/*
+ *     Copyright 2022 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package peer
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/pkg/errors"
+	testifyassert "github.com/stretchr/testify/assert"
+
+	"d7y.io/dragonfly/v2/pkg/rpc/base"
+)
+
+func TestScheduleActionOf(t *testing.T) {
+	testCases := []struct {
+		code      base.Code
+		action    scheduleAction
+		retriable bool
+	}{
+		{code: base.Code_SchedNeedBackSource, action: scheduleActionBackSource, retriable: false},
+		{code: base.Code_SchedPeerNotFound, action: scheduleActionFail, retriable: true},
+		{code: base.Code_PeerTaskNotFound, action: scheduleActionFail, retriable: true},
+		{code: base.Code_ResourceLacked, action: scheduleActionFail, retriable: true},
+		{code: base.Code_RequestTimeOut, action: scheduleActionFail, retriable: true},
+		{code: base.Code_SchedPeerPieceResultReportFail, action: scheduleActionWait, retriable: false},
+		{code: base.Code_ClientWaitPieceReady, action: scheduleActionWait, retriable: false},
+		{code: base.Code_BadRequest, action: scheduleActionFail, retriable: false},
+		{code: base.Code_UnknownError, action: scheduleActionFail, retriable: false},
+		{code: base.Code_SchedError, action: scheduleActionFail, retriable: true},
+		{code: base.Code_SchedTaskStatusError, action: scheduleActionFail, retriable: false},
+		{code: base.Code_SchedPeerGone, action: scheduleActionFail, retriable: true},
+		{code: base.Code_CDNError, action: scheduleActionFail, retriable: true},
+		{code: base.Code_CDNTaskRegistryFail, action: scheduleActionFail, retriable: false},
+		{code: base.Code_CDNTaskDownloadFail, action: scheduleActionFail, retriable: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("code %d", tc.code), func(t *testing.T) {
+			assert := testifyassert.New(t)
+			assert.Equal(tc.action, scheduleActionOf(tc.code), "action of code %d", tc.code)
+
+			err := newScheduleError(tc.code, "test")
+			assert.Equal(tc.retriable, err.Retriable)
+			assert.Equal(tc.retriable, IsRetriableScheduleError(errors.Wrap(err, "wrapped")))
+		})
+	}
+}
+
+func TestIsRetriableScheduleError(t *testing.T) {
+	assert := testifyassert.New(t)
+	assert.False(IsRetriableScheduleError(nil))
+	assert.False(IsRetriableScheduleError(errors.New("not schedule error")))
+	assert.True(IsRetriableScheduleError(&ScheduleError{Code: base.Code_SchedError, Retriable: true}))
+}
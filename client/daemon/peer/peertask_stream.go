@@ -22,6 +22,7 @@ import (
 	"io"
 
 	"github.com/go-http-utils/headers"
+	"github.com/pkg/errors"
 	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/time/rate"
 
@@ -105,8 +106,7 @@ func (s *streamTask) Start(ctx context.Context) (io.ReadCloser, map[string]strin
 		s.span.End()
 		return nil, attr, ctx.Err()
 	case <-s.peerTaskConductor.failCh:
-		return nil, attr, fmt.Errorf("peer task failed: %d/%s",
-			s.peerTaskConductor.failedCode, s.peerTaskConductor.failedReason)
+		return nil, attr, s.peerTaskConductor.failedError()
 	case <-s.peerTaskConductor.successCh:
 		if s.peerTaskConductor.GetContentLength() != -1 {
 			attr[headers.ContentLength] = fmt.Sprintf("%d", s.peerTaskConductor.GetContentLength())
@@ -216,8 +216,7 @@ func (s *streamTask) writeToPipe(firstPiece *pieceInfo, pw *io.PipeWriter) {
 			// FIXME check missing piece for non-block broker channel
 			continue
 		case <-s.peerTaskConductor.failCh:
-			ptError := fmt.Errorf("context done due to peer task fail: %d/%s",
-				s.peerTaskConductor.failedCode, s.peerTaskConductor.failedReason)
+			ptError := errors.Wrap(s.peerTaskConductor.failedError(), "context done due to peer task fail")
 			s.Error(ptError.Error())
 			s.span.RecordError(ptError)
 			if err = pw.CloseWithError(ptError); err != nil {
@@ -18,18 +18,28 @@ package peer
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/pkg/errors"
+	"golang.org/x/time/rate"
+
 	"d7y.io/dragonfly/v2/client/daemon/storage"
 	"d7y.io/dragonfly/v2/client/daemon/upload"
 	logger "d7y.io/dragonfly/v2/internal/dflog"
+	"d7y.io/dragonfly/v2/internal/dfnet"
 	"d7y.io/dragonfly/v2/pkg/rpc/base"
 	"d7y.io/dragonfly/v2/pkg/util/digestutils"
+	"d7y.io/dragonfly/v2/pkg/util/mathutils"
 )
 
 type DownloadPieceRequest struct {
@@ -41,6 +51,12 @@ type DownloadPieceRequest struct {
 	DstPid     string
 	DstAddr    string
 	CalcDigest bool
+	// ComputeDigest computes the piece digest even when it is not known, the digest is returned
+	// in DownloadPieceResult.PieceMd5, the known piece digest is still verified
+	ComputeDigest bool
+	// DigestAlgorithm is the algorithm of piece digest, md5, sha256 or sha512,
+	// the algorithm prefix of piece digest takes precedence, md5 is used when neither is set
+	DigestAlgorithm string
 }
 
 type DownloadPieceResult struct {
@@ -50,16 +66,44 @@ type DownloadPieceResult struct {
 	BeginTime int64
 	// FinishTime nanosecond
 	FinishTime int64
+	// PieceMd5 is the digest computed when downloading, it's set when DownloadPieceRequest.ComputeDigest is true
+	PieceMd5 string
 }
 
+//go:generate mockgen -source piece_downloader.go -package peer -self_package d7y.io/dragonfly/v2/client/daemon/peer -destination piece_downloader_mock_test.go
 //go:generate mockgen -source piece_downloader.go -destination ../test/mock/peer/piece_downloader.go
 type PieceDownloader interface {
 	DownloadPiece(context.Context, *DownloadPieceRequest) (io.Reader, io.Closer, error)
+	// HasPiece checks whether the destination peer holds the piece without downloading it
+	HasPiece(context.Context, *DownloadPieceRequest) (bool, error)
 }
 
 type pieceDownloader struct {
 	transport  http.RoundTripper
 	httpClient *http.Client
+
+	// scheme is the url scheme for downloading pieces from other peers, http or https
+	scheme string
+	// caCertPool is used to verify the certificates of other peers when tls enabled
+	caCertPool *x509.CertPool
+	// insecureSkipVerify disables the verification of the certificates of other peers
+	insecureSkipVerify bool
+
+	// retryAttempts is the max attempts to connect other peers, only connection errors will be retried
+	retryAttempts    int
+	retryInitBackoff time.Duration
+	retryMaxBackoff  time.Duration
+
+	// unixSocket enables downloading pieces via unix domain socket when DstAddr is a socket file
+	unixSocket bool
+	// unixSocketClients caches http clients by socket path
+	unixSocketClients sync.Map
+
+	// limiter limits the aggregate read throughput of all pieces, bytes per second
+	limiter *rate.Limiter
+
+	// trafficMark marks the sockets connected to other peers
+	trafficMark dfnet.TrafficMark
 }
 
 type pieceDownloadError struct {
@@ -84,6 +128,12 @@ func isPieceNotFound(err error) bool {
 	return false
 }
 
+// isDigestMismatch returns whether the downloaded piece is corrupt
+func isDigestMismatch(err error) bool {
+	var mismatch *digestutils.DigestMismatchError
+	return errors.As(err, &mismatch)
+}
+
 func (e *pieceDownloadError) Error() string {
 	if e.connectionError {
 		return fmt.Sprintf("connect with %s with error: %s", e.target, e.err)
@@ -91,15 +141,53 @@ func (e *pieceDownloadError) Error() string {
 	return fmt.Sprintf("download %s with error status: %s", e.target, e.status)
 }
 
+// taskIDPrefixLength is the length of task id prefix in the piece download url
+const taskIDPrefixLength = 3
+
+// invalidPieceRequestError is returned before any network call when the DownloadPieceRequest is invalid
+type invalidPieceRequestError struct {
+	field  string
+	reason string
+}
+
+func (e *invalidPieceRequestError) Error() string {
+	return fmt.Sprintf("invalid download piece request, %s %s", e.field, e.reason)
+}
+
+func isInvalidPieceRequest(err error) bool {
+	_, ok := err.(*invalidPieceRequestError)
+	return ok
+}
+
+// validateDownloadPieceRequest checks the fields used to build the piece download url
+func validateDownloadPieceRequest(req *DownloadPieceRequest) error {
+	if strings.TrimSpace(req.DstAddr) == "" {
+		return &invalidPieceRequestError{field: "DstAddr", reason: "is empty"}
+	}
+	if len(req.TaskID) < taskIDPrefixLength {
+		return &invalidPieceRequestError{field: "TaskID", reason: fmt.Sprintf("%q is shorter than %d", req.TaskID, taskIDPrefixLength)}
+	}
+	if req.DstPid == "" {
+		return &invalidPieceRequestError{field: "DstPid", reason: "is empty"}
+	}
+	if req.piece == nil {
+		return &invalidPieceRequestError{field: "piece", reason: "is nil"}
+	}
+	return nil
+}
+
 var _ PieceDownloader = (*pieceDownloader)(nil)
 
+// defaultDialer dials other peers, it's copied when sockets are marked
+var defaultDialer = &net.Dialer{
+	Timeout:   2 * time.Second,
+	KeepAlive: 30 * time.Second,
+	DualStack: true,
+}
+
 var defaultTransport http.RoundTripper = &http.Transport{
-	Proxy: http.ProxyFromEnvironment,
-	DialContext: (&net.Dialer{
-		Timeout:   2 * time.Second,
-		KeepAlive: 30 * time.Second,
-		DualStack: true,
-	}).DialContext,
+	Proxy:                 http.ProxyFromEnvironment,
+	DialContext:           defaultDialer.DialContext,
 	MaxIdleConns:          100,
 	IdleConnTimeout:       90 * time.Second,
 	ResponseHeaderTimeout: 2 * time.Second,
@@ -108,7 +196,10 @@ var defaultTransport http.RoundTripper = &http.Transport{
 }
 
 func NewPieceDownloader(timeout time.Duration, opts ...func(*pieceDownloader) error) (PieceDownloader, error) {
-	pd := &pieceDownloader{}
+	pd := &pieceDownloader{
+		scheme:        "http",
+		retryAttempts: 1,
+	}
 
 	for _, opt := range opts {
 		if err := opt(pd); err != nil {
@@ -118,6 +209,21 @@ func NewPieceDownloader(timeout time.Duration, opts ...func(*pieceDownloader) er
 
 	if pd.transport == nil {
 		pd.transport = defaultTransport
+		if pd.scheme == "https" || pd.trafficMark.Enabled() {
+			transport := defaultTransport.(*http.Transport).Clone()
+			if pd.scheme == "https" {
+				transport.TLSClientConfig = &tls.Config{
+					RootCAs:            pd.caCertPool,
+					InsecureSkipVerify: pd.insecureSkipVerify,
+				}
+			}
+			if pd.trafficMark.Enabled() {
+				dialer := *defaultDialer
+				dialer.Control = pd.trafficMark.Control
+				transport.DialContext = dialer.DialContext
+			}
+			pd.transport = transport
+		}
 	}
 
 	pd.httpClient = &http.Client{
@@ -134,11 +240,94 @@ func WithTransport(rt http.RoundTripper) func(*pieceDownloader) error {
 	}
 }
 
+// WithScheme sets the url scheme for downloading pieces, only http and https are supported
+func WithScheme(scheme string) func(*pieceDownloader) error {
+	return func(d *pieceDownloader) error {
+		if scheme != "http" && scheme != "https" {
+			return fmt.Errorf("unsupported piece download scheme: %s", scheme)
+		}
+		d.scheme = scheme
+		return nil
+	}
+}
+
+// WithTrafficMark marks the sockets connected to other peers with DSCP or SO_MARK,
+// it only takes effect with the default transport
+func WithTrafficMark(mark dfnet.TrafficMark) func(*pieceDownloader) error {
+	return func(d *pieceDownloader) error {
+		d.trafficMark = mark
+		return nil
+	}
+}
+
+// WithTLSEnabled switches the url scheme to https when other peers serve pieces with tls
+func WithTLSEnabled(enabled bool) func(*pieceDownloader) error {
+	return func(d *pieceDownloader) error {
+		if enabled {
+			d.scheme = "https"
+		} else {
+			d.scheme = "http"
+		}
+		return nil
+	}
+}
+
+// WithCACertPool sets the ca cert pool for verifying other peers, the system pool is used when it's nil
+func WithCACertPool(pool *x509.CertPool) func(*pieceDownloader) error {
+	return func(d *pieceDownloader) error {
+		d.caCertPool = pool
+		return nil
+	}
+}
+
+// WithInsecureSkipVerify skips the verification of other peers' certificates, only for testing
+func WithInsecureSkipVerify(skip bool) func(*pieceDownloader) error {
+	return func(d *pieceDownloader) error {
+		d.insecureSkipVerify = skip
+		return nil
+	}
+}
+
+// WithRetry sets the max attempts and the backoff for retrying connection errors,
+// the status errors from other peers are never retried
+func WithRetry(attempts int, initBackoff, maxBackoff time.Duration) func(*pieceDownloader) error {
+	return func(d *pieceDownloader) error {
+		if attempts < 1 {
+			return fmt.Errorf("invalid piece download retry attempts: %d", attempts)
+		}
+		if initBackoff > maxBackoff {
+			return fmt.Errorf("init backoff %s is bigger than max backoff %s", initBackoff, maxBackoff)
+		}
+		d.retryAttempts = attempts
+		d.retryInitBackoff = initBackoff
+		d.retryMaxBackoff = maxBackoff
+		return nil
+	}
+}
+
+// WithUnixSocket enables downloading pieces via unix domain socket for co-located peers,
+// it falls back to tcp when DstAddr is not a socket file
+func WithUnixSocket(enabled bool) func(*pieceDownloader) error {
+	return func(d *pieceDownloader) error {
+		d.unixSocket = enabled
+		return nil
+	}
+}
+
+// WithRateLimiter sets the shared limiter for reading piece data from other peers
+func WithRateLimiter(limiter *rate.Limiter) func(*pieceDownloader) error {
+	return func(d *pieceDownloader) error {
+		d.limiter = limiter
+		return nil
+	}
+}
+
 func (p *pieceDownloader) DownloadPiece(ctx context.Context, req *DownloadPieceRequest) (io.Reader, io.Closer, error) {
-	resp, err := p.httpClient.Do(buildDownloadPieceHTTPRequest(ctx, req))
+	if err := validateDownloadPieceRequest(req); err != nil {
+		return nil, nil, err
+	}
+	resp, err := p.doWithRetry(ctx, req)
 	if err != nil {
-		logger.Errorf("task id: %s, piece num: %d, dst: %s, download piece failed: %s",
-			req.TaskID, req.piece.PieceNum, req.DstAddr, err)
 		return nil, nil, &pieceDownloadError{err: err, connectionError: true}
 	}
 	if resp.StatusCode > 299 {
@@ -147,20 +336,155 @@ func (p *pieceDownloader) DownloadPiece(ctx context.Context, req *DownloadPieceR
 		return nil, nil, &pieceDownloadError{err: err, connectionError: false, status: resp.Status, statusCode: resp.StatusCode}
 	}
 	reader, closer := resp.Body.(io.Reader), resp.Body.(io.Closer)
-	if req.CalcDigest {
+	if p.limiter != nil {
+		reader = &rateLimitReader{ctx: ctx, limiter: p.limiter, reader: reader}
+	}
+	if req.CalcDigest || req.ComputeDigest {
 		req.log.Debugf("calculate digest for piece %d, digest: %s", req.piece.PieceNum, req.piece.PieceMd5)
-		reader = digestutils.NewDigestReader(req.log, io.LimitReader(resp.Body, int64(req.piece.RangeSize)), req.piece.PieceMd5)
+		reader = digestutils.NewPieceDigestReader(req.log, io.LimitReader(reader, int64(req.piece.RangeSize)),
+			req.piece.PieceNum, req.DigestAlgorithm, req.piece.PieceMd5)
 	}
 	return reader, closer, nil
 }
 
-func buildDownloadPieceHTTPRequest(ctx context.Context, d *DownloadPieceRequest) *http.Request {
+// rateLimitReader waits for the limiter after every read, the wait is canceled with ctx
+type rateLimitReader struct {
+	ctx     context.Context
+	limiter *rate.Limiter
+	reader  io.Reader
+}
+
+func (r *rateLimitReader) Read(p []byte) (int, error) {
+	// WaitN fails when n is bigger than burst, read at most burst bytes once
+	if burst := r.limiter.Burst(); burst > 0 && len(p) > burst {
+		p = p[:burst]
+	}
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		if werr := r.limiter.WaitN(r.ctx, n); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
+
+func (p *pieceDownloader) doWithRetry(ctx context.Context, req *DownloadPieceRequest) (*http.Response, error) {
+	var (
+		resp *http.Response
+		err  error
+	)
+	for i := 0; i < p.retryAttempts; i++ {
+		if i > 0 {
+			backoff := mathutils.RandBackoff(p.retryInitBackoff.Seconds(), p.retryMaxBackoff.Seconds(), 2.0, i)
+			select {
+			case <-ctx.Done():
+				return nil, err
+			case <-time.After(backoff):
+			}
+		}
+
+		resp, err = p.do(ctx, http.MethodGet, req)
+		if err == nil {
+			return resp, nil
+		}
+		logger.Errorf("task id: %s, piece num: %d, dst: %s, download piece failed, attempt %d/%d: %s",
+			req.TaskID, req.piece.PieceNum, req.DstAddr, i+1, p.retryAttempts, err)
+		if ctx.Err() != nil {
+			return nil, err
+		}
+	}
+	return nil, err
+}
+
+func (p *pieceDownloader) HasPiece(ctx context.Context, req *DownloadPieceRequest) (bool, error) {
+	if err := validateDownloadPieceRequest(req); err != nil {
+		return false, err
+	}
+	resp, err := p.do(ctx, http.MethodHead, req)
+	if err != nil {
+		return false, &pieceDownloadError{err: err, connectionError: true, target: req.DstAddr}
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if resp.StatusCode > 299 {
+		return false, &pieceDownloadError{connectionError: false, status: resp.Status, statusCode: resp.StatusCode, target: req.DstAddr}
+	}
+	return true, nil
+}
+
+func (p *pieceDownloader) do(ctx context.Context, method string, req *DownloadPieceRequest) (*http.Response, error) {
+	client, request := p.httpClient, buildDownloadPieceHTTPRequest(ctx, p.scheme, req.DstAddr, req)
+	if p.unixSocket && isUnixSocket(req.DstAddr) {
+		client, request = p.unixSocketClient(req.DstAddr), buildDownloadPieceHTTPRequest(ctx, "http", "unix", req)
+	}
+	request.Method = method
+	return client.Do(request)
+}
+
+// unixSocketClient returns the http client which dials the unix domain socket at path
+func (p *pieceDownloader) unixSocketClient(path string) *http.Client {
+	if client, ok := p.unixSocketClients.Load(path); ok {
+		return client.(*http.Client)
+	}
+
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", path)
+		},
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		ResponseHeaderTimeout: 2 * time.Second,
+	}
+	client, _ := p.unixSocketClients.LoadOrStore(path, &http.Client{
+		Transport: transport,
+		Timeout:   p.httpClient.Timeout,
+	})
+	return client.(*http.Client)
+}
+
+func isUnixSocket(addr string) bool {
+	if !filepath.IsAbs(addr) {
+		return false
+	}
+	fi, err := os.Stat(addr)
+	return err == nil && fi.Mode()&os.ModeSocket != 0
+}
+
+// urlHost returns the host of url for addr, the ipv6 ip of addr like "fe80::1:65002",
+// which is built without net.JoinHostPort by old peers, is enclosed in square brackets
+func urlHost(addr string) string {
+	if _, _, err := net.SplitHostPort(addr); err == nil {
+		return addr
+	}
+	if i := strings.LastIndex(addr, ":"); i > 0 && isIPv6(addr[:i]) {
+		return net.JoinHostPort(addr[:i], addr[i+1:])
+	}
+	if isIPv6(addr) {
+		return "[" + addr + "]"
+	}
+	return addr
+}
+
+func isIPv6(host string) bool {
+	return strings.Contains(host, ":") && net.ParseIP(host) != nil
+}
+
+func buildDownloadPieceHTTPRequest(ctx context.Context, scheme, host string, d *DownloadPieceRequest) *http.Request {
 	b := strings.Builder{}
-	// FIXME switch to https when tls enabled
-	b.WriteString("http://")
-	b.WriteString(d.DstAddr)
+	b.WriteString(scheme)
+	b.WriteString("://")
+	b.WriteString(urlHost(host))
 	b.WriteString(upload.PeerDownloadHTTPPathPrefix)
-	b.Write([]byte(d.TaskID)[:3])
+	if len(d.TaskID) >= taskIDPrefixLength {
+		b.WriteString(d.TaskID[:taskIDPrefixLength])
+	} else {
+		b.WriteString(d.TaskID)
+	}
 	b.Write([]byte("/"))
 	b.WriteString(d.TaskID)
 	b.Write([]byte("?peerId="))
@@ -19,20 +19,25 @@ package peer
 import (
 	"context"
 	"crypto/md5"
+	"crypto/x509"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"math"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"path"
 	"testing"
 	"time"
 
 	"github.com/go-http-utils/headers"
 	testifyassert "github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
 
 	"d7y.io/dragonfly/v2/client/clientutil"
 	"d7y.io/dragonfly/v2/client/daemon/test"
@@ -136,7 +141,7 @@ func TestPieceDownloader_DownloadPiece(t *testing.T) {
 			digest := hex.EncodeToString(hash.Sum(nil)[:16])
 			r, c, err := pd.DownloadPiece(context.Background(), &DownloadPieceRequest{
 				TaskID:     tt.taskID,
-				DstPid:     "",
+				DstPid:     "peer-0",
 				DstAddr:    addr.Host,
 				CalcDigest: true,
 				piece: &base.PieceInfo{
@@ -160,3 +165,521 @@ func TestPieceDownloader_DownloadPiece(t *testing.T) {
 		server.Close()
 	}
 }
+
+func TestPieceDownloader_DownloadPieceWithTLS(t *testing.T) {
+	assert := testifyassert.New(t)
+	testData := []byte("test tls piece data")
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(upload.PeerDownloadHTTPPathPrefix+"tas/"+"task-0", r.URL.Path)
+		rg := clientutil.MustParseRange(r.Header.Get("Range"), math.MaxInt64)
+		w.Header().Set(headers.ContentLength, fmt.Sprintf("%d", rg.Length))
+		if _, err := w.Write(testData[rg.Start : rg.Start+rg.Length]); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer server.Close()
+	addr, _ := url.Parse(server.URL)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(server.Certificate())
+
+	tests := []struct {
+		name    string
+		opts    []func(*pieceDownloader) error
+		success bool
+	}{
+		{
+			name:    "verify with ca cert pool",
+			opts:    []func(*pieceDownloader) error{WithTLSEnabled(true), WithCACertPool(pool)},
+			success: true,
+		},
+		{
+			name:    "insecure skip verify",
+			opts:    []func(*pieceDownloader) error{WithScheme("https"), WithInsecureSkipVerify(true)},
+			success: true,
+		},
+		{
+			name:    "unknown certificate authority",
+			opts:    []func(*pieceDownloader) error{WithTLSEnabled(true)},
+			success: false,
+		},
+		{
+			name:    "plain http to tls server",
+			opts:    []func(*pieceDownloader) error{},
+			success: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pd, err := NewPieceDownloader(30*time.Second, tt.opts...)
+			assert.Nil(err)
+			r, c, err := pd.DownloadPiece(context.Background(), &DownloadPieceRequest{
+				TaskID:  "task-0",
+				DstPid:  "peer-0",
+				DstAddr: addr.Host,
+				piece: &base.PieceInfo{
+					RangeStart: 0,
+					RangeSize:  uint32(len(testData)),
+					PieceStyle: base.PieceStyle_PLAIN,
+				},
+				log: logger.With("test", "test"),
+			})
+			if !tt.success {
+				assert.NotNil(err)
+				return
+			}
+			assert.Nil(err)
+			data, err := io.ReadAll(r)
+			assert.Nil(err)
+			c.Close()
+			assert.Equal(testData, data)
+		})
+	}
+}
+
+func TestPieceDownloader_WithScheme(t *testing.T) {
+	assert := testifyassert.New(t)
+	_, err := NewPieceDownloader(time.Second, WithScheme("ftp"))
+	assert.NotNil(err)
+}
+
+func TestBuildDownloadPieceHTTPRequest(t *testing.T) {
+	tests := []struct {
+		name     string
+		addr     string
+		expected string
+	}{
+		{
+			name:     "ipv4",
+			addr:     "127.0.0.1:65002",
+			expected: "127.0.0.1:65002",
+		},
+		{
+			name:     "hostname",
+			addr:     "localhost:65002",
+			expected: "localhost:65002",
+		},
+		{
+			name:     "ipv6 with brackets",
+			addr:     "[fe80::1]:65002",
+			expected: "[fe80::1]:65002",
+		},
+		{
+			name:     "ipv6 without brackets",
+			addr:     "fe80::1:65002",
+			expected: "[fe80::1]:65002",
+		},
+		{
+			name:     "ipv6 loopback without brackets",
+			addr:     "::1:65002",
+			expected: "[::1]:65002",
+		},
+		{
+			name:     "ipv6 without brackets and port looks like hex",
+			addr:     "fe80::1:6500",
+			expected: "[fe80::1]:6500",
+		},
+		{
+			name:     "ipv4-mapped ipv6 without brackets",
+			addr:     "::ffff:10.0.0.1:65002",
+			expected: "[::ffff:10.0.0.1]:65002",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := testifyassert.New(t)
+			req := buildDownloadPieceHTTPRequest(context.Background(), "http", tc.addr, &DownloadPieceRequest{
+				TaskID: "task-id",
+				DstPid: "peer-id",
+				piece:  &base.PieceInfo{RangeStart: 0, RangeSize: 10},
+			})
+			assert.NotNil(req)
+			assert.Equal(tc.expected, req.URL.Host)
+			assert.Equal(upload.PeerDownloadHTTPPathPrefix+"tas/task-id", req.URL.Path)
+			assert.Equal("peer-id", req.URL.Query().Get("peerId"))
+		})
+	}
+}
+
+func TestPieceDownloader_DownloadPieceWithIPv6(t *testing.T) {
+	assert := testifyassert.New(t)
+	listener, err := net.Listen("tcp", "[::1]:0")
+	if err != nil {
+		t.Skipf("ipv6 is not available: %v", err)
+	}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.Listener.Close()
+	server.Listener = listener
+	server.Start()
+	defer server.Close()
+
+	pd, err := NewPieceDownloader(time.Second)
+	assert.Nil(err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	// the address is built without brackets like fmt.Sprintf("%s:%d", ip, port)
+	ok, err := pd.HasPiece(context.Background(), &DownloadPieceRequest{
+		TaskID:  "task-id",
+		DstPid:  "peer-id",
+		DstAddr: fmt.Sprintf("::1:%d", port),
+		piece:   &base.PieceInfo{RangeStart: 0, RangeSize: 10},
+	})
+	assert.Nil(err)
+	assert.True(ok)
+}
+
+func TestPieceDownloader_InvalidRequest(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	addr, _ := url.Parse(server.URL)
+
+	tests := []struct {
+		name   string
+		req    *DownloadPieceRequest
+		expect string
+	}{
+		{
+			name: "empty DstAddr",
+			req: &DownloadPieceRequest{
+				TaskID: "task-id",
+				DstPid: "peer-id",
+				piece:  &base.PieceInfo{RangeSize: 10},
+			},
+			expect: "invalid download piece request, DstAddr is empty",
+		},
+		{
+			name: "blank DstAddr",
+			req: &DownloadPieceRequest{
+				TaskID:  "task-id",
+				DstPid:  "peer-id",
+				DstAddr: " ",
+				piece:   &base.PieceInfo{RangeSize: 10},
+			},
+			expect: "invalid download piece request, DstAddr is empty",
+		},
+		{
+			name: "short TaskID",
+			req: &DownloadPieceRequest{
+				TaskID:  "ta",
+				DstPid:  "peer-id",
+				DstAddr: addr.Host,
+				piece:   &base.PieceInfo{RangeSize: 10},
+			},
+			expect: `invalid download piece request, TaskID "ta" is shorter than 3`,
+		},
+		{
+			name: "empty DstPid",
+			req: &DownloadPieceRequest{
+				TaskID:  "task-id",
+				DstAddr: addr.Host,
+				piece:   &base.PieceInfo{RangeSize: 10},
+			},
+			expect: "invalid download piece request, DstPid is empty",
+		},
+		{
+			name: "nil piece",
+			req: &DownloadPieceRequest{
+				TaskID:  "task-id",
+				DstPid:  "peer-id",
+				DstAddr: addr.Host,
+			},
+			expect: "invalid download piece request, piece is nil",
+		},
+	}
+
+	pd, err := NewPieceDownloader(time.Second)
+	require.Nil(t, err)
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := testifyassert.New(t)
+			r, c, err := pd.DownloadPiece(context.Background(), tc.req)
+			assert.EqualError(err, tc.expect)
+			assert.True(isInvalidPieceRequest(err))
+			assert.False(isConnectionError(err))
+			assert.Nil(r)
+			assert.Nil(c)
+
+			ok, err := pd.HasPiece(context.Background(), tc.req)
+			assert.EqualError(err, tc.expect)
+			assert.False(ok)
+		})
+	}
+	// no request is sent for invalid requests
+	testifyassert.Equal(t, 0, calls)
+}
+
+func TestBuildDownloadPieceHTTPRequest_ShortTaskID(t *testing.T) {
+	assert := testifyassert.New(t)
+	req := buildDownloadPieceHTTPRequest(context.Background(), "http", "127.0.0.1:65002", &DownloadPieceRequest{
+		TaskID: "t",
+		DstPid: "peer-id",
+		piece:  &base.PieceInfo{RangeSize: 10},
+	})
+	assert.Equal(upload.PeerDownloadHTTPPathPrefix+"t/t", req.URL.Path)
+}
+
+type flakyRoundTripper struct {
+	failures int
+	calls    int
+}
+
+func (f *flakyRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	f.calls++
+	if f.calls <= f.failures {
+		return nil, errors.New("connection refused")
+	}
+	return defaultTransport.RoundTrip(req)
+}
+
+func TestPieceDownloader_DownloadPieceWithRetry(t *testing.T) {
+	assert := testifyassert.New(t)
+	testData := []byte("test retry piece data")
+	var statusCode int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if statusCode != 0 {
+			w.WriteHeader(statusCode)
+			return
+		}
+		w.Header().Set(headers.ContentLength, fmt.Sprintf("%d", len(testData)))
+		if _, err := w.Write(testData); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer server.Close()
+	addr, _ := url.Parse(server.URL)
+
+	tests := []struct {
+		name       string
+		failures   int
+		attempts   int
+		statusCode int
+		calls      int
+		success    bool
+		notFound   bool
+	}{
+		{
+			name:     "recover from connection errors",
+			failures: 2,
+			attempts: 3,
+			calls:    3,
+			success:  true,
+		},
+		{
+			name:     "exceed max attempts",
+			failures: 3,
+			attempts: 3,
+			calls:    3,
+			success:  false,
+		},
+		{
+			name:       "never retry status errors",
+			attempts:   3,
+			statusCode: http.StatusNotFound,
+			calls:      1,
+			success:    false,
+			notFound:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statusCode = tt.statusCode
+			rt := &flakyRoundTripper{failures: tt.failures}
+			pd, err := NewPieceDownloader(30*time.Second, WithTransport(rt), WithRetry(tt.attempts, time.Millisecond, 10*time.Millisecond))
+			assert.Nil(err)
+			r, c, err := pd.DownloadPiece(context.Background(), &DownloadPieceRequest{
+				TaskID:  "task-0",
+				DstPid:  "peer-0",
+				DstAddr: addr.Host,
+				piece: &base.PieceInfo{
+					RangeStart: 0,
+					RangeSize:  uint32(len(testData)),
+					PieceStyle: base.PieceStyle_PLAIN,
+				},
+				log: logger.With("test", "test"),
+			})
+			assert.Equal(tt.calls, rt.calls)
+			if !tt.success {
+				assert.NotNil(err)
+				assert.Equal(!tt.notFound, isConnectionError(err))
+				assert.Equal(tt.notFound, isPieceNotFound(err))
+				return
+			}
+			assert.Nil(err)
+			data, err := io.ReadAll(r)
+			assert.Nil(err)
+			c.Close()
+			assert.Equal(testData, data)
+		})
+	}
+}
+
+func TestPieceDownloader_DownloadPieceWithUnixSocket(t *testing.T) {
+	assert := testifyassert.New(t)
+	testData := []byte("test unix socket piece data")
+
+	dir, err := os.MkdirTemp("", "dragonfly-test-")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+
+	sock := path.Join(dir, "upload.sock")
+	ln, err := net.Listen("unix", sock)
+	assert.Nil(err)
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal("unix", r.Host)
+		assert.Equal(upload.PeerDownloadHTTPPathPrefix+"tas/"+"task-0", r.URL.Path)
+		w.Header().Set(headers.ContentLength, fmt.Sprintf("%d", len(testData)))
+		if _, err := w.Write(testData); err != nil {
+			t.Error(err)
+		}
+	})}
+	go server.Serve(ln)
+	defer server.Close()
+
+	request := &DownloadPieceRequest{
+		TaskID:  "task-0",
+		DstPid:  "peer-0",
+		DstAddr: sock,
+		piece: &base.PieceInfo{
+			RangeStart: 0,
+			RangeSize:  uint32(len(testData)),
+			PieceStyle: base.PieceStyle_PLAIN,
+		},
+		log: logger.With("test", "test"),
+	}
+
+	pd, err := NewPieceDownloader(30*time.Second, WithUnixSocket(true))
+	assert.Nil(err)
+	r, c, err := pd.DownloadPiece(context.Background(), request)
+	assert.Nil(err)
+	data, err := io.ReadAll(r)
+	assert.Nil(err)
+	c.Close()
+	assert.Equal(testData, data)
+
+	pd, err = NewPieceDownloader(30 * time.Second)
+	assert.Nil(err)
+	_, _, err = pd.DownloadPiece(context.Background(), request)
+	assert.True(isConnectionError(err))
+}
+
+func TestPieceDownloader_DownloadPieceWithRateLimiter(t *testing.T) {
+	assert := testifyassert.New(t)
+	testData := make([]byte, 4096)
+	for i := range testData {
+		testData[i] = byte(i)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(headers.ContentLength, fmt.Sprintf("%d", len(testData)))
+		if _, err := w.Write(testData); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer server.Close()
+	addr, _ := url.Parse(server.URL)
+
+	hash := md5.New()
+	hash.Write(testData)
+	request := &DownloadPieceRequest{
+		TaskID:     "task-0",
+		DstPid:     "peer-0",
+		DstAddr:    addr.Host,
+		CalcDigest: true,
+		piece: &base.PieceInfo{
+			RangeStart: 0,
+			RangeSize:  uint32(len(testData)),
+			PieceMd5:   hex.EncodeToString(hash.Sum(nil)),
+			PieceStyle: base.PieceStyle_PLAIN,
+		},
+		log: logger.With("test", "test"),
+	}
+
+	// the first 1024 bytes are allowed by burst, the rest takes about 300ms
+	limiter := rate.NewLimiter(rate.Limit(10240), 1024)
+	pd, err := NewPieceDownloader(30*time.Second, WithRateLimiter(limiter))
+	assert.Nil(err)
+
+	start := time.Now()
+	r, c, err := pd.DownloadPiece(context.Background(), request)
+	assert.Nil(err)
+	data, err := io.ReadAll(r)
+	assert.Nil(err, "digest should match")
+	c.Close()
+	assert.Equal(testData, data)
+	assert.GreaterOrEqual(time.Since(start), 250*time.Millisecond)
+
+	// the limiter must not block forever when context is done
+	pd, err = NewPieceDownloader(30*time.Second, WithRateLimiter(rate.NewLimiter(rate.Limit(1), 1024)))
+	assert.Nil(err)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	r, c, err = pd.DownloadPiece(ctx, request)
+	assert.Nil(err)
+	_, err = io.ReadAll(r)
+	assert.NotNil(err)
+	c.Close()
+}
+
+func TestPieceDownloader_HasPiece(t *testing.T) {
+	assert := testifyassert.New(t)
+	tests := []struct {
+		name       string
+		statusCode int
+		has        bool
+		success    bool
+	}{
+		{
+			name:       "piece exists",
+			statusCode: http.StatusOK,
+			has:        true,
+			success:    true,
+		},
+		{
+			name:       "piece not found",
+			statusCode: http.StatusNotFound,
+			has:        false,
+			success:    true,
+		},
+		{
+			name:       "server error",
+			statusCode: http.StatusInternalServerError,
+			has:        false,
+			success:    false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(http.MethodHead, r.Method)
+				assert.Equal(upload.PeerDownloadHTTPPathPrefix+"tas/"+"task-0", r.URL.Path)
+				assert.Equal("bytes=0-9", r.Header.Get("Range"))
+				w.WriteHeader(tt.statusCode)
+			}))
+			defer server.Close()
+			addr, _ := url.Parse(server.URL)
+
+			pd, err := NewPieceDownloader(30 * time.Second)
+			assert.Nil(err)
+			has, err := pd.HasPiece(context.Background(), &DownloadPieceRequest{
+				TaskID:  "task-0",
+				DstPid:  "peer-0",
+				DstAddr: addr.Host,
+				piece: &base.PieceInfo{
+					RangeStart: 0,
+					RangeSize:  10,
+				},
+				log: logger.With("test", "test"),
+			})
+			assert.Equal(tt.has, has)
+			assert.Equal(tt.success, err == nil)
+		})
+	}
+}
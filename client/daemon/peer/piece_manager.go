@@ -21,14 +21,17 @@ import (
 	"fmt"
 	"io"
 	"math"
-	"net"
 	"net/http"
 	"time"
 
+	"go.uber.org/atomic"
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/semaphore"
 	"golang.org/x/time/rate"
 
 	"d7y.io/dragonfly/v2/client/clientutil"
 	"d7y.io/dragonfly/v2/client/config"
+	"d7y.io/dragonfly/v2/client/daemon/metrics"
 	"d7y.io/dragonfly/v2/client/daemon/storage"
 	logger "d7y.io/dragonfly/v2/internal/dflog"
 	"d7y.io/dragonfly/v2/internal/util"
@@ -38,6 +41,9 @@ import (
 	"d7y.io/dragonfly/v2/pkg/util/digestutils"
 )
 
+// maxRetryAfterAttempts is the max attempts to download from source when source responds with Retry-After
+const maxRetryAfterAttempts = 3
+
 type PieceManager interface {
 	DownloadSource(ctx context.Context, pt Task, request *scheduler.PeerTaskRequest) error
 	DownloadPiece(ctx context.Context, request *DownloadPieceRequest) (*DownloadPieceResult, error)
@@ -49,6 +55,12 @@ type pieceManager struct {
 	computePieceSize func(contentLength int64) uint32
 
 	calculateDigest bool
+	// backSourceConcurrency is the count of concurrent ranged downloads when back source
+	backSourceConcurrency int
+	// backSourceLimiter limits the count of simultaneous back source downloads in daemon, nil means unlimited
+	backSourceLimiter *semaphore.Weighted
+	// pieceDownloaderOptions are applied when the default piece downloader is created
+	pieceDownloaderOptions []func(*pieceDownloader) error
 }
 
 var _ PieceManager = (*pieceManager)(nil)
@@ -64,7 +76,11 @@ func NewPieceManager(s storage.TaskStorageDriver, pieceDownloadTimeout time.Dura
 
 	// set default value
 	if pm.pieceDownloader == nil {
-		pm.pieceDownloader, _ = NewPieceDownloader(pieceDownloadTimeout)
+		pieceDownloader, err := NewPieceDownloader(pieceDownloadTimeout, pm.pieceDownloaderOptions...)
+		if err != nil {
+			return nil, err
+		}
+		pm.pieceDownloader = pieceDownloader
 	}
 	return pm, nil
 }
@@ -76,6 +92,34 @@ func WithCalculateDigest(enable bool) func(*pieceManager) {
 	}
 }
 
+// WithBackSourceConcurrency sets the count of concurrent ranged downloads when back source,
+// the source is downloaded serially when it does not support range
+func WithBackSourceConcurrency(concurrency int) func(*pieceManager) {
+	return func(pm *pieceManager) {
+		pm.backSourceConcurrency = concurrency
+	}
+}
+
+// WithBackSourceLimit sets the max count of simultaneous back source downloads in daemon,
+// it is unlimited when limit is not positive
+func WithBackSourceLimit(limit int) func(*pieceManager) {
+	return func(pm *pieceManager) {
+		if limit <= 0 {
+			pm.backSourceLimiter = nil
+			return
+		}
+		logger.Infof("set backSourceLimit to %d for piece manager", limit)
+		pm.backSourceLimiter = semaphore.NewWeighted(int64(limit))
+	}
+}
+
+// WithPieceDownloaderOptions sets the options of the default piece downloader
+func WithPieceDownloaderOptions(opts ...func(*pieceDownloader) error) func(*pieceManager) {
+	return func(pm *pieceManager) {
+		pm.pieceDownloaderOptions = append(pm.pieceDownloaderOptions, opts...)
+	}
+}
+
 // WithLimiter sets upload rate limiter, the burst size must be bigger than piece size
 func WithLimiter(limiter *rate.Limiter) func(*pieceManager) {
 	return func(manager *pieceManager) {
@@ -92,11 +136,8 @@ func WithTransportOption(opt *config.TransportOption) func(*pieceManager) {
 			defaultTransport.(*http.Transport).IdleConnTimeout = opt.IdleConnTimeout
 		}
 		if opt.DialTimeout > 0 && opt.KeepAlive > 0 {
-			defaultTransport.(*http.Transport).DialContext = (&net.Dialer{
-				Timeout:   opt.DialTimeout,
-				KeepAlive: opt.KeepAlive,
-				DualStack: true,
-			}).DialContext
+			defaultDialer.Timeout = opt.DialTimeout
+			defaultDialer.KeepAlive = opt.KeepAlive
 		}
 		if opt.MaxIdleConns > 0 {
 			defaultTransport.(*http.Transport).MaxIdleConns = opt.MaxIdleConns
@@ -170,11 +211,23 @@ func (pm *pieceManager) DownloadPiece(ctx context.Context, request *DownloadPiec
 	result.FinishTime = time.Now().UnixNano()
 
 	span.RecordError(err)
+	if isDigestMismatch(err) {
+		// the piece is not recorded in storage when the digest does not match, the corrupt data is discarded
+		// and overwritten by the next download, report the error to choose another parent
+		request.log.Warnf("discard corrupt piece %d from peer %s: %s", request.piece.PieceNum, request.DstPid, err)
+		result.Size = 0
+		return result, err
+	}
 	if err != nil {
 		request.log.Errorf("put piece to storage failed, piece num: %d, wrote: %d, error: %s",
 			request.piece.PieceNum, result.Size, err)
 		return result, err
 	}
+	if request.ComputeDigest {
+		if dr, ok := r.(digestutils.DigestReader); ok {
+			result.PieceMd5 = dr.Digest()
+		}
+	}
 	return result, nil
 }
 
@@ -239,6 +292,27 @@ func (pm *pieceManager) processPieceFromSource(pt Task,
 	return
 }
 
+// acquireBackSource waits for a back source slot until ctx is done,
+// the returned release func must be called after the source is downloaded
+func (pm *pieceManager) acquireBackSource(ctx context.Context) (func(), error) {
+	if pm.backSourceLimiter == nil {
+		return func() {}, nil
+	}
+
+	metrics.BackSourceWaitingCount.Add(1)
+	err := pm.backSourceLimiter.Acquire(ctx, 1)
+	metrics.BackSourceWaitingCount.Add(-1)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics.BackSourceRunningCount.Add(1)
+	return func() {
+		metrics.BackSourceRunningCount.Add(-1)
+		pm.backSourceLimiter.Release(1)
+	}, nil
+}
+
 func (pm *pieceManager) DownloadSource(ctx context.Context, pt Task, request *scheduler.PeerTaskRequest) error {
 	if request.UrlMeta == nil {
 		request.UrlMeta = &base.UrlMeta{
@@ -252,6 +326,15 @@ func (pm *pieceManager) DownloadSource(ctx context.Context, pt Task, request *sc
 		request.UrlMeta.Header[source.Range] = request.UrlMeta.Range
 	}
 	log := pt.Log()
+	// the source clients log with the task id and peer id in the context
+	ctx = source.WithTaskContext(ctx, pt.GetTaskID(), pt.GetPeerID())
+	release, err := pm.acquireBackSource(ctx)
+	if err != nil {
+		log.Errorf("wait for back source slot error: %s", err)
+		return err
+	}
+	defer release()
+
 	log.Infof("start to download from source")
 	contentLengthRequest, err := source.NewRequestWithContext(ctx, request.Url, request.UrlMeta.Header)
 	if err != nil {
@@ -277,12 +360,28 @@ func (pm *pieceManager) DownloadSource(ctx context.Context, pt Task, request *sc
 		}
 	}
 	log.Debugf("get content length: %d", contentLength)
+	// empty source file has no pieces, the task is completed without downloading
+	if err == nil && contentLength == 0 {
+		return pm.downloadEmptySource(ctx, pt)
+	}
+	// the digest of whole content can not be calculated when downloading ranges concurrently
+	if contentLength > 0 && pm.backSourceConcurrency > 1 && request.UrlMeta.Range == "" && request.UrlMeta.Digest == "" {
+		supportRangeRequest, err := source.NewRequestWithContext(ctx, request.Url, request.UrlMeta.Header)
+		if err != nil {
+			return err
+		}
+		if supportRange, err := source.IsSupportRange(supportRangeRequest); err == nil && supportRange {
+			return pm.downloadConcurrentSource(ctx, pt, request, contentLength, pm.computePieceSize(contentLength))
+		}
+		log.Warnf("source does not support range, download serially")
+	}
+
 	// 1. download piece from source
 	downloadRequest, err := source.NewRequestWithContext(ctx, request.Url, request.UrlMeta.Header)
 	if err != nil {
 		return err
 	}
-	response, err := source.Download(downloadRequest)
+	response, err := downloadSourceWithRetryAfter(ctx, downloadRequest)
 	// TODO update expire info
 	if err != nil {
 		return err
@@ -306,6 +405,55 @@ func (pm *pieceManager) DownloadSource(ctx context.Context, pt Task, request *sc
 	return pm.downloadKnownLengthSource(ctx, pt, contentLength, pieceSize, reader)
 }
 
+// downloadSourceWithRetryAfter downloads from source, and waits the suggested time to retry when source is busy
+// and responds with Retry-After, the wait is bounded by the deadline of ctx
+func downloadSourceWithRetryAfter(ctx context.Context, request *source.Request) (*source.Response, error) {
+	for i := 1; ; i++ {
+		response, err := source.Download(request.Clone(ctx))
+		if err == nil {
+			return response, nil
+		}
+
+		retryAfter, ok := source.RetryAfterOf(err)
+		if !ok || i >= maxRetryAfterAttempts {
+			return nil, err
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < retryAfter {
+			return nil, err
+		}
+
+		logger.Warnf("download %s error: %s, retry after %s", request.URL, err, retryAfter)
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-time.After(retryAfter):
+		}
+	}
+}
+
+// downloadEmptySource completes the task of empty source file with zero pieces
+func (pm *pieceManager) downloadEmptySource(ctx context.Context, pt Task) error {
+	log := pt.Log()
+	pt.SetContentLength(0)
+	pt.SetTotalPieces(0)
+	err := pt.GetStorage().UpdateTask(ctx,
+		&storage.UpdateTaskRequest{
+			PeerTaskMetadata: storage.PeerTaskMetadata{
+				PeerID: pt.GetPeerID(),
+				TaskID: pt.GetTaskID(),
+			},
+			ContentLength: 0,
+			TotalPieces:   0,
+		})
+	if err != nil {
+		log.Errorf("update task failed %s", err)
+		return err
+	}
+
+	log.Infof("source file is empty, download from source ok")
+	return nil
+}
+
 func (pm *pieceManager) downloadKnownLengthSource(ctx context.Context, pt Task, contentLength int64, pieceSize uint32, reader io.Reader) error {
 	log := pt.Log()
 	pt.SetContentLength(contentLength)
@@ -374,6 +522,133 @@ func (pm *pieceManager) downloadKnownLengthSource(ctx context.Context, pt Task,
 	return nil
 }
 
+// downloadConcurrentSource splits the pieces into backSourceConcurrency ranges and downloads them concurrently,
+// the piece md5 sign is generated by the piece digests in order after all pieces are written
+func (pm *pieceManager) downloadConcurrentSource(ctx context.Context, pt Task, request *scheduler.PeerTaskRequest, contentLength int64, pieceSize uint32) error {
+	pt.SetContentLength(contentLength)
+	maxPieceNum := int32(math.Ceil(float64(contentLength) / float64(pieceSize)))
+	pt.SetTotalPieces(maxPieceNum)
+
+	concurrency := int32(pm.backSourceConcurrency)
+	if concurrency > maxPieceNum {
+		concurrency = maxPieceNum
+	}
+	piecesPerRange := (maxPieceNum + concurrency - 1) / concurrency
+	pt.Log().Infof("download %d pieces from source with %d ranges concurrently", maxPieceNum, concurrency)
+
+	var (
+		md5s      = make([]string, maxPieceNum)
+		remaining = atomic.NewInt32(maxPieceNum)
+	)
+	eg, egCtx := errgroup.WithContext(ctx)
+	for startNum := int32(0); startNum < maxPieceNum; startNum += piecesPerRange {
+		startNum, endNum := startNum, startNum+piecesPerRange
+		if endNum > maxPieceNum {
+			endNum = maxPieceNum
+		}
+		eg.Go(func() error {
+			return pm.downloadSourceRange(egCtx, pt, request, contentLength, pieceSize, startNum, endNum,
+				func(pieceNum int32, md5 string) error {
+					md5s[pieceNum] = md5
+					if remaining.Dec() > 0 {
+						return nil
+					}
+					// all pieces are written, update the task before publishing the last piece
+					updateRequest := &storage.UpdateTaskRequest{
+						PeerTaskMetadata: storage.PeerTaskMetadata{
+							PeerID: pt.GetPeerID(),
+							TaskID: pt.GetTaskID(),
+						},
+						ContentLength: contentLength,
+						TotalPieces:   maxPieceNum,
+					}
+					if pm.calculateDigest {
+						updateRequest.PieceMd5Sign = digestutils.Sha256(md5s...)
+						pt.SetPieceMd5Sign(updateRequest.PieceMd5Sign)
+					}
+					return pt.GetStorage().UpdateTask(ctx, updateRequest)
+				})
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return err
+	}
+
+	pt.Log().Infof("download from source ok")
+	return nil
+}
+
+// downloadSourceRange downloads the pieces in [startNum, endNum) with a ranged request,
+// pieceDone is called after every piece is written and before it is published
+func (pm *pieceManager) downloadSourceRange(ctx context.Context, pt Task, request *scheduler.PeerTaskRequest,
+	contentLength int64, pieceSize uint32, startNum, endNum int32, pieceDone func(pieceNum int32, md5 string) error) error {
+	log := pt.Log()
+	start := int64(startNum) * int64(pieceSize)
+	end := int64(endNum) * int64(pieceSize)
+	if end > contentLength {
+		end = contentLength
+	}
+
+	header := map[string]string{}
+	for k, v := range request.UrlMeta.Header {
+		header[k] = v
+	}
+	header[source.Range] = fmt.Sprintf("%d-%d", start, end-1)
+	downloadRequest, err := source.NewRequestWithContext(ctx, request.Url, header)
+	if err != nil {
+		return err
+	}
+	response, err := downloadSourceWithRetryAfter(ctx, downloadRequest)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	for pieceNum := startNum; pieceNum < endNum; pieceNum++ {
+		size := pieceSize
+		offset := uint64(pieceNum) * uint64(pieceSize)
+		// calculate piece size for last piece
+		if int64(offset)+int64(size) > contentLength {
+			size = uint32(contentLength - int64(offset))
+		}
+
+		log.Debugf("download piece %d", pieceNum)
+		result, md5, err := pm.processPieceFromSource(pt, response.Body, contentLength, pieceNum, offset, size, nil)
+		downloadPieceRequest := &DownloadPieceRequest{
+			TaskID: pt.GetTaskID(),
+			PeerID: pt.GetPeerID(),
+			piece: &base.PieceInfo{
+				PieceNum:    pieceNum,
+				RangeStart:  offset,
+				RangeSize:   uint32(result.Size),
+				PieceMd5:    md5,
+				PieceOffset: offset,
+				PieceStyle:  0,
+			},
+		}
+		if err != nil {
+			log.Errorf("download piece %d error: %s", pieceNum, err)
+			pt.ReportPieceResult(downloadPieceRequest, result, err)
+			return err
+		}
+
+		if result.Size != int64(size) {
+			log.Errorf("download piece %d size not match, desired: %d, actual: %d", pieceNum, size, result.Size)
+			pt.ReportPieceResult(downloadPieceRequest, result, storage.ErrShortRead)
+			return storage.ErrShortRead
+		}
+
+		if err := pieceDone(pieceNum, md5); err != nil {
+			log.Errorf("update task failed %s", err)
+			pt.ReportPieceResult(downloadPieceRequest, result, err)
+			return err
+		}
+		pt.ReportPieceResult(downloadPieceRequest, result, nil)
+		pt.PublishPieceInfo(pieceNum, uint32(result.Size))
+	}
+	return nil
+}
+
 func (pm *pieceManager) downloadUnknownLengthSource(ctx context.Context, pt Task, pieceSize uint32, reader io.Reader) error {
 	var contentLength int64 = -1
 	log := pt.Log()
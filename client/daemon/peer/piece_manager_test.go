@@ -21,11 +21,13 @@ import (
 	"context"
 	"crypto/md5"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -44,6 +46,8 @@ import (
 	"d7y.io/dragonfly/v2/pkg/rpc/scheduler"
 	"d7y.io/dragonfly/v2/pkg/source"
 	"d7y.io/dragonfly/v2/pkg/source/httpprotocol"
+	sourceMock "d7y.io/dragonfly/v2/pkg/source/mock"
+	"d7y.io/dragonfly/v2/pkg/util/digestutils"
 )
 
 func TestPieceManager_DownloadSource(t *testing.T) {
@@ -76,10 +80,11 @@ func TestPieceManager_DownloadSource(t *testing.T) {
 	digest := hex.EncodeToString(hash.Sum(nil)[:16])
 
 	testCases := []struct {
-		name              string
-		pieceSize         uint32
-		withContentLength bool
-		checkDigest       bool
+		name                  string
+		pieceSize             uint32
+		withContentLength     bool
+		checkDigest           bool
+		backSourceConcurrency int
 	}{
 		{
 			name:              "multiple pieces with content length, check digest",
@@ -125,14 +130,27 @@ func TestPieceManager_DownloadSource(t *testing.T) {
 			pieceSize:         uint32(len(testBytes)) + 1,
 			withContentLength: false,
 		},
+		{
+			name:                  "multiple pieces with concurrent ranges",
+			pieceSize:             1024,
+			withContentLength:     true,
+			backSourceConcurrency: 3,
+		},
+		{
+			name:                  "one piece with concurrent ranges",
+			pieceSize:             uint32(len(testBytes)),
+			withContentLength:     true,
+			backSourceConcurrency: 3,
+		},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			/********** prepare test start **********/
 			mockPeerTask := NewMockTask(ctrl)
 			var (
-				totalPieces = &atomic.Int32{}
-				taskStorage storage.TaskStorageDriver
+				totalPieces  = &atomic.Int32{}
+				pieceMd5Sign = &atomic.String{}
+				taskStorage  storage.TaskStorageDriver
 			)
 			mockPeerTask.EXPECT().SetContentLength(gomock.Any()).AnyTimes().DoAndReturn(
 				func(arg0 int64) error {
@@ -146,6 +164,10 @@ func TestPieceManager_DownloadSource(t *testing.T) {
 				func() int32 {
 					return totalPieces.Load()
 				})
+			mockPeerTask.EXPECT().SetPieceMd5Sign(gomock.Any()).AnyTimes().DoAndReturn(
+				func(arg0 string) {
+					pieceMd5Sign.Store(arg0)
+				})
 			mockPeerTask.EXPECT().GetPeerID().AnyTimes().DoAndReturn(
 				func() string {
 					return peerID
@@ -186,7 +208,15 @@ func TestPieceManager_DownloadSource(t *testing.T) {
 			defer storageManager.CleanUp()
 			defer os.Remove(output)
 			/********** prepare test end **********/
+			rangeRequests := atomic.NewInt32(0)
 			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tc.backSourceConcurrency > 0 {
+					if r.Header.Get("Range") != "" {
+						rangeRequests.Inc()
+					}
+					http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(testBytes))
+					return
+				}
 				if tc.withContentLength {
 					w.Header().Set("Content-Length",
 						fmt.Sprintf("%d", len(testBytes)))
@@ -197,7 +227,7 @@ func TestPieceManager_DownloadSource(t *testing.T) {
 			}))
 			defer ts.Close()
 
-			pm, err := NewPieceManager(storageManager, pieceDownloadTimeout)
+			pm, err := NewPieceManager(storageManager, pieceDownloadTimeout, WithBackSourceConcurrency(tc.backSourceConcurrency))
 			assert.Nil(err)
 			pm.(*pieceManager).computePieceSize = func(length int64) uint32 {
 				return tc.pieceSize
@@ -218,6 +248,29 @@ func TestPieceManager_DownloadSource(t *testing.T) {
 			err = pm.DownloadSource(context.Background(), mockPeerTask, request)
 			assert.Nil(err)
 
+			if tc.backSourceConcurrency > 0 {
+				// the piece md5 sign is generated by the piece digests in order
+				var pieceDigests []string
+				for start := 0; start < len(testBytes); start += int(tc.pieceSize) {
+					end := start + int(tc.pieceSize)
+					if end > len(testBytes) {
+						end = len(testBytes)
+					}
+					pieceDigests = append(pieceDigests, digestutils.Md5Bytes(testBytes[start:end]))
+				}
+				assert.Equal(digestutils.Sha256(pieceDigests...), pieceMd5Sign.Load())
+				assert.Equal(int32(len(pieceDigests)), totalPieces.Load())
+				assert.Nil(taskStorage.ValidateDigest(&storage.PeerTaskMetadata{PeerID: peerID, TaskID: taskID}))
+				// one request for checking range support and one request for every range
+				concurrency := tc.backSourceConcurrency
+				if concurrency > len(pieceDigests) {
+					concurrency = len(pieceDigests)
+				}
+				piecesPerRange := (len(pieceDigests) + concurrency - 1) / concurrency
+				ranges := (len(pieceDigests) + piecesPerRange - 1) / piecesPerRange
+				assert.Equal(int32(ranges+1), rangeRequests.Load())
+			}
+
 			err = storageManager.Store(context.Background(),
 				&storage.StoreRequest{
 					CommonTaskRequest: storage.CommonTaskRequest{
@@ -234,3 +287,438 @@ func TestPieceManager_DownloadSource(t *testing.T) {
 		})
 	}
 }
+
+func TestPieceManager_DownloadUnknownLengthSource(t *testing.T) {
+	testBytes, err := os.ReadFile(test.File)
+	require.Nil(t, err, "load test file")
+
+	var (
+		peerID    = "peer-unknown-length"
+		taskID    = "task-unknown-length"
+		url       = "http://localhost/test/unknown-length"
+		pieceSize = 1024
+	)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{
+			name: "multiple pieces",
+			data: testBytes[:4000],
+		},
+		{
+			name: "content length is aligned at piece size",
+			data: testBytes[:4096],
+		},
+		{
+			name: "one piece",
+			data: testBytes[:100],
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := testifyassert.New(t)
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			// the source does not report content length
+			sourceClient := sourceMock.NewMockResourceClient(ctrl)
+			sourceClient.EXPECT().GetContentLength(source.RequestEq(url)).AnyTimes().Return(source.UnknownSourceFileLen, nil)
+			sourceClient.EXPECT().Download(source.RequestEq(url)).Times(1).DoAndReturn(
+				func(request *source.Request) (*source.Response, error) {
+					return source.NewResponse(io.NopCloser(bytes.NewReader(tc.data))), nil
+				})
+			source.UnRegister("http")
+			require.Nil(t, source.Register("http", sourceClient, httpprotocol.Adapter))
+			defer source.UnRegister("http")
+
+			storageManager, _ := storage.NewStorageManager(
+				config.SimpleLocalTaskStoreStrategy,
+				&config.StorageOption{
+					DataPath: t.TempDir(),
+					TaskExpireTime: clientutil.Duration{
+						Duration: -1 * time.Second,
+					},
+				}, func(request storage.CommonTaskRequest) {})
+			defer storageManager.CleanUp()
+			taskStorage, err := storageManager.RegisterTask(context.Background(),
+				storage.RegisterTaskRequest{
+					CommonTaskRequest: storage.CommonTaskRequest{
+						PeerID: peerID,
+						TaskID: taskID,
+					},
+					ContentLength: -1,
+					TotalPieces:   -1,
+				})
+			require.Nil(t, err)
+
+			var (
+				contentLength = atomic.NewInt64(-1)
+				totalPieces   = atomic.NewInt32(-1)
+				published     = atomic.NewInt32(0)
+			)
+			mockPeerTask := NewMockTask(ctrl)
+			mockPeerTask.EXPECT().SetContentLength(gomock.Any()).AnyTimes().DoAndReturn(
+				func(arg0 int64) {
+					contentLength.Store(arg0)
+				})
+			mockPeerTask.EXPECT().SetTotalPieces(gomock.Any()).AnyTimes().DoAndReturn(
+				func(arg0 int32) {
+					totalPieces.Store(arg0)
+				})
+			mockPeerTask.EXPECT().GetTotalPieces().AnyTimes().DoAndReturn(
+				func() int32 {
+					return totalPieces.Load()
+				})
+			mockPeerTask.EXPECT().GetPeerID().AnyTimes().Return(peerID)
+			mockPeerTask.EXPECT().GetTaskID().AnyTimes().Return(taskID)
+			mockPeerTask.EXPECT().GetStorage().AnyTimes().Return(taskStorage)
+			mockPeerTask.EXPECT().AddTraffic(gomock.Any()).AnyTimes()
+			mockPeerTask.EXPECT().ReportPieceResult(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+			mockPeerTask.EXPECT().PublishPieceInfo(gomock.Any(), gomock.Any()).AnyTimes().DoAndReturn(
+				func(pieceNum int32, size uint32) {
+					published.Inc()
+				})
+			mockPeerTask.EXPECT().Context().AnyTimes().Return(context.Background())
+			mockPeerTask.EXPECT().Log().AnyTimes().Return(logger.With("test case", tc.name))
+
+			pm, err := NewPieceManager(storageManager, 30*time.Second)
+			require.Nil(t, err)
+			pm.(*pieceManager).computePieceSize = func(length int64) uint32 {
+				return uint32(pieceSize)
+			}
+
+			err = pm.DownloadSource(context.Background(), mockPeerTask, &scheduler.PeerTaskRequest{
+				Url:     url,
+				UrlMeta: &base.UrlMeta{},
+			})
+			assert.Nil(err)
+
+			// the discovered content length and piece count are recorded at EOF
+			var pieceDigests []string
+			for start := 0; start < len(tc.data); start += pieceSize {
+				end := start + pieceSize
+				if end > len(tc.data) {
+					end = len(tc.data)
+				}
+				pieceDigests = append(pieceDigests, digestutils.Md5Bytes(tc.data[start:end]))
+			}
+			assert.Equal(int64(len(tc.data)), contentLength.Load())
+			assert.Equal(int32(len(pieceDigests)), totalPieces.Load())
+			assert.Equal(int32(len(pieceDigests)), published.Load())
+
+			packet, err := taskStorage.GetPieces(context.Background(), &base.PieceTaskRequest{
+				TaskId: taskID,
+				DstPid: peerID,
+				Limit:  uint32(len(pieceDigests) + 1),
+			})
+			assert.Nil(err)
+			assert.Equal(int64(len(tc.data)), packet.ContentLength)
+			assert.Equal(int32(len(pieceDigests)), packet.TotalPiece)
+			assert.Equal(digestutils.Sha256(pieceDigests...), packet.PieceMd5Sign)
+			assert.Len(packet.PieceInfos, len(pieceDigests))
+			assert.Nil(taskStorage.ValidateDigest(&storage.PeerTaskMetadata{PeerID: peerID, TaskID: taskID}))
+		})
+	}
+}
+
+func TestDownloadSourceWithRetryAfter(t *testing.T) {
+	source.UnRegister("http")
+	require.Nil(t, source.Register("http", httpprotocol.NewHTTPSourceClient(), httpprotocol.Adapter))
+	defer source.UnRegister("http")
+
+	tests := []struct {
+		name      string
+		busyTimes int32
+		timeout   time.Duration
+		expect    func(t *testing.T, response *source.Response, err error, requests int32)
+	}{
+		{
+			name:      "retry after source is not busy",
+			busyTimes: 1,
+			expect: func(t *testing.T, response *source.Response, err error, requests int32) {
+				assert := testifyassert.New(t)
+				assert.Nil(err)
+				data, err := io.ReadAll(response.Body)
+				assert.Nil(err)
+				response.Body.Close()
+				assert.Equal("ok", string(data))
+				assert.Equal(int32(2), requests)
+			},
+		},
+		{
+			name:      "stop retrying when source is always busy",
+			busyTimes: maxRetryAfterAttempts,
+			expect: func(t *testing.T, response *source.Response, err error, requests int32) {
+				assert := testifyassert.New(t)
+				assert.True(source.IsTooManyRequests(err))
+				assert.Equal(int32(maxRetryAfterAttempts), requests)
+			},
+		},
+		{
+			name:      "do not wait beyond context deadline",
+			busyTimes: 1,
+			timeout:   500 * time.Millisecond,
+			expect: func(t *testing.T, response *source.Response, err error, requests int32) {
+				assert := testifyassert.New(t)
+				_, ok := source.RetryAfterOf(err)
+				assert.True(ok)
+				assert.Equal(int32(1), requests)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			requests := atomic.NewInt32(0)
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if requests.Inc() <= tc.busyTimes {
+					if tc.timeout > 0 {
+						w.Header().Set("Retry-After", "1")
+					} else {
+						w.Header().Set("Retry-After", "0")
+					}
+					w.WriteHeader(http.StatusTooManyRequests)
+					return
+				}
+				_, _ = w.Write([]byte("ok"))
+			}))
+			defer ts.Close()
+
+			ctx := context.Background()
+			if tc.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tc.timeout)
+				defer cancel()
+			}
+			request, err := source.NewRequestWithContext(ctx, ts.URL, nil)
+			require.Nil(t, err)
+			response, err := downloadSourceWithRetryAfter(ctx, request)
+			tc.expect(t, response, err, requests.Load())
+		})
+	}
+}
+
+func TestPieceManager_AcquireBackSource(t *testing.T) {
+	assert := testifyassert.New(t)
+
+	pm, err := NewPieceManager(nil, time.Second, WithBackSourceLimit(1))
+	require.Nil(t, err)
+	manager := pm.(*pieceManager)
+
+	release, err := manager.acquireBackSource(context.Background())
+	assert.Nil(err)
+
+	// wait until ctx is done when no slot is free
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = manager.acquireBackSource(ctx)
+	assert.ErrorIs(err, context.DeadlineExceeded)
+
+	release()
+	release, err = manager.acquireBackSource(context.Background())
+	assert.Nil(err)
+	release()
+
+	// unlimited
+	pm, err = NewPieceManager(nil, time.Second, WithBackSourceLimit(0))
+	require.Nil(t, err)
+	manager = pm.(*pieceManager)
+	for i := 0; i < 10; i++ {
+		_, err = manager.acquireBackSource(context.Background())
+		assert.Nil(err)
+	}
+}
+
+func TestPieceManager_DownloadPieceDigestMismatch(t *testing.T) {
+	assert := testifyassert.New(t)
+	var (
+		peerID = "peer-digest-mismatch"
+		taskID = "task-digest-mismatch"
+		data   = []byte("hello dragonfly")
+		// the parent serves the wrong bytes first
+		corrupt = atomic.NewBool(true)
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content := data
+		if corrupt.Load() {
+			content = []byte("hello DRAGONFLY")
+		}
+		if _, err := w.Write(content); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer server.Close()
+
+	storageManager, _ := storage.NewStorageManager(
+		config.SimpleLocalTaskStoreStrategy,
+		&config.StorageOption{
+			DataPath: t.TempDir(),
+			TaskExpireTime: clientutil.Duration{
+				Duration: -1 * time.Second,
+			},
+		}, func(request storage.CommonTaskRequest) {})
+	defer storageManager.CleanUp()
+	taskStorage, err := storageManager.RegisterTask(context.Background(),
+		storage.RegisterTaskRequest{
+			CommonTaskRequest: storage.CommonTaskRequest{
+				PeerID: peerID,
+				TaskID: taskID,
+			},
+			ContentLength: int64(len(data)),
+			TotalPieces:   1,
+		})
+	require.Nil(t, err)
+
+	pm, err := NewPieceManager(storageManager, 30*time.Second)
+	require.Nil(t, err)
+	newRequest := func() *DownloadPieceRequest {
+		return &DownloadPieceRequest{
+			TaskID:  taskID,
+			PeerID:  peerID,
+			DstPid:  "peer-parent",
+			DstAddr: strings.TrimPrefix(server.URL, "http://"),
+			storage: taskStorage,
+			piece: &base.PieceInfo{
+				PieceNum:   0,
+				RangeStart: 0,
+				RangeSize:  uint32(len(data)),
+				PieceMd5:   digestutils.Md5Bytes(data),
+				PieceStyle: base.PieceStyle_PLAIN,
+			},
+			log: logger.With("test", "digest mismatch"),
+		}
+	}
+	getPieces := func() []*base.PieceInfo {
+		packet, err := taskStorage.GetPieces(context.Background(), &base.PieceTaskRequest{
+			TaskId: taskID,
+			DstPid: peerID,
+			Limit:  1,
+		})
+		assert.Nil(err)
+		return packet.PieceInfos
+	}
+
+	_, err = pm.DownloadPiece(context.Background(), newRequest())
+	var mismatch *digestutils.DigestMismatchError
+	assert.True(errors.As(err, &mismatch))
+	assert.ErrorIs(err, digestutils.ErrDigestNotMatch)
+	assert.Equal(int32(0), mismatch.PieceNum)
+	assert.Equal(digestutils.Md5Bytes(data), mismatch.Expected)
+	assert.Equal(digestutils.Md5Bytes([]byte("hello DRAGONFLY")), mismatch.Got)
+	// the corrupt piece is not recorded
+	assert.Len(getPieces(), 0)
+
+	// the piece is downloaded again from a good parent
+	corrupt.Store(false)
+	result, err := pm.DownloadPiece(context.Background(), newRequest())
+	assert.Nil(err)
+	assert.Equal(int64(len(data)), result.Size)
+	pieces := getPieces()
+	assert.Len(pieces, 1)
+	assert.Equal(digestutils.Md5Bytes(data), pieces[0].PieceMd5)
+}
+
+func TestPieceManager_DownloadPieceComputeDigest(t *testing.T) {
+	data := []byte("hello dragonfly")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := w.Write(data); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name      string
+		pieceMd5  string
+		algorithm string
+		expect    func(t *testing.T, result *DownloadPieceResult, err error)
+	}{
+		{
+			name: "digest is not known",
+			expect: func(t *testing.T, result *DownloadPieceResult, err error) {
+				assert := testifyassert.New(t)
+				assert.Nil(err)
+				assert.Equal("f8cc21a652679f815f54a77f51bbc55a", result.PieceMd5)
+			},
+		},
+		{
+			name:      "digest is not known with sha256",
+			algorithm: "sha256",
+			expect: func(t *testing.T, result *DownloadPieceResult, err error) {
+				assert := testifyassert.New(t)
+				assert.Nil(err)
+				assert.Equal("63c16e8873b9cfbc564909e5255fe0c09d7e3f534eb3123d6a914ee8fbd3453b", result.PieceMd5)
+			},
+		},
+		{
+			name:     "digest is known",
+			pieceMd5: "f8cc21a652679f815f54a77f51bbc55a",
+			expect: func(t *testing.T, result *DownloadPieceResult, err error) {
+				assert := testifyassert.New(t)
+				assert.Nil(err)
+				assert.Equal("f8cc21a652679f815f54a77f51bbc55a", result.PieceMd5)
+			},
+		},
+		{
+			name:     "known digest is still verified",
+			pieceMd5: digestutils.Md5Bytes([]byte("hello world")),
+			expect: func(t *testing.T, result *DownloadPieceResult, err error) {
+				assert := testifyassert.New(t)
+				assert.ErrorIs(err, digestutils.ErrDigestNotMatch)
+				assert.Equal("", result.PieceMd5)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				peerID = "peer-compute-digest"
+				taskID = "task-compute-digest"
+			)
+			storageManager, _ := storage.NewStorageManager(
+				config.SimpleLocalTaskStoreStrategy,
+				&config.StorageOption{
+					DataPath: t.TempDir(),
+					TaskExpireTime: clientutil.Duration{
+						Duration: -1 * time.Second,
+					},
+				}, func(request storage.CommonTaskRequest) {})
+			defer storageManager.CleanUp()
+			taskStorage, err := storageManager.RegisterTask(context.Background(),
+				storage.RegisterTaskRequest{
+					CommonTaskRequest: storage.CommonTaskRequest{
+						PeerID: peerID,
+						TaskID: taskID,
+					},
+					ContentLength: int64(len(data)),
+					TotalPieces:   1,
+				})
+			require.Nil(t, err)
+
+			pm, err := NewPieceManager(storageManager, 30*time.Second)
+			require.Nil(t, err)
+			result, err := pm.DownloadPiece(context.Background(), &DownloadPieceRequest{
+				TaskID:          taskID,
+				PeerID:          peerID,
+				DstPid:          "peer-parent",
+				DstAddr:         strings.TrimPrefix(server.URL, "http://"),
+				ComputeDigest:   true,
+				DigestAlgorithm: tc.algorithm,
+				storage:         taskStorage,
+				piece: &base.PieceInfo{
+					PieceNum:   0,
+					RangeStart: 0,
+					RangeSize:  uint32(len(data)),
+					PieceMd5:   tc.pieceMd5,
+					PieceStyle: base.PieceStyle_PLAIN,
+				},
+				log: logger.With("test", tc.name),
+			})
+			tc.expect(t, result, err)
+		})
+	}
+}
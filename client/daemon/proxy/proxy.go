@@ -101,6 +101,12 @@ type Proxy struct {
 
 	// dumpHTTPContent indicates to dump http request header and response header
 	dumpHTTPContent bool
+
+	// dumpHTTPBodyLimit is the max bytes of http body to dump in transport
+	dumpHTTPBodyLimit int
+
+	// dumpRedactHeaders are the sensitive headers redacted in transport dump, the default ones are used when it is nil
+	dumpRedactHeaders []string
 }
 
 // Option is a functional option for configuring the proxy
@@ -214,6 +220,22 @@ func WithDumpHTTPContent(dump bool) Option {
 	}
 }
 
+// WithDumpHTTPBodyLimit sets the max bytes of http body to dump in transport
+func WithDumpHTTPBodyLimit(n int) Option {
+	return func(p *Proxy) *Proxy {
+		p.dumpHTTPBodyLimit = n
+		return p
+	}
+}
+
+// WithDumpRedactHeaders sets the sensitive headers redacted in transport dump
+func WithDumpRedactHeaders(hdrs []string) Option {
+	return func(p *Proxy) *Proxy {
+		p.dumpRedactHeaders = hdrs
+		return p
+	}
+}
+
 // NewProxy returns a new transparent proxy from the given options
 func NewProxy(options ...Option) (*Proxy, error) {
 	return NewProxyWithOptions(options...)
@@ -457,29 +479,39 @@ func (proxy *Proxy) handleHTTPS(w http.ResponseWriter, r *http.Request) {
 }
 
 func (proxy *Proxy) newTransport(tlsConfig *tls.Config) http.RoundTripper {
-	rt, _ := transport.New(
+	rt, _ := transport.New(append([]transport.Option{
 		transport.WithPeerHost(proxy.peerHost),
 		transport.WithPeerTaskManager(proxy.peerTaskManager),
 		transport.WithTLS(tlsConfig),
 		transport.WithCondition(proxy.shouldUseDragonfly),
 		transport.WithDefaultFilter(proxy.defaultFilter),
 		transport.WithDefaultBiz(bizTag),
-		transport.WithDumpHTTPContent(proxy.dumpHTTPContent),
-	)
+	}, proxy.dumpOptions()...)...)
 	return rt
 }
 
+// dumpOptions returns the transport options to dump http content
+func (proxy *Proxy) dumpOptions() []transport.Option {
+	options := []transport.Option{
+		transport.WithDumpHTTPContent(proxy.dumpHTTPContent),
+		transport.WithDumpBodyLimit(proxy.dumpHTTPBodyLimit),
+	}
+	if proxy.dumpRedactHeaders != nil {
+		options = append(options, transport.WithDumpRedactHeaders(proxy.dumpRedactHeaders))
+	}
+	return options
+}
+
 func (proxy *Proxy) mirrorRegistry(w http.ResponseWriter, r *http.Request) {
 	reverseProxy := newReverseProxy(proxy.registry)
-	t, err := transport.New(
+	t, err := transport.New(append([]transport.Option{
 		transport.WithPeerHost(proxy.peerHost),
 		transport.WithPeerTaskManager(proxy.peerTaskManager),
 		transport.WithTLS(proxy.registry.TLSConfig()),
 		transport.WithCondition(proxy.shouldUseDragonflyForMirror),
 		transport.WithDefaultFilter(proxy.defaultFilter),
 		transport.WithDefaultBiz(bizTag),
-		transport.WithDumpHTTPContent(proxy.dumpHTTPContent),
-	)
+	}, proxy.dumpOptions()...)...)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("failed to get transport: %v", err), http.StatusInternalServerError)
 	}
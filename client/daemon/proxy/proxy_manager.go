@@ -71,6 +71,8 @@ func NewProxyManager(peerHost *scheduler.PeerHost, peerTaskManager peer.TaskMana
 		WithDefaultFilter(opts.DefaultFilter),
 		WithBasicAuth(opts.BasicAuth),
 		WithDumpHTTPContent(opts.DumpHTTPContent),
+		WithDumpHTTPBodyLimit(opts.DumpHTTPBodyLimit),
+		WithDumpRedactHeaders(opts.DumpRedactHeaders),
 	}
 
 	if registry != nil {
@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"strconv"
 
 	"github.com/pkg/errors"
 	"google.golang.org/grpc"
@@ -74,7 +75,7 @@ func (m *server) ServeDownload(listener net.Listener) error {
 }
 
 func (m *server) ServePeer(listener net.Listener) error {
-	m.uploadAddr = fmt.Sprintf("%s:%d", m.peerHost.Ip, m.peerHost.DownPort)
+	m.uploadAddr = net.JoinHostPort(m.peerHost.Ip, strconv.Itoa(int(m.peerHost.DownPort)))
 	return m.peerServer.Serve(listener)
 }
 
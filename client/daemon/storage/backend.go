@@ -0,0 +1,104 @@
+/*
+ *     Copyright 2020 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage
+
+import (
+	"context"
+	"io"
+	"os"
+)
+
+// StorageBackend stores the data of tasks, the key is the data file path of a task
+type StorageBackend interface {
+	// WritePiece writes the data of reader to the key at offset, returns the written bytes
+	WritePiece(ctx context.Context, key string, offset int64, reader io.Reader) (int64, error)
+	// ReadPiece returns a reader of [offset, offset+length) of the key, a negative length reads to the end,
+	// caller should close it
+	ReadPiece(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error)
+	// Stat returns the stat of the key
+	Stat(ctx context.Context, key string) (*BackendStat, error)
+	// Delete deletes the data of the key
+	Delete(ctx context.Context, key string) error
+}
+
+// BackendStat is the stat of the data in StorageBackend
+type BackendStat struct {
+	// Size is the bytes of the data
+	Size int64
+}
+
+// linker is implemented by the StorageBackend which can link the data to a local file without copying
+type linker interface {
+	Link(ctx context.Context, key string, dst string) error
+}
+
+// localBackend stores task data in local files
+type localBackend struct{}
+
+var _ StorageBackend = (*localBackend)(nil)
+var _ linker = (*localBackend)(nil)
+
+func newLocalBackend() StorageBackend {
+	return &localBackend{}
+}
+
+func (b *localBackend) WritePiece(ctx context.Context, key string, offset int64, reader io.Reader) (int64, error) {
+	file, err := os.OpenFile(key, os.O_RDWR, defaultFileMode)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	if _, err = file.Seek(offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return io.Copy(file, reader)
+}
+
+func (b *localBackend) ReadPiece(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	file, err := os.Open(key)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = file.Seek(offset, io.SeekStart); err != nil {
+		file.Close()
+		return nil, err
+	}
+	// keep *os.File to use copy_file_range or sendfile when copying
+	if length < 0 {
+		return file, nil
+	}
+	return &limitedReadFile{
+		reader: io.LimitReader(file, length),
+		closer: file,
+	}, nil
+}
+
+func (b *localBackend) Stat(ctx context.Context, key string) (*BackendStat, error) {
+	stat, err := os.Stat(key)
+	if err != nil {
+		return nil, err
+	}
+	return &BackendStat{Size: stat.Size()}, nil
+}
+
+func (b *localBackend) Delete(ctx context.Context, key string) error {
+	return os.Remove(key)
+}
+
+func (b *localBackend) Link(ctx context.Context, key string, dst string) error {
+	return os.Link(key, dst)
+}
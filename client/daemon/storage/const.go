@@ -18,6 +18,7 @@ package storage
 
 import (
 	"os"
+	"time"
 
 	"github.com/pkg/errors"
 )
@@ -28,6 +29,11 @@ const (
 
 	defaultFileMode      = os.FileMode(0644)
 	defaultDirectoryMode = os.FileMode(0755)
+
+	defaultDiskWatermarkInterval = 10 * time.Second
+
+	// idleCheckTimes is the times to check idle within the alive duration
+	idleCheckTimes = 4
 )
 
 var (
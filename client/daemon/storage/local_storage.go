@@ -17,16 +17,24 @@
 package storage
 
 import (
+	"bytes"
 	"context"
+	"crypto/md5"
+	"encoding/hex"
 	"encoding/json"
 	"io"
 	"os"
 	"path"
+	"runtime"
+	"sort"
 	"sync"
 	"time"
 
+	"github.com/pkg/errors"
 	"go.uber.org/atomic"
+	"golang.org/x/sync/errgroup"
 
+	"d7y.io/dragonfly/v2/client/config"
 	logger "d7y.io/dragonfly/v2/internal/dflog"
 	"d7y.io/dragonfly/v2/pkg/rpc/base"
 	"d7y.io/dragonfly/v2/pkg/util/digestutils"
@@ -51,6 +59,15 @@ type localTaskStore struct {
 	// when digest not match, invalid will be set
 	invalid atomic.Bool
 
+	// backend stores the task data
+	backend StorageBackend
+
+	// verifyPieceMd5Sign verifies piece md5 sign after all pieces are written
+	verifyPieceMd5Sign bool
+
+	// digestConcurrency is the count of pieces hashed concurrently when validating data
+	digestConcurrency int
+
 	// content stores tiny file which length less than 128 bytes
 	content []byte
 }
@@ -82,16 +99,7 @@ func (t *localTaskStore) WritePiece(ctx context.Context, req *WritePieceRequest)
 	}
 	t.RUnlock()
 
-	file, err := os.OpenFile(t.DataFilePath, os.O_RDWR, defaultFileMode)
-	if err != nil {
-		return 0, err
-	}
-	defer file.Close()
-	if _, err = file.Seek(req.Range.Start, io.SeekStart); err != nil {
-		return 0, err
-	}
-
-	n, err := io.Copy(file, io.LimitReader(req.Reader, req.Range.Length))
+	n, err := t.backend.WritePiece(ctx, t.DataFilePath, req.Range.Start, io.LimitReader(req.Reader, req.Range.Length))
 	if err != nil {
 		return 0, err
 	}
@@ -141,9 +149,38 @@ func (t *localTaskStore) WritePiece(ctx context.Context, req *WritePieceRequest)
 	}
 	t.Pieces[req.Num] = req.PieceMetadata
 	t.genDigest(n, req)
+	if err := t.checkPieceMd5Sign(); err != nil {
+		return n, err
+	}
 	return n, nil
 }
 
+// checkPieceMd5Sign verifies piece md5 sign when all pieces are written, caller must hold the lock
+func (t *localTaskStore) checkPieceMd5Sign() error {
+	if !t.verifyPieceMd5Sign || t.PieceMd5Sign == "" || t.TotalPieces <= 0 || int32(len(t.Pieces)) != t.TotalPieces {
+		return nil
+	}
+
+	var pieceDigests []string
+	for i := int32(0); i < t.TotalPieces; i++ {
+		piece, ok := t.Pieces[i]
+		if !ok || piece.Md5 == "" {
+			t.Warnf("piece %d md5 not found, skip verifying piece md5 sign", i)
+			return nil
+		}
+		pieceDigests = append(pieceDigests, piece.Md5)
+	}
+
+	digest := digestutils.Sha256(pieceDigests...)
+	if digest != t.PieceMd5Sign {
+		t.Errorf("piece md5 sign not match, desired: %s, actual: %s", t.PieceMd5Sign, digest)
+		t.invalid.Store(true)
+		return ErrPieceMd5SignNotMatch
+	}
+	t.Debugf("verify piece md5 sign ok")
+	return nil
+}
+
 func (t *localTaskStore) genDigest(n int64, req *WritePieceRequest) {
 	if req.GenPieceDigest == nil || t.PieceMd5Sign != "" {
 		return
@@ -170,7 +207,8 @@ func (t *localTaskStore) UpdateTask(ctx context.Context, req *UpdateTaskRequest)
 	t.Lock()
 	defer t.Unlock()
 	t.persistentMetadata.ContentLength = req.ContentLength
-	if req.TotalPieces > 0 {
+	// empty content has zero pieces
+	if req.TotalPieces > 0 || (req.TotalPieces == 0 && req.ContentLength == 0) {
 		t.TotalPieces = req.TotalPieces
 		t.Debugf("update total pieces: %d", t.TotalPieces)
 	}
@@ -178,27 +216,74 @@ func (t *localTaskStore) UpdateTask(ctx context.Context, req *UpdateTaskRequest)
 		t.PieceMd5Sign = req.PieceMd5Sign
 		t.Debugf("update piece md5 sign: %s", t.PieceMd5Sign)
 	}
-	return nil
+	return t.checkPieceMd5Sign()
 }
 
+// ValidateDigest validates the piece md5 sign with the md5 of pieces in metadata, the data is not read
 func (t *localTaskStore) ValidateDigest(*PeerTaskMetadata) error {
-	t.Lock()
-	defer t.Unlock()
+	t.RLock()
+	defer t.RUnlock()
+	pieces, err := t.validatingPieces()
+	if err != nil || pieces == nil {
+		return err
+	}
+
+	var pieceDigests []string
+	for _, piece := range pieces {
+		pieceDigests = append(pieceDigests, piece.Md5)
+	}
+	return t.checkDigest(pieceDigests)
+}
+
+// validateData validates the piece md5 sign with the md5 of pieces computed from the data concurrently,
+// it's used to recover the reloaded tasks whose data may be corrupted
+func (t *localTaskStore) validateData() error {
+	t.RLock()
+	defer t.RUnlock()
+	pieces, err := t.validatingPieces()
+	if err != nil || pieces == nil {
+		return err
+	}
+
+	pieceDigests, err := t.hashPieces(pieces)
+	if err != nil {
+		t.Errorf("hash pieces error when validate data: %s", err)
+		t.invalid.Store(true)
+		return err
+	}
+	return t.checkDigest(pieceDigests)
+}
+
+// validatingPieces returns the pieces in order to validate, nil for empty content which has no pieces
+func (t *localTaskStore) validatingPieces() ([]PieceMetadata, error) {
+	// empty content has no pieces to validate
+	if t.isEmpty() {
+		return nil, nil
+	}
 	if t.persistentMetadata.PieceMd5Sign == "" {
 		t.invalid.Store(true)
-		return ErrDigestNotSet
+		return nil, ErrDigestNotSet
 	}
 	if t.TotalPieces <= 0 {
 		t.Errorf("total piece count not set when validate digest")
 		t.invalid.Store(true)
-		return ErrPieceCountNotSet
+		return nil, ErrPieceCountNotSet
 	}
 
-	var pieceDigests []string
+	pieces := make([]PieceMetadata, t.TotalPieces)
 	for i := int32(0); i < t.TotalPieces; i++ {
-		pieceDigests = append(pieceDigests, t.Pieces[i].Md5)
+		piece, ok := t.Pieces[i]
+		if !ok {
+			t.Errorf("piece %d not found when validate digest", i)
+			t.invalid.Store(true)
+			return nil, ErrPieceNotFound
+		}
+		pieces[i] = piece
 	}
+	return pieces, nil
+}
 
+func (t *localTaskStore) checkDigest(pieceDigests []string) error {
 	digest := digestutils.Sha256(pieceDigests...)
 	if digest != t.PieceMd5Sign {
 		t.Errorf("invalid digest, desired: %s, actual: %s", t.PieceMd5Sign, digest)
@@ -208,6 +293,67 @@ func (t *localTaskStore) ValidateDigest(*PeerTaskMetadata) error {
 	return nil
 }
 
+// isEmpty returns whether the task content is empty with zero pieces
+func (t *localTaskStore) isEmpty() bool {
+	return t.ContentLength == 0 && t.TotalPieces == 0
+}
+
+// hashPieces computes the md5 of pieces from the data file concurrently and compares them with the stored md5,
+// the md5 list is in the order of pieces, so the aggregate digest does not depend on the completion order
+func (t *localTaskStore) hashPieces(pieces []PieceMetadata) ([]string, error) {
+	concurrency := t.digestConcurrency
+	if concurrency <= 0 {
+		concurrency = runtime.NumCPU()
+	}
+
+	var (
+		pieceDigests = make([]string, len(pieces))
+		indexes      = make(chan int)
+	)
+	eg, ctx := errgroup.WithContext(context.Background())
+	for i := 0; i < concurrency; i++ {
+		eg.Go(func() error {
+			for i := range indexes {
+				piece := pieces[i]
+				rc, err := t.backend.ReadPiece(ctx, t.DataFilePath, piece.Range.Start, piece.Range.Length)
+				if err != nil {
+					return errors.Wrapf(err, "piece %d", piece.Num)
+				}
+				hash := md5.New()
+				_, err = io.Copy(hash, rc)
+				rc.Close()
+				if err != nil {
+					return errors.Wrapf(err, "piece %d", piece.Num)
+				}
+
+				pieceDigest := hex.EncodeToString(hash.Sum(nil))
+				if piece.Md5 != "" && piece.Md5 != pieceDigest {
+					t.Errorf("piece %d md5 not match, desired: %s, actual: %s", piece.Num, piece.Md5, pieceDigest)
+					return errors.Wrapf(digestutils.ErrDigestNotMatch, "piece %d", piece.Num)
+				}
+				pieceDigests[i] = pieceDigest
+			}
+			return nil
+		})
+	}
+
+	// stop dispatching on the first error
+dispatch:
+	for i := range pieces {
+		select {
+		case indexes <- i:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(indexes)
+
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+	return pieceDigests, nil
+}
+
 func (t *localTaskStore) IsInvalid(*PeerTaskMetadata) (bool, error) {
 	return t.invalid.Load(), nil
 }
@@ -220,10 +366,6 @@ func (t *localTaskStore) ReadPiece(ctx context.Context, req *ReadPieceRequest) (
 	}
 
 	t.touch()
-	file, err := os.Open(t.DataFilePath)
-	if err != nil {
-		return nil, nil, err
-	}
 
 	// If req.Num is equal to -1, range has a fixed value.
 	if req.Num != -1 {
@@ -233,19 +375,18 @@ func (t *localTaskStore) ReadPiece(ctx context.Context, req *ReadPieceRequest) (
 			req.Range = piece.Range
 		} else {
 			t.RUnlock()
-			file.Close()
 			t.Errorf("invalid piece num: %d", req.Num)
 			return nil, nil, ErrPieceNotFound
 		}
 	}
 
-	if _, err = file.Seek(req.Range.Start, io.SeekStart); err != nil {
-		file.Close()
-		t.Errorf("file seek failed: %v", err)
+	// who call ReadPiece, who close the io.ReadCloser
+	rc, err := t.backend.ReadPiece(ctx, t.DataFilePath, req.Range.Start, req.Range.Length)
+	if err != nil {
+		t.Errorf("read piece failed: %v", err)
 		return nil, nil, err
 	}
-	// who call ReadPiece, who close the io.ReadCloser
-	return io.LimitReader(file, req.Range.Length), file, nil
+	return rc, rc, nil
 }
 
 func (t *localTaskStore) ReadAllPieces(ctx context.Context, req *ReadAllPiecesRequest) (io.ReadCloser, error) {
@@ -256,28 +397,46 @@ func (t *localTaskStore) ReadAllPieces(ctx context.Context, req *ReadAllPiecesRe
 
 	t.touch()
 
-	// who call ReadPiece, who close the io.ReadCloser
-	file, err := os.Open(t.DataFilePath)
-	if err != nil {
-		return nil, err
+	// empty content may have no data in backend, eg: object storage
+	if t.Done && t.isEmpty() {
+		return io.NopCloser(bytes.NewReader(nil)), nil
 	}
+
+	// who call ReadPiece, who close the io.ReadCloser
 	if req.Range == nil {
-		return file, nil
+		return t.backend.ReadPiece(ctx, t.DataFilePath, 0, -1)
 	}
+	return t.backend.ReadPiece(ctx, t.DataFilePath, req.Range.Start, req.Range.Length)
+}
 
-	if _, err = file.Seek(req.Range.Start, io.SeekStart); err != nil {
-		file.Close()
-		t.Errorf("file seek to %d failed: %v", req.Range.Start, err)
-		return nil, err
+func (t *localTaskStore) ReadPiecesRange(ctx context.Context, req *PeerTaskMetadata, start, length int64) (io.ReadCloser, error) {
+	if t.invalid.Load() {
+		t.Errorf("invalid digest, refuse to read pieces range")
+		return nil, ErrInvalidDigest
 	}
 
-	return &limitedReadFile{
-		reader: io.LimitReader(file, req.Range.Length),
-		closer: file,
-	}, nil
+	t.touch()
+	if start < 0 || length <= 0 || (t.ContentLength >= 0 && start+length > t.ContentLength) {
+		return nil, errors.Errorf("invalid range, start: %d, length: %d, content length: %d", start, length, t.ContentLength)
+	}
+
+	// only the pieces overlapping the range are required
+	if _, ok := coverRange(t.sortedPieces(), start, length); !ok {
+		t.Warnf("pieces not found for range, start: %d, length: %d", start, length)
+		return nil, ErrPieceNotFound
+	}
+
+	// who call ReadPiecesRange, who close the io.ReadCloser
+	return t.backend.ReadPiece(ctx, t.DataFilePath, start, length)
 }
 
 func (t *localTaskStore) Store(ctx context.Context, req *StoreRequest) error {
+	if !t.Done {
+		if err := t.verifyContentDigest(ctx, req.Digest); err != nil {
+			return err
+		}
+	}
+
 	// Store is called in callback.Done, mark local task store done, for fast search
 	t.Done = true
 	t.touch()
@@ -303,25 +462,22 @@ func (t *localTaskStore) Store(ctx context.Context, req *StoreRequest) error {
 		os.Remove(req.Destination)
 	}
 	// 1. try to link
-	err = os.Link(t.DataFilePath, req.Destination)
-	if err == nil {
-		t.Infof("task data link to file %q success", req.Destination)
-		return nil
+	if l, ok := t.backend.(linker); ok {
+		err = l.Link(ctx, t.DataFilePath, req.Destination)
+		if err == nil {
+			t.Infof("task data link to file %q success", req.Destination)
+			return nil
+		}
+		t.Warnf("task data link to file %q error: %s", req.Destination, err)
 	}
-	t.Warnf("task data link to file %q error: %s", req.Destination, err)
 	// 2. link failed, copy it
-	file, err := os.Open(t.DataFilePath)
+	file, err := t.backend.ReadPiece(ctx, t.DataFilePath, 0, -1)
 	if err != nil {
 		t.Debugf("open tasks data error: %s", err)
 		return err
 	}
 	defer file.Close()
 
-	_, err = file.Seek(0, io.SeekStart)
-	if err != nil {
-		t.Debugf("task seek file error: %s", err)
-		return err
-	}
 	dstFile, err := os.OpenFile(req.Destination, os.O_CREATE|os.O_RDWR|os.O_TRUNC, defaultFileMode)
 	if err != nil {
 		t.Errorf("open tasks destination file error: %s", err)
@@ -335,7 +491,53 @@ func (t *localTaskStore) Store(ctx context.Context, req *StoreRequest) error {
 	return err
 }
 
+// verifyContentDigest verifies the digest of the whole task content when task is completed,
+// it catches the corruption of reassembled content which per piece md5 misses, eg: pieces out of order.
+// The expected digest in StoreRequest takes precedence over the registered one.
+func (t *localTaskStore) verifyContentDigest(ctx context.Context, expected string) error {
+	if expected == "" {
+		expected = t.Digest
+	}
+	if expected == "" {
+		return nil
+	}
+
+	file, err := t.backend.ReadPiece(ctx, t.DataFilePath, 0, -1)
+	if err != nil {
+		t.Errorf("open task data to verify content digest error: %s", err)
+		return err
+	}
+	defer file.Close()
+
+	reader := digestutils.NewDigestReaderWithAlgorithm(t.SugaredLoggerOnWith, file, "", expected)
+	if _, err = io.Copy(io.Discard, reader); err != nil {
+		if errors.Is(err, digestutils.ErrDigestNotMatch) {
+			t.Errorf("content digest not match, desired: %s", expected)
+			t.invalid.Store(true)
+			return ErrContentDigestNotMatch
+		}
+		t.Errorf("verify content digest error: %s", err)
+		return err
+	}
+
+	if t.Digest == "" {
+		t.Lock()
+		t.Digest = expected
+		t.Unlock()
+	}
+	t.Debugf("verify content digest ok")
+	return nil
+}
+
 func (t *localTaskStore) GetPieces(ctx context.Context, req *base.PieceTaskRequest) (*base.PiecePacket, error) {
+	page, err := t.GetPiecesPage(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return page.PiecePacket, nil
+}
+
+func (t *localTaskStore) GetPiecesPage(ctx context.Context, req *base.PieceTaskRequest) (*PiecePage, error) {
 	if t.invalid.Load() {
 		t.Errorf("invalid digest, refuse to get pieces")
 		return nil, ErrInvalidDigest
@@ -366,7 +568,72 @@ func (t *localTaskStore) GetPieces(ctx context.Context, req *base.PieceTaskReque
 			})
 		}
 	}
-	return piecePacket, nil
+
+	page := &PiecePage{
+		PiecePacket:  piecePacket,
+		NextStartNum: lastPiecePage,
+	}
+	if next := int64(req.StartNum) + int64(req.Limit); req.Limit > 0 && t.hasPiecesFrom(next) {
+		page.NextStartNum = int32(next)
+	}
+	return page, nil
+}
+
+// hasPiecesFrom returns whether there are pieces whose num is not less than num,
+// the stored pieces are checked when total piece count is unknown, caller must hold the lock
+func (t *localTaskStore) hasPiecesFrom(num int64) bool {
+	if t.TotalPieces > -1 {
+		return num < int64(t.TotalPieces)
+	}
+
+	for n := range t.Pieces {
+		if int64(n) >= num {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedPieces returns the pieces sorted by range start for range lookup
+func (t *localTaskStore) sortedPieces() []PieceMetadata {
+	t.RLock()
+	defer t.RUnlock()
+	pieces := make([]PieceMetadata, 0, len(t.Pieces))
+	for _, piece := range t.Pieces {
+		pieces = append(pieces, piece)
+	}
+	sort.Slice(pieces, func(i, j int) bool {
+		return pieces[i].Range.Start < pieces[j].Range.Start
+	})
+	return pieces
+}
+
+// coverRange checks whether the sorted pieces cover [start, start+length) continuously,
+// the piece is returned when the range is exactly one piece
+func coverRange(pieces []PieceMetadata, start, length int64) (*PieceMetadata, bool) {
+	end := start + length
+	// find the last piece starts before or at start
+	i := sort.Search(len(pieces), func(i int) bool {
+		return pieces[i].Range.Start > start
+	}) - 1
+	if i < 0 {
+		return nil, false
+	}
+
+	if pieces[i].Range.Start == start && pieces[i].Range.Length == length {
+		return &pieces[i], true
+	}
+
+	covered := start
+	for ; i < len(pieces) && covered < end; i++ {
+		if pieces[i].Range.Start > covered {
+			return nil, false
+		}
+		if pieceEnd := pieces[i].Range.Start + pieces[i].Range.Length; pieceEnd > covered {
+			covered = pieceEnd
+		}
+	}
+	return nil, covered >= end
 }
 
 func (t *localTaskStore) CanReclaim() bool {
@@ -429,12 +696,12 @@ func (t *localTaskStore) reclaimData() error {
 	// remove data
 	data := path.Join(t.dataDir, taskData)
 	stat, err := os.Lstat(data)
-	if err != nil {
+	if err != nil && !(os.IsNotExist(err) && t.StoreStrategy == string(config.ObjectStorageTaskStoreStrategy)) {
 		t.Errorf("stat task data %q error: %s", data, err)
 		return err
 	}
 	// remove symbol link cache file
-	if stat.Mode()&os.ModeSymlink == os.ModeSymlink {
+	if stat != nil && stat.Mode()&os.ModeSymlink == os.ModeSymlink {
 		dest, err0 := os.Readlink(data)
 		if err0 == nil {
 			if err = os.Remove(dest); err != nil && !os.IsNotExist(err) {
@@ -444,7 +711,7 @@ func (t *localTaskStore) reclaimData() error {
 			}
 		}
 	} else { // remove cache file
-		if err = os.Remove(t.DataFilePath); err != nil && !os.IsNotExist(err) {
+		if err = t.backend.Delete(context.Background(), t.DataFilePath); err != nil && !os.IsNotExist(err) {
 			t.Errorf("remove data file %s error: %s", data, err)
 			return err
 		}
@@ -503,9 +770,29 @@ func (l *limitedReadFile) Close() error {
 	return l.closer.Close()
 }
 
+// WriteTo hands the file to w when w is an io.ReaderFrom, like *net.TCPConn,
+// so that sendfile or splice is used, other readers fall back to normal copying
 func (l *limitedReadFile) WriteTo(w io.Writer) (n int64, err error) {
-	if r, ok := w.(io.ReaderFrom); ok {
+	if r, ok := w.(io.ReaderFrom); ok && isFileReader(l.reader) {
 		return r.ReadFrom(l.reader)
 	}
-	return io.Copy(w, l.reader)
+	return io.Copy(onlyWriter{w}, l.reader)
+}
+
+// isFileReader checks whether the reader is backed by a file directly
+func isFileReader(r io.Reader) bool {
+	switch r := r.(type) {
+	case *os.File:
+		return true
+	case *io.LimitedReader:
+		_, ok := r.R.(*os.File)
+		return ok
+	default:
+		return false
+	}
+}
+
+// onlyWriter hides the io.ReaderFrom of the writer
+type onlyWriter struct {
+	io.Writer
 }
@@ -20,16 +20,21 @@ import (
 	"bytes"
 	"context"
 	"crypto/md5"
+	"crypto/sha256"
 	"encoding/hex"
+	"fmt"
 	"io"
 	"math/rand"
+	"net"
 	"os"
 	"path"
 	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/shirou/gopsutil/v3/disk"
 	testifyassert "github.com/stretchr/testify/assert"
+	"go.uber.org/atomic"
 
 	"d7y.io/dragonfly/v2/client/clientutil"
 	"d7y.io/dragonfly/v2/client/config"
@@ -37,6 +42,7 @@ import (
 	logger "d7y.io/dragonfly/v2/internal/dflog"
 	"d7y.io/dragonfly/v2/pkg/rpc/base"
 	_ "d7y.io/dragonfly/v2/pkg/rpc/dfdaemon/server"
+	"d7y.io/dragonfly/v2/pkg/util/digestutils"
 )
 
 func TestMain(m *testing.M) {
@@ -197,6 +203,7 @@ func TestLocalTaskStore_StoreTaskData_Simple(t *testing.T) {
 		},
 		dataDir:      test.DataDir,
 		metadataFile: matadata,
+		backend:      newLocalBackend(),
 	}
 	ts.lastAccess.Store(time.Now().UnixNano())
 	err = ts.Store(context.Background(), &StoreRequest{
@@ -212,7 +219,125 @@ func TestLocalTaskStore_StoreTaskData_Simple(t *testing.T) {
 }
 
 func TestLocalTaskStore_ReloadPersistentTask_Simple(t *testing.T) {
+	tests := []struct {
+		name    string
+		corrupt bool
+		expect  func(t *testing.T, sm *storageManager, meta PeerTaskMetadata, testBytes []byte)
+	}{
+		{
+			name:    "reload completed task",
+			corrupt: false,
+			expect: func(t *testing.T, sm *storageManager, meta PeerTaskMetadata, testBytes []byte) {
+				assert := testifyassert.New(t)
+				reuse := sm.FindCompletedTask(meta.TaskID)
+				assert.NotNil(reuse)
+				assert.Equal(int64(len(testBytes)), reuse.ContentLength)
+				assert.Equal(int32(4), reuse.TotalPieces)
 
+				invalid, err := sm.IsInvalid(&meta)
+				assert.Nil(err)
+				assert.False(invalid)
+
+				reader, closer, err := sm.ReadPiece(context.Background(), &ReadPieceRequest{
+					PeerTaskMetadata: meta,
+					PieceMetadata:    PieceMetadata{Num: 3},
+				})
+				assert.Nil(err)
+				defer closer.Close()
+				data, err := io.ReadAll(reader)
+				assert.Nil(err)
+				assert.Equal(testBytes[12:], data)
+			},
+		},
+		{
+			name:    "reload corrupted task",
+			corrupt: true,
+			expect: func(t *testing.T, sm *storageManager, meta PeerTaskMetadata, testBytes []byte) {
+				assert := testifyassert.New(t)
+				invalid, err := sm.IsInvalid(&meta)
+				assert.Nil(err)
+				assert.True(invalid)
+				assert.Nil(sm.FindCompletedTask(meta.TaskID))
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			opt := &config.StorageOption{
+				DataPath: t.TempDir(),
+				TaskExpireTime: clientutil.Duration{
+					Duration: time.Minute,
+				},
+			}
+			sm, err := NewStorageManager(config.SimpleLocalTaskStoreStrategy, opt, func(request CommonTaskRequest) {})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var (
+				testBytes = []byte("hello dragonfly!")
+				meta      = PeerTaskMetadata{PeerID: "peer-reload", TaskID: "task-reload"}
+				pieceMd5s []string
+			)
+			for start := 0; start < len(testBytes); start += 4 {
+				sum := md5.Sum(testBytes[start : start+4])
+				pieceMd5s = append(pieceMd5s, hex.EncodeToString(sum[:]))
+			}
+			ts, err := sm.RegisterTask(context.Background(), RegisterTaskRequest{
+				CommonTaskRequest: CommonTaskRequest{PeerID: meta.PeerID, TaskID: meta.TaskID},
+				ContentLength:     int64(len(testBytes)),
+				TotalPieces:       int32(len(pieceMd5s)),
+				PieceMd5Sign:      digestutils.Sha256(pieceMd5s...),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for num := range pieceMd5s {
+				start := num * 4
+				_, err := ts.WritePiece(context.Background(), &WritePieceRequest{
+					PeerTaskMetadata: meta,
+					PieceMetadata: PieceMetadata{
+						Num:    int32(num),
+						Md5:    pieceMd5s[num],
+						Offset: uint64(start),
+						Range: clientutil.Range{
+							Start:  int64(start),
+							Length: 4,
+						},
+						Style: base.PieceStyle_PLAIN,
+					},
+					Reader: bytes.NewBuffer(testBytes[start : start+4]),
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			err = sm.Store(context.Background(), &StoreRequest{
+				CommonTaskRequest: CommonTaskRequest{PeerID: meta.PeerID, TaskID: meta.TaskID},
+				MetadataOnly:      true,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tc.corrupt {
+				dataFile := ts.(*localTaskStore).DataFilePath
+				if err := os.WriteFile(dataFile, []byte("hello dragonfly?"), defaultFileMode); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// restart with the same data path
+			reloaded, err := NewStorageManager(config.SimpleLocalTaskStoreStrategy, opt, func(request CommonTaskRequest) {})
+			if err != nil {
+				t.Fatal(err)
+			}
+			s := reloaded.(*storageManager)
+			s.validating.Wait()
+			tc.expect(t, s, meta, testBytes)
+		})
+	}
 }
 
 func TestLocalTaskStore_PutAndGetPiece_Advance(t *testing.T) {
@@ -366,3 +491,1279 @@ func calcFileMd5(filePath string) (string, error) {
 	md5String = hex.EncodeToString(hashInBytes)
 	return md5String, nil
 }
+
+func TestLocalTaskStore_VerifyPieceMd5Sign(t *testing.T) {
+	var (
+		taskID    = "task-verify-piece-md5-sign"
+		pieceSize = 4
+		testBytes = []byte("hello dragonfly!")
+	)
+
+	var pieceMd5s []string
+	for i := 0; i*pieceSize < len(testBytes); i++ {
+		sum := md5.Sum(testBytes[i*pieceSize : (i+1)*pieceSize])
+		pieceMd5s = append(pieceMd5s, hex.EncodeToString(sum[:]))
+	}
+
+	tests := []struct {
+		name         string
+		verify       bool
+		pieceMd5Sign string
+		expectErr    error
+	}{
+		{
+			name:         "piece md5 sign matches",
+			verify:       true,
+			pieceMd5Sign: digestutils.Sha256(pieceMd5s...),
+		},
+		{
+			name:         "piece md5 sign not match",
+			verify:       true,
+			pieceMd5Sign: digestutils.Sha256("foo"),
+			expectErr:    ErrPieceMd5SignNotMatch,
+		},
+		{
+			name:         "piece md5 sign not match without verification",
+			verify:       false,
+			pieceMd5Sign: digestutils.Sha256("foo"),
+		},
+	}
+
+	for i, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := testifyassert.New(t)
+			sm, err := NewStorageManager(config.SimpleLocalTaskStoreStrategy,
+				&config.StorageOption{
+					DataPath: t.TempDir(),
+					TaskExpireTime: clientutil.Duration{
+						Duration: time.Minute,
+					},
+				}, func(request CommonTaskRequest) {
+				})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			peerID := fmt.Sprintf("peer-verify-piece-md5-sign-%d", i)
+			ts, err := sm.(*storageManager).CreateTask(
+				RegisterTaskRequest{
+					CommonTaskRequest: CommonTaskRequest{
+						PeerID: peerID,
+						TaskID: taskID,
+					},
+					ContentLength:      int64(len(testBytes)),
+					TotalPieces:        int32(len(pieceMd5s)),
+					PieceMd5Sign:       tc.pieceMd5Sign,
+					VerifyPieceMd5Sign: tc.verify,
+				})
+			assert.Nil(err, "create task storage")
+
+			for num, pieceMd5 := range pieceMd5s {
+				start := num * pieceSize
+				_, err = ts.WritePiece(context.Background(), &WritePieceRequest{
+					PeerTaskMetadata: PeerTaskMetadata{
+						PeerID: peerID,
+						TaskID: taskID,
+					},
+					PieceMetadata: PieceMetadata{
+						Num:    int32(num),
+						Md5:    pieceMd5,
+						Offset: uint64(start),
+						Range: clientutil.Range{
+							Start:  int64(start),
+							Length: int64(pieceSize),
+						},
+						Style: base.PieceStyle_PLAIN,
+					},
+					Reader: bytes.NewBuffer(testBytes[start : start+pieceSize]),
+				})
+				if num < len(pieceMd5s)-1 {
+					assert.Nil(err, "put piece")
+				}
+			}
+
+			assert.Equal(tc.expectErr, err)
+			invalid, err := ts.IsInvalid(&PeerTaskMetadata{PeerID: peerID, TaskID: taskID})
+			assert.Nil(err)
+			assert.Equal(tc.expectErr != nil, invalid)
+		})
+	}
+}
+
+func TestLocalTaskStore_VerifyContentDigest(t *testing.T) {
+	var (
+		taskID    = "task-verify-content-digest"
+		pieceSize = 4
+		testBytes = []byte("hello dragonfly!")
+	)
+
+	sha256Sum := sha256.Sum256(testBytes)
+	md5Sum := md5.Sum(testBytes)
+	sha256Digest := "sha256:" + hex.EncodeToString(sha256Sum[:])
+	md5Digest := "md5:" + hex.EncodeToString(md5Sum[:])
+
+	tests := []struct {
+		name           string
+		registerDigest string
+		storeDigest    string
+		// pieceData returns the data written for piece num
+		pieceData func(num int) []byte
+		expectErr error
+	}{
+		{
+			name:           "sha256 digest matches",
+			registerDigest: sha256Digest,
+		},
+		{
+			name:        "md5 digest in store request matches",
+			storeDigest: md5Digest,
+		},
+		{
+			name: "without digest",
+			pieceData: func(num int) []byte {
+				return []byte("xxxx")
+			},
+		},
+		{
+			name:           "corrupted piece",
+			registerDigest: sha256Digest,
+			pieceData: func(num int) []byte {
+				if num == 1 {
+					return []byte("xxxx")
+				}
+				return nil
+			},
+			expectErr: ErrContentDigestNotMatch,
+		},
+		{
+			name:           "pieces out of order",
+			registerDigest: md5Digest,
+			pieceData: func(num int) []byte {
+				switch num {
+				case 0:
+					return testBytes[pieceSize : 2*pieceSize]
+				case 1:
+					return testBytes[:pieceSize]
+				}
+				return nil
+			},
+			expectErr: ErrContentDigestNotMatch,
+		},
+		{
+			name:           "digest in store request takes precedence",
+			registerDigest: sha256Digest,
+			storeDigest:    "sha256:" + digestutils.Sha256("foo"),
+			expectErr:      ErrContentDigestNotMatch,
+		},
+	}
+
+	for i, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := testifyassert.New(t)
+			sm, err := NewStorageManager(config.SimpleLocalTaskStoreStrategy,
+				&config.StorageOption{
+					DataPath: t.TempDir(),
+					TaskExpireTime: clientutil.Duration{
+						Duration: time.Minute,
+					},
+				}, func(request CommonTaskRequest) {
+				})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			peerID := fmt.Sprintf("peer-verify-content-digest-%d", i)
+			ts, err := sm.(*storageManager).CreateTask(
+				RegisterTaskRequest{
+					CommonTaskRequest: CommonTaskRequest{
+						PeerID: peerID,
+						TaskID: taskID,
+					},
+					ContentLength: int64(len(testBytes)),
+					TotalPieces:   int32(len(testBytes) / pieceSize),
+					Digest:        tc.registerDigest,
+				})
+			assert.Nil(err, "create task storage")
+
+			// write pieces without md5, per piece checks are skipped
+			for num := 0; num*pieceSize < len(testBytes); num++ {
+				start := num * pieceSize
+				data := testBytes[start : start+pieceSize]
+				if tc.pieceData != nil {
+					if d := tc.pieceData(num); d != nil {
+						data = d
+					}
+				}
+				_, err = ts.WritePiece(context.Background(), &WritePieceRequest{
+					PeerTaskMetadata: PeerTaskMetadata{
+						PeerID: peerID,
+						TaskID: taskID,
+					},
+					PieceMetadata: PieceMetadata{
+						Num:    int32(num),
+						Offset: uint64(start),
+						Range: clientutil.Range{
+							Start:  int64(start),
+							Length: int64(pieceSize),
+						},
+						Style: base.PieceStyle_PLAIN,
+					},
+					Reader: bytes.NewBuffer(data),
+				})
+				assert.Nil(err, "put piece")
+			}
+
+			err = sm.Store(context.Background(), &StoreRequest{
+				CommonTaskRequest: CommonTaskRequest{
+					PeerID: peerID,
+					TaskID: taskID,
+				},
+				MetadataOnly: true,
+				Digest:       tc.storeDigest,
+			})
+			assert.Equal(tc.expectErr, err)
+
+			invalid, err := ts.IsInvalid(&PeerTaskMetadata{PeerID: peerID, TaskID: taskID})
+			assert.Nil(err)
+			assert.Equal(tc.expectErr != nil, invalid)
+			assert.Equal(tc.expectErr == nil, ts.(*localTaskStore).Done)
+		})
+	}
+}
+
+func TestLocalTaskStore_GetPiecesPage(t *testing.T) {
+	tests := []struct {
+		name        string
+		pieces      int
+		totalPieces int32
+		startNum    uint32
+		limit       uint32
+		expectNums  []int32
+		expectNext  int32
+	}{
+		{
+			name:        "first page",
+			pieces:      10,
+			totalPieces: 10,
+			startNum:    0,
+			limit:       4,
+			expectNums:  []int32{0, 1, 2, 3},
+			expectNext:  4,
+		},
+		{
+			name:        "final partial page",
+			pieces:      10,
+			totalPieces: 10,
+			startNum:    8,
+			limit:       4,
+			expectNums:  []int32{8, 9},
+			expectNext:  -1,
+		},
+		{
+			name:        "final full page",
+			pieces:      8,
+			totalPieces: 8,
+			startNum:    4,
+			limit:       4,
+			expectNums:  []int32{4, 5, 6, 7},
+			expectNext:  -1,
+		},
+		{
+			name:        "page ends before the last piece",
+			pieces:      9,
+			totalPieces: 9,
+			startNum:    4,
+			limit:       4,
+			expectNums:  []int32{4, 5, 6, 7},
+			expectNext:  8,
+		},
+		{
+			name:        "start num exceeds total pieces",
+			pieces:      4,
+			totalPieces: 4,
+			startNum:    4,
+			limit:       4,
+			expectNext:  -1,
+		},
+		{
+			name:        "pieces not downloaded yet",
+			pieces:      2,
+			totalPieces: 10,
+			startNum:    0,
+			limit:       4,
+			expectNums:  []int32{0, 1},
+			expectNext:  4,
+		},
+		{
+			name:        "unknown total pieces with more stored pieces",
+			pieces:      6,
+			totalPieces: -1,
+			startNum:    0,
+			limit:       4,
+			expectNums:  []int32{0, 1, 2, 3},
+			expectNext:  4,
+		},
+		{
+			name:        "unknown total pieces without more stored pieces",
+			pieces:      6,
+			totalPieces: -1,
+			startNum:    4,
+			limit:       4,
+			expectNums:  []int32{4, 5},
+			expectNext:  -1,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := testifyassert.New(t)
+			ts := &localTaskStore{
+				SugaredLoggerOnWith: logger.With("test", "test"),
+				persistentMetadata: persistentMetadata{
+					TaskID:      "task-get-pieces-page",
+					PeerID:      "peer-get-pieces-page",
+					TotalPieces: tc.totalPieces,
+					Pieces:      map[int32]PieceMetadata{},
+				},
+			}
+			for i := 0; i < tc.pieces; i++ {
+				ts.Pieces[int32(i)] = PieceMetadata{Num: int32(i)}
+			}
+
+			page, err := ts.GetPiecesPage(context.Background(), &base.PieceTaskRequest{
+				TaskId:   ts.TaskID,
+				DstPid:   ts.PeerID,
+				StartNum: tc.startNum,
+				Limit:    tc.limit,
+			})
+			assert.Nil(err)
+
+			var nums []int32
+			for _, piece := range page.PieceInfos {
+				nums = append(nums, piece.PieceNum)
+			}
+			assert.Equal(tc.expectNums, nums)
+			assert.Equal(tc.expectNext, page.NextStartNum)
+			assert.Equal(tc.expectNext == -1, page.Last())
+		})
+	}
+}
+
+func TestLocalTaskStore_GetPiecesPage_PageThrough(t *testing.T) {
+	assert := testifyassert.New(t)
+	ts := &localTaskStore{
+		SugaredLoggerOnWith: logger.With("test", "test"),
+		persistentMetadata: persistentMetadata{
+			TaskID:      "task-get-pieces-page",
+			PeerID:      "peer-get-pieces-page",
+			TotalPieces: 1000,
+			Pieces:      map[int32]PieceMetadata{},
+		},
+	}
+	for i := int32(0); i < ts.TotalPieces; i++ {
+		ts.Pieces[i] = PieceMetadata{Num: i}
+	}
+
+	var (
+		count int
+		pages int
+		req   = &base.PieceTaskRequest{TaskId: ts.TaskID, DstPid: ts.PeerID, Limit: 64}
+	)
+	for {
+		page, err := ts.GetPiecesPage(context.Background(), req)
+		assert.Nil(err)
+		pages++
+		for _, piece := range page.PieceInfos {
+			assert.Equal(int32(count), piece.PieceNum)
+			count++
+		}
+		if page.Last() {
+			break
+		}
+		req.StartNum = uint32(page.NextStartNum)
+	}
+	assert.Equal(1000, count)
+	assert.Equal(16, pages)
+
+	// GetPieces returns the same packet as the page
+	packet, err := ts.GetPieces(context.Background(), &base.PieceTaskRequest{TaskId: ts.TaskID, DstPid: ts.PeerID, StartNum: 960, Limit: 64})
+	assert.Nil(err)
+	assert.Len(packet.PieceInfos, 40)
+}
+
+func TestLocalTaskStore_EmptyContent(t *testing.T) {
+	assert := testifyassert.New(t)
+	sm, err := NewStorageManager(config.SimpleLocalTaskStoreStrategy,
+		&config.StorageOption{
+			DataPath: t.TempDir(),
+			TaskExpireTime: clientutil.Duration{
+				Duration: time.Minute,
+			},
+		}, func(request CommonTaskRequest) {
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	meta := PeerTaskMetadata{PeerID: "peer-empty-content", TaskID: "task-empty-content"}
+	ts, err := sm.(*storageManager).CreateTask(
+		RegisterTaskRequest{
+			CommonTaskRequest: CommonTaskRequest{
+				PeerID: meta.PeerID,
+				TaskID: meta.TaskID,
+			},
+			ContentLength: -1,
+			TotalPieces:   -1,
+		})
+	assert.Nil(err, "create task storage")
+
+	assert.Nil(ts.UpdateTask(context.Background(), &UpdateTaskRequest{
+		PeerTaskMetadata: meta,
+		ContentLength:    0,
+		TotalPieces:      0,
+	}))
+	assert.Equal(int32(0), ts.(*localTaskStore).TotalPieces)
+
+	// empty content has no piece md5 sign to validate
+	assert.Nil(ts.ValidateDigest(&meta))
+	assert.Nil(ts.Store(context.Background(), &StoreRequest{
+		CommonTaskRequest: CommonTaskRequest{
+			PeerID: meta.PeerID,
+			TaskID: meta.TaskID,
+		},
+		MetadataOnly: true,
+	}))
+
+	rc, err := ts.ReadAllPieces(context.Background(), &ReadAllPiecesRequest{PeerTaskMetadata: meta})
+	assert.Nil(err)
+	data, err := io.ReadAll(rc)
+	assert.Nil(err)
+	assert.Empty(data)
+	assert.Nil(rc.Close())
+
+	reuse := sm.FindCompletedTask(meta.TaskID)
+	assert.NotNil(reuse)
+	assert.Equal(int64(0), reuse.ContentLength)
+	assert.Equal(int32(0), reuse.TotalPieces)
+}
+
+func TestStorageManager_ReclaimByDiskWatermark(t *testing.T) {
+	tests := []struct {
+		name         string
+		total        uint64
+		usedPercent  float64
+		notExpired   bool
+		expectMarked []string
+		expectTasks  []string
+	}{
+		{
+			name:         "disk usage is below high watermark",
+			total:        1000,
+			usedPercent:  85,
+			expectMarked: nil,
+			expectTasks:  []string{"task-0", "task-1", "task-2"},
+		},
+		{
+			name:         "disk usage is above high watermark",
+			total:        1000,
+			usedPercent:  95,
+			expectMarked: []string{"task-0", "task-1"},
+			expectTasks:  []string{"task-2"},
+		},
+		{
+			name:         "no enough tasks to reclaim",
+			total:        10000,
+			usedPercent:  95,
+			expectMarked: []string{"task-0", "task-1", "task-2"},
+			expectTasks:  nil,
+		},
+		{
+			name:         "tasks are not expired",
+			total:        1000,
+			usedPercent:  95,
+			notExpired:   true,
+			expectMarked: nil,
+			expectTasks:  []string{"task-0", "task-1", "task-2"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := testifyassert.New(t)
+			sm, err := NewStorageManager(config.SimpleLocalTaskStoreStrategy,
+				&config.StorageOption{
+					DataPath: t.TempDir(),
+					TaskExpireTime: clientutil.Duration{
+						Duration: time.Minute,
+					},
+				}, func(request CommonTaskRequest) {
+				})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			s := sm.(*storageManager)
+			s.storeOption.DiskHighWatermarkPercent = 90
+			s.storeOption.DiskLowWatermarkPercent = 80
+			s.diskUsage = func(path string) (*disk.UsageStat, error) {
+				return &disk.UsageStat{Total: tc.total, UsedPercent: tc.usedPercent}, nil
+			}
+
+			// total 300 bytes, task-0 is the least recently used
+			for i := 0; i < 3; i++ {
+				ts, err := s.CreateTask(
+					RegisterTaskRequest{
+						CommonTaskRequest: CommonTaskRequest{
+							PeerID: fmt.Sprintf("peer-%d", i),
+							TaskID: fmt.Sprintf("task-%d", i),
+						},
+						ContentLength: 100,
+					})
+				assert.Nil(err, "create task storage")
+				task := ts.(*localTaskStore)
+				task.Done = true
+				if tc.notExpired {
+					task.lastAccess.Store(time.Now().UnixNano() + int64(i))
+				} else {
+					task.lastAccess.Store(int64(i))
+				}
+			}
+
+			// the first loop only marks tasks, all tasks are still loaded
+			assert.Equal(int64(0), s.reclaimByDiskWatermark())
+			var marked []string
+			for i := 0; i < 3; i++ {
+				ts, ok := s.LoadTask(PeerTaskMetadata{PeerID: fmt.Sprintf("peer-%d", i), TaskID: fmt.Sprintf("task-%d", i)})
+				assert.True(ok)
+				if ts.(*localTaskStore).reclaimMarked.Load() {
+					marked = append(marked, fmt.Sprintf("task-%d", i))
+				}
+			}
+			assert.Equal(tc.expectMarked, marked)
+
+			// the next loop reclaims the marked tasks
+			assert.Equal(int64(len(tc.expectMarked)*100), s.reclaimByDiskWatermark())
+			var tasks []string
+			for i := 0; i < 3; i++ {
+				if _, ok := s.LoadTask(PeerTaskMetadata{PeerID: fmt.Sprintf("peer-%d", i), TaskID: fmt.Sprintf("task-%d", i)}); ok {
+					tasks = append(tasks, fmt.Sprintf("task-%d", i))
+				}
+			}
+			assert.Equal(tc.expectTasks, tasks)
+		})
+	}
+}
+
+func TestStorageManager_FindReusableTask(t *testing.T) {
+	assert := testifyassert.New(t)
+	sm, err := NewStorageManager(config.SimpleLocalTaskStoreStrategy,
+		&config.StorageOption{
+			DataPath: t.TempDir(),
+			TaskExpireTime: clientutil.Duration{
+				Duration: time.Minute,
+			},
+		}, func(request CommonTaskRequest) {
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := sm.(*storageManager)
+
+	// the content digest is verified when task is completed, no data is written
+	emptySum := sha256.Sum256(nil)
+	digest := "sha256:" + hex.EncodeToString(emptySum[:])
+
+	tasks := []struct {
+		taskID string
+		url    string
+		digest string
+		done   bool
+	}{
+		{taskID: "task-a", url: "http://a", digest: digest, done: true},
+		{taskID: "task-b", url: "http://b", digest: digest, done: true},
+		{taskID: "task-c", url: "http://c", digest: "sha256:bar", done: false},
+	}
+	for _, task := range tasks {
+		_, err := s.CreateTask(
+			RegisterTaskRequest{
+				CommonTaskRequest: CommonTaskRequest{
+					PeerID: "peer-" + task.taskID,
+					TaskID: task.taskID,
+				},
+				ContentLength: 10,
+				TotalPieces:   1,
+				URL:           task.url,
+				Digest:        task.digest,
+			})
+		assert.Nil(err, "create task storage")
+		if task.done {
+			assert.Nil(s.Store(context.Background(), &StoreRequest{
+				CommonTaskRequest: CommonTaskRequest{
+					PeerID: "peer-" + task.taskID,
+					TaskID: task.taskID,
+				},
+				MetadataOnly: true,
+			}))
+		}
+	}
+
+	// prefer the task with the same url
+	reuse := s.FindReusableTask("http://b", digest)
+	assert.NotNil(reuse)
+	assert.Equal(reuse.TaskID, "task-b")
+	assert.Equal(reuse.ContentLength, int64(10))
+
+	// any task with the same digest
+	reuse = s.FindReusableTask("http://d", digest)
+	assert.NotNil(reuse)
+	assert.Equal(reuse.TaskID, "task-a")
+
+	// task is not completed
+	assert.Nil(s.FindReusableTask("http://c", "sha256:bar"))
+
+	// digest is empty
+	assert.Nil(s.FindReusableTask("http://a", ""))
+
+	// task is removed from index after reclaimed
+	s.cleanIndex("task-a", "peer-task-a")
+	reuse = s.FindReusableTask("http://a", digest)
+	assert.NotNil(reuse)
+	assert.Equal(reuse.TaskID, "task-b")
+
+	s.cleanIndex("task-b", "peer-task-b")
+	assert.Nil(s.FindReusableTask("http://a", digest))
+}
+
+func TestCoverRange(t *testing.T) {
+	pieces := []PieceMetadata{
+		{Num: 0, Md5: "0", Range: clientutil.Range{Start: 0, Length: 4}},
+		{Num: 1, Md5: "1", Range: clientutil.Range{Start: 4, Length: 4}},
+		{Num: 3, Md5: "3", Range: clientutil.Range{Start: 12, Length: 4}},
+	}
+
+	tests := []struct {
+		name        string
+		start       int64
+		length      int64
+		expectPiece string
+		expectOK    bool
+	}{
+		{
+			name:        "aligned with one piece",
+			start:       4,
+			length:      4,
+			expectPiece: "1",
+			expectOK:    true,
+		},
+		{
+			name:     "inside one piece",
+			start:    1,
+			length:   2,
+			expectOK: true,
+		},
+		{
+			name:     "across continuous pieces",
+			start:    2,
+			length:   6,
+			expectOK: true,
+		},
+		{
+			name:     "across missing piece",
+			start:    6,
+			length:   8,
+			expectOK: false,
+		},
+		{
+			name:     "exceed the last piece",
+			start:    12,
+			length:   8,
+			expectOK: false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := testifyassert.New(t)
+			piece, ok := coverRange(pieces, tc.start, tc.length)
+			assert.Equal(tc.expectOK, ok)
+			if tc.expectPiece == "" {
+				assert.Nil(piece)
+			} else {
+				assert.Equal(tc.expectPiece, piece.Md5)
+			}
+		})
+	}
+}
+
+func TestStorageManager_GetReusablePieces(t *testing.T) {
+	assert := testifyassert.New(t)
+	sm, err := NewStorageManager(config.SimpleLocalTaskStoreStrategy,
+		&config.StorageOption{
+			DataPath: t.TempDir(),
+			TaskExpireTime: clientutil.Duration{
+				Duration: time.Minute,
+			},
+		}, func(request CommonTaskRequest) {
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := sm.(*storageManager)
+
+	testBytes := []byte("hello dragonfly!")
+	writePieces := func(meta PeerTaskMetadata, pieceSize int, nums ...int) []string {
+		var pieceMd5s []string
+		for _, num := range nums {
+			start := num * pieceSize
+			end := start + pieceSize
+			if end > len(testBytes) {
+				end = len(testBytes)
+			}
+			sum := md5.Sum(testBytes[start:end])
+			pieceMd5s = append(pieceMd5s, hex.EncodeToString(sum[:]))
+			_, err := s.WritePiece(context.Background(), &WritePieceRequest{
+				PeerTaskMetadata: meta,
+				PieceMetadata: PieceMetadata{
+					Num:    int32(num),
+					Md5:    hex.EncodeToString(sum[:]),
+					Offset: uint64(start),
+					Range: clientutil.Range{
+						Start:  int64(start),
+						Length: int64(end - start),
+					},
+					Style: base.PieceStyle_PLAIN,
+				},
+				Reader: bytes.NewBuffer(testBytes[start:end]),
+			})
+			assert.Nil(err, "put piece")
+		}
+		return pieceMd5s
+	}
+
+	// completed task with piece size 4 misses the range [8, 12), which is zero filled in the data file
+	content := append([]byte{}, testBytes...)
+	copy(content[8:12], make([]byte, 4))
+	contentSum := sha256.Sum256(content)
+	digest := "sha256:" + hex.EncodeToString(contentSum[:])
+
+	completed := PeerTaskMetadata{PeerID: "peer-completed", TaskID: "task-completed"}
+	_, err = s.CreateTask(RegisterTaskRequest{
+		CommonTaskRequest: CommonTaskRequest{PeerID: completed.PeerID, TaskID: completed.TaskID},
+		ContentLength:     int64(len(testBytes)),
+		TotalPieces:       4,
+		Digest:            digest,
+	})
+	assert.Nil(err, "create task storage")
+	pieceMd5s := writePieces(completed, 4, 0, 1, 3)
+	assert.Nil(s.Store(context.Background(), &StoreRequest{
+		CommonTaskRequest: CommonTaskRequest{PeerID: completed.PeerID, TaskID: completed.TaskID},
+		MetadataOnly:      true,
+	}))
+
+	// new task with piece size 6
+	running := PeerTaskMetadata{PeerID: "peer-running", TaskID: "task-running"}
+	_, err = s.CreateTask(RegisterTaskRequest{
+		CommonTaskRequest: CommonTaskRequest{PeerID: running.PeerID, TaskID: running.TaskID},
+		ContentLength:     int64(len(testBytes)),
+	})
+	assert.Nil(err, "create task storage")
+	writePieces(running, 6, 0)
+
+	packet, err := s.GetReusablePieces(context.Background(), &base.PieceTaskRequest{
+		TaskId:   running.TaskID,
+		SrcPid:   running.PeerID,
+		StartNum: 0,
+		Limit:    16,
+	}, digest)
+	assert.Nil(err)
+	assert.Equal(completed.TaskID, packet.TaskId)
+	assert.Equal(completed.PeerID, packet.DstPid)
+	assert.Equal(int32(3), packet.TotalPiece)
+
+	// piece 0 [0, 6) spans two pieces, piece 1 [6, 12) is missing, piece 2 [12, 16) is aligned
+	assert.Len(packet.PieceInfos, 2)
+	assert.Equal(int32(0), packet.PieceInfos[0].PieceNum)
+	assert.Equal(uint32(6), packet.PieceInfos[0].RangeSize)
+	assert.Equal("", packet.PieceInfos[0].PieceMd5)
+	assert.Equal(int32(2), packet.PieceInfos[1].PieceNum)
+	assert.Equal(uint64(12), packet.PieceInfos[1].RangeStart)
+	assert.Equal(uint32(4), packet.PieceInfos[1].RangeSize)
+	assert.Equal(pieceMd5s[2], packet.PieceInfos[1].PieceMd5)
+
+	_, err = s.GetReusablePieces(context.Background(), &base.PieceTaskRequest{
+		TaskId: running.TaskID,
+		SrcPid: running.PeerID,
+		Limit:  16,
+	}, "sha256:bar")
+	assert.ErrorIs(err, ErrPieceNotFound)
+}
+
+func TestLocalTaskStore_ReadPiecesRange(t *testing.T) {
+	sm, err := NewStorageManager(config.SimpleLocalTaskStoreStrategy,
+		&config.StorageOption{
+			DataPath: t.TempDir(),
+			TaskExpireTime: clientutil.Duration{
+				Duration: time.Minute,
+			},
+		}, func(request CommonTaskRequest) {
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := sm.(*storageManager)
+
+	var (
+		testBytes = []byte("hello dragonfly!")
+		pieceSize = 4
+		meta      = PeerTaskMetadata{PeerID: "peer-range", TaskID: "task-range"}
+	)
+	_, err = s.CreateTask(RegisterTaskRequest{
+		CommonTaskRequest: CommonTaskRequest{PeerID: meta.PeerID, TaskID: meta.TaskID},
+		ContentLength:     int64(len(testBytes)),
+		TotalPieces:       4,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// piece 2 [8, 12) is missing
+	for _, num := range []int{0, 1, 3} {
+		start := num * pieceSize
+		_, err := s.WritePiece(context.Background(), &WritePieceRequest{
+			PeerTaskMetadata: meta,
+			PieceMetadata: PieceMetadata{
+				Num:    int32(num),
+				Offset: uint64(start),
+				Range: clientutil.Range{
+					Start:  int64(start),
+					Length: int64(pieceSize),
+				},
+				Style: base.PieceStyle_PLAIN,
+			},
+			Reader: bytes.NewBuffer(testBytes[start : start+pieceSize]),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		start  int64
+		length int64
+		expect func(t *testing.T, data []byte, err error)
+	}{
+		{
+			name:   "range inside one piece",
+			start:  1,
+			length: 2,
+			expect: func(t *testing.T, data []byte, err error) {
+				assert := testifyassert.New(t)
+				assert.Nil(err)
+				assert.Equal(testBytes[1:3], data)
+			},
+		},
+		{
+			name:   "range spans piece boundaries",
+			start:  2,
+			length: 5,
+			expect: func(t *testing.T, data []byte, err error) {
+				assert := testifyassert.New(t)
+				assert.Nil(err)
+				assert.Equal(testBytes[2:7], data)
+			},
+		},
+		{
+			name:   "range ends with content",
+			start:  13,
+			length: 3,
+			expect: func(t *testing.T, data []byte, err error) {
+				assert := testifyassert.New(t)
+				assert.Nil(err)
+				assert.Equal(testBytes[13:], data)
+			},
+		},
+		{
+			name:   "range overlaps missing piece",
+			start:  6,
+			length: 4,
+			expect: func(t *testing.T, data []byte, err error) {
+				assert := testifyassert.New(t)
+				assert.ErrorIs(err, ErrPieceNotFound)
+			},
+		},
+		{
+			name:   "range out of content",
+			start:  14,
+			length: 4,
+			expect: func(t *testing.T, data []byte, err error) {
+				assert := testifyassert.New(t)
+				assert.Error(err)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rc, err := s.ReadPiecesRange(context.Background(), &meta, tc.start, tc.length)
+			if err != nil {
+				tc.expect(t, nil, err)
+				return
+			}
+			defer rc.Close()
+			data, err := io.ReadAll(rc)
+			tc.expect(t, data, err)
+		})
+	}
+}
+
+func BenchmarkLocalTaskStore_ReadPiece(b *testing.B) {
+	sm, err := NewStorageManager(config.SimpleLocalTaskStoreStrategy,
+		&config.StorageOption{
+			DataPath: b.TempDir(),
+			TaskExpireTime: clientutil.Duration{
+				Duration: time.Minute,
+			},
+		}, func(request CommonTaskRequest) {
+		})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	var (
+		pieceSize = 4 * 1024 * 1024
+		meta      = PeerTaskMetadata{PeerID: "peer-bench", TaskID: "task-bench"}
+	)
+	_, err = sm.RegisterTask(context.Background(), RegisterTaskRequest{
+		CommonTaskRequest: CommonTaskRequest{PeerID: meta.PeerID, TaskID: meta.TaskID},
+		ContentLength:     int64(pieceSize),
+		TotalPieces:       1,
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+	_, err = sm.WritePiece(context.Background(), &WritePieceRequest{
+		PeerTaskMetadata: meta,
+		PieceMetadata: PieceMetadata{
+			Num: 0,
+			Range: clientutil.Range{
+				Length: int64(pieceSize),
+			},
+			Style: base.PieceStyle_PLAIN,
+		},
+		Reader: io.LimitReader(rand.New(rand.NewSource(time.Now().UnixNano())), int64(pieceSize)),
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(io.Discard, conn)
+			}()
+		}
+	}()
+
+	benchmarks := []struct {
+		name string
+		copy func(w io.Writer, r io.Reader) (int64, error)
+	}{
+		{
+			name: "sendfile",
+			copy: io.Copy,
+		},
+		{
+			name: "buffered",
+			copy: func(w io.Writer, r io.Reader) (int64, error) {
+				// hide io.WriterTo and io.ReaderFrom to copy through user-space buffers
+				return io.Copy(onlyWriter{w}, struct{ io.Reader }{r})
+			},
+		},
+	}
+
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			conn, err := net.Dial("tcp", ln.Addr().String())
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer conn.Close()
+
+			b.SetBytes(int64(pieceSize))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				reader, closer, err := sm.ReadPiece(context.Background(), &ReadPieceRequest{
+					PeerTaskMetadata: meta,
+					PieceMetadata:    PieceMetadata{Num: 0},
+				})
+				if err != nil {
+					b.Fatal(err)
+				}
+				if _, err := bm.copy(conn, reader); err != nil {
+					b.Fatal(err)
+				}
+				closer.Close()
+			}
+		})
+	}
+}
+
+func newValidateDigestTask(tb testing.TB, pieceSize, pieceCount, concurrency int) *localTaskStore {
+	sm, err := NewStorageManager(config.SimpleLocalTaskStoreStrategy,
+		&config.StorageOption{
+			DataPath: tb.TempDir(),
+			TaskExpireTime: clientutil.Duration{
+				Duration: time.Minute,
+			},
+			ValidateDigestConcurrency: concurrency,
+		}, func(request CommonTaskRequest) {
+		})
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	testBytes := make([]byte, pieceSize*pieceCount)
+	rand.Read(testBytes)
+	var pieceMd5s []string
+	for i := 0; i < pieceCount; i++ {
+		sum := md5.Sum(testBytes[i*pieceSize : (i+1)*pieceSize])
+		pieceMd5s = append(pieceMd5s, hex.EncodeToString(sum[:]))
+	}
+
+	meta := PeerTaskMetadata{PeerID: "peer-digest", TaskID: "task-digest"}
+	ts, err := sm.RegisterTask(context.Background(), RegisterTaskRequest{
+		CommonTaskRequest: CommonTaskRequest{PeerID: meta.PeerID, TaskID: meta.TaskID},
+		ContentLength:     int64(len(testBytes)),
+		TotalPieces:       int32(pieceCount),
+		PieceMd5Sign:      digestutils.Sha256(pieceMd5s...),
+	})
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	for i := 0; i < pieceCount; i++ {
+		start := i * pieceSize
+		_, err = ts.WritePiece(context.Background(), &WritePieceRequest{
+			PeerTaskMetadata: meta,
+			PieceMetadata: PieceMetadata{
+				Num:    int32(i),
+				Md5:    pieceMd5s[i],
+				Offset: uint64(start),
+				Range: clientutil.Range{
+					Start:  int64(start),
+					Length: int64(pieceSize),
+				},
+				Style: base.PieceStyle_PLAIN,
+			},
+			Reader: bytes.NewBuffer(testBytes[start : start+pieceSize]),
+		})
+		if err != nil {
+			tb.Fatal(err)
+		}
+	}
+	return ts.(*localTaskStore)
+}
+
+func TestLocalTaskStore_ValidateData(t *testing.T) {
+	tests := []struct {
+		name        string
+		concurrency int
+		corrupt     int
+		expect      func(t *testing.T, ts *localTaskStore, err error)
+	}{
+		{
+			name:        "validate data serially",
+			concurrency: 1,
+			corrupt:     -1,
+			expect: func(t *testing.T, ts *localTaskStore, err error) {
+				assert := testifyassert.New(t)
+				assert.Nil(err)
+				assert.False(ts.invalid.Load())
+			},
+		},
+		{
+			name:        "validate data concurrently",
+			concurrency: 4,
+			corrupt:     -1,
+			expect: func(t *testing.T, ts *localTaskStore, err error) {
+				assert := testifyassert.New(t)
+				assert.Nil(err)
+				assert.False(ts.invalid.Load())
+			},
+		},
+		{
+			name:        "piece data is corrupted",
+			concurrency: 4,
+			corrupt:     5,
+			expect: func(t *testing.T, ts *localTaskStore, err error) {
+				assert := testifyassert.New(t)
+				assert.ErrorIs(err, digestutils.ErrDigestNotMatch)
+				assert.Contains(err.Error(), "piece 5")
+				assert.True(ts.invalid.Load())
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			pieceSize := 1024
+			ts := newValidateDigestTask(t, pieceSize, 16, tc.concurrency)
+			if tc.corrupt >= 0 {
+				file, err := os.OpenFile(ts.DataFilePath, os.O_RDWR, 0)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if _, err = file.WriteAt([]byte("corrupted"), int64(tc.corrupt*pieceSize)); err != nil {
+					t.Fatal(err)
+				}
+				file.Close()
+			}
+
+			err := ts.validateData()
+			tc.expect(t, ts, err)
+		})
+	}
+}
+
+func BenchmarkLocalTaskStore_ValidateData(b *testing.B) {
+	for _, concurrency := range []int{1, 4, 16} {
+		b.Run(fmt.Sprintf("concurrency-%d", concurrency), func(b *testing.B) {
+			pieceSize, pieceCount := 1024*1024, 64
+			ts := newValidateDigestTask(b, pieceSize, pieceCount, concurrency)
+			b.SetBytes(int64(pieceSize * pieceCount))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := ts.validateData(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestStorageManager_OnIdle(t *testing.T) {
+	tests := []struct {
+		name   string
+		run    func(sm Manager)
+		expect func(t *testing.T, idle *atomic.Int32)
+	}{
+		{
+			name: "call on idle when no keep",
+			run: func(sm Manager) {
+				time.Sleep(300 * time.Millisecond)
+			},
+			expect: func(t *testing.T, idle *atomic.Int32) {
+				assert := testifyassert.New(t)
+				assert.Equal(int32(1), idle.Load())
+			},
+		},
+		{
+			name: "keep delays on idle",
+			run: func(sm Manager) {
+				for i := 0; i < 6; i++ {
+					time.Sleep(50 * time.Millisecond)
+					sm.Keep()
+				}
+			},
+			expect: func(t *testing.T, idle *atomic.Int32) {
+				assert := testifyassert.New(t)
+				assert.Equal(int32(0), idle.Load())
+			},
+		},
+		{
+			name: "clean up stops watching idle",
+			run: func(sm Manager) {
+				sm.CleanUp()
+				time.Sleep(300 * time.Millisecond)
+			},
+			expect: func(t *testing.T, idle *atomic.Int32) {
+				assert := testifyassert.New(t)
+				assert.Equal(int32(0), idle.Load())
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			idle := atomic.NewInt32(0)
+			sm, err := NewStorageManager(config.SimpleLocalTaskStoreStrategy,
+				&config.StorageOption{
+					DataPath: t.TempDir(),
+					TaskExpireTime: clientutil.Duration{
+						Duration: time.Minute,
+					},
+				}, func(request CommonTaskRequest) {
+				}, WithOnIdle(100*time.Millisecond, func() {
+					idle.Inc()
+				}))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tc.run(sm)
+			tc.expect(t, idle)
+		})
+	}
+}
+
+func TestStorageManager_UnregisterTask(t *testing.T) {
+	assert := testifyassert.New(t)
+	gcCallbacks := atomic.NewInt32(0)
+	sm, err := NewStorageManager(config.SimpleLocalTaskStoreStrategy,
+		&config.StorageOption{
+			DataPath: t.TempDir(),
+			TaskExpireTime: clientutil.Duration{
+				Duration: time.Minute,
+			},
+		}, func(request CommonTaskRequest) {
+			gcCallbacks.Inc()
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sm.CleanUp()
+
+	var (
+		testBytes = []byte("hello dragonfly!")
+		request   = CommonTaskRequest{PeerID: "peer-unregister", TaskID: "task-unregister"}
+	)
+	ts, err := sm.RegisterTask(context.Background(), RegisterTaskRequest{
+		CommonTaskRequest: request,
+		ContentLength:     int64(len(testBytes)),
+		TotalPieces:       1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ts.WritePiece(context.Background(), &WritePieceRequest{
+		PeerTaskMetadata: PeerTaskMetadata{PeerID: request.PeerID, TaskID: request.TaskID},
+		PieceMetadata: PieceMetadata{
+			Num:   0,
+			Range: clientutil.Range{Start: 0, Length: int64(len(testBytes))},
+		},
+		Reader: bytes.NewBuffer(testBytes),
+	})
+	assert.Nil(err)
+	dataDir := ts.(*localTaskStore).dataDir
+
+	assert.Nil(sm.UnregisterTask(context.Background(), request))
+	_, err = os.Stat(dataDir)
+	assert.True(os.IsNotExist(err))
+	_, ok := sm.(*storageManager).LoadTask(PeerTaskMetadata{PeerID: request.PeerID, TaskID: request.TaskID})
+	assert.False(ok)
+	// the caller leaves the task itself
+	assert.Equal(int32(0), gcCallbacks.Load())
+
+	assert.Equal(ErrTaskNotFound, sm.UnregisterTask(context.Background(), request))
+}
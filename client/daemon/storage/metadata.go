@@ -34,6 +34,8 @@ type persistentMetadata struct {
 	PieceMd5Sign  string                  `json:"pieceMd5Sign"`
 	DataFilePath  string                  `json:"dataFilePath"`
 	Done          bool                    `json:"done"`
+	URL           string                  `json:"url,omitempty"`
+	Digest        string                  `json:"digest,omitempty"`
 }
 
 type PeerTaskMetadata struct {
@@ -60,6 +62,13 @@ type RegisterTaskRequest struct {
 	ContentLength int64
 	TotalPieces   int32
 	PieceMd5Sign  string
+	// VerifyPieceMd5Sign verifies PieceMd5Sign after all pieces are written
+	VerifyPieceMd5Sign bool
+	// URL is the source url of task
+	URL string
+	// Digest is the content digest of task, eg: sha256:xxx, it's verified when task is completed,
+	// and completed tasks are indexed by it for cross-task reuse
+	Digest string
 }
 
 type WritePieceRequest struct {
@@ -76,6 +85,9 @@ type StoreRequest struct {
 	MetadataOnly bool
 	StoreOnly    bool
 	TotalPieces  int32
+	// Digest is the expected digest of the whole task content with algorithm prefix, eg: sha256:xxx or md5:xxx,
+	// it's verified when task is completed, RegisterTaskRequest.Digest is used when it's empty
+	Digest string
 }
 
 type ReadPieceRequest struct {
@@ -87,6 +99,21 @@ type ReadAllPiecesRequest struct {
 	Range *clientutil.Range
 }
 
+// lastPiecePage is the NextStartNum of the last page
+const lastPiecePage int32 = -1
+
+// PiecePage is a page of the piece infos of task
+type PiecePage struct {
+	*base.PiecePacket
+	// NextStartNum is the StartNum of the next page, it's -1 on the last page
+	NextStartNum int32
+}
+
+// Last returns whether it's the last page, no more pieces are known after it
+func (p *PiecePage) Last() bool {
+	return p.NextStartNum == lastPiecePage
+}
+
 type UpdateTaskRequest struct {
 	PeerTaskMetadata
 	ContentLength int64
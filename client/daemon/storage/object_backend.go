@@ -0,0 +1,423 @@
+/*
+ *     Copyright 2020 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage
+
+import (
+	"context"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/pkg/errors"
+
+	"d7y.io/dragonfly/v2/client/config"
+)
+
+// ObjectStorageClient is the object storage api used by the object storage backend
+type ObjectStorageClient interface {
+	// PutObject uploads the data of reader as the object
+	PutObject(ctx context.Context, key string, reader io.Reader) error
+	// GetObject returns a reader of [offset, offset+length) of the object
+	GetObject(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error)
+	// ListObjects returns all objects with the prefix
+	ListObjects(ctx context.Context, prefix string) ([]ObjectMetadata, error)
+	// DeleteObject deletes the object
+	DeleteObject(ctx context.Context, key string) error
+}
+
+// ObjectMetadata is the metadata of an object
+type ObjectMetadata struct {
+	Key  string
+	Size int64
+}
+
+// objectStorageBackend stores every written piece of a task as an object named by its offset,
+// as object storage does not support writing at offset
+type objectStorageBackend struct {
+	client ObjectStorageClient
+	// root is the local data path, it is trimmed from the key
+	root string
+	// prefix is the prefix of object keys
+	prefix string
+}
+
+var _ StorageBackend = (*objectStorageBackend)(nil)
+
+// objectPart is a written piece of the data
+type objectPart struct {
+	key    string
+	offset int64
+	size   int64
+}
+
+func newObjectStorageBackend(client ObjectStorageClient, root, prefix string) StorageBackend {
+	return &objectStorageBackend{
+		client: client,
+		root:   root,
+		prefix: prefix,
+	}
+}
+
+func (b *objectStorageBackend) objectDir(key string) string {
+	if rel, err := filepath.Rel(b.root, key); err == nil && !strings.HasPrefix(rel, "..") {
+		key = rel
+	}
+	return path.Join(b.prefix, strings.TrimPrefix(key, "/")) + "/"
+}
+
+// partKey returns the object key of the piece written at offset
+func (b *objectStorageBackend) partKey(key string, offset int64) string {
+	return b.objectDir(key) + strconv.FormatInt(offset, 10)
+}
+
+// listParts returns the written pieces of the key sorted by offset
+func (b *objectStorageBackend) listParts(ctx context.Context, key string) ([]objectPart, error) {
+	dir := b.objectDir(key)
+	objects, err := b.client.ListObjects(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var parts []objectPart
+	for _, object := range objects {
+		offset, err := strconv.ParseInt(strings.TrimPrefix(object.Key, dir), 10, 64)
+		if err != nil {
+			continue
+		}
+		parts = append(parts, objectPart{key: object.Key, offset: offset, size: object.Size})
+	}
+	sort.Slice(parts, func(i, j int) bool {
+		return parts[i].offset < parts[j].offset
+	})
+	return parts, nil
+}
+
+func (b *objectStorageBackend) WritePiece(ctx context.Context, key string, offset int64, reader io.Reader) (int64, error) {
+	cr := &countReader{reader: reader}
+	if err := b.client.PutObject(ctx, b.partKey(key, offset), cr); err != nil {
+		return cr.n, err
+	}
+	return cr.n, nil
+}
+
+func (b *objectStorageBackend) ReadPiece(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	// most reads are exactly a written piece, read the part directly without listing,
+	// the part is shorter than length when the range spans several parts, the rest is read by listing
+	if length > 0 {
+		rc, err := b.client.GetObject(ctx, b.partKey(key, offset), 0, length)
+		if err == nil {
+			return &partReader{
+				current: rc,
+				reader:  io.LimitReader(rc, length),
+				offset:  offset,
+				remain:  length,
+				readRest: func(offset, length int64) (io.ReadCloser, error) {
+					return b.readParts(ctx, key, offset, length)
+				},
+			}, nil
+		}
+	}
+	return b.readParts(ctx, key, offset, length)
+}
+
+// readParts lists the written parts of the key and reads [offset, offset+length) from them
+func (b *objectStorageBackend) readParts(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	parts, err := b.listParts(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if length < 0 {
+		length = 0
+		if len(parts) > 0 {
+			last := parts[len(parts)-1]
+			length = last.offset + last.size - offset
+		}
+	}
+
+	// trim the overlapped parts to [offset, offset+length)
+	var (
+		ranges  []objectPart
+		covered = offset
+		end     = offset + length
+	)
+	for _, part := range parts {
+		if covered >= end {
+			break
+		}
+		partEnd := part.offset + part.size
+		if partEnd <= covered {
+			continue
+		}
+		if part.offset > covered {
+			break
+		}
+		size := partEnd - covered
+		if covered+size > end {
+			size = end - covered
+		}
+		ranges = append(ranges, objectPart{key: part.key, offset: covered - part.offset, size: size})
+		covered += size
+	}
+	if covered < end {
+		return nil, errors.Wrapf(ErrPieceNotFound, "range %d-%d of %s", offset, end-1, key)
+	}
+
+	return &objectReader{ctx: ctx, client: b.client, parts: ranges}, nil
+}
+
+func (b *objectStorageBackend) Stat(ctx context.Context, key string) (*BackendStat, error) {
+	parts, err := b.listParts(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if len(parts) == 0 {
+		return nil, errors.Wrapf(os.ErrNotExist, "object %s", b.objectDir(key))
+	}
+
+	stat := &BackendStat{}
+	for _, part := range parts {
+		if part.offset+part.size > stat.Size {
+			stat.Size = part.offset + part.size
+		}
+	}
+	return stat, nil
+}
+
+func (b *objectStorageBackend) Delete(ctx context.Context, key string) error {
+	parts, err := b.listParts(ctx, key)
+	if err != nil {
+		return err
+	}
+	for _, part := range parts {
+		if err := b.client.DeleteObject(ctx, part.key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// objectReader reads the parts of objects in order, the part is opened when it is read
+type objectReader struct {
+	ctx     context.Context
+	client  ObjectStorageClient
+	parts   []objectPart
+	current io.ReadCloser
+}
+
+func (r *objectReader) Read(p []byte) (int, error) {
+	for {
+		if r.current == nil {
+			if len(r.parts) == 0 {
+				return 0, io.EOF
+			}
+			part := r.parts[0]
+			r.parts = r.parts[1:]
+			rc, err := r.client.GetObject(r.ctx, part.key, part.offset, part.size)
+			if err != nil {
+				return 0, err
+			}
+			r.current = rc
+		}
+
+		n, err := r.current.Read(p)
+		if err == io.EOF {
+			r.current.Close()
+			r.current = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
+}
+
+func (r *objectReader) Close() error {
+	if r.current == nil {
+		return nil
+	}
+	err := r.current.Close()
+	r.current = nil
+	return err
+}
+
+// partReader reads the part written at offset, and reads the rest with readRest when the part ends early
+type partReader struct {
+	current  io.Closer
+	reader   io.Reader
+	offset   int64
+	remain   int64
+	readRest func(offset, length int64) (io.ReadCloser, error)
+}
+
+func (r *partReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.offset += int64(n)
+	r.remain -= int64(n)
+	if err != io.EOF || r.remain <= 0 {
+		return n, err
+	}
+	if r.readRest == nil {
+		return n, io.ErrUnexpectedEOF
+	}
+
+	r.current.Close()
+	rc, err := r.readRest(r.offset, r.remain)
+	r.readRest = nil
+	if err != nil {
+		r.current, r.reader = io.NopCloser(nil), strings.NewReader("")
+		return n, err
+	}
+	r.current, r.reader = rc, rc
+	return n, nil
+}
+
+func (r *partReader) Close() error {
+	return r.current.Close()
+}
+
+// contextReader stops reading when the context is done
+type contextReader struct {
+	ctx    context.Context
+	reader io.Reader
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.reader.Read(p)
+}
+
+// contextReadCloser closes the body when the context is done, to abort the blocked reading
+type contextReadCloser struct {
+	io.ReadCloser
+	ctx  context.Context
+	stop chan struct{}
+	once sync.Once
+}
+
+func newContextReadCloser(ctx context.Context, rc io.ReadCloser) io.ReadCloser {
+	r := &contextReadCloser{ReadCloser: rc, ctx: ctx, stop: make(chan struct{})}
+	go func() {
+		select {
+		case <-ctx.Done():
+			r.ReadCloser.Close()
+		case <-r.stop:
+		}
+	}()
+	return r
+}
+
+func (r *contextReadCloser) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if err != nil && r.ctx.Err() != nil {
+		err = r.ctx.Err()
+	}
+	return n, err
+}
+
+func (r *contextReadCloser) Close() error {
+	r.once.Do(func() {
+		close(r.stop)
+	})
+	return r.ReadCloser.Close()
+}
+
+type countReader struct {
+	reader io.Reader
+	n      int64
+}
+
+func (r *countReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// ossClient is the ObjectStorageClient of aliyun oss, the sdk does not support context,
+// so the context is checked before every request and aborts the transferring of data
+type ossClient struct {
+	bucket *oss.Bucket
+}
+
+func newOSSClient(opt *config.ObjectStorageOption) (ObjectStorageClient, error) {
+	client, err := oss.New(opt.Endpoint, opt.AccessKeyID, opt.AccessKeySecret)
+	if err != nil {
+		return nil, err
+	}
+	bucket, err := client.Bucket(opt.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	return &ossClient{bucket: bucket}, nil
+}
+
+func (c *ossClient) PutObject(ctx context.Context, key string, reader io.Reader) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.bucket.PutObject(key, &contextReader{ctx: ctx, reader: reader})
+}
+
+func (c *ossClient) GetObject(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	// standard range behavior returns error instead of the whole object when the range is invalid
+	rc, err := c.bucket.GetObject(key, oss.Range(offset, offset+length-1), oss.RangeBehavior("standard"))
+	if err != nil {
+		return nil, err
+	}
+	return newContextReadCloser(ctx, rc), nil
+}
+
+func (c *ossClient) ListObjects(ctx context.Context, prefix string) ([]ObjectMetadata, error) {
+	var (
+		objects []ObjectMetadata
+		marker  string
+	)
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		result, err := c.bucket.ListObjects(oss.Prefix(prefix), oss.Marker(marker))
+		if err != nil {
+			return nil, err
+		}
+		for _, object := range result.Objects {
+			objects = append(objects, ObjectMetadata{Key: object.Key, Size: object.Size})
+		}
+		if !result.IsTruncated {
+			return objects, nil
+		}
+		marker = result.NextMarker
+	}
+}
+
+func (c *ossClient) DeleteObject(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.bucket.DeleteObject(key)
+}
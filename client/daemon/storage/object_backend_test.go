@@ -0,0 +1,234 @@
+/*
+ *     Copyright 2020 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	testifyassert "github.com/stretchr/testify/assert"
+
+	"d7y.io/dragonfly/v2/client/clientutil"
+	"d7y.io/dragonfly/v2/client/config"
+	"d7y.io/dragonfly/v2/pkg/rpc/base"
+)
+
+type fakeObjectStorageClient struct {
+	sync.Mutex
+	objects   map[string][]byte
+	listCount int
+}
+
+func newFakeObjectStorageClient() *fakeObjectStorageClient {
+	return &fakeObjectStorageClient{objects: map[string][]byte{}}
+}
+
+func (c *fakeObjectStorageClient) PutObject(ctx context.Context, key string, reader io.Reader) error {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	c.Lock()
+	defer c.Unlock()
+	c.objects[key] = data
+	return nil
+}
+
+func (c *fakeObjectStorageClient) GetObject(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	c.Lock()
+	defer c.Unlock()
+	data, ok := c.objects[key]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	if offset >= int64(len(data)) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	// like oss, the data is truncated when the range exceeds the object
+	if offset+length > int64(len(data)) {
+		length = int64(len(data)) - offset
+	}
+	return io.NopCloser(bytes.NewReader(data[offset : offset+length])), nil
+}
+
+func (c *fakeObjectStorageClient) ListObjects(ctx context.Context, prefix string) ([]ObjectMetadata, error) {
+	c.Lock()
+	defer c.Unlock()
+	c.listCount++
+	var objects []ObjectMetadata
+	for key, data := range c.objects {
+		if strings.HasPrefix(key, prefix) {
+			objects = append(objects, ObjectMetadata{Key: key, Size: int64(len(data))})
+		}
+	}
+	return objects, nil
+}
+
+func (c *fakeObjectStorageClient) DeleteObject(ctx context.Context, key string) error {
+	c.Lock()
+	defer c.Unlock()
+	delete(c.objects, key)
+	return nil
+}
+
+func TestObjectStorageBackend(t *testing.T) {
+	assert := testifyassert.New(t)
+	client := newFakeObjectStorageClient()
+	backend := newObjectStorageBackend(client, "/data", "dragonfly")
+	key := "/data/task/peer/data"
+	testBytes := []byte("hello dragonfly!")
+
+	// write pieces out of order and skip [8, 12)
+	for _, start := range []int{12, 0, 4} {
+		n, err := backend.WritePiece(context.Background(), key, int64(start), bytes.NewReader(testBytes[start:start+4]))
+		assert.Nil(err)
+		assert.Equal(int64(4), n)
+	}
+	_, ok := client.objects["dragonfly/task/peer/data/4"]
+	assert.True(ok)
+
+	stat, err := backend.Stat(context.Background(), key)
+	assert.Nil(err)
+	assert.Equal(int64(len(testBytes)), stat.Size)
+
+	rc, err := backend.ReadPiece(context.Background(), key, 2, 5)
+	assert.Nil(err)
+	data, err := io.ReadAll(rc)
+	assert.Nil(err)
+	assert.Nil(rc.Close())
+	assert.Equal(testBytes[2:7], data)
+
+	// read a written piece directly without listing
+	listCount := client.listCount
+	rc, err = backend.ReadPiece(context.Background(), key, 4, 4)
+	assert.Nil(err)
+	data, err = io.ReadAll(rc)
+	assert.Nil(err)
+	assert.Nil(rc.Close())
+	assert.Equal(testBytes[4:8], data)
+	assert.Equal(listCount, client.listCount)
+
+	_, err = backend.ReadPiece(context.Background(), key, 6, 4)
+	assert.ErrorIs(err, ErrPieceNotFound)
+
+	_, err = backend.WritePiece(context.Background(), key, 8, bytes.NewReader(testBytes[8:12]))
+	assert.Nil(err)
+	rc, err = backend.ReadPiece(context.Background(), key, 0, -1)
+	assert.Nil(err)
+	data, err = io.ReadAll(rc)
+	assert.Nil(err)
+	assert.Equal(testBytes, data)
+
+	// read multiple parts starting at the boundary of a part
+	rc, err = backend.ReadPiece(context.Background(), key, 4, 10)
+	assert.Nil(err)
+	data, err = io.ReadAll(rc)
+	assert.Nil(err)
+	assert.Nil(rc.Close())
+	assert.Equal(testBytes[4:14], data)
+
+	assert.Nil(backend.Delete(context.Background(), key))
+	assert.Len(client.objects, 0)
+	_, err = backend.Stat(context.Background(), key)
+	assert.ErrorIs(err, os.ErrNotExist)
+}
+
+func TestStorageManager_ObjectStorageBackend(t *testing.T) {
+	assert := testifyassert.New(t)
+	client := newFakeObjectStorageClient()
+	dataPath := t.TempDir()
+	sm, err := NewStorageManager(config.ObjectStorageTaskStoreStrategy,
+		&config.StorageOption{
+			DataPath: dataPath,
+			TaskExpireTime: clientutil.Duration{
+				Duration: time.Minute,
+			},
+		}, func(request CommonTaskRequest) {
+		}, WithStorageBackend(newObjectStorageBackend(client, dataPath, "")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testBytes := []byte("hello dragonfly!")
+	meta := PeerTaskMetadata{PeerID: "peer-object", TaskID: "task-object"}
+	ts, err := sm.RegisterTask(context.Background(), RegisterTaskRequest{
+		CommonTaskRequest: CommonTaskRequest{
+			PeerID:      meta.PeerID,
+			TaskID:      meta.TaskID,
+			Destination: path.Join(t.TempDir(), "output"),
+		},
+		ContentLength: int64(len(testBytes)),
+		TotalPieces:   2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for num := 0; num < 2; num++ {
+		start := num * 8
+		_, err := ts.WritePiece(context.Background(), &WritePieceRequest{
+			PeerTaskMetadata: meta,
+			PieceMetadata: PieceMetadata{
+				Num: int32(num),
+				Range: clientutil.Range{
+					Start:  int64(start),
+					Length: 8,
+				},
+				Style: base.PieceStyle_PLAIN,
+			},
+			Reader: bytes.NewReader(testBytes[start : start+8]),
+		})
+		assert.Nil(err)
+	}
+	assert.Len(client.objects, 2)
+
+	// no data is stored in local disk
+	_, err = os.Stat(path.Join(dataPath, meta.TaskID, meta.PeerID, taskData))
+	assert.True(os.IsNotExist(err))
+
+	reader, closer, err := sm.ReadPiece(context.Background(), &ReadPieceRequest{
+		PeerTaskMetadata: meta,
+		PieceMetadata:    PieceMetadata{Num: 1},
+	})
+	assert.Nil(err)
+	data, err := io.ReadAll(reader)
+	assert.Nil(err)
+	assert.Nil(closer.Close())
+	assert.Equal(testBytes[8:], data)
+
+	destination := path.Join(t.TempDir(), "output")
+	assert.Nil(sm.Store(context.Background(), &StoreRequest{
+		CommonTaskRequest: CommonTaskRequest{
+			PeerID:      meta.PeerID,
+			TaskID:      meta.TaskID,
+			Destination: destination,
+		},
+	}))
+	data, err = os.ReadFile(destination)
+	assert.Nil(err)
+	assert.Equal(testBytes, data)
+
+	assert.Nil(ts.(Reclaimer).Reclaim())
+	assert.Len(client.objects, 0)
+}
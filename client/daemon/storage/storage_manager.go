@@ -24,6 +24,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"runtime"
 	"sort"
 	"strings"
 	"sync"
@@ -39,7 +40,9 @@ import (
 	"d7y.io/dragonfly/v2/client/clientutil"
 	"d7y.io/dragonfly/v2/client/config"
 	"d7y.io/dragonfly/v2/client/daemon/gc"
+	"d7y.io/dragonfly/v2/client/daemon/metrics"
 	logger "d7y.io/dragonfly/v2/internal/dflog"
+	"d7y.io/dragonfly/v2/internal/util"
 	"d7y.io/dragonfly/v2/pkg/rpc/base"
 )
 
@@ -55,8 +58,16 @@ type TaskStorageDriver interface {
 
 	ReadAllPieces(ctx context.Context, req *ReadAllPiecesRequest) (io.ReadCloser, error)
 
+	// ReadPiecesRange get a reader of [start, start+length) from the pieces overlapping the range,
+	// it returns ErrPieceNotFound when any overlapping piece is not stored, caller should read bytes and close it.
+	ReadPiecesRange(ctx context.Context, req *PeerTaskMetadata, start, length int64) (io.ReadCloser, error)
+
 	GetPieces(ctx context.Context, req *base.PieceTaskRequest) (*base.PiecePacket, error)
 
+	// GetPiecesPage returns a page of the piece infos in [StartNum, StartNum+Limit),
+	// callers page through all pieces with PiecePage.NextStartNum
+	GetPiecesPage(ctx context.Context, req *base.PieceTaskRequest) (*PiecePage, error)
+
 	UpdateTask(ctx context.Context, req *UpdateTaskRequest) error
 
 	// Store stores task data to the target path
@@ -85,8 +96,17 @@ type Manager interface {
 	clientutil.KeepAlive
 	// RegisterTask registers a task in storage driver
 	RegisterTask(ctx context.Context, req RegisterTaskRequest) (TaskStorageDriver, error)
+	// UnregisterTask removes a task from storage driver and reclaims its data immediately,
+	// the gc callback is not invoked as the caller leaves the task itself
+	UnregisterTask(ctx context.Context, req CommonTaskRequest) error
 	// FindCompletedTask try to find a completed task for fast path
 	FindCompletedTask(taskID string) *ReusePeerTask
+	// FindReusableTask try to find a completed task with the same content digest,
+	// the task of the same url is preferred
+	FindReusableTask(url, digest string) *ReusePeerTask
+	// GetReusablePieces returns the pieces of req task covered by the data of a completed task with the same content digest,
+	// TaskId and DstPid of the returned packet are the completed task to read the data by range
+	GetReusablePieces(ctx context.Context, req *base.PieceTaskRequest, digest string) (*base.PiecePacket, error)
 	// CleanUp cleans all storage data
 	CleanUp()
 }
@@ -97,6 +117,11 @@ var (
 	ErrPieceCountNotSet = errors.New("total piece count not set")
 	ErrDigestNotSet     = errors.New("digest not set")
 	ErrInvalidDigest    = errors.New("invalid digest")
+	// ErrPieceMd5SignNotMatch is returned when the aggregate piece md5 sign mismatches,
+	// the per piece md5 mismatch is digestutils.ErrDigestNotMatch
+	ErrPieceMd5SignNotMatch = errors.New("piece md5 sign not match")
+	// ErrContentDigestNotMatch is returned when the digest of the whole task content mismatches
+	ErrContentDigestNotMatch = errors.New("content digest not match")
 )
 
 const (
@@ -116,11 +141,31 @@ type storageManager struct {
 	storeOption        *config.StorageOption
 	tasks              sync.Map
 	markedReclaimTasks []PeerTaskMetadata
+	// watermarkMarkedTasks are marked by disk watermark and reclaimed in the next watermark loop
+	watermarkMarkedTasks []PeerTaskMetadata
+	// gcLock serializes gc and disk watermark reclaiming
+	gcLock             sync.Mutex
 	dataPathStat       *syscall.Stat_t
 	gcCallback         func(CommonTaskRequest)
 	gcInterval         time.Duration
 	indexRWMutex       sync.RWMutex
 	indexTask2PeerTask map[string][]*localTaskStore // key: task id, value: slice of localTaskStore
+	// key: content digest, value: slice of completed localTaskStore
+	indexDigest2PeerTask map[string][]*localTaskStore
+	verifyPieceMd5Sign   bool
+	diskUsage            func(path string) (*disk.UsageStat, error)
+	// digestConcurrency is the count of pieces hashed concurrently when validating data
+	digestConcurrency int
+	// backend stores the data of all tasks
+	backend StorageBackend
+	// validating waits for validating the digest of reloaded tasks
+	validating sync.WaitGroup
+	// onIdle is called when there is no Keep within idleAlive
+	onIdle    func()
+	idleAlive time.Duration
+	// done is closed by CleanUp to stop the background goroutines
+	done     chan struct{}
+	doneOnce sync.Once
 }
 
 var _ gc.GC = (*storageManager)(nil)
@@ -147,7 +192,7 @@ func NewStorageManager(storeStrategy config.StoreStrategy, opt *config.StorageOp
 		return nil, err
 	}
 	switch storeStrategy {
-	case config.SimpleLocalTaskStoreStrategy, config.AdvanceLocalTaskStoreStrategy:
+	case config.SimpleLocalTaskStoreStrategy, config.AdvanceLocalTaskStoreStrategy, config.ObjectStorageTaskStoreStrategy:
 	case config.StoreStrategy(""):
 		storeStrategy = config.SimpleLocalTaskStoreStrategy
 	default:
@@ -155,13 +200,20 @@ func NewStorageManager(storeStrategy config.StoreStrategy, opt *config.StorageOp
 	}
 
 	s := &storageManager{
-		KeepAlive:          clientutil.NewKeepAlive("storage manager"),
-		storeStrategy:      storeStrategy,
-		storeOption:        opt,
-		dataPathStat:       stat.Sys().(*syscall.Stat_t),
-		gcCallback:         gcCallback,
-		gcInterval:         time.Minute,
-		indexTask2PeerTask: map[string][]*localTaskStore{},
+		KeepAlive:            clientutil.NewKeepAlive("storage manager"),
+		storeStrategy:        storeStrategy,
+		storeOption:          opt,
+		dataPathStat:         stat.Sys().(*syscall.Stat_t),
+		gcCallback:           gcCallback,
+		gcInterval:           time.Minute,
+		indexTask2PeerTask:   map[string][]*localTaskStore{},
+		indexDigest2PeerTask: map[string][]*localTaskStore{},
+		diskUsage:            disk.Usage,
+		digestConcurrency:    opt.ValidateDigestConcurrency,
+		done:                 make(chan struct{}),
+	}
+	if s.digestConcurrency <= 0 {
+		s.digestConcurrency = runtime.NumCPU()
 	}
 
 	for _, o := range moreOpts {
@@ -170,11 +222,23 @@ func NewStorageManager(storeStrategy config.StoreStrategy, opt *config.StorageOp
 		}
 	}
 
+	if s.backend == nil {
+		if s.backend, err = newStorageBackend(storeStrategy, s.storeOption); err != nil {
+			return nil, err
+		}
+	}
+
 	if err := s.ReloadPersistentTask(gcCallback); err != nil {
 		logger.Warnf("reload tasks error: %s", err)
 	}
 
 	gc.Register(GCName, s)
+	if s.storeOption.DiskHighWatermarkPercent > 0 {
+		go s.watchDiskWatermark()
+	}
+	if s.onIdle != nil && s.idleAlive > 0 {
+		go s.watchIdle()
+	}
 	return s, nil
 }
 
@@ -192,6 +256,45 @@ func WithGCInterval(gcInterval time.Duration) func(*storageManager) error {
 	}
 }
 
+// WithVerifyPieceMd5Sign verifies piece md5 sign of all tasks after all pieces are written,
+// RegisterTaskRequest.VerifyPieceMd5Sign verifies a single task
+func WithVerifyPieceMd5Sign(verify bool) func(*storageManager) error {
+	return func(manager *storageManager) error {
+		manager.verifyPieceMd5Sign = verify
+		return nil
+	}
+}
+
+// WithOnIdle calls onIdle once when there is no Keep within alive, it is stopped by CleanUp
+func WithOnIdle(alive time.Duration, onIdle func()) func(*storageManager) error {
+	return func(manager *storageManager) error {
+		manager.idleAlive = alive
+		manager.onIdle = onIdle
+		return nil
+	}
+}
+
+// newStorageBackend returns the StorageBackend of store strategy
+func newStorageBackend(storeStrategy config.StoreStrategy, opt *config.StorageOption) (StorageBackend, error) {
+	if storeStrategy != config.ObjectStorageTaskStoreStrategy {
+		return newLocalBackend(), nil
+	}
+
+	client, err := newOSSClient(&opt.ObjectStorage)
+	if err != nil {
+		return nil, err
+	}
+	return newObjectStorageBackend(client, opt.DataPath, opt.ObjectStorage.Prefix), nil
+}
+
+// WithStorageBackend stores task data in the backend instead of the one of store strategy
+func WithStorageBackend(backend StorageBackend) func(*storageManager) error {
+	return func(manager *storageManager) error {
+		manager.backend = backend
+		return nil
+	}
+}
+
 func (s *storageManager) RegisterTask(ctx context.Context, req RegisterTaskRequest) (TaskStorageDriver, error) {
 	ts, ok := s.LoadTask(
 		PeerTaskMetadata{
@@ -254,6 +357,14 @@ func (s *storageManager) ReadAllPieces(ctx context.Context, req *ReadAllPiecesRe
 	return t.(TaskStorageDriver).ReadAllPieces(ctx, req)
 }
 
+func (s *storageManager) ReadPiecesRange(ctx context.Context, req *PeerTaskMetadata, start, length int64) (io.ReadCloser, error) {
+	t, ok := s.LoadTask(*req)
+	if !ok {
+		return nil, ErrTaskNotFound
+	}
+	return t.(TaskStorageDriver).ReadPiecesRange(ctx, req, start, length)
+}
+
 func (s *storageManager) Store(ctx context.Context, req *StoreRequest) error {
 	t, ok := s.LoadTask(
 		PeerTaskMetadata{
@@ -264,7 +375,14 @@ func (s *storageManager) Store(ctx context.Context, req *StoreRequest) error {
 		// TODO recover for local task persistentMetadata data
 		return ErrTaskNotFound
 	}
-	return t.(TaskStorageDriver).Store(ctx, req)
+	if err := t.(TaskStorageDriver).Store(ctx, req); err != nil {
+		return err
+	}
+
+	s.indexRWMutex.Lock()
+	s.indexDigest(t.(*localTaskStore))
+	s.indexRWMutex.Unlock()
+	return nil
 }
 
 func (s *storageManager) GetPieces(ctx context.Context, req *base.PieceTaskRequest) (*base.PiecePacket, error) {
@@ -279,6 +397,18 @@ func (s *storageManager) GetPieces(ctx context.Context, req *base.PieceTaskReque
 	return t.(TaskStorageDriver).GetPieces(ctx, req)
 }
 
+func (s *storageManager) GetPiecesPage(ctx context.Context, req *base.PieceTaskRequest) (*PiecePage, error) {
+	t, ok := s.LoadTask(
+		PeerTaskMetadata{
+			TaskID: req.TaskId,
+			PeerID: req.DstPid,
+		})
+	if !ok {
+		return nil, ErrTaskNotFound
+	}
+	return t.(TaskStorageDriver).GetPiecesPage(ctx, req)
+}
+
 func (s *storageManager) LoadTask(meta PeerTaskMetadata) (TaskStorageDriver, bool) {
 	s.Keep()
 	d, ok := s.tasks.Load(meta)
@@ -288,6 +418,28 @@ func (s *storageManager) LoadTask(meta PeerTaskMetadata) (TaskStorageDriver, boo
 	return d.(TaskStorageDriver), ok
 }
 
+func (s *storageManager) UnregisterTask(ctx context.Context, req CommonTaskRequest) error {
+	key := PeerTaskMetadata{
+		TaskID: req.TaskID,
+		PeerID: req.PeerID,
+	}
+	t, ok := s.tasks.LoadAndDelete(key)
+	if !ok {
+		return ErrTaskNotFound
+	}
+	s.cleanIndex(req.TaskID, req.PeerID)
+
+	task := t.(*localTaskStore)
+	// mark reclaimed without gc callback, the task is skipped by gc
+	task.reclaimMarked.Store(true)
+	if err := task.Reclaim(); err != nil {
+		logger.Errorf("unregister task %s/%s, reclaim error: %s", req.TaskID, req.PeerID, err)
+		return err
+	}
+	logger.Infof("task %s/%s unregistered and reclaimed", req.TaskID, req.PeerID)
+	return nil
+}
+
 func (s *storageManager) UpdateTask(ctx context.Context, req *UpdateTaskRequest) error {
 	t, ok := s.LoadTask(
 		PeerTaskMetadata{
@@ -315,11 +467,16 @@ func (s *storageManager) CreateTask(req RegisterTaskRequest) (TaskStorageDriver,
 			PieceMd5Sign:  req.PieceMd5Sign,
 			PeerID:        req.PeerID,
 			Pieces:        map[int32]PieceMetadata{},
+			URL:           req.URL,
+			Digest:        req.Digest,
 		},
-		gcCallback:       s.gcCallback,
-		dataDir:          dataDir,
-		metadataFilePath: path.Join(dataDir, taskMetadata),
-		expireTime:       s.storeOption.TaskExpireTime.Duration,
+		gcCallback:         s.gcCallback,
+		dataDir:            dataDir,
+		verifyPieceMd5Sign: s.verifyPieceMd5Sign || req.VerifyPieceMd5Sign,
+		digestConcurrency:  s.digestConcurrency,
+		backend:            s.backend,
+		metadataFilePath:   path.Join(dataDir, taskMetadata),
+		expireTime:         s.storeOption.TaskExpireTime.Duration,
 
 		SugaredLoggerOnWith: logger.With("task", req.TaskID, "peer", req.PeerID, "component", "localTaskStore"),
 	}
@@ -334,11 +491,14 @@ func (s *storageManager) CreateTask(req RegisterTaskRequest) (TaskStorageDriver,
 	t.metadataFile = metadata
 
 	// fallback to simple strategy for proxy
-	if req.Destination == "" {
+	if req.Destination == "" && t.StoreStrategy == string(config.AdvanceLocalTaskStoreStrategy) {
 		t.StoreStrategy = string(config.SimpleLocalTaskStoreStrategy)
 	}
 	data := path.Join(dataDir, taskData)
 	switch t.StoreStrategy {
+	case string(config.ObjectStorageTaskStoreStrategy):
+		// the data is written to object storage by pieces, nothing to create in local disk
+		t.DataFilePath = data
 	case string(config.SimpleLocalTaskStoreStrategy):
 		t.DataFilePath = data
 		f, err := os.OpenFile(t.DataFilePath, os.O_CREATE|os.O_RDWR, defaultFileMode)
@@ -431,6 +591,138 @@ func (s *storageManager) FindCompletedTask(taskID string) *ReusePeerTask {
 	return nil
 }
 
+func (s *storageManager) FindReusableTask(url, digest string) *ReusePeerTask {
+	if digest == "" {
+		return nil
+	}
+
+	s.indexRWMutex.RLock()
+	defer s.indexRWMutex.RUnlock()
+	var reuse *localTaskStore
+	for _, t := range s.indexDigest2PeerTask[digest] {
+		if t.invalid.Load() || t.reclaimMarked.Load() || !t.Done {
+			continue
+		}
+
+		if reuse == nil || (t.URL == url && reuse.URL != url) {
+			reuse = t
+		}
+	}
+	if reuse == nil {
+		return nil
+	}
+
+	reuse.touch()
+	return &ReusePeerTask{
+		PeerTaskMetadata: PeerTaskMetadata{
+			PeerID: reuse.PeerID,
+			TaskID: reuse.TaskID,
+		},
+		ContentLength: reuse.ContentLength,
+		TotalPieces:   reuse.TotalPieces,
+	}
+}
+
+func (s *storageManager) GetReusablePieces(ctx context.Context, req *base.PieceTaskRequest, digest string) (*base.PiecePacket, error) {
+	if digest == "" {
+		return nil, ErrDigestNotSet
+	}
+
+	s.indexRWMutex.RLock()
+	var candidates []*localTaskStore
+	for _, t := range s.indexDigest2PeerTask[digest] {
+		if t.invalid.Load() || t.reclaimMarked.Load() || !t.Done {
+			continue
+		}
+		if t.TaskID == req.TaskId && t.PeerID == req.SrcPid {
+			continue
+		}
+		candidates = append(candidates, t)
+	}
+	s.indexRWMutex.RUnlock()
+
+	var reusePacket *base.PiecePacket
+	for _, t := range candidates {
+		t.touch()
+		pieceSize := s.reusePieceSize(req, t.ContentLength)
+		packet := &base.PiecePacket{
+			TaskId:        t.TaskID,
+			DstPid:        t.PeerID,
+			ContentLength: t.ContentLength,
+		}
+		if t.ContentLength > 0 {
+			packet.TotalPiece = int32((t.ContentLength + pieceSize - 1) / pieceSize)
+		}
+
+		pieces := t.sortedPieces()
+		for num := int64(req.StartNum); num < int64(req.StartNum)+int64(req.Limit); num++ {
+			start := num * pieceSize
+			if t.ContentLength >= 0 && start >= t.ContentLength {
+				break
+			}
+			length := pieceSize
+			if t.ContentLength >= 0 && start+length > t.ContentLength {
+				length = t.ContentLength - start
+			}
+
+			piece, ok := coverRange(pieces, start, length)
+			if !ok {
+				continue
+			}
+			pieceInfo := &base.PieceInfo{
+				PieceNum:    int32(num),
+				RangeStart:  uint64(start),
+				RangeSize:   uint32(length),
+				PieceOffset: uint64(start),
+				PieceStyle:  base.PieceStyle_PLAIN,
+			}
+			// reuse the md5 only when the piece is aligned, otherwise caller should calculate it
+			if piece != nil {
+				pieceInfo.PieceMd5 = piece.Md5
+			}
+			packet.PieceInfos = append(packet.PieceInfos, pieceInfo)
+		}
+
+		if reusePacket == nil || len(packet.PieceInfos) > len(reusePacket.PieceInfos) {
+			reusePacket = packet
+		}
+	}
+
+	if reusePacket == nil || len(reusePacket.PieceInfos) == 0 {
+		return nil, ErrPieceNotFound
+	}
+	return reusePacket, nil
+}
+
+// reusePieceSize returns the piece size of req task, it is the length of the first piece stored
+// in req task, or computed by content length when the first piece is not stored yet
+func (s *storageManager) reusePieceSize(req *base.PieceTaskRequest, contentLength int64) int64 {
+	if t, ok := s.LoadTask(PeerTaskMetadata{PeerID: req.SrcPid, TaskID: req.TaskId}); ok {
+		ts := t.(*localTaskStore)
+		ts.RLock()
+		piece, ok := ts.Pieces[0]
+		ts.RUnlock()
+		if ok && piece.Range.Length > 0 {
+			return piece.Range.Length
+		}
+	}
+	return int64(util.ComputePieceSize(contentLength))
+}
+
+// indexDigest indexes the completed task by content digest, caller should hold indexRWMutex
+func (s *storageManager) indexDigest(t *localTaskStore) {
+	if !t.Done || t.Digest == "" {
+		return
+	}
+
+	for _, indexed := range s.indexDigest2PeerTask[t.Digest] {
+		if indexed == t {
+			return
+		}
+	}
+	s.indexDigest2PeerTask[t.Digest] = append(s.indexDigest2PeerTask[t.Digest], t)
+}
+
 func (s *storageManager) cleanIndex(taskID, peerID string) {
 	s.indexRWMutex.Lock()
 	defer s.indexRWMutex.Unlock()
@@ -443,6 +735,7 @@ func (s *storageManager) cleanIndex(taskID, peerID string) {
 	for _, t := range ts {
 		if t.PeerID == peerID {
 			logger.Debugf("clean index for %s/%s", taskID, peerID)
+			s.cleanDigestIndex(t)
 			continue
 		}
 		remain = append(remain, t)
@@ -450,6 +743,25 @@ func (s *storageManager) cleanIndex(taskID, peerID string) {
 	s.indexTask2PeerTask[taskID] = remain
 }
 
+// cleanDigestIndex removes the task from content digest index, caller should hold indexRWMutex
+func (s *storageManager) cleanDigestIndex(t *localTaskStore) {
+	if t.Digest == "" {
+		return
+	}
+
+	var remain []*localTaskStore
+	for _, indexed := range s.indexDigest2PeerTask[t.Digest] {
+		if indexed != t {
+			remain = append(remain, indexed)
+		}
+	}
+	if len(remain) == 0 {
+		delete(s.indexDigest2PeerTask, t.Digest)
+		return
+	}
+	s.indexDigest2PeerTask[t.Digest] = remain
+}
+
 func (s *storageManager) ValidateDigest(req *PeerTaskMetadata) error {
 	t, ok := s.LoadTask(
 		PeerTaskMetadata{
@@ -485,6 +797,7 @@ func (s *storageManager) ReloadPersistentTask(gcCallback GCCallback) error {
 	var (
 		loadErrs    []error
 		loadErrDirs []string
+		loadedTasks []*localTaskStore
 	)
 	for _, dir := range dirs {
 		taskID := dir.Name()
@@ -513,6 +826,8 @@ func (s *storageManager) ReloadPersistentTask(gcCallback GCCallback) error {
 				dataDir:             dataDir,
 				metadataFilePath:    path.Join(dataDir, taskMetadata),
 				expireTime:          s.storeOption.TaskExpireTime.Duration,
+				digestConcurrency:   s.digestConcurrency,
+				backend:             s.backend,
 				gcCallback:          gcCallback,
 				SugaredLoggerOnWith: logger.With("task", taskID, "peer", peerID, "component", s.storeStrategy),
 			}
@@ -541,6 +856,17 @@ func (s *storageManager) ReloadPersistentTask(gcCallback GCCallback) error {
 					Warnf("load task from disk error: %s", err0)
 				continue
 			}
+
+			// the data of completed task must exist in backend
+			if t.Done {
+				if _, err0 = s.backend.Stat(context.Background(), t.DataFilePath); err0 != nil {
+					loadErrs = append(loadErrs, err0)
+					loadErrDirs = append(loadErrDirs, dataDir)
+					logger.With("action", "reload", "stage", "stat data", "taskID", taskID, "peerID", peerID).
+						Warnf("load task data from backend error: %s", err0)
+					continue
+				}
+			}
 			logger.Debugf("load task %s/%s from disk, metadata %s, last access: %v, expire time: %s",
 				t.persistentMetadata.TaskID, t.persistentMetadata.PeerID, t.metadataFilePath, time.Unix(0, t.lastAccess.Load()), t.expireTime)
 			s.tasks.Store(PeerTaskMetadata{
@@ -555,8 +881,15 @@ func (s *storageManager) ReloadPersistentTask(gcCallback GCCallback) error {
 			} else {
 				s.indexTask2PeerTask[taskID] = []*localTaskStore{t}
 			}
+			s.indexDigest(t)
+			loadedTasks = append(loadedTasks, t)
 		}
 	}
+
+	// validate the data of completed tasks in background, the corrupted tasks will be marked invalid
+	s.validating.Add(1)
+	go s.validateLoadedTasks(loadedTasks)
+
 	// remove load error peer tasks
 	for _, dir := range loadErrDirs {
 		// remove metadata
@@ -601,7 +934,24 @@ func (s *storageManager) ReloadPersistentTask(gcCallback GCCallback) error {
 	return nil
 }
 
+func (s *storageManager) validateLoadedTasks(tasks []*localTaskStore) {
+	defer s.validating.Done()
+	for _, t := range tasks {
+		if !t.Done || t.PieceMd5Sign == "" || t.TotalPieces <= 0 {
+			continue
+		}
+		if err := t.validateData(); err != nil {
+			t.Warnf("validate reloaded task error: %s, mark it invalid", err)
+			continue
+		}
+		t.Debugf("validate reloaded task ok")
+	}
+}
+
 func (s *storageManager) TryGC() (bool, error) {
+	s.gcLock.Lock()
+	defer s.gcLock.Unlock()
+
 	var markedTasks []PeerTaskMetadata
 	var totalNotMarkedSize int64
 	s.tasks.Range(func(key, task interface{}) bool {
@@ -665,25 +1015,9 @@ func (s *storageManager) TryGC() (bool, error) {
 	}
 
 	for _, key := range s.markedReclaimTasks {
-		t, ok := s.tasks.Load(key)
-		if !ok {
-			continue
-		}
-		task := t.(*localTaskStore)
-		_, span := tracer.Start(context.Background(), config.SpanPeerGC)
-		span.SetAttributes(config.AttributePeerID.String(task.PeerID))
-		span.SetAttributes(config.AttributeTaskID.String(task.TaskID))
-
-		s.tasks.Delete(key)
-		s.cleanIndex(task.TaskID, task.PeerID)
-		if err := task.Reclaim(); err != nil {
-			// FIXME: retry later or push to queue
-			logger.Errorf("gc task %s/%s error: %s", key.TaskID, key.PeerID, err)
-			span.RecordError(err)
-			span.End()
+		if _, ok := s.reclaimMarkedTask(key); !ok {
 			continue
 		}
-		logger.Infof("task %s/%s reclaimed", key.TaskID, key.PeerID)
 		// remove reclaimed task in markedTasks
 		for i, k := range markedTasks {
 			if k.TaskID == key.TaskID && k.PeerID == key.PeerID {
@@ -691,14 +1025,41 @@ func (s *storageManager) TryGC() (bool, error) {
 				break
 			}
 		}
-		span.End()
 	}
 	logger.Infof("marked %d task(s), reclaimed %d task(s)", len(markedTasks), len(s.markedReclaimTasks))
 	s.markedReclaimTasks = markedTasks
 	return true, nil
 }
 
+// reclaimMarkedTask deletes the marked task from storage manager and reclaims its data,
+// returns the reclaimed bytes and whether the task is reclaimed
+func (s *storageManager) reclaimMarkedTask(key PeerTaskMetadata) (int64, bool) {
+	t, ok := s.tasks.Load(key)
+	if !ok {
+		return 0, false
+	}
+	task := t.(*localTaskStore)
+	_, span := tracer.Start(context.Background(), config.SpanPeerGC)
+	defer span.End()
+	span.SetAttributes(config.AttributePeerID.String(task.PeerID))
+	span.SetAttributes(config.AttributeTaskID.String(task.TaskID))
+
+	s.tasks.Delete(key)
+	s.cleanIndex(task.TaskID, task.PeerID)
+	if err := task.Reclaim(); err != nil {
+		// FIXME: retry later or push to queue
+		logger.Errorf("gc task %s/%s error: %s", key.TaskID, key.PeerID, err)
+		span.RecordError(err)
+		return 0, false
+	}
+	logger.Infof("task %s/%s reclaimed", key.TaskID, key.PeerID)
+	return task.ContentLength, true
+}
+
 func (s *storageManager) CleanUp() {
+	s.doneOnce.Do(func() {
+		close(s.done)
+	})
 	_, _ = s.forceGC()
 }
 
@@ -717,6 +1078,115 @@ func (s *storageManager) forceGC() (bool, error) {
 	return true, nil
 }
 
+// watchDiskWatermark reclaims tasks periodically when the disk usage is above the high watermark
+func (s *storageManager) watchDiskWatermark() {
+	interval := s.storeOption.DiskWatermarkInterval.Duration
+	if interval <= 0 {
+		interval = defaultDiskWatermarkInterval
+	}
+
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-tick.C:
+			s.reclaimByDiskWatermark()
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// watchIdle calls onIdle when the storage manager is not alive
+func (s *storageManager) watchIdle() {
+	interval := s.idleAlive / idleCheckTimes
+	if interval <= 0 {
+		interval = s.idleAlive
+	}
+
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-tick.C:
+			if !s.Alive(s.idleAlive) {
+				logger.Infof("storage manager is idle for %s", s.idleAlive)
+				s.onIdle()
+				return
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// reclaimByDiskWatermark reclaims the tasks marked in the last loop, then marks the least recently
+// used reclaimable tasks until the disk usage is below the low watermark when it is above the high
+// watermark, like TryGC, the marked tasks are reclaimed in the next loop, returns the reclaimed bytes
+func (s *storageManager) reclaimByDiskWatermark() int64 {
+	s.gcLock.Lock()
+	defer s.gcLock.Unlock()
+
+	var reclaimed int64
+	for _, key := range s.watermarkMarkedTasks {
+		size, ok := s.reclaimMarkedTask(key)
+		if !ok {
+			continue
+		}
+		reclaimed += size
+		metrics.StorageReclaimedBytesCount.Add(float64(size))
+	}
+	s.watermarkMarkedTasks = nil
+
+	usage, err := s.diskUsage(s.storeOption.DataPath)
+	if err != nil {
+		logger.Warnf("get %s disk usage error: %s", s.storeOption.DataPath, err)
+		return reclaimed
+	}
+
+	if usage.UsedPercent < s.storeOption.DiskHighWatermarkPercent {
+		return reclaimed
+	}
+
+	bytesExceed := int64((usage.UsedPercent - s.storeOption.DiskLowWatermarkPercent) / 100 * float64(usage.Total))
+	logger.Infof("disk used percent %f exceeds high watermark %f, %d bytes to reclaim",
+		usage.UsedPercent, s.storeOption.DiskHighWatermarkPercent, bytesExceed)
+
+	var tasks []*localTaskStore
+	s.tasks.Range(func(key, val interface{}) bool {
+		task := val.(*localTaskStore)
+		// skip running, reclaimed and not expired tasks
+		if !task.Done || task.reclaimMarked.Load() || !task.CanReclaim() {
+			return true
+		}
+		tasks = append(tasks, task)
+		return true
+	})
+
+	// sort by access time
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return tasks[i].lastAccess.Load() < tasks[j].lastAccess.Load()
+	})
+
+	var marked int64
+	for _, task := range tasks {
+		if marked >= bytesExceed {
+			break
+		}
+		task.MarkReclaim()
+		s.watermarkMarkedTasks = append(s.watermarkMarkedTasks, PeerTaskMetadata{PeerID: task.PeerID, TaskID: task.TaskID})
+		marked += task.ContentLength
+		logger.Infof("disk watermark reached, mark task %s/%s reclaimed, last access: %s, size: %s",
+			task.TaskID, task.PeerID, time.Unix(0, task.lastAccess.Load()).Format(time.RFC3339Nano),
+			units.BytesSize(float64(task.ContentLength)))
+	}
+
+	if marked < bytesExceed {
+		logger.Warnf("no enough tasks to reclaim by disk watermark, remind %d bytes", bytesExceed-marked)
+	}
+	return reclaimed
+}
+
 func (s *storageManager) diskUsageExceed() (exceed bool, bytes int64) {
 	if s.storeOption.DiskGCThresholdPercent <= 0 {
 		return false, 0
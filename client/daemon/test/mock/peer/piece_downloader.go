@@ -51,3 +51,18 @@ func (mr *MockPieceDownloaderMockRecorder) DownloadPiece(arg0, arg1 interface{})
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DownloadPiece", reflect.TypeOf((*MockPieceDownloader)(nil).DownloadPiece), arg0, arg1)
 }
+
+// HasPiece mocks base method.
+func (m *MockPieceDownloader) HasPiece(arg0 context.Context, arg1 *peer.DownloadPieceRequest) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HasPiece", arg0, arg1)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// HasPiece indicates an expected call of HasPiece.
+func (mr *MockPieceDownloaderMockRecorder) HasPiece(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HasPiece", reflect.TypeOf((*MockPieceDownloader)(nil).HasPiece), arg0, arg1)
+}
@@ -53,6 +53,21 @@ func (mr *MockTaskStorageDriverMockRecorder) GetPieces(ctx, req interface{}) *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPieces", reflect.TypeOf((*MockTaskStorageDriver)(nil).GetPieces), ctx, req)
 }
 
+// GetPiecesPage mocks base method.
+func (m *MockTaskStorageDriver) GetPiecesPage(ctx context.Context, req *base.PieceTaskRequest) (*storage.PiecePage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPiecesPage", ctx, req)
+	ret0, _ := ret[0].(*storage.PiecePage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPiecesPage indicates an expected call of GetPiecesPage.
+func (mr *MockTaskStorageDriverMockRecorder) GetPiecesPage(ctx, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPiecesPage", reflect.TypeOf((*MockTaskStorageDriver)(nil).GetPiecesPage), ctx, req)
+}
+
 // IsInvalid mocks base method.
 func (m *MockTaskStorageDriver) IsInvalid(req *storage.PeerTaskMetadata) (bool, error) {
 	m.ctrl.T.Helper()
@@ -99,6 +114,21 @@ func (mr *MockTaskStorageDriverMockRecorder) ReadPiece(ctx, req interface{}) *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadPiece", reflect.TypeOf((*MockTaskStorageDriver)(nil).ReadPiece), ctx, req)
 }
 
+// ReadPiecesRange mocks base method.
+func (m *MockTaskStorageDriver) ReadPiecesRange(ctx context.Context, req *storage.PeerTaskMetadata, start, length int64) (io.ReadCloser, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadPiecesRange", ctx, req, start, length)
+	ret0, _ := ret[0].(io.ReadCloser)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadPiecesRange indicates an expected call of ReadPiecesRange.
+func (mr *MockTaskStorageDriverMockRecorder) ReadPiecesRange(ctx, req, start, length interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadPiecesRange", reflect.TypeOf((*MockTaskStorageDriver)(nil).ReadPiecesRange), ctx, req, start, length)
+}
+
 // Store mocks base method.
 func (m *MockTaskStorageDriver) Store(ctx context.Context, req *storage.StoreRequest) error {
 	m.ctrl.T.Helper()
@@ -282,6 +312,20 @@ func (mr *MockManagerMockRecorder) FindCompletedTask(taskID interface{}) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindCompletedTask", reflect.TypeOf((*MockManager)(nil).FindCompletedTask), taskID)
 }
 
+// FindReusableTask mocks base method.
+func (m *MockManager) FindReusableTask(url, digest string) *storage.ReusePeerTask {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindReusableTask", url, digest)
+	ret0, _ := ret[0].(*storage.ReusePeerTask)
+	return ret0
+}
+
+// FindReusableTask indicates an expected call of FindReusableTask.
+func (mr *MockManagerMockRecorder) FindReusableTask(url, digest interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindReusableTask", reflect.TypeOf((*MockManager)(nil).FindReusableTask), url, digest)
+}
+
 // GetPieces mocks base method.
 func (m *MockManager) GetPieces(ctx context.Context, req *base.PieceTaskRequest) (*base.PiecePacket, error) {
 	m.ctrl.T.Helper()
@@ -297,6 +341,36 @@ func (mr *MockManagerMockRecorder) GetPieces(ctx, req interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPieces", reflect.TypeOf((*MockManager)(nil).GetPieces), ctx, req)
 }
 
+// GetPiecesPage mocks base method.
+func (m *MockManager) GetPiecesPage(ctx context.Context, req *base.PieceTaskRequest) (*storage.PiecePage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPiecesPage", ctx, req)
+	ret0, _ := ret[0].(*storage.PiecePage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPiecesPage indicates an expected call of GetPiecesPage.
+func (mr *MockManagerMockRecorder) GetPiecesPage(ctx, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPiecesPage", reflect.TypeOf((*MockManager)(nil).GetPiecesPage), ctx, req)
+}
+
+// GetReusablePieces mocks base method.
+func (m *MockManager) GetReusablePieces(ctx context.Context, req *base.PieceTaskRequest, digest string) (*base.PiecePacket, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetReusablePieces", ctx, req, digest)
+	ret0, _ := ret[0].(*base.PiecePacket)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetReusablePieces indicates an expected call of GetReusablePieces.
+func (mr *MockManagerMockRecorder) GetReusablePieces(ctx, req, digest interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetReusablePieces", reflect.TypeOf((*MockManager)(nil).GetReusablePieces), ctx, req, digest)
+}
+
 // IsInvalid mocks base method.
 func (m *MockManager) IsInvalid(req *storage.PeerTaskMetadata) (bool, error) {
 	m.ctrl.T.Helper()
@@ -355,6 +429,21 @@ func (mr *MockManagerMockRecorder) ReadPiece(ctx, req interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadPiece", reflect.TypeOf((*MockManager)(nil).ReadPiece), ctx, req)
 }
 
+// ReadPiecesRange mocks base method.
+func (m *MockManager) ReadPiecesRange(ctx context.Context, req *storage.PeerTaskMetadata, start, length int64) (io.ReadCloser, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadPiecesRange", ctx, req, start, length)
+	ret0, _ := ret[0].(io.ReadCloser)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadPiecesRange indicates an expected call of ReadPiecesRange.
+func (mr *MockManagerMockRecorder) ReadPiecesRange(ctx, req, start, length interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadPiecesRange", reflect.TypeOf((*MockManager)(nil).ReadPiecesRange), ctx, req, start, length)
+}
+
 // RegisterTask mocks base method.
 func (m *MockManager) RegisterTask(ctx context.Context, req storage.RegisterTaskRequest) (storage.TaskStorageDriver, error) {
 	m.ctrl.T.Helper()
@@ -384,6 +473,20 @@ func (mr *MockManagerMockRecorder) Store(ctx, req interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Store", reflect.TypeOf((*MockManager)(nil).Store), ctx, req)
 }
 
+// UnregisterTask mocks base method.
+func (m *MockManager) UnregisterTask(ctx context.Context, req storage.CommonTaskRequest) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnregisterTask", ctx, req)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UnregisterTask indicates an expected call of UnregisterTask.
+func (mr *MockManagerMockRecorder) UnregisterTask(ctx, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnregisterTask", reflect.TypeOf((*MockManager)(nil).UnregisterTask), ctx, req)
+}
+
 // UpdateTask mocks base method.
 func (m *MockManager) UpdateTask(ctx context.Context, req *storage.UpdateTaskRequest) error {
 	m.ctrl.T.Helper()
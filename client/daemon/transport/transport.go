@@ -29,16 +29,22 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-http-utils/headers"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/semconv"
+	"go.opentelemetry.io/otel/trace"
 
 	"d7y.io/dragonfly/v2/client/clientutil"
 	"d7y.io/dragonfly/v2/client/config"
 	"d7y.io/dragonfly/v2/client/daemon/metrics"
 	"d7y.io/dragonfly/v2/client/daemon/peer"
 	logger "d7y.io/dragonfly/v2/internal/dflog"
+	"d7y.io/dragonfly/v2/pkg/cache"
 	"d7y.io/dragonfly/v2/pkg/idgen"
 	"d7y.io/dragonfly/v2/pkg/rpc/base"
 	"d7y.io/dragonfly/v2/pkg/rpc/scheduler"
@@ -51,8 +57,19 @@ var (
 	// layerReg the regex to determine if it is an image download
 	layerReg     = regexp.MustCompile("^.+/blobs/sha256.*$")
 	traceContext = propagation.TraceContext{}
+
+	// defaultDumpRedactHeaders are the sensitive headers redacted when dumping http content
+	defaultDumpRedactHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
 )
 
+const redactedValue = "[REDACTED]"
+
+var tracer trace.Tracer
+
+func init() {
+	tracer = otel.Tracer("dfget-daemon-transport")
+}
+
 // transport implements RoundTripper for dragonfly.
 // It uses http.fileTransport to serve requests that need to use dragonfly,
 // and uses http.Transport to serve the other requests.
@@ -60,9 +77,28 @@ type transport struct {
 	// baseRoundTripper is an implementation of RoundTripper that supports HTTP
 	baseRoundTripper http.RoundTripper
 
+	// tlsConfig is the tls config for baseRoundTripper
+	tlsConfig *tls.Config
+
+	// insecureSkipVerify skips the verification of server certificates when tlsConfig is nil
+	insecureSkipVerify bool
+
+	// dialTimeout is the dial timeout for baseRoundTripper
+	dialTimeout time.Duration
+
+	// idleConnTimeout is the idle connection timeout for baseRoundTripper
+	idleConnTimeout time.Duration
+
+	// maxIdleConns is the max idle connections for baseRoundTripper
+	maxIdleConns int
+
 	// shouldUseDragonfly is used to determine to download resources with or without dragonfly
 	shouldUseDragonfly func(req *http.Request) bool
 
+	// regexps are matched against the full url of GET requests to determine to use dragonfly,
+	// it's only used when shouldUseDragonfly is not configured by WithCondition
+	regexps []*regexp.Regexp
+
 	// peerTaskManager is the peer task manager
 	peerTaskManager peer.TaskManager
 
@@ -75,8 +111,29 @@ type transport struct {
 	// defaultBiz is used when http request without X-Dragonfly-Biz Header
 	defaultBiz string
 
+	// headerInjector computes additional url meta headers for requests downloaded with dragonfly
+	headerInjector func(req *http.Request) map[string]string
+
+	// smallObjectSize is the threshold of content length to bypass dragonfly, it's disabled when it is 0
+	smallObjectSize int64
+
+	// smallObjectCacheTTL is the ttl to cache the bypass decision by url
+	smallObjectCacheTTL time.Duration
+
+	// smallObjects caches whether the content length of url is below smallObjectSize
+	smallObjects cache.Cache
+
 	// dumpHTTPContent indicates to dump http request header and response header
 	dumpHTTPContent bool
+
+	// dumpBodyLimit is the max bytes of request body and response body to dump, only headers are dumped when it is 0
+	dumpBodyLimit int
+
+	// dumpRedactHeaders are the canonical header keys whose values are redacted in dump
+	dumpRedactHeaders map[string]struct{}
+
+	// preserveHeaders are the canonical header keys which are not removed by dragonfly
+	preserveHeaders map[string]struct{}
 }
 
 // Option is functional config for transport.
@@ -101,12 +158,45 @@ func WithPeerTaskManager(peerTaskManager peer.TaskManager) Option {
 // WithTLS configures TLS config used for http transport.
 func WithTLS(cfg *tls.Config) Option {
 	return func(rt *transport) *transport {
-		rt.baseRoundTripper = defaultHTTPTransport(cfg)
+		rt.tlsConfig = cfg
+		return rt
+	}
+}
+
+// WithInsecureSkipVerify skips the verification of server certificates when no TLS config is given.
+func WithInsecureSkipVerify(b bool) Option {
+	return func(rt *transport) *transport {
+		rt.insecureSkipVerify = b
+		return rt
+	}
+}
+
+// WithDialTimeout sets the dial timeout for http transport.
+func WithDialTimeout(timeout time.Duration) Option {
+	return func(rt *transport) *transport {
+		rt.dialTimeout = timeout
+		return rt
+	}
+}
+
+// WithIdleConnTimeout sets the idle connection timeout for http transport.
+func WithIdleConnTimeout(timeout time.Duration) Option {
+	return func(rt *transport) *transport {
+		rt.idleConnTimeout = timeout
+		return rt
+	}
+}
+
+// WithMaxIdleConns sets the max idle connections for http transport.
+func WithMaxIdleConns(n int) Option {
+	return func(rt *transport) *transport {
+		rt.maxIdleConns = n
 		return rt
 	}
 }
 
 // WithCondition configures how to decide whether to use dragonfly or not.
+// It takes precedence over WithRegexps regardless of the option order.
 func WithCondition(c func(r *http.Request) bool) Option {
 	return func(rt *transport) *transport {
 		rt.shouldUseDragonfly = c
@@ -114,6 +204,16 @@ func WithCondition(c func(r *http.Request) bool) Option {
 	}
 }
 
+// WithRegexps configures the url patterns to decide whether to use dragonfly or not,
+// GET requests whose full url matches any of the patterns are downloaded with dragonfly.
+// It's ignored when WithCondition is given, and NeedUseDragonfly is used when both are absent.
+func WithRegexps(regexps []*regexp.Regexp) Option {
+	return func(rt *transport) *transport {
+		rt.regexps = regexps
+		return rt
+	}
+}
+
 // WithDefaultFilter sets default filter for http requests with X-Dragonfly-Filter Header
 func WithDefaultFilter(f string) Option {
 	return func(rt *transport) *transport {
@@ -130,6 +230,49 @@ func WithDefaultBiz(b string) Option {
 	}
 }
 
+// WithHeaderInjector computes additional headers per request downloaded with dragonfly,
+// like cache tags or auth depending on X-Dragonfly-Biz. The injector receives the original request
+// before dragonfly reads or removes any header, and the injected headers are set on the request,
+// so they take effect like the ones set by the client. Three of them change the task id:
+// injected X-Dragonfly-Biz sets the task tag, injected X-Dragonfly-Filter sets the query params
+// filtered out of the url, and injected Range sets the task range.
+// Other injected headers are only forwarded to the source with the url meta and are not part of the task id,
+// requests which only differ in them share the same task and the content downloaded with the first one.
+// Injected headers never override the headers explicitly set by the client.
+func WithHeaderInjector(injector func(req *http.Request) map[string]string) Option {
+	return func(rt *transport) *transport {
+		rt.headerInjector = injector
+		return rt
+	}
+}
+
+// WithSmallObjectBypass downloads the objects whose content length is below threshold directly, as the p2p
+// overhead exceeds direct fetch for tiny objects. A HEAD request is sent through the base round tripper before
+// downloading with dragonfly, and the decision is cached by url for ttl, the default ttl is one minute.
+// It's disabled when threshold is 0.
+func WithSmallObjectBypass(threshold int64, ttl time.Duration) Option {
+	return func(rt *transport) *transport {
+		rt.smallObjectSize = threshold
+		rt.smallObjectCacheTTL = ttl
+		return rt
+	}
+}
+
+// WithPreserveHeaders keeps the headers which are removed by dragonfly by default, like User-Agent,
+// they are forwarded to the source with the url meta. The hop-by-hop headers are always removed.
+// The headers are not part of the task id, requests which only differ in the preserved headers
+// share the same task and the content downloaded with the first one, use X-Dragonfly-Biz
+// to separate them when the source varies content by these headers.
+func WithPreserveHeaders(hdrs []string) Option {
+	return func(rt *transport) *transport {
+		rt.preserveHeaders = make(map[string]struct{}, len(hdrs))
+		for _, h := range hdrs {
+			rt.preserveHeaders[http.CanonicalHeaderKey(h)] = struct{}{}
+		}
+		return rt
+	}
+}
+
 func WithDumpHTTPContent(b bool) Option {
 	return func(rt *transport) *transport {
 		rt.dumpHTTPContent = b
@@ -137,23 +280,71 @@ func WithDumpHTTPContent(b bool) Option {
 	}
 }
 
+// WithDumpBodyLimit dumps up to n bytes of request body and response body when dumping http content,
+// the bodies are still consumable for downstream. Only headers are dumped when n is 0.
+func WithDumpBodyLimit(n int) Option {
+	return func(rt *transport) *transport {
+		rt.dumpBodyLimit = n
+		return rt
+	}
+}
+
+// WithDumpRedactHeaders sets the sensitive headers whose values are redacted when dumping http content,
+// it replaces the default ones: Authorization, Proxy-Authorization, Cookie and Set-Cookie.
+func WithDumpRedactHeaders(hdrs []string) Option {
+	return func(rt *transport) *transport {
+		rt.dumpRedactHeaders = make(map[string]struct{}, len(hdrs))
+		for _, h := range hdrs {
+			rt.dumpRedactHeaders[http.CanonicalHeaderKey(h)] = struct{}{}
+		}
+		return rt
+	}
+}
+
 // New constructs a new instance of a RoundTripper with additional options.
 func New(options ...Option) (http.RoundTripper, error) {
 	rt := &transport{
-		baseRoundTripper:   defaultHTTPTransport(nil),
-		shouldUseDragonfly: NeedUseDragonfly,
+		dialTimeout:     10 * time.Second,
+		idleConnTimeout: 90 * time.Second,
+		maxIdleConns:    100,
 	}
+	WithDumpRedactHeaders(defaultDumpRedactHeaders)(rt)
 
 	for _, opt := range options {
 		opt(rt)
 	}
 
+	rt.baseRoundTripper = rt.defaultHTTPTransport()
+
+	if rt.smallObjectSize > 0 {
+		if rt.smallObjectCacheTTL <= 0 {
+			rt.smallObjectCacheTTL = time.Minute
+		}
+		// expired decisions are deleted when new decisions are cached, no janitor is needed
+		rt.smallObjects = cache.New(rt.smallObjectCacheTTL, 0)
+	}
+
+	if rt.shouldUseDragonfly == nil {
+		if len(rt.regexps) > 0 {
+			rt.shouldUseDragonfly = rt.matchRegexps
+		} else {
+			rt.shouldUseDragonfly = NeedUseDragonfly
+		}
+	}
+
 	return rt, nil
 }
 
 // RoundTrip only process first redirect at present
 func (rt *transport) RoundTrip(req *http.Request) (resp *http.Response, err error) {
-	if rt.shouldUseDragonfly(req) {
+	// record the request body when it is sent
+	var reqBody *bodyRecorder
+	if rt.dumpHTTPContent && rt.dumpBodyLimit > 0 && req.Body != nil && req.Body != http.NoBody {
+		reqBody = &bodyRecorder{ReadCloser: req.Body, limit: rt.dumpBodyLimit}
+		req.Body = reqBody
+	}
+
+	if rt.shouldUseDragonfly(req) && !rt.isSmallObject(req) {
 		// delete the Accept-Encoding header to avoid returning the same cached
 		// result for different requests
 		req.Header.Del("Accept-Encoding")
@@ -186,7 +377,7 @@ func (rt *transport) RoundTrip(req *http.Request) (resp *http.Response, err erro
 		logger.With("method", req.Method, "url", req.URL.String()).
 			Errorf("round trip error: %s", err)
 	}
-	rt.processDumpHTTPContent(req, resp)
+	rt.processDumpHTTPContent(req, reqBody, resp)
 	return resp, err
 }
 
@@ -196,6 +387,76 @@ func NeedUseDragonfly(req *http.Request) bool {
 	return req.Method == http.MethodGet && layerReg.MatchString(req.URL.Path)
 }
 
+// matchRegexps downloads the GET requests whose full url matches any of the regexps with dragonfly.
+func (rt *transport) matchRegexps(req *http.Request) bool {
+	if req.Method != http.MethodGet {
+		return false
+	}
+	url := req.URL.String()
+	for _, reg := range rt.regexps {
+		if reg.MatchString(url) {
+			return true
+		}
+	}
+	return false
+}
+
+// isSmallObject checks whether the content length of the request url is below smallObjectSize with a HEAD request,
+// the HEAD request is sent through the base round tripper, and the result is cached by url.
+func (rt *transport) isSmallObject(req *http.Request) bool {
+	if rt.smallObjectSize <= 0 {
+		return false
+	}
+
+	url := req.URL.String()
+	if small, ok := rt.smallObjects.Get(url); ok {
+		return small.(bool)
+	}
+
+	contentLength, err := rt.headContentLength(req)
+	small := contentLength >= 0 && contentLength < rt.smallObjectSize
+	if err != nil {
+		// the decision is also cached to avoid sending HEAD requests again
+		logger.Warnf("head %s to check content length error: %s", url, err)
+	}
+
+	rt.smallObjects.DeleteExpired()
+	rt.smallObjects.SetDefault(url, small)
+	logger.Debugf("check content length of %s, small object: %t", url, small)
+	return small
+}
+
+// headContentLength returns the content length of the whole object with a HEAD request sent through
+// the base round tripper, -1 is returned when the length is unknown
+func (rt *transport) headContentLength(req *http.Request) (int64, error) {
+	headReq, err := http.NewRequestWithContext(req.Context(), http.MethodHead, req.URL.String(), nil)
+	if err != nil {
+		return -1, err
+	}
+	headReq.Header = req.Header.Clone()
+	headReq.Header.Del(headers.Range)
+	resp, err := rt.baseRoundTripper.RoundTrip(headReq)
+	if err != nil {
+		return -1, err
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return -1, nil
+	}
+	return resp.ContentLength, nil
+}
+
+// isSuffixRange returns whether any range in header is relative to the end of content, like "bytes=-500"
+func isSuffixRange(rangeHeader string) bool {
+	for _, ra := range strings.Split(strings.TrimPrefix(rangeHeader, "bytes="), ",") {
+		if strings.HasPrefix(strings.TrimSpace(ra), "-") {
+			return true
+		}
+	}
+	return false
+}
+
 // download uses dragonfly to download.
 // the ctx has span info from transport, did not use the ctx from request
 func (rt *transport) download(ctx context.Context, req *http.Request) (*http.Response, error) {
@@ -204,9 +465,26 @@ func (rt *transport) download(ctx context.Context, req *http.Request) (*http.Res
 	log := logger.With("peer", peerID, "component", "transport")
 	log.Infof("start download with url: %s", url)
 
+	// Inject headers before reading any header, the headers set by client are kept
+	injected := map[string]string{}
+	if rt.headerInjector != nil {
+		for k, v := range rt.headerInjector(req) {
+			k = http.CanonicalHeaderKey(k)
+			if _, ok := req.Header[k]; ok {
+				continue
+			}
+			req.Header.Set(k, v)
+			injected[k] = v
+		}
+	}
+
 	// Init meta value
 	meta := &base.UrlMeta{Header: map[string]string{}}
-	var rg *clientutil.Range
+	var (
+		rg *clientutil.Range
+		// total is the length of the whole object, -1 when it is unknown
+		total int64 = -1
+	)
 
 	// Set meta range's value
 	if rangeHeader := req.Header.Get("Range"); len(rangeHeader) > 0 {
@@ -223,16 +501,50 @@ func (rt *transport) download(ctx context.Context, req *http.Request) (*http.Res
 		rg = &rgs[0]
 		// range in dragonfly is without "bytes="
 		meta.Range = strings.TrimLeft(rangeHeader, "bytes=")
+
+		// the start of suffix range is resolved against the content length of the whole object
+		if isSuffixRange(rangeHeader) {
+			if total, err = rt.headContentLength(req); err != nil || total < 0 {
+				log.Warnf("get content length to resolve range %s error: %v", rangeHeader, err)
+				return requestedRangeNotSatisfiable(req, "suffix range is not supported when content length is unknown")
+			}
+			if rgs, err = clientutil.ParseRange(rangeHeader, total); err != nil {
+				return badRequest(req, err.Error())
+			} else if len(rgs) == 0 {
+				return requestedRangeNotSatisfiable(req, "range is beyond the content")
+			}
+			rg = &rgs[0]
+			meta.Range = fmt.Sprintf("%d-%d", rg.Start, rg.Start+rg.Length-1)
+		}
 	}
 
 	// Pick header's parameters
-	filter := httputils.PickHeader(req.Header, config.HeaderDragonflyFilter, rt.defaultFilter)
+	// multiple filters are separated by comma in X-Dragonfly-Filter header, and are joined by & in url meta
+	filter := strings.Join(httputils.PickHeaderValues(req.Header, config.HeaderDragonflyFilter, ","), "&")
+	if filter == "" {
+		filter = rt.defaultFilter
+	}
 	tag := httputils.PickHeader(req.Header, config.HeaderDragonflyBiz, rt.defaultBiz)
+	// the picked headers are not forwarded to the source
+	delete(injected, config.HeaderDragonflyFilter)
+	delete(injected, config.HeaderDragonflyBiz)
+
+	// the span is finished when the stream attributes return, the tracer is no-op without tracer provider
+	ctx, span := tracer.Start(ctx, config.SpanTransportDownload, trace.WithSpanKind(trace.SpanKindClient))
+	defer span.End()
+	span.SetAttributes(semconv.HTTPURLKey.String(url))
+	span.SetAttributes(semconv.HTTPMethodKey.String(req.Method))
+	span.SetAttributes(config.AttributePeerID.String(peerID))
+	span.SetAttributes(config.AttributeFilter.String(filter))
+	span.SetAttributes(config.AttributeTag.String(tag))
 
 	// Delete hop-by-hop headers
-	delHopHeaders(req.Header)
+	delHopHeaders(req.Header, rt.preserveHeaders)
 
 	meta.Header = httputils.HeaderToMap(req.Header)
+	for k, v := range injected {
+		meta.Header[k] = v
+	}
 	meta.Tag = tag
 	meta.Filter = filter
 
@@ -247,6 +559,8 @@ func (rt *transport) download(ctx context.Context, req *http.Request) (*http.Res
 	)
 	if err != nil {
 		log.Errorf("download fail: %v", err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
 		// add more info for debugging
 		if attr != nil {
 			err = fmt.Errorf("task: %s\npeer: %s\nerror: %s",
@@ -255,6 +569,8 @@ func (rt *transport) download(ctx context.Context, req *http.Request) (*http.Res
 		return nil, err
 	}
 
+	span.SetAttributes(config.AttributeTaskID.String(attr[config.HeaderDragonflyTask]))
+
 	hdr := httputils.MapToHeader(attr)
 	log.Infof("download stream attribute: %v", hdr)
 
@@ -265,12 +581,19 @@ func (rt *transport) download(ctx context.Context, req *http.Request) (*http.Res
 		}
 	}
 
-	var status int
-	if meta.Range == "" {
-		status = http.StatusOK
-	} else {
+	var status = http.StatusOK
+	if rg != nil {
 		status = http.StatusPartialContent
+		// the reused task carries the content range with the full length
+		if hdr.Get(headers.ContentRange) == "" && contentLength > 0 {
+			size := "*"
+			if total >= 0 {
+				size = strconv.FormatInt(total, 10)
+			}
+			hdr.Set(headers.ContentRange, fmt.Sprintf("bytes %d-%d/%s", rg.Start, rg.Start+contentLength-1, size))
+		}
 	}
+	hdr.Set(headers.AcceptRanges, "bytes")
 	resp := &http.Response{
 		StatusCode:    status,
 		Body:          body,
@@ -284,11 +607,16 @@ func (rt *transport) download(ctx context.Context, req *http.Request) (*http.Res
 	return resp, nil
 }
 
-func (rt *transport) processDumpHTTPContent(req *http.Request, resp *http.Response) {
+func (rt *transport) processDumpHTTPContent(req *http.Request, reqBody *bodyRecorder, resp *http.Response) {
 	if !rt.dumpHTTPContent {
 		return
 	}
-	if out, e := httputil.DumpRequest(req, false); e == nil {
+	dumpReq := *req
+	dumpReq.Header = rt.redactHeader(req.Header)
+	if out, e := httputil.DumpRequest(&dumpReq, false); e == nil {
+		if reqBody != nil {
+			out = append(out, reqBody.Bytes()...)
+		}
 		logger.Debugf("dump request in transport: %s", string(out))
 	} else {
 		logger.Errorf("dump request in transport error: %s", e)
@@ -296,25 +624,92 @@ func (rt *transport) processDumpHTTPContent(req *http.Request, resp *http.Respon
 	if resp == nil {
 		return
 	}
-	if out, e := httputil.DumpResponse(resp, false); e == nil {
+	dumpResp := *resp
+	dumpResp.Header = rt.redactHeader(resp.Header)
+	if out, e := httputil.DumpResponse(&dumpResp, false); e == nil {
+		if rt.dumpBodyLimit > 0 && resp.Body != nil && resp.Body != http.NoBody {
+			out = append(out, peekBody(resp, rt.dumpBodyLimit)...)
+		}
 		logger.Debugf("dump response in transport: %s", string(out))
 	} else {
 		logger.Errorf("dump response in transport error: %s", e)
 	}
 }
 
-func defaultHTTPTransport(cfg *tls.Config) *http.Transport {
+// redactHeader returns a copy of header whose sensitive values are redacted
+func (rt *transport) redactHeader(header http.Header) http.Header {
+	if len(rt.dumpRedactHeaders) == 0 {
+		return header
+	}
+	redacted := header.Clone()
+	for h := range rt.dumpRedactHeaders {
+		if _, ok := redacted[h]; ok {
+			redacted[h] = []string{redactedValue}
+		}
+	}
+	return redacted
+}
+
+// peekBody reads up to limit bytes of the response body, and puts them back for downstream
+func peekBody(resp *http.Response, limit int) []byte {
+	buf := make([]byte, limit)
+	n, _ := io.ReadFull(resp.Body, buf)
+	buf = buf[:n]
+	resp.Body = &peekedBody{
+		Reader: io.MultiReader(bytes.NewReader(buf), resp.Body),
+		Closer: resp.Body,
+	}
+	return buf
+}
+
+type peekedBody struct {
+	io.Reader
+	io.Closer
+}
+
+// bodyRecorder records the first limit bytes read from the body,
+// the body may be read in another goroutine by http transport
+type bodyRecorder struct {
+	io.ReadCloser
+	limit int
+
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (r *bodyRecorder) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.mu.Lock()
+	if remain := r.limit - r.buf.Len(); remain > 0 && n > 0 {
+		if remain > n {
+			remain = n
+		}
+		r.buf.Write(p[:remain])
+	}
+	r.mu.Unlock()
+	return n, err
+}
+
+// Bytes returns a copy of the recorded bytes
+func (r *bodyRecorder) Bytes() []byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]byte(nil), r.buf.Bytes()...)
+}
+
+func (rt *transport) defaultHTTPTransport() *http.Transport {
+	cfg := rt.tlsConfig
 	if cfg == nil {
-		cfg = &tls.Config{InsecureSkipVerify: true}
+		cfg = &tls.Config{InsecureSkipVerify: rt.insecureSkipVerify}
 	}
 
 	return &http.Transport{
 		DialContext: (&net.Dialer{
-			Timeout:   10 * time.Second,
+			Timeout:   rt.dialTimeout,
 			KeepAlive: 30 * time.Second,
 		}).DialContext,
-		MaxIdleConns:          100,
-		IdleConnTimeout:       90 * time.Second,
+		MaxIdleConns:          rt.maxIdleConns,
+		IdleConnTimeout:       rt.idleConnTimeout,
 		TLSHandshakeTimeout:   10 * time.Second,
 		ExpectContinueTimeout: 1 * time.Second,
 		TLSClientConfig:       cfg,
@@ -339,18 +734,26 @@ var hopHeaders = []string{
 	"Trailer", // not Trailers per URL above; https://www.rfc-editor.org/errata_search.php?eid=4522
 	"Transfer-Encoding",
 	"Upgrade",
+}
 
-	// remove by dragonfly
+// removedHeaders are removed by dragonfly, they can be kept with WithPreserveHeaders
+var removedHeaders = []string{
 	"Accept",
 	"User-Agent",
 	"X-Forwarded-For",
 }
 
-// delHopHeaders delete hop-by-hop headers.
-func delHopHeaders(header http.Header) {
+// delHopHeaders delete hop-by-hop headers and the headers removed by dragonfly except the preserved ones.
+func delHopHeaders(header http.Header, preserve map[string]struct{}) {
 	for _, h := range hopHeaders {
 		header.Del(h)
 	}
+	for _, h := range removedHeaders {
+		if _, ok := preserve[h]; ok {
+			continue
+		}
+		header.Del(h)
+	}
 	// remove correlation with trace header
 	for _, h := range traceContext.Fields() {
 		header.Del(h)
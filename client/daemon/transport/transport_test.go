@@ -19,17 +19,27 @@ package transport
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
+	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"os"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/go-http-utils/headers"
 	"github.com/golang/mock/gomock"
 	testifyassert "github.com/stretchr/testify/assert"
+	"go.uber.org/atomic"
 
+	"d7y.io/dragonfly/v2/client/config"
 	"d7y.io/dragonfly/v2/client/daemon/peer"
 	"d7y.io/dragonfly/v2/client/daemon/test"
 	mock_peer "d7y.io/dragonfly/v2/client/daemon/test/mock/peer"
+	"d7y.io/dragonfly/v2/pkg/idgen"
 	"d7y.io/dragonfly/v2/pkg/rpc/scheduler"
 )
 
@@ -72,3 +82,593 @@ func TestTransport_RoundTrip(t *testing.T) {
 	}
 	assert.Equal(testData, output)
 }
+
+func TestTransport_WithRegexps(t *testing.T) {
+	assert := testifyassert.New(t)
+	regexps := []*regexp.Regexp{
+		regexp.MustCompile(`^https?://data\.example\.com/`),
+		regexp.MustCompile(`\.tar\.gz$`),
+	}
+
+	tests := []struct {
+		name    string
+		options []Option
+		method  string
+		url     string
+		expect  bool
+	}{
+		{
+			name:   "default condition matches image layers",
+			method: http.MethodGet,
+			url:    "http://registry/v2/library/alpine/blobs/sha256:abc",
+			expect: true,
+		},
+		{
+			name:   "default condition ignores other urls",
+			method: http.MethodGet,
+			url:    "http://data.example.com/file",
+			expect: false,
+		},
+		{
+			name:    "regexps match host in full url",
+			options: []Option{WithRegexps(regexps)},
+			method:  http.MethodGet,
+			url:     "http://data.example.com/file",
+			expect:  true,
+		},
+		{
+			name:    "regexps match path suffix",
+			options: []Option{WithRegexps(regexps)},
+			method:  http.MethodGet,
+			url:     "http://other.example.com/release.tar.gz",
+			expect:  true,
+		},
+		{
+			name:    "regexps override default condition",
+			options: []Option{WithRegexps(regexps)},
+			method:  http.MethodGet,
+			url:     "http://registry/v2/library/alpine/blobs/sha256:abc",
+			expect:  false,
+		},
+		{
+			name:    "regexps only match GET requests",
+			options: []Option{WithRegexps(regexps)},
+			method:  http.MethodPost,
+			url:     "http://data.example.com/file",
+			expect:  false,
+		},
+		{
+			name: "condition takes precedence over regexps",
+			options: []Option{
+				WithCondition(func(r *http.Request) bool { return false }),
+				WithRegexps(regexps),
+			},
+			method: http.MethodGet,
+			url:    "http://data.example.com/file",
+			expect: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rt, err := New(tt.options...)
+			assert.Nil(err)
+			req, _ := http.NewRequest(tt.method, tt.url, nil)
+			assert.Equal(tt.expect, rt.(*transport).shouldUseDragonfly(req))
+		})
+	}
+}
+
+func TestTransport_WithPreserveHeaders(t *testing.T) {
+	assert := testifyassert.New(t)
+	ctrl := gomock.NewController(t)
+
+	tests := []struct {
+		name     string
+		preserve []string
+		expect   map[string]string
+	}{
+		{
+			name: "remove by default",
+			expect: map[string]string{
+				"Authorization": "Bearer token",
+			},
+		},
+		{
+			name:     "preserve selected headers",
+			preserve: []string{"user-agent", "Connection"},
+			expect: map[string]string{
+				"Authorization": "Bearer token",
+				"User-Agent":    "test-agent",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			peerTaskManager := mock_peer.NewMockTaskManager(ctrl)
+			peerTaskManager.EXPECT().StartStreamTask(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(ctx context.Context, req *peer.StreamTaskRequest) (io.ReadCloser, map[string]string, error) {
+					assert.Equal(tt.expect, req.URLMeta.Header)
+					return io.NopCloser(bytes.NewBuffer(nil)), nil, nil
+				},
+			)
+			rt, _ := New(
+				WithPeerHost(&scheduler.PeerHost{}),
+				WithPeerTaskManager(peerTaskManager),
+				WithPreserveHeaders(tt.preserve),
+				WithCondition(func(r *http.Request) bool {
+					return true
+				}))
+			req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, "http://x/y", nil)
+			req.Header.Set("Authorization", "Bearer token")
+			req.Header.Set("User-Agent", "test-agent")
+			req.Header.Set("Accept", "*/*")
+			req.Header.Set("Connection", "keep-alive")
+			resp, err := rt.RoundTrip(req)
+			assert.Nil(err)
+			resp.Body.Close()
+		})
+	}
+}
+
+func TestTransport_RoundTripWithRange(t *testing.T) {
+	assert := testifyassert.New(t)
+	ctrl := gomock.NewController(t)
+	testData, err := os.ReadFile(test.File)
+	assert.Nil(err, "load test file")
+	size := len(testData)
+
+	// the origin answers HEAD requests to resolve suffix ranges
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/y" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set(headers.ContentLength, fmt.Sprintf("%d", size))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name         string
+		path         string
+		rangeHeader  string
+		metaRange    string
+		attr         map[string]string
+		data         []byte
+		status       int
+		contentRange string
+	}{
+		{
+			name:   "full content",
+			data:   testData,
+			status: http.StatusOK,
+			attr: map[string]string{
+				headers.ContentLength: fmt.Sprintf("%d", len(testData)),
+			},
+		},
+		{
+			name:        "partial content",
+			rangeHeader: "bytes=10-109",
+			data:        testData[10:110],
+			status:      http.StatusPartialContent,
+			attr: map[string]string{
+				headers.ContentLength: "100",
+			},
+			contentRange: "bytes 10-109/*",
+		},
+		{
+			name:        "partial content with full length",
+			rangeHeader: "bytes=10-109",
+			data:        testData[10:110],
+			status:      http.StatusPartialContent,
+			attr: map[string]string{
+				headers.ContentLength: "100",
+				headers.ContentRange:  fmt.Sprintf("bytes 10-109/%d", len(testData)),
+			},
+			contentRange: fmt.Sprintf("bytes 10-109/%d", len(testData)),
+		},
+		{
+			name:        "open-ended range",
+			rangeHeader: "bytes=10-",
+			data:        testData[10:],
+			status:      http.StatusPartialContent,
+			attr: map[string]string{
+				headers.ContentLength: fmt.Sprintf("%d", size-10),
+			},
+			contentRange: fmt.Sprintf("bytes 10-%d/*", size-1),
+		},
+		{
+			name:        "suffix range",
+			rangeHeader: "bytes=-100",
+			metaRange:   fmt.Sprintf("%d-%d", size-100, size-1),
+			data:        testData[size-100:],
+			status:      http.StatusPartialContent,
+			attr: map[string]string{
+				headers.ContentLength: "100",
+			},
+			contentRange: fmt.Sprintf("bytes %d-%d/%d", size-100, size-1, size),
+		},
+		{
+			name:        "suffix range longer than content",
+			rangeHeader: fmt.Sprintf("bytes=-%d", size+100),
+			metaRange:   fmt.Sprintf("0-%d", size-1),
+			data:        testData,
+			status:      http.StatusPartialContent,
+			attr: map[string]string{
+				headers.ContentLength: fmt.Sprintf("%d", size),
+			},
+			contentRange: fmt.Sprintf("bytes 0-%d/%d", size-1, size),
+		},
+		{
+			name:        "suffix range with unknown content length",
+			path:        "/unknown",
+			rangeHeader: "bytes=-100",
+			status:      http.StatusRequestedRangeNotSatisfiable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			peerTaskManager := mock_peer.NewMockTaskManager(ctrl)
+			if tt.data != nil {
+				peerTaskManager.EXPECT().StartStreamTask(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, req *peer.StreamTaskRequest) (io.ReadCloser, map[string]string, error) {
+						if tt.rangeHeader != "" {
+							metaRange := tt.metaRange
+							if metaRange == "" {
+								metaRange = strings.TrimPrefix(tt.rangeHeader, "bytes=")
+							}
+							assert.NotNil(req.Range)
+							assert.Equal(metaRange, req.URLMeta.Range)
+						}
+						return io.NopCloser(bytes.NewBuffer(tt.data)), tt.attr, nil
+					},
+				)
+			}
+			rt, _ := New(
+				WithPeerHost(&scheduler.PeerHost{}),
+				WithPeerTaskManager(peerTaskManager),
+				WithCondition(func(r *http.Request) bool {
+					return true
+				}))
+			path := tt.path
+			if path == "" {
+				path = "/y"
+			}
+			req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, server.URL+path, nil)
+			if tt.rangeHeader != "" {
+				req.Header.Set(headers.Range, tt.rangeHeader)
+			}
+			resp, err := rt.RoundTrip(req)
+			assert.Nil(err)
+			defer resp.Body.Close()
+
+			assert.Equal(tt.status, resp.StatusCode)
+			if tt.data == nil {
+				return
+			}
+			assert.Equal("bytes", resp.Header.Get(headers.AcceptRanges))
+			assert.Equal(tt.contentRange, resp.Header.Get(headers.ContentRange))
+			assert.Equal(int64(len(tt.data)), resp.ContentLength)
+			output, err := io.ReadAll(resp.Body)
+			assert.Nil(err)
+			assert.Equal(tt.data, output)
+		})
+	}
+}
+
+func TestTransport_BaseRoundTripperOptions(t *testing.T) {
+	assert := testifyassert.New(t)
+
+	rt, err := New()
+	assert.Nil(err)
+	base := rt.(*transport).baseRoundTripper.(*http.Transport)
+	assert.False(base.TLSClientConfig.InsecureSkipVerify)
+	assert.Equal(100, base.MaxIdleConns)
+	assert.Equal(90*time.Second, base.IdleConnTimeout)
+
+	rt, err = New(
+		WithInsecureSkipVerify(true),
+		WithDialTimeout(time.Second),
+		WithIdleConnTimeout(time.Minute),
+		WithMaxIdleConns(10))
+	assert.Nil(err)
+	base = rt.(*transport).baseRoundTripper.(*http.Transport)
+	assert.True(base.TLSClientConfig.InsecureSkipVerify)
+	assert.Equal(10, base.MaxIdleConns)
+	assert.Equal(time.Minute, base.IdleConnTimeout)
+
+	tlsConfig := &tls.Config{ServerName: "example.com"}
+	rt, err = New(WithInsecureSkipVerify(true), WithTLS(tlsConfig))
+	assert.Nil(err)
+	base = rt.(*transport).baseRoundTripper.(*http.Transport)
+	assert.Equal(tlsConfig, base.TLSClientConfig)
+}
+
+func TestTransport_DumpHTTPBody(t *testing.T) {
+	assert := testifyassert.New(t)
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get(headers.Authorization)
+		w.Header().Set("Set-Cookie", "session=secret")
+		_, _ = io.Copy(w, r.Body)
+	}))
+	defer server.Close()
+
+	rt, err := New(
+		WithCondition(func(r *http.Request) bool {
+			return false
+		}),
+		WithDumpHTTPContent(true),
+		WithDumpBodyLimit(5))
+	assert.Nil(err)
+
+	req, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader("hello dragonfly"))
+	req.Header.Set(headers.Authorization, "Bearer secret")
+	resp, err := rt.RoundTrip(req)
+	assert.Nil(err)
+	defer resp.Body.Close()
+
+	// the peeked response body is still consumable
+	output, err := io.ReadAll(resp.Body)
+	assert.Nil(err)
+	assert.Equal("hello dragonfly", string(output))
+	assert.Equal("Bearer secret", auth)
+	assert.Equal("session=secret", resp.Header.Get("Set-Cookie"))
+
+	// only the first bytes of request body are recorded
+	assert.Equal([]byte("hello"), req.Body.(*bodyRecorder).Bytes())
+}
+
+func TestTransport_DumpRedactHeaders(t *testing.T) {
+	assert := testifyassert.New(t)
+	header := http.Header{}
+	header.Set(headers.Authorization, "Bearer secret")
+	header.Set("X-Token", "secret")
+	header.Set(headers.Accept, "*/*")
+
+	rt, _ := New()
+	redacted := rt.(*transport).redactHeader(header)
+	assert.Equal(redactedValue, redacted.Get(headers.Authorization))
+	assert.Equal("secret", redacted.Get("X-Token"))
+	assert.Equal("Bearer secret", header.Get(headers.Authorization))
+
+	rt, _ = New(WithDumpRedactHeaders([]string{"x-token"}))
+	redacted = rt.(*transport).redactHeader(header)
+	assert.Equal("Bearer secret", redacted.Get(headers.Authorization))
+	assert.Equal(redactedValue, redacted.Get("X-Token"))
+	assert.Equal("*/*", redacted.Get(headers.Accept))
+}
+
+func TestTransport_WithHeaderInjector(t *testing.T) {
+	assert := testifyassert.New(t)
+	ctrl := gomock.NewController(t)
+
+	injector := func(req *http.Request) map[string]string {
+		switch req.Header.Get(config.HeaderDragonflyBiz) {
+		case "biz-a":
+			return map[string]string{
+				"x-cache-tag":   "a",
+				"authorization": "Bearer injected",
+			}
+		case "":
+			return map[string]string{
+				"x-dragonfly-biz":    "biz-injected",
+				"x-dragonfly-filter": "token",
+			}
+		}
+		return nil
+	}
+
+	tests := []struct {
+		name         string
+		header       map[string]string
+		expect       map[string]string
+		expectTag    string
+		expectFilter string
+	}{
+		{
+			name: "inject headers",
+			header: map[string]string{
+				config.HeaderDragonflyBiz: "biz-a",
+			},
+			expect: map[string]string{
+				"X-Cache-Tag":   "a",
+				"Authorization": "Bearer injected",
+			},
+			expectTag: "biz-a",
+		},
+		{
+			name: "do not override headers set by client",
+			header: map[string]string{
+				config.HeaderDragonflyBiz: "biz-a",
+				"Authorization":           "Bearer client",
+			},
+			expect: map[string]string{
+				"X-Cache-Tag":   "a",
+				"Authorization": "Bearer client",
+			},
+			expectTag: "biz-a",
+		},
+		{
+			name: "nothing injected",
+			header: map[string]string{
+				config.HeaderDragonflyBiz: "biz-b",
+			},
+			expect:    map[string]string{},
+			expectTag: "biz-b",
+		},
+		{
+			name:         "inject biz and filter",
+			header:       map[string]string{},
+			expect:       map[string]string{},
+			expectTag:    "biz-injected",
+			expectFilter: "token",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			peerTaskManager := mock_peer.NewMockTaskManager(ctrl)
+			peerTaskManager.EXPECT().StartStreamTask(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(ctx context.Context, req *peer.StreamTaskRequest) (io.ReadCloser, map[string]string, error) {
+					assert.Equal(tt.expect, req.URLMeta.Header)
+					assert.Equal(tt.expectTag, req.URLMeta.Tag)
+					assert.Equal(tt.expectFilter, req.URLMeta.Filter)
+					return io.NopCloser(bytes.NewBuffer(nil)), nil, nil
+				},
+			)
+			rt, _ := New(
+				WithPeerHost(&scheduler.PeerHost{}),
+				WithPeerTaskManager(peerTaskManager),
+				WithHeaderInjector(injector),
+				WithCondition(func(r *http.Request) bool {
+					return true
+				}))
+			req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, "http://x/y", nil)
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+			resp, err := rt.RoundTrip(req)
+			assert.Nil(err)
+			resp.Body.Close()
+		})
+	}
+}
+
+func TestTransport_WithHeaderInjectorTaskID(t *testing.T) {
+	assert := testifyassert.New(t)
+	ctrl := gomock.NewController(t)
+
+	tests := []struct {
+		name       string
+		injected   []map[string]string
+		expectSame bool
+	}{
+		{
+			name: "injected biz changes task id",
+			injected: []map[string]string{
+				{config.HeaderDragonflyBiz: "biz-a"},
+				{config.HeaderDragonflyBiz: "biz-b"},
+			},
+			expectSame: false,
+		},
+		{
+			name: "injected filter changes task id",
+			injected: []map[string]string{
+				{},
+				{config.HeaderDragonflyFilter: "token"},
+			},
+			expectSame: false,
+		},
+		{
+			name: "injected range changes task id",
+			injected: []map[string]string{
+				{"Range": "bytes=0-9"},
+				{"Range": "bytes=10-19"},
+			},
+			expectSame: false,
+		},
+		{
+			name: "other injected headers do not change task id",
+			injected: []map[string]string{
+				{"x-cache-tag": "a", "authorization": "Bearer a"},
+				{"x-cache-tag": "b", "authorization": "Bearer b"},
+			},
+			expectSame: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var taskIDs []string
+			peerTaskManager := mock_peer.NewMockTaskManager(ctrl)
+			peerTaskManager.EXPECT().StartStreamTask(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(ctx context.Context, req *peer.StreamTaskRequest) (io.ReadCloser, map[string]string, error) {
+					taskIDs = append(taskIDs, idgen.TaskID(req.URL, req.URLMeta))
+					return io.NopCloser(bytes.NewBuffer(nil)), nil, nil
+				},
+			).Times(len(tt.injected))
+
+			for _, injected := range tt.injected {
+				injected := injected
+				rt, _ := New(
+					WithPeerHost(&scheduler.PeerHost{}),
+					WithPeerTaskManager(peerTaskManager),
+					WithHeaderInjector(func(req *http.Request) map[string]string {
+						return injected
+					}),
+					WithCondition(func(r *http.Request) bool {
+						return true
+					}))
+				req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, "http://x/y?token=abc", nil)
+				resp, err := rt.RoundTrip(req)
+				assert.Nil(err)
+				resp.Body.Close()
+			}
+
+			assert.Len(taskIDs, 2)
+			if tt.expectSame {
+				assert.Equal(taskIDs[0], taskIDs[1])
+			} else {
+				assert.NotEqual(taskIDs[0], taskIDs[1])
+			}
+		})
+	}
+}
+
+func TestTransport_WithSmallObjectBypass(t *testing.T) {
+	assert := testifyassert.New(t)
+	ctrl := gomock.NewController(t)
+
+	heads := atomic.NewInt32(0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			heads.Inc()
+		}
+		switch r.URL.Path {
+		case "/small":
+			_, _ = w.Write([]byte("small"))
+		case "/large":
+			_, _ = w.Write(bytes.Repeat([]byte("l"), 1024))
+		}
+	}))
+	defer server.Close()
+
+	peerTaskManager := mock_peer.NewMockTaskManager(ctrl)
+	peerTaskManager.EXPECT().StartStreamTask(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, req *peer.StreamTaskRequest) (io.ReadCloser, map[string]string, error) {
+			assert.Equal(server.URL+"/large", req.URL)
+			return io.NopCloser(bytes.NewBufferString("dragonfly")), nil, nil
+		},
+	).Times(2)
+	rt, _ := New(
+		WithPeerHost(&scheduler.PeerHost{}),
+		WithPeerTaskManager(peerTaskManager),
+		WithSmallObjectBypass(1024, time.Minute),
+		WithCondition(func(r *http.Request) bool {
+			return true
+		}))
+
+	tests := []struct {
+		path   string
+		expect string
+	}{
+		{path: "/small", expect: "small"},
+		{path: "/small", expect: "small"},
+		{path: "/large", expect: "dragonfly"},
+		{path: "/large", expect: "dragonfly"},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, server.URL+tt.path, nil)
+		resp, err := rt.RoundTrip(req)
+		assert.Nil(err)
+		data, err := io.ReadAll(resp.Body)
+		assert.Nil(err)
+		resp.Body.Close()
+		assert.Equal(tt.expect, string(data))
+	}
+	// the decisions are cached by url
+	assert.Equal(int32(2), heads.Load())
+}
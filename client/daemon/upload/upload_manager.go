@@ -23,9 +23,11 @@ import (
 	"math"
 	"net"
 	"net/http"
+	"os"
 
 	"github.com/go-http-utils/headers"
 	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
 	"golang.org/x/time/rate"
 
 	"d7y.io/dragonfly/v2/client/clientutil"
@@ -38,6 +40,8 @@ var _ *logger.SugaredLoggerOnWith // pin this package for no log code generation
 type Manager interface {
 	Serve(lis net.Listener) error
 	Stop() error
+	// SetRateLimit adjusts the total upload rate limit at runtime, rate.Inf disables the limit
+	SetRateLimit(limit rate.Limit)
 }
 
 type uploadManager struct {
@@ -55,6 +59,7 @@ const (
 func NewUploadManager(s storage.Manager, opts ...func(*uploadManager)) (Manager, error) {
 	u := &uploadManager{
 		Server:         &http.Server{},
+		Limiter:        rate.NewLimiter(rate.Inf, 0),
 		StorageManager: s,
 	}
 	u.initRouter()
@@ -73,7 +78,7 @@ func WithLimiter(limiter *rate.Limiter) func(*uploadManager) {
 
 func (um *uploadManager) initRouter() {
 	r := mux.NewRouter()
-	r.HandleFunc(PeerDownloadHTTPPathPrefix+"{taskPrefix:.*}/"+"{task:.*}", um.handleUpload).Queries("peerId", "{.*}").Methods("GET")
+	r.HandleFunc(PeerDownloadHTTPPathPrefix+"{taskPrefix:.*}/"+"{task:.*}", um.handleUpload).Queries("peerId", "{.*}").Methods("GET", "HEAD")
 	um.Server.Handler = r
 }
 
@@ -85,7 +90,19 @@ func (um *uploadManager) Stop() error {
 	return um.Server.Shutdown(context.Background())
 }
 
-// handleUpload uses to upload a task file when other peers download from it.
+func (um *uploadManager) SetRateLimit(limit rate.Limit) {
+	burst := 0
+	if limit != rate.Inf {
+		burst = int(limit)
+	}
+	// update burst first to avoid the limiter waits with a burst of the old limit
+	um.Limiter.SetBurst(burst)
+	um.Limiter.SetLimit(limit)
+	logger.Infof("upload rate limit is set to %v", limit)
+}
+
+// handleUpload uses to upload a task file when other peers download from it,
+// HEAD requests only check whether the piece exists without transferring data.
 func (um *uploadManager) handleUpload(w http.ResponseWriter, r *http.Request) {
 	var (
 		task = mux.Vars(r)["task"]
@@ -122,22 +139,31 @@ func (um *uploadManager) handleUpload(w http.ResponseWriter, r *http.Request) {
 		})
 	if err != nil {
 		sLogger.Errorf("get task data failed: %s", err)
+		if r.Method == http.MethodHead && isNotFound(err) {
+			w.Header().Del(headers.ContentLength)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
 		http.Error(w, fmt.Sprintf("get piece data error: %s", err), http.StatusInternalServerError)
 		return
 	}
 	defer closer.Close()
-	if um.Limiter != nil {
-		if err = um.Limiter.WaitN(r.Context(), int(rg[0].Length)); err != nil {
-			sLogger.Errorf("get limit failed: %s", err)
-			http.Error(w, fmt.Sprintf("get limit error: %s", err), http.StatusInternalServerError)
-			return
-		}
+
+	if r.Method == http.MethodHead {
+		w.Header().Set(headers.AcceptRanges, "bytes")
+		w.WriteHeader(http.StatusOK)
+		return
 	}
 
-	// if w is a socket, golang will use sendfile or splice syscall for zero copy feature
+	// if w is a socket and upload is not limited, golang will use sendfile or splice syscall for zero copy feature
 	// when start to transfer data, we could not call http.Error with header
-	if n, err := io.Copy(w, reader); err != nil {
+	if n, err := um.copyWithLimit(r.Context(), w, reader, rg[0].Length); err != nil {
 		sLogger.Errorf("transfer data failed: %s", err)
+		if n == 0 {
+			// nothing is transferred, like waiting limit failed
+			w.Header().Del(headers.ContentLength)
+			http.Error(w, fmt.Sprintf("transfer data error: %s", err), http.StatusInternalServerError)
+		}
 		return
 	} else if n != rg[0].Length {
 		sLogger.Errorf("transferred data length not match request, request: %d, transferred: %d",
@@ -145,3 +171,34 @@ func (um *uploadManager) handleUpload(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+func isNotFound(err error) bool {
+	return errors.Is(err, storage.ErrTaskNotFound) || errors.Is(err, storage.ErrPieceNotFound) || errors.Is(err, os.ErrNotExist)
+}
+
+// copyWithLimit copies length bytes from reader to w in chunks no larger than the burst of limiter,
+// every chunk waits its tokens before copying, so the total upload bandwidth is shared by all requests.
+// Zero copy is only kept without limit, the chunks are copied through a user space buffer
+func (um *uploadManager) copyWithLimit(ctx context.Context, w io.Writer, reader io.Reader, length int64) (int64, error) {
+	if um.Limiter == nil || um.Limiter.Limit() == rate.Inf {
+		return io.Copy(w, reader)
+	}
+
+	var written int64
+	for written < length {
+		chunk := length - written
+		if burst := int64(um.Limiter.Burst()); burst > 0 && chunk > burst {
+			chunk = burst
+		}
+		if err := um.Limiter.WaitN(ctx, int(chunk)); err != nil {
+			return written, err
+		}
+		// io.CopyN wraps reader with io.LimitReader, which hides the file from sendfile or splice syscall
+		n, err := io.CopyN(w, reader, chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
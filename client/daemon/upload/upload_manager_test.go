@@ -107,3 +107,150 @@ func TestUploadManager_Serve(t *testing.T) {
 		assert.Equal(tt.targetPieceData, data)
 	}
 }
+
+func TestUploadManager_RateLimit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	assert := testifyassert.New(t)
+	testData, err := os.ReadFile(test.File)
+	assert.Nil(err, "load test file")
+
+	mockStorageManager := mock_storage.NewMockManager(ctrl)
+	mockStorageManager.EXPECT().ReadPiece(gomock.Any(), gomock.Any()).AnyTimes().
+		DoAndReturn(func(ctx context.Context, req *storage.ReadPieceRequest) (io.Reader, io.Closer, error) {
+			return bytes.NewBuffer(testData[req.Range.Start : req.Range.Start+req.Range.Length]),
+				io.NopCloser(nil), nil
+		})
+
+	// the piece is bigger than the burst, it is uploaded in chunks
+	um, err := NewUploadManager(mockStorageManager, WithLimiter(rate.NewLimiter(1024*1024, 1024)))
+	assert.Nil(err, "NewUploadManager")
+
+	listen, err := net.Listen("tcp4", "127.0.0.1:0")
+	assert.Nil(err, "Listen")
+	addr := listen.Addr().String()
+
+	go func() {
+		if err := um.Serve(listen); err != nil && err != http.ErrServerClosed {
+			t.Error(err)
+		}
+	}()
+	defer um.Stop()
+
+	download := func() []byte {
+		req, _ := http.NewRequest(http.MethodGet,
+			fmt.Sprintf("http://%s%s%s/%s?peerId=%s", addr, PeerDownloadHTTPPathPrefix, "666", "task-0", "peer-0"), nil)
+		req.Header.Add("Range", fmt.Sprintf("bytes=0-%d", len(testData)-1))
+		resp, err := http.DefaultClient.Do(req)
+		assert.Nil(err, "get piece data")
+		defer resp.Body.Close()
+		assert.Equal(http.StatusOK, resp.StatusCode)
+		data, _ := io.ReadAll(resp.Body)
+		return data
+	}
+	assert.Equal(testData, download())
+
+	limiter := um.(*uploadManager).Limiter
+	um.SetRateLimit(2048)
+	assert.Equal(rate.Limit(2048), limiter.Limit())
+	assert.Equal(2048, limiter.Burst())
+
+	um.SetRateLimit(rate.Inf)
+	assert.Equal(rate.Inf, limiter.Limit())
+	assert.Equal(testData, download())
+}
+
+func TestUploadManager_Head(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	assert := testifyassert.New(t)
+	testData, err := os.ReadFile(test.File)
+	assert.Nil(err, "load test file")
+
+	mockStorageManager := mock_storage.NewMockManager(ctrl)
+	mockStorageManager.EXPECT().ReadPiece(gomock.Any(), gomock.Any()).AnyTimes().
+		DoAndReturn(func(ctx context.Context, req *storage.ReadPieceRequest) (io.Reader, io.Closer, error) {
+			switch req.TaskID {
+			case "task-miss":
+				return nil, nil, storage.ErrTaskNotFound
+			case "task-piece-miss":
+				return nil, nil, storage.ErrPieceNotFound
+			case "task-error":
+				return nil, nil, fmt.Errorf("disk error")
+			}
+			return bytes.NewBuffer(testData[req.Range.Start : req.Range.Start+req.Range.Length]),
+				io.NopCloser(nil), nil
+		})
+
+	um, err := NewUploadManager(mockStorageManager)
+	assert.Nil(err, "NewUploadManager")
+
+	listen, err := net.Listen("tcp4", "127.0.0.1:0")
+	assert.Nil(err, "Listen")
+	addr := listen.Addr().String()
+
+	go func() {
+		if err := um.Serve(listen); err != nil && err != http.ErrServerClosed {
+			t.Error(err)
+		}
+	}()
+	defer um.Stop()
+
+	tests := []struct {
+		name       string
+		taskID     string
+		statusCode int
+		// contentLength is not checked when it is -1
+		contentLength int64
+	}{
+		{
+			name:          "piece exists",
+			taskID:        "task-hit",
+			statusCode:    http.StatusOK,
+			contentLength: 512,
+		},
+		{
+			name:          "task not found",
+			taskID:        "task-miss",
+			statusCode:    http.StatusNotFound,
+			contentLength: -1,
+		},
+		{
+			name:          "piece not found",
+			taskID:        "task-piece-miss",
+			statusCode:    http.StatusNotFound,
+			contentLength: -1,
+		},
+		{
+			name:          "read piece error",
+			taskID:        "task-error",
+			statusCode:    http.StatusInternalServerError,
+			contentLength: -1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := testifyassert.New(t)
+			req, _ := http.NewRequest(http.MethodHead,
+				fmt.Sprintf("http://%s%s%s/%s?peerId=%s", addr, PeerDownloadHTTPPathPrefix, "666", tt.taskID, "peer-0"), nil)
+			req.Header.Add("Range", "bytes=512-1023")
+
+			resp, err := http.DefaultClient.Do(req)
+			assert.Nil(err, "head piece")
+			defer resp.Body.Close()
+
+			assert.Equal(tt.statusCode, resp.StatusCode)
+			if tt.contentLength >= 0 {
+				assert.Equal(tt.contentLength, resp.ContentLength)
+			}
+			if tt.statusCode == http.StatusOK {
+				assert.Equal("bytes", resp.Header.Get("Accept-Ranges"))
+			}
+			data, _ := io.ReadAll(resp.Body)
+			assert.Empty(data)
+		})
+	}
+}
@@ -19,8 +19,11 @@ package main
 import (
 	_ "d7y.io/dragonfly/v2/cdn/supervisor/cdn/storage/disk"   //nolint:gci    // Register disk storage manager
 	_ "d7y.io/dragonfly/v2/cdn/supervisor/cdn/storage/hybrid" // Register hybrid storage manager
+	_ "d7y.io/dragonfly/v2/pkg/source/gcsprotocol"            // Register gcs client
 	_ "d7y.io/dragonfly/v2/pkg/source/httpprotocol"           // Register http client
+	_ "d7y.io/dragonfly/v2/pkg/source/ociprotocol"            // Register oci client
 	_ "d7y.io/dragonfly/v2/pkg/source/ossprotocol"            // Register oss client
+	_ "d7y.io/dragonfly/v2/pkg/source/sftpprotocol"           // Register sftp client
 
 	"d7y.io/dragonfly/v2/cmd/cdn/cmd" //nolint:gci
 )
@@ -24,6 +24,15 @@ import (
 
 	// Register oss client
 	_ "d7y.io/dragonfly/v2/pkg/source/ossprotocol"
+
+	// Register gcs client
+	_ "d7y.io/dragonfly/v2/pkg/source/gcsprotocol"
+
+	// Register oci client
+	_ "d7y.io/dragonfly/v2/pkg/source/ociprotocol"
+
+	// Register sftp client
+	_ "d7y.io/dragonfly/v2/pkg/source/sftpprotocol"
 )
 
 func main() {
@@ -0,0 +1,90 @@
+/*
+ *     Copyright 2022 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dfnet
+
+import (
+	"net"
+	"sync"
+	"syscall"
+
+	logger "d7y.io/dragonfly/v2/internal/dflog"
+)
+
+// TrafficMark marks sockets for QoS classification of traffic
+type TrafficMark struct {
+	// DSCP is the differentiated services code point in IP TOS or IPv6 traffic class, 0 ~ 63
+	DSCP int `mapstructure:"dscp" yaml:"dscp"`
+	// Mark is the SO_MARK of sockets, it's only supported on linux and requires CAP_NET_ADMIN
+	Mark int `mapstructure:"mark" yaml:"mark"`
+}
+
+// warnTrafficMarkOnce avoids logging the same failure for every connection
+var warnTrafficMarkOnce sync.Once
+
+// Enabled returns whether any marking is set
+func (m TrafficMark) Enabled() bool {
+	return m.DSCP > 0 || m.Mark > 0
+}
+
+// Control marks the socket, it's used as net.Dialer.Control and net.ListenConfig.Control.
+// Failures are logged and never returned, the connection continues without marking.
+func (m TrafficMark) Control(network, address string, c syscall.RawConn) error {
+	if !m.Enabled() {
+		return nil
+	}
+
+	var err error
+	if cerr := c.Control(func(fd uintptr) {
+		err = setTrafficMark(fd, m)
+	}); cerr != nil {
+		err = cerr
+	}
+	if err != nil {
+		warnTrafficMarkOnce.Do(func() {
+			logger.Warnf("mark %s socket of %s with dscp %d and mark %d error: %s, continue without marking",
+				network, address, m.DSCP, m.Mark, err)
+		})
+		logger.Debugf("mark %s socket of %s error: %s", network, address, err)
+	}
+	return nil
+}
+
+// Listener returns a listener which marks the accepted connections of ln
+func (m TrafficMark) Listener(ln net.Listener) net.Listener {
+	if !m.Enabled() {
+		return ln
+	}
+	return &trafficMarkListener{Listener: ln, mark: m}
+}
+
+type trafficMarkListener struct {
+	net.Listener
+	mark TrafficMark
+}
+
+func (l *trafficMarkListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	if sc, ok := conn.(syscall.Conn); ok {
+		if raw, err := sc.SyscallConn(); err == nil {
+			_ = l.mark.Control(conn.LocalAddr().Network(), conn.RemoteAddr().String(), raw)
+		}
+	}
+	return conn, nil
+}
@@ -0,0 +1,51 @@
+//go:build linux
+// +build linux
+
+/*
+ *     Copyright 2022 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dfnet
+
+import (
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+func setTrafficMark(fd uintptr, m TrafficMark) error {
+	if m.DSCP > 0 {
+		tos := m.DSCP << 2
+		domain, err := unix.GetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_DOMAIN)
+		if err != nil {
+			return errors.Wrap(err, "get socket domain")
+		}
+		if domain == unix.AF_INET6 {
+			if err := unix.SetsockoptInt(int(fd), unix.IPPROTO_IPV6, unix.IPV6_TCLASS, tos); err != nil {
+				return errors.Wrap(err, "set IPV6_TCLASS")
+			}
+		}
+		// dual stack sockets send ipv4 traffic with IP_TOS, the failure is only returned for ipv4 sockets
+		if err := unix.SetsockoptInt(int(fd), unix.IPPROTO_IP, unix.IP_TOS, tos); err != nil && domain == unix.AF_INET {
+			return errors.Wrap(err, "set IP_TOS")
+		}
+	}
+
+	if m.Mark > 0 {
+		if err := unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_MARK, m.Mark); err != nil {
+			return errors.Wrap(err, "set SO_MARK")
+		}
+	}
+	return nil
+}
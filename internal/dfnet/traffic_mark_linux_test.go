@@ -0,0 +1,116 @@
+//go:build linux
+// +build linux
+
+/*
+ *     Copyright 2022 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dfnet
+
+import (
+	"errors"
+	"net"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sys/unix"
+)
+
+func getsockopt(t *testing.T, conn net.Conn, level, opt int) int {
+	raw, err := conn.(syscall.Conn).SyscallConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		value int
+		gerr  error
+	)
+	if err := raw.Control(func(fd uintptr) {
+		value, gerr = unix.GetsockoptInt(int(fd), level, opt)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if gerr != nil {
+		t.Fatal(gerr)
+	}
+	return value
+}
+
+func TestTrafficMark_DSCP(t *testing.T) {
+	assert := assert.New(t)
+	mark := TrafficMark{DSCP: 46}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln = mark.Listener(ln)
+	defer ln.Close()
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			t.Error(err)
+		}
+		accepted <- conn
+	}()
+
+	dialer := &net.Dialer{Control: mark.Control}
+	conn, err := dialer.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	assert.Equal(46<<2, getsockopt(t, conn, unix.IPPROTO_IP, unix.IP_TOS))
+
+	serverConn := <-accepted
+	if serverConn == nil {
+		t.FailNow()
+	}
+	defer serverConn.Close()
+	assert.Equal(46<<2, getsockopt(t, serverConn, unix.IPPROTO_IP, unix.IP_TOS))
+}
+
+func TestTrafficMark_Mark(t *testing.T) {
+	assert := assert.New(t)
+	mark := TrafficMark{Mark: 100}
+	fd, err := unix.Socket(unix.AF_INET, unix.SOCK_STREAM, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unix.Close(fd)
+
+	if err := setTrafficMark(uintptr(fd), mark); err != nil {
+		if errors.Is(err, unix.EPERM) {
+			t.Skip("CAP_NET_ADMIN is required to set SO_MARK")
+		}
+		t.Fatal(err)
+	}
+	value, err := unix.GetsockoptInt(fd, unix.SOL_SOCKET, unix.SO_MARK)
+	assert.Nil(err)
+	assert.Equal(100, value)
+}
+
+func TestTrafficMark_Disabled(t *testing.T) {
+	assert := assert.New(t)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	assert.Equal(ln, TrafficMark{}.Listener(ln))
+	assert.False(TrafficMark{}.Enabled())
+}
@@ -41,6 +41,9 @@ type Config struct {
 
 	// Metrics configuration
 	Metrics *RestConfig `yaml:"metrics" mapstructure:"metrics"`
+
+	// Object storage configuration
+	ObjectStorage *ObjectStorageConfig `yaml:"objectStorage" mapstructure:"objectStorage"`
 }
 
 type ServerConfig struct {
@@ -157,6 +160,26 @@ type LocalCacheConfig struct {
 	TTL time.Duration `yaml:"ttl" mapstructure:"ttl"`
 }
 
+type ObjectStorageConfig struct {
+	// Enable object storage
+	Enable bool `yaml:"enable" mapstructure:"enable"`
+
+	// Object storage name of type, it can be s3 or oss
+	Name string `yaml:"name" mapstructure:"name"`
+
+	// Storage region
+	Region string `yaml:"region" mapstructure:"region"`
+
+	// Datacenter endpoint
+	Endpoint string `yaml:"endpoint" mapstructure:"endpoint"`
+
+	// Access key id
+	AccessKey string `yaml:"accessKey" mapstructure:"accessKey"`
+
+	// Access key secret
+	SecretKey string `yaml:"secretKey" mapstructure:"secretKey"`
+}
+
 type RestConfig struct {
 	// REST server address
 	Addr string `yaml:"addr" mapstructure:"addr"`
@@ -325,5 +348,15 @@ func (cfg *Config) Validate() error {
 		return errors.New("empty metrics addr is not specified")
 	}
 
+	if cfg.ObjectStorage != nil && cfg.ObjectStorage.Enable {
+		if cfg.ObjectStorage.Name == "" {
+			return errors.New("empty object storage name is not specified")
+		}
+
+		if cfg.ObjectStorage.Endpoint == "" {
+			return errors.New("empty object storage endpoint is not specified")
+		}
+	}
+
 	return nil
 }
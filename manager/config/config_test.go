@@ -79,6 +79,14 @@ func TestManagerConfig_Load(t *testing.T) {
 		Metrics: &RestConfig{
 			Addr: ":8000",
 		},
+		ObjectStorage: &ObjectStorageConfig{
+			Enable:    true,
+			Name:      "s3",
+			Region:    "bar",
+			Endpoint:  "127.0.0.1",
+			AccessKey: "foo",
+			SecretKey: "bar",
+		},
 	}
 
 	managerConfigYAML := &Config{}
@@ -112,7 +112,7 @@ func New(cfg *config.Config, d dfpath.Dfpath) (*Server, error) {
 			grpc.ChainStreamInterceptor(otelgrpc.StreamServerInterceptor()),
 		}
 	}
-	grpcServer := rpcserver.New(db, cache, searcher, options...)
+	grpcServer := rpcserver.New(cfg, db, cache, searcher, options...)
 	s.grpcServer = grpcServer
 
 	// Initialize prometheus
@@ -35,6 +35,7 @@ import (
 
 	logger "d7y.io/dragonfly/v2/internal/dflog"
 	"d7y.io/dragonfly/v2/manager/cache"
+	"d7y.io/dragonfly/v2/manager/config"
 	"d7y.io/dragonfly/v2/manager/database"
 	"d7y.io/dragonfly/v2/manager/model"
 	"d7y.io/dragonfly/v2/manager/searcher"
@@ -56,6 +57,7 @@ var defaultUnaryMiddleWares = []grpc.UnaryServerInterceptor{
 }
 
 type Server struct {
+	config   *config.Config
 	db       *gorm.DB
 	rdb      *redis.Client
 	cache    *cache.Cache
@@ -63,8 +65,9 @@ type Server struct {
 	manager.UnimplementedManagerServer
 }
 
-func New(database *database.Database, cache *cache.Cache, searcher searcher.Searcher, opts ...grpc.ServerOption) *grpc.Server {
+func New(cfg *config.Config, database *database.Database, cache *cache.Cache, searcher searcher.Searcher, opts ...grpc.ServerOption) *grpc.Server {
 	server := &Server{
+		config:   cfg,
 		db:       database.DB,
 		rdb:      database.RDB,
 		cache:    cache,
@@ -459,6 +462,20 @@ func (s *Server) ListSchedulers(ctx context.Context, req *manager.ListSchedulers
 	return &pbListSchedulersResponse, nil
 }
 
+func (s *Server) GetObjectStorage(ctx context.Context, req *manager.GetObjectStorageRequest) (*manager.ObjectStorage, error) {
+	if s.config.ObjectStorage == nil || !s.config.ObjectStorage.Enable {
+		return nil, status.Error(codes.NotFound, "object storage is disabled")
+	}
+
+	return &manager.ObjectStorage{
+		Name:      s.config.ObjectStorage.Name,
+		Region:    s.config.ObjectStorage.Region,
+		Endpoint:  s.config.ObjectStorage.Endpoint,
+		AccessKey: s.config.ObjectStorage.AccessKey,
+		SecretKey: s.config.ObjectStorage.SecretKey,
+	}, nil
+}
+
 func (s *Server) KeepAlive(stream manager.Manager_KeepAliveServer) error {
 	req, err := stream.Recv()
 	if err != nil {
@@ -52,6 +52,9 @@ type GetSchedulerClustersQuery struct {
 }
 
 type SchedulerClusterConfig struct {
+	RetryLimit           uint32 `yaml:"retryLimit" mapstructure:"retryLimit" json:"retry_limit" binding:"omitempty,gte=1,lte=100"`
+	RetryBackSourceLimit uint32 `yaml:"retryBackSourceLimit" mapstructure:"retryBackSourceLimit" json:"retry_back_source_limit" binding:"omitempty,gte=1,lte=100"`
+	CandidateParentLimit uint32 `yaml:"candidateParentLimit" mapstructure:"candidateParentLimit" json:"candidate_parent_limit" binding:"omitempty,gte=1,lte=20"`
 }
 
 type SchedulerClusterClientConfig struct {
@@ -17,8 +17,6 @@
 package idgen
 
 import (
-	"strings"
-
 	"d7y.io/dragonfly/v2/pkg/rpc/base"
 	"d7y.io/dragonfly/v2/pkg/util/digestutils"
 	"d7y.io/dragonfly/v2/pkg/util/net/urlutils"
@@ -26,8 +24,8 @@ import (
 
 func taskID(url string, meta *base.UrlMeta, ignoreRange bool) string {
 	var filters []string
-	if meta != nil && meta.Filter != "" {
-		filters = strings.Split(meta.Filter, "&")
+	if meta != nil {
+		filters = urlutils.SplitFilter(meta.Filter)
 	}
 
 	var data []string
@@ -50,7 +48,7 @@ func taskID(url string, meta *base.UrlMeta, ignoreRange bool) string {
 }
 
 // TaskID generates a task id.
-// filter is separated by & character.
+// filter is separated by & or comma character.
 func TaskID(url string, meta *base.UrlMeta) string {
 	return taskID(url, meta, false)
 }
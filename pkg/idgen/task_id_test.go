@@ -80,6 +80,18 @@ func TestTaskID(t *testing.T) {
 				assert.Equal("2773851c628744fb7933003195db436ce397c1722920696c4274ff804d86920b", d)
 			},
 		},
+		{
+			name: "generate taskID with comma-separated filter",
+			url:  "https://example.com?foo=foo&bar=bar",
+			meta: &base.UrlMeta{
+				Tag:    "foo",
+				Filter: "foo, bar",
+			},
+			expect: func(t *testing.T, d interface{}) {
+				assert := assert.New(t)
+				assert.Equal("2773851c628744fb7933003195db436ce397c1722920696c4274ff804d86920b", d)
+			},
+		},
 		{
 			name: "generate taskID with tag",
 			url:  "https://example.com",
@@ -0,0 +1,185 @@
+/*
+ *     Copyright 2022 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"d7y.io/dragonfly/v2/internal/dferrors"
+	logger "d7y.io/dragonfly/v2/internal/dflog"
+	"d7y.io/dragonfly/v2/pkg/rpc/base"
+)
+
+// ErrCircuitOpen is returned when the circuit breaker of cdn is open
+var ErrCircuitOpen = errors.New("cdn circuit breaker is open")
+
+// CircuitState is the state of circuit breaker of cdn
+type CircuitState int
+
+const (
+	// CircuitClosed lets calls pass
+	CircuitClosed CircuitState = iota
+	// CircuitOpen fails calls fast until cooldown is over
+	CircuitOpen
+	// CircuitHalfOpen lets one call pass to test recovery
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("unknown(%d)", int(s))
+}
+
+type circuit struct {
+	state    CircuitState
+	failures int
+	openAt   time.Time
+	// probing indicates a call is testing recovery in half-open state
+	probing bool
+}
+
+// circuitBreaker opens the circuit of a cdn address after consecutive failures,
+// it's disabled when failureThreshold is not positive
+type circuitBreaker struct {
+	mu               sync.Mutex
+	failureThreshold int
+	cooldown         time.Duration
+	circuits         map[string]*circuit
+
+	// Current time, replaced in tests
+	now func() time.Time
+}
+
+func newCircuitBreaker(failureThreshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{
+		failureThreshold: failureThreshold,
+		cooldown:         cooldown,
+		circuits:         map[string]*circuit{},
+		now:              time.Now,
+	}
+}
+
+// allow returns ErrCircuitOpen when the calls to addr should fail fast,
+// every allowed call must be recorded by record
+func (b *circuitBreaker) allow(addr string) error {
+	if b.failureThreshold <= 0 {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c, ok := b.circuits[addr]
+	if !ok {
+		return nil
+	}
+
+	switch c.state {
+	case CircuitOpen:
+		if b.now().Sub(c.openAt) < b.cooldown {
+			return ErrCircuitOpen
+		}
+		logger.GrpcLogger.Infof("cdn %s circuit breaker is half-open", addr)
+		c.state = CircuitHalfOpen
+		c.probing = true
+	case CircuitHalfOpen:
+		if c.probing {
+			return ErrCircuitOpen
+		}
+		c.probing = true
+	}
+	return nil
+}
+
+// record updates the circuit of addr with the result of call
+func (b *circuitBreaker) record(addr string, err error) {
+	if b.failureThreshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c, ok := b.circuits[addr]
+	if !isCircuitFailure(err) {
+		if ok && c.state != CircuitClosed {
+			logger.GrpcLogger.Infof("cdn %s circuit breaker is closed", addr)
+		}
+		delete(b.circuits, addr)
+		return
+	}
+
+	if !ok {
+		c = &circuit{}
+		b.circuits[addr] = c
+	}
+
+	c.failures++
+	c.probing = false
+	if c.state == CircuitHalfOpen || c.failures >= b.failureThreshold {
+		if c.state != CircuitOpen {
+			logger.GrpcLogger.Warnf("cdn %s circuit breaker is open after %d consecutive failures: %v", addr, c.failures, err)
+		}
+		c.state = CircuitOpen
+		c.openAt = b.now()
+	}
+}
+
+// state returns the circuit state of addr
+func (b *circuitBreaker) state(addr string) CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if c, ok := b.circuits[addr]; ok {
+		return c.state
+	}
+	return CircuitClosed
+}
+
+// isCircuitFailure returns whether err indicates cdn is unavailable or overloaded,
+// the errors responded by cdn except resource lacked are not failures
+func isCircuitFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if e, ok := errors.Cause(err).(*dferrors.DfError); ok {
+		return e.Code == base.Code_ResourceLacked
+	}
+
+	return !errors.Is(err, context.Canceled) && status.Code(err) != codes.Canceled
+}
+
+// circuitOpenError converts ErrCircuitOpen of addr to resource lacked error,
+// the call is not retried on the same cdn, and the task can be migrated to another cdn
+func circuitOpenError(addr string) error {
+	return dferrors.Newf(base.Code_ResourceLacked, "%s: %v", addr, ErrCircuitOpen)
+}
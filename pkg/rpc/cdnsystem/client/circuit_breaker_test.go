@@ -0,0 +1,154 @@
+/*
+ *     Copyright 2022 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"d7y.io/dragonfly/v2/internal/dferrors"
+	"d7y.io/dragonfly/v2/pkg/rpc/base"
+)
+
+const mockCdnAddr = "127.0.0.1:8003"
+
+var errMockUnavailable = status.Error(codes.Unavailable, "unavailable")
+
+func TestCircuitBreaker(t *testing.T) {
+	tests := []struct {
+		name             string
+		failureThreshold int
+		expect           func(t *testing.T, b *circuitBreaker, now *time.Time)
+	}{
+		{
+			name:             "open after consecutive failures",
+			failureThreshold: 2,
+			expect: func(t *testing.T, b *circuitBreaker, now *time.Time) {
+				assert := assert.New(t)
+				assert.NoError(b.allow(mockCdnAddr))
+				b.record(mockCdnAddr, errMockUnavailable)
+				assert.Equal(b.state(mockCdnAddr), CircuitClosed)
+
+				assert.NoError(b.allow(mockCdnAddr))
+				b.record(mockCdnAddr, errMockUnavailable)
+				assert.Equal(b.state(mockCdnAddr), CircuitOpen)
+				assert.ErrorIs(b.allow(mockCdnAddr), ErrCircuitOpen)
+				assert.NoError(b.allow("127.0.0.1:8004"))
+			},
+		},
+		{
+			name:             "success resets failures",
+			failureThreshold: 2,
+			expect: func(t *testing.T, b *circuitBreaker, now *time.Time) {
+				assert := assert.New(t)
+				b.record(mockCdnAddr, errMockUnavailable)
+				b.record(mockCdnAddr, nil)
+				b.record(mockCdnAddr, errMockUnavailable)
+				assert.Equal(b.state(mockCdnAddr), CircuitClosed)
+			},
+		},
+		{
+			name:             "half-open then close",
+			failureThreshold: 1,
+			expect: func(t *testing.T, b *circuitBreaker, now *time.Time) {
+				assert := assert.New(t)
+				b.record(mockCdnAddr, errMockUnavailable)
+				assert.Equal(b.state(mockCdnAddr), CircuitOpen)
+
+				*now = now.Add(time.Second)
+				assert.NoError(b.allow(mockCdnAddr))
+				assert.Equal(b.state(mockCdnAddr), CircuitHalfOpen)
+				// only one call tests recovery
+				assert.ErrorIs(b.allow(mockCdnAddr), ErrCircuitOpen)
+
+				b.record(mockCdnAddr, nil)
+				assert.Equal(b.state(mockCdnAddr), CircuitClosed)
+				assert.NoError(b.allow(mockCdnAddr))
+			},
+		},
+		{
+			name:             "half-open then open",
+			failureThreshold: 3,
+			expect: func(t *testing.T, b *circuitBreaker, now *time.Time) {
+				assert := assert.New(t)
+				for i := 0; i < 3; i++ {
+					b.record(mockCdnAddr, errMockUnavailable)
+				}
+				assert.Equal(b.state(mockCdnAddr), CircuitOpen)
+
+				*now = now.Add(time.Second)
+				assert.NoError(b.allow(mockCdnAddr))
+				b.record(mockCdnAddr, errMockUnavailable)
+				assert.Equal(b.state(mockCdnAddr), CircuitOpen)
+				assert.ErrorIs(b.allow(mockCdnAddr), ErrCircuitOpen)
+			},
+		},
+		{
+			name:             "errors which are not failures",
+			failureThreshold: 1,
+			expect: func(t *testing.T, b *circuitBreaker, now *time.Time) {
+				assert := assert.New(t)
+				b.record(mockCdnAddr, context.Canceled)
+				b.record(mockCdnAddr, status.Error(codes.Canceled, "canceled"))
+				b.record(mockCdnAddr, dferrors.New(base.Code_CDNTaskNotFound, "not found"))
+				assert.Equal(b.state(mockCdnAddr), CircuitClosed)
+
+				b.record(mockCdnAddr, dferrors.New(base.Code_ResourceLacked, "resource lacked"))
+				assert.Equal(b.state(mockCdnAddr), CircuitOpen)
+			},
+		},
+		{
+			name:             "disabled",
+			failureThreshold: 0,
+			expect: func(t *testing.T, b *circuitBreaker, now *time.Time) {
+				assert := assert.New(t)
+				for i := 0; i < 10; i++ {
+					b.record(mockCdnAddr, errors.New("foo"))
+				}
+				assert.NoError(b.allow(mockCdnAddr))
+				assert.Equal(b.state(mockCdnAddr), CircuitClosed)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			b := newCircuitBreaker(tc.failureThreshold, time.Second)
+			now := time.Now()
+			b.now = func() time.Time {
+				return now
+			}
+
+			tc.expect(t, b, &now)
+		})
+	}
+}
+
+func TestCircuitOpenError(t *testing.T) {
+	assert := assert.New(t)
+	err := circuitOpenError(mockCdnAddr)
+	e, ok := err.(*dferrors.DfError)
+	assert.True(ok)
+	assert.Equal(e.Code, base.Code_ResourceLacked)
+	assert.Contains(e.Message, ErrCircuitOpen.Error())
+}
@@ -23,6 +23,9 @@ import (
 
 	"github.com/pkg/errors"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
 
 	logger "d7y.io/dragonfly/v2/internal/dflog"
 	"d7y.io/dragonfly/v2/internal/dfnet"
@@ -31,15 +34,102 @@ import (
 	"d7y.io/dragonfly/v2/pkg/rpc/cdnsystem"
 )
 
+const (
+	// healthCheckTimeout is the timeout of cdn health check
+	healthCheckTimeout = 5 * time.Second
+
+	// defaultCircuitFailureThreshold is the default count of consecutive failures to open the circuit of cdn
+	defaultCircuitFailureThreshold = 5
+
+	// defaultCircuitCooldown is the default duration to fail fast before testing recovery of cdn
+	defaultCircuitCooldown = 30 * time.Second
+)
+
+var (
+	// ErrClientClosing is returned by ObtainSeeds when the client is closing gracefully
+	ErrClientClosing = errors.New("cdn client is closing")
+
+	// ErrCdnUnreachable is returned by CheckHealth when the cdn can not be connected
+	ErrCdnUnreachable = errors.New("cdn is unreachable")
+
+	// ErrCdnNotServing is returned by CheckHealth when the cdn is connected but not serving
+	ErrCdnNotServing = errors.New("cdn is not serving")
+
+	// ErrFirstResponseTimeout is returned by PieceSeedStream.Recv when the cdn does not send
+	// the first piece seed in time, the stream is canceled and the caller can fail over
+	ErrFirstResponseTimeout = errors.New("cdn first response timeout")
+)
+
+type options struct {
+	dialOptions             []grpc.DialOption
+	weights                 map[string]int
+	circuitFailureThreshold int
+	circuitCooldown         time.Duration
+	firstResponseTimeout    time.Duration
+}
+
+// Option is the option of cdn client
+type Option func(o *options)
+
+// WithDialOptions sets the grpc dial options
+func WithDialOptions(opts ...grpc.DialOption) Option {
+	return func(o *options) {
+		o.dialOptions = append(o.dialOptions, opts...)
+	}
+}
+
+// WithWeights sets the weights of cdns by endpoint, higher-capacity cdns with larger weights get more tasks,
+// tasks are still pinned to cdns by consistent hashing of task id
+func WithWeights(weights map[string]int) Option {
+	return func(o *options) {
+		o.weights = weights
+	}
+}
+
+// WithCircuitBreaker sets the circuit breaker of cdns by endpoint, the calls to a cdn fail fast for cooldown
+// after failureThreshold consecutive failures, then one call is let pass to test recovery.
+// The circuit breaker is disabled when failureThreshold is 0.
+func WithCircuitBreaker(failureThreshold int, cooldown time.Duration) Option {
+	return func(o *options) {
+		o.circuitFailureThreshold = failureThreshold
+		o.circuitCooldown = cooldown
+	}
+}
+
+// WithFirstResponseTimeout sets the timeout to receive the first piece seed of ObtainSeeds stream,
+// the stream is canceled and Recv returns ErrFirstResponseTimeout when it's exceeded.
+// It's disabled when timeout is 0.
+func WithFirstResponseTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.firstResponseTimeout = timeout
+	}
+}
+
 func GetClientByAddr(addrs []dfnet.NetAddr, opts ...grpc.DialOption) (CdnClient, error) {
+	return GetClientByAddrWithOptions(addrs, WithDialOptions(opts...))
+}
+
+func GetClientByAddrWithOptions(addrs []dfnet.NetAddr, opts ...Option) (CdnClient, error) {
 	if len(addrs) == 0 {
 		return nil, errors.New("address list of cdn is empty")
 	}
+
+	o := &options{
+		circuitFailureThreshold: defaultCircuitFailureThreshold,
+		circuitCooldown:         defaultCircuitCooldown,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
 	cc := &cdnClient{
-		rpc.NewConnection(context.Background(), "cdn", addrs, []rpc.ConnOption{
+		Connection: rpc.NewConnection(context.Background(), "cdn", addrs, []rpc.ConnOption{
 			rpc.WithConnExpireTime(60 * time.Second),
-			rpc.WithDialOption(opts),
+			rpc.WithDialOption(o.dialOptions),
+			rpc.WithWeights(o.weights),
 		}),
+		breaker:              newCircuitBreaker(o.circuitFailureThreshold, o.circuitCooldown),
+		firstResponseTimeout: o.firstResponseTimeout,
 	}
 	return cc, nil
 }
@@ -50,10 +140,11 @@ var elasticCdnClient *cdnClient
 func GetElasticClientByAddrs(addrs []dfnet.NetAddr, opts ...grpc.DialOption) (CdnClient, error) {
 	once.Do(func() {
 		elasticCdnClient = &cdnClient{
-			rpc.NewConnection(context.Background(), "cdn-elastic", make([]dfnet.NetAddr, 0), []rpc.ConnOption{
+			Connection: rpc.NewConnection(context.Background(), "cdn-elastic", make([]dfnet.NetAddr, 0), []rpc.ConnOption{
 				rpc.WithConnExpireTime(30 * time.Second),
 				rpc.WithDialOption(opts),
 			}),
+			breaker: newCircuitBreaker(defaultCircuitFailureThreshold, defaultCircuitCooldown),
 		}
 	})
 	err := elasticCdnClient.Connection.AddServerNodes(addrs)
@@ -71,11 +162,34 @@ type CdnClient interface {
 
 	UpdateState(addrs []dfnet.NetAddr)
 
+	// CheckHealth checks whether the cdn is serving by grpc health service,
+	// it returns ErrCdnUnreachable or ErrCdnNotServing when the cdn is unhealthy
+	CheckHealth(ctx context.Context, addr dfnet.NetAddr) error
+
+	// CircuitState returns the state of circuit breaker of the cdn
+	CircuitState(addr dfnet.NetAddr) CircuitState
+
+	// CloseGracefully stops accepting new ObtainSeeds calls, waits for active streams
+	// to finish until ctx is done, then closes the connections
+	CloseGracefully(ctx context.Context) error
+
 	Close() error
 }
 
 type cdnClient struct {
 	*rpc.Connection
+
+	// mu guards closing and the increment of streams
+	mu      sync.Mutex
+	closing bool
+	// streams counts the active piece seed streams
+	streams sync.WaitGroup
+
+	// breaker fails calls to the unavailable cdns fast
+	breaker *circuitBreaker
+
+	// firstResponseTimeout is the timeout to receive the first piece seed of stream
+	firstResponseTimeout time.Duration
 }
 
 var _ CdnClient = (*cdnClient)(nil)
@@ -97,16 +211,78 @@ func (cc *cdnClient) getSeederClientWithTarget(target string) (cdnsystem.SeederC
 }
 
 func (cc *cdnClient) ObtainSeeds(ctx context.Context, sr *cdnsystem.SeedRequest, opts ...grpc.CallOption) (*PieceSeedStream, error) {
-	return newPieceSeedStream(ctx, cc, sr.TaskId, sr, opts)
+	if err := cc.acquireStream(); err != nil {
+		return nil, err
+	}
+
+	if cc.firstResponseTimeout <= 0 {
+		pss, err := newPieceSeedStream(ctx, cc, sr.TaskId, sr, opts)
+		if err != nil {
+			cc.streams.Done()
+			return nil, err
+		}
+		return pss, nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	pss, err := newPieceSeedStream(ctx, cc, sr.TaskId, sr, opts)
+	if err != nil {
+		cancel()
+		cc.streams.Done()
+		return nil, err
+	}
+	pss.watchFirstResponse(cc.firstResponseTimeout, cancel)
+	return pss, nil
+}
+
+// acquireStream counts a new active stream, it fails when the client is closing
+func (cc *cdnClient) acquireStream() error {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	if cc.closing {
+		return ErrClientClosing
+	}
+	cc.streams.Add(1)
+	return nil
+}
+
+func (cc *cdnClient) CloseGracefully(ctx context.Context) error {
+	cc.mu.Lock()
+	cc.closing = true
+	cc.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		cc.streams.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return cc.Close()
+	case <-ctx.Done():
+		logger.GrpcLogger.Warnf("cdn client close gracefully timeout, close with active streams: %v", ctx.Err())
+		if err := cc.Close(); err != nil {
+			return err
+		}
+		return ctx.Err()
+	}
 }
 
 func (cc *cdnClient) GetPieceTasks(ctx context.Context, addr dfnet.NetAddr, req *base.PieceTaskRequest, opts ...grpc.CallOption) (*base.PiecePacket, error) {
 	res, err := rpc.ExecuteWithRetry(func() (interface{}, error) {
+		if err := cc.breaker.allow(addr.GetEndpoint()); err != nil {
+			return nil, circuitOpenError(addr.GetEndpoint())
+		}
+
 		client, err := cc.getSeederClientWithTarget(addr.GetEndpoint())
 		if err != nil {
+			cc.breaker.record(addr.GetEndpoint(), err)
 			return nil, err
 		}
-		return client.GetPieceTasks(ctx, req, opts...)
+		res, err := client.GetPieceTasks(ctx, req, opts...)
+		cc.breaker.record(addr.GetEndpoint(), err)
+		return res, err
 	}, 0.2, 2.0, 3, nil)
 	if err != nil {
 		logger.WithTaskID(req.TaskId).Infof("GetPieceTasks: invoke cdn node %s GetPieceTasks failed: %v", addr.GetEndpoint(), err)
@@ -114,3 +290,47 @@ func (cc *cdnClient) GetPieceTasks(ctx context.Context, addr dfnet.NetAddr, req
 	}
 	return res.(*base.PiecePacket), nil
 }
+
+// obtainSeeds creates the seed stream on the cdn which the hash key is pinned to, it consults the circuit breaker
+// of the cdn before creating the stream
+func (cc *cdnClient) obtainSeeds(ctx context.Context, hashKey string, stick bool, sr *cdnsystem.SeedRequest, opts ...grpc.CallOption) (cdnsystem.Seeder_ObtainSeedsClient, string, error) {
+	client, target, err := cc.getCdnClient(hashKey, stick)
+	if err != nil {
+		return nil, target, err
+	}
+
+	if err := cc.breaker.allow(target); err != nil {
+		return nil, target, circuitOpenError(target)
+	}
+
+	stream, err := client.ObtainSeeds(ctx, sr, opts...)
+	cc.breaker.record(target, err)
+	return stream, target, err
+}
+
+func (cc *cdnClient) CircuitState(addr dfnet.NetAddr) CircuitState {
+	return cc.breaker.state(addr.GetEndpoint())
+}
+
+func (cc *cdnClient) CheckHealth(ctx context.Context, addr dfnet.NetAddr) error {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	conn, err := cc.Connection.GetClientConnByTarget(addr.GetEndpoint())
+	if err != nil {
+		return errors.Wrapf(ErrCdnUnreachable, "connect cdn %s failed: %v", addr.GetEndpoint(), err)
+	}
+
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		if status.Code(err) == codes.Unavailable {
+			return errors.Wrapf(ErrCdnUnreachable, "check cdn %s health failed: %v", addr.GetEndpoint(), err)
+		}
+		return errors.Wrapf(err, "check cdn %s health failed", addr.GetEndpoint())
+	}
+
+	if resp.Status != healthpb.HealthCheckResponse_SERVING {
+		return errors.Wrapf(ErrCdnNotServing, "cdn %s status is %s", addr.GetEndpoint(), resp.Status)
+	}
+	return nil
+}
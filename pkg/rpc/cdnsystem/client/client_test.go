@@ -0,0 +1,217 @@
+/*
+ *     Copyright 2020 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"d7y.io/dragonfly/v2/internal/dfnet"
+	"d7y.io/dragonfly/v2/pkg/rpc/cdnsystem"
+)
+
+func TestCdnClient_CloseGracefully(t *testing.T) {
+	tests := []struct {
+		name   string
+		run    func(cc *cdnClient)
+		expect func(t *testing.T, err error)
+	}{
+		{
+			name: "close without active streams",
+			run:  func(cc *cdnClient) {},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+			},
+		},
+		{
+			name: "close after active streams finished",
+			run: func(cc *cdnClient) {
+				if err := cc.acquireStream(); err != nil {
+					t.Fatal(err)
+				}
+				go func() {
+					time.Sleep(10 * time.Millisecond)
+					cc.streams.Done()
+				}()
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+			},
+		},
+		{
+			name: "close timeout with active streams",
+			run: func(cc *cdnClient) {
+				if err := cc.acquireStream(); err != nil {
+					t.Fatal(err)
+				}
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.ErrorIs(err, context.DeadlineExceeded)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			client, err := GetClientByAddrWithOptions([]dfnet.NetAddr{{Type: dfnet.TCP, Addr: "127.0.0.1:8003"}})
+			assert.NoError(err)
+			cc := client.(*cdnClient)
+			tc.run(cc)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			tc.expect(t, cc.CloseGracefully(ctx))
+
+			_, err = cc.ObtainSeeds(context.Background(), &cdnsystem.SeedRequest{TaskId: "foo"})
+			assert.ErrorIs(err, ErrClientClosing)
+		})
+	}
+}
+
+func TestCdnClient_CheckHealth(t *testing.T) {
+	tests := []struct {
+		name   string
+		status healthpb.HealthCheckResponse_ServingStatus
+		expect func(t *testing.T, err error)
+	}{
+		{
+			name:   "cdn is serving",
+			status: healthpb.HealthCheckResponse_SERVING,
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+			},
+		},
+		{
+			name:   "cdn is not serving",
+			status: healthpb.HealthCheckResponse_NOT_SERVING,
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.ErrorIs(err, ErrCdnNotServing)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			assert.NoError(err)
+
+			healthServer := health.NewServer()
+			healthServer.SetServingStatus("", tc.status)
+			grpcServer := grpc.NewServer()
+			healthpb.RegisterHealthServer(grpcServer, healthServer)
+			go grpcServer.Serve(listener)
+			defer grpcServer.Stop()
+
+			addr := dfnet.NetAddr{Type: dfnet.TCP, Addr: listener.Addr().String()}
+			client, err := GetClientByAddr([]dfnet.NetAddr{addr})
+			assert.NoError(err)
+			defer client.Close()
+
+			tc.expect(t, client.CheckHealth(context.Background(), addr))
+		})
+	}
+}
+
+type mockSeederServer struct {
+	cdnsystem.UnimplementedSeederServer
+	obtainSeeds func(*cdnsystem.SeedRequest, cdnsystem.Seeder_ObtainSeedsServer) error
+}
+
+func (s *mockSeederServer) ObtainSeeds(req *cdnsystem.SeedRequest, stream cdnsystem.Seeder_ObtainSeedsServer) error {
+	return s.obtainSeeds(req, stream)
+}
+
+func TestCdnClient_WithFirstResponseTimeout(t *testing.T) {
+	tests := []struct {
+		name        string
+		obtainSeeds func(*cdnsystem.SeedRequest, cdnsystem.Seeder_ObtainSeedsServer) error
+		expect      func(t *testing.T, stream *PieceSeedStream)
+	}{
+		{
+			name: "cdn accepts the stream but never sends",
+			obtainSeeds: func(req *cdnsystem.SeedRequest, stream cdnsystem.Seeder_ObtainSeedsServer) error {
+				<-stream.Context().Done()
+				return stream.Context().Err()
+			},
+			expect: func(t *testing.T, stream *PieceSeedStream) {
+				assert := assert.New(t)
+				_, err := stream.Recv()
+				assert.ErrorIs(err, ErrFirstResponseTimeout)
+			},
+		},
+		{
+			name: "cdn sends slowly after the first response",
+			obtainSeeds: func(req *cdnsystem.SeedRequest, stream cdnsystem.Seeder_ObtainSeedsServer) error {
+				if err := stream.Send(&cdnsystem.PieceSeed{PeerId: "foo"}); err != nil {
+					return err
+				}
+				time.Sleep(300 * time.Millisecond)
+				return stream.Send(&cdnsystem.PieceSeed{PeerId: "foo", Done: true})
+			},
+			expect: func(t *testing.T, stream *PieceSeedStream) {
+				assert := assert.New(t)
+				ps, err := stream.Recv()
+				assert.NoError(err)
+				assert.False(ps.Done)
+
+				ps, err = stream.Recv()
+				assert.NoError(err)
+				assert.True(ps.Done)
+
+				_, err = stream.Recv()
+				assert.ErrorIs(err, io.EOF)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			assert.NoError(err)
+
+			grpcServer := grpc.NewServer()
+			cdnsystem.RegisterSeederServer(grpcServer, &mockSeederServer{obtainSeeds: tc.obtainSeeds})
+			go grpcServer.Serve(listener)
+			defer grpcServer.Stop()
+
+			addr := dfnet.NetAddr{Type: dfnet.TCP, Addr: listener.Addr().String()}
+			client, err := GetClientByAddrWithOptions([]dfnet.NetAddr{addr}, WithFirstResponseTimeout(100*time.Millisecond))
+			assert.NoError(err)
+			defer client.Close()
+
+			stream, err := client.ObtainSeeds(context.Background(), &cdnsystem.SeedRequest{TaskId: "foo", Url: "http://example.com"})
+			assert.NoError(err)
+			tc.expect(t, stream)
+		})
+	}
+}
@@ -18,8 +18,11 @@ package client
 
 import (
 	"context"
+	"sync"
+	"time"
 
 	"github.com/pkg/errors"
+	"go.uber.org/atomic"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -41,6 +44,14 @@ type PieceSeedStream struct {
 	stream cdnsystem.Seeder_ObtainSeedsClient
 	// server list which cannot serve
 	failedServers []string
+	// finishOnce marks the stream finished in client only once
+	finishOnce sync.Once
+	// cancel cancels the stream context, it's set when the first response is watched
+	cancel context.CancelFunc
+	// firstResponseTimer cancels the stream when the first piece seed is not received in time
+	firstResponseTimer *time.Timer
+	// firstResponseTimedOut indicates the stream is canceled by firstResponseTimer
+	firstResponseTimedOut *atomic.Bool
 	rpc.RetryMeta
 }
 
@@ -67,13 +78,10 @@ func newPieceSeedStream(ctx context.Context, sc *cdnClient, hashKey string, sr *
 func (pss *PieceSeedStream) initStream() error {
 	var target string
 	stream, err := rpc.ExecuteWithRetry(func() (interface{}, error) {
-		var client cdnsystem.SeederClient
+		var stream cdnsystem.Seeder_ObtainSeedsClient
 		var err error
-		client, target, err = pss.sc.getCdnClient(pss.hashKey, false)
-		if err != nil {
-			return nil, err
-		}
-		return client.ObtainSeeds(pss.ctx, pss.sr, pss.opts...)
+		stream, target, err = pss.sc.obtainSeeds(pss.ctx, pss.hashKey, false, pss.sr, pss.opts...)
+		return stream, err
 	}, pss.InitBackoff, pss.MaxBackOff, pss.MaxAttempts, nil)
 	if err != nil {
 		if errors.Cause(err) == dferrors.ErrNoCandidateNode {
@@ -87,9 +95,43 @@ func (pss *PieceSeedStream) initStream() error {
 	return nil
 }
 
+// watchFirstResponse cancels the stream when the first piece seed is not received in timeout
+func (pss *PieceSeedStream) watchFirstResponse(timeout time.Duration, cancel context.CancelFunc) {
+	pss.cancel = cancel
+	pss.firstResponseTimedOut = atomic.NewBool(false)
+	pss.firstResponseTimer = time.AfterFunc(timeout, func() {
+		logger.WithTaskID(pss.hashKey).Warnf("cdn does not send the first piece seed in %s, cancel the stream", timeout)
+		pss.firstResponseTimedOut.Store(true)
+		cancel()
+	})
+}
+
 func (pss *PieceSeedStream) Recv() (ps *cdnsystem.PieceSeed, err error) {
 	pss.sc.UpdateAccessNodeMapByHashKey(pss.hashKey)
-	return pss.stream.Recv()
+	ps, err = pss.stream.Recv()
+	if pss.firstResponseTimer != nil {
+		// the stream is normal after the first response
+		pss.firstResponseTimer.Stop()
+		pss.firstResponseTimer = nil
+	}
+
+	if err != nil {
+		if pss.firstResponseTimedOut != nil && pss.firstResponseTimedOut.Load() {
+			err = errors.Wrapf(ErrFirstResponseTimeout, "task %s: %v", pss.hashKey, err)
+		}
+		pss.finish()
+	}
+	return ps, err
+}
+
+// finish marks the stream finished, the client can be closed gracefully after all streams are finished
+func (pss *PieceSeedStream) finish() {
+	pss.finishOnce.Do(func() {
+		pss.sc.streams.Done()
+		if pss.cancel != nil {
+			pss.cancel()
+		}
+	})
 }
 
 func (pss *PieceSeedStream) retryRecv(cause error) (*cdnsystem.PieceSeed, error) {
@@ -110,13 +152,10 @@ func (pss *PieceSeedStream) replaceStream(cause error) error {
 	}
 	var target string
 	stream, err := rpc.ExecuteWithRetry(func() (interface{}, error) {
-		var client cdnsystem.SeederClient
+		var stream cdnsystem.Seeder_ObtainSeedsClient
 		var err error
-		client, target, err = pss.sc.getCdnClient(pss.hashKey, true)
-		if err != nil {
-			return nil, err
-		}
-		return client.ObtainSeeds(pss.ctx, pss.sr, pss.opts...)
+		stream, target, err = pss.sc.obtainSeeds(pss.ctx, pss.hashKey, true, pss.sr, pss.opts...)
+		return stream, err
 	}, pss.InitBackoff, pss.MaxBackOff, pss.MaxAttempts, cause)
 	if err != nil {
 		logger.WithTaskID(pss.hashKey).Infof("replaceStream: invoke cdn node %s ObtainSeeds failed: %v", target, err)
@@ -136,13 +175,10 @@ func (pss *PieceSeedStream) replaceClient(key string, cause error) error {
 	pss.failedServers = append(pss.failedServers, preNode)
 	var target string
 	stream, err := rpc.ExecuteWithRetry(func() (interface{}, error) {
-		var client cdnsystem.SeederClient
+		var stream cdnsystem.Seeder_ObtainSeedsClient
 		var err error
-		client, target, err = pss.sc.getCdnClient(key, true)
-		if err != nil {
-			return nil, err
-		}
-		return client.ObtainSeeds(pss.ctx, pss.sr, pss.opts...)
+		stream, target, err = pss.sc.obtainSeeds(pss.ctx, key, true, pss.sr, pss.opts...)
+		return stream, err
 	}, pss.InitBackoff, pss.MaxBackOff, pss.MaxAttempts, cause)
 	if err != nil {
 		logger.WithTaskID(pss.hashKey).Infof("replaceClient: invoke cdn node %s ObtainSeeds failed: %v", target, err)
@@ -22,6 +22,7 @@ import (
 	"sync"
 	"time"
 
+	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
 	"github.com/pkg/errors"
 	"github.com/serialx/hashring"
 	"google.golang.org/grpc"
@@ -69,6 +70,7 @@ type Connection struct {
 	dialTimeout    time.Duration
 	name           string
 	hashRing       *hashring.HashRing // server hash ring
+	weights        map[string]int     // server node -> weight of hash ring
 	serverNodes    []dfnet.NetAddr
 	status         ConnStatus
 }
@@ -86,18 +88,48 @@ func newDefaultConnection(ctx context.Context) *Connection {
 	}
 }
 
-var defaultClientOpts = []grpc.DialOption{
-	grpc.FailOnNonTempDialError(true),
-	grpc.WithBlock(),
-	grpc.WithDisableServiceConfig(),
-	grpc.WithInitialConnWindowSize(8 * 1024 * 1024),
-	grpc.WithInsecure(),
-	grpc.WithKeepaliveParams(keepalive.ClientParameters{
-		Time:    2 * time.Minute,
-		Timeout: 10 * time.Second,
-	}),
-	grpc.WithStreamInterceptor(streamClientInterceptor),
-	grpc.WithUnaryInterceptor(unaryClientInterceptor),
+var (
+	// clientKeepaliveParams is keepalive parameters of grpc clients, it keeps idle connections alive through NAT.
+	// The ping interval must be permitted by KeepaliveEnforcementPolicy of servers,
+	// otherwise servers send GOAWAY with too_many_pings and close the connections.
+	clientKeepaliveParams = keepalive.ClientParameters{
+		Time:                2 * time.Minute,
+		Timeout:             10 * time.Second,
+		PermitWithoutStream: true,
+	}
+
+	defaultClientOpts = newDefaultClientOpts()
+)
+
+func newDefaultClientOpts() []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.FailOnNonTempDialError(true),
+		grpc.WithBlock(),
+		grpc.WithDisableServiceConfig(),
+		grpc.WithInitialConnWindowSize(8 * 1024 * 1024),
+		grpc.WithInsecure(),
+		grpc.WithKeepaliveParams(clientKeepaliveParams),
+		grpc.WithStreamInterceptor(grpc_middleware.ChainStreamClient(
+			StreamClientRequestIDInterceptor,
+			streamClientInterceptor,
+		)),
+		grpc.WithUnaryInterceptor(grpc_middleware.ChainUnaryClient(
+			UnaryClientRequestIDInterceptor,
+			unaryClientInterceptor,
+		)),
+	}
+}
+
+// SetClientKeepaliveParams sets keepalive parameters of grpc clients,
+// it must be called before creating clients and it does not affect the created clients.
+func SetClientKeepaliveParams(params keepalive.ClientParameters) {
+	clientKeepaliveParams = params
+	defaultClientOpts = newDefaultClientOpts()
+}
+
+// ClientKeepaliveParams returns keepalive parameters of grpc clients
+func ClientKeepaliveParams() keepalive.ClientParameters {
+	return clientKeepaliveParams
 }
 
 type ConnOption interface {
@@ -148,22 +180,52 @@ func WithDialTimeout(dialTimeout time.Duration) ConnOption {
 	})
 }
 
+// WithWeights sets the weights of server nodes in hash ring, the key is the endpoint of server node.
+// Weight is the count of virtual nodes of server node, server nodes without weight have weight 1,
+// so the same hash key is still pinned to the same server node when weights are equal.
+func WithWeights(weights map[string]int) ConnOption {
+	return newFuncConnOption(func(conn *Connection) {
+		conn.weights = weights
+	})
+}
+
 func NewConnection(ctx context.Context, name string, addrs []dfnet.NetAddr, connOpts []ConnOption) *Connection {
 	conn := newDefaultConnection(ctx)
 	conn.name = name
+	for _, opt := range connOpts {
+		opt.apply(conn)
+	}
 	addresses := make([]string, 0, len(addrs))
 	for _, addr := range addrs {
 		addresses = append(addresses, addr.GetEndpoint())
 	}
-	conn.hashRing = hashring.New(addresses)
+	conn.hashRing = conn.newHashRing(addresses)
 	conn.serverNodes = addrs
-	for _, opt := range connOpts {
-		opt.apply(conn)
-	}
 	go conn.startGC()
 	return conn
 }
 
+// newHashRing returns consistent hash ring of server nodes with weights
+func (conn *Connection) newHashRing(serverNodes []string) *hashring.HashRing {
+	if len(conn.weights) == 0 {
+		return hashring.New(serverNodes)
+	}
+
+	weights := make(map[string]int, len(serverNodes))
+	for _, serverNode := range serverNodes {
+		weights[serverNode] = conn.weight(serverNode)
+	}
+	return hashring.NewWithWeights(weights)
+}
+
+// weight returns the weight of server node, default is 1
+func (conn *Connection) weight(serverNode string) int {
+	if weight, ok := conn.weights[serverNode]; ok && weight > 0 {
+		return weight
+	}
+	return 1
+}
+
 func (conn *Connection) CorrectKey2NodeRelation(tmpHashKey, realHashKey string) {
 	if tmpHashKey == realHashKey {
 		return
@@ -201,7 +263,7 @@ func (conn *Connection) AddServerNodes(addrs []dfnet.NetAddr) error {
 	defer conn.rwMutex.Unlock()
 	for _, addr := range addrs {
 		serverNode := addr.GetEndpoint()
-		conn.hashRing = conn.hashRing.AddNode(serverNode)
+		conn.hashRing = conn.hashRing.AddWeightedNode(serverNode, conn.weight(serverNode))
 		logger.GrpcLogger.With("conn", conn.name).Debugf("success add %s to server node list", addr)
 	}
 	return nil
@@ -435,7 +497,7 @@ func (conn *Connection) UpdateState(addrs []dfnet.NetAddr) {
 	conn.rwMutex.Lock()
 	defer conn.rwMutex.Unlock()
 	conn.serverNodes = addrs
-	conn.hashRing = hashring.New(addresses)
+	conn.hashRing = conn.newHashRing(addresses)
 
 	logger.GrpcLogger.Infof("update grpc client addresses %v", addresses)
 }
@@ -0,0 +1,119 @@
+/*
+ *     Copyright 2020 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/keepalive"
+
+	"d7y.io/dragonfly/v2/internal/dfnet"
+)
+
+var testAddrs = []dfnet.NetAddr{
+	{Type: dfnet.TCP, Addr: "127.0.0.1:8001"},
+	{Type: dfnet.TCP, Addr: "127.0.0.1:8002"},
+	{Type: dfnet.TCP, Addr: "127.0.0.1:8003"},
+}
+
+func TestConnection_WithWeights(t *testing.T) {
+	tests := []struct {
+		name    string
+		weights map[string]int
+		expect  func(t *testing.T, conn, defaultConn *Connection)
+	}{
+		{
+			name:    "equal weights pin hash keys like default",
+			weights: map[string]int{"127.0.0.1:8001": 1, "127.0.0.1:8002": 1},
+			expect: func(t *testing.T, conn, defaultConn *Connection) {
+				assert := assert.New(t)
+				for i := 0; i < 100; i++ {
+					key := fmt.Sprintf("task-%d", i)
+					node, ok := conn.hashRing.GetNode(key)
+					assert.True(ok)
+					defaultNode, ok := defaultConn.hashRing.GetNode(key)
+					assert.True(ok)
+					assert.Equal(defaultNode, node)
+				}
+			},
+		},
+		{
+			name:    "larger weight gets more hash keys",
+			weights: map[string]int{"127.0.0.1:8001": 100, "127.0.0.1:8002": 1, "127.0.0.1:8003": 1},
+			expect: func(t *testing.T, conn, defaultConn *Connection) {
+				assert := assert.New(t)
+				counts := map[string]int{}
+				for i := 0; i < 1000; i++ {
+					node, ok := conn.hashRing.GetNode(fmt.Sprintf("task-%d", i))
+					assert.True(ok)
+					counts[node]++
+				}
+				assert.Greater(counts["127.0.0.1:8001"], 800)
+			},
+		},
+		{
+			name:    "weights are kept after update state",
+			weights: map[string]int{"127.0.0.1:8001": 100},
+			expect: func(t *testing.T, conn, defaultConn *Connection) {
+				assert := assert.New(t)
+				conn.UpdateState(testAddrs[:2])
+				assert.Equal(2, conn.hashRing.Size())
+				counts := map[string]int{}
+				for i := 0; i < 1000; i++ {
+					node, ok := conn.hashRing.GetNode(fmt.Sprintf("task-%d", i))
+					assert.True(ok)
+					counts[node]++
+				}
+				assert.Greater(counts["127.0.0.1:8001"], 800)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			conn := NewConnection(context.Background(), "test", testAddrs, []ConnOption{WithWeights(tc.weights)})
+			defer conn.Close()
+			defaultConn := NewConnection(context.Background(), "test", testAddrs, nil)
+			defer defaultConn.Close()
+			tc.expect(t, conn, defaultConn)
+		})
+	}
+}
+
+func TestSetClientKeepaliveParams(t *testing.T) {
+	assert := assert.New(t)
+	defaultParams := ClientKeepaliveParams()
+	defer SetClientKeepaliveParams(defaultParams)
+
+	assert.True(defaultParams.PermitWithoutStream)
+	assert.GreaterOrEqual(int64(defaultParams.Time), int64(KeepaliveEnforcementPolicy.MinTime))
+	assert.True(KeepaliveEnforcementPolicy.PermitWithoutStream)
+
+	opts := defaultClientOpts
+	params := keepalive.ClientParameters{
+		Time:    time.Minute,
+		Timeout: time.Second,
+	}
+	SetClientKeepaliveParams(params)
+	assert.Equal(params, ClientKeepaliveParams())
+	assert.Equal(len(opts), len(defaultClientOpts))
+	assert.Equal(len(newDefaultConnection(context.Background()).dialOpts), len(defaultClientOpts))
+}
@@ -39,6 +39,34 @@ func (m *MockCdnClient) EXPECT() *MockCdnClientMockRecorder {
 	return m.recorder
 }
 
+// CheckHealth mocks base method.
+func (m *MockCdnClient) CheckHealth(ctx context.Context, addr dfnet.NetAddr) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckHealth", ctx, addr)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CheckHealth indicates an expected call of CheckHealth.
+func (mr *MockCdnClientMockRecorder) CheckHealth(ctx, addr interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckHealth", reflect.TypeOf((*MockCdnClient)(nil).CheckHealth), ctx, addr)
+}
+
+// CircuitState mocks base method.
+func (m *MockCdnClient) CircuitState(addr dfnet.NetAddr) client.CircuitState {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CircuitState", addr)
+	ret0, _ := ret[0].(client.CircuitState)
+	return ret0
+}
+
+// CircuitState indicates an expected call of CircuitState.
+func (mr *MockCdnClientMockRecorder) CircuitState(addr interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CircuitState", reflect.TypeOf((*MockCdnClient)(nil).CircuitState), addr)
+}
+
 // Close mocks base method.
 func (m *MockCdnClient) Close() error {
 	m.ctrl.T.Helper()
@@ -53,6 +81,20 @@ func (mr *MockCdnClientMockRecorder) Close() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockCdnClient)(nil).Close))
 }
 
+// CloseGracefully mocks base method.
+func (m *MockCdnClient) CloseGracefully(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CloseGracefully", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CloseGracefully indicates an expected call of CloseGracefully.
+func (mr *MockCdnClientMockRecorder) CloseGracefully(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CloseGracefully", reflect.TypeOf((*MockCdnClient)(nil).CloseGracefully), ctx)
+}
+
 // GetPieceTasks mocks base method.
 func (m *MockCdnClient) GetPieceTasks(ctx context.Context, addr dfnet.NetAddr, req *base.PieceTaskRequest, opts ...grpc.CallOption) (*base.PiecePacket, error) {
 	m.ctrl.T.Helper()
@@ -18,7 +18,8 @@ package client
 
 import (
 	"context"
-	"fmt"
+	"net"
+	"strconv"
 	"strings"
 
 	"google.golang.org/grpc"
@@ -32,7 +33,7 @@ import (
 )
 
 func GetPieceTasks(ctx context.Context, destPeer *scheduler.PeerPacket_DestPeer, ptr *base.PieceTaskRequest, opts ...grpc.CallOption) (*base.PiecePacket, error) {
-	destAddr := fmt.Sprintf("%s:%d", destPeer.Ip, destPeer.RpcPort)
+	destAddr := net.JoinHostPort(destPeer.Ip, strconv.Itoa(int(destPeer.RpcPort)))
 	peerID := destPeer.PeerId
 	toCdn := strings.HasSuffix(peerID, common.CdnSuffix)
 	var err error
@@ -19,6 +19,9 @@ package client
 import (
 	"context"
 	"errors"
+	"math"
+	"math/rand"
+	"sync"
 	"time"
 
 	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
@@ -26,10 +29,13 @@ import (
 	grpc_prometheus "github.com/grpc-ecosystem/go-grpc-prometheus"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/backoff"
+	"google.golang.org/protobuf/proto"
 
 	logger "d7y.io/dragonfly/v2/internal/dflog"
 	"d7y.io/dragonfly/v2/internal/dfnet"
+	"d7y.io/dragonfly/v2/pkg/cache"
 	"d7y.io/dragonfly/v2/pkg/reachable"
+	"d7y.io/dragonfly/v2/pkg/rpc"
 	"d7y.io/dragonfly/v2/pkg/rpc/manager"
 )
 
@@ -41,6 +47,20 @@ const (
 	backoffMaxDelay   = 10 * time.Second
 )
 
+const (
+	// defaultKeepAliveBackoffMultiplier is the factor with which to multiply keepalive interval after a failure
+	defaultKeepAliveBackoffMultiplier = 2
+
+	// defaultKeepAliveBackoffJitter is the factor with which keepalive interval is randomized
+	defaultKeepAliveBackoffJitter = 0.2
+
+	// defaultKeepAliveBackoffMaxInterval is the upper bound of keepalive interval after failures
+	defaultKeepAliveBackoffMaxInterval = 2 * time.Minute
+)
+
+// schedulersCacheKey is the cache key of the last successful ListSchedulersResponse
+const schedulersCacheKey = "schedulers"
+
 type Client interface {
 	// Get Scheduler and Scheduler cluster configuration
 	GetScheduler(*manager.GetSchedulerRequest) (*manager.Scheduler, error)
@@ -54,6 +74,13 @@ type Client interface {
 	// List acitve schedulers configuration
 	ListSchedulers(*manager.ListSchedulersRequest) (*manager.ListSchedulersResponse, error)
 
+	// List acitve schedulers configuration, stale is true when the schedulers
+	// are from cache because manager is unavailable
+	ListSchedulersWithCache(*manager.ListSchedulersRequest) (schedulers *manager.ListSchedulersResponse, stale bool, err error)
+
+	// Get object storage configuration
+	GetObjectStorage(*manager.GetObjectStorageRequest) (*manager.ObjectStorage, error)
+
 	// KeepAlive with manager
 	KeepAlive(time.Duration, *manager.KeepAliveRequest)
 
@@ -64,9 +91,45 @@ type Client interface {
 type client struct {
 	manager.ManagerClient
 	conn *grpc.ClientConn
+
+	// keepAliveBackoffMultiplier multiplies keepalive interval for every consecutive failure
+	keepAliveBackoffMultiplier float64
+	// keepAliveBackoffJitter randomizes keepalive interval to avoid reconnecting in lockstep
+	keepAliveBackoffJitter float64
+	// keepAliveBackoffMaxInterval caps keepalive interval after failures
+	keepAliveBackoffMaxInterval time.Duration
+
+	// schedulersCache caches the last successful ListSchedulersResponse in schedulersCachePath
+	schedulersCache     cache.Cache
+	schedulersCachePath string
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+type Option func(c *client)
+
+// WithKeepAliveBackoff sets the backoff of keepalive on consecutive failures,
+// keepalive interval is multiplied by multiplier for every failure and capped at maxInterval,
+// then it is randomized by jitter and never less than the base interval
+func WithKeepAliveBackoff(multiplier, jitter float64, maxInterval time.Duration) Option {
+	return func(c *client) {
+		c.keepAliveBackoffMultiplier = multiplier
+		c.keepAliveBackoffJitter = jitter
+		c.keepAliveBackoffMaxInterval = maxInterval
+	}
+}
+
+// WithSchedulersCache caches the last successful ListSchedulersResponse in cachePath,
+// it is used when manager is unavailable until ttl expires, even after restart
+func WithSchedulersCache(cachePath string, ttl time.Duration) Option {
+	return func(c *client) {
+		c.schedulersCache = cache.New(ttl, cache.NoCleanup)
+		c.schedulersCachePath = cachePath
+	}
 }
 
-func New(target string) (Client, error) {
+func New(target string, options ...Option) (Client, error) {
 	conn, err := grpc.Dial(
 		target,
 		grpc.WithInsecure(),
@@ -80,26 +143,48 @@ func New(target string) (Client, error) {
 			},
 		}),
 		grpc.WithStreamInterceptor(grpc_middleware.ChainStreamClient(
+			rpc.StreamClientRequestIDInterceptor,
 			grpc_prometheus.StreamClientInterceptor,
 			grpc_zap.StreamClientInterceptor(logger.GrpcLogger.Desugar()),
 		)),
+		grpc.WithUnaryInterceptor(rpc.UnaryClientRequestIDInterceptor),
+		grpc.WithKeepaliveParams(rpc.ClientKeepaliveParams()),
 	)
 	if err != nil {
 		return nil, err
 	}
 
-	return &client{
-		ManagerClient: manager.NewManagerClient(conn),
-		conn:          conn,
-	}, nil
+	return newClient(manager.NewManagerClient(conn), conn, options...), nil
+}
+
+func newClient(managerClient manager.ManagerClient, conn *grpc.ClientConn, options ...Option) *client {
+	c := &client{
+		ManagerClient:               managerClient,
+		conn:                        conn,
+		keepAliveBackoffMultiplier:  defaultKeepAliveBackoffMultiplier,
+		keepAliveBackoffJitter:      defaultKeepAliveBackoffJitter,
+		keepAliveBackoffMaxInterval: defaultKeepAliveBackoffMaxInterval,
+		done:                        make(chan struct{}),
+	}
+
+	for _, opt := range options {
+		opt(c)
+	}
+
+	if c.schedulersCache != nil {
+		if err := c.schedulersCache.LoadFile(c.schedulersCachePath); err != nil {
+			logger.Infof("load schedulers cache %s failed: %v", c.schedulersCachePath, err)
+		}
+	}
+	return c
 }
 
-func NewWithAddrs(netAddrs []dfnet.NetAddr) (Client, error) {
+func NewWithAddrs(netAddrs []dfnet.NetAddr, options ...Option) (Client, error) {
 	for _, netAddr := range netAddrs {
 		ipReachable := reachable.New(&reachable.Config{Address: netAddr.Addr})
 		if err := ipReachable.Check(); err == nil {
 			logger.Infof("use %s address for manager grpc client", netAddr.Addr)
-			return New(netAddr.Addr)
+			return New(netAddr.Addr, options...)
 		}
 		logger.Warnf("%s address can not reachable", netAddr.Addr)
 	}
@@ -129,42 +214,144 @@ func (c *client) UpdateCDN(req *manager.UpdateCDNRequest) (*manager.CDN, error)
 }
 
 func (c *client) ListSchedulers(req *manager.ListSchedulersRequest) (*manager.ListSchedulersResponse, error) {
+	schedulers, _, err := c.ListSchedulersWithCache(req)
+	return schedulers, err
+}
+
+func (c *client) ListSchedulersWithCache(req *manager.ListSchedulersRequest) (*manager.ListSchedulersResponse, bool, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), contextTimeout)
 	defer cancel()
 
-	return c.ManagerClient.ListSchedulers(ctx, req)
+	schedulers, err := c.ManagerClient.ListSchedulers(ctx, req)
+	if c.schedulersCache == nil {
+		return schedulers, false, err
+	}
+
+	if err != nil {
+		cachedSchedulers, ok := c.loadSchedulers()
+		if !ok {
+			return nil, false, err
+		}
+
+		logger.Warnf("list schedulers failed, use cached schedulers: %v", err)
+		return cachedSchedulers, true, nil
+	}
+
+	if err := c.storeSchedulers(schedulers); err != nil {
+		logger.Warnf("store schedulers cache %s failed: %v", c.schedulersCachePath, err)
+	}
+	return schedulers, false, nil
 }
 
-func (c *client) KeepAlive(interval time.Duration, keepalive *manager.KeepAliveRequest) {
-retry:
-	ctx, cancel := context.WithCancel(context.Background())
-	stream, err := c.ManagerClient.KeepAlive(ctx)
+// loadSchedulers returns the cached schedulers which are not expired
+func (c *client) loadSchedulers() (*manager.ListSchedulersResponse, bool) {
+	data, ok := c.schedulersCache.Get(schedulersCacheKey)
+	if !ok {
+		return nil, false
+	}
+
+	b, ok := data.([]byte)
+	if !ok {
+		return nil, false
+	}
+
+	schedulers := &manager.ListSchedulersResponse{}
+	if err := proto.Unmarshal(b, schedulers); err != nil {
+		logger.Warnf("unmarshal cached schedulers failed: %v", err)
+		return nil, false
+	}
+	return schedulers, true
+}
+
+// storeSchedulers caches schedulers in memory and file
+func (c *client) storeSchedulers(schedulers *manager.ListSchedulersResponse) error {
+	b, err := proto.Marshal(schedulers)
 	if err != nil {
-		time.Sleep(interval)
-		cancel()
-		goto retry
+		return err
 	}
 
-	tick := time.NewTicker(interval)
+	c.schedulersCache.SetDefault(schedulersCacheKey, b)
+	return c.schedulersCache.SaveFile(c.schedulersCachePath)
+}
+
+func (c *client) GetObjectStorage(req *manager.GetObjectStorageRequest) (*manager.ObjectStorage, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), contextTimeout)
+	defer cancel()
+
+	return c.ManagerClient.GetObjectStorage(ctx, req)
+}
+
+// KeepAlive sends keepalive to manager until the client is closed, interval is the base interval,
+// it backs off exponentially with jitter on consecutive failures and resets after a success
+func (c *client) KeepAlive(interval time.Duration, keepalive *manager.KeepAliveRequest) {
+	var (
+		stream   manager.Manager_KeepAliveClient
+		cancel   context.CancelFunc
+		failures int
+	)
+
 	for {
 		select {
-		case <-tick.C:
-			if err := stream.Send(&manager.KeepAliveRequest{
-				HostName:   keepalive.HostName,
-				SourceType: keepalive.SourceType,
-				ClusterId:  keepalive.ClusterId,
-			}); err != nil {
-				if _, err := stream.CloseAndRecv(); err != nil {
-					logger.Errorf("hostname %s cluster id %v close and recv stream failed: %v", keepalive.HostName, keepalive.ClusterId, err)
-				}
+		case <-time.After(c.keepAliveInterval(interval, failures)):
+		case <-c.done:
+			if cancel != nil {
+				cancel()
+			}
+			return
+		}
 
+		if stream == nil {
+			var (
+				ctx context.Context
+				err error
+			)
+			ctx, cancel = context.WithCancel(context.Background())
+			stream, err = c.ManagerClient.KeepAlive(ctx)
+			if err != nil {
+				logger.Warnf("hostname %s cluster id %v create keepalive stream failed %d times: %v", keepalive.HostName, keepalive.ClusterId, failures+1, err)
 				cancel()
-				goto retry
+				stream = nil
+				failures++
+				continue
+			}
+		}
+
+		if err := stream.Send(&manager.KeepAliveRequest{
+			HostName:   keepalive.HostName,
+			SourceType: keepalive.SourceType,
+			ClusterId:  keepalive.ClusterId,
+		}); err != nil {
+			if _, err := stream.CloseAndRecv(); err != nil {
+				logger.Errorf("hostname %s cluster id %v close and recv stream failed: %v", keepalive.HostName, keepalive.ClusterId, err)
 			}
+
+			cancel()
+			stream = nil
+			failures++
+			continue
 		}
+
+		failures = 0
+	}
+}
+
+// keepAliveInterval returns the interval before next keepalive after consecutive failures
+func (c *client) keepAliveInterval(interval time.Duration, failures int) time.Duration {
+	backoff := float64(interval) * math.Pow(c.keepAliveBackoffMultiplier, float64(failures))
+	if maxInterval := float64(c.keepAliveBackoffMaxInterval); backoff > maxInterval {
+		backoff = maxInterval
+	}
+
+	backoff *= 1 + c.keepAliveBackoffJitter*(rand.Float64()*2-1)
+	if backoff < float64(interval) {
+		return interval
 	}
+	return time.Duration(backoff)
 }
 
 func (c *client) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.done)
+	})
 	return c.conn.Close()
 }
@@ -0,0 +1,195 @@
+/*
+ *     Copyright 2020 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"d7y.io/dragonfly/v2/pkg/rpc/manager"
+	"d7y.io/dragonfly/v2/pkg/rpc/manager/mocks"
+)
+
+func TestClient_KeepAliveInterval(t *testing.T) {
+	tests := []struct {
+		name     string
+		jitter   float64
+		failures int
+		expect   func(t *testing.T, interval time.Duration)
+	}{
+		{
+			name:     "base interval without failures",
+			failures: 0,
+			expect: func(t *testing.T, interval time.Duration) {
+				assert.Equal(t, time.Second, interval)
+			},
+		},
+		{
+			name:     "backoff after failures",
+			failures: 3,
+			expect: func(t *testing.T, interval time.Duration) {
+				assert.Equal(t, 8*time.Second, interval)
+			},
+		},
+		{
+			name:     "backoff is capped",
+			failures: 10,
+			expect: func(t *testing.T, interval time.Duration) {
+				assert.Equal(t, 10*time.Second, interval)
+			},
+		},
+		{
+			name:     "jitter never goes below base interval",
+			jitter:   0.5,
+			failures: 0,
+			expect: func(t *testing.T, interval time.Duration) {
+				assert := assert.New(t)
+				assert.GreaterOrEqual(interval, time.Second)
+				assert.LessOrEqual(interval, 1500*time.Millisecond)
+			},
+		},
+		{
+			name:     "jitter randomizes backoff",
+			jitter:   0.5,
+			failures: 2,
+			expect: func(t *testing.T, interval time.Duration) {
+				assert := assert.New(t)
+				assert.GreaterOrEqual(interval, 2*time.Second)
+				assert.LessOrEqual(interval, 6*time.Second)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := newClient(nil, nil, WithKeepAliveBackoff(2, tc.jitter, 10*time.Second))
+			for i := 0; i < 10; i++ {
+				tc.expect(t, c.keepAliveInterval(time.Second, tc.failures))
+			}
+		})
+	}
+}
+
+func TestClient_KeepAlive(t *testing.T) {
+	assert := assert.New(t)
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+
+	var (
+		failures = 3
+		sent     = make(chan *manager.KeepAliveRequest, 1)
+	)
+	stream := mocks.NewMockManager_KeepAliveClient(ctl)
+	stream.EXPECT().Send(gomock.Any()).DoAndReturn(func(req *manager.KeepAliveRequest) error {
+		select {
+		case sent <- req:
+		default:
+		}
+		return nil
+	}).AnyTimes()
+
+	managerClient := mocks.NewMockManagerClient(ctl)
+	gomock.InOrder(
+		managerClient.EXPECT().KeepAlive(gomock.Any()).Return(nil, errors.New("foo")).Times(failures),
+		managerClient.EXPECT().KeepAlive(gomock.Any()).Return(stream, nil).Times(1),
+	)
+
+	c := newClient(managerClient, nil, WithKeepAliveBackoff(2, 0, time.Second))
+	done := make(chan struct{})
+	go func() {
+		c.KeepAlive(10*time.Millisecond, &manager.KeepAliveRequest{
+			HostName:  "foo",
+			ClusterId: 1,
+		})
+		close(done)
+	}()
+
+	// the intervals before each keepalive are 10ms, 20ms, 40ms, 80ms
+	start := time.Now()
+	req := <-sent
+	assert.Equal("foo", req.HostName)
+	assert.Equal(uint64(1), req.ClusterId)
+	assert.GreaterOrEqual(time.Since(start), 150*time.Millisecond)
+
+	// the interval is reset to base after a success
+	start = time.Now()
+	<-sent
+	assert.Less(time.Since(start), 80*time.Millisecond)
+
+	c.closeOnce.Do(func() {
+		close(c.done)
+	})
+	<-done
+}
+
+func TestClient_ListSchedulersWithCache(t *testing.T) {
+	assert := assert.New(t)
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+
+	var (
+		cachePath  = filepath.Join(t.TempDir(), "schedulers")
+		req        = &manager.ListSchedulersRequest{HostName: "foo", Ip: "127.0.0.1"}
+		schedulers = &manager.ListSchedulersResponse{
+			Schedulers: []*manager.Scheduler{{HostName: "bar", Ip: "127.0.0.1", Port: 8002}},
+		}
+	)
+
+	managerClient := mocks.NewMockManagerClient(ctl)
+	gomock.InOrder(
+		managerClient.EXPECT().ListSchedulers(gomock.Any(), gomock.Any()).Return(nil, errors.New("foo")).Times(1),
+		managerClient.EXPECT().ListSchedulers(gomock.Any(), gomock.Any()).Return(schedulers, nil).Times(1),
+		managerClient.EXPECT().ListSchedulers(gomock.Any(), gomock.Any()).Return(nil, errors.New("foo")).AnyTimes(),
+	)
+
+	// no cached schedulers
+	c := newClient(managerClient, nil, WithSchedulersCache(cachePath, time.Minute))
+	_, stale, err := c.ListSchedulersWithCache(req)
+	assert.EqualError(err, "foo")
+	assert.False(stale)
+
+	resp, stale, err := c.ListSchedulersWithCache(req)
+	assert.NoError(err)
+	assert.False(stale)
+	assert.Equal("bar", resp.Schedulers[0].HostName)
+
+	// manager is unavailable
+	resp, stale, err = c.ListSchedulersWithCache(req)
+	assert.NoError(err)
+	assert.True(stale)
+	assert.Equal("bar", resp.Schedulers[0].HostName)
+
+	// bootstrap from cache file
+	c = newClient(managerClient, nil, WithSchedulersCache(cachePath, time.Minute))
+	resp, err = c.ListSchedulers(req)
+	assert.NoError(err)
+	assert.Equal("bar", resp.Schedulers[0].HostName)
+	assert.Equal(int32(8002), resp.Schedulers[0].Port)
+
+	// cached schedulers expired
+	c = newClient(managerClient, nil, WithSchedulersCache(filepath.Join(t.TempDir(), "schedulers"), time.Millisecond))
+	assert.NoError(c.storeSchedulers(schedulers))
+	time.Sleep(10 * time.Millisecond)
+	_, stale, err = c.ListSchedulersWithCache(req)
+	assert.EqualError(err, "foo")
+	assert.False(stale)
+}
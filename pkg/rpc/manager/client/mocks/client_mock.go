@@ -49,6 +49,21 @@ func (mr *MockClientMockRecorder) Close() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockClient)(nil).Close))
 }
 
+// GetObjectStorage mocks base method.
+func (m *MockClient) GetObjectStorage(arg0 *manager.GetObjectStorageRequest) (*manager.ObjectStorage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetObjectStorage", arg0)
+	ret0, _ := ret[0].(*manager.ObjectStorage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetObjectStorage indicates an expected call of GetObjectStorage.
+func (mr *MockClientMockRecorder) GetObjectStorage(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetObjectStorage", reflect.TypeOf((*MockClient)(nil).GetObjectStorage), arg0)
+}
+
 // GetScheduler mocks base method.
 func (m *MockClient) GetScheduler(arg0 *manager.GetSchedulerRequest) (*manager.Scheduler, error) {
 	m.ctrl.T.Helper()
@@ -91,6 +106,22 @@ func (mr *MockClientMockRecorder) ListSchedulers(arg0 interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSchedulers", reflect.TypeOf((*MockClient)(nil).ListSchedulers), arg0)
 }
 
+// ListSchedulersWithCache mocks base method.
+func (m *MockClient) ListSchedulersWithCache(arg0 *manager.ListSchedulersRequest) (*manager.ListSchedulersResponse, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListSchedulersWithCache", arg0)
+	ret0, _ := ret[0].(*manager.ListSchedulersResponse)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ListSchedulersWithCache indicates an expected call of ListSchedulersWithCache.
+func (mr *MockClientMockRecorder) ListSchedulersWithCache(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSchedulersWithCache", reflect.TypeOf((*MockClient)(nil).ListSchedulersWithCache), arg0)
+}
+
 // UpdateCDN mocks base method.
 func (m *MockClient) UpdateCDN(arg0 *manager.UpdateCDNRequest) (*manager.CDN, error) {
 	m.ctrl.T.Helper()
@@ -1370,3 +1370,197 @@ var _ interface {
 	Cause() error
 	ErrorName() string
 } = KeepAliveRequestValidationError{}
+
+// Validate checks the field values on ObjectStorage with the rules defined in
+// the proto definition for this message. If any rules are violated, an error is
+// returned.
+func (m *ObjectStorage) Validate() error {
+	if m == nil {
+		return nil
+	}
+
+	// no validation rules for Name
+
+	// no validation rules for Region
+
+	// no validation rules for Endpoint
+
+	// no validation rules for AccessKey
+
+	// no validation rules for SecretKey
+
+	return nil
+}
+
+// ObjectStorageValidationError is the validation error returned by
+// ObjectStorage.Validate if the designated constraints aren't met.
+type ObjectStorageValidationError struct {
+	field  string
+	reason string
+	cause  error
+	key    bool
+}
+
+// Field function returns field value.
+func (e ObjectStorageValidationError) Field() string { return e.field }
+
+// Reason function returns reason value.
+func (e ObjectStorageValidationError) Reason() string { return e.reason }
+
+// Cause function returns cause value.
+func (e ObjectStorageValidationError) Cause() error { return e.cause }
+
+// Key function returns key value.
+func (e ObjectStorageValidationError) Key() bool { return e.key }
+
+// ErrorName returns error name.
+func (e ObjectStorageValidationError) ErrorName() string { return "ObjectStorageValidationError" }
+
+// Error satisfies the builtin error interface
+func (e ObjectStorageValidationError) Error() string {
+	cause := ""
+	if e.cause != nil {
+		cause = fmt.Sprintf(" | caused by: %v", e.cause)
+	}
+
+	key := ""
+	if e.key {
+		key = "key for "
+	}
+
+	return fmt.Sprintf(
+		"invalid %sObjectStorage.%s: %s%s",
+		key,
+		e.field,
+		e.reason,
+		cause)
+}
+
+var _ error = ObjectStorageValidationError{}
+
+var _ interface {
+	Field() string
+	Reason() string
+	Key() bool
+	Cause() error
+	ErrorName() string
+} = ObjectStorageValidationError{}
+
+// Validate checks the field values on GetObjectStorageRequest with the rules
+// defined in the proto definition for this message. If any rules are violated,
+// an error is returned.
+func (m *GetObjectStorageRequest) Validate() error {
+	if m == nil {
+		return nil
+	}
+
+	if _, ok := SourceType_name[int32(m.GetSourceType())]; !ok {
+		return GetObjectStorageRequestValidationError{
+			field:  "SourceType",
+			reason: "value must be one of the defined enum values",
+		}
+	}
+
+	if err := m._validateHostname(m.GetHostName()); err != nil {
+		return GetObjectStorageRequestValidationError{
+			field:  "HostName",
+			reason: "value must be a valid hostname",
+			cause:  err,
+		}
+	}
+
+	if ip := net.ParseIP(m.GetIp()); ip == nil {
+		return GetObjectStorageRequestValidationError{
+			field:  "Ip",
+			reason: "value must be a valid IP address",
+		}
+	}
+
+	return nil
+}
+
+func (m *GetObjectStorageRequest) _validateHostname(host string) error {
+	s := strings.ToLower(strings.TrimSuffix(host, "."))
+
+	if len(host) > 253 {
+		return errors.New("hostname cannot exceed 253 characters")
+	}
+
+	for _, part := range strings.Split(s, ".") {
+		if l := len(part); l == 0 || l > 63 {
+			return errors.New("hostname part must be non-empty and cannot exceed 63 characters")
+		}
+
+		if part[0] == '-' {
+			return errors.New("hostname parts cannot begin with hyphens")
+		}
+
+		if part[len(part)-1] == '-' {
+			return errors.New("hostname parts cannot end with hyphens")
+		}
+
+		for _, r := range part {
+			if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' {
+				return fmt.Errorf("hostname parts can only contain alphanumeric characters or hyphens, got %q", string(r))
+			}
+		}
+	}
+
+	return nil
+}
+
+// GetObjectStorageRequestValidationError is the validation error returned by
+// GetObjectStorageRequest.Validate if the designated constraints aren't met.
+type GetObjectStorageRequestValidationError struct {
+	field  string
+	reason string
+	cause  error
+	key    bool
+}
+
+// Field function returns field value.
+func (e GetObjectStorageRequestValidationError) Field() string { return e.field }
+
+// Reason function returns reason value.
+func (e GetObjectStorageRequestValidationError) Reason() string { return e.reason }
+
+// Cause function returns cause value.
+func (e GetObjectStorageRequestValidationError) Cause() error { return e.cause }
+
+// Key function returns key value.
+func (e GetObjectStorageRequestValidationError) Key() bool { return e.key }
+
+// ErrorName returns error name.
+func (e GetObjectStorageRequestValidationError) ErrorName() string {
+	return "GetObjectStorageRequestValidationError"
+}
+
+// Error satisfies the builtin error interface
+func (e GetObjectStorageRequestValidationError) Error() string {
+	cause := ""
+	if e.cause != nil {
+		cause = fmt.Sprintf(" | caused by: %v", e.cause)
+	}
+
+	key := ""
+	if e.key {
+		key = "key for "
+	}
+
+	return fmt.Sprintf(
+		"invalid %sGetObjectStorageRequest.%s: %s%s",
+		key,
+		e.field,
+		e.reason,
+		cause)
+}
+
+var _ error = GetObjectStorageRequestValidationError{}
+
+var _ interface {
+	Field() string
+	Reason() string
+	Key() bool
+	Cause() error
+	ErrorName() string
+} = GetObjectStorageRequestValidationError{}
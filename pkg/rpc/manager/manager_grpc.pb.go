@@ -31,6 +31,8 @@ type ManagerClient interface {
 	ListSchedulers(ctx context.Context, in *ListSchedulersRequest, opts ...grpc.CallOption) (*ListSchedulersResponse, error)
 	// KeepAlive with manager
 	KeepAlive(ctx context.Context, opts ...grpc.CallOption) (Manager_KeepAliveClient, error)
+	// Get object storage configuration
+	GetObjectStorage(ctx context.Context, in *GetObjectStorageRequest, opts ...grpc.CallOption) (*ObjectStorage, error)
 }
 
 type managerClient struct {
@@ -120,6 +122,15 @@ func (x *managerKeepAliveClient) CloseAndRecv() (*emptypb.Empty, error) {
 	return m, nil
 }
 
+func (c *managerClient) GetObjectStorage(ctx context.Context, in *GetObjectStorageRequest, opts ...grpc.CallOption) (*ObjectStorage, error) {
+	out := new(ObjectStorage)
+	err := c.cc.Invoke(ctx, "/manager.Manager/GetObjectStorage", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ManagerServer is the server API for Manager service.
 // All implementations must embed UnimplementedManagerServer
 // for forward compatibility
@@ -136,6 +147,8 @@ type ManagerServer interface {
 	ListSchedulers(context.Context, *ListSchedulersRequest) (*ListSchedulersResponse, error)
 	// KeepAlive with manager
 	KeepAlive(Manager_KeepAliveServer) error
+	// Get object storage configuration
+	GetObjectStorage(context.Context, *GetObjectStorageRequest) (*ObjectStorage, error)
 	mustEmbedUnimplementedManagerServer()
 }
 
@@ -161,6 +174,9 @@ func (UnimplementedManagerServer) ListSchedulers(context.Context, *ListScheduler
 func (UnimplementedManagerServer) KeepAlive(Manager_KeepAliveServer) error {
 	return status.Errorf(codes.Unimplemented, "method KeepAlive not implemented")
 }
+func (UnimplementedManagerServer) GetObjectStorage(context.Context, *GetObjectStorageRequest) (*ObjectStorage, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetObjectStorage not implemented")
+}
 func (UnimplementedManagerServer) mustEmbedUnimplementedManagerServer() {}
 
 // UnsafeManagerServer may be embedded to opt out of forward compatibility for this service.
@@ -290,6 +306,24 @@ func (x *managerKeepAliveServer) Recv() (*KeepAliveRequest, error) {
 	return m, nil
 }
 
+func _Manager_GetObjectStorage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetObjectStorageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ManagerServer).GetObjectStorage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/manager.Manager/GetObjectStorage",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ManagerServer).GetObjectStorage(ctx, req.(*GetObjectStorageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Manager_ServiceDesc is the grpc.ServiceDesc for Manager service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -317,6 +351,10 @@ var Manager_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "ListSchedulers",
 			Handler:    _Manager_ListSchedulers_Handler,
 		},
+		{
+			MethodName: "GetObjectStorage",
+			Handler:    _Manager_GetObjectStorage_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -28,6 +28,7 @@ import (
 	"google.golang.org/grpc"
 
 	logger "d7y.io/dragonfly/v2/internal/dflog"
+	"d7y.io/dragonfly/v2/pkg/rpc"
 	"d7y.io/dragonfly/v2/pkg/rpc/manager"
 )
 
@@ -36,6 +37,7 @@ var defaultStreamMiddleWares = []grpc.StreamServerInterceptor{
 	grpc_recovery.StreamServerInterceptor(),
 	grpc_prometheus.StreamServerInterceptor,
 	grpc_zap.StreamServerInterceptor(logger.GrpcLogger.Desugar()),
+	rpc.StreamServerRequestIDInterceptor,
 }
 
 var defaultUnaryMiddleWares = []grpc.UnaryServerInterceptor{
@@ -43,6 +45,7 @@ var defaultUnaryMiddleWares = []grpc.UnaryServerInterceptor{
 	grpc_recovery.UnaryServerInterceptor(),
 	grpc_prometheus.UnaryServerInterceptor,
 	grpc_zap.UnaryServerInterceptor(logger.GrpcLogger.Desugar()),
+	rpc.UnaryServerRequestIDInterceptor,
 }
 
 // ManagerServer is the server API for Manager service.
@@ -59,6 +62,8 @@ type ManagerServer interface {
 	ListSchedulers(context.Context, *manager.ListSchedulersRequest) (*manager.ListSchedulersResponse, error)
 	// KeepAlive with manager
 	KeepAlive(manager.Manager_KeepAliveServer) error
+	// Get object storage configuration
+	GetObjectStorage(context.Context, *manager.GetObjectStorageRequest) (*manager.ObjectStorage, error)
 }
 
 type proxy struct {
@@ -73,6 +78,7 @@ func New(managerServer ManagerServer, jaeger bool, opts ...grpc.ServerOption) *g
 	}
 
 	grpcServer := grpc.NewServer(append([]grpc.ServerOption{
+		grpc.KeepaliveEnforcementPolicy(rpc.KeepaliveEnforcementPolicy),
 		grpc.StreamInterceptor(grpc_middleware.ChainStreamServer(defaultStreamMiddleWares...)),
 		grpc.UnaryInterceptor(grpc_middleware.ChainUnaryServer(defaultUnaryMiddleWares...)),
 	}, opts...)...)
@@ -105,3 +111,7 @@ func (p *proxy) ListSchedulers(ctx context.Context, req *manager.ListSchedulersR
 func (p *proxy) KeepAlive(req manager.Manager_KeepAliveServer) error {
 	return p.server.KeepAlive(req)
 }
+
+func (p *proxy) GetObjectStorage(ctx context.Context, req *manager.GetObjectStorageRequest) (*manager.ObjectStorage, error) {
+	return p.server.GetObjectStorage(ctx, req)
+}
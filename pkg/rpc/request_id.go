@@ -0,0 +1,99 @@
+/*
+ *     Copyright 2022 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rpc
+
+import (
+	"context"
+
+	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"d7y.io/dragonfly/v2/pkg/idgen"
+)
+
+// RequestIDKey is the grpc metadata key of request id
+const RequestIDKey = "x-request-id"
+
+type requestIDContextKey struct{}
+
+// WithRequestID returns a copy of ctx with request id
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey{}, requestID)
+}
+
+// RequestIDFromContext returns request id in ctx
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	requestID, ok := ctx.Value(requestIDContextKey{}).(string)
+	return requestID, ok && requestID != ""
+}
+
+// outgoingRequestID injects request id of ctx into outgoing metadata, a new request id is generated when absent.
+// The request id received by server is propagated to the next hop.
+func outgoingRequestID(ctx context.Context) context.Context {
+	if md, ok := metadata.FromOutgoingContext(ctx); ok && len(md.Get(RequestIDKey)) > 0 {
+		return ctx
+	}
+
+	requestID, ok := RequestIDFromContext(ctx)
+	if !ok {
+		requestID = idgen.UUIDString()
+	}
+	return metadata.AppendToOutgoingContext(ctx, RequestIDKey, requestID)
+}
+
+// incomingRequestID extracts request id from incoming metadata into ctx and logger fields
+func incomingRequestID(ctx context.Context) context.Context {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx
+	}
+
+	values := md.Get(RequestIDKey)
+	if len(values) == 0 || values[0] == "" {
+		return ctx
+	}
+
+	ctxzap.AddFields(ctx, zap.String("requestID", values[0]))
+	return WithRequestID(ctx, values[0])
+}
+
+// UnaryClientRequestIDInterceptor injects request id into outgoing metadata of unary calls
+func UnaryClientRequestIDInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	return invoker(outgoingRequestID(ctx), method, req, reply, cc, opts...)
+}
+
+// StreamClientRequestIDInterceptor injects request id into outgoing metadata of streams
+func StreamClientRequestIDInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return streamer(outgoingRequestID(ctx), desc, cc, method, opts...)
+}
+
+// UnaryServerRequestIDInterceptor extracts request id from incoming metadata of unary calls,
+// it should be chained after the zap interceptor to add the logger fields
+func UnaryServerRequestIDInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	return handler(incomingRequestID(ctx), req)
+}
+
+// StreamServerRequestIDInterceptor extracts request id from incoming metadata of streams,
+// it should be chained after the zap interceptor to add the logger fields
+func StreamServerRequestIDInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	wrapped := grpc_middleware.WrapServerStream(ss)
+	wrapped.WrappedContext = incomingRequestID(ss.Context())
+	return handler(srv, wrapped)
+}
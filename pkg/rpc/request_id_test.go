@@ -0,0 +1,125 @@
+/*
+ *     Copyright 2022 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestUnaryClientRequestIDInterceptor(t *testing.T) {
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		expect func(t *testing.T, requestIDs []string)
+	}{
+		{
+			name: "generate request id",
+			ctx:  context.Background(),
+			expect: func(t *testing.T, requestIDs []string) {
+				assert := assert.New(t)
+				assert.Equal(len(requestIDs), 1)
+				assert.NotEmpty(requestIDs[0])
+			},
+		},
+		{
+			name: "inject request id from context",
+			ctx:  WithRequestID(context.Background(), "foo"),
+			expect: func(t *testing.T, requestIDs []string) {
+				assert := assert.New(t)
+				assert.Equal(requestIDs, []string{"foo"})
+			},
+		},
+		{
+			name: "keep request id in outgoing metadata",
+			ctx:  metadata.AppendToOutgoingContext(WithRequestID(context.Background(), "foo"), RequestIDKey, "bar"),
+			expect: func(t *testing.T, requestIDs []string) {
+				assert := assert.New(t)
+				assert.Equal(requestIDs, []string{"bar"})
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var requestIDs []string
+			invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+				md, _ := metadata.FromOutgoingContext(ctx)
+				requestIDs = md.Get(RequestIDKey)
+				return nil
+			}
+
+			assert.Nil(t, UnaryClientRequestIDInterceptor(tc.ctx, "/foo", nil, nil, nil, invoker))
+			tc.expect(t, requestIDs)
+		})
+	}
+}
+
+func TestUnaryServerRequestIDInterceptor(t *testing.T) {
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		expect func(t *testing.T, requestID string, ok bool)
+	}{
+		{
+			name: "extract request id",
+			ctx:  metadata.NewIncomingContext(context.Background(), metadata.Pairs(RequestIDKey, "foo")),
+			expect: func(t *testing.T, requestID string, ok bool) {
+				assert := assert.New(t)
+				assert.True(ok)
+				assert.Equal(requestID, "foo")
+			},
+		},
+		{
+			name: "request id does not exist",
+			ctx:  metadata.NewIncomingContext(context.Background(), metadata.Pairs("foo", "bar")),
+			expect: func(t *testing.T, requestID string, ok bool) {
+				assert := assert.New(t)
+				assert.False(ok)
+			},
+		},
+		{
+			name: "metadata does not exist",
+			ctx:  context.Background(),
+			expect: func(t *testing.T, requestID string, ok bool) {
+				assert := assert.New(t)
+				assert.False(ok)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				requestID string
+				ok        bool
+			)
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				requestID, ok = RequestIDFromContext(ctx)
+				return nil, nil
+			}
+
+			_, err := UnaryServerRequestIDInterceptor(tc.ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/foo"}, handler)
+			assert.Nil(t, err)
+			tc.expect(t, requestID, ok)
+		})
+	}
+}
@@ -0,0 +1,48 @@
+/*
+ *     Copyright 2022 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"github.com/pkg/errors"
+
+	"d7y.io/dragonfly/v2/internal/dferrors"
+	"d7y.io/dragonfly/v2/pkg/rpc/base"
+	"d7y.io/dragonfly/v2/pkg/rpc/scheduler"
+)
+
+// ErrNeedBackSource is returned by CheckRegisterResult when the scheduler asks the peer
+// to download from source at registration
+var ErrNeedBackSource = errors.New("scheduler asks to download from source")
+
+// NeedBackSource returns whether err is base.Code_SchedNeedBackSource from the scheduler
+func NeedBackSource(err error) bool {
+	var de *dferrors.DfError
+	if errors.As(err, &de) {
+		return de.Code == base.Code_SchedNeedBackSource
+	}
+	return false
+}
+
+// CheckRegisterResult interprets the result of RegisterPeerTask, ErrNeedBackSource is returned
+// when the scheduler asks to download from source, the piece result stream should not be
+// established in this case, other errors are returned as they are
+func CheckRegisterResult(result *scheduler.RegisterResult, err error) (*scheduler.RegisterResult, error) {
+	if NeedBackSource(err) {
+		return nil, errors.Wrap(ErrNeedBackSource, err.Error())
+	}
+	return result, err
+}
@@ -0,0 +1,81 @@
+/*
+ *     Copyright 2022 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"errors"
+	"testing"
+
+	testifyassert "github.com/stretchr/testify/assert"
+
+	"d7y.io/dragonfly/v2/internal/dferrors"
+	"d7y.io/dragonfly/v2/pkg/rpc/base"
+	"d7y.io/dragonfly/v2/pkg/rpc/scheduler"
+)
+
+func TestCheckRegisterResult(t *testing.T) {
+	result := &scheduler.RegisterResult{TaskId: "task"}
+	tests := []struct {
+		name   string
+		result *scheduler.RegisterResult
+		err    error
+		expect func(t *testing.T, result *scheduler.RegisterResult, err error)
+	}{
+		{
+			name:   "success",
+			result: result,
+			expect: func(t *testing.T, rr *scheduler.RegisterResult, err error) {
+				assert := testifyassert.New(t)
+				assert.Nil(err)
+				assert.Equal(result, rr)
+			},
+		},
+		{
+			name: "need back source",
+			err:  dferrors.New(base.Code_SchedNeedBackSource, "no available peers"),
+			expect: func(t *testing.T, rr *scheduler.RegisterResult, err error) {
+				assert := testifyassert.New(t)
+				assert.ErrorIs(err, ErrNeedBackSource)
+				assert.Nil(rr)
+			},
+		},
+		{
+			name: "scheduler error",
+			err:  dferrors.New(base.Code_SchedError, "fake error"),
+			expect: func(t *testing.T, rr *scheduler.RegisterResult, err error) {
+				assert := testifyassert.New(t)
+				assert.Error(err)
+				assert.False(errors.Is(err, ErrNeedBackSource))
+			},
+		},
+		{
+			name: "grpc error",
+			err:  errors.New("connection refused"),
+			expect: func(t *testing.T, rr *scheduler.RegisterResult, err error) {
+				assert := testifyassert.New(t)
+				assert.EqualError(err, "connection refused")
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rr, err := CheckRegisterResult(tc.result, tc.err)
+			tc.expect(t, rr, err)
+		})
+	}
+}
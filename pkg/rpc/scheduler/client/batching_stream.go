@@ -0,0 +1,178 @@
+/*
+ *     Copyright 2022 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"d7y.io/dragonfly/v2/pkg/rpc/base/common"
+	"d7y.io/dragonfly/v2/pkg/rpc/scheduler"
+)
+
+// pieceResultBatchOption is the grpc.CallOption of SchedulerClient.ReportPieceResult, it's not passed to grpc
+type pieceResultBatchOption struct {
+	grpc.EmptyCallOption
+	count    int
+	interval time.Duration
+}
+
+// WithPieceResultBatch coalesces the successful piece results of SchedulerClient.ReportPieceResult,
+// they are sent when count results are buffered or every interval, other piece results are sent immediately
+// after the buffered ones. A non-positive count or interval disables the corresponding flush.
+func WithPieceResultBatch(count int, interval time.Duration) grpc.CallOption {
+	return &pieceResultBatchOption{
+		count:    count,
+		interval: interval,
+	}
+}
+
+// splitPieceResultBatchOption returns the last pieceResultBatchOption in opts and the other options
+func splitPieceResultBatchOption(opts []grpc.CallOption) (*pieceResultBatchOption, []grpc.CallOption) {
+	var (
+		batch  *pieceResultBatchOption
+		others = make([]grpc.CallOption, 0, len(opts))
+	)
+	for _, opt := range opts {
+		if o, ok := opt.(*pieceResultBatchOption); ok {
+			batch = o
+			continue
+		}
+		others = append(others, opt)
+	}
+	return batch, others
+}
+
+// batchingPeerPacketStream buffers the successful piece results of PeerPacketStream,
+// the buffered results are sent in the batch of one piece result when flushing. Recv is passed through.
+type batchingPeerPacketStream struct {
+	PeerPacketStream
+	count    int
+	interval time.Duration
+
+	mu     sync.Mutex
+	buffer []*scheduler.PieceResult
+	// err is the error of sending, it's returned by the following Send
+	err  error
+	done chan struct{}
+	once sync.Once
+}
+
+func newBatchingPeerPacketStream(ctx context.Context, stream PeerPacketStream, count int, interval time.Duration) PeerPacketStream {
+	if count <= 0 && interval <= 0 {
+		return stream
+	}
+
+	s := &batchingPeerPacketStream{
+		PeerPacketStream: stream,
+		count:            count,
+		interval:         interval,
+		done:             make(chan struct{}),
+	}
+	if interval > 0 {
+		go s.flushLoop(ctx)
+	}
+	return s
+}
+
+func (s *batchingPeerPacketStream) Send(pr *scheduler.PieceResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return s.err
+	}
+
+	isEnd := pr.PieceInfo != nil && pr.PieceInfo.PieceNum == common.EndOfPiece
+	isBegin := pr.PieceInfo != nil && pr.PieceInfo.PieceNum == common.BeginOfPiece
+	if pr.Success && !isBegin && !isEnd {
+		s.buffer = append(s.buffer, pr)
+		if s.count > 0 && len(s.buffer) >= s.count {
+			return s.flush()
+		}
+		return nil
+	}
+
+	// the buffered results are sent first to keep the order
+	if err := s.flush(); err != nil {
+		return err
+	}
+	if isEnd {
+		s.stop()
+	}
+	if err := s.PeerPacketStream.Send(pr); err != nil {
+		s.fail(err)
+		return err
+	}
+	return nil
+}
+
+// flush sends the buffered piece results in one message, caller must hold the lock
+func (s *batchingPeerPacketStream) flush() error {
+	if len(s.buffer) == 0 {
+		return nil
+	}
+
+	pr := &scheduler.PieceResult{
+		TaskId: s.buffer[0].TaskId,
+		SrcPid: s.buffer[0].SrcPid,
+		Batch: &scheduler.PieceResults{
+			PieceResults: s.buffer,
+		},
+	}
+	// the buffer is owned by the sent message now
+	s.buffer = nil
+	if err := s.PeerPacketStream.Send(pr); err != nil {
+		s.fail(err)
+		return err
+	}
+	return nil
+}
+
+// fail records err and drops the buffered piece results, caller must hold the lock
+func (s *batchingPeerPacketStream) fail(err error) {
+	s.err = err
+	s.buffer = nil
+	s.stop()
+}
+
+func (s *batchingPeerPacketStream) stop() {
+	s.once.Do(func() {
+		close(s.done)
+	})
+}
+
+func (s *batchingPeerPacketStream) flushLoop(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.mu.Lock()
+			_ = s.flush()
+			s.mu.Unlock()
+		case <-s.done:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
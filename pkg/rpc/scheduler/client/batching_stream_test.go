@@ -0,0 +1,152 @@
+/*
+ *     Copyright 2022 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	testifyassert "github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+
+	"d7y.io/dragonfly/v2/pkg/rpc/base"
+	"d7y.io/dragonfly/v2/pkg/rpc/base/common"
+	"d7y.io/dragonfly/v2/pkg/rpc/scheduler"
+)
+
+// recordingStream records the piece numbers of sent piece results and the count of sent messages
+type recordingStream struct {
+	mu       sync.Mutex
+	pieces   []int32
+	messages int
+	err      error
+}
+
+func (s *recordingStream) Send(pr *scheduler.PieceResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	s.messages++
+	if pr.Batch != nil {
+		for _, p := range pr.Batch.PieceResults {
+			s.pieces = append(s.pieces, p.PieceInfo.PieceNum)
+		}
+		return nil
+	}
+	s.pieces = append(s.pieces, pr.PieceInfo.PieceNum)
+	return nil
+}
+
+func (s *recordingStream) Recv() (*scheduler.PeerPacket, error) {
+	return nil, nil
+}
+
+func (s *recordingStream) sent() []int32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]int32{}, s.pieces...)
+}
+
+func (s *recordingStream) sentMessages() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.messages
+}
+
+func newPieceResult(pieceNum int32, success bool) *scheduler.PieceResult {
+	return &scheduler.PieceResult{
+		TaskId:    "task",
+		SrcPid:    "peer",
+		PieceInfo: &base.PieceInfo{PieceNum: pieceNum},
+		Success:   success,
+	}
+}
+
+func TestBatchingPeerPacketStream_FlushByCount(t *testing.T) {
+	assert := testifyassert.New(t)
+	rs := &recordingStream{}
+	s := newBatchingPeerPacketStream(context.Background(), rs, 3, 0)
+
+	assert.Nil(s.Send(newPieceResult(0, true)))
+	assert.Nil(s.Send(newPieceResult(1, true)))
+	assert.Empty(rs.sent())
+	assert.Nil(s.Send(newPieceResult(2, true)))
+	assert.Equal([]int32{0, 1, 2}, rs.sent())
+	// the buffered results are sent in one message
+	assert.Equal(1, rs.sentMessages())
+
+	// failures are sent immediately after the buffered results
+	assert.Nil(s.Send(newPieceResult(3, true)))
+	assert.Nil(s.Send(newPieceResult(4, false)))
+	assert.Equal([]int32{0, 1, 2, 3, 4}, rs.sent())
+	assert.Equal(3, rs.sentMessages())
+
+	// the end of piece flushes the buffered results
+	assert.Nil(s.Send(newPieceResult(5, true)))
+	assert.Nil(s.Send(newPieceResult(common.EndOfPiece, true)))
+	assert.Equal([]int32{0, 1, 2, 3, 4, 5, common.EndOfPiece}, rs.sent())
+	assert.Equal(5, rs.sentMessages())
+}
+
+func TestBatchingPeerPacketStream_FlushByTime(t *testing.T) {
+	assert := testifyassert.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rs := &recordingStream{}
+	s := newBatchingPeerPacketStream(ctx, rs, 100, 50*time.Millisecond)
+
+	assert.Nil(s.Send(newPieceResult(0, true)))
+	assert.Nil(s.Send(newPieceResult(1, true)))
+	assert.Eventually(func() bool {
+		return len(rs.sent()) == 2
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal([]int32{0, 1}, rs.sent())
+	assert.Equal(1, rs.sentMessages())
+}
+
+func TestBatchingPeerPacketStream_SendError(t *testing.T) {
+	assert := testifyassert.New(t)
+	rs := &recordingStream{}
+	s := newBatchingPeerPacketStream(context.Background(), rs, 2, 0)
+
+	assert.Nil(s.Send(newPieceResult(0, true)))
+	rs.err = errors.New("stream is broken")
+	assert.Equal(rs.err, s.Send(newPieceResult(1, true)))
+	// the error is returned by the following sends
+	assert.Equal(rs.err, s.Send(newPieceResult(2, false)))
+}
+
+func TestSplitPieceResultBatchOption(t *testing.T) {
+	assert := testifyassert.New(t)
+	batch, opts := splitPieceResultBatchOption([]grpc.CallOption{grpc.WaitForReady(true), WithPieceResultBatch(10, time.Second)})
+	assert.Len(opts, 1)
+	assert.Equal(10, batch.count)
+	assert.Equal(time.Second, batch.interval)
+
+	batch, opts = splitPieceResultBatchOption([]grpc.CallOption{grpc.WaitForReady(true)})
+	assert.Nil(batch)
+	assert.Len(opts, 1)
+
+	// batching is disabled without count and interval
+	rs := &recordingStream{}
+	assert.Equal(rs, newBatchingPeerPacketStream(context.Background(), rs, 0, 0))
+}
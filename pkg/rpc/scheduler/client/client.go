@@ -51,7 +51,8 @@ func GetClientByAddr(addrs []dfnet.NetAddr, opts ...grpc.DialOption) (SchedulerC
 type SchedulerClient interface {
 	// RegisterPeerTask register peer task to scheduler
 	RegisterPeerTask(context.Context, *scheduler.PeerTaskRequest, ...grpc.CallOption) (*scheduler.RegisterResult, error)
-	// ReportPieceResult IsMigrating of ptr will be set to true
+	// ReportPieceResult IsMigrating of ptr will be set to true, WithPieceResultBatch coalesces successful piece results,
+	// the returned stream reconnects the scheduler when it's broken or the scheduler lost the peer, see Reconnects
 	ReportPieceResult(context.Context, string, *scheduler.PeerTaskRequest, ...grpc.CallOption) (PeerPacketStream, error)
 
 	ReportPeerResult(context.Context, *scheduler.PeerResult, ...grpc.CallOption) error
@@ -94,6 +95,11 @@ func (sc *schedulerClient) RegisterPeerTask(ctx context.Context, ptr *scheduler.
 		return client.RegisterPeerTask(ctx, ptr, opts...)
 	}
 	res, err := rpc.ExecuteWithRetry(reg, 0.2, 2.0, 3, nil)
+	if NeedBackSource(err) {
+		// the scheduler asks to download from source, other schedulers are not tried
+		logger.WithTaskAndPeerID(key, ptr.PeerId).Infof("RegisterPeerTask: scheduler %s asks to download from source", schedulerNode)
+		return nil, err
+	}
 	if err != nil {
 		logger.WithTaskAndPeerID(key, ptr.PeerId).Errorf("RegisterPeerTask: register peer task to scheduler %s failed: %v", schedulerNode, err)
 		return sc.retryRegisterPeerTask(ctx, key, ptr, []string{schedulerNode}, err, opts)
@@ -132,6 +138,10 @@ func (sc *schedulerClient) retryRegisterPeerTask(ctx context.Context, hashKey st
 		}
 		return client.RegisterPeerTask(ctx, ptr, opts...)
 	}, 0.2, 2.0, 3, cause)
+	if NeedBackSource(err) {
+		logger.WithTaskAndPeerID(hashKey, ptr.PeerId).Infof("retryRegisterPeerTask: scheduler %s asks to download from source", schedulerNode)
+		return nil, err
+	}
 	if err != nil {
 		logger.WithTaskAndPeerID(hashKey, ptr.PeerId).Errorf("retryRegisterPeerTask: register peer task to scheduler %s failed: %v", schedulerNode, err)
 		return sc.retryRegisterPeerTask(ctx, hashKey, ptr, exclusiveNodes, err, opts)
@@ -149,6 +159,7 @@ func (sc *schedulerClient) retryRegisterPeerTask(ctx context.Context, hashKey st
 }
 
 func (sc *schedulerClient) ReportPieceResult(ctx context.Context, taskID string, ptr *scheduler.PeerTaskRequest, opts ...grpc.CallOption) (PeerPacketStream, error) {
+	batch, opts := splitPieceResultBatchOption(opts)
 	pps, err := newPeerPacketStream(ctx, sc, taskID, ptr, opts)
 	if err != nil {
 		return pps, err
@@ -156,7 +167,13 @@ func (sc *schedulerClient) ReportPieceResult(ctx context.Context, taskID string,
 	logger.With("peerId", ptr.PeerId, "errMsg", err).Infof("start to report piece result for taskID: %s", taskID)
 
 	// trigger scheduling
-	return pps, pps.Send(scheduler.NewZeroPieceResult(taskID, ptr.PeerId))
+	if err := pps.Send(scheduler.NewZeroPieceResult(taskID, ptr.PeerId)); err != nil {
+		return pps, err
+	}
+	if batch != nil {
+		return newBatchingPeerPacketStream(ctx, pps, batch.count, batch.interval), nil
+	}
+	return pps, nil
 }
 
 func (sc *schedulerClient) ReportPeerResult(ctx context.Context, pr *scheduler.PeerResult, opts ...grpc.CallOption) error {
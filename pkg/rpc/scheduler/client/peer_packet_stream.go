@@ -18,8 +18,11 @@ package client
 
 import (
 	"context"
+	"io"
+	"sync"
 
 	"github.com/pkg/errors"
+	"go.uber.org/atomic"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -27,6 +30,7 @@ import (
 	"d7y.io/dragonfly/v2/internal/dferrors"
 	logger "d7y.io/dragonfly/v2/internal/dflog"
 	"d7y.io/dragonfly/v2/pkg/rpc"
+	"d7y.io/dragonfly/v2/pkg/rpc/base"
 	"d7y.io/dragonfly/v2/pkg/rpc/base/common"
 	"d7y.io/dragonfly/v2/pkg/rpc/scheduler"
 )
@@ -36,6 +40,9 @@ type PeerPacketStream interface {
 	Send(pr *scheduler.PieceResult) (err error)
 }
 
+// peerPacketStream reconnects the scheduler when the stream is broken or the scheduler lost the peer,
+// it registers the peer task again, opens a new stream and sends the zero piece result to trigger scheduling.
+// The other errors from scheduler, io.EOF and the errors of canceled ctx are returned directly.
 type peerPacketStream struct {
 	sc      *schedulerClient
 	ctx     context.Context
@@ -43,10 +50,20 @@ type peerPacketStream struct {
 	ptr     *scheduler.PeerTaskRequest
 	opts    []grpc.CallOption
 
+	getSchedulerClient func(key string, stick bool) (scheduler.SchedulerClient, string, error)
+
+	// mu protects stream and generation, the stream is replaced when reconnecting
+	mu sync.Mutex
 	// stream for one client
-	stream          scheduler.Scheduler_ReportPieceResultClient
-	failedServers   []string
-	lastPieceResult *scheduler.PieceResult
+	stream scheduler.Scheduler_ReportPieceResultClient
+	// generation is increased when the stream is replaced, the errors of replaced streams do not reconnect again
+	generation    int64
+	failedServers []string
+
+	// sendMu serializes Send, as grpc streams do not support concurrent Send
+	sendMu sync.Mutex
+	// reconnects is the count of reconnect attempts
+	reconnects *atomic.Int64
 
 	retryMeta rpc.RetryMeta
 }
@@ -55,11 +72,13 @@ func newPeerPacketStream(ctx context.Context, sc *schedulerClient, hashKey strin
 	ptr.IsMigrating = true
 
 	pps := &peerPacketStream{
-		sc:      sc,
-		ctx:     ctx,
-		hashKey: hashKey,
-		ptr:     ptr,
-		opts:    opts,
+		sc:                 sc,
+		ctx:                ctx,
+		hashKey:            hashKey,
+		ptr:                ptr,
+		opts:               opts,
+		getSchedulerClient: sc.getSchedulerClient,
+		reconnects:         atomic.NewInt64(0),
 		retryMeta: rpc.RetryMeta{
 			MaxAttempts: 3,
 			InitBackoff: 0.2,
@@ -73,78 +92,144 @@ func newPeerPacketStream(ctx context.Context, sc *schedulerClient, hashKey strin
 	return pps, nil
 }
 
+// Reconnects returns the count of reconnect attempts of the stream returned by SchedulerClient.ReportPieceResult,
+// it's 0 for other streams
+func Reconnects(stream PeerPacketStream) int64 {
+	switch s := stream.(type) {
+	case *peerPacketStream:
+		return s.reconnects.Load()
+	case *batchingPeerPacketStream:
+		return Reconnects(s.PeerPacketStream)
+	}
+	return 0
+}
+
 func (pps *peerPacketStream) Send(pr *scheduler.PieceResult) error {
-	pps.lastPieceResult = pr
+	pps.sendMu.Lock()
+	defer pps.sendMu.Unlock()
 	pps.sc.UpdateAccessNodeMapByHashKey(pps.hashKey)
 
-	if err := pps.stream.Send(pr); err != nil {
-		if err := pps.closeSend(); err != nil {
-			return err
+	stream, generation := pps.currentStream()
+	err := stream.Send(pr)
+	if err != nil && pps.shouldReconnect(err) {
+		logger.WithTaskAndPeerID(pps.hashKey, pps.ptr.PeerId).Warnf("send piece result failed: %s, reconnect scheduler", err)
+		if newStream, rerr := pps.reconnect(generation, err); rerr == nil {
+			stream, err = newStream, nil
+			// the zero piece result is sent when reconnecting
+			if pr.PieceInfo == nil || pr.PieceInfo.PieceNum != common.BeginOfPiece {
+				err = stream.Send(pr)
+			}
 		}
-		return err
 	}
 
-	if pr.PieceInfo.PieceNum == common.EndOfPiece {
-		if err := pps.closeSend(); err != nil {
-			return err
-		}
+	if err != nil {
+		_ = stream.CloseSend()
+		return err
 	}
 
+	// the batched piece results have no piece info
+	if pr.PieceInfo != nil && pr.PieceInfo.PieceNum == common.EndOfPiece {
+		return stream.CloseSend()
+	}
 	return nil
 }
 
-func (pps *peerPacketStream) closeSend() error {
-	return pps.stream.CloseSend()
+func (pps *peerPacketStream) Recv() (*scheduler.PeerPacket, error) {
+	for attempt := 0; ; attempt++ {
+		pps.sc.UpdateAccessNodeMapByHashKey(pps.hashKey)
+		stream, generation := pps.currentStream()
+		pp, err := stream.Recv()
+
+		cause := err
+		if err == nil {
+			if !lostPeer(pp.Code) {
+				return pp, nil
+			}
+			cause = dferrors.New(pp.Code, "scheduler lost the peer")
+		}
+		if !pps.shouldReconnect(cause) || attempt >= pps.retryMeta.MaxAttempts {
+			return pp, err
+		}
+
+		logger.WithTaskAndPeerID(pps.hashKey, pps.ptr.PeerId).Warnf("receive peer packet failed: %s, reconnect scheduler", cause)
+		if _, err := pps.reconnect(generation, cause); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (pps *peerPacketStream) currentStream() (scheduler.Scheduler_ReportPieceResultClient, int64) {
+	pps.mu.Lock()
+	defer pps.mu.Unlock()
+	return pps.stream, pps.generation
 }
 
-func (pps *peerPacketStream) Recv() (pp *scheduler.PeerPacket, err error) {
-	pps.sc.UpdateAccessNodeMapByHashKey(pps.hashKey)
-	return pps.stream.Recv()
+// lostPeer returns whether the code means the scheduler lost the peer, eg: scheduler restarted
+func lostPeer(code base.Code) bool {
+	return code == base.Code_SchedPeerNotFound || code == base.Code_PeerTaskNotFound
 }
 
-func (pps *peerPacketStream) retrySend(pr *scheduler.PieceResult, cause error) error {
-	if status.Code(cause) == codes.DeadlineExceeded || status.Code(cause) == codes.Canceled {
-		return cause
+func (pps *peerPacketStream) shouldReconnect(err error) bool {
+	if err == io.EOF || pps.ctx.Err() != nil {
+		return false
 	}
-
-	if err := pps.replaceStream(cause); err != nil {
-		return cause
+	if de, ok := err.(*dferrors.DfError); ok {
+		return lostPeer(de.Code)
 	}
-
-	return pps.Send(pr)
+	code := status.Code(err)
+	return code != codes.Canceled && code != codes.DeadlineExceeded
 }
 
-func (pps *peerPacketStream) retryRecv(cause error) (*scheduler.PeerPacket, error) {
-	if status.Code(cause) == codes.DeadlineExceeded || status.Code(cause) == codes.Canceled {
-		return nil, cause
+// reconnect replaces the stream of generation, the stream is not replaced again when it's replaced by others.
+// When the scheduler asks to download from source at registering again, a *dferrors.DfError with
+// base.Code_SchedNeedBackSource is returned, otherwise cause is returned when reconnecting failed
+func (pps *peerPacketStream) reconnect(generation int64, cause error) (scheduler.Scheduler_ReportPieceResultClient, error) {
+	pps.mu.Lock()
+	defer pps.mu.Unlock()
+
+	if generation != pps.generation {
+		return pps.stream, nil
 	}
-	_, err := rpc.ExecuteWithRetry(func() (interface{}, error) {
-		client, _, err := pps.sc.getSchedulerClient(pps.hashKey, false)
+
+	log := logger.WithTaskAndPeerID(pps.hashKey, pps.ptr.PeerId)
+	res, err := rpc.ExecuteWithRetry(func() (interface{}, error) {
+		pps.reconnects.Inc()
+		client, _, err := pps.getSchedulerClient(pps.hashKey, false)
 		if err != nil {
 			return nil, err
 		}
-		//timeCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		//defer cancel()
-		_, err = client.RegisterPeerTask(pps.ctx, pps.ptr)
+		result, err := client.RegisterPeerTask(pps.ctx, pps.ptr)
 		if err != nil {
 			return nil, err
 		}
+		// the peer task is downloading pieces from peers already, it can not switch to the direct piece,
+		// and scheduler does not schedule parents for small or tiny task, download it from source instead
+		if result.SizeScope != base.SizeScope_NORMAL {
+			return nil, dferrors.Newf(base.Code_SchedNeedBackSource, "scheduler returns size scope %s at registering again",
+				base.SizeScope_name[int32(result.SizeScope)])
+		}
 		stream, err := client.ReportPieceResult(pps.ctx, pps.opts...)
 		if err != nil {
 			return nil, err
 		}
-		pps.stream = stream.(scheduler.Scheduler_ReportPieceResultClient)
-		pps.retryMeta.StreamTimes = 1
-		err = pps.Send(pps.lastPieceResult)
-		if err != nil {
+		if err := stream.Send(scheduler.NewZeroPieceResult(pps.hashKey, pps.ptr.PeerId)); err != nil {
 			return nil, err
 		}
-		return nil, nil
+		return stream, nil
 	}, pps.retryMeta.InitBackoff, pps.retryMeta.MaxBackOff, pps.retryMeta.MaxAttempts, nil)
 	if err != nil {
+		log.Errorf("reconnect scheduler failed: %s, cause: %s", err, cause)
+		if NeedBackSource(err) {
+			return nil, err
+		}
 		return nil, cause
 	}
-	return pps.Recv()
+
+	_ = pps.stream.CloseSend()
+	pps.stream = res.(scheduler.Scheduler_ReportPieceResultClient)
+	pps.generation++
+	log.Infof("reconnect scheduler success, generation: %d", pps.generation)
+	return pps.stream, nil
 }
 
 func (pps *peerPacketStream) initStream() error {
@@ -152,7 +237,7 @@ func (pps *peerPacketStream) initStream() error {
 	stream, err := rpc.ExecuteWithRetry(func() (interface{}, error) {
 		var client scheduler.SchedulerClient
 		var err error
-		client, target, err = pps.sc.getSchedulerClient(pps.hashKey, true)
+		client, target, err = pps.getSchedulerClient(pps.hashKey, true)
 		if err != nil {
 			return nil, err
 		}
@@ -170,29 +255,6 @@ func (pps *peerPacketStream) initStream() error {
 	return nil
 }
 
-func (pps *peerPacketStream) replaceStream(cause error) error {
-	if pps.retryMeta.StreamTimes >= pps.retryMeta.MaxAttempts {
-		return cause
-	}
-	var target string
-	res, err := rpc.ExecuteWithRetry(func() (interface{}, error) {
-		var client scheduler.SchedulerClient
-		var err error
-		client, target, err = pps.sc.getSchedulerClient(pps.hashKey, true)
-		if err != nil {
-			return nil, err
-		}
-		return client.ReportPieceResult(pps.ctx, pps.opts...)
-	}, pps.retryMeta.InitBackoff, pps.retryMeta.MaxBackOff, pps.retryMeta.MaxAttempts, cause)
-	if err != nil {
-		logger.WithTaskID(pps.hashKey).Infof("replaceStream: invoke scheduler node %s ReportPieceResult failed: %v", target, err)
-		return pps.replaceStream(cause)
-	}
-	pps.stream = res.(scheduler.Scheduler_ReportPieceResultClient)
-	pps.retryMeta.StreamTimes++
-	return nil
-}
-
 func (pps *peerPacketStream) replaceClient(cause error) error {
 	preNode, err := pps.sc.TryMigrate(pps.hashKey, cause, pps.failedServers)
 	if err != nil {
@@ -204,7 +266,7 @@ func (pps *peerPacketStream) replaceClient(cause error) error {
 	stream, err := rpc.ExecuteWithRetry(func() (interface{}, error) {
 		var client scheduler.SchedulerClient
 		var err error
-		client, target, err = pps.sc.getSchedulerClient(pps.hashKey, true)
+		client, target, err = pps.getSchedulerClient(pps.hashKey, true)
 		if err != nil {
 			return nil, err
 		}
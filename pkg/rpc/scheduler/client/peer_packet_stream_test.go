@@ -0,0 +1,244 @@
+/*
+ *     Copyright 2022 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	testifyassert "github.com/stretchr/testify/assert"
+	"go.uber.org/atomic"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"d7y.io/dragonfly/v2/internal/dferrors"
+	"d7y.io/dragonfly/v2/pkg/rpc"
+	"d7y.io/dragonfly/v2/pkg/rpc/base"
+	"d7y.io/dragonfly/v2/pkg/rpc/base/common"
+	"d7y.io/dragonfly/v2/pkg/rpc/scheduler"
+	"d7y.io/dragonfly/v2/pkg/rpc/scheduler/mocks"
+)
+
+func newTestPeerPacketStream(t *testing.T, client scheduler.SchedulerClient) *peerPacketStream {
+	pps := &peerPacketStream{
+		sc:      &schedulerClient{rpc.NewConnection(context.Background(), "scheduler-test", nil, nil)},
+		ctx:     context.Background(),
+		hashKey: "task",
+		ptr:     &scheduler.PeerTaskRequest{PeerId: "peer", IsMigrating: true},
+		getSchedulerClient: func(key string, stick bool) (scheduler.SchedulerClient, string, error) {
+			return client, "scheduler", nil
+		},
+		reconnects: atomic.NewInt64(0),
+		retryMeta: rpc.RetryMeta{
+			MaxAttempts: 3,
+			InitBackoff: 0.001,
+			MaxBackOff:  0.01,
+		},
+	}
+	if err := pps.initStream(); err != nil {
+		t.Fatal(err)
+	}
+	return pps
+}
+
+func TestPeerPacketStream_SendReconnect(t *testing.T) {
+	assert := testifyassert.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var (
+		client  = mocks.NewMockSchedulerClient(ctrl)
+		stream1 = mocks.NewMockScheduler_ReportPieceResultClient(ctrl)
+		stream2 = mocks.NewMockScheduler_ReportPieceResultClient(ctrl)
+		first   = scheduler.NewZeroPieceResult("task", "peer")
+		second  = &scheduler.PieceResult{TaskId: "task", SrcPid: "peer", PieceInfo: &base.PieceInfo{PieceNum: 1}}
+		end     = &scheduler.PieceResult{TaskId: "task", SrcPid: "peer", PieceInfo: &base.PieceInfo{PieceNum: common.EndOfPiece}}
+	)
+	gomock.InOrder(
+		client.EXPECT().ReportPieceResult(gomock.Any()).Return(stream1, nil),
+		stream1.EXPECT().Send(first).Return(nil),
+		stream1.EXPECT().Send(second).Return(status.Error(codes.Unavailable, "scheduler is gone")),
+		// the peer task is registered again and the zero piece result is sent with the new stream
+		client.EXPECT().RegisterPeerTask(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, ptr *scheduler.PeerTaskRequest) (*scheduler.RegisterResult, error) {
+				assert.True(ptr.IsMigrating)
+				return &scheduler.RegisterResult{TaskId: "task", SizeScope: base.SizeScope_NORMAL}, nil
+			}),
+		client.EXPECT().ReportPieceResult(gomock.Any()).Return(stream2, nil),
+		stream2.EXPECT().Send(first).Return(nil),
+		stream1.EXPECT().CloseSend().Return(nil),
+		stream2.EXPECT().Send(second).Return(nil),
+		stream2.EXPECT().Send(end).Return(nil),
+		stream2.EXPECT().CloseSend().Return(nil),
+	)
+
+	pps := newTestPeerPacketStream(t, client)
+	assert.Nil(pps.Send(first))
+	assert.Nil(pps.Send(second))
+	assert.Nil(pps.Send(end))
+	assert.Equal(int64(1), Reconnects(pps))
+}
+
+func TestPeerPacketStream_RecvReconnect(t *testing.T) {
+	tests := []struct {
+		name       string
+		recvResult func(stream *mocks.MockScheduler_ReportPieceResultClient) *gomock.Call
+	}{
+		{
+			name: "stream is broken",
+			recvResult: func(stream *mocks.MockScheduler_ReportPieceResultClient) *gomock.Call {
+				return stream.EXPECT().Recv().Return(nil, status.Error(codes.Unavailable, "scheduler is gone"))
+			},
+		},
+		{
+			name: "scheduler lost the peer",
+			recvResult: func(stream *mocks.MockScheduler_ReportPieceResultClient) *gomock.Call {
+				return stream.EXPECT().Recv().Return(nil, dferrors.New(base.Code_SchedPeerNotFound, "peer not found"))
+			},
+		},
+		{
+			name: "scheduler sends peer not found",
+			recvResult: func(stream *mocks.MockScheduler_ReportPieceResultClient) *gomock.Call {
+				return stream.EXPECT().Recv().Return(&scheduler.PeerPacket{TaskId: "task", SrcPid: "peer", Code: base.Code_PeerTaskNotFound}, nil)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := testifyassert.New(t)
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			var (
+				client  = mocks.NewMockSchedulerClient(ctrl)
+				stream1 = mocks.NewMockScheduler_ReportPieceResultClient(ctrl)
+				stream2 = mocks.NewMockScheduler_ReportPieceResultClient(ctrl)
+				first   = scheduler.NewZeroPieceResult("task", "peer")
+				packet  = &scheduler.PeerPacket{TaskId: "task", SrcPid: "peer", Code: base.Code_Success}
+			)
+			gomock.InOrder(
+				client.EXPECT().ReportPieceResult(gomock.Any()).Return(stream1, nil),
+				stream1.EXPECT().Send(first).Return(nil),
+				tc.recvResult(stream1),
+				// the first reconnect attempt fails
+				client.EXPECT().RegisterPeerTask(gomock.Any(), gomock.Any()).Return(nil, status.Error(codes.Unavailable, "scheduler is gone")),
+				client.EXPECT().RegisterPeerTask(gomock.Any(), gomock.Any()).Return(&scheduler.RegisterResult{TaskId: "task"}, nil),
+				client.EXPECT().ReportPieceResult(gomock.Any()).Return(stream2, nil),
+				stream2.EXPECT().Send(first).Return(nil),
+				stream1.EXPECT().CloseSend().Return(nil),
+				stream2.EXPECT().Recv().Return(packet, nil),
+				stream2.EXPECT().Recv().Return(nil, io.EOF),
+			)
+
+			pps := newTestPeerPacketStream(t, client)
+			assert.Nil(pps.Send(first))
+			pp, err := pps.Recv()
+			assert.Nil(err)
+			assert.Equal(packet, pp)
+			// the end of stream is not reconnected
+			_, err = pps.Recv()
+			assert.Equal(io.EOF, err)
+			assert.Equal(int64(2), Reconnects(pps))
+		})
+	}
+}
+
+func TestPeerPacketStream_NotReconnect(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{
+			name: "scheduler error",
+			err:  dferrors.New(base.Code_SchedNeedBackSource, "need back source"),
+		},
+		{
+			name: "canceled",
+			err:  status.Error(codes.Canceled, "canceled"),
+		},
+		{
+			name: "deadline exceeded",
+			err:  status.Error(codes.DeadlineExceeded, "deadline exceeded"),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := testifyassert.New(t)
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			client := mocks.NewMockSchedulerClient(ctrl)
+			stream := mocks.NewMockScheduler_ReportPieceResultClient(ctrl)
+			client.EXPECT().ReportPieceResult(gomock.Any()).Return(stream, nil)
+			stream.EXPECT().Recv().Return(nil, tc.err)
+
+			pps := newTestPeerPacketStream(t, client)
+			_, err := pps.Recv()
+			assert.Equal(tc.err, err)
+			assert.Equal(int64(0), Reconnects(pps))
+		})
+	}
+}
+
+func TestPeerPacketStream_ReconnectFailed(t *testing.T) {
+	assert := testifyassert.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var (
+		client = mocks.NewMockSchedulerClient(ctrl)
+		stream = mocks.NewMockScheduler_ReportPieceResultClient(ctrl)
+		first  = scheduler.NewZeroPieceResult("task", "peer")
+		cause  = status.Error(codes.Unavailable, "scheduler is gone")
+	)
+	client.EXPECT().ReportPieceResult(gomock.Any()).Return(stream, nil)
+	stream.EXPECT().Send(first).Return(cause)
+	client.EXPECT().RegisterPeerTask(gomock.Any(), gomock.Any()).Times(3).Return(nil, cause)
+	stream.EXPECT().CloseSend().Return(nil)
+
+	pps := newTestPeerPacketStream(t, client)
+	assert.Equal(cause, pps.Send(first))
+	assert.Equal(int64(3), Reconnects(pps))
+}
+
+func TestPeerPacketStream_NeedBackSourceAtReconnecting(t *testing.T) {
+	assert := testifyassert.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var (
+		client = mocks.NewMockSchedulerClient(ctrl)
+		stream = mocks.NewMockScheduler_ReportPieceResultClient(ctrl)
+	)
+	gomock.InOrder(
+		client.EXPECT().ReportPieceResult(gomock.Any()).Return(stream, nil),
+		stream.EXPECT().Recv().Return(nil, status.Error(codes.Unavailable, "scheduler is gone")),
+		// scheduler does not schedule parents for small task
+		client.EXPECT().RegisterPeerTask(gomock.Any(), gomock.Any()).Return(&scheduler.RegisterResult{TaskId: "task", SizeScope: base.SizeScope_SMALL}, nil),
+	)
+
+	pps := newTestPeerPacketStream(t, client)
+	_, err := pps.Recv()
+	assert.True(NeedBackSource(err))
+	assert.Equal(int64(1), Reconnects(pps))
+	// the reconnects of the batched stream are the ones of the wrapped stream
+	assert.Equal(int64(1), Reconnects(newBatchingPeerPacketStream(context.Background(), pps, 2, 0)))
+}
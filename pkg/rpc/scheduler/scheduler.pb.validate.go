@@ -556,6 +556,16 @@ func (m *PieceResult) Validate() error {
 
 	// no validation rules for FinishedCount
 
+	if v, ok := interface{}(m.GetBatch()).(interface{ Validate() error }); ok {
+		if err := v.Validate(); err != nil {
+			return PieceResultValidationError{
+				field:  "Batch",
+				reason: "embedded message failed validation",
+				cause:  err,
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -927,6 +937,86 @@ var _ interface {
 	ErrorName() string
 } = PeerTargetValidationError{}
 
+// Validate checks the field values on PieceResults with the rules defined in
+// the proto definition for this message. If any rules are violated, an error
+// is returned.
+func (m *PieceResults) Validate() error {
+	if m == nil {
+		return nil
+	}
+
+	for idx, item := range m.GetPieceResults() {
+		_, _ = idx, item
+
+		if v, ok := interface{}(item).(interface{ Validate() error }); ok {
+			if err := v.Validate(); err != nil {
+				return PieceResultsValidationError{
+					field:  fmt.Sprintf("PieceResults[%v]", idx),
+					reason: "embedded message failed validation",
+					cause:  err,
+				}
+			}
+		}
+
+	}
+
+	return nil
+}
+
+// PieceResultsValidationError is the validation error returned by
+// PieceResults.Validate if the designated constraints aren't met.
+type PieceResultsValidationError struct {
+	field  string
+	reason string
+	cause  error
+	key    bool
+}
+
+// Field function returns field value.
+func (e PieceResultsValidationError) Field() string { return e.field }
+
+// Reason function returns reason value.
+func (e PieceResultsValidationError) Reason() string { return e.reason }
+
+// Cause function returns cause value.
+func (e PieceResultsValidationError) Cause() error { return e.cause }
+
+// Key function returns key value.
+func (e PieceResultsValidationError) Key() bool { return e.key }
+
+// ErrorName returns error name.
+func (e PieceResultsValidationError) ErrorName() string { return "PieceResultsValidationError" }
+
+// Error satisfies the builtin error interface
+func (e PieceResultsValidationError) Error() string {
+	cause := ""
+	if e.cause != nil {
+		cause = fmt.Sprintf(" | caused by: %v", e.cause)
+	}
+
+	key := ""
+	if e.key {
+		key = "key for "
+	}
+
+	return fmt.Sprintf(
+		"invalid %sPieceResults.%s: %s%s",
+		key,
+		e.field,
+		e.reason,
+		cause)
+}
+
+var _ error = PieceResultsValidationError{}
+
+var _ interface {
+	Field() string
+	Reason() string
+	Key() bool
+	Cause() error
+	ErrorName() string
+} = PieceResultsValidationError{}
+
 // Validate checks the field values on PeerPacket_DestPeer with the rules
 // defined in the proto definition for this message. If any rules are
 // violated, an error is returned.
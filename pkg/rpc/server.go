@@ -35,12 +35,17 @@ import (
 	"d7y.io/dragonfly/v2/pkg/rpc/base/common"
 )
 
+// KeepaliveEnforcementPolicy is keepalive enforcement policy of grpc servers,
+// it permits the pings from clients with default keepalive parameters, even if there is no active stream.
+var KeepaliveEnforcementPolicy = keepalive.EnforcementPolicy{
+	MinTime:             1 * time.Minute,
+	PermitWithoutStream: true,
+}
+
 var DefaultServerOptions = []grpc.ServerOption{
 	grpc.ConnectionTimeout(10 * time.Second),
 	grpc.InitialConnWindowSize(8 * 1024 * 1024),
-	grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
-		MinTime: 1 * time.Minute,
-	}),
+	grpc.KeepaliveEnforcementPolicy(KeepaliveEnforcementPolicy),
 	grpc.KeepaliveParams(keepalive.ServerParameters{
 		MaxConnectionIdle: 5 * time.Minute,
 	}),
@@ -49,12 +54,14 @@ var DefaultServerOptions = []grpc.ServerOption{
 		streamServerInterceptor,
 		grpc_prometheus.StreamServerInterceptor,
 		grpc_zap.StreamServerInterceptor(logger.GrpcLogger.Desugar()),
+		StreamServerRequestIDInterceptor,
 		grpc_validator.StreamServerInterceptor(),
 	)),
 	grpc.UnaryInterceptor(grpc_middleware.ChainUnaryServer(
 		unaryServerInterceptor,
 		grpc_prometheus.UnaryServerInterceptor,
 		grpc_zap.UnaryServerInterceptor(logger.GrpcLogger.Desugar()),
+		UnaryServerRequestIDInterceptor,
 		grpc_validator.UnaryServerInterceptor(),
 	)),
 }
@@ -0,0 +1,157 @@
+/*
+ *     Copyright 2022 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package source
+
+import (
+	"net/url"
+
+	"github.com/pkg/errors"
+
+	"d7y.io/dragonfly/v2/pkg/util/rangeutils"
+)
+
+// FallbackResourceClient is a chain of clients for a single scheme, it is registered like any other client.
+// Every request is sent with the clients in order until one succeeds, the next client is tried only when
+// the error is ErrResourceNotReachable, the other errors are returned directly.
+//
+//	source.Register("s3", source.NewFallbackResourceClient(directClient, signingProxyClient), adapter)
+type FallbackResourceClient struct {
+	clients []ResourceClient
+}
+
+var (
+	_ ResourceClient       = (*FallbackResourceClient)(nil)
+	_ ResourceChecker      = (*FallbackResourceClient)(nil)
+	_ ResourceLister       = (*FallbackResourceClient)(nil)
+	_ RangeReader          = (*FallbackResourceClient)(nil)
+	_ MultiRangeDownloader = (*FallbackResourceClient)(nil)
+)
+
+// NewFallbackResourceClient returns a FallbackResourceClient trying clients in order
+func NewFallbackResourceClient(clients ...ResourceClient) *FallbackResourceClient {
+	return &FallbackResourceClient{
+		clients: clients,
+	}
+}
+
+// fallback calls fn with the clients in order until it returns nil or an error other than ErrResourceNotReachable.
+// The clients returning unsupported are skipped, unsupported is returned when no client supports the request,
+// and the error of the last client is returned when all clients are not reachable
+func (f *FallbackResourceClient) fallback(unsupported error, fn func(client ResourceClient) error) error {
+	err := unsupported
+	for _, client := range f.clients {
+		clientErr := fn(client)
+		if errors.Is(clientErr, unsupported) {
+			continue
+		}
+		err = clientErr
+		if !IsResourceNotReachableError(err) {
+			return err
+		}
+	}
+	return err
+}
+
+func (f *FallbackResourceClient) GetContentLength(request *Request) (int64, error) {
+	var contentLength int64 = UnknownSourceFileLen
+	err := f.fallback(ErrNoClientFound, func(client ResourceClient) (err error) {
+		contentLength, err = client.GetContentLength(request)
+		return err
+	})
+	return contentLength, err
+}
+
+func (f *FallbackResourceClient) IsSupportRange(request *Request) (bool, error) {
+	var support bool
+	err := f.fallback(ErrNoClientFound, func(client ResourceClient) (err error) {
+		support, err = client.IsSupportRange(request)
+		return err
+	})
+	return support, err
+}
+
+func (f *FallbackResourceClient) IsExpired(request *Request, info *ExpireInfo) (bool, error) {
+	var expired bool
+	err := f.fallback(ErrNoClientFound, func(client ResourceClient) (err error) {
+		expired, err = client.IsExpired(request, info)
+		return err
+	})
+	return expired, err
+}
+
+func (f *FallbackResourceClient) Download(request *Request) (*Response, error) {
+	var response *Response
+	err := f.fallback(ErrNoClientFound, func(client ResourceClient) (err error) {
+		response, err = client.Download(request)
+		return err
+	})
+	return response, err
+}
+
+func (f *FallbackResourceClient) GetLastModified(request *Request) (int64, error) {
+	var lastModified int64 = -1
+	err := f.fallback(ErrNoClientFound, func(client ResourceClient) (err error) {
+		lastModified, err = client.GetLastModified(request)
+		return err
+	})
+	return lastModified, err
+}
+
+// Check checks the clients in order, it succeeds when any client is reachable
+func (f *FallbackResourceClient) Check(request *Request) error {
+	return f.fallback(ErrNoClientFound, func(client ResourceClient) error {
+		return check(client, request)
+	})
+}
+
+// List lists resources with the clients implementing ResourceLister
+func (f *FallbackResourceClient) List(request *Request) ([]*url.URL, error) {
+	var urls []*url.URL
+	err := f.fallback(ErrClientNotSupportList, func(client ResourceClient) (err error) {
+		lister, ok := client.(ResourceLister)
+		if !ok {
+			return ErrClientNotSupportList
+		}
+		urls, err = lister.List(request)
+		return err
+	})
+	return urls, err
+}
+
+// DownloadRange downloads ranges with the clients implementing RangeReader
+func (f *FallbackResourceClient) DownloadRange(request *Request, ranges []rangeutils.Range) ([]*Response, error) {
+	var responses []*Response
+	err := f.fallback(ErrClientNotSupportRangeRead, func(client ResourceClient) (err error) {
+		rangeReader, ok := client.(RangeReader)
+		if !ok {
+			return ErrClientNotSupportRangeRead
+		}
+		responses, err = rangeReader.DownloadRange(request, ranges)
+		return err
+	})
+	return responses, err
+}
+
+// DownloadMultiRange downloads Request.Ranges with the clients implementing MultiRangeDownloader or RangeReader
+func (f *FallbackResourceClient) DownloadMultiRange(request *Request) (*Response, error) {
+	var response *Response
+	err := f.fallback(ErrClientNotSupportRangeRead, func(client ResourceClient) (err error) {
+		response, err = downloadMultiRange(client, request)
+		return err
+	})
+	return response, err
+}
@@ -0,0 +1,99 @@
+/*
+ *     Copyright 2022 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package source
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	testifyassert "github.com/stretchr/testify/assert"
+
+	"d7y.io/dragonfly/v2/pkg/util/rangeutils"
+)
+
+func TestFallbackResourceClient(t *testing.T) {
+	assert := testifyassert.New(t)
+	unreachable := newCountingClient()
+	unreachable.err = ErrResourceNotReachable
+	manager := NewManager()
+	assert.NoError(manager.Register("fake", NewFallbackResourceClient(unreachable, &fakeClient{contentLength: 10}), noopAdapter))
+	rc, ok := manager.GetClient("fake")
+	assert.True(ok)
+	request, err := NewRequest("fake://example.com/file")
+	assert.NoError(err)
+
+	// the first client is not reachable, the second one succeeds
+	contentLength, err := rc.GetContentLength(request)
+	assert.NoError(err)
+	assert.Equal(int64(10), contentLength)
+	response, err := rc.Download(request)
+	assert.NoError(err)
+	data, err := io.ReadAll(response.Body)
+	assert.NoError(err)
+	assert.Equal("hello world", string(data))
+	// the clients without ResourceChecker are checked by GetContentLength
+	assert.NoError(rc.(ResourceChecker).Check(request))
+	assert.Equal(2, unreachable.calls["GetContentLength"])
+	assert.Equal(1, unreachable.calls["Download"])
+}
+
+func TestFallbackResourceClient_Errors(t *testing.T) {
+	assert := testifyassert.New(t)
+	request, err := NewRequest("fake://example.com/file")
+	assert.NoError(err)
+
+	// the other errors are returned without trying the next client
+	failed := newCountingClient()
+	failed.err = errors.New("forbidden")
+	next := newCountingClient()
+	client := NewFallbackResourceClient(failed, next)
+	_, err = client.Download(request)
+	assert.EqualError(err, "forbidden")
+	assert.Equal(0, next.calls["Download"])
+
+	// the error of the last client is returned when all clients are not reachable
+	first := newCountingClient()
+	first.err = ErrResourceNotReachable
+	last := newCountingClient()
+	last.err = &notReachableError{cause: errors.New("connection refused")}
+	client = NewFallbackResourceClient(first, last)
+	_, err = client.GetLastModified(request)
+	assert.True(IsResourceNotReachableError(err))
+	assert.EqualError(err, "resource is not reachable: connection refused")
+
+	_, err = NewFallbackResourceClient().GetContentLength(request)
+	assert.True(IsNoClientFoundError(err))
+}
+
+func TestFallbackResourceClient_DownloadRange(t *testing.T) {
+	assert := testifyassert.New(t)
+	request, err := NewRequest("fake://example.com/file")
+	assert.NoError(err)
+	ranges := []rangeutils.Range{{StartIndex: 0, EndIndex: 1}, {StartIndex: 4, EndIndex: 7}}
+
+	// the clients without RangeReader are skipped
+	client := NewFallbackResourceClient(&fakeClient{}, &fakeRangeClient{})
+	responses, err := client.DownloadRange(request, ranges)
+	assert.NoError(err)
+	assert.Len(responses, 2)
+
+	_, err = NewFallbackResourceClient(&fakeClient{}).DownloadRange(request, ranges)
+	assert.ErrorIs(err, ErrClientNotSupportRangeRead)
+	_, err = NewFallbackResourceClient(&fakeClient{}).List(request)
+	assert.ErrorIs(err, ErrClientNotSupportList)
+}
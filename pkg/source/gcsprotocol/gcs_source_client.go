@@ -0,0 +1,268 @@
+/*
+ *     Copyright 2020 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gcsprotocol
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-http-utils/headers"
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+
+	"d7y.io/dragonfly/v2/pkg/source"
+)
+
+const GCSClient = "gs"
+
+const (
+	// defaultEndpoint is the endpoint of gcs json api
+	defaultEndpoint = "https://storage.googleapis.com"
+
+	// readOnlyScope is the oauth2 scope to read gcs objects
+	readOnlyScope = "https://www.googleapis.com/auth/devstorage.read_only"
+
+	// generationHeader is the response header of object generation
+	generationHeader = "X-Goog-Generation"
+)
+
+var _ source.ResourceClient = (*gcsSourceClient)(nil)
+
+func init() {
+	if err := source.Register(GCSClient, NewGCSSourceClient(), adaptor); err != nil {
+		panic(err)
+	}
+}
+
+func adaptor(request *source.Request) *source.Request {
+	clonedRequest := request.Clone(request.Context())
+	if request.Header.Get(source.Range) != "" {
+		clonedRequest.Header.Set(headers.Range, fmt.Sprintf("bytes=%s", request.Header.Get(source.Range)))
+		clonedRequest.Header.Del(source.Range)
+	}
+	clonedRequest.Header.Del(source.LastModified)
+	clonedRequest.Header.Del(source.ETag)
+	return clonedRequest
+}
+
+func NewGCSSourceClient(opts ...GCSSourceClientOption) source.ResourceClient {
+	return newGCSSourceClient(opts...)
+}
+
+func newGCSSourceClient(opts ...GCSSourceClientOption) *gcsSourceClient {
+	sourceClient := &gcsSourceClient{
+		endpoint: defaultEndpoint,
+	}
+	for i := range opts {
+		opts[i](sourceClient)
+	}
+	return sourceClient
+}
+
+type GCSSourceClientOption func(p *gcsSourceClient)
+
+// WithCredentialsFile sets the service account json file,
+// application default credentials are used if it is empty
+func WithCredentialsFile(credentialsFile string) GCSSourceClientOption {
+	return func(sourceClient *gcsSourceClient) {
+		sourceClient.credentialsFile = credentialsFile
+	}
+}
+
+// WithEndpoint sets the endpoint of gcs json api
+func WithEndpoint(endpoint string) GCSSourceClientOption {
+	return func(sourceClient *gcsSourceClient) {
+		sourceClient.endpoint = strings.TrimSuffix(endpoint, "/")
+	}
+}
+
+// WithHTTPClient sets the http client which is already authorized
+func WithHTTPClient(client *http.Client) GCSSourceClientOption {
+	return func(sourceClient *gcsSourceClient) {
+		sourceClient.httpClient = client
+	}
+}
+
+// gcsSourceClient is an implementation of the interface of source.ResourceClient.
+type gcsSourceClient struct {
+	endpoint        string
+	credentialsFile string
+
+	// httpClient is authorized lazily, credentials may be absent when the client is registered
+	mu         sync.Mutex
+	httpClient *http.Client
+}
+
+// objectAttrs is the object metadata of gcs json api
+type objectAttrs struct {
+	Size       string    `json:"size"`
+	Generation string    `json:"generation"`
+	Updated    time.Time `json:"updated"`
+}
+
+func (gsc *gcsSourceClient) GetContentLength(request *source.Request) (int64, error) {
+	attrs, err := gsc.getObjectAttrs(request)
+	if err != nil {
+		return source.UnknownSourceFileLen, err
+	}
+	contentLen, err := strconv.ParseInt(attrs.Size, 10, 64)
+	if err != nil {
+		return source.UnknownSourceFileLen, errors.Wrapf(err, "parse object size str to int64")
+	}
+	return contentLen, nil
+}
+
+func (gsc *gcsSourceClient) IsSupportRange(request *source.Request) (bool, error) {
+	if _, err := gsc.getObjectAttrs(request); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (gsc *gcsSourceClient) IsExpired(request *source.Request, info *source.ExpireInfo) (bool, error) {
+	attrs, err := gsc.getObjectAttrs(request)
+	if err != nil {
+		return false, err
+	}
+	return !(attrs.Generation == info.ETag || attrs.Updated.UTC().Format(http.TimeFormat) == info.LastModified), nil
+}
+
+func (gsc *gcsSourceClient) Download(request *source.Request) (*source.Response, error) {
+	resp, err := gsc.doRequest(request, true)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, errors.Wrapf(source.ErrResourceNotReachable, "gcs object %s", request.URL)
+	}
+	err = source.CheckResponseCode(resp.StatusCode, []int{http.StatusOK, http.StatusPartialContent})
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	response := source.NewResponse(
+		resp.Body,
+		source.WithContentLength(resp.ContentLength),
+		source.WithExpireInfo(
+			source.ExpireInfo{
+				LastModified: resp.Header.Get(headers.LastModified),
+				ETag:         resp.Header.Get(generationHeader),
+			},
+		))
+	return response, nil
+}
+
+func (gsc *gcsSourceClient) GetLastModified(request *source.Request) (int64, error) {
+	attrs, err := gsc.getObjectAttrs(request)
+	if err != nil {
+		return -1, err
+	}
+	return attrs.Updated.UnixNano() / int64(time.Millisecond), nil
+}
+
+func (gsc *gcsSourceClient) getObjectAttrs(request *source.Request) (*objectAttrs, error) {
+	resp, err := gsc.doRequest(request, false)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, errors.Wrapf(source.ErrResourceNotReachable, "gcs object %s", request.URL)
+	}
+	if err := source.CheckResponseCode(resp.StatusCode, []int{http.StatusOK}); err != nil {
+		return nil, err
+	}
+
+	attrs := &objectAttrs{}
+	if err := json.NewDecoder(resp.Body).Decode(attrs); err != nil {
+		return nil, errors.Wrapf(err, "decode gcs object %s metadata", request.URL)
+	}
+	return attrs, nil
+}
+
+// doRequest gets object metadata, or object content when media is true
+func (gsc *gcsSourceClient) doRequest(request *source.Request, media bool) (*http.Response, error) {
+	client, err := gsc.getClient()
+	if err != nil {
+		return nil, errors.Wrap(err, "get gcs client")
+	}
+
+	bucket := request.URL.Host
+	object := strings.TrimPrefix(request.URL.Path, "/")
+	if bucket == "" || object == "" {
+		return nil, errors.Errorf("invalid gcs url %s", request.URL)
+	}
+	objectURL := fmt.Sprintf("%s/storage/v1/b/%s/o/%s", gsc.endpoint, url.PathEscape(bucket), url.PathEscape(object))
+	if media {
+		objectURL += "?alt=media"
+	}
+
+	req, err := http.NewRequestWithContext(request.Context(), http.MethodGet, objectURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if media {
+		for key, values := range request.Header {
+			for i := range values {
+				req.Header.Add(key, values[i])
+			}
+		}
+	}
+	return client.Do(req)
+}
+
+func (gsc *gcsSourceClient) getClient() (*http.Client, error) {
+	gsc.mu.Lock()
+	defer gsc.mu.Unlock()
+	if gsc.httpClient != nil {
+		return gsc.httpClient, nil
+	}
+
+	ctx := context.Background()
+	var (
+		credentials *google.Credentials
+		err         error
+	)
+	if gsc.credentialsFile != "" {
+		data, err := os.ReadFile(gsc.credentialsFile)
+		if err != nil {
+			return nil, errors.Wrapf(err, "read credentials file %s", gsc.credentialsFile)
+		}
+		credentials, err = google.CredentialsFromJSON(ctx, data, readOnlyScope)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parse credentials file %s", gsc.credentialsFile)
+		}
+	} else {
+		credentials, err = google.FindDefaultCredentials(ctx, readOnlyScope)
+		if err != nil {
+			return nil, errors.Wrap(err, "find application default credentials")
+		}
+	}
+
+	gsc.httpClient = oauth2.NewClient(ctx, credentials.TokenSource)
+	return gsc.httpClient, nil
+}
@@ -0,0 +1,225 @@
+/*
+ *     Copyright 2020 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gcsprotocol
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-http-utils/headers"
+	"github.com/stretchr/testify/assert"
+
+	"d7y.io/dragonfly/v2/pkg/source"
+)
+
+const (
+	testContent    = "hello dragonfly"
+	testGeneration = "1634000000000000"
+)
+
+var testUpdated = time.Date(2021, 10, 12, 0, 0, 0, 0, time.UTC)
+
+func newTestServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/storage/v1/b/bucket/o/dir%2Fobject" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		if r.URL.Query().Get("alt") != "media" {
+			fmt.Fprintf(w, `{"size":"%d","generation":"%s","updated":"%s"}`,
+				len(testContent), testGeneration, testUpdated.Format(time.RFC3339))
+			return
+		}
+
+		w.Header().Set(headers.LastModified, testUpdated.Format(http.TimeFormat))
+		w.Header().Set(generationHeader, testGeneration)
+		http.ServeContent(w, r, "", testUpdated, strings.NewReader(testContent))
+	}))
+}
+
+func newTestRequest(t *testing.T, rawURL string, header map[string]string) *source.Request {
+	request, err := source.NewRequestWithHeader(rawURL, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return adaptor(request)
+}
+
+func TestGCSSourceClient_GetContentLength(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+	client := newGCSSourceClient(WithEndpoint(server.URL), WithHTTPClient(server.Client()))
+
+	tests := []struct {
+		name   string
+		rawURL string
+		expect func(t *testing.T, length int64, err error)
+	}{
+		{
+			name:   "get content length",
+			rawURL: "gs://bucket/dir/object",
+			expect: func(t *testing.T, length int64, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Equal(length, int64(len(testContent)))
+			},
+		},
+		{
+			name:   "object does not exist",
+			rawURL: "gs://bucket/notfound",
+			expect: func(t *testing.T, length int64, err error) {
+				assert := assert.New(t)
+				assert.True(source.IsResourceNotReachableError(err))
+				assert.Equal(length, int64(source.UnknownSourceFileLen))
+			},
+		},
+		{
+			name:   "object is empty",
+			rawURL: "gs://bucket",
+			expect: func(t *testing.T, length int64, err error) {
+				assert := assert.New(t)
+				assert.Error(err)
+				assert.Equal(length, int64(source.UnknownSourceFileLen))
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			length, err := client.GetContentLength(newTestRequest(t, tc.rawURL, nil))
+			tc.expect(t, length, err)
+		})
+	}
+}
+
+func TestGCSSourceClient_IsExpired(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+	client := newGCSSourceClient(WithEndpoint(server.URL), WithHTTPClient(server.Client()))
+
+	tests := []struct {
+		name   string
+		info   *source.ExpireInfo
+		expect bool
+	}{
+		{
+			name:   "generation is not changed",
+			info:   &source.ExpireInfo{ETag: testGeneration},
+			expect: false,
+		},
+		{
+			name:   "updated time is not changed",
+			info:   &source.ExpireInfo{LastModified: testUpdated.Format(http.TimeFormat)},
+			expect: false,
+		},
+		{
+			name:   "object is changed",
+			info:   &source.ExpireInfo{ETag: "1", LastModified: time.Now().Format(http.TimeFormat)},
+			expect: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			expired, err := client.IsExpired(newTestRequest(t, "gs://bucket/dir/object", nil), tc.info)
+			assert.NoError(err)
+			assert.Equal(expired, tc.expect)
+		})
+	}
+}
+
+func TestGCSSourceClient_GetLastModified(t *testing.T) {
+	assert := assert.New(t)
+	server := newTestServer()
+	defer server.Close()
+	client := newGCSSourceClient(WithEndpoint(server.URL), WithHTTPClient(server.Client()))
+
+	lastModified, err := client.GetLastModified(newTestRequest(t, "gs://bucket/dir/object", nil))
+	assert.NoError(err)
+	assert.Equal(lastModified, testUpdated.UnixNano()/int64(time.Millisecond))
+}
+
+func TestGCSSourceClient_Download(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+	client := newGCSSourceClient(WithEndpoint(server.URL), WithHTTPClient(server.Client()))
+
+	tests := []struct {
+		name   string
+		rawURL string
+		header map[string]string
+		expect func(t *testing.T, resp *source.Response, err error)
+	}{
+		{
+			name:   "download object",
+			rawURL: "gs://bucket/dir/object",
+			expect: func(t *testing.T, resp *source.Response, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				defer resp.Body.Close()
+				data, err := io.ReadAll(resp.Body)
+				assert.NoError(err)
+				assert.Equal(string(data), testContent)
+				assert.Equal(resp.ExpireInfo().ETag, testGeneration)
+			},
+		},
+		{
+			name:   "download object with range",
+			rawURL: "gs://bucket/dir/object",
+			header: map[string]string{source.Range: "0-4"},
+			expect: func(t *testing.T, resp *source.Response, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				defer resp.Body.Close()
+				data, err := io.ReadAll(resp.Body)
+				assert.NoError(err)
+				assert.Equal(string(data), testContent[:5])
+			},
+		},
+		{
+			name:   "object does not exist",
+			rawURL: "gs://bucket/notfound",
+			expect: func(t *testing.T, resp *source.Response, err error) {
+				assert := assert.New(t)
+				assert.True(source.IsResourceNotReachableError(err))
+				assert.Nil(resp)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := client.Download(newTestRequest(t, tc.rawURL, tc.header))
+			tc.expect(t, resp, err)
+		})
+	}
+}
+
+func TestGCSSourceClient_CredentialsFile(t *testing.T) {
+	assert := assert.New(t)
+	client := newGCSSourceClient(WithCredentialsFile("/does/not/exist.json"))
+
+	_, err := client.GetContentLength(newTestRequest(t, "gs://bucket/dir/object", nil))
+	assert.Error(err)
+}
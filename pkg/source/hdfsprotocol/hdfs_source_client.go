@@ -17,9 +17,15 @@
 package hdfsprotocol
 
 import (
+	"context"
 	"io"
+	"math"
+	"net"
 	"net/url"
+	"os"
 	"os/user"
+	"path"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -34,6 +40,9 @@ import (
 
 const (
 	HDFSClient = "hdfs"
+
+	// defaultNameNodePort is the default rpc port of hdfs name node
+	defaultNameNodePort = "8020"
 )
 const (
 	// hdfsUseDataNodeHostName set hdfs client whether user hostname connect to datanode
@@ -48,8 +57,19 @@ func init() {
 	}
 }
 
+// adapter applies the default port to the name nodes without port and normalizes the url path
 func adapter(request *source.Request) *source.Request {
 	clonedRequest := request.Clone(request.Context())
+	if clonedRequest.URL.Host != "" {
+		addresses := strings.Split(clonedRequest.URL.Host, ",")
+		for i, address := range addresses {
+			if _, _, err := net.SplitHostPort(address); err != nil {
+				addresses[i] = net.JoinHostPort(strings.Trim(address, "[]"), defaultNameNodePort)
+			}
+		}
+		clonedRequest.URL.Host = strings.Join(addresses, ",")
+	}
+	source.NormalizePath(clonedRequest.URL)
 	return clonedRequest
 }
 
@@ -57,23 +77,62 @@ func adapter(request *source.Request) *source.Request {
 type hdfsSourceClient struct {
 	sync.RWMutex
 	clientMap map[string]*hdfs.Client
+
+	// listMaxDepth is the max depth of sub-directories recursed by List
+	listMaxDepth int
+
+	// listIncludeDir indicates whether List includes the sub-directories which are not recursed
+	listIncludeDir bool
 }
 
-// hdfsFileReaderClose is a combination object of the  io.LimitedReader and io.Closer
+// hdfsFileReaderClose is a combination object of the  io.LimitedReader and io.Closer,
+// reading stops with the context error and the file is closed when the context is done
 type hdfsFileReaderClose struct {
+	ctx           context.Context
 	limitedReader io.Reader
 	closer        io.Closer
+	closeOnce     sync.Once
+	closeErr      error
+	done          chan struct{}
 }
 
-func newHdfsFileReaderClose(r io.ReadCloser, n int64) io.ReadCloser {
-	return &hdfsFileReaderClose{
+func newHdfsFileReaderClose(ctx context.Context, r io.ReadCloser, n int64) io.ReadCloser {
+	rc := &hdfsFileReaderClose{
+		ctx:           ctx,
 		limitedReader: io.LimitReader(r, n),
 		closer:        r,
+		done:          make(chan struct{}),
 	}
+
+	if ctx.Done() != nil {
+		go func() {
+			select {
+			case <-ctx.Done():
+				rc.close()
+			case <-rc.done:
+			}
+		}()
+	}
+	return rc
 }
 
 type HDFSSourceClientOption func(p *hdfsSourceClient)
 
+// WithListMaxDepth sets the max depth of sub-directories recursed by List,
+// sub-directories are not recursed when depth is 0
+func WithListMaxDepth(depth int) HDFSSourceClientOption {
+	return func(p *hdfsSourceClient) {
+		p.listMaxDepth = depth
+	}
+}
+
+// WithListIncludeDir sets whether List includes the sub-directories which are not recursed
+func WithListIncludeDir(includeDir bool) HDFSSourceClientOption {
+	return func(p *hdfsSourceClient) {
+		p.listIncludeDir = includeDir
+	}
+}
+
 func (h *hdfsSourceClient) GetContentLength(request *source.Request) (int64, error) {
 	hdfsClient, path, err := h.getHDFSClientAndPath(request.URL)
 	if err != nil {
@@ -111,14 +170,27 @@ func (h *hdfsSourceClient) IsExpired(request *source.Request, info *source.Expir
 	return fileInfo.ModTime().Format(source.LastModifiedLayout) != info.LastModified, nil
 }
 
+// Check connects to the name node and stats the file
+func (h *hdfsSourceClient) Check(request *source.Request) error {
+	hdfsClient, path, err := h.getHDFSClientAndPath(request.URL)
+	if err != nil {
+		return err
+	}
+	_, err = hdfsClient.Stat(path)
+	return err
+}
+
 func (h *hdfsSourceClient) Download(request *source.Request) (*source.Response, error) {
+	log := request.Logger()
 	hdfsClient, path, err := h.getHDFSClientAndPath(request.URL)
 	if err != nil {
+		log.Warnf("download hdfs file %s error: %s", request.URL.Redacted(), err)
 		return nil, err
 	}
 
 	hdfsFile, err := hdfsClient.Open(path)
 	if err != nil {
+		log.Warnf("open hdfs file %s error: %s", path, err)
 		return nil, err
 	}
 
@@ -130,9 +202,11 @@ func (h *hdfsSourceClient) Download(request *source.Request) (*source.Response,
 		return nil, errors.Errorf("file length is illegal, length: %d", limitReadN)
 	}
 
-	if request.Header.Get(source.Range) != "" {
-		requestRange, err := rangeutils.ParseRange(request.Header.Get(source.Range), uint64(limitReadN))
+	if rangeStr := request.Header.Get(source.Range); rangeStr != "" {
+		requestRange, err := parseRange(rangeStr, limitReadN)
 		if err != nil {
+			hdfsFile.Close()
+			log.Warnf("parse range %s of hdfs file %s error: %s", rangeStr, path, err)
 			return nil, err
 		}
 		_, err = hdfsFile.Seek(int64(requestRange.StartIndex), 0)
@@ -143,14 +217,244 @@ func (h *hdfsSourceClient) Download(request *source.Request) (*source.Response,
 		limitReadN = int64(requestRange.Length())
 	}
 
+	log.Debugf("download hdfs file %s, length: %d", path, limitReadN)
+	response := source.NewResponse(
+		newHdfsFileReaderClose(request.Context(), hdfsFile, limitReadN),
+		source.WithExpireInfo(source.ExpireInfo{
+			LastModified: timeutils.Format(fileInfo.ModTime()),
+		}))
+	return response, nil
+}
+
+// parseRange parses rangeStr against the file size, both "start-end" and "bytes=start-end" forms are accepted.
+// A suffix range "-N" longer than the file selects the whole file, the end of other ranges is truncated to the
+// end of file, and a range starting at or beyond the end of file is not satisfiable.
+func parseRange(rangeStr string, size int64) (*rangeutils.Range, error) {
+	spec := strings.TrimPrefix(strings.TrimSpace(rangeStr), "bytes=")
+	if strings.HasPrefix(spec, "-") {
+		suffixLength, err := strconv.ParseInt(spec[1:], 10, 64)
+		if err != nil || suffixLength < 0 {
+			return nil, errors.Errorf("range %s is invalid", rangeStr)
+		}
+		if suffixLength == 0 || size == 0 {
+			return nil, errors.Errorf("range %s is not satisfiable, file size: %d", rangeStr, size)
+		}
+		if suffixLength > size {
+			suffixLength = size
+		}
+		return &rangeutils.Range{StartIndex: uint64(size - suffixLength), EndIndex: uint64(size - 1)}, nil
+	}
+
+	// parse with the max length to get the start and end as they are, then check them against the file size
+	rg, err := rangeutils.ParseRange(spec, math.MaxInt64)
+	if err != nil {
+		return nil, errors.Wrapf(err, "range %s is invalid", rangeStr)
+	}
+	if rg.StartIndex >= uint64(size) {
+		return nil, errors.Errorf("range %s is beyond the end of file, file size: %d", rangeStr, size)
+	}
+	if rg.EndIndex >= uint64(size) {
+		rg.EndIndex = uint64(size - 1)
+	}
+	return rg, nil
+}
+
+// DownloadRange opens the file for every range, seeks to the start and limits reading to the range length
+func (h *hdfsSourceClient) DownloadRange(request *source.Request, ranges []rangeutils.Range) ([]*source.Response, error) {
+	hdfsClient, path, err := h.getHDFSClientAndPath(request.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	var responses []*source.Response
+	closeResponses := func() {
+		for _, response := range responses {
+			response.Body.Close()
+		}
+	}
+	for _, rg := range ranges {
+		hdfsFile, err := hdfsClient.Open(path)
+		if err != nil {
+			closeResponses()
+			return nil, err
+		}
+
+		fileInfo := hdfsFile.Stat()
+		if rg.EndIndex >= uint64(fileInfo.Size()) {
+			hdfsFile.Close()
+			closeResponses()
+			return nil, errors.Errorf("range %s is out of file length %d", rg.String(), fileInfo.Size())
+		}
+
+		if _, err := hdfsFile.Seek(int64(rg.StartIndex), io.SeekStart); err != nil {
+			hdfsFile.Close()
+			closeResponses()
+			return nil, err
+		}
+
+		responses = append(responses, source.NewResponse(
+			newHdfsFileReaderClose(request.Context(), hdfsFile, int64(rg.Length())),
+			source.WithContentLength(int64(rg.Length())),
+			source.WithExpireInfo(source.ExpireInfo{
+				LastModified: timeutils.Format(fileInfo.ModTime()),
+			})))
+	}
+	return responses, nil
+}
+
+// DownloadMultiRange opens the file once and seeks to every range sequentially
+func (h *hdfsSourceClient) DownloadMultiRange(request *source.Request) (*source.Response, error) {
+	hdfsClient, path, err := h.getHDFSClientAndPath(request.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	hdfsFile, err := hdfsClient.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	fileInfo := hdfsFile.Stat()
+	var contentLength int64
+	for _, rg := range request.Ranges {
+		if rg.EndIndex >= uint64(fileInfo.Size()) {
+			hdfsFile.Close()
+			return nil, errors.Errorf("range %s is out of file length %d", rg.String(), fileInfo.Size())
+		}
+		contentLength += int64(rg.Length())
+	}
+
 	response := source.NewResponse(
-		newHdfsFileReaderClose(hdfsFile, limitReadN),
+		newHdfsFileReaderClose(request.Context(), &seekRangesReader{file: hdfsFile, ranges: request.Ranges}, contentLength),
+		source.WithContentLength(contentLength),
 		source.WithExpireInfo(source.ExpireInfo{
 			LastModified: timeutils.Format(fileInfo.ModTime()),
 		}))
 	return response, nil
 }
 
+// seekRangesReader reads the ranges of file in order, it seeks to the start of next range
+// when the current range is read
+type seekRangesReader struct {
+	file    io.ReadSeekCloser
+	ranges  []rangeutils.Range
+	current io.Reader
+}
+
+func (r *seekRangesReader) Read(p []byte) (int, error) {
+	for {
+		if r.current == nil {
+			if len(r.ranges) == 0 {
+				return 0, io.EOF
+			}
+			rg := r.ranges[0]
+			r.ranges = r.ranges[1:]
+			if _, err := r.file.Seek(int64(rg.StartIndex), io.SeekStart); err != nil {
+				return 0, err
+			}
+			r.current = io.LimitReader(r.file, int64(rg.Length()))
+		}
+
+		n, err := r.current.Read(p)
+		if err == io.EOF {
+			r.current = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
+}
+
+func (r *seekRangesReader) Close() error {
+	return r.file.Close()
+}
+
+// List lists the files in the directory of request url, the url itself is returned when it is a file
+func (h *hdfsSourceClient) List(request *source.Request) ([]*url.URL, error) {
+	infos, err := h.ListWithMetadata(request)
+	if err != nil {
+		return nil, err
+	}
+
+	var urls []*url.URL
+	for _, info := range infos {
+		urls = append(urls, info.URL)
+	}
+	return urls, nil
+}
+
+// ListWithMetadata lists the files like List, the size and modification time are taken from the listed file info
+func (h *hdfsSourceClient) ListWithMetadata(request *source.Request) ([]*source.ResourceInfo, error) {
+	hdfsClient, path, err := h.getHDFSClientAndPath(request.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := hdfsClient.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		return []*source.ResourceInfo{newResourceInfo(request.URL, info)}, nil
+	}
+
+	return h.listDir(request.Context(), hdfsClient, request.URL, 0)
+}
+
+func newResourceInfo(u *url.URL, info os.FileInfo) *source.ResourceInfo {
+	contentLength := info.Size()
+	if info.IsDir() {
+		contentLength = source.UnknownSourceFileLen
+	}
+	return &source.ResourceInfo{
+		URL:           u,
+		ContentLength: contentLength,
+		LastModified:  info.ModTime().UnixNano() / time.Millisecond.Nanoseconds(),
+	}
+}
+
+// listDir lists the files in directory and recurses sub-directories within max depth
+func (h *hdfsSourceClient) listDir(ctx context.Context, hdfsClient *hdfs.Client, dirURL *url.URL, depth int) ([]*source.ResourceInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	infos, err := hdfsClient.ReadDir(dirURL.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	var resourceInfos []*source.ResourceInfo
+	for _, info := range infos {
+		childURL := *dirURL
+		childURL.Path = path.Join(dirURL.Path, info.Name())
+		childURL.RawPath = ""
+
+		if !info.IsDir() {
+			resourceInfos = append(resourceInfos, newResourceInfo(&childURL, info))
+			continue
+		}
+
+		if depth < h.listMaxDepth {
+			childInfos, err := h.listDir(ctx, hdfsClient, &childURL, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			resourceInfos = append(resourceInfos, childInfos...)
+			continue
+		}
+
+		if h.listIncludeDir {
+			resourceInfos = append(resourceInfos, newResourceInfo(&childURL, info))
+		}
+	}
+
+	return resourceInfos, nil
+}
+
 func (h *hdfsSourceClient) GetLastModified(request *source.Request) (int64, error) {
 
 	hdfsClient, path, err := h.getHDFSClientAndPath(request.URL)
@@ -223,11 +527,33 @@ func newHDFSSourceClient(opts ...HDFSSourceClientOption) *hdfsSourceClient {
 }
 
 var _ source.ResourceClient = (*hdfsSourceClient)(nil)
+var _ source.RangeReader = (*hdfsSourceClient)(nil)
+var _ source.MultiRangeDownloader = (*hdfsSourceClient)(nil)
+var _ source.ResourceLister = (*hdfsSourceClient)(nil)
+var _ source.ResourceMetadataLister = (*hdfsSourceClient)(nil)
+var _ source.ResourceChecker = (*hdfsSourceClient)(nil)
 
 func (rc *hdfsFileReaderClose) Read(p []byte) (n int, err error) {
-	return rc.limitedReader.Read(p)
+	if err := rc.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	n, err = rc.limitedReader.Read(p)
+	if err != nil && rc.ctx.Err() != nil {
+		// file is closed by the done context
+		return n, rc.ctx.Err()
+	}
+	return n, err
 }
 
 func (rc *hdfsFileReaderClose) Close() error {
-	return rc.closer.Close()
+	return rc.close()
+}
+
+func (rc *hdfsFileReaderClose) close() error {
+	rc.closeOnce.Do(func() {
+		close(rc.done)
+		rc.closeErr = rc.closer.Close()
+	})
+	return rc.closeErr
 }
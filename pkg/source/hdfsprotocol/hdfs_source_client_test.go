@@ -20,9 +20,11 @@
 package hdfsprotocol
 
 import (
+	"context"
 	"io"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -32,6 +34,7 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"d7y.io/dragonfly/v2/pkg/source"
+	"d7y.io/dragonfly/v2/pkg/source/sourcetest"
 	"d7y.io/dragonfly/v2/pkg/util/rangeutils"
 	"d7y.io/dragonfly/v2/pkg/util/timeutils"
 )
@@ -113,6 +116,37 @@ func TestGetContentLength_Fail(t *testing.T) {
 	assert.EqualError(t, err, "stat /user/root/input/f3.txt: file does not exist")
 }
 
+// TestCheck_FileExist test file exist, return nil
+func TestCheck_FileExist(t *testing.T) {
+	var info os.FileInfo = fakeHDFSFileInfo{
+		contents: hdfsExistFileContent,
+	}
+	stubRet := []gomonkey.OutputCell{
+		{Values: gomonkey.Params{info, nil}},
+	}
+
+	patch := gomonkey.ApplyMethodSeq(reflect.TypeOf(fakeHDFSClient), "Stat", stubRet)
+
+	defer patch.Reset()
+	request, err := source.NewRequest(hdfsExistFileURL)
+	assert.Nil(t, err)
+	assert.Nil(t, sourceClient.(source.ResourceChecker).Check(request))
+}
+
+// TestCheck_FileNotExist test file not exist, return error
+func TestCheck_FileNotExist(t *testing.T) {
+	stubRet := []gomonkey.OutputCell{
+		{Values: gomonkey.Params{nil, errors.New("stat /user/root/input/f3.txt: file does not exist")}},
+	}
+
+	patch := gomonkey.ApplyMethodSeq(reflect.TypeOf(fakeHDFSClient), "Stat", stubRet)
+
+	defer patch.Reset()
+	request, err := source.NewRequest(hdfsNotExistFileURL)
+	assert.Nil(t, err)
+	assert.EqualError(t, sourceClient.(source.ResourceChecker).Check(request), "stat /user/root/input/f3.txt: file does not exist")
+}
+
 // TestIsSupportRange_FileExist test file exist, return file  support range
 func TestIsSupportRange_FileExist(t *testing.T) {
 	var info os.FileInfo = fakeHDFSFileInfo{
@@ -235,6 +269,269 @@ func Test_Download_FileExist_ByRange(t *testing.T) {
 	assert.Equal(t, hdfsExistFileContent, string(data))
 }
 
+func Test_Download_FileExist_ByRangeForms(t *testing.T) {
+	var (
+		reader  *hdfs.FileReader = &hdfs.FileReader{}
+		content                  = strings.Repeat("0123456789", 100)
+		offset  int64
+		closed  bool
+	)
+	patch := gomonkey.ApplyMethod(reflect.TypeOf(fakeHDFSClient), "Open", func(*hdfs.Client, string) (*hdfs.FileReader, error) {
+		offset, closed = 0, false
+		return reader, nil
+	})
+	patch.ApplyMethod(reflect.TypeOf(reader), "Seek", func(_ *hdfs.FileReader, off int64, whence int) (int64, error) {
+		offset = off
+		return offset, nil
+	})
+	patch.ApplyMethod(reflect.TypeOf(reader), "Read", func(_ *hdfs.FileReader, b []byte) (int, error) {
+		if offset >= int64(len(content)) {
+			return 0, io.EOF
+		}
+		n := copy(b, content[offset:])
+		offset += int64(n)
+		return n, nil
+	})
+	patch.ApplyMethod(reflect.TypeOf(reader), "Stat", func(_ *hdfs.FileReader) os.FileInfo {
+		return fakeHDFSFileInfo{contents: content}
+	})
+	patch.ApplyMethod(reflect.TypeOf(reader), "Close", func(_ *hdfs.FileReader) error {
+		closed = true
+		return nil
+	})
+	defer patch.Reset()
+
+	tests := []struct {
+		rangeStr string
+		expect   func(t *testing.T, data string, err error)
+	}{
+		{
+			rangeStr: "bytes=-500",
+			expect: func(t *testing.T, data string, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, content[500:], data)
+			},
+		},
+		{
+			rangeStr: "bytes=100-",
+			expect: func(t *testing.T, data string, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, content[100:], data)
+			},
+		},
+		{
+			rangeStr: "bytes=100-199",
+			expect: func(t *testing.T, data string, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, content[100:200], data)
+			},
+		},
+		{
+			rangeStr: "-2000",
+			expect: func(t *testing.T, data string, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, content, data)
+			},
+		},
+		{
+			rangeStr: "900-1999",
+			expect: func(t *testing.T, data string, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, content[900:], data)
+			},
+		},
+		{
+			rangeStr: "bytes=1000-",
+			expect: func(t *testing.T, data string, err error) {
+				assert.EqualError(t, err, "range bytes=1000- is beyond the end of file, file size: 1000")
+				assert.True(t, closed)
+			},
+		},
+		{
+			rangeStr: "bytes=2000-2999",
+			expect: func(t *testing.T, data string, err error) {
+				assert.EqualError(t, err, "range bytes=2000-2999 is beyond the end of file, file size: 1000")
+				assert.True(t, closed)
+			},
+		},
+		{
+			rangeStr: "bytes=-0",
+			expect: func(t *testing.T, data string, err error) {
+				assert.EqualError(t, err, "range bytes=-0 is not satisfiable, file size: 1000")
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.rangeStr, func(t *testing.T) {
+			request, err := source.NewRequestWithHeader(hdfsExistFileURL, map[string]string{
+				source.Range: tc.rangeStr,
+			})
+			assert.Nil(t, err)
+
+			response, err := sourceClient.Download(request)
+			if err != nil {
+				tc.expect(t, "", err)
+				return
+			}
+			data, err := io.ReadAll(response.Body)
+			response.Body.Close()
+			tc.expect(t, string(data), err)
+		})
+	}
+}
+
+func TestDownload_ContextCanceled(t *testing.T) {
+	var (
+		reader    *hdfs.FileReader = &hdfs.FileReader{}
+		readCount int
+		closed    = make(chan struct{})
+	)
+	patch := gomonkey.ApplyMethod(reflect.TypeOf(fakeHDFSClient), "Open", func(*hdfs.Client, string) (*hdfs.FileReader, error) {
+		return reader, nil
+	})
+	patch.ApplyMethod(reflect.TypeOf(reader), "Read", func(_ *hdfs.FileReader, b []byte) (int, error) {
+		readCount++
+		return copy(b, hdfsExistFileContent), nil
+	})
+	patch.ApplyMethod(reflect.TypeOf(reader), "Close", func(_ *hdfs.FileReader) error {
+		close(closed)
+		return nil
+	})
+	patch.ApplyMethodSeq(reflect.TypeOf(reader), "Stat", []gomonkey.OutputCell{
+		{
+			Values: gomonkey.Params{
+				fakeHDFSFileInfo{
+					contents: hdfsExistFileContent,
+				},
+			},
+		},
+	})
+	defer patch.Reset()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	request, err := source.NewRequestWithContext(ctx, hdfsExistFileURL, nil)
+	assert.Nil(t, err)
+
+	response, err := sourceClient.Download(request)
+	assert.Nil(t, err)
+
+	cancel()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("hdfs file is not closed after context canceled")
+	}
+
+	_, err = response.Body.Read(make([]byte, hdfsExistFileContentLength))
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 0, readCount)
+	assert.Nil(t, response.Body.Close())
+}
+
+func TestDownloadRange_FileExist(t *testing.T) {
+	// every opened file has its own offset
+	var (
+		reader  *hdfs.FileReader
+		offsets = map[*hdfs.FileReader]int64{}
+	)
+	patch := gomonkey.ApplyMethod(reflect.TypeOf(fakeHDFSClient), "Open", func(*hdfs.Client, string) (*hdfs.FileReader, error) {
+		return &hdfs.FileReader{}, nil
+	})
+	patch.ApplyMethod(reflect.TypeOf(reader), "Seek", func(r *hdfs.FileReader, offset int64, whence int) (int64, error) {
+		offsets[r] = offset
+		return offset, nil
+	})
+	patch.ApplyMethod(reflect.TypeOf(reader), "Read", func(r *hdfs.FileReader, b []byte) (int, error) {
+		n := copy(b, hdfsExistFileContent[offsets[r]:])
+		offsets[r] += int64(n)
+		return n, nil
+	})
+	patch.ApplyMethod(reflect.TypeOf(reader), "Close", func(_ *hdfs.FileReader) error {
+		return nil
+	})
+	patch.ApplyMethod(reflect.TypeOf(reader), "Stat", func(_ *hdfs.FileReader) os.FileInfo {
+		return fakeHDFSFileInfo{
+			contents: hdfsExistFileContent,
+		}
+	})
+	defer patch.Reset()
+
+	request, err := source.NewRequest(hdfsExistFileURL)
+	assert.Nil(t, err)
+
+	responses, err := sourceClient.(source.RangeReader).DownloadRange(request, []rangeutils.Range{
+		{StartIndex: 0, EndIndex: 4},
+		{StartIndex: 6, EndIndex: 10},
+	})
+	assert.Nil(t, err)
+	assert.Len(t, responses, 2)
+	for i, content := range []string{"Hello", "World"} {
+		data, err := io.ReadAll(responses[i].Body)
+		assert.Nil(t, err)
+		assert.Equal(t, content, string(data))
+		assert.Nil(t, responses[i].Body.Close())
+	}
+
+	responses, err = sourceClient.(source.RangeReader).DownloadRange(request, []rangeutils.Range{
+		{StartIndex: 0, EndIndex: uint64(hdfsExistFileContentLength)},
+	})
+	assert.NotNil(t, err)
+	assert.Nil(t, responses)
+}
+
+func TestDownloadMultiRange_FileExist(t *testing.T) {
+	var (
+		reader *hdfs.FileReader
+		offset int64
+		opened int
+	)
+	patch := gomonkey.ApplyMethod(reflect.TypeOf(fakeHDFSClient), "Open", func(*hdfs.Client, string) (*hdfs.FileReader, error) {
+		opened++
+		return &hdfs.FileReader{}, nil
+	})
+	patch.ApplyMethod(reflect.TypeOf(reader), "Seek", func(_ *hdfs.FileReader, o int64, whence int) (int64, error) {
+		offset = o
+		return offset, nil
+	})
+	patch.ApplyMethod(reflect.TypeOf(reader), "Read", func(_ *hdfs.FileReader, b []byte) (int, error) {
+		n := copy(b, hdfsExistFileContent[offset:])
+		offset += int64(n)
+		return n, nil
+	})
+	patch.ApplyMethod(reflect.TypeOf(reader), "Close", func(_ *hdfs.FileReader) error {
+		return nil
+	})
+	patch.ApplyMethod(reflect.TypeOf(reader), "Stat", func(_ *hdfs.FileReader) os.FileInfo {
+		return fakeHDFSFileInfo{
+			contents: hdfsExistFileContent,
+		}
+	})
+	defer patch.Reset()
+
+	request, err := source.NewRequest(hdfsExistFileURL)
+	assert.Nil(t, err)
+	request.Ranges = []rangeutils.Range{
+		{StartIndex: 0, EndIndex: 1},
+		{StartIndex: 4, EndIndex: 6},
+		{StartIndex: 9, EndIndex: 10},
+	}
+
+	response, err := sourceClient.(source.MultiRangeDownloader).DownloadMultiRange(request)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(7), response.ContentLength)
+	data, err := io.ReadAll(response.Body)
+	assert.Nil(t, err)
+	assert.Equal(t, "Heo Wld", string(data))
+	assert.Nil(t, response.Body.Close())
+	assert.Equal(t, 1, opened)
+
+	request.Ranges = []rangeutils.Range{{StartIndex: 0, EndIndex: uint64(hdfsExistFileContentLength)}}
+	response, err = sourceClient.(source.MultiRangeDownloader).DownloadMultiRange(request)
+	assert.NotNil(t, err)
+	assert.Nil(t, response)
+}
+
 func TestDownload_FileNotExist(t *testing.T) {
 	stubRet := []gomonkey.OutputCell{
 		{Values: gomonkey.Params{nil, errors.New("open /user/root/input/f3.txt: file does not exist")}},
@@ -346,6 +643,163 @@ func TestGetLastModified_FileNotExist(t *testing.T) {
 	assert.Equal(t, hdfsNotExistLastModified, lastModifiedMillis)
 }
 
+func TestList(t *testing.T) {
+	dirs := map[string][]os.FileInfo{
+		"/user/root/input": {
+			fakeHDFSFileInfo{basename: "f1.txt"},
+			fakeHDFSFileInfo{basename: "sub", dir: true},
+		},
+		"/user/root/input/sub": {
+			fakeHDFSFileInfo{basename: "f2.txt"},
+			fakeHDFSFileInfo{basename: "deep", dir: true},
+		},
+		"/user/root/input/sub/deep": {
+			fakeHDFSFileInfo{basename: "f3.txt"},
+		},
+	}
+	patch := gomonkey.ApplyMethod(reflect.TypeOf(fakeHDFSClient), "Stat", func(_ *hdfs.Client, path string) (os.FileInfo, error) {
+		if _, ok := dirs[path]; ok {
+			return fakeHDFSFileInfo{dir: true}, nil
+		}
+		return fakeHDFSFileInfo{}, nil
+	})
+	patch.ApplyMethod(reflect.TypeOf(fakeHDFSClient), "ReadDir", func(_ *hdfs.Client, dirname string) ([]os.FileInfo, error) {
+		infos, ok := dirs[dirname]
+		if !ok {
+			return nil, errors.Errorf("open %s: file does not exist", dirname)
+		}
+		return infos, nil
+	})
+	defer patch.Reset()
+
+	tests := []struct {
+		name    string
+		options []HDFSSourceClientOption
+		rawURL  string
+		ctx     func() context.Context
+		expect  func(t *testing.T, urls []string, err error)
+	}{
+		{
+			name:   "list directory",
+			rawURL: "hdfs://" + hdfsExistFileHost + "/user/root/input",
+			expect: func(t *testing.T, urls []string, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, []string{"hdfs://" + hdfsExistFileHost + "/user/root/input/f1.txt"}, urls)
+			},
+		},
+		{
+			name:    "list directory with sub-directories",
+			options: []HDFSSourceClientOption{WithListIncludeDir(true)},
+			rawURL:  "hdfs://" + hdfsExistFileHost + "/user/root/input",
+			expect: func(t *testing.T, urls []string, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, []string{
+					"hdfs://" + hdfsExistFileHost + "/user/root/input/f1.txt",
+					"hdfs://" + hdfsExistFileHost + "/user/root/input/sub",
+				}, urls)
+			},
+		},
+		{
+			name:    "list directory recursively within depth",
+			options: []HDFSSourceClientOption{WithListMaxDepth(1)},
+			rawURL:  "hdfs://" + hdfsExistFileHost + "/user/root/input",
+			expect: func(t *testing.T, urls []string, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, []string{
+					"hdfs://" + hdfsExistFileHost + "/user/root/input/f1.txt",
+					"hdfs://" + hdfsExistFileHost + "/user/root/input/sub/f2.txt",
+				}, urls)
+			},
+		},
+		{
+			name:    "list directory recursively",
+			options: []HDFSSourceClientOption{WithListMaxDepth(10)},
+			rawURL:  "hdfs://" + hdfsExistFileHost + "/user/root/input",
+			expect: func(t *testing.T, urls []string, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, []string{
+					"hdfs://" + hdfsExistFileHost + "/user/root/input/f1.txt",
+					"hdfs://" + hdfsExistFileHost + "/user/root/input/sub/f2.txt",
+					"hdfs://" + hdfsExistFileHost + "/user/root/input/sub/deep/f3.txt",
+				}, urls)
+			},
+		},
+		{
+			name:   "list file",
+			rawURL: hdfsExistFileURL,
+			expect: func(t *testing.T, urls []string, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, []string{hdfsExistFileURL}, urls)
+			},
+		},
+		{
+			name:   "context is canceled",
+			rawURL: "hdfs://" + hdfsExistFileHost + "/user/root/input",
+			ctx: func() context.Context {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				return ctx
+			},
+			expect: func(t *testing.T, urls []string, err error) {
+				assert.ErrorIs(t, err, context.Canceled)
+				assert.Nil(t, urls)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newHDFSSourceClient(append(tc.options, func(p *hdfsSourceClient) {
+				p.clientMap[hdfsExistFileHost] = fakeHDFSClient
+			})...)
+
+			ctx := context.Background()
+			if tc.ctx != nil {
+				ctx = tc.ctx()
+			}
+			request, err := source.NewRequestWithContext(ctx, tc.rawURL, nil)
+			assert.Nil(t, err)
+
+			urls, err := client.List(request)
+			var rawURLs []string
+			for _, u := range urls {
+				rawURLs = append(rawURLs, u.String())
+			}
+			tc.expect(t, rawURLs, err)
+		})
+	}
+}
+
+func TestListWithMetadata(t *testing.T) {
+	modTime := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	patch := gomonkey.ApplyMethod(reflect.TypeOf(fakeHDFSClient), "Stat", func(_ *hdfs.Client, path string) (os.FileInfo, error) {
+		return fakeHDFSFileInfo{dir: true}, nil
+	})
+	patch.ApplyMethod(reflect.TypeOf(fakeHDFSClient), "ReadDir", func(_ *hdfs.Client, dirname string) ([]os.FileInfo, error) {
+		return []os.FileInfo{
+			fakeHDFSFileInfo{basename: "f1.txt", contents: hdfsExistFileContent, modtime: modTime},
+			fakeHDFSFileInfo{basename: "sub", dir: true, modtime: modTime},
+		}, nil
+	})
+	defer patch.Reset()
+
+	client := newHDFSSourceClient(WithListIncludeDir(true), func(p *hdfsSourceClient) {
+		p.clientMap[hdfsExistFileHost] = fakeHDFSClient
+	})
+	request, err := source.NewRequest("hdfs://" + hdfsExistFileHost + "/user/root/input")
+	assert.Nil(t, err)
+
+	// the metadata is taken from ReadDir without stat of every file
+	infos, err := client.ListWithMetadata(request)
+	assert.Nil(t, err)
+	assert.Len(t, infos, 2)
+	assert.Equal(t, "hdfs://"+hdfsExistFileHost+"/user/root/input/f1.txt", infos[0].URL.String())
+	assert.Equal(t, int64(len(hdfsExistFileContent)), infos[0].ContentLength)
+	assert.Equal(t, modTime.UnixNano()/int64(time.Millisecond), infos[0].LastModified)
+	assert.Equal(t, "hdfs://"+hdfsExistFileHost+"/user/root/input/sub", infos[1].URL.String())
+	assert.Equal(t, int64(source.UnknownSourceFileLen), infos[1].ContentLength)
+}
+
 func TestNewHDFSSourceClient(t *testing.T) {
 	client := newHDFSSourceClient()
 	assert.NotNil(t, client)
@@ -382,3 +836,39 @@ func (f fakeHDFSFileInfo) Mode() os.FileMode {
 	}
 	return 0644
 }
+
+func TestAdapter(t *testing.T) {
+	tests := []struct {
+		rawURL string
+		expect string
+	}{
+		{rawURL: "hdfs://127.0.0.1:9000/user/root/f1.txt", expect: "hdfs://127.0.0.1:9000/user/root/f1.txt"},
+		{rawURL: "hdfs://127.0.0.1/user/root/f1.txt", expect: "hdfs://127.0.0.1:8020/user/root/f1.txt"},
+		{rawURL: "hdfs://[::1]//user/root/./f1.txt", expect: "hdfs://[::1]:8020/user/root/f1.txt"},
+		{rawURL: "hdfs://namenode1,namenode2:9000/user/root/dir/", expect: "hdfs://namenode1:8020,namenode2:9000/user/root/dir/"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.rawURL, func(t *testing.T) {
+			request, err := source.NewRequest(tc.rawURL)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expect, adapter(request).URL.String())
+			assert.Equal(t, tc.rawURL, request.URL.String())
+		})
+	}
+}
+
+func TestAdapter_Register(t *testing.T) {
+	client := sourcetest.NewFakeSourceClient(sourcetest.WithObject("hdfs://127.0.0.1:8020/user/root/f1.txt", []byte(hdfsExistFileContent)))
+	manager := source.NewManager()
+	assert.Nil(t, manager.Register(HDFSClient, client, adapter))
+	rc, ok := manager.GetClient(HDFSClient)
+	assert.True(t, ok)
+
+	// the normalized url reaches the underlying client
+	request, err := source.NewRequest("hdfs://127.0.0.1//user/root/f1.txt")
+	assert.Nil(t, err)
+	length, err := rc.GetContentLength(request)
+	assert.Nil(t, err)
+	assert.Equal(t, hdfsExistFileContentLength, length)
+}
@@ -0,0 +1,190 @@
+/*
+ *     Copyright 2022 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package httpprotocol
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultHostTransportTTL is the default time a host transport without requests is kept
+	defaultHostTransportTTL = 5 * time.Minute
+)
+
+// hostTransportOptions is the options of partitioning connection pools by host
+type hostTransportOptions struct {
+	// maxIdleConns is the max idle connections of every host, the setting of base transport is kept when it is not positive
+	maxIdleConns int
+	// ttl is the time a host transport without requests is kept before it is removed
+	ttl time.Duration
+}
+
+// hostTransport is a http.RoundTripper which sends requests of every host:port:tls
+// with a separate transport, so that a slow origin can not starve the connections of others
+type hostTransport struct {
+	base    *http.Transport
+	options hostTransportOptions
+	now     func() time.Time
+
+	mu         sync.Mutex
+	transports map[string]*hostTransportEntry
+	lastSweep  time.Time
+}
+
+type hostTransportEntry struct {
+	transport *http.Transport
+	// refs is the count of requests whose body is not closed yet
+	refs     int
+	lastUsed time.Time
+}
+
+var _ http.RoundTripper = (*hostTransport)(nil)
+
+func newHostTransport(base *http.Transport, options hostTransportOptions) *hostTransport {
+	if options.ttl <= 0 {
+		options.ttl = defaultHostTransportTTL
+	}
+	return &hostTransport{
+		base:       base,
+		options:    options,
+		now:        time.Now,
+		transports: map[string]*hostTransportEntry{},
+	}
+}
+
+// partitionHTTPClient returns a copy of httpClient which partitions connection pools by host,
+// httpClient is returned directly when its transport is not a *http.Transport
+func partitionHTTPClient(httpClient *http.Client, options hostTransportOptions) *http.Client {
+	var base *http.Transport
+	switch t := httpClient.Transport.(type) {
+	case nil:
+		base = http.DefaultTransport.(*http.Transport).Clone()
+	case *http.Transport:
+		base = t
+	default:
+		return httpClient
+	}
+
+	partitioned := *httpClient
+	partitioned.Transport = newHostTransport(base, options)
+	return &partitioned
+}
+
+// hostKey returns host:port:tls of the request url, the port is completed with the default port of scheme
+func hostKey(req *http.Request) string {
+	isTLS := strings.EqualFold(req.URL.Scheme, "https")
+	port := req.URL.Port()
+	if port == "" {
+		port = "80"
+		if isTLS {
+			port = "443"
+		}
+	}
+	key := net.JoinHostPort(strings.ToLower(req.URL.Hostname()), port)
+	if isTLS {
+		return key + ":tls"
+	}
+	return key
+}
+
+func (t *hostTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	key := hostKey(req)
+	transport := t.acquire(key)
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		t.release(key)
+		return nil, err
+	}
+	resp.Body = &hostTransportBody{ReadCloser: resp.Body, release: func() { t.release(key) }}
+	return resp, nil
+}
+
+// CloseIdleConnections closes the idle connections of all hosts, it's called by http.Client.CloseIdleConnections
+func (t *hostTransport) CloseIdleConnections() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, entry := range t.transports {
+		entry.transport.CloseIdleConnections()
+	}
+}
+
+func (t *hostTransport) acquire(key string) *http.Transport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	t.sweep(now)
+	entry, ok := t.transports[key]
+	if !ok {
+		transport := t.base.Clone()
+		if t.options.maxIdleConns > 0 {
+			transport.MaxIdleConns = t.options.maxIdleConns
+			transport.MaxIdleConnsPerHost = t.options.maxIdleConns
+		}
+		entry = &hostTransportEntry{transport: transport}
+		t.transports[key] = entry
+	}
+	entry.refs++
+	entry.lastUsed = now
+	return entry.transport
+}
+
+func (t *hostTransport) release(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	entry, ok := t.transports[key]
+	if !ok {
+		return
+	}
+	entry.refs--
+	entry.lastUsed = t.now()
+}
+
+// sweep removes the host transports without requests in ttl, it runs at most once per ttl
+func (t *hostTransport) sweep(now time.Time) {
+	if now.Sub(t.lastSweep) < t.options.ttl {
+		return
+	}
+	t.lastSweep = now
+
+	for key, entry := range t.transports {
+		if entry.refs > 0 || now.Sub(entry.lastUsed) < t.options.ttl {
+			continue
+		}
+		entry.transport.CloseIdleConnections()
+		delete(t.transports, key)
+	}
+}
+
+// hostTransportBody releases the host transport when the response body is closed
+type hostTransportBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *hostTransportBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
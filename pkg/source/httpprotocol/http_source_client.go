@@ -17,16 +17,25 @@
 package httpprotocol
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
 	"net"
 	"net/http"
 	"net/url"
 	"os"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-http-utils/headers"
+	"github.com/pkg/errors"
 
 	"d7y.io/dragonfly/v2/pkg/source"
+	"d7y.io/dragonfly/v2/pkg/util/rangeutils"
 	"d7y.io/dragonfly/v2/pkg/util/timeutils"
 )
 
@@ -35,10 +44,16 @@ const (
 	HTTPSClient = "https"
 
 	ProxyEnv = "D7Y_SOURCE_PROXY"
+
+	// maxDrainBodySize is the max bytes to drain before closing body, so the connection can be reused
+	maxDrainBodySize = 4 * 1024
 )
 
 var _defaultHTTPClient *http.Client
 var _ source.ResourceClient = (*httpSourceClient)(nil)
+var _ source.RangeReader = (*httpSourceClient)(nil)
+var _ source.MultiRangeDownloader = (*httpSourceClient)(nil)
+var _ source.ResourceChecker = (*httpSourceClient)(nil)
 
 func init() {
 	// TODO support customize source client
@@ -73,11 +88,12 @@ func init() {
 		panic(err)
 	}
 
-	if err := source.Register(HTTPSClient, sc, Adapter); err != nil {
+	if err := source.Alias(HTTPSClient, HTTPClient); err != nil {
 		panic(err)
 	}
 }
 
+// Adapter converts the source headers to http headers, the url path is kept as it is
 func Adapter(request *source.Request) *source.Request {
 	clonedRequest := request.Clone(request.Context())
 	if request.Header.Get(source.Range) != "" {
@@ -95,9 +111,33 @@ func Adapter(request *source.Request) *source.Request {
 	return clonedRequest
 }
 
+// NormalizingAdapter is Adapter which also normalizes the url path, it's opt-in by registering
+// the http client with it, as the duplicate slashes are significant for s3 keys and presigned urls
+func NormalizingAdapter(request *source.Request) *source.Request {
+	clonedRequest := Adapter(request)
+	source.NormalizePath(clonedRequest.URL)
+	return clonedRequest
+}
+
+// CredentialStore provides the credentials of source hosts
+type CredentialStore interface {
+	// Lookup returns the authorization scheme like Basic or Bearer and the token of host,
+	// host may contain port, ok is false when there is no credential for host
+	Lookup(host string) (scheme, token string, ok bool)
+}
+
 // httpSourceClient is an implementation of the interface of source.ResourceClient.
 type httpSourceClient struct {
-	httpClient *http.Client
+	httpClient      *http.Client
+	credentialStore CredentialStore
+	// transportOptions tunes the transport of httpClient
+	transportOptions []func(*http.Transport)
+	// hostTransportOptions partitions the connection pools of httpClient by host when it is set
+	hostTransportOptions *hostTransportOptions
+	// recorderOptions records requests and responses of httpClient when it is set
+	recorderOptions *recorderOptions
+	// optionErr is returned by all requests when an option is invalid
+	optionErr error
 }
 
 // NewHTTPSourceClient returns a new HTTPSourceClientOption.
@@ -112,9 +152,39 @@ func newHTTPSourceClient(opts ...HTTPSourceClientOption) *httpSourceClient {
 	for i := range opts {
 		opts[i](client)
 	}
+	if len(client.transportOptions) > 0 {
+		client.httpClient = tuneHTTPClient(client.httpClient, client.transportOptions)
+	}
+	if client.hostTransportOptions != nil {
+		client.httpClient = partitionHTTPClient(client.httpClient, *client.hostTransportOptions)
+	}
+	if client.recorderOptions != nil && client.recorderOptions.dir != "" {
+		client.httpClient = recordHTTPClient(client.httpClient, *client.recorderOptions)
+	}
 	return client
 }
 
+// tuneHTTPClient returns a copy of httpClient with the tuned transport,
+// httpClient is returned directly when its transport is not a *http.Transport
+func tuneHTTPClient(httpClient *http.Client, transportOptions []func(*http.Transport)) *http.Client {
+	var transport *http.Transport
+	switch t := httpClient.Transport.(type) {
+	case nil:
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	case *http.Transport:
+		transport = t.Clone()
+	default:
+		return httpClient
+	}
+
+	for _, opt := range transportOptions {
+		opt(transport)
+	}
+	tuned := *httpClient
+	tuned.Transport = transport
+	return &tuned
+}
+
 type HTTPSourceClientOption func(p *httpSourceClient)
 
 func WithHTTPClient(client *http.Client) HTTPSourceClientOption {
@@ -123,13 +193,185 @@ func WithHTTPClient(client *http.Client) HTTPSourceClientOption {
 	}
 }
 
+// WithMaxIdleConnsPerHost sets the max idle connections to keep per host
+func WithMaxIdleConnsPerHost(n int) HTTPSourceClientOption {
+	return func(sourceClient *httpSourceClient) {
+		sourceClient.transportOptions = append(sourceClient.transportOptions, func(transport *http.Transport) {
+			transport.MaxIdleConnsPerHost = n
+		})
+	}
+}
+
+// WithIdleConnTimeout sets the max time an idle connection will remain idle before closing itself
+func WithIdleConnTimeout(timeout time.Duration) HTTPSourceClientOption {
+	return func(sourceClient *httpSourceClient) {
+		sourceClient.transportOptions = append(sourceClient.transportOptions, func(transport *http.Transport) {
+			transport.IdleConnTimeout = timeout
+		})
+	}
+}
+
+// WithDisableKeepAlives disables reusing connections, a connection is used for a single request
+func WithDisableKeepAlives(disable bool) HTTPSourceClientOption {
+	return func(sourceClient *httpSourceClient) {
+		sourceClient.transportOptions = append(sourceClient.transportOptions, func(transport *http.Transport) {
+			transport.DisableKeepAlives = disable
+		})
+	}
+}
+
+// WithHostTransports sends requests of every host:port:tls with a separate connection pool
+// keeping at most maxIdleConns idle connections, so that a slow origin can not starve others.
+// The pool of a host is closed after it has no requests in ttl, ttl defaults to 5 minutes when it is not positive
+func WithHostTransports(maxIdleConns int, ttl time.Duration) HTTPSourceClientOption {
+	return func(sourceClient *httpSourceClient) {
+		sourceClient.hostTransportOptions = &hostTransportOptions{
+			maxIdleConns: maxIdleConns,
+			ttl:          ttl,
+		}
+	}
+}
+
+// WithRecorder writes the request line and headers and the response headers of every request
+// to a timestamped file under dir for debugging, the values of Authorization headers are redacted
+func WithRecorder(dir string) HTTPSourceClientOption {
+	return func(sourceClient *httpSourceClient) {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			sourceClient.optionErr = errors.Wrapf(err, "create recorder directory %s", dir)
+			return
+		}
+		if sourceClient.recorderOptions == nil {
+			sourceClient.recorderOptions = &recorderOptions{}
+		}
+		sourceClient.recorderOptions.dir = dir
+	}
+}
+
+// WithRecordBody records at most maxBodySize bytes of response body with WithRecorder,
+// the body is teed when it is read by the caller, it takes no effect without WithRecorder
+func WithRecordBody(maxBodySize int64) HTTPSourceClientOption {
+	return func(sourceClient *httpSourceClient) {
+		if sourceClient.recorderOptions == nil {
+			sourceClient.recorderOptions = &recorderOptions{}
+		}
+		sourceClient.recorderOptions.maxBodySize = maxBodySize
+	}
+}
+
+// WithProxy sends requests through the proxy, the proxy of the transport is used if it is not set,
+// like http.ProxyFromEnvironment which honors HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+func WithProxy(proxyURL string) HTTPSourceClientOption {
+	return func(sourceClient *httpSourceClient) {
+		proxy, err := url.Parse(proxyURL)
+		sourceClient.transportOptions = append(sourceClient.transportOptions, func(transport *http.Transport) {
+			if err != nil {
+				// fail the requests rather than bypass the proxy
+				transport.Proxy = func(*http.Request) (*url.URL, error) {
+					return nil, errors.Wrapf(err, "parse proxy %s", proxyURL)
+				}
+				return
+			}
+			transport.Proxy = http.ProxyURL(proxy)
+		})
+	}
+}
+
+// WithClientCert presents the client certificate for mutual tls,
+// the certificate is reloaded when the files are changed
+func WithClientCert(certFile, keyFile string) HTTPSourceClientOption {
+	return func(sourceClient *httpSourceClient) {
+		reloader := &certReloader{certFile: certFile, keyFile: keyFile}
+		if _, err := reloader.GetClientCertificate(nil); err != nil {
+			sourceClient.optionErr = err
+			return
+		}
+		sourceClient.transportOptions = append(sourceClient.transportOptions, func(transport *http.Transport) {
+			transport.TLSClientConfig = tlsClientConfig(transport)
+			transport.TLSClientConfig.GetClientCertificate = reloader.GetClientCertificate
+		})
+	}
+}
+
+// WithRootCAs verifies the certificates of servers with the CAs in caFile
+func WithRootCAs(caFile string) HTTPSourceClientOption {
+	return func(sourceClient *httpSourceClient) {
+		data, err := os.ReadFile(caFile)
+		if err != nil {
+			sourceClient.optionErr = errors.Wrapf(err, "read root ca file %s", caFile)
+			return
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			sourceClient.optionErr = errors.Errorf("no certificate found in root ca file %s", caFile)
+			return
+		}
+		sourceClient.transportOptions = append(sourceClient.transportOptions, func(transport *http.Transport) {
+			transport.TLSClientConfig = tlsClientConfig(transport)
+			transport.TLSClientConfig.RootCAs = pool
+			transport.TLSClientConfig.InsecureSkipVerify = false
+		})
+	}
+}
+
+func tlsClientConfig(transport *http.Transport) *tls.Config {
+	if transport.TLSClientConfig == nil {
+		return &tls.Config{}
+	}
+	return transport.TLSClientConfig
+}
+
+// certReloader loads the client certificate again when the files are modified
+type certReloader struct {
+	certFile string
+	keyFile  string
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+func (r *certReloader) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	var modTime time.Time
+	for _, file := range []string{r.certFile, r.keyFile} {
+		stat, err := os.Stat(file)
+		if err != nil {
+			return nil, errors.Wrapf(err, "load client certificate %s and key %s", r.certFile, r.keyFile)
+		}
+		if stat.ModTime().After(modTime) {
+			modTime = stat.ModTime()
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cert != nil && !modTime.After(r.modTime) {
+		return r.cert, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return nil, errors.Wrapf(err, "load client certificate %s and key %s", r.certFile, r.keyFile)
+	}
+	r.cert = &cert
+	r.modTime = modTime
+	return r.cert, nil
+}
+
+// WithCredentialStore sets the credential store, Authorization header is attached
+// with the matched credential when the request does not have one
+func WithCredentialStore(store CredentialStore) HTTPSourceClientOption {
+	return func(sourceClient *httpSourceClient) {
+		sourceClient.credentialStore = store
+	}
+}
+
 func (client *httpSourceClient) GetContentLength(request *source.Request) (int64, error) {
 	resp, err := client.doRequest(http.MethodGet, request)
 	if err != nil {
 		return source.UnknownSourceFileLen, err
 	}
-	defer resp.Body.Close()
-	err = source.CheckResponseCode(resp.StatusCode, []int{http.StatusOK, http.StatusPartialContent})
+	defer closeBody(resp.Body)
+	err = checkResponseCode(resp, []int{http.StatusOK, http.StatusPartialContent})
 	if err != nil {
 		return source.UnknownSourceFileLen, err
 	}
@@ -144,29 +386,60 @@ func (client *httpSourceClient) IsSupportRange(request *source.Request) (bool, e
 	if err != nil {
 		return false, err
 	}
-	defer resp.Body.Close()
+	defer closeBody(resp.Body)
+	if resp.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+		return false, checkResponseCode(resp, []int{http.StatusOK, http.StatusPartialContent})
+	}
 	return resp.StatusCode == http.StatusPartialContent, nil
 }
 
+// IsExpired issues a conditional request to check whether the resource is modified,
+// ETag is preferred as many CDNs do not send a stable Last-Modified, and Last-Modified is
+// compared when there is no ETag. The resource is regarded as not expired when error occurs.
 func (client *httpSourceClient) IsExpired(request *source.Request, info *source.ExpireInfo) (bool, error) {
-	if info != nil {
-		if request.Header == nil {
-			request.Header = source.Header{}
-		}
-		if info.LastModified != "" {
-			request.Header.Set(headers.IfModifiedSince, info.LastModified)
-		}
-		if info.ETag != "" {
-			request.Header.Set(headers.IfNoneMatch, info.ETag)
-		}
+	if info == nil || (info.ETag == "" && info.LastModified == "") {
+		return false, nil
+	}
+	request = request.Clone(request.Context())
+	if request.Header == nil {
+		request.Header = source.Header{}
+	}
+	if info.ETag != "" {
+		request.Header.Set(headers.IfNoneMatch, info.ETag)
+	} else {
+		request.Header.Set(headers.IfModifiedSince, info.LastModified)
 	}
 	resp, err := client.doRequest(http.MethodGet, request)
 	if err != nil {
 		return false, err
 	}
-	defer resp.Body.Close()
-	return !(resp.StatusCode == http.StatusNotModified || (resp.Header.Get(headers.ETag) == info.ETag || resp.Header.Get(headers.LastModified) == info.
-		LastModified)), nil
+	defer closeBody(resp.Body)
+
+	if resp.StatusCode == http.StatusNotModified {
+		return false, nil
+	}
+	if err := checkResponseCode(resp, []int{http.StatusOK, http.StatusPartialContent}); err != nil {
+		return false, err
+	}
+	if etag := resp.Header.Get(headers.ETag); info.ETag != "" && etag != "" {
+		return etag != info.ETag, nil
+	}
+	return resp.Header.Get(headers.LastModified) != info.LastModified, nil
+}
+
+// Check sends a HEAD request to verify the connectivity and authorization,
+// GetContentLength is used instead when the server does not allow HEAD
+func (client *httpSourceClient) Check(request *source.Request) error {
+	resp, err := client.doRequest(http.MethodHead, request)
+	if err != nil {
+		return err
+	}
+	closeBody(resp.Body)
+	if resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented {
+		_, err = client.GetContentLength(request)
+		return err
+	}
+	return checkResponseCode(resp, []int{http.StatusOK, http.StatusPartialContent})
 }
 
 func (client *httpSourceClient) Download(request *source.Request) (*source.Response, error) {
@@ -174,7 +447,7 @@ func (client *httpSourceClient) Download(request *source.Request) (*source.Respo
 	if err != nil {
 		return nil, err
 	}
-	err = source.CheckResponseCode(resp.StatusCode, []int{http.StatusOK, http.StatusPartialContent})
+	err = checkResponseCode(resp, []int{http.StatusOK, http.StatusPartialContent})
 	if err != nil {
 		resp.Body.Close()
 		return nil, err
@@ -190,20 +463,220 @@ func (client *httpSourceClient) Download(request *source.Request) (*source.Respo
 	return response, nil
 }
 
+// DownloadRange downloads ranges sequentially with a single range request for each range,
+// so that every range gets its own response without parsing multipart body
+func (client *httpSourceClient) DownloadRange(request *source.Request, ranges []rangeutils.Range) ([]*source.Response, error) {
+	var responses []*source.Response
+	for _, rg := range ranges {
+		rangeRequest := request.Clone(request.Context())
+		rangeRequest.Header.Set(headers.Range, fmt.Sprintf("bytes=%s", rg.String()))
+		resp, err := client.doRequest(http.MethodGet, rangeRequest)
+		if err == nil {
+			if err = checkResponseCode(resp, []int{http.StatusPartialContent}); err != nil {
+				resp.Body.Close()
+			}
+		}
+		if err != nil {
+			for _, response := range responses {
+				response.Body.Close()
+			}
+			return nil, err
+		}
+
+		responses = append(responses, source.NewResponse(
+			resp.Body,
+			source.WithStatus(resp.StatusCode, resp.Status),
+			source.WithContentLength(resp.ContentLength),
+			source.WithExpireInfo(
+				source.ExpireInfo{
+					LastModified: resp.Header.Get(headers.LastModified),
+					ETag:         resp.Header.Get(headers.ETag),
+				},
+			)))
+	}
+	return responses, nil
+}
+
+// DownloadMultiRange downloads Request.Ranges with a single request,
+// the multipart/byteranges response is read part by part in the order of ranges
+func (client *httpSourceClient) DownloadMultiRange(request *source.Request) (*source.Response, error) {
+	var (
+		specs         []string
+		contentLength int64
+	)
+	for _, rg := range request.Ranges {
+		specs = append(specs, rg.String())
+		contentLength += int64(rg.Length())
+	}
+	rangeRequest := request.Clone(request.Context())
+	rangeRequest.Header.Set(headers.Range, "bytes="+strings.Join(specs, ","))
+	resp, err := client.doRequest(http.MethodGet, rangeRequest)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkResponseCode(resp, []int{http.StatusPartialContent}); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+
+	body, err := newByteRangesReader(resp, request.Ranges)
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return source.NewResponse(
+		body,
+		source.WithStatus(resp.StatusCode, resp.Status),
+		source.WithContentLength(contentLength),
+		source.WithExpireInfo(
+			source.ExpireInfo{
+				LastModified: resp.Header.Get(headers.LastModified),
+				ETag:         resp.Header.Get(headers.ETag),
+			},
+		)), nil
+}
+
+// byteRangesReader reads the parts of multipart/byteranges body in order,
+// every part must match the expected range
+type byteRangesReader struct {
+	body      io.ReadCloser
+	reader    *multipart.Reader
+	ranges    []rangeutils.Range
+	current   io.Reader
+	remaining int64
+}
+
+func newByteRangesReader(resp *http.Response, ranges []rangeutils.Range) (io.ReadCloser, error) {
+	mediaType, params, err := mime.ParseMediaType(resp.Header.Get(headers.ContentType))
+	if err == nil && mediaType == "multipart/byteranges" {
+		return &byteRangesReader{
+			body:   resp.Body,
+			reader: multipart.NewReader(resp.Body, params["boundary"]),
+			ranges: ranges,
+		}, nil
+	}
+
+	// a single range is responded without multipart
+	if len(ranges) == 1 {
+		if err := checkContentRange(resp.Header.Get(headers.ContentRange), ranges[0]); err != nil {
+			return nil, err
+		}
+		return resp.Body, nil
+	}
+	return nil, errors.Errorf("expect multipart/byteranges response for %d ranges, but got %q",
+		len(ranges), resp.Header.Get(headers.ContentType))
+}
+
+func (r *byteRangesReader) Read(p []byte) (int, error) {
+	for {
+		if r.current == nil {
+			if len(r.ranges) == 0 {
+				return 0, io.EOF
+			}
+			part, err := r.reader.NextPart()
+			if err == io.EOF {
+				return 0, errors.Errorf("part of range %s is missing", r.ranges[0].String())
+			}
+			if err != nil {
+				return 0, err
+			}
+			if err := checkContentRange(part.Header.Get(headers.ContentRange), r.ranges[0]); err != nil {
+				return 0, err
+			}
+			r.current = part
+			r.remaining = int64(r.ranges[0].Length())
+			r.ranges = r.ranges[1:]
+		}
+
+		if int64(len(p)) > r.remaining {
+			p = p[:r.remaining]
+		}
+		n, err := r.current.Read(p)
+		r.remaining -= int64(n)
+		if r.remaining == 0 {
+			r.current = nil
+			if n == 0 {
+				continue
+			}
+			return n, nil
+		}
+		if err == io.EOF {
+			return n, io.ErrUnexpectedEOF
+		}
+		return n, err
+	}
+}
+
+func (r *byteRangesReader) Close() error {
+	return r.body.Close()
+}
+
+// checkContentRange checks the Content-Range like "bytes 0-1023/146515" matches the range
+func checkContentRange(contentRange string, rg rangeutils.Range) error {
+	var start, end uint64
+	if _, err := fmt.Sscanf(contentRange, "bytes %d-%d/", &start, &end); err != nil {
+		return errors.Wrapf(err, "invalid content range %q", contentRange)
+	}
+	if start != rg.StartIndex || end != rg.EndIndex {
+		return errors.Errorf("content range %q does not match range %s", contentRange, rg.String())
+	}
+	return nil
+}
+
+// unsatisfiedRangeLength returns the complete length in the Content-Range of 416 response like "bytes */146515",
+// -1 is returned when it is unknown
+func unsatisfiedRangeLength(contentRange string) int64 {
+	var length int64
+	if _, err := fmt.Sscanf(contentRange, "bytes */%d", &length); err != nil || length < 0 {
+		return -1
+	}
+	return length
+}
+
 func (client *httpSourceClient) GetLastModified(request *source.Request) (int64, error) {
 	resp, err := client.doRequest(http.MethodGet, request)
 	if err != nil {
 		return -1, err
 	}
-	defer resp.Body.Close()
-	err = source.CheckResponseCode(resp.StatusCode, []int{http.StatusOK, http.StatusPartialContent})
+	defer closeBody(resp.Body)
+	err = checkResponseCode(resp, []int{http.StatusOK, http.StatusPartialContent})
 	if err != nil {
 		return -1, err
 	}
 	return timeutils.UnixMillis(resp.Header.Get(headers.LastModified)), nil
 }
 
+// checkResponseCode returns UnexpectedStatusCodeError if the response code is not one of the allowed status codes,
+// the error is wrapped by RetryAfterError when source is busy and responds with a Retry-After header,
+// and by RangeNotSatisfiableError when source can not satisfy the requested range
+func checkResponseCode(resp *http.Response, allowed []int) error {
+	err := source.CheckResponseCode(resp.StatusCode, allowed)
+	if err == nil {
+		return nil
+	}
+
+	if resp.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+		return source.NewRangeNotSatisfiableError(err, unsatisfiedRangeLength(resp.Header.Get(headers.ContentRange)))
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		if retryAfter, ok := source.ParseRetryAfter(resp.Header.Get(headers.RetryAfter), time.Now()); ok {
+			return source.NewRetryAfterError(err, retryAfter)
+		}
+	}
+	return err
+}
+
+// closeBody drains the small body before closing it, the connection is not reused when the body is not read to EOF
+func closeBody(body io.ReadCloser) {
+	_, _ = io.CopyN(io.Discard, body, maxDrainBodySize)
+	body.Close()
+}
+
 func (client *httpSourceClient) doRequest(method string, request *source.Request) (*http.Response, error) {
+	if client.optionErr != nil {
+		return nil, client.optionErr
+	}
 	req, err := http.NewRequestWithContext(request.Context(), method, request.URL.String(), nil)
 	if err != nil {
 		return nil, err
@@ -213,9 +686,18 @@ func (client *httpSourceClient) doRequest(method string, request *source.Request
 			req.Header.Add(key, values[i])
 		}
 	}
+	if client.credentialStore != nil && req.Header.Get(headers.Authorization) == "" {
+		if scheme, token, ok := client.credentialStore.Lookup(req.URL.Host); ok {
+			req.Header.Set(headers.Authorization, fmt.Sprintf("%s %s", scheme, token))
+		}
+	}
+	log := request.Logger()
+	log.Debugf("%s %s, range: %q", method, req.URL.Redacted(), req.Header.Get(headers.Range))
 	resp, err := client.httpClient.Do(req)
 	if err != nil {
+		log.Warnf("%s %s error: %s", method, req.URL.Redacted(), err)
 		return nil, err
 	}
+	log.Debugf("%s %s response status: %s", method, req.URL.Redacted(), resp.Status)
 	return resp, nil
 }
@@ -18,9 +18,23 @@ package httpprotocol
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
 	"fmt"
 	"io"
+	"math/big"
+	"net"
 	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -28,8 +42,14 @@ import (
 	"github.com/jarcoal/httpmock"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/suite"
+	"go.uber.org/atomic"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
 
+	logger "d7y.io/dragonfly/v2/internal/dflog"
 	"d7y.io/dragonfly/v2/pkg/source"
+	"d7y.io/dragonfly/v2/pkg/source/sourcetest"
 	"d7y.io/dragonfly/v2/pkg/util/rangeutils"
 )
 
@@ -91,7 +111,7 @@ func (suite *HTTPSourceClientTestSuite) SetupTest() {
 			}
 			return res, nil
 		}
-		if expire := request.Header.Get(headers.IfModifiedSince); expire != "" {
+		if request.Header.Get(headers.IfNoneMatch) == etag || request.Header.Get(headers.IfModifiedSince) == lastModified {
 			header := http.Header{}
 			header.Set(headers.LastModified, lastModified)
 			header.Set(headers.ETag, etag)
@@ -215,6 +235,33 @@ func (suite *HTTPSourceClientTestSuite) TestHttpSourceClientDownloadWithResponse
 	}
 }
 
+func (suite *HTTPSourceClientTestSuite) TestHttpSourceClientDownloadRange() {
+	normalRequest, _ := source.NewRequest(normalRawURL)
+	responses, err := suite.httpClient.DownloadRange(normalRequest, []rangeutils.Range{
+		{StartIndex: 0, EndIndex: 3},
+		{StartIndex: 5, EndIndex: 8},
+	})
+	suite.Nil(err)
+	suite.Len(responses, 2)
+	for i, content := range []string{testContent[0:3], testContent[5:8]} {
+		bytes, err := io.ReadAll(responses[i].Body)
+		suite.Nil(err)
+		suite.Equal(content, string(bytes))
+		suite.Equal(http.StatusPartialContent, responses[i].StatusCode)
+		suite.Nil(responses[i].Body.Close())
+	}
+
+	notSupportRangeRequest, _ := source.NewRequest(normalNotSupportRangeRawURL)
+	responses, err = suite.httpClient.DownloadRange(notSupportRangeRequest, []rangeutils.Range{{StartIndex: 0, EndIndex: 3}})
+	suite.Equal(source.CheckResponseCode(http.StatusOK, []int{http.StatusPartialContent}), err)
+	suite.Nil(responses)
+
+	notfoundRequest, _ := source.NewRequest(notfoundRawURL)
+	responses, err = suite.httpClient.DownloadRange(notfoundRequest, []rangeutils.Range{{StartIndex: 0, EndIndex: 3}})
+	suite.NotNil(err)
+	suite.Nil(responses)
+}
+
 func (suite *HTTPSourceClientTestSuite) TestHttpSourceClientGetContentLength() {
 	normalRequest, _ := source.NewRequest(normalRawURL)
 	normalRangeRequest, _ := source.NewRequest(normalRawURL)
@@ -245,6 +292,7 @@ func (suite *HTTPSourceClientTestSuite) TestHttpSourceClientIsExpired() {
 	normalRequest, _ := source.NewRequest(normalRawURL)
 	errorRequest, _ := source.NewRequest(errorRawURL)
 	expireRequest, _ := source.NewRequest(expireRawURL)
+	notfoundRequest, _ := source.NewRequest(notfoundRawURL)
 	tests := []struct {
 		name       string
 		request    *source.Request
@@ -264,6 +312,23 @@ func (suite *HTTPSourceClientTestSuite) TestHttpSourceClientIsExpired() {
 			LastModified: expireLastModified,
 			ETag:         expireEtag,
 		}, want: true, wantErr: false},
+		{name: "not expire with etag", request: normalRequest, expireInfo: &source.ExpireInfo{
+			ETag: etag,
+		}, want: false, wantErr: false},
+		{name: "expired with etag", request: normalRequest, expireInfo: &source.ExpireInfo{
+			LastModified: lastModified,
+			ETag:         expireEtag,
+		}, want: true, wantErr: false},
+		{name: "not expire with last modified", request: expireRequest, expireInfo: &source.ExpireInfo{
+			LastModified: lastModified,
+		}, want: false, wantErr: false},
+		{name: "expired with last modified", request: expireRequest, expireInfo: &source.ExpireInfo{
+			LastModified: expireLastModified,
+		}, want: true, wantErr: false},
+		{name: "no expire info", request: expireRequest, expireInfo: &source.ExpireInfo{}, want: false, wantErr: false},
+		{name: "error status not expire", request: notfoundRequest, expireInfo: &source.ExpireInfo{
+			ETag: etag,
+		}, want: false, wantErr: true},
 	}
 	for _, tt := range tests {
 		suite.Run(tt.name, func() {
@@ -328,3 +393,681 @@ func (suite *HTTPSourceClientTestSuite) TestHttpSourceClientDoRequest() {
 	suite.Nil(err)
 	suite.EqualValues("ok", string(bytes))
 }
+
+func (suite *HTTPSourceClientTestSuite) TestHttpSourceClientCheck() {
+	var (
+		okURL           = "https://check.com/ok"
+		forbiddenURL    = "https://check.com/forbidden"
+		headNotAllowURL = "https://check.com/head-not-allowed"
+		unreachableURL  = "https://check.com/unreachable"
+	)
+	httpmock.RegisterResponder(http.MethodHead, okURL, httpmock.NewStringResponder(http.StatusOK, ""))
+	httpmock.RegisterResponder(http.MethodHead, forbiddenURL, httpmock.NewStringResponder(http.StatusForbidden, ""))
+	httpmock.RegisterResponder(http.MethodHead, headNotAllowURL, httpmock.NewStringResponder(http.StatusMethodNotAllowed, ""))
+	httpmock.RegisterResponder(http.MethodGet, headNotAllowURL, httpmock.NewStringResponder(http.StatusOK, "ok"))
+	httpmock.RegisterResponder(http.MethodHead, unreachableURL, httpmock.NewErrorResponder(errors.New("connection refused")))
+
+	tests := []struct {
+		url    string
+		expect func(err error)
+	}{
+		{
+			url: okURL,
+			expect: func(err error) {
+				suite.Nil(err)
+			},
+		},
+		{
+			url: forbiddenURL,
+			expect: func(err error) {
+				suite.True(source.IsUnauthorized(err))
+			},
+		},
+		{
+			url: headNotAllowURL,
+			expect: func(err error) {
+				suite.Nil(err)
+			},
+		},
+		{
+			url: unreachableURL,
+			expect: func(err error) {
+				suite.NotNil(err)
+			},
+		},
+	}
+	for _, tc := range tests {
+		request, err := source.NewRequest(tc.url)
+		suite.Nil(err)
+		tc.expect(suite.httpClient.Check(request))
+	}
+}
+
+func (suite *HTTPSourceClientTestSuite) TestHttpSourceClientLogTaskContext() {
+	core, logs := observer.New(zapcore.DebugLevel)
+	coreLogger := logger.CoreLogger
+	logger.SetCoreLogger(zap.New(core).Sugar())
+	defer logger.SetCoreLogger(coreLogger)
+
+	ctx := source.WithTaskContext(context.Background(), "task-1", "peer-1")
+	request, err := source.NewRequestWithContext(ctx, normalRawURL, nil)
+	suite.Nil(err)
+	response, err := suite.httpClient.Download(request)
+	suite.Nil(err)
+	suite.Nil(response.Body.Close())
+
+	suite.NotZero(logs.Len())
+	for _, entry := range logs.All() {
+		fields := entry.ContextMap()
+		suite.Equal("task-1", fields["taskID"], entry.Message)
+		suite.Equal("peer-1", fields["peerID"], entry.Message)
+	}
+}
+
+type fakeCredentialStore map[string][2]string
+
+func (s fakeCredentialStore) Lookup(host string) (string, string, bool) {
+	credential, ok := s[host]
+	return credential[0], credential[1], ok
+}
+
+func (suite *HTTPSourceClientTestSuite) TestHttpSourceClientWithCredentialStore() {
+	var (
+		authURL   = "https://auth.com"
+		noAuthURL = "https://noauth.com"
+	)
+	authorizationResponder := func(request *http.Request) (*http.Response, error) {
+		return httpmock.NewStringResponse(http.StatusOK, request.Header.Get(headers.Authorization)), nil
+	}
+	httpmock.RegisterResponder(http.MethodGet, authURL, authorizationResponder)
+	httpmock.RegisterResponder(http.MethodGet, noAuthURL, authorizationResponder)
+
+	client := newHTTPSourceClient(WithCredentialStore(fakeCredentialStore{
+		"auth.com": {"Bearer", "foo"},
+	}))
+
+	tests := []struct {
+		name          string
+		request       func() *source.Request
+		authorization string
+	}{
+		{
+			name: "credential matches",
+			request: func() *source.Request {
+				request, _ := source.NewRequest(authURL)
+				return request
+			},
+			authorization: "Bearer foo",
+		},
+		{
+			name: "credential does not match",
+			request: func() *source.Request {
+				request, _ := source.NewRequest(noAuthURL)
+				return request
+			},
+			authorization: "",
+		},
+		{
+			name: "explicit authorization is not clobbered",
+			request: func() *source.Request {
+				request, _ := source.NewRequest(authURL)
+				request.Header.Set(headers.Authorization, "Basic bar")
+				return request
+			},
+			authorization: "Basic bar",
+		},
+	}
+
+	for _, tt := range tests {
+		suite.Run(tt.name, func() {
+			response, err := client.Download(tt.request())
+			suite.Nil(err)
+			bytes, err := io.ReadAll(response.Body)
+			suite.Nil(err)
+			suite.Equal(tt.authorization, string(bytes))
+		})
+	}
+}
+
+func (suite *HTTPSourceClientTestSuite) TestHttpSourceClientWithTransportOptions() {
+	client := newHTTPSourceClient(
+		WithHTTPClient(&http.Client{Timeout: time.Minute}),
+		WithMaxIdleConnsPerHost(16),
+		WithIdleConnTimeout(time.Second),
+		WithDisableKeepAlives(false))
+	transport, ok := client.httpClient.Transport.(*http.Transport)
+	suite.True(ok)
+	suite.Equal(16, transport.MaxIdleConnsPerHost)
+	suite.Equal(time.Second, transport.IdleConnTimeout)
+	suite.False(transport.DisableKeepAlives)
+	suite.Equal(time.Minute, client.httpClient.Timeout)
+
+	// the transport of httpmock can not be tuned
+	suite.Equal(_defaultHTTPClient, newHTTPSourceClient(WithMaxIdleConnsPerHost(16)).httpClient)
+}
+
+func (suite *HTTPSourceClientTestSuite) TestHttpSourceClientReuseConnections() {
+	tests := []struct {
+		name          string
+		disable       bool
+		expectedConns int64
+	}{
+		{
+			name:          "keep alive reuses connection",
+			disable:       false,
+			expectedConns: 1,
+		},
+		{
+			name:          "disable keep alive",
+			disable:       true,
+			expectedConns: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		suite.Run(tt.name, func() {
+			conns := atomic.NewInt64(0)
+			server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				http.ServeContent(w, r, "", time.Time{}, strings.NewReader(testContent))
+			}))
+			server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+				if state == http.StateNew {
+					conns.Inc()
+				}
+			}
+			server.Start()
+			defer server.Close()
+
+			client := newHTTPSourceClient(WithHTTPClient(&http.Client{}), WithDisableKeepAlives(tt.disable))
+			request, err := source.NewRequest(server.URL)
+			suite.Nil(err)
+
+			_, err = client.GetContentLength(request)
+			suite.Nil(err)
+			_, err = client.IsSupportRange(request)
+			suite.Nil(err)
+			response, err := client.Download(request)
+			suite.Nil(err)
+			_, err = io.ReadAll(response.Body)
+			suite.Nil(err)
+			response.Body.Close()
+			suite.Equal(tt.expectedConns, conns.Load())
+		})
+	}
+}
+
+func (suite *HTTPSourceClientTestSuite) TestHttpSourceClientWithProxy() {
+	var proxiedHosts []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the proxy receives the absolute url of origin
+		proxiedHosts = append(proxiedHosts, r.URL.Host)
+		http.ServeContent(w, r, "", time.Time{}, strings.NewReader(testContent))
+	}))
+	defer proxy.Close()
+
+	client := newHTTPSourceClient(WithHTTPClient(&http.Client{}), WithProxy(proxy.URL))
+	request, err := source.NewRequest("http://origin.d7y.io/file")
+	suite.Nil(err)
+
+	length, err := client.GetContentLength(request)
+	suite.Nil(err)
+	suite.Equal(int64(len(testContent)), length)
+	support, err := client.IsSupportRange(request)
+	suite.Nil(err)
+	suite.True(support)
+	response, err := client.Download(request)
+	suite.Nil(err)
+	bytes, err := io.ReadAll(response.Body)
+	suite.Nil(err)
+	response.Body.Close()
+	suite.Equal(testContent[:1], string(bytes))
+	suite.Equal([]string{"origin.d7y.io", "origin.d7y.io", "origin.d7y.io"}, proxiedHosts)
+
+	client = newHTTPSourceClient(WithHTTPClient(&http.Client{}), WithProxy("://invalid"))
+	_, err = client.GetContentLength(request)
+	suite.NotNil(err)
+}
+
+// writeClientCert generates a self-signed client certificate and writes it to files
+func writeClientCert(t *testing.T, certFile, keyFile string) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "dragonfly"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func (suite *HTTPSourceClientTestSuite) TestHttpSourceClientWithClientCert() {
+	var (
+		dir      = suite.T().TempDir()
+		certFile = filepath.Join(dir, "client.crt")
+		keyFile  = filepath.Join(dir, "client.key")
+		caFile   = filepath.Join(dir, "ca.crt")
+	)
+
+	// the server only trusts the second client certificate
+	writeClientCert(suite.T(), certFile, keyFile)
+	trusted := writeClientCert(suite.T(), filepath.Join(dir, "trusted.crt"), filepath.Join(dir, "trusted.key"))
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(trusted)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "", time.Time{}, strings.NewReader(testContent))
+	}))
+	server.TLS = &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  clientCAs,
+	}
+	server.StartTLS()
+	defer server.Close()
+	err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0600)
+	suite.Nil(err)
+
+	client := newHTTPSourceClient(WithHTTPClient(&http.Client{}), WithClientCert(certFile, keyFile), WithRootCAs(caFile))
+	request, err := source.NewRequest(server.URL)
+	suite.Nil(err)
+	_, err = client.GetContentLength(request)
+	suite.NotNil(err)
+
+	// reload the trusted client certificate
+	for _, ext := range []string{"crt", "key"} {
+		data, err := os.ReadFile(filepath.Join(dir, "trusted."+ext))
+		suite.Nil(err)
+		file := filepath.Join(dir, "client."+ext)
+		suite.Nil(os.WriteFile(file, data, 0600))
+		suite.Nil(os.Chtimes(file, time.Now().Add(time.Minute), time.Now().Add(time.Minute)))
+	}
+	length, err := client.GetContentLength(request)
+	suite.Nil(err)
+	suite.Equal(int64(len(testContent)), length)
+	response, err := client.Download(request)
+	suite.Nil(err)
+	bytes, err := io.ReadAll(response.Body)
+	suite.Nil(err)
+	response.Body.Close()
+	suite.Equal(testContent, string(bytes))
+
+	client = newHTTPSourceClient(WithClientCert(filepath.Join(dir, "notfound.crt"), keyFile))
+	_, err = client.GetContentLength(request)
+	suite.NotNil(err)
+	suite.Contains(err.Error(), "load client certificate")
+}
+
+func (suite *HTTPSourceClientTestSuite) TestHttpSourceClientDownloadMultiRange() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "", time.Time{}, strings.NewReader(testContent))
+	}))
+	defer server.Close()
+	client := newHTTPSourceClient(WithHTTPClient(server.Client()))
+
+	tests := []struct {
+		name    string
+		ranges  []rangeutils.Range
+		expect  string
+		wantErr bool
+	}{
+		{
+			name:   "multiple ranges",
+			ranges: []rangeutils.Range{{StartIndex: 0, EndIndex: 0}, {StartIndex: 5, EndIndex: 6}, {StartIndex: 10, EndIndex: 13}},
+			expect: "ltecase",
+		},
+		{
+			name:   "single range",
+			ranges: []rangeutils.Range{{StartIndex: 2, EndIndex: 3}},
+			expect: "am",
+		},
+		{
+			name:    "range is not satisfiable",
+			ranges:  []rangeutils.Range{{StartIndex: 100, EndIndex: 200}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		suite.Run(tt.name, func() {
+			request, err := source.NewRequest(server.URL)
+			suite.Nil(err)
+			request.Ranges = tt.ranges
+			response, err := client.DownloadMultiRange(request)
+			if tt.wantErr {
+				suite.NotNil(err)
+				return
+			}
+			suite.Nil(err)
+			suite.Equal(int64(len(tt.expect)), response.ContentLength)
+			data, err := io.ReadAll(response.Body)
+			suite.Nil(err)
+			suite.Nil(response.Body.Close())
+			suite.Equal(tt.expect, string(data))
+		})
+	}
+}
+
+func (suite *HTTPSourceClientTestSuite) TestHttpSourceClientRangeNotSatisfiable() {
+	withContentRange := atomic.NewBool(true)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(headers.Range) == "" {
+			w.Write([]byte(testContent))
+			return
+		}
+		if withContentRange.Load() {
+			w.Header().Set(headers.ContentRange, fmt.Sprintf("bytes */%d", len(testContent)))
+		}
+		w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+	}))
+	defer server.Close()
+	client := newHTTPSourceClient(WithHTTPClient(&http.Client{}))
+
+	for _, expectLength := range []int64{int64(len(testContent)), -1} {
+		withContentRange.Store(expectLength >= 0)
+
+		request, err := source.NewRequestWithHeader(server.URL, map[string]string{source.Range: "100-200"})
+		suite.Nil(err)
+		response, err := client.Download(request)
+		suite.Nil(response)
+		suite.True(source.IsRangeNotSatisfiable(err))
+		var e *source.RangeNotSatisfiableError
+		suite.True(errors.As(err, &e))
+		suite.Equal(expectLength, e.ContentLength())
+
+		request, err = source.NewRequest(server.URL)
+		suite.Nil(err)
+		support, err := client.IsSupportRange(request)
+		suite.False(support)
+		suite.True(source.IsRangeNotSatisfiable(err))
+	}
+
+	// the whole resource can be downloaded again without the range
+	request, err := source.NewRequest(server.URL)
+	suite.Nil(err)
+	response, err := client.Download(request)
+	suite.Nil(err)
+	data, err := io.ReadAll(response.Body)
+	suite.Nil(err)
+	suite.Nil(response.Body.Close())
+	suite.Equal(testContent, string(data))
+}
+
+func (suite *HTTPSourceClientTestSuite) TestHttpSourceClientRetryAfter() {
+	retryAt := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/delta-seconds":
+			w.Header().Set(headers.RetryAfter, "120")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case "/http-date":
+			w.Header().Set(headers.RetryAfter, retryAt)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case "/invalid":
+			w.Header().Set(headers.RetryAfter, "foo")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case "/not-busy":
+			w.Header().Set(headers.RetryAfter, "120")
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	client := newHTTPSourceClient(WithHTTPClient(server.Client()))
+
+	tests := []struct {
+		path       string
+		retryAfter bool
+		expect     func(retryAfter time.Duration)
+	}{
+		{
+			path:       "/delta-seconds",
+			retryAfter: true,
+			expect: func(retryAfter time.Duration) {
+				suite.Equal(120*time.Second, retryAfter)
+			},
+		},
+		{
+			path:       "/http-date",
+			retryAfter: true,
+			expect: func(retryAfter time.Duration) {
+				suite.True(retryAfter > 59*time.Minute && retryAfter <= time.Hour)
+			},
+		},
+		{
+			path: "/invalid",
+		},
+		{
+			path: "/not-busy",
+		},
+	}
+
+	for _, tt := range tests {
+		suite.Run(tt.path, func() {
+			request, err := source.NewRequest(server.URL + tt.path)
+			suite.Nil(err)
+			response, err := client.Download(request)
+			suite.Nil(response)
+			suite.NotNil(err)
+
+			var statusCodeError source.UnexpectedStatusCodeError
+			suite.True(errors.As(err, &statusCodeError))
+			retryAfter, ok := source.RetryAfterOf(err)
+			suite.Equal(tt.retryAfter, ok)
+			if tt.expect != nil {
+				tt.expect(retryAfter)
+			}
+		})
+	}
+}
+
+func (suite *HTTPSourceClientTestSuite) TestHttpSourceClientWithHostTransports() {
+	const (
+		hostCount    = 4
+		requestCount = 16
+	)
+	var servers []*httptest.Server
+	for i := 0; i < hostCount; i++ {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.ServeContent(w, r, "", time.Time{}, strings.NewReader(testContent))
+		}))
+		defer server.Close()
+		servers = append(servers, server)
+	}
+
+	client := newHTTPSourceClient(WithHTTPClient(&http.Client{}), WithHostTransports(2, time.Minute))
+	transport, ok := client.httpClient.Transport.(*hostTransport)
+	suite.True(ok)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, hostCount*requestCount)
+	for _, server := range servers {
+		for i := 0; i < requestCount; i++ {
+			wg.Add(1)
+			go func(rawURL string) {
+				defer wg.Done()
+				request, err := source.NewRequest(rawURL)
+				if err != nil {
+					errs <- err
+					return
+				}
+				response, err := client.Download(request)
+				if err != nil {
+					errs <- err
+					return
+				}
+				defer response.Body.Close()
+				data, err := io.ReadAll(response.Body)
+				if err != nil {
+					errs <- err
+					return
+				}
+				if string(data) != testContent {
+					errs <- errors.Errorf("unexpected content %q", data)
+				}
+			}(server.URL)
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		suite.Nil(err)
+	}
+
+	// every host has its own transport, and all references are released after bodies are closed
+	transport.mu.Lock()
+	defer transport.mu.Unlock()
+	suite.Len(transport.transports, hostCount)
+	for _, entry := range transport.transports {
+		suite.Equal(0, entry.refs)
+		suite.Equal(2, entry.transport.MaxIdleConnsPerHost)
+	}
+}
+
+func (suite *HTTPSourceClientTestSuite) TestHostTransportSweep() {
+	now := time.Now()
+	transport := newHostTransport(&http.Transport{}, hostTransportOptions{ttl: time.Minute})
+	transport.now = func() time.Time { return now }
+
+	transport.acquire("a.com:443:tls")
+	transport.acquire("b.com:80")
+	transport.release("b.com:80")
+
+	// the transport in use is kept and the idle one is removed after ttl
+	now = now.Add(2 * time.Minute)
+	transport.acquire("c.com:80")
+	suite.Len(transport.transports, 2)
+	suite.Contains(transport.transports, "a.com:443:tls")
+	suite.Contains(transport.transports, "c.com:80")
+
+	// sweep runs at most once per ttl
+	transport.release("a.com:443:tls")
+	now = now.Add(30 * time.Second)
+	transport.acquire("c.com:80")
+	suite.Len(transport.transports, 2)
+	now = now.Add(2 * time.Minute)
+	transport.acquire("c.com:80")
+	suite.Len(transport.transports, 1)
+}
+
+func (suite *HTTPSourceClientTestSuite) TestHttpSourceClientWithRecorder() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Test", "recorded")
+		http.ServeContent(w, r, "", time.Time{}, strings.NewReader(testContent))
+	}))
+	defer server.Close()
+
+	dir := suite.T().TempDir()
+	client := newHTTPSourceClient(WithHTTPClient(&http.Client{}), WithRecorder(dir), WithRecordBody(4))
+	request, err := source.NewRequestWithHeader(server.URL+"/file", map[string]string{headers.Authorization: "Bearer secret"})
+	suite.Nil(err)
+	response, err := client.Download(request)
+	suite.Nil(err)
+	data, err := io.ReadAll(response.Body)
+	suite.Nil(err)
+	suite.Nil(response.Body.Close())
+	// recording does not consume the body
+	suite.Equal(testContent, string(data))
+
+	files, err := os.ReadDir(dir)
+	suite.Nil(err)
+	suite.Len(files, 1)
+	record, err := os.ReadFile(filepath.Join(dir, files[0].Name()))
+	suite.Nil(err)
+	suite.Contains(string(record), "GET /file HTTP/1.1\r\n")
+	suite.Contains(string(record), "Authorization: REDACTED\r\n")
+	suite.NotContains(string(record), "secret")
+	suite.Contains(string(record), "200 OK\r\n")
+	suite.Contains(string(record), "X-Test: recorded\r\n")
+	suite.True(strings.HasSuffix(string(record), "\r\n\r\n"+testContent[:4]))
+
+	// nothing is recorded without WithRecorder
+	client = newHTTPSourceClient(WithHTTPClient(&http.Client{}), WithRecordBody(4))
+	_, ok := client.httpClient.Transport.(*recordingTransport)
+	suite.False(ok)
+}
+
+func (suite *HTTPSourceClientTestSuite) TestAdapter() {
+	presignedURL := "http://example.com/bucket//a/b?X-Amz-Signature=abc"
+	client := sourcetest.NewFakeSourceClient(sourcetest.WithObject(presignedURL, []byte(testContent)))
+	manager := source.NewManager()
+	suite.Nil(manager.Register(HTTPClient, client, Adapter))
+	rc, ok := manager.GetClient(HTTPClient)
+	suite.True(ok)
+
+	// the url reaches the underlying client as it is
+	request, err := source.NewRequest(presignedURL)
+	suite.Nil(err)
+	contentLength, err := rc.GetContentLength(request)
+	suite.Nil(err)
+	suite.Equal(int64(len(testContent)), contentLength)
+
+	request, err = source.NewRequestWithHeader("http://example.com/a//./b", map[string]string{source.Range: "0-3"})
+	suite.Nil(err)
+	adapted := Adapter(request)
+	suite.Equal("http://example.com/a//./b", adapted.URL.String())
+	suite.Equal("bytes=0-3", adapted.Header.Get(headers.Range))
+}
+
+func (suite *HTTPSourceClientTestSuite) TestNormalizingAdapter() {
+	client := sourcetest.NewFakeSourceClient(sourcetest.WithObject("http://example.com/a/b", []byte(testContent)))
+	manager := source.NewManager()
+	suite.Nil(manager.Register(HTTPClient, client, NormalizingAdapter))
+	rc, ok := manager.GetClient(HTTPClient)
+	suite.True(ok)
+
+	// the normalized url reaches the underlying client
+	request, err := source.NewRequest("http://example.com//a/./b")
+	suite.Nil(err)
+	contentLength, err := rc.GetContentLength(request)
+	suite.Nil(err)
+	suite.Equal(int64(len(testContent)), contentLength)
+	// the request of caller is not modified
+	suite.Equal("http://example.com//a/./b", request.URL.String())
+
+	request, err = source.NewRequestWithHeader("http://example.com/a//b", map[string]string{source.Range: "0-3"})
+	suite.Nil(err)
+	adapted := NormalizingAdapter(request)
+	suite.Equal("http://example.com/a/b", adapted.URL.String())
+	suite.Equal("bytes=0-3", adapted.Header.Get(headers.Range))
+}
+
+func (suite *HTTPSourceClientTestSuite) TestHostKey() {
+	tests := []struct {
+		rawURL   string
+		expected string
+	}{
+		{rawURL: "http://Example.com/file", expected: "example.com:80"},
+		{rawURL: "https://example.com/file", expected: "example.com:443:tls"},
+		{rawURL: "https://example.com:8443/file", expected: "example.com:8443:tls"},
+		{rawURL: "http://[::1]:8080/file", expected: "[::1]:8080"},
+	}
+	for _, tt := range tests {
+		req, err := http.NewRequest(http.MethodGet, tt.rawURL, nil)
+		suite.Nil(err)
+		suite.Equal(tt.expected, hostKey(req))
+	}
+}
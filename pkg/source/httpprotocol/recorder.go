@@ -0,0 +1,164 @@
+/*
+ *     Copyright 2022 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package httpprotocol
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/go-http-utils/headers"
+	"go.uber.org/atomic"
+
+	logger "d7y.io/dragonfly/v2/internal/dflog"
+)
+
+// redactedHeaders are the headers whose values are not recorded
+var redactedHeaders = []string{headers.Authorization, headers.ProxyAuthorization}
+
+// recorderOptions is the options of recording requests and responses
+type recorderOptions struct {
+	// dir is the directory of the recorded files
+	dir string
+	// maxBodySize is the max bytes of response body to record, the body is not recorded when it is not positive
+	maxBodySize int64
+}
+
+// recordingTransport is a http.RoundTripper which writes every request and response to a file under dir,
+// the response body is teed when it is read, so that recording does not consume it
+type recordingTransport struct {
+	base    http.RoundTripper
+	options recorderOptions
+	seq     *atomic.Uint64
+}
+
+var _ http.RoundTripper = (*recordingTransport)(nil)
+
+// recordHTTPClient returns a copy of httpClient which records requests and responses
+func recordHTTPClient(httpClient *http.Client, options recorderOptions) *http.Client {
+	base := httpClient.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	recorded := *httpClient
+	recorded.Transport = &recordingTransport{
+		base:    base,
+		options: options,
+		seq:     atomic.NewUint64(0),
+	}
+	return &recorded
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	file, err := t.create()
+	if err != nil {
+		logger.Warnf("create http source record file in %s error: %s", t.options.dir, err)
+		return t.base.RoundTrip(req)
+	}
+	writeRequest(file, req)
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		fmt.Fprintf(file, "\r\nerror: %s\r\n", err)
+		file.Close()
+		return nil, err
+	}
+	writeResponse(file, resp)
+	if t.options.maxBodySize <= 0 {
+		file.Close()
+		return resp, nil
+	}
+	fmt.Fprint(file, "\r\n")
+	resp.Body = &recordingBody{ReadCloser: resp.Body, file: file, remaining: t.options.maxBodySize}
+	return resp, nil
+}
+
+// CloseIdleConnections closes the idle connections of base transport, it's called by http.Client.CloseIdleConnections
+func (t *recordingTransport) CloseIdleConnections() {
+	if closer, ok := t.base.(interface{ CloseIdleConnections() }); ok {
+		closer.CloseIdleConnections()
+	}
+}
+
+// create creates a new record file named with the timestamp and the sequence of request
+func (t *recordingTransport) create() (*os.File, error) {
+	name := fmt.Sprintf("%s-%d.txt", time.Now().Format("20060102T150405.000000000"), t.seq.Inc())
+	return os.OpenFile(filepath.Join(t.options.dir, name), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+}
+
+// writeRequest writes the request line and headers, the values of redactedHeaders are replaced
+func writeRequest(w io.Writer, req *http.Request) {
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	fmt.Fprintf(w, "%s %s %s\r\nHost: %s\r\n", req.Method, req.URL.RequestURI(), req.Proto, host)
+	header := req.Header.Clone()
+	for _, key := range redactedHeaders {
+		if header.Get(key) != "" {
+			header.Set(key, "REDACTED")
+		}
+	}
+	_ = header.Write(w)
+}
+
+// writeResponse writes the status line and headers of response
+func writeResponse(w io.Writer, resp *http.Response) {
+	fmt.Fprintf(w, "\r\n%s %s\r\n", resp.Proto, resp.Status)
+	_ = resp.Header.Write(w)
+}
+
+// recordingBody writes at most remaining bytes of body to file when it is read,
+// file is closed when the bytes are recorded or the body is closed
+type recordingBody struct {
+	io.ReadCloser
+	file      *os.File
+	remaining int64
+	once      sync.Once
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 && b.remaining > 0 {
+		size := int64(n)
+		if size > b.remaining {
+			size = b.remaining
+		}
+		_, _ = b.file.Write(p[:size])
+		b.remaining -= size
+	}
+	if b.remaining <= 0 || err != nil {
+		b.closeFile()
+	}
+	return n, err
+}
+
+func (b *recordingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.closeFile()
+	return err
+}
+
+func (b *recordingBody) closeFile() {
+	b.once.Do(func() {
+		b.file.Close()
+	})
+}
@@ -0,0 +1,255 @@
+/*
+ *     Copyright 2022 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package source
+
+import (
+	"container/list"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"d7y.io/dragonfly/v2/pkg/util/rangeutils"
+)
+
+const (
+	metadataContentLength = "content-length"
+	metadataSupportRange  = "support-range"
+	metadataLastModified  = "last-modified"
+)
+
+// WithMetadataCache returns a RegisterOption which caches the results of GetContentLength, IsSupportRange
+// and GetLastModified by url and headers for ttl, at most maxEntries results are kept in lru order.
+// Download is never cached, and the cached results of url are invalidated when IsExpired returns true.
+//
+//	source.Register("https", client, adapter, source.WithMetadataCache(time.Minute, 1024))
+func WithMetadataCache(ttl time.Duration, maxEntries int) RegisterOption {
+	return WithClientDecorator(func(resourceClient ResourceClient) ResourceClient {
+		return newMetadataCacheClient(resourceClient, ttl, maxEntries)
+	})
+}
+
+// metadataCacheClient caches the metadata of resourceClient, the optional interfaces are passed through
+type metadataCacheClient struct {
+	rc         ResourceClient
+	ttl        time.Duration
+	maxEntries int
+	now        func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+}
+
+type metadataCacheEntry struct {
+	key      string
+	url      string
+	value    interface{}
+	expireAt time.Time
+}
+
+var (
+	_ ResourceClient         = (*metadataCacheClient)(nil)
+	_ ResourceLister         = (*metadataCacheClient)(nil)
+	_ ResourceMetadataLister = (*metadataCacheClient)(nil)
+	_ RangeReader            = (*metadataCacheClient)(nil)
+	_ MultiRangeDownloader   = (*metadataCacheClient)(nil)
+	_ ResourceChecker        = (*metadataCacheClient)(nil)
+)
+
+func newMetadataCacheClient(resourceClient ResourceClient, ttl time.Duration, maxEntries int) *metadataCacheClient {
+	return &metadataCacheClient{
+		rc:         resourceClient,
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		now:        time.Now,
+		entries:    map[string]*list.Element{},
+		lru:        list.New(),
+	}
+}
+
+func (c *metadataCacheClient) GetContentLength(request *Request) (int64, error) {
+	key := metadataKey(metadataContentLength, request)
+	if value, ok := c.get(key); ok {
+		return value.(int64), nil
+	}
+	contentLength, err := c.rc.GetContentLength(request)
+	if err != nil {
+		return contentLength, err
+	}
+	c.set(key, request.URL.String(), contentLength)
+	return contentLength, nil
+}
+
+func (c *metadataCacheClient) IsSupportRange(request *Request) (bool, error) {
+	// the key is computed before the request, as some clients set the range header of request
+	key := metadataKey(metadataSupportRange, request)
+	if value, ok := c.get(key); ok {
+		return value.(bool), nil
+	}
+	support, err := c.rc.IsSupportRange(request)
+	if err != nil {
+		return support, err
+	}
+	c.set(key, request.URL.String(), support)
+	return support, nil
+}
+
+func (c *metadataCacheClient) GetLastModified(request *Request) (int64, error) {
+	key := metadataKey(metadataLastModified, request)
+	if value, ok := c.get(key); ok {
+		return value.(int64), nil
+	}
+	lastModified, err := c.rc.GetLastModified(request)
+	if err != nil {
+		return lastModified, err
+	}
+	c.set(key, request.URL.String(), lastModified)
+	return lastModified, nil
+}
+
+// IsExpired is never cached, the cached metadata of url is invalidated when the resource is expired
+func (c *metadataCacheClient) IsExpired(request *Request, info *ExpireInfo) (bool, error) {
+	rawURL := request.URL.String()
+	expired, err := c.rc.IsExpired(request, info)
+	if err == nil && expired {
+		c.deleteURL(rawURL)
+	}
+	return expired, err
+}
+
+// Check is never cached, the source is always requested
+func (c *metadataCacheClient) Check(request *Request) error {
+	return check(c.rc, request)
+}
+
+func (c *metadataCacheClient) Download(request *Request) (*Response, error) {
+	return c.rc.Download(request)
+}
+
+func (c *metadataCacheClient) List(request *Request) ([]*url.URL, error) {
+	lister, ok := c.rc.(ResourceLister)
+	if !ok {
+		return nil, ErrClientNotSupportList
+	}
+	return lister.List(request)
+}
+
+func (c *metadataCacheClient) ListWithMetadata(request *Request) ([]*ResourceInfo, error) {
+	lister, ok := c.rc.(ResourceMetadataLister)
+	if !ok {
+		return nil, ErrClientNotSupportList
+	}
+	return lister.ListWithMetadata(request)
+}
+
+func (c *metadataCacheClient) DownloadRange(request *Request, ranges []rangeutils.Range) ([]*Response, error) {
+	rangeReader, ok := c.rc.(RangeReader)
+	if !ok {
+		return nil, ErrClientNotSupportRangeRead
+	}
+	return rangeReader.DownloadRange(request, ranges)
+}
+
+func (c *metadataCacheClient) DownloadMultiRange(request *Request) (*Response, error) {
+	downloader, ok := c.rc.(MultiRangeDownloader)
+	if !ok {
+		return nil, ErrClientNotSupportRangeRead
+	}
+	return downloader.DownloadMultiRange(request)
+}
+
+func (c *metadataCacheClient) get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*metadataCacheEntry)
+	if !c.now().Before(entry.expireAt) {
+		c.lru.Remove(elem)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.lru.MoveToFront(elem)
+	return entry.value, true
+}
+
+func (c *metadataCacheClient) set(key, rawURL string, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expireAt := c.now().Add(c.ttl)
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*metadataCacheEntry)
+		entry.value = value
+		entry.expireAt = expireAt
+		c.lru.MoveToFront(elem)
+		return
+	}
+
+	c.entries[key] = c.lru.PushFront(&metadataCacheEntry{key: key, url: rawURL, value: value, expireAt: expireAt})
+	for c.maxEntries > 0 && c.lru.Len() > c.maxEntries {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*metadataCacheEntry).key)
+	}
+}
+
+// deleteURL deletes the entries of url with any headers
+func (c *metadataCacheClient) deleteURL(rawURL string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for elem := c.lru.Front(); elem != nil; {
+		next := elem.Next()
+		if entry := elem.Value.(*metadataCacheEntry); entry.url == rawURL {
+			c.lru.Remove(elem)
+			delete(c.entries, entry.key)
+		}
+		elem = next
+	}
+}
+
+// metadataKey returns the cache key of kind for request, it consists of url, headers and ranges
+func metadataKey(kind string, request *Request) string {
+	var builder strings.Builder
+	builder.WriteString(kind)
+	builder.WriteString(" ")
+	builder.WriteString(request.URL.String())
+
+	keys := make([]string, 0, len(request.Header))
+	for key := range request.Header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		builder.WriteString("\n")
+		builder.WriteString(key)
+		builder.WriteString(": ")
+		builder.WriteString(strings.Join(request.Header[key], ","))
+	}
+
+	for _, rg := range request.Ranges {
+		builder.WriteString("\nrange: ")
+		builder.WriteString(rg.String())
+	}
+	return builder.String()
+}
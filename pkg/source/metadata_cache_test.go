@@ -0,0 +1,246 @@
+/*
+ *     Copyright 2022 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package source
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	testifyassert "github.com/stretchr/testify/assert"
+
+	"d7y.io/dragonfly/v2/pkg/util/rangeutils"
+)
+
+// countingClient counts the calls of every method, the results change with the calls
+type countingClient struct {
+	calls   map[string]int
+	expired bool
+	err     error
+}
+
+func newCountingClient() *countingClient {
+	return &countingClient{calls: map[string]int{}}
+}
+
+func (c *countingClient) GetContentLength(request *Request) (int64, error) {
+	c.calls["GetContentLength"]++
+	return int64(c.calls["GetContentLength"]), c.err
+}
+
+func (c *countingClient) IsSupportRange(request *Request) (bool, error) {
+	c.calls["IsSupportRange"]++
+	return true, c.err
+}
+
+func (c *countingClient) IsExpired(request *Request, info *ExpireInfo) (bool, error) {
+	c.calls["IsExpired"]++
+	return c.expired, c.err
+}
+
+func (c *countingClient) Download(request *Request) (*Response, error) {
+	c.calls["Download"]++
+	return NewResponse(io.NopCloser(bytes.NewBufferString(fmt.Sprintf("download %d", c.calls["Download"])))), c.err
+}
+
+func (c *countingClient) GetLastModified(request *Request) (int64, error) {
+	c.calls["GetLastModified"]++
+	return int64(c.calls["GetLastModified"]), c.err
+}
+
+func TestMetadataCacheClient_Cache(t *testing.T) {
+	assert := testifyassert.New(t)
+	rc := newCountingClient()
+	client := newMetadataCacheClient(rc, time.Minute, 16)
+	now := time.Now()
+	client.now = func() time.Time { return now }
+	request, err := NewRequest("http://example.com/file")
+	assert.NoError(err)
+
+	for i := 0; i < 3; i++ {
+		contentLength, err := client.GetContentLength(request)
+		assert.NoError(err)
+		assert.Equal(int64(1), contentLength)
+		support, err := client.IsSupportRange(request)
+		assert.NoError(err)
+		assert.True(support)
+		lastModified, err := client.GetLastModified(request)
+		assert.NoError(err)
+		assert.Equal(int64(1), lastModified)
+	}
+	assert.Equal(1, rc.calls["GetContentLength"])
+	assert.Equal(1, rc.calls["IsSupportRange"])
+	assert.Equal(1, rc.calls["GetLastModified"])
+
+	// headers are part of the key
+	rangeRequest, err := NewRequestWithHeader("http://example.com/file", map[string]string{Range: "0-9"})
+	assert.NoError(err)
+	contentLength, err := client.GetContentLength(rangeRequest)
+	assert.NoError(err)
+	assert.Equal(int64(2), contentLength)
+
+	// entries are expired after ttl
+	now = now.Add(time.Minute)
+	contentLength, err = client.GetContentLength(request)
+	assert.NoError(err)
+	assert.Equal(int64(3), contentLength)
+}
+
+func TestMetadataCacheClient_DownloadIsNotCached(t *testing.T) {
+	assert := testifyassert.New(t)
+	rc := newCountingClient()
+	client := newMetadataCacheClient(rc, time.Minute, 16)
+	request, err := NewRequest("http://example.com/file")
+	assert.NoError(err)
+
+	for i := 1; i <= 3; i++ {
+		_, err := client.GetContentLength(request)
+		assert.NoError(err)
+		response, err := client.Download(request)
+		assert.NoError(err)
+		data, err := io.ReadAll(response.Body)
+		assert.NoError(err)
+		assert.Equal(fmt.Sprintf("download %d", i), string(data))
+	}
+	assert.Equal(3, rc.calls["Download"])
+	assert.Equal(1, rc.calls["GetContentLength"])
+}
+
+func TestMetadataCacheClient_Invalidate(t *testing.T) {
+	assert := testifyassert.New(t)
+	rc := newCountingClient()
+	client := newMetadataCacheClient(rc, time.Minute, 16)
+	request, err := NewRequest("http://example.com/file")
+	assert.NoError(err)
+
+	rangeRequest, err := NewRequestWithHeader("http://example.com/file", map[string]string{Range: "0-9"})
+	assert.NoError(err)
+	_, err = client.GetContentLength(request)
+	assert.NoError(err)
+	_, err = client.GetLastModified(request)
+	assert.NoError(err)
+	_, err = client.IsSupportRange(rangeRequest)
+	assert.NoError(err)
+
+	// not expired resource keeps the cache
+	expired, err := client.IsExpired(request, &ExpireInfo{ETag: "foo"})
+	assert.NoError(err)
+	assert.False(expired)
+	contentLength, err := client.GetContentLength(request)
+	assert.NoError(err)
+	assert.Equal(int64(1), contentLength)
+
+	rc.expired = true
+	expired, err = client.IsExpired(request, &ExpireInfo{ETag: "foo"})
+	assert.NoError(err)
+	assert.True(expired)
+	contentLength, err = client.GetContentLength(request)
+	assert.NoError(err)
+	assert.Equal(int64(2), contentLength)
+	lastModified, err := client.GetLastModified(request)
+	assert.NoError(err)
+	assert.Equal(int64(2), lastModified)
+	// the entries of url with other headers are invalidated too
+	_, err = client.IsSupportRange(rangeRequest)
+	assert.NoError(err)
+	assert.Equal(2, rc.calls["IsSupportRange"])
+	assert.Equal(2, rc.calls["IsExpired"])
+}
+
+func TestMetadataCacheClient_ErrorIsNotCached(t *testing.T) {
+	assert := testifyassert.New(t)
+	rc := newCountingClient()
+	rc.err = errors.New("foo")
+	client := newMetadataCacheClient(rc, time.Minute, 16)
+	request, err := NewRequest("http://example.com/file")
+	assert.NoError(err)
+
+	_, err = client.GetContentLength(request)
+	assert.Error(err)
+	rc.err = nil
+	contentLength, err := client.GetContentLength(request)
+	assert.NoError(err)
+	assert.Equal(int64(2), contentLength)
+}
+
+func TestMetadataCacheClient_Evict(t *testing.T) {
+	assert := testifyassert.New(t)
+	rc := newCountingClient()
+	client := newMetadataCacheClient(rc, time.Minute, 2)
+	var requests []*Request
+	for i := 0; i < 3; i++ {
+		request, err := NewRequest(fmt.Sprintf("http://example.com/file%d", i))
+		assert.NoError(err)
+		requests = append(requests, request)
+	}
+
+	_, err := client.GetContentLength(requests[0])
+	assert.NoError(err)
+	_, err = client.GetContentLength(requests[1])
+	assert.NoError(err)
+	// file0 is the most recently used, file1 is evicted
+	_, err = client.GetContentLength(requests[0])
+	assert.NoError(err)
+	_, err = client.GetContentLength(requests[2])
+	assert.NoError(err)
+	assert.Equal(3, rc.calls["GetContentLength"])
+	assert.Equal(2, client.lru.Len())
+
+	_, err = client.GetContentLength(requests[0])
+	assert.NoError(err)
+	assert.Equal(3, rc.calls["GetContentLength"])
+	_, err = client.GetContentLength(requests[1])
+	assert.NoError(err)
+	assert.Equal(4, rc.calls["GetContentLength"])
+}
+
+func TestWithMetadataCache_Register(t *testing.T) {
+	assert := testifyassert.New(t)
+	rc := newCountingClient()
+	manager := NewManager()
+	assert.NoError(manager.Register("fake", rc, noopAdapter, WithMetadataCache(time.Minute, 16)))
+	// the same client is registered again with the decorator
+	assert.NoError(manager.Register("fake", rc, noopAdapter, WithMetadataCache(time.Minute, 16)))
+	assert.NoError(manager.Register("fakerange", &fakeRangeClient{}, noopAdapter, WithMetadataCache(time.Minute, 16)))
+
+	client, ok := manager.GetClient("fake")
+	assert.True(ok)
+	assert.Empty(client.(*clientWrapper).hooks)
+	request, err := NewRequest("fake://example.com/file")
+	assert.NoError(err)
+	for i := 0; i < 2; i++ {
+		_, err := client.GetContentLength(request)
+		assert.NoError(err)
+		response, err := client.Download(request)
+		assert.NoError(err)
+		assert.NoError(response.Body.Close())
+	}
+	assert.Equal(1, rc.calls["GetContentLength"])
+	assert.Equal(2, rc.calls["Download"])
+
+	// optional interfaces are passed through
+	_, err = client.(RangeReader).DownloadRange(request, []rangeutils.Range{{StartIndex: 0, EndIndex: 1}})
+	assert.ErrorIs(err, ErrClientNotSupportRangeRead)
+	client, ok = manager.GetClient("fakerange")
+	assert.True(ok)
+	responses, err := client.(RangeReader).DownloadRange(request, []rangeutils.Range{{StartIndex: 0, EndIndex: 1}})
+	assert.NoError(err)
+	assert.Len(responses, 1)
+}
@@ -1,13 +1,15 @@
 // Code generated by MockGen. DO NOT EDIT.
-// Source: d7y.io/dragonfly/v2/pkg/source (interfaces: ResourceClient)
+// Source: d7y.io/dragonfly/v2/pkg/source (interfaces: ResourceClient,ResourceLister,ResourceMetadataLister,RangeReader)
 
 // Package mock is a generated GoMock package.
 package mock
 
 import (
+	url "net/url"
 	reflect "reflect"
 
 	source "d7y.io/dragonfly/v2/pkg/source"
+	rangeutils "d7y.io/dragonfly/v2/pkg/util/rangeutils"
 	gomock "github.com/golang/mock/gomock"
 )
 
@@ -108,3 +110,117 @@ func (mr *MockResourceClientMockRecorder) IsSupportRange(arg0 interface{}) *gomo
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsSupportRange", reflect.TypeOf((*MockResourceClient)(nil).IsSupportRange), arg0)
 }
+
+// MockResourceLister is a mock of ResourceLister interface.
+type MockResourceLister struct {
+	ctrl     *gomock.Controller
+	recorder *MockResourceListerMockRecorder
+}
+
+// MockResourceListerMockRecorder is the mock recorder for MockResourceLister.
+type MockResourceListerMockRecorder struct {
+	mock *MockResourceLister
+}
+
+// NewMockResourceLister creates a new mock instance.
+func NewMockResourceLister(ctrl *gomock.Controller) *MockResourceLister {
+	mock := &MockResourceLister{ctrl: ctrl}
+	mock.recorder = &MockResourceListerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockResourceLister) EXPECT() *MockResourceListerMockRecorder {
+	return m.recorder
+}
+
+// List mocks base method.
+func (m *MockResourceLister) List(arg0 *source.Request) ([]*url.URL, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", arg0)
+	ret0, _ := ret[0].([]*url.URL)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockResourceListerMockRecorder) List(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockResourceLister)(nil).List), arg0)
+}
+
+// MockResourceMetadataLister is a mock of ResourceMetadataLister interface.
+type MockResourceMetadataLister struct {
+	ctrl     *gomock.Controller
+	recorder *MockResourceMetadataListerMockRecorder
+}
+
+// MockResourceMetadataListerMockRecorder is the mock recorder for MockResourceMetadataLister.
+type MockResourceMetadataListerMockRecorder struct {
+	mock *MockResourceMetadataLister
+}
+
+// NewMockResourceMetadataLister creates a new mock instance.
+func NewMockResourceMetadataLister(ctrl *gomock.Controller) *MockResourceMetadataLister {
+	mock := &MockResourceMetadataLister{ctrl: ctrl}
+	mock.recorder = &MockResourceMetadataListerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockResourceMetadataLister) EXPECT() *MockResourceMetadataListerMockRecorder {
+	return m.recorder
+}
+
+// ListWithMetadata mocks base method.
+func (m *MockResourceMetadataLister) ListWithMetadata(arg0 *source.Request) ([]*source.ResourceInfo, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListWithMetadata", arg0)
+	ret0, _ := ret[0].([]*source.ResourceInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListWithMetadata indicates an expected call of ListWithMetadata.
+func (mr *MockResourceMetadataListerMockRecorder) ListWithMetadata(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListWithMetadata", reflect.TypeOf((*MockResourceMetadataLister)(nil).ListWithMetadata), arg0)
+}
+
+// MockRangeReader is a mock of RangeReader interface.
+type MockRangeReader struct {
+	ctrl     *gomock.Controller
+	recorder *MockRangeReaderMockRecorder
+}
+
+// MockRangeReaderMockRecorder is the mock recorder for MockRangeReader.
+type MockRangeReaderMockRecorder struct {
+	mock *MockRangeReader
+}
+
+// NewMockRangeReader creates a new mock instance.
+func NewMockRangeReader(ctrl *gomock.Controller) *MockRangeReader {
+	mock := &MockRangeReader{ctrl: ctrl}
+	mock.recorder = &MockRangeReaderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRangeReader) EXPECT() *MockRangeReaderMockRecorder {
+	return m.recorder
+}
+
+// DownloadRange mocks base method.
+func (m *MockRangeReader) DownloadRange(arg0 *source.Request, arg1 []rangeutils.Range) ([]*source.Response, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DownloadRange", arg0, arg1)
+	ret0, _ := ret[0].([]*source.Response)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DownloadRange indicates an expected call of DownloadRange.
+func (mr *MockRangeReaderMockRecorder) DownloadRange(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DownloadRange", reflect.TypeOf((*MockRangeReader)(nil).DownloadRange), arg0, arg1)
+}
@@ -0,0 +1,316 @@
+/*
+ *     Copyright 2020 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ociprotocol
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/go-http-utils/headers"
+	"github.com/pkg/errors"
+
+	"d7y.io/dragonfly/v2/pkg/source"
+)
+
+const OCIClient = "oci"
+
+var _ source.ResourceClient = (*ociSourceClient)(nil)
+
+func init() {
+	if err := source.Register(OCIClient, NewOCISourceClient(), adaptor); err != nil {
+		panic(err)
+	}
+}
+
+func adaptor(request *source.Request) *source.Request {
+	clonedRequest := request.Clone(request.Context())
+	if request.Header.Get(source.Range) != "" {
+		clonedRequest.Header.Set(headers.Range, fmt.Sprintf("bytes=%s", request.Header.Get(source.Range)))
+		clonedRequest.Header.Del(source.Range)
+	}
+	clonedRequest.Header.Del(source.LastModified)
+	clonedRequest.Header.Del(source.ETag)
+	return clonedRequest
+}
+
+func NewOCISourceClient(opts ...OCISourceClientOption) source.ResourceClient {
+	return newOCISourceClient(opts...)
+}
+
+func newOCISourceClient(opts ...OCISourceClientOption) *ociSourceClient {
+	sourceClient := &ociSourceClient{
+		scheme:     "https",
+		httpClient: http.DefaultClient,
+		tokens:     map[string]string{},
+	}
+	for i := range opts {
+		opts[i](sourceClient)
+	}
+	return sourceClient
+}
+
+type OCISourceClientOption func(p *ociSourceClient)
+
+// WithHTTPClient sets the http client to access registries
+func WithHTTPClient(client *http.Client) OCISourceClientOption {
+	return func(sourceClient *ociSourceClient) {
+		sourceClient.httpClient = client
+	}
+}
+
+// WithPlainHTTP accesses registries with http instead of https
+func WithPlainHTTP(plainHTTP bool) OCISourceClientOption {
+	return func(sourceClient *ociSourceClient) {
+		if plainHTTP {
+			sourceClient.scheme = "http"
+		} else {
+			sourceClient.scheme = "https"
+		}
+	}
+}
+
+// WithBasicAuth sets the username and password to request registry tokens,
+// anonymous tokens are requested if it is not set
+func WithBasicAuth(username, password string) OCISourceClientOption {
+	return func(sourceClient *ociSourceClient) {
+		sourceClient.username = username
+		sourceClient.password = password
+	}
+}
+
+// ociSourceClient is an implementation of the interface of source.ResourceClient,
+// it pulls blobs pinned by digest like oci://registry/repository@sha256:digest.
+type ociSourceClient struct {
+	scheme     string
+	httpClient *http.Client
+	username   string
+	password   string
+
+	// tokens caches the bearer token by repository
+	mu     sync.RWMutex
+	tokens map[string]string
+}
+
+// blob is the blob in registry of the url
+type blob struct {
+	registry   string
+	repository string
+	digest     string
+}
+
+// tokenResponse is the response of registry token server
+type tokenResponse struct {
+	Token       string `json:"token"`
+	AccessToken string `json:"access_token"`
+}
+
+func (osc *ociSourceClient) GetContentLength(request *source.Request) (int64, error) {
+	resp, err := osc.doRequest(request, http.MethodHead)
+	if err != nil {
+		return source.UnknownSourceFileLen, err
+	}
+	resp.Body.Close()
+	return resp.ContentLength, nil
+}
+
+func (osc *ociSourceClient) IsSupportRange(request *source.Request) (bool, error) {
+	resp, err := osc.doRequest(request, http.MethodHead)
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+	return true, nil
+}
+
+// IsExpired always returns false, the blob is pinned by digest
+func (osc *ociSourceClient) IsExpired(request *source.Request, info *source.ExpireInfo) (bool, error) {
+	return false, nil
+}
+
+func (osc *ociSourceClient) Download(request *source.Request) (*source.Response, error) {
+	resp, err := osc.doRequest(request, http.MethodGet)
+	if err != nil {
+		return nil, err
+	}
+	return source.NewResponse(
+		resp.Body,
+		source.WithStatus(resp.StatusCode, resp.Status),
+		source.WithContentLength(resp.ContentLength),
+	), nil
+}
+
+func (osc *ociSourceClient) GetLastModified(request *source.Request) (int64, error) {
+	return -1, nil
+}
+
+// doRequest requests the blob, the registry token is requested and the request is retried when it is unauthorized
+func (osc *ociSourceClient) doRequest(request *source.Request, method string) (*http.Response, error) {
+	b, err := parseBlob(request.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	blobURL := fmt.Sprintf("%s://%s/v2/%s/blobs/%s", osc.scheme, b.registry, b.repository, b.digest)
+	resp, err := osc.do(request, method, blobURL, osc.getToken(b.repository))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		challenge := resp.Header.Get(headers.WWWAuthenticate)
+		resp.Body.Close()
+		token, err := osc.fetchToken(request, b.repository, challenge)
+		if err != nil {
+			return nil, err
+		}
+		if resp, err = osc.do(request, method, blobURL, token); err != nil {
+			return nil, err
+		}
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, errors.Wrapf(source.ErrResourceNotReachable, "oci blob %s", request.URL)
+	}
+	if err := source.CheckResponseCode(resp.StatusCode, []int{http.StatusOK, http.StatusPartialContent}); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (osc *ociSourceClient) do(request *source.Request, method, blobURL, token string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(request.Context(), method, blobURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range request.Header {
+		for i := range values {
+			req.Header.Add(key, values[i])
+		}
+	}
+	if token != "" {
+		req.Header.Set(headers.Authorization, "Bearer "+token)
+	}
+	return osc.httpClient.Do(req)
+}
+
+func (osc *ociSourceClient) getToken(repository string) string {
+	osc.mu.RLock()
+	defer osc.mu.RUnlock()
+	return osc.tokens[repository]
+}
+
+// fetchToken requests a bearer token from the token server in the challenge of registry
+func (osc *ociSourceClient) fetchToken(request *source.Request, repository, challenge string) (string, error) {
+	scheme, params := parseChallenge(challenge)
+	if !strings.EqualFold(scheme, "bearer") || params["realm"] == "" {
+		return "", errors.Errorf("unsupported registry challenge %q", challenge)
+	}
+
+	tokenURL, err := url.Parse(params["realm"])
+	if err != nil {
+		return "", errors.Wrapf(err, "parse token realm %s", params["realm"])
+	}
+	query := tokenURL.Query()
+	if params["service"] != "" {
+		query.Set("service", params["service"])
+	}
+	scope := params["scope"]
+	if scope == "" {
+		scope = fmt.Sprintf("repository:%s:pull", repository)
+	}
+	query.Set("scope", scope)
+	tokenURL.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(request.Context(), http.MethodGet, tokenURL.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if osc.username != "" {
+		req.SetBasicAuth(osc.username, osc.password)
+	}
+	resp, err := osc.httpClient.Do(req)
+	if err != nil {
+		return "", errors.Wrap(err, "request registry token")
+	}
+	defer resp.Body.Close()
+	if err := source.CheckResponseCode(resp.StatusCode, []int{http.StatusOK}); err != nil {
+		return "", err
+	}
+
+	tr := &tokenResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(tr); err != nil {
+		return "", errors.Wrap(err, "decode registry token")
+	}
+	token := tr.Token
+	if token == "" {
+		token = tr.AccessToken
+	}
+	if token == "" {
+		return "", errors.New("empty registry token")
+	}
+
+	osc.mu.Lock()
+	osc.tokens[repository] = token
+	osc.mu.Unlock()
+	return token, nil
+}
+
+// parseBlob parses url like oci://registry/repository@sha256:digest
+func parseBlob(u *url.URL) (*blob, error) {
+	repository, digest, ok := cut(strings.TrimPrefix(u.Path, "/"), "@")
+	if !ok {
+		return nil, errors.Errorf("oci url %s is not pinned by digest", u)
+	}
+	if u.Host == "" || repository == "" || !strings.Contains(digest, ":") {
+		return nil, errors.Errorf("invalid oci url %s, expect oci://registry/repository@digest", u)
+	}
+	return &blob{
+		registry:   u.Host,
+		repository: repository,
+		digest:     digest,
+	}, nil
+}
+
+// parseChallenge parses the WWW-Authenticate header like Bearer realm="...",service="...",scope="..."
+func parseChallenge(challenge string) (string, map[string]string) {
+	scheme, rest, _ := cut(strings.TrimSpace(challenge), " ")
+	params := map[string]string{}
+	for rest != "" {
+		var key, value string
+		key, rest, _ = cut(strings.TrimLeft(rest, ", "), "=")
+		if strings.HasPrefix(rest, `"`) {
+			value, rest, _ = cut(rest[1:], `"`)
+		} else {
+			value, rest, _ = cut(rest, ",")
+		}
+		params[strings.ToLower(strings.TrimSpace(key))] = value
+	}
+	return scheme, params
+}
+
+func cut(s, sep string) (before, after string, found bool) {
+	if i := strings.Index(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
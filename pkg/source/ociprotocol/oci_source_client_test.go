@@ -0,0 +1,188 @@
+/*
+ *     Copyright 2020 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ociprotocol
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-http-utils/headers"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/atomic"
+
+	"d7y.io/dragonfly/v2/pkg/source"
+)
+
+const (
+	testContent = "hello dragonfly"
+	testDigest  = "sha256:2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae"
+	testToken   = "token"
+)
+
+// newTestServer serves the blob of library/alpine which requires a token
+func newTestServer(tokenRequests *atomic.Int32) *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			tokenRequests.Inc()
+			if r.URL.Query().Get("scope") != "repository:library/alpine:pull" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			fmt.Fprintf(w, `{"token":"%s"}`, testToken)
+			return
+		}
+
+		if r.Header.Get(headers.Authorization) != "Bearer "+testToken {
+			w.Header().Set(headers.WWWAuthenticate,
+				fmt.Sprintf(`Bearer realm="%s/token",service="registry",scope="repository:library/alpine:pull"`, server.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if r.URL.Path != "/v2/library/alpine/blobs/"+testDigest {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		http.ServeContent(w, r, "", time.Time{}, strings.NewReader(testContent))
+	}))
+	return server
+}
+
+func newTestRequest(t *testing.T, server *httptest.Server, path string, header map[string]string) *source.Request {
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request, err := source.NewRequestWithHeader(fmt.Sprintf("oci://%s/%s", u.Host, path), header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return adaptor(request)
+}
+
+func TestOCISourceClient_GetContentLength(t *testing.T) {
+	tokenRequests := atomic.NewInt32(0)
+	server := newTestServer(tokenRequests)
+	defer server.Close()
+	client := newOCISourceClient(WithPlainHTTP(true), WithHTTPClient(server.Client()))
+
+	tests := []struct {
+		name   string
+		path   string
+		expect func(t *testing.T, length int64, err error)
+	}{
+		{
+			name: "get content length",
+			path: "library/alpine@" + testDigest,
+			expect: func(t *testing.T, length int64, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Equal(length, int64(len(testContent)))
+			},
+		},
+		{
+			name: "blob does not exist",
+			path: "library/alpine@sha256:foo",
+			expect: func(t *testing.T, length int64, err error) {
+				assert := assert.New(t)
+				assert.True(source.IsResourceNotReachableError(err))
+				assert.Equal(length, int64(source.UnknownSourceFileLen))
+			},
+		},
+		{
+			name: "blob is not pinned by digest",
+			path: "library/alpine:latest",
+			expect: func(t *testing.T, length int64, err error) {
+				assert := assert.New(t)
+				assert.Error(err)
+				assert.Equal(length, int64(source.UnknownSourceFileLen))
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			length, err := client.GetContentLength(newTestRequest(t, server, tc.path, nil))
+			tc.expect(t, length, err)
+		})
+	}
+}
+
+func TestOCISourceClient_Download(t *testing.T) {
+	tokenRequests := atomic.NewInt32(0)
+	server := newTestServer(tokenRequests)
+	defer server.Close()
+	client := newOCISourceClient(WithPlainHTTP(true), WithHTTPClient(server.Client()))
+
+	tests := []struct {
+		name   string
+		header map[string]string
+		expect string
+	}{
+		{
+			name:   "download blob",
+			expect: testContent,
+		},
+		{
+			name:   "download blob with range",
+			header: map[string]string{source.Range: "6-14"},
+			expect: testContent[6:],
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			resp, err := client.Download(newTestRequest(t, server, "library/alpine@"+testDigest, tc.header))
+			assert.NoError(err)
+			defer resp.Body.Close()
+			data, err := io.ReadAll(resp.Body)
+			assert.NoError(err)
+			assert.Equal(string(data), tc.expect)
+		})
+	}
+
+	// the token is cached after the first handshake
+	assert.Equal(t, tokenRequests.Load(), int32(1))
+}
+
+func TestOCISourceClient_IsExpired(t *testing.T) {
+	assert := assert.New(t)
+	client := newOCISourceClient()
+	request, err := source.NewRequest("oci://registry/library/alpine@" + testDigest)
+	assert.NoError(err)
+
+	expired, err := client.IsExpired(request, &source.ExpireInfo{})
+	assert.NoError(err)
+	assert.False(expired)
+}
+
+func TestParseChallenge(t *testing.T) {
+	assert := assert.New(t)
+	scheme, params := parseChallenge(`Bearer realm="https://auth.docker.io/token",service="registry.docker.io",scope="repository:library/alpine:pull"`)
+	assert.Equal(scheme, "Bearer")
+	assert.Equal(params["realm"], "https://auth.docker.io/token")
+	assert.Equal(params["service"], "registry.docker.io")
+	assert.Equal(params["scope"], "repository:library/alpine:pull")
+}
@@ -18,6 +18,9 @@ package source
 
 import (
 	"errors"
+	"fmt"
+	"os"
+	"strings"
 
 	logger "d7y.io/dragonfly/v2/internal/dflog"
 	"d7y.io/dragonfly/v2/internal/dfplugin"
@@ -27,6 +30,11 @@ const (
 	pluginMetadataScheme = "scheme"
 )
 
+// PluginAdapter is implemented by the ResourceClient of source plugin to adapt requests
+type PluginAdapter interface {
+	Adapter(request *Request) *Request
+}
+
 func LoadPlugin(dir, scheme string) (ResourceClient, error) {
 	// TODO init option
 	logger.Debugf("try to load source plugin: %s", scheme)
@@ -50,3 +58,59 @@ func LoadPlugin(dir, scheme string) (ResourceClient, error) {
 	logger.Debugf("loaded source plugin %s", scheme)
 	return rc, nil
 }
+
+// loadAllPlugins loads all resource plugins in dir, returns the loaded clients keyed by scheme
+// and the errors of plugins which are broken
+func loadAllPlugins(dir string) (map[string]ResourceClient, []error) {
+	files, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, []error{err}
+	}
+
+	var (
+		clients = map[string]ResourceClient{}
+		errs    []error
+	)
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+
+		subs := dfplugin.PluginFormatExpr.FindStringSubmatch(file.Name())
+		if len(subs) != 3 || subs[1] != string(dfplugin.PluginTypeResource) {
+			continue
+		}
+
+		scheme := subs[2]
+		rc, err := LoadPlugin(dir, scheme)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("plugin %s: %w", file.Name(), err))
+			continue
+		}
+
+		if _, ok := rc.(PluginAdapter); !ok {
+			errs = append(errs, fmt.Errorf("plugin %s: invalid client, not a PluginAdapter", file.Name()))
+			continue
+		}
+
+		clients[scheme] = rc
+	}
+
+	return clients, errs
+}
+
+// aggregateErrors joins errors into one error
+func aggregateErrors(errs []error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+
+	msgs := make([]string, 0, len(errs))
+	for _, err := range errs {
+		msgs = append(msgs, err.Error())
+	}
+	return fmt.Errorf("load source plugins failed: %s", strings.Join(msgs, "; "))
+}
@@ -73,3 +73,60 @@ func Test_loadPlugin(t *testing.T) {
 		return
 	}
 }
+
+func TestLoadAllPlugins(t *testing.T) {
+	tests := []struct {
+		name   string
+		files  []string
+		expect func(t *testing.T, schemes []string, err error)
+	}{
+		{
+			name: "plugin directory is empty",
+			expect: func(t *testing.T, schemes []string, err error) {
+				assert := testifyassert.New(t)
+				assert.NoError(err)
+				assert.Empty(schemes)
+			},
+		},
+		{
+			name:  "skip files which are not resource plugins",
+			files: []string{"README.md", "d7y-scheduler-plugin-evaluator.so"},
+			expect: func(t *testing.T, schemes []string, err error) {
+				assert := testifyassert.New(t)
+				assert.NoError(err)
+				assert.Empty(schemes)
+			},
+		},
+		{
+			name:  "resource plugin is broken",
+			files: []string{"d7y-resource-plugin-broken.so"},
+			expect: func(t *testing.T, schemes []string, err error) {
+				assert := testifyassert.New(t)
+				assert.Error(err)
+				assert.Contains(err.Error(), "d7y-resource-plugin-broken.so")
+				assert.Empty(schemes)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, file := range tc.files {
+				if err := os.WriteFile(path.Join(dir, file), []byte("broken"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			schemes, err := NewManager().LoadAllPlugins(dir)
+			tc.expect(t, schemes, err)
+		})
+	}
+}
+
+func TestLoadAllPlugins_DirNotExist(t *testing.T) {
+	assert := testifyassert.New(t)
+	schemes, err := NewManager().LoadAllPlugins(path.Join(t.TempDir(), "not-exist"))
+	assert.NoError(err)
+	assert.Empty(schemes)
+}
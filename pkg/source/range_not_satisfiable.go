@@ -0,0 +1,55 @@
+/*
+ *     Copyright 2022 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package source
+
+import (
+	"github.com/pkg/errors"
+)
+
+// RangeNotSatisfiableError is returned when source can not satisfy the requested range, eg: http 416,
+// the whole resource can be downloaded again without the range
+type RangeNotSatisfiableError struct {
+	err           error
+	contentLength int64
+}
+
+// NewRangeNotSatisfiableError returns RangeNotSatisfiableError which wraps err with the content length
+// reported by source, contentLength is -1 when it is unknown
+func NewRangeNotSatisfiableError(err error, contentLength int64) *RangeNotSatisfiableError {
+	return &RangeNotSatisfiableError{err: err, contentLength: contentLength}
+}
+
+// Error implements interface error
+func (e *RangeNotSatisfiableError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the wrapped error, eg: UnexpectedStatusCodeError
+func (e *RangeNotSatisfiableError) Unwrap() error {
+	return e.err
+}
+
+// ContentLength is the length of the whole resource reported by source, -1 when it is unknown
+func (e *RangeNotSatisfiableError) ContentLength() int64 {
+	return e.contentLength
+}
+
+// IsRangeNotSatisfiable returns whether there is RangeNotSatisfiableError in err chain
+func IsRangeNotSatisfiable(err error) bool {
+	var e *RangeNotSatisfiableError
+	return errors.As(err, &e)
+}
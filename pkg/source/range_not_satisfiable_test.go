@@ -0,0 +1,45 @@
+/*
+ *     Copyright 2022 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package source
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	testifyassert "github.com/stretchr/testify/assert"
+)
+
+func TestRangeNotSatisfiableError(t *testing.T) {
+	assert := testifyassert.New(t)
+	statusCodeErr := CheckResponseCode(http.StatusRequestedRangeNotSatisfiable, []int{http.StatusOK, http.StatusPartialContent})
+	err := fmt.Errorf("download: %w", NewRangeNotSatisfiableError(statusCodeErr, 1024))
+
+	assert.True(IsRangeNotSatisfiable(err))
+	assert.Equal("download: "+statusCodeErr.Error(), err.Error())
+	var e *RangeNotSatisfiableError
+	assert.True(errors.As(err, &e))
+	assert.Equal(int64(1024), e.ContentLength())
+	code, ok := statusCodeOf(err)
+	assert.True(ok)
+	assert.Equal(http.StatusRequestedRangeNotSatisfiable, code)
+
+	assert.False(IsRangeNotSatisfiable(statusCodeErr))
+	assert.False(IsRangeNotSatisfiable(errors.New("foo")))
+	assert.False(IsRangeNotSatisfiable(nil))
+}
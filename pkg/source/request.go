@@ -19,13 +19,20 @@ package source
 import (
 	"context"
 	"net/url"
+	"path"
+	"strings"
 
 	"github.com/pkg/errors"
+
+	"d7y.io/dragonfly/v2/pkg/util/rangeutils"
 )
 
 type Request struct {
 	URL    *url.URL
 	Header Header
+	// Ranges are the sorted and disjoint byte ranges downloaded by Download,
+	// the body of response yields the ranges in order
+	Ranges []rangeutils.Range
 	// ctx is either the client or server context. It should only
 	// be modified via copying the whole Request using WithContext.
 	// It is unexported to prevent people from using Context wrong
@@ -145,5 +152,30 @@ func (r *Request) Clone(ctx context.Context) *Request {
 	if r.Header != nil {
 		r2.Header = r.Header.Clone()
 	}
+	if r.Ranges != nil {
+		r2.Ranges = append([]rangeutils.Range(nil), r.Ranges...)
+	}
 	return r2
 }
+
+// NormalizePath removes the duplicate slashes and the dot segments of the path of u in place,
+// the trailing slash and the escaped characters like %2F are kept as they are
+func NormalizePath(u *url.URL) {
+	escaped := u.EscapedPath()
+	if escaped == "" {
+		return
+	}
+	normalized := path.Clean(escaped)
+	if strings.HasSuffix(escaped, "/") && normalized != "/" {
+		normalized += "/"
+	}
+	if normalized == escaped {
+		return
+	}
+	unescaped, err := url.PathUnescape(normalized)
+	if err != nil {
+		return
+	}
+	u.Path = unescaped
+	u.RawPath = normalized
+}
@@ -84,3 +84,26 @@ func TestRequest_Clone(t *testing.T) {
 		ctx:    testContext,
 	}, got)
 }
+
+func TestNormalizePath(t *testing.T) {
+	tests := []struct {
+		rawURL string
+		expect string
+	}{
+		{rawURL: "http://www.dragonfly.io", expect: "http://www.dragonfly.io"},
+		{rawURL: "http://www.dragonfly.io/", expect: "http://www.dragonfly.io/"},
+		{rawURL: "http://www.dragonfly.io//a///b", expect: "http://www.dragonfly.io/a/b"},
+		{rawURL: "http://www.dragonfly.io/a/./b/../c/", expect: "http://www.dragonfly.io/a/c/"},
+		{rawURL: "http://www.dragonfly.io/a//b%2Fc?x=1", expect: "http://www.dragonfly.io/a/b%2Fc?x=1"},
+		{rawURL: "http://www.dragonfly.io/a%20b//c", expect: "http://www.dragonfly.io/a%20b/c"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.rawURL, func(t *testing.T) {
+			u, err := url.Parse(tc.rawURL)
+			assert.Nil(t, err)
+			NormalizePath(u)
+			assert.Equal(t, tc.expect, u.String())
+		})
+	}
+}
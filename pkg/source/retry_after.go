@@ -0,0 +1,87 @@
+/*
+ *     Copyright 2022 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package source
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// RetryAfterError is returned when source responds with a Retry-After header,
+// the request should be retried after the suggested wait
+type RetryAfterError struct {
+	err        error
+	retryAfter time.Duration
+}
+
+// NewRetryAfterError returns RetryAfterError which wraps err with the suggested wait
+func NewRetryAfterError(err error, retryAfter time.Duration) *RetryAfterError {
+	return &RetryAfterError{err: err, retryAfter: retryAfter}
+}
+
+// Error implements interface error
+func (e *RetryAfterError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the wrapped error, eg: UnexpectedStatusCodeError
+func (e *RetryAfterError) Unwrap() error {
+	return e.err
+}
+
+// RetryAfter is the wait suggested by source
+func (e *RetryAfterError) RetryAfter() time.Duration {
+	return e.retryAfter
+}
+
+// RetryAfterOf returns the wait suggested by source in err chain
+func RetryAfterOf(err error) (time.Duration, bool) {
+	var e *RetryAfterError
+	if !errors.As(err, &e) {
+		return 0, false
+	}
+	return e.retryAfter, true
+}
+
+// ParseRetryAfter parses Retry-After header value in delta-seconds or HTTP-date form,
+// the wait of HTTP-date in the past is 0
+func ParseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	if wait := date.Sub(now); wait > 0 {
+		return wait, true
+	}
+	return 0, true
+}
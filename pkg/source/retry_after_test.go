@@ -0,0 +1,68 @@
+/*
+ *     Copyright 2022 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package source
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	testifyassert "github.com/stretchr/testify/assert"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value  string
+		expect time.Duration
+		ok     bool
+	}{
+		{value: "120", expect: 120 * time.Second, ok: true},
+		{value: " 0 ", expect: 0, ok: true},
+		{value: now.Add(time.Minute).Format(http.TimeFormat), expect: time.Minute, ok: true},
+		{value: now.Add(-time.Minute).Format(http.TimeFormat), expect: 0, ok: true},
+		{value: "-1", ok: false},
+		{value: "foo", ok: false},
+		{value: "", ok: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.value, func(t *testing.T) {
+			assert := testifyassert.New(t)
+			retryAfter, ok := ParseRetryAfter(tc.value, now)
+			assert.Equal(tc.ok, ok)
+			assert.Equal(tc.expect, retryAfter)
+		})
+	}
+}
+
+func TestRetryAfterError(t *testing.T) {
+	assert := testifyassert.New(t)
+	statusCodeErr := CheckResponseCode(http.StatusTooManyRequests, []int{http.StatusOK})
+	err := fmt.Errorf("download: %w", NewRetryAfterError(statusCodeErr, time.Minute))
+
+	retryAfter, ok := RetryAfterOf(err)
+	assert.True(ok)
+	assert.Equal(time.Minute, retryAfter)
+	assert.True(IsTooManyRequests(err))
+	assert.Equal("download: "+statusCodeErr.Error(), err.Error())
+
+	_, ok = RetryAfterOf(errors.New("foo"))
+	assert.False(ok)
+}
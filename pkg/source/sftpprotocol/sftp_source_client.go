@@ -0,0 +1,433 @@
+/*
+ *     Copyright 2022 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sftpprotocol
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	logger "d7y.io/dragonfly/v2/internal/dflog"
+	"d7y.io/dragonfly/v2/pkg/source"
+	"d7y.io/dragonfly/v2/pkg/util/digestutils"
+	"d7y.io/dragonfly/v2/pkg/util/rangeutils"
+	"d7y.io/dragonfly/v2/pkg/util/stringutils"
+	"d7y.io/dragonfly/v2/pkg/util/timeutils"
+)
+
+const (
+	SFTPClient = "sftp"
+)
+
+const (
+	// password is the header of ssh password, the password in url takes precedence
+	password = "password"
+	// privateKey is the header of PEM encoded ssh private key
+	privateKey = "privateKey"
+	// privateKeyPassphrase is the header of the passphrase of encrypted private key
+	privateKeyPassphrase = "privateKeyPassphrase"
+
+	defaultPort        = "22"
+	defaultDialTimeout = 30 * time.Second
+)
+
+func init() {
+	if err := source.Register(SFTPClient, NewSFTPSourceClient(), adapter); err != nil {
+		panic(err)
+	}
+}
+
+func adapter(request *source.Request) *source.Request {
+	clonedRequest := request.Clone(request.Context())
+	return clonedRequest
+}
+
+// connection is a sftp connection shared by the requests of the same host and credential,
+// it's closed when the last reference is released
+type connection struct {
+	key    string
+	client *sftp.Client
+	close  func() error
+	refs   int
+}
+
+// sftpSourceClient is an implementation of the interface of source.ResourceClient.
+type sftpSourceClient struct {
+	sync.Mutex
+	connections map[string]*connection
+
+	dialTimeout     time.Duration
+	hostKeyCallback ssh.HostKeyCallback
+
+	// dial connects to addr, replaced in tests
+	dial func(addr string, config *ssh.ClientConfig) (*connection, error)
+}
+
+type SFTPSourceClientOption func(p *sftpSourceClient)
+
+// WithDialTimeout sets the timeout of establishing ssh connection
+func WithDialTimeout(timeout time.Duration) SFTPSourceClientOption {
+	return func(p *sftpSourceClient) {
+		p.dialTimeout = timeout
+	}
+}
+
+// WithHostKeyCallback sets the callback to verify host key of server,
+// the host keys in ~/.ssh/known_hosts are used by default
+func WithHostKeyCallback(callback ssh.HostKeyCallback) SFTPSourceClientOption {
+	return func(p *sftpSourceClient) {
+		p.hostKeyCallback = callback
+	}
+}
+
+func NewSFTPSourceClient(opts ...SFTPSourceClientOption) source.ResourceClient {
+	return newSFTPSourceClient(opts...)
+}
+
+func newSFTPSourceClient(opts ...SFTPSourceClientOption) *sftpSourceClient {
+	sourceClient := &sftpSourceClient{
+		connections: make(map[string]*connection),
+		dialTimeout: defaultDialTimeout,
+		dial:        dial,
+	}
+	for i := range opts {
+		opts[i](sourceClient)
+	}
+	return sourceClient
+}
+
+var _ source.ResourceClient = (*sftpSourceClient)(nil)
+
+func (s *sftpSourceClient) GetContentLength(request *source.Request) (int64, error) {
+	info, err := s.stat(request)
+	if err != nil {
+		return source.UnknownSourceFileLen, err
+	}
+	return info.Size(), nil
+}
+
+func (s *sftpSourceClient) IsSupportRange(request *source.Request) (bool, error) {
+	if _, err := s.stat(request); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *sftpSourceClient) IsExpired(request *source.Request, info *source.ExpireInfo) (bool, error) {
+	fileInfo, err := s.stat(request)
+	if err != nil {
+		return false, err
+	}
+	return fileInfo.ModTime().Format(source.LastModifiedLayout) != info.LastModified, nil
+}
+
+func (s *sftpSourceClient) GetLastModified(request *source.Request) (int64, error) {
+	info, err := s.stat(request)
+	if err != nil {
+		return -1, err
+	}
+	return info.ModTime().UnixNano() / time.Millisecond.Nanoseconds(), nil
+}
+
+func (s *sftpSourceClient) Download(request *source.Request) (*source.Response, error) {
+	conn, err := s.acquire(request)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := conn.client.Open(request.URL.Path)
+	if err != nil {
+		s.release(conn)
+		return nil, errors.Wrapf(err, "open sftp file %s", request.URL.Path)
+	}
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		file.Close()
+		s.release(conn)
+		return nil, errors.Wrapf(err, "stat sftp file %s", request.URL.Path)
+	}
+
+	// default read all data when rang is nil
+	limitReadN := fileInfo.Size()
+	if request.Header.Get(source.Range) != "" {
+		requestRange, err := rangeutils.ParseRange(request.Header.Get(source.Range), uint64(limitReadN))
+		if err != nil {
+			file.Close()
+			s.release(conn)
+			return nil, err
+		}
+		if _, err = file.Seek(int64(requestRange.StartIndex), io.SeekStart); err != nil {
+			file.Close()
+			s.release(conn)
+			return nil, err
+		}
+		limitReadN = int64(requestRange.Length())
+	}
+
+	response := source.NewResponse(
+		newSFTPFileReadCloser(request.Context(), file, limitReadN, func() { s.release(conn) }),
+		source.WithContentLength(limitReadN),
+		source.WithExpireInfo(source.ExpireInfo{
+			LastModified: timeutils.Format(fileInfo.ModTime()),
+		}))
+	return response, nil
+}
+
+// stat returns the file info of request url
+func (s *sftpSourceClient) stat(request *source.Request) (os.FileInfo, error) {
+	conn, err := s.acquire(request)
+	if err != nil {
+		return nil, err
+	}
+	defer s.release(conn)
+
+	info, err := conn.client.Stat(request.URL.Path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "stat sftp file %s", request.URL.Path)
+	}
+	return info, nil
+}
+
+// acquire returns the connection of request host and credential, a new one is dialed when absent,
+// every acquired connection must be released
+func (s *sftpSourceClient) acquire(request *source.Request) (*connection, error) {
+	config, err := s.newClientConfig(request)
+	if err != nil {
+		return nil, err
+	}
+
+	addr := hostAddr(request.URL)
+	key := connectionKey(addr, config.User, request)
+
+	s.Lock()
+	defer s.Unlock()
+	if conn, ok := s.connections[key]; ok {
+		conn.refs++
+		return conn, nil
+	}
+
+	conn, err := s.dial(addr, config)
+	if err != nil {
+		return nil, err
+	}
+	conn.key = key
+	conn.refs = 1
+	s.connections[key] = conn
+	return conn, nil
+}
+
+// release releases the reference of connection, the connection is closed without references
+func (s *sftpSourceClient) release(conn *connection) {
+	s.Lock()
+	conn.refs--
+	if conn.refs > 0 {
+		s.Unlock()
+		return
+	}
+	if s.connections[conn.key] == conn {
+		delete(s.connections, conn.key)
+	}
+	s.Unlock()
+
+	if err := conn.close(); err != nil {
+		logger.Warnf("close sftp connection error: %s", err)
+	}
+}
+
+// newClientConfig returns ssh client config with the user and password in url,
+// and the password or private key in header
+func (s *sftpSourceClient) newClientConfig(request *source.Request) (*ssh.ClientConfig, error) {
+	var auths []ssh.AuthMethod
+	if key := request.Header.Get(privateKey); !stringutils.IsBlank(key) {
+		var (
+			signer ssh.Signer
+			err    error
+		)
+		if passphrase := request.Header.Get(privateKeyPassphrase); passphrase != "" {
+			signer, err = ssh.ParsePrivateKeyWithPassphrase([]byte(key), []byte(passphrase))
+		} else {
+			signer, err = ssh.ParsePrivateKey([]byte(key))
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "parse ssh private key")
+		}
+		auths = append(auths, ssh.PublicKeys(signer))
+	}
+
+	pass, ok := request.URL.User.Password()
+	if !ok {
+		pass = request.Header.Get(password)
+	}
+	if pass != "" {
+		auths = append(auths, ssh.Password(pass))
+	}
+
+	if len(auths) == 0 {
+		return nil, errors.New("sftp password and private key are empty")
+	}
+
+	username := request.URL.User.Username()
+	if username == "" {
+		u, err := user.Current()
+		if err != nil {
+			return nil, err
+		}
+		username = u.Username
+	}
+
+	hostKeyCallback := s.hostKeyCallback
+	if hostKeyCallback == nil {
+		var err error
+		if hostKeyCallback, err = defaultHostKeyCallback(); err != nil {
+			return nil, err
+		}
+	}
+
+	return &ssh.ClientConfig{
+		User:            username,
+		Auth:            auths,
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         s.dialTimeout,
+	}, nil
+}
+
+// defaultHostKeyCallback verifies host key with ~/.ssh/known_hosts
+func defaultHostKeyCallback() (ssh.HostKeyCallback, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+
+	callback, err := knownhosts.New(filepath.Join(home, ".ssh", "known_hosts"))
+	if err != nil {
+		return nil, errors.Wrap(err, "load ssh known hosts")
+	}
+	return callback, nil
+}
+
+// hostAddr returns host:port of url, the port is 22 when absent
+func hostAddr(u *url.URL) string {
+	port := u.Port()
+	if port == "" {
+		port = defaultPort
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
+
+// connectionKey identifies the connection by address, user and credential without keeping the secrets
+func connectionKey(addr, username string, request *source.Request) string {
+	pass, _ := request.URL.User.Password()
+	return digestutils.Sha256(addr, username, pass,
+		request.Header.Get(password), request.Header.Get(privateKey), request.Header.Get(privateKeyPassphrase))
+}
+
+// dial connects to addr with ssh and starts sftp subsystem
+func dial(addr string, config *ssh.ClientConfig) (*connection, error) {
+	sshClient, err := ssh.Dial("tcp", addr, config)
+	if err != nil {
+		return nil, errors.Wrapf(err, "dial ssh %s", addr)
+	}
+
+	sftpClient, err := sftp.NewClient(sshClient)
+	if err != nil {
+		sshClient.Close()
+		return nil, errors.Wrapf(err, "start sftp subsystem %s", addr)
+	}
+
+	return &connection{
+		client: sftpClient,
+		close: func() error {
+			err := sftpClient.Close()
+			if sshErr := sshClient.Close(); err == nil {
+				err = sshErr
+			}
+			return err
+		},
+	}, nil
+}
+
+// sftpFileReadCloser reads the file within limit, reading stops with the context error
+// and the file is closed when the context is done.
+// The connection reference is released after the file is closed.
+type sftpFileReadCloser struct {
+	ctx           context.Context
+	limitedReader io.Reader
+	closer        io.Closer
+	release       func()
+	closeOnce     sync.Once
+	closeErr      error
+	done          chan struct{}
+}
+
+func newSFTPFileReadCloser(ctx context.Context, r io.ReadCloser, n int64, release func()) io.ReadCloser {
+	rc := &sftpFileReadCloser{
+		ctx:           ctx,
+		limitedReader: io.LimitReader(r, n),
+		closer:        r,
+		release:       release,
+		done:          make(chan struct{}),
+	}
+
+	if ctx.Done() != nil {
+		go func() {
+			select {
+			case <-ctx.Done():
+				rc.close()
+			case <-rc.done:
+			}
+		}()
+	}
+	return rc
+}
+
+func (rc *sftpFileReadCloser) Read(p []byte) (n int, err error) {
+	if err := rc.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	n, err = rc.limitedReader.Read(p)
+	if err != nil && rc.ctx.Err() != nil {
+		// file is closed by the done context
+		return n, rc.ctx.Err()
+	}
+	return n, err
+}
+
+func (rc *sftpFileReadCloser) Close() error {
+	return rc.close()
+}
+
+func (rc *sftpFileReadCloser) close() error {
+	rc.closeOnce.Do(func() {
+		close(rc.done)
+		rc.closeErr = rc.closer.Close()
+		rc.release()
+	})
+	return rc.closeErr
+}
@@ -13,22 +13,27 @@
  * See the License for the specific language governing permissions and
  * limitations under the License.
  */
-//go:generate mockgen -destination ./mock/mock_source_client.go -package mock d7y.io/dragonfly/v2/pkg/source ResourceClient
+//go:generate mockgen -destination ./mock/mock_source_client.go -package mock d7y.io/dragonfly/v2/pkg/source ResourceClient,ResourceLister,ResourceMetadataLister,RangeReader
 
 package source
 
 import (
 	"context"
 	"fmt"
+	"io"
+	"net/http"
 	"net/url"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/pkg/errors"
+	"go.uber.org/atomic"
 
 	logger "d7y.io/dragonfly/v2/internal/dflog"
+	"d7y.io/dragonfly/v2/pkg/util/rangeutils"
 )
 
 var (
@@ -40,6 +45,9 @@ var (
 
 	// ErrClientNotSupportList represents the source client not support list action
 	ErrClientNotSupportList = errors.New("source client not support list")
+
+	// ErrClientNotSupportRangeRead represents the source client not support download multiple ranges
+	ErrClientNotSupportRangeRead = errors.New("source client not support range read")
 )
 
 // UnexpectedStatusCodeError is returned when a source responds with neither an error
@@ -75,6 +83,59 @@ func CheckResponseCode(respCode int, allowed []int) error {
 	return UnexpectedStatusCodeError{allowed, respCode}
 }
 
+// statusCodeOf returns the status code of UnexpectedStatusCodeError in err chain
+func statusCodeOf(err error) (int, bool) {
+	var e UnexpectedStatusCodeError
+	if !errors.As(err, &e) {
+		return 0, false
+	}
+	return e.Got(), true
+}
+
+// IsNotFound returns whether err is UnexpectedStatusCodeError with status code 404 or 410,
+// the resource is gone from source and retrying does not help
+func IsNotFound(err error) bool {
+	code, ok := statusCodeOf(err)
+	return ok && (code == http.StatusNotFound || code == http.StatusGone)
+}
+
+// IsUnauthorized returns whether err is UnexpectedStatusCodeError with status code 401 or 403
+func IsUnauthorized(err error) bool {
+	code, ok := statusCodeOf(err)
+	return ok && (code == http.StatusUnauthorized || code == http.StatusForbidden)
+}
+
+// IsTooManyRequests returns whether err is UnexpectedStatusCodeError with status code 429,
+// the source is busy and the request can be retried later
+func IsTooManyRequests(err error) bool {
+	code, ok := statusCodeOf(err)
+	return ok && code == http.StatusTooManyRequests
+}
+
+// IsServerError returns whether err is UnexpectedStatusCodeError with status code 5xx
+func IsServerError(err error) bool {
+	code, ok := statusCodeOf(err)
+	return ok && code >= http.StatusInternalServerError && code <= 599
+}
+
+// notReachableError is returned by Check, it is ErrResourceNotReachable and keeps the cause in err chain,
+// so that the callers can tell the reason like IsUnauthorized
+type notReachableError struct {
+	cause error
+}
+
+func (e *notReachableError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrResourceNotReachable, e.cause)
+}
+
+func (e *notReachableError) Is(target error) bool {
+	return target == ErrResourceNotReachable
+}
+
+func (e *notReachableError) Unwrap() error {
+	return e.cause
+}
+
 func IsResourceNotReachableError(err error) bool {
 	return errors.Is(err, ErrResourceNotReachable)
 }
@@ -85,6 +146,9 @@ func IsNoClientFoundError(err error) bool {
 
 const (
 	UnknownSourceFileLen = -2
+
+	// defaultPluginLoadCooldown is the default time to wait before loading a failed plugin again
+	defaultPluginLoadCooldown = 30 * time.Second
 )
 
 // ResourceClient defines the API interface to interact with source.
@@ -114,61 +178,225 @@ type ResourceLister interface {
 	List(request *Request) (urls []*url.URL, err error)
 }
 
+// ResourceInfo is the metadata of a listed resource
+type ResourceInfo struct {
+	URL *url.URL
+	// ContentLength is source.UnknownSourceFileLen when it is unknown
+	ContentLength int64
+	// LastModified is the last modified timestamp milliseconds, it is -1 when it is unknown
+	LastModified int64
+}
+
+// ResourceMetadataLister defines the interface to list resources with metadata in a batch,
+// it is implemented by the clients which get the metadata for free during listing like hdfs ReadDir,
+// so that callers like preheat avoid requesting the content length of every resource.
+// ListWithMetadata falls back to List and GetContentLength for the other listers
+type ResourceMetadataLister interface {
+	ListWithMetadata(request *Request) ([]*ResourceInfo, error)
+}
+
+// RangeReader defines the interface to download multiple byte ranges of resource,
+// every range has its own response and the responses are in the order of ranges
+type RangeReader interface {
+	DownloadRange(request *Request, ranges []rangeutils.Range) ([]*Response, error)
+}
+
+// MultiRangeDownloader defines the interface to download Request.Ranges with a single response,
+// the body of response yields the ranges in order
+type MultiRangeDownloader interface {
+	DownloadMultiRange(request *Request) (*Response, error)
+}
+
+// ResourceChecker defines the interface to verify the connectivity and authorization of resource without downloading it,
+// the clients without it are checked by GetContentLength
+type ResourceChecker interface {
+	Check(request *Request) error
+}
+
+// ValidateRanges checks the ranges are sorted and do not overlap
+func ValidateRanges(ranges []rangeutils.Range) error {
+	for i, rg := range ranges {
+		if rg.EndIndex < rg.StartIndex {
+			return errors.Errorf("invalid range %s, start is larger than end", rg.String())
+		}
+		if i == 0 {
+			continue
+		}
+		prev := ranges[i-1]
+		if rg.StartIndex < prev.StartIndex {
+			return errors.Errorf("range %s is not sorted, it starts before range %s", rg.String(), prev.String())
+		}
+		if rg.StartIndex <= prev.EndIndex {
+			return errors.Errorf("range %s overlaps range %s", rg.String(), prev.String())
+		}
+	}
+	return nil
+}
+
 type ClientManager interface {
 	// Register a source client with scheme
-	Register(scheme string, resourceClient ResourceClient, adapter requestAdapter, hook ...Hook) error
+	Register(scheme string, resourceClient ResourceClient, adapter requestAdapter, opts ...RegisterOption) error
 
-	// UnRegister a source client from manager
+	// RegisterOrReplace a source client with scheme, the existing client will be replaced
+	RegisterOrReplace(scheme string, resourceClient ResourceClient, adapter requestAdapter, opts ...RegisterOption)
+
+	// UnRegister a source client from manager, the aliases of scheme are removed too
 	UnRegister(scheme string)
 
+	// Alias makes aliasScheme resolve to the client of targetScheme
+	Alias(aliasScheme, targetScheme string) error
+
 	// GetClient a source client by scheme
 	GetClient(scheme string, options ...Option) (ResourceClient, bool)
+
+	// LoadAllPlugins loads and registers all resource plugins in plugin directory,
+	// returns the registered schemes and the aggregate error of broken plugins
+	LoadAllPlugins(pluginDir string) ([]string, error)
+
+	// Stats returns the statistics of the schemes ever registered, aliases are not included
+	Stats() map[string]SchemeStats
+}
+
+// SchemeStats is the runtime statistics of the client of a scheme
+type SchemeStats struct {
+	// Registered is whether a client is registered for the scheme now
+	Registered bool
+	// Requests is the count of requests sent with the client, it's kept when the client is replaced
+	Requests int64
+	// Errors is the count of failed requests
+	Errors int64
+}
+
+// schemeStats counts the requests of a scheme with atomics, so that recording and reading
+// the statistics never contend with the lock of clientManager
+type schemeStats struct {
+	registered *atomic.Bool
+	requests   *atomic.Int64
+	errors     *atomic.Int64
+}
+
+func newSchemeStats() *schemeStats {
+	return &schemeStats{
+		registered: atomic.NewBool(false),
+		requests:   atomic.NewInt64(0),
+		errors:     atomic.NewInt64(0),
+	}
+}
+
+// record counts a request with its error and returns the error
+func (s *schemeStats) record(err error) error {
+	if s == nil {
+		return err
+	}
+	s.requests.Inc()
+	if err != nil {
+		s.errors.Inc()
+	}
+	return err
 }
 
 // clientManager implements the interface ClientManager
 type clientManager struct {
-	mu        sync.RWMutex
-	clients   map[string]ResourceClient
+	mu      sync.RWMutex
+	clients map[string]ResourceClient
+	// alias scheme -> target scheme
+	aliases   map[string]string
 	pluginDir string
+	// stats is scheme -> *schemeStats, it is not guarded by mu
+	stats sync.Map
+
+	// pluginLoadCooldown is the time to wait before loading a failed plugin again
+	pluginLoadCooldown time.Duration
+	// pluginLoadFailures is scheme -> the time of the last failed plugin loading
+	pluginLoadFailures map[string]time.Time
+	loadPlugin         func(dir, scheme string) (ResourceClient, error)
+	now                func() time.Time
 }
 
 var _ ClientManager = (*clientManager)(nil)
 
 var _defaultManager = NewManager()
 
-func NewManager() ClientManager {
-	return &clientManager{
-		clients: make(map[string]ResourceClient),
+func NewManager(options ...Option) ClientManager {
+	m := &clientManager{
+		clients:            make(map[string]ResourceClient),
+		aliases:            make(map[string]string),
+		pluginLoadCooldown: defaultPluginLoadCooldown,
+		pluginLoadFailures: make(map[string]time.Time),
+		loadPlugin:         LoadPlugin,
+		now:                time.Now,
+	}
+	for _, opt := range options {
+		opt(m)
 	}
+	return m
 }
 
 type Option func(c *clientManager)
 
+// WithPluginLoadCooldown sets the time to wait before loading the plugin of a scheme again after it fails,
+// GetClient returns false for the scheme in cooldown without loading plugin, it's disabled when cooldown is not positive
+func WithPluginLoadCooldown(cooldown time.Duration) Option {
+	return func(m *clientManager) {
+		m.pluginLoadCooldown = cooldown
+	}
+}
+
 func UpdatePluginDir(pluginDir string) {
-	_defaultManager.(*clientManager).pluginDir = pluginDir
+	m := _defaultManager.(*clientManager)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pluginDir = pluginDir
+	// the plugins may exist in the new directory
+	m.pluginLoadFailures = make(map[string]time.Time)
+}
+
+// UpdatePluginLoadCooldown updates the plugin load cooldown of default manager, see WithPluginLoadCooldown
+func UpdatePluginLoadCooldown(cooldown time.Duration) {
+	m := _defaultManager.(*clientManager)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pluginLoadCooldown = cooldown
 }
 
-func (m *clientManager) Register(scheme string, resourceClient ResourceClient, adaptor requestAdapter, hooks ...Hook) error {
+func (m *clientManager) Register(scheme string, resourceClient ResourceClient, adaptor requestAdapter, opts ...RegisterOption) error {
 	scheme = strings.ToLower(scheme)
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	if target, ok := m.aliases[scheme]; ok {
+		return errors.Errorf("scheme %s is an alias of %s", scheme, target)
+	}
 	if client, ok := m.clients[scheme]; ok {
-		if client.(*clientWrapper).rc != resourceClient {
+		if client.(*clientWrapper).origin != resourceClient {
 			return errors.Errorf("client with scheme %s already exist, current client: %#v", scheme, client)
 		}
 		logger.Warnf("client with scheme %s already exist, no need register again", scheme)
 		return nil
 	}
-	m.doRegister(scheme, &clientWrapper{
-		adapter: adaptor,
-		hooks:   hooks,
-		rc:      resourceClient,
-	})
+	m.doRegister(scheme, newClientWrapper(resourceClient, adaptor, opts))
 	return nil
 }
 
-func (m *clientManager) doRegister(scheme string, resourceClient ResourceClient) {
-	m.clients[strings.ToLower(scheme)] = resourceClient
+func (m *clientManager) RegisterOrReplace(scheme string, resourceClient ResourceClient, adaptor requestAdapter, opts ...RegisterOption) {
+	scheme = strings.ToLower(scheme)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if client, ok := m.clients[scheme]; ok {
+		logger.Infof("replace client %#v for scheme %s", client, scheme)
+	}
+	if target, ok := m.aliases[scheme]; ok {
+		logger.Infof("replace alias of %s for scheme %s", target, scheme)
+		delete(m.aliases, scheme)
+	}
+	m.doRegister(scheme, newClientWrapper(resourceClient, adaptor, opts))
+}
+
+func (m *clientManager) doRegister(scheme string, wrapper *clientWrapper) {
+	scheme = strings.ToLower(scheme)
+	stats, _ := m.stats.LoadOrStore(scheme, newSchemeStats())
+	wrapper.stats = stats.(*schemeStats)
+	wrapper.stats.registered.Store(true)
+	m.clients[scheme] = wrapper
 }
 
 func (m *clientManager) UnRegister(scheme string) {
@@ -179,17 +407,64 @@ func (m *clientManager) UnRegister(scheme string) {
 		logger.Infof("remove client %#v for scheme %s", client, scheme)
 	}
 	delete(m.clients, scheme)
+	delete(m.aliases, scheme)
+	if stats, ok := m.stats.Load(scheme); ok {
+		stats.(*schemeStats).registered.Store(false)
+	}
+
+	// remove the aliases which resolve to the removed scheme
+	var removed []string
+	for alias := range m.aliases {
+		if m.resolve(alias) == scheme {
+			removed = append(removed, alias)
+		}
+	}
+	for _, alias := range removed {
+		logger.Infof("remove alias %s of scheme %s", alias, scheme)
+		delete(m.aliases, alias)
+	}
+}
+
+func (m *clientManager) Alias(aliasScheme, targetScheme string) error {
+	aliasScheme = strings.ToLower(aliasScheme)
+	targetScheme = strings.ToLower(targetScheme)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.clients[aliasScheme]; ok {
+		return errors.Errorf("client with scheme %s already exist", aliasScheme)
+	}
+	if m.resolve(targetScheme) == aliasScheme {
+		return errors.Errorf("alias %s to %s makes a cycle", aliasScheme, targetScheme)
+	}
+	m.aliases[aliasScheme] = targetScheme
+	return nil
+}
+
+// resolve follows aliases and returns the target scheme, aliases never make a cycle
+func (m *clientManager) resolve(scheme string) string {
+	for {
+		target, ok := m.aliases[scheme]
+		if !ok {
+			return scheme
+		}
+		scheme = target
+	}
 }
 
 func (m *clientManager) GetClient(scheme string, options ...Option) (ResourceClient, bool) {
-	logger.Debugf("current clients: %#v", m.clients)
 	m.mu.RLock()
-	scheme = strings.ToLower(scheme)
+	logger.Debugf("current clients: %#v", m.clients)
+	scheme = m.resolve(strings.ToLower(scheme))
 	client, ok := m.clients[scheme]
 	if ok {
 		m.mu.RUnlock()
 		return client, true
 	}
+	// the plugin of scheme failed to load recently, return fast without the write lock
+	if len(options) == 0 && m.inPluginLoadCooldown(scheme) {
+		m.mu.RUnlock()
+		return nil, false
+	}
 	m.mu.RUnlock()
 	m.mu.Lock()
 	client, ok = m.clients[scheme]
@@ -197,61 +472,330 @@ func (m *clientManager) GetClient(scheme string, options ...Option) (ResourceCli
 		m.mu.Unlock()
 		return client, true
 	}
+	// the plugin may fail to load while waiting for the lock
+	if len(options) == 0 && m.inPluginLoadCooldown(scheme) {
+		m.mu.Unlock()
+		return nil, false
+	}
 
 	for _, opt := range options {
 		opt(m)
 	}
 
-	client, err := LoadPlugin(m.pluginDir, scheme)
+	plugin, err := m.loadPlugin(m.pluginDir, scheme)
 	if err != nil {
 		logger.Errorf("failed to load source plugin for scheme %s: %v", scheme, err)
+		m.pluginLoadFailures[scheme] = m.now()
 		m.mu.Unlock()
 		return nil, false
 	}
-	m.doRegister(scheme, client)
+	delete(m.pluginLoadFailures, scheme)
+	// the plugin is wrapped without adapting requests, so that its requests are counted in stats
+	wrapper := newClientWrapper(plugin, func(request *Request) *Request { return request }, nil)
+	m.doRegister(scheme, wrapper)
 	m.mu.Unlock()
-	return client, true
+	return wrapper, true
+}
+
+// inPluginLoadCooldown returns whether the plugin of scheme failed to load in cooldown, m.mu must be held
+func (m *clientManager) inPluginLoadCooldown(scheme string) bool {
+	if m.pluginLoadCooldown <= 0 {
+		return false
+	}
+	failedAt, ok := m.pluginLoadFailures[scheme]
+	return ok && m.now().Sub(failedAt) < m.pluginLoadCooldown
+}
+
+func (m *clientManager) Stats() map[string]SchemeStats {
+	result := map[string]SchemeStats{}
+	m.stats.Range(func(key, value interface{}) bool {
+		stats := value.(*schemeStats)
+		result[key.(string)] = SchemeStats{
+			Registered: stats.registered.Load(),
+			Requests:   stats.requests.Load(),
+			Errors:     stats.errors.Load(),
+		}
+		return true
+	})
+	return result
+}
+
+func (m *clientManager) LoadAllPlugins(pluginDir string) ([]string, error) {
+	clients, errs := loadAllPlugins(pluginDir)
+	var schemes []string
+	for scheme, client := range clients {
+		if err := m.Register(scheme, client, client.(PluginAdapter).Adapter); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		logger.Infof("register source plugin for scheme %s", scheme)
+		schemes = append(schemes, scheme)
+	}
+
+	sort.Strings(schemes)
+	return schemes, aggregateErrors(errs)
+}
+
+func Register(scheme string, resourceClient ResourceClient, adaptor requestAdapter, opts ...RegisterOption) error {
+	return _defaultManager.Register(scheme, resourceClient, adaptor, opts...)
 }
 
-func Register(scheme string, resourceClient ResourceClient, adaptor requestAdapter, hooks ...Hook) error {
-	return _defaultManager.Register(scheme, resourceClient, adaptor, hooks...)
+func RegisterOrReplace(scheme string, resourceClient ResourceClient, adaptor requestAdapter, opts ...RegisterOption) {
+	_defaultManager.RegisterOrReplace(scheme, resourceClient, adaptor, opts...)
 }
 
 func UnRegister(scheme string) {
 	_defaultManager.UnRegister(scheme)
 }
 
+func Alias(aliasScheme, targetScheme string) error {
+	return _defaultManager.Alias(aliasScheme, targetScheme)
+}
+
+// LoadAllPlugins loads and registers all resource plugins in plugin directory to default manager
+func LoadAllPlugins(pluginDir string) ([]string, error) {
+	return _defaultManager.LoadAllPlugins(pluginDir)
+}
+
+// Stats returns the statistics of the schemes of default manager
+func Stats() map[string]SchemeStats {
+	return _defaultManager.Stats()
+}
+
 type requestAdapter func(request *Request) *Request
 
-// Hook TODO hook
+// Hook intercepts the requests and responses of a registered source client.
+// BeforeRequest is called with the adapted request before every client method in registration order,
+// AfterResponse is called in reverse registration order after a successful Download,
+// which is the only method returning a *Response. The first hook error short-circuits the call.
 type Hook interface {
 	BeforeRequest(request *Request) error
 	AfterResponse(response *Response) error
 }
 
+// ClientDecorator wraps the registered client rather than intercepting the requests like Hook
+type ClientDecorator func(resourceClient ResourceClient) ResourceClient
+
+// RegisterOption configures the client registered by Register and RegisterOrReplace
+type RegisterOption func(wrapper *clientWrapper)
+
+// WithHooks appends hooks to the registered client, see Hook for the order of calls
+func WithHooks(hooks ...Hook) RegisterOption {
+	return func(wrapper *clientWrapper) {
+		wrapper.hooks = append(wrapper.hooks, hooks...)
+	}
+}
+
+// WithClientDecorator wraps the registered client with decorator, the decorators are applied
+// in registration order, the hooks intercept the requests before the decorated client
+func WithClientDecorator(decorator ClientDecorator) RegisterOption {
+	return func(wrapper *clientWrapper) {
+		wrapper.rc = decorator(wrapper.rc)
+	}
+}
+
 type clientWrapper struct {
 	adapter requestAdapter
 	hooks   []Hook
 	rc      ResourceClient
+	// origin is the registered client before decorated
+	origin ResourceClient
+	// stats counts the requests of the registered scheme
+	stats *schemeStats
+}
+
+func newClientWrapper(resourceClient ResourceClient, adapter requestAdapter, opts []RegisterOption) *clientWrapper {
+	wrapper := &clientWrapper{
+		adapter: adapter,
+		rc:      resourceClient,
+		origin:  resourceClient,
+	}
+	for _, opt := range opts {
+		opt(wrapper)
+	}
+	return wrapper
 }
 
 func (c *clientWrapper) GetContentLength(request *Request) (int64, error) {
-	return c.rc.GetContentLength(c.adapter(request))
+	request, err := c.beforeRequest(request)
+	if err != nil {
+		return UnknownSourceFileLen, c.stats.record(err)
+	}
+	contentLength, err := c.rc.GetContentLength(request)
+	return contentLength, c.stats.record(err)
 }
 
 func (c *clientWrapper) IsSupportRange(request *Request) (bool, error) {
-	return c.rc.IsSupportRange(c.adapter(request))
+	request, err := c.beforeRequest(request)
+	if err != nil {
+		return false, c.stats.record(err)
+	}
+	support, err := c.rc.IsSupportRange(request)
+	return support, c.stats.record(err)
 }
 
 func (c *clientWrapper) IsExpired(request *Request, info *ExpireInfo) (bool, error) {
-	return c.rc.IsExpired(c.adapter(request), info)
+	request, err := c.beforeRequest(request)
+	if err != nil {
+		return false, c.stats.record(err)
+	}
+	expired, err := c.rc.IsExpired(request, info)
+	return expired, c.stats.record(err)
 }
+
 func (c *clientWrapper) Download(request *Request) (*Response, error) {
-	return c.rc.Download(c.adapter(request))
+	request, err := c.beforeRequest(request)
+	if err != nil {
+		return nil, c.stats.record(err)
+	}
+	response, err := c.rc.Download(request)
+	if err != nil {
+		return nil, c.stats.record(err)
+	}
+	if err := c.afterResponse(response); err != nil {
+		response.Body.Close()
+		return nil, c.stats.record(err)
+	}
+	return response, c.stats.record(nil)
+}
+
+// DownloadRange downloads ranges if the wrapped client implements RangeReader,
+// AfterResponse hooks are called for every response
+func (c *clientWrapper) DownloadRange(request *Request, ranges []rangeutils.Range) ([]*Response, error) {
+	rangeReader, ok := c.rc.(RangeReader)
+	if !ok {
+		return nil, ErrClientNotSupportRangeRead
+	}
+	request, err := c.beforeRequest(request)
+	if err != nil {
+		return nil, c.stats.record(err)
+	}
+	responses, err := rangeReader.DownloadRange(request, ranges)
+	if err != nil {
+		return nil, c.stats.record(err)
+	}
+	for _, response := range responses {
+		if err := c.afterResponse(response); err != nil {
+			for _, response := range responses {
+				response.Body.Close()
+			}
+			return nil, c.stats.record(err)
+		}
+	}
+	return responses, c.stats.record(nil)
+}
+
+// DownloadMultiRange downloads Request.Ranges if the wrapped client implements MultiRangeDownloader,
+// AfterResponse hooks are called for the response
+func (c *clientWrapper) DownloadMultiRange(request *Request) (*Response, error) {
+	downloader, ok := c.rc.(MultiRangeDownloader)
+	if !ok {
+		return nil, ErrClientNotSupportRangeRead
+	}
+	request, err := c.beforeRequest(request)
+	if err != nil {
+		return nil, c.stats.record(err)
+	}
+	response, err := downloader.DownloadMultiRange(request)
+	if err != nil {
+		return nil, c.stats.record(err)
+	}
+	if err := c.afterResponse(response); err != nil {
+		response.Body.Close()
+		return nil, c.stats.record(err)
+	}
+	return response, c.stats.record(nil)
+}
+
+// Check checks the resource with the wrapped client
+func (c *clientWrapper) Check(request *Request) error {
+	request, err := c.beforeRequest(request)
+	if err != nil {
+		return c.stats.record(err)
+	}
+	return c.stats.record(check(c.rc, request))
+}
+
+// List lists resources if the wrapped client implements ResourceLister
+func (c *clientWrapper) List(request *Request) ([]*url.URL, error) {
+	lister, ok := c.rc.(ResourceLister)
+	if !ok {
+		return nil, ErrClientNotSupportList
+	}
+	request, err := c.beforeRequest(request)
+	if err != nil {
+		return nil, c.stats.record(err)
+	}
+	urls, err := lister.List(request)
+	return urls, c.stats.record(err)
+}
+
+// ListWithMetadata lists resources with metadata if the wrapped client implements ResourceMetadataLister,
+// otherwise the content length of every listed resource is requested
+func (c *clientWrapper) ListWithMetadata(request *Request) ([]*ResourceInfo, error) {
+	if lister, ok := c.rc.(ResourceMetadataLister); ok {
+		adapted, err := c.beforeRequest(request)
+		if err != nil {
+			return nil, c.stats.record(err)
+		}
+		infos, err := lister.ListWithMetadata(adapted)
+		if !errors.Is(err, ErrClientNotSupportList) {
+			return infos, c.stats.record(err)
+		}
+	}
+
+	urls, err := c.List(request)
+	if err != nil {
+		return nil, err
+	}
+	var infos []*ResourceInfo
+	for _, u := range urls {
+		if err := request.Context().Err(); err != nil {
+			return nil, err
+		}
+		resourceRequest := request.Clone(request.Context())
+		resourceRequest.URL = u
+		contentLength, err := c.GetContentLength(resourceRequest)
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, &ResourceInfo{
+			URL:           u,
+			ContentLength: contentLength,
+			LastModified:  -1,
+		})
+	}
+	return infos, nil
 }
 
 func (c *clientWrapper) GetLastModified(request *Request) (int64, error) {
-	return c.rc.GetLastModified(c.adapter(request))
+	request, err := c.beforeRequest(request)
+	if err != nil {
+		return -1, c.stats.record(err)
+	}
+	lastModified, err := c.rc.GetLastModified(request)
+	return lastModified, c.stats.record(err)
+}
+
+// beforeRequest adapts request and calls hooks in registration order
+func (c *clientWrapper) beforeRequest(request *Request) (*Request, error) {
+	request = c.adapter(request)
+	for _, hook := range c.hooks {
+		if err := hook.BeforeRequest(request); err != nil {
+			return nil, err
+		}
+	}
+	return request, nil
+}
+
+// afterResponse calls hooks in reverse registration order
+func (c *clientWrapper) afterResponse(response *Response) error {
+	for i := len(c.hooks) - 1; i >= 0; i-- {
+		if err := c.hooks[i].AfterResponse(response); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func GetContentLength(request *Request) (int64, error) {
@@ -309,12 +853,121 @@ func GetLastModified(request *Request) (int64, error) {
 	return client.GetLastModified(request)
 }
 
+// Check verifies the resource is reachable without downloading it, the returned error is
+// ErrResourceNotReachable with the cause in err chain
+func Check(request *Request) error {
+	client, ok := _defaultManager.GetClient(request.URL.Scheme)
+	if !ok {
+		return errors.Wrapf(ErrNoClientFound, "scheme: %s", request.URL.Scheme)
+	}
+	if _, ok := request.Context().Deadline(); !ok {
+		ctx, cancel := context.WithTimeout(context.Background(), 4*time.Second)
+		request = request.WithContext(ctx)
+		defer cancel()
+	}
+	return check(client, request)
+}
+
+// check checks the resource with ResourceChecker, or with GetContentLength for the other clients
+func check(client ResourceClient, request *Request) error {
+	var err error
+	if checker, ok := client.(ResourceChecker); ok {
+		err = checker.Check(request)
+	} else {
+		_, err = client.GetContentLength(request)
+	}
+	if err == nil || IsResourceNotReachableError(err) {
+		return err
+	}
+	return &notReachableError{cause: err}
+}
+
+// Download downloads the resource, only Request.Ranges are downloaded if they are set
 func Download(request *Request) (*Response, error) {
 	client, ok := _defaultManager.GetClient(request.URL.Scheme)
 	if !ok {
 		return nil, errors.Wrapf(ErrNoClientFound, "scheme: %s", request.URL.Scheme)
 	}
-	return client.Download(request)
+	if len(request.Ranges) == 0 {
+		return client.Download(request)
+	}
+	if err := ValidateRanges(request.Ranges); err != nil {
+		return nil, err
+	}
+	response, err := downloadMultiRange(client, request)
+	if errors.Is(err, ErrClientNotSupportRangeRead) {
+		return nil, errors.Wrapf(err, "scheme: %s", request.URL.Scheme)
+	}
+	return response, err
+}
+
+// downloadMultiRange downloads Request.Ranges with MultiRangeDownloader,
+// or downloads every range with RangeReader and concatenates the responses
+func downloadMultiRange(client ResourceClient, request *Request) (*Response, error) {
+	if downloader, ok := client.(MultiRangeDownloader); ok {
+		response, err := downloader.DownloadMultiRange(request)
+		if !errors.Is(err, ErrClientNotSupportRangeRead) {
+			return response, err
+		}
+	}
+
+	rangeReader, ok := client.(RangeReader)
+	if !ok {
+		return nil, ErrClientNotSupportRangeRead
+	}
+	responses, err := rangeReader.DownloadRange(request, request.Ranges)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		bodies        []io.ReadCloser
+		contentLength int64
+	)
+	for _, rg := range request.Ranges {
+		contentLength += int64(rg.Length())
+	}
+	for _, response := range responses {
+		bodies = append(bodies, response.Body)
+	}
+	response := NewResponse(&multiReadCloser{readClosers: bodies},
+		WithStatus(http.StatusPartialContent, http.StatusText(http.StatusPartialContent)),
+		WithContentLength(contentLength))
+	if len(responses) > 0 {
+		response.Header = responses[0].Header
+	}
+	return response, nil
+}
+
+// multiReadCloser reads the readers in order and closes all of them
+type multiReadCloser struct {
+	readClosers []io.ReadCloser
+	index       int
+}
+
+func (m *multiReadCloser) Read(p []byte) (int, error) {
+	for m.index < len(m.readClosers) {
+		n, err := m.readClosers[m.index].Read(p)
+		if err == io.EOF {
+			m.index++
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
+	return 0, io.EOF
+}
+
+func (m *multiReadCloser) Close() error {
+	var err error
+	for _, rc := range m.readClosers {
+		if closeErr := rc.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	return err
 }
 
 func List(request *Request) ([]*url.URL, error) {
@@ -326,5 +979,42 @@ func List(request *Request) ([]*url.URL, error) {
 	if !ok {
 		return nil, errors.Wrapf(ErrClientNotSupportList, "scheme: %s", request.URL.Scheme)
 	}
-	return lister.List(request)
+	urls, err := lister.List(request)
+	if errors.Is(err, ErrClientNotSupportList) {
+		return nil, errors.Wrapf(err, "scheme: %s", request.URL.Scheme)
+	}
+	return urls, err
+}
+
+// ListWithMetadata lists resources with metadata in request url, see ResourceMetadataLister
+func ListWithMetadata(request *Request) ([]*ResourceInfo, error) {
+	client, ok := _defaultManager.GetClient(request.URL.Scheme)
+	if !ok {
+		return nil, errors.Wrapf(ErrNoClientFound, "scheme: %s", request.URL.Scheme)
+	}
+	lister, ok := client.(ResourceMetadataLister)
+	if !ok {
+		return nil, errors.Wrapf(ErrClientNotSupportList, "scheme: %s", request.URL.Scheme)
+	}
+	infos, err := lister.ListWithMetadata(request)
+	if errors.Is(err, ErrClientNotSupportList) {
+		return nil, errors.Wrapf(err, "scheme: %s", request.URL.Scheme)
+	}
+	return infos, err
+}
+
+func DownloadRange(request *Request, ranges []rangeutils.Range) ([]*Response, error) {
+	client, ok := _defaultManager.GetClient(request.URL.Scheme)
+	if !ok {
+		return nil, errors.Wrapf(ErrNoClientFound, "scheme: %s", request.URL.Scheme)
+	}
+	rangeReader, ok := client.(RangeReader)
+	if !ok {
+		return nil, errors.Wrapf(ErrClientNotSupportRangeRead, "scheme: %s", request.URL.Scheme)
+	}
+	responses, err := rangeReader.DownloadRange(request, ranges)
+	if errors.Is(err, ErrClientNotSupportRangeRead) {
+		return nil, errors.Wrapf(err, "scheme: %s", request.URL.Scheme)
+	}
+	return responses, err
 }
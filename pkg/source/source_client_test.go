@@ -0,0 +1,748 @@
+/*
+ *     Copyright 2020 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package source
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	testifyassert "github.com/stretchr/testify/assert"
+	"go.uber.org/atomic"
+
+	"d7y.io/dragonfly/v2/pkg/util/rangeutils"
+)
+
+type fakeClient struct {
+	contentLength int64
+}
+
+func (c *fakeClient) GetContentLength(request *Request) (int64, error) {
+	return c.contentLength, nil
+}
+
+func (c *fakeClient) IsSupportRange(request *Request) (bool, error) {
+	return true, nil
+}
+
+func (c *fakeClient) IsExpired(request *Request, info *ExpireInfo) (bool, error) {
+	return false, nil
+}
+
+func (c *fakeClient) Download(request *Request) (*Response, error) {
+	return NewResponse(io.NopCloser(bytes.NewBufferString("hello world"))), nil
+}
+
+func (c *fakeClient) GetLastModified(request *Request) (int64, error) {
+	return -1, nil
+}
+
+func noopAdapter(request *Request) *Request {
+	return request
+}
+
+func TestClientManager_Register(t *testing.T) {
+	assert := testifyassert.New(t)
+	manager := NewManager()
+	client := &fakeClient{contentLength: 1}
+
+	assert.NoError(manager.Register("fake", client, noopAdapter))
+	assert.NoError(manager.Register("FAKE", client, noopAdapter))
+	assert.Error(manager.Register("fake", &fakeClient{contentLength: 2}, noopAdapter))
+
+	rc, ok := manager.GetClient("fake")
+	assert.True(ok)
+	length, err := rc.GetContentLength(&Request{})
+	assert.NoError(err)
+	assert.Equal(length, int64(1))
+}
+
+func TestClientManager_RegisterOrReplace(t *testing.T) {
+	assert := testifyassert.New(t)
+	manager := NewManager()
+
+	manager.RegisterOrReplace("fake", &fakeClient{contentLength: 1}, noopAdapter)
+	manager.RegisterOrReplace("FAKE", &fakeClient{contentLength: 2}, noopAdapter)
+
+	rc, ok := manager.GetClient("fake")
+	assert.True(ok)
+	length, err := rc.GetContentLength(&Request{})
+	assert.NoError(err)
+	assert.Equal(length, int64(2))
+}
+
+func TestClientManager_RegisterOrReplaceConcurrently(t *testing.T) {
+	assert := testifyassert.New(t)
+	manager := NewManager()
+	manager.RegisterOrReplace("fake", &fakeClient{contentLength: 0}, noopAdapter)
+
+	var wg sync.WaitGroup
+	for i := 1; i <= 10; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			manager.RegisterOrReplace("fake", &fakeClient{contentLength: int64(i)}, noopAdapter)
+		}(i)
+
+		go func() {
+			defer wg.Done()
+			rc, ok := manager.GetClient("fake")
+			assert.True(ok)
+			_, err := rc.GetContentLength(&Request{})
+			assert.NoError(err)
+		}()
+	}
+	wg.Wait()
+}
+
+type recordHook struct {
+	name      string
+	records   *[]string
+	beforeErr error
+	afterErr  error
+}
+
+func (h *recordHook) BeforeRequest(request *Request) error {
+	*h.records = append(*h.records, "before "+h.name)
+	return h.beforeErr
+}
+
+func (h *recordHook) AfterResponse(response *Response) error {
+	*h.records = append(*h.records, "after "+h.name)
+	return h.afterErr
+}
+
+func TestClientWrapper_Hooks(t *testing.T) {
+	tests := []struct {
+		name   string
+		hooks  func(records *[]string) []Hook
+		run    func(t *testing.T, rc ResourceClient)
+		expect []string
+	}{
+		{
+			name: "download calls before hooks in order and after hooks in reverse order",
+			hooks: func(records *[]string) []Hook {
+				return []Hook{&recordHook{name: "a", records: records}, &recordHook{name: "b", records: records}}
+			},
+			run: func(t *testing.T, rc ResourceClient) {
+				assert := testifyassert.New(t)
+				response, err := rc.Download(&Request{})
+				assert.NoError(err)
+				assert.NoError(response.Body.Close())
+			},
+			expect: []string{"before a", "before b", "after b", "after a"},
+		},
+		{
+			name: "get content length only calls before hooks",
+			hooks: func(records *[]string) []Hook {
+				return []Hook{&recordHook{name: "a", records: records}, &recordHook{name: "b", records: records}}
+			},
+			run: func(t *testing.T, rc ResourceClient) {
+				assert := testifyassert.New(t)
+				length, err := rc.GetContentLength(&Request{})
+				assert.NoError(err)
+				assert.Equal(length, int64(1))
+			},
+			expect: []string{"before a", "before b"},
+		},
+		{
+			name: "before hook error short-circuits",
+			hooks: func(records *[]string) []Hook {
+				return []Hook{&recordHook{name: "a", records: records, beforeErr: errors.New("foo")}, &recordHook{name: "b", records: records}}
+			},
+			run: func(t *testing.T, rc ResourceClient) {
+				assert := testifyassert.New(t)
+				response, err := rc.Download(&Request{})
+				assert.EqualError(err, "foo")
+				assert.Nil(response)
+				length, err := rc.GetContentLength(&Request{})
+				assert.EqualError(err, "foo")
+				assert.Equal(length, int64(UnknownSourceFileLen))
+			},
+			expect: []string{"before a", "before a"},
+		},
+		{
+			name: "after hook error short-circuits",
+			hooks: func(records *[]string) []Hook {
+				return []Hook{&recordHook{name: "a", records: records}, &recordHook{name: "b", records: records, afterErr: errors.New("bar")}}
+			},
+			run: func(t *testing.T, rc ResourceClient) {
+				assert := testifyassert.New(t)
+				response, err := rc.Download(&Request{})
+				assert.EqualError(err, "bar")
+				assert.Nil(response)
+			},
+			expect: []string{"before a", "before b", "after b"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var records []string
+			manager := NewManager()
+			assert := testifyassert.New(t)
+			assert.NoError(manager.Register("fake", &fakeClient{contentLength: 1}, noopAdapter, WithHooks(tc.hooks(&records)...)))
+			rc, ok := manager.GetClient("fake")
+			assert.True(ok)
+
+			tc.run(t, rc)
+			assert.Equal(records, tc.expect)
+		})
+	}
+}
+
+type fakeRangeClient struct {
+	fakeClient
+}
+
+func (c *fakeRangeClient) DownloadRange(request *Request, ranges []rangeutils.Range) ([]*Response, error) {
+	var responses []*Response
+	for _, rg := range ranges {
+		responses = append(responses, NewResponse(io.NopCloser(bytes.NewBufferString(rg.String()))))
+	}
+	return responses, nil
+}
+
+func TestClientWrapper_DownloadRange(t *testing.T) {
+	assert := testifyassert.New(t)
+	manager := NewManager()
+	assert.NoError(manager.Register("fake", &fakeClient{}, noopAdapter))
+	assert.NoError(manager.Register("fakerange", &fakeRangeClient{}, noopAdapter))
+	ranges := []rangeutils.Range{{StartIndex: 0, EndIndex: 1}, {StartIndex: 4, EndIndex: 7}}
+
+	rc, ok := manager.GetClient("fake")
+	assert.True(ok)
+	responses, err := rc.(RangeReader).DownloadRange(&Request{}, ranges)
+	assert.ErrorIs(err, ErrClientNotSupportRangeRead)
+	assert.Nil(responses)
+
+	rc, ok = manager.GetClient("fakerange")
+	assert.True(ok)
+	responses, err = rc.(RangeReader).DownloadRange(&Request{}, ranges)
+	assert.NoError(err)
+	assert.Len(responses, 2)
+	for i, rg := range ranges {
+		data, err := io.ReadAll(responses[i].Body)
+		assert.NoError(err)
+		assert.Equal(string(data), rg.String())
+	}
+}
+
+func TestValidateRanges(t *testing.T) {
+	tests := []struct {
+		name    string
+		ranges  []rangeutils.Range
+		wantErr string
+	}{
+		{
+			name:   "sorted and disjoint",
+			ranges: []rangeutils.Range{{StartIndex: 0, EndIndex: 1}, {StartIndex: 2, EndIndex: 2}, {StartIndex: 10, EndIndex: 20}},
+		},
+		{
+			name:    "unsorted",
+			ranges:  []rangeutils.Range{{StartIndex: 10, EndIndex: 20}, {StartIndex: 0, EndIndex: 1}},
+			wantErr: "range 0-1 is not sorted",
+		},
+		{
+			name:    "overlapped",
+			ranges:  []rangeutils.Range{{StartIndex: 0, EndIndex: 10}, {StartIndex: 10, EndIndex: 20}},
+			wantErr: "range 10-20 overlaps range 0-10",
+		},
+		{
+			name:    "start is larger than end",
+			ranges:  []rangeutils.Range{{StartIndex: 10, EndIndex: 1}},
+			wantErr: "start is larger than end",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateRanges(tc.ranges)
+			if tc.wantErr == "" {
+				testifyassert.NoError(t, err)
+				return
+			}
+			testifyassert.Error(t, err)
+			testifyassert.Contains(t, err.Error(), tc.wantErr)
+		})
+	}
+}
+
+type fakeMultiRangeClient struct {
+	fakeClient
+}
+
+func (c *fakeMultiRangeClient) DownloadMultiRange(request *Request) (*Response, error) {
+	return NewResponse(io.NopCloser(bytes.NewBufferString("multi range"))), nil
+}
+
+func TestDownloadMultiRange(t *testing.T) {
+	assert := testifyassert.New(t)
+	manager := NewManager()
+	assert.NoError(manager.Register("fake", &fakeClient{}, noopAdapter))
+	assert.NoError(manager.Register("fakerange", &fakeRangeClient{}, noopAdapter))
+	assert.NoError(manager.Register("fakemultirange", &fakeMultiRangeClient{}, noopAdapter))
+	request := &Request{Ranges: []rangeutils.Range{{StartIndex: 0, EndIndex: 1}, {StartIndex: 4, EndIndex: 7}}}
+
+	rc, ok := manager.GetClient("fake")
+	assert.True(ok)
+	_, err := downloadMultiRange(rc, request)
+	assert.ErrorIs(err, ErrClientNotSupportRangeRead)
+
+	// the responses of RangeReader are concatenated
+	rc, ok = manager.GetClient("fakerange")
+	assert.True(ok)
+	response, err := downloadMultiRange(rc, request)
+	assert.NoError(err)
+	assert.Equal(int64(6), response.ContentLength)
+	data, err := io.ReadAll(response.Body)
+	assert.NoError(err)
+	assert.Equal("0-14-7", string(data))
+	assert.NoError(response.Body.Close())
+
+	rc, ok = manager.GetClient("fakemultirange")
+	assert.True(ok)
+	response, err = downloadMultiRange(rc, request)
+	assert.NoError(err)
+	data, err = io.ReadAll(response.Body)
+	assert.NoError(err)
+	assert.Equal("multi range", string(data))
+}
+
+type fakeListClient struct {
+	fakeClient
+	urls []*url.URL
+	// requested is the urls of requested content length
+	requested []string
+}
+
+func (c *fakeListClient) List(request *Request) ([]*url.URL, error) {
+	return c.urls, nil
+}
+
+func (c *fakeListClient) GetContentLength(request *Request) (int64, error) {
+	c.requested = append(c.requested, request.URL.String())
+	return c.contentLength, nil
+}
+
+type fakeMetadataListClient struct {
+	fakeListClient
+}
+
+func (c *fakeMetadataListClient) ListWithMetadata(request *Request) ([]*ResourceInfo, error) {
+	var infos []*ResourceInfo
+	for _, u := range c.urls {
+		infos = append(infos, &ResourceInfo{URL: u, ContentLength: c.contentLength, LastModified: 1})
+	}
+	return infos, nil
+}
+
+func TestClientWrapper_ListWithMetadata(t *testing.T) {
+	assert := testifyassert.New(t)
+	urls := []*url.URL{
+		{Scheme: "fake", Host: "example.com", Path: "/dir/a"},
+		{Scheme: "fake", Host: "example.com", Path: "/dir/b"},
+	}
+	listClient := &fakeListClient{fakeClient: fakeClient{contentLength: 10}, urls: urls}
+	metadataListClient := &fakeMetadataListClient{fakeListClient{fakeClient: fakeClient{contentLength: 20}, urls: urls}}
+	manager := NewManager()
+	assert.NoError(manager.Register("fake", &fakeClient{}, noopAdapter))
+	assert.NoError(manager.Register("fakelist", listClient, noopAdapter))
+	assert.NoError(manager.Register("fakemetadatalist", metadataListClient, noopAdapter))
+	request, err := NewRequest("fake://example.com/dir")
+	assert.NoError(err)
+
+	rc, ok := manager.GetClient("fake")
+	assert.True(ok)
+	_, err = rc.(ResourceMetadataLister).ListWithMetadata(request)
+	assert.ErrorIs(err, ErrClientNotSupportList)
+
+	// the content length of every resource is requested without ResourceMetadataLister
+	rc, ok = manager.GetClient("fakelist")
+	assert.True(ok)
+	infos, err := rc.(ResourceMetadataLister).ListWithMetadata(request)
+	assert.NoError(err)
+	assert.Equal([]*ResourceInfo{
+		{URL: urls[0], ContentLength: 10, LastModified: -1},
+		{URL: urls[1], ContentLength: 10, LastModified: -1},
+	}, infos)
+	assert.Equal([]string{"fake://example.com/dir/a", "fake://example.com/dir/b"}, listClient.requested)
+
+	rc, ok = manager.GetClient("fakemetadatalist")
+	assert.True(ok)
+	infos, err = rc.(ResourceMetadataLister).ListWithMetadata(request)
+	assert.NoError(err)
+	assert.Equal([]*ResourceInfo{
+		{URL: urls[0], ContentLength: 20, LastModified: 1},
+		{URL: urls[1], ContentLength: 20, LastModified: 1},
+	}, infos)
+	assert.Empty(metadataListClient.requested)
+}
+
+func TestClientManager_Alias(t *testing.T) {
+	tests := []struct {
+		name   string
+		expect func(t *testing.T, manager ClientManager)
+	}{
+		{
+			name: "alias resolves to target client",
+			expect: func(t *testing.T, manager ClientManager) {
+				assert := testifyassert.New(t)
+				assert.NoError(manager.Alias("HTTPS", "fake"))
+				client, ok := manager.GetClient("fake")
+				assert.True(ok)
+				aliasClient, ok := manager.GetClient("https")
+				assert.True(ok)
+				assert.Same(client, aliasClient)
+			},
+		},
+		{
+			name: "alias chain resolves to target client",
+			expect: func(t *testing.T, manager ClientManager) {
+				assert := testifyassert.New(t)
+				assert.NoError(manager.Alias("b", "fake"))
+				assert.NoError(manager.Alias("a", "b"))
+				client, ok := manager.GetClient("fake")
+				assert.True(ok)
+				aliasClient, ok := manager.GetClient("a")
+				assert.True(ok)
+				assert.Same(client, aliasClient)
+			},
+		},
+		{
+			name: "alias cycle is rejected",
+			expect: func(t *testing.T, manager ClientManager) {
+				assert := testifyassert.New(t)
+				assert.NoError(manager.Alias("a", "b"))
+				assert.NoError(manager.Alias("b", "c"))
+				assert.Error(manager.Alias("c", "a"))
+				assert.Error(manager.Alias("d", "d"))
+			},
+		},
+		{
+			name: "alias registered scheme is rejected",
+			expect: func(t *testing.T, manager ClientManager) {
+				assert := testifyassert.New(t)
+				assert.Error(manager.Alias("fake", "https"))
+			},
+		},
+		{
+			name: "register alias scheme is rejected",
+			expect: func(t *testing.T, manager ClientManager) {
+				assert := testifyassert.New(t)
+				assert.NoError(manager.Alias("https", "fake"))
+				assert.Error(manager.Register("https", &fakeClient{}, noopAdapter))
+			},
+		},
+		{
+			name: "unregister target removes aliases",
+			expect: func(t *testing.T, manager ClientManager) {
+				assert := testifyassert.New(t)
+				assert.NoError(manager.Alias("b", "fake"))
+				assert.NoError(manager.Alias("a", "b"))
+				manager.UnRegister("fake")
+				assert.NoError(manager.Register("a", &fakeClient{}, noopAdapter))
+				assert.NoError(manager.Register("b", &fakeClient{}, noopAdapter))
+			},
+		},
+		{
+			name: "unregister alias keeps target",
+			expect: func(t *testing.T, manager ClientManager) {
+				assert := testifyassert.New(t)
+				assert.NoError(manager.Alias("https", "fake"))
+				manager.UnRegister("https")
+				_, ok := manager.GetClient("fake")
+				assert.True(ok)
+				assert.NoError(manager.Register("https", &fakeClient{}, noopAdapter))
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			manager := NewManager()
+			if err := manager.Register("fake", &fakeClient{}, noopAdapter); err != nil {
+				t.Fatal(err)
+			}
+			tc.expect(t, manager)
+		})
+	}
+}
+
+func TestUnexpectedStatusCodeError_Predicates(t *testing.T) {
+	tests := []struct {
+		name            string
+		err             error
+		notFound        bool
+		unauthorized    bool
+		tooManyRequests bool
+		serverError     bool
+	}{
+		{name: "404", err: CheckResponseCode(http.StatusNotFound, []int{http.StatusOK}), notFound: true},
+		{name: "410", err: CheckResponseCode(http.StatusGone, []int{http.StatusOK}), notFound: true},
+		{name: "401", err: CheckResponseCode(http.StatusUnauthorized, []int{http.StatusOK}), unauthorized: true},
+		{name: "403", err: CheckResponseCode(http.StatusForbidden, []int{http.StatusOK}), unauthorized: true},
+		{name: "429", err: CheckResponseCode(http.StatusTooManyRequests, []int{http.StatusOK}), tooManyRequests: true},
+		{name: "503", err: CheckResponseCode(http.StatusServiceUnavailable, []int{http.StatusOK}), serverError: true},
+		{name: "wrapped 404", err: fmt.Errorf("download: %w", CheckResponseCode(http.StatusNotFound, []int{http.StatusOK})), notFound: true},
+		{name: "400", err: CheckResponseCode(http.StatusBadRequest, []int{http.StatusOK})},
+		{name: "other error", err: errors.New("404")},
+		{name: "nil", err: nil},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := testifyassert.New(t)
+			assert.Equal(tc.notFound, IsNotFound(tc.err))
+			assert.Equal(tc.unauthorized, IsUnauthorized(tc.err))
+			assert.Equal(tc.tooManyRequests, IsTooManyRequests(tc.err))
+			assert.Equal(tc.serverError, IsServerError(tc.err))
+		})
+	}
+
+	assert := testifyassert.New(t)
+	assert.Equal("status code from source is 404; was expecting 200 or 206",
+		CheckResponseCode(http.StatusNotFound, []int{http.StatusOK, http.StatusPartialContent}).Error())
+}
+
+type fakeCheckClient struct {
+	fakeClient
+	err error
+}
+
+func (c *fakeCheckClient) Check(request *Request) error {
+	return c.err
+}
+
+func TestClientWrapper_Check(t *testing.T) {
+	unreachable := &countingClient{calls: map[string]int{}, err: errors.New("connection refused")}
+	tests := []struct {
+		name   string
+		client ResourceClient
+		expect func(t *testing.T, err error)
+	}{
+		{
+			name:   "reachable",
+			client: &fakeClient{contentLength: 1},
+			expect: func(t *testing.T, err error) {
+				assert := testifyassert.New(t)
+				assert.NoError(err)
+			},
+		},
+		{
+			name:   "not reachable without checker",
+			client: unreachable,
+			expect: func(t *testing.T, err error) {
+				assert := testifyassert.New(t)
+				assert.True(IsResourceNotReachableError(err))
+				assert.EqualError(err, "resource is not reachable: connection refused")
+				assert.Equal(1, unreachable.calls["GetContentLength"])
+			},
+		},
+		{
+			name:   "checker",
+			client: &fakeCheckClient{},
+			expect: func(t *testing.T, err error) {
+				assert := testifyassert.New(t)
+				assert.NoError(err)
+			},
+		},
+		{
+			name:   "checker unauthorized",
+			client: &fakeCheckClient{err: CheckResponseCode(http.StatusUnauthorized, []int{http.StatusOK})},
+			expect: func(t *testing.T, err error) {
+				assert := testifyassert.New(t)
+				assert.True(IsResourceNotReachableError(err))
+				assert.True(IsUnauthorized(err))
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			manager := NewManager()
+			if err := manager.Register("fake", tc.client, noopAdapter); err != nil {
+				t.Fatal(err)
+			}
+			rc, _ := manager.GetClient("fake")
+			request, err := NewRequest("fake://example.com/file")
+			if err != nil {
+				t.Fatal(err)
+			}
+			tc.expect(t, rc.(ResourceChecker).Check(request))
+		})
+	}
+}
+
+func TestCheck_NoClientFound(t *testing.T) {
+	assert := testifyassert.New(t)
+	request, err := NewRequest("fakenotfound://example.com/file")
+	assert.NoError(err)
+	assert.True(IsNoClientFoundError(Check(request)))
+}
+
+type fakeErrorClient struct {
+	fakeClient
+}
+
+func (c *fakeErrorClient) GetContentLength(request *Request) (int64, error) {
+	return UnknownSourceFileLen, ErrResourceNotReachable
+}
+
+func TestClientManager_Stats(t *testing.T) {
+	const (
+		workers  = 8
+		requests = 100
+	)
+	assert := testifyassert.New(t)
+	manager := NewManager()
+	assert.NoError(manager.Register("fake", &fakeClient{}, noopAdapter))
+	assert.NoError(manager.Register("fakeerr", &fakeErrorClient{}, noopAdapter))
+	assert.NoError(manager.Alias("fakealias", "fake"))
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < requests; j++ {
+				scheme := "fake"
+				if j%2 == 1 {
+					scheme = "fakealias"
+				}
+				rc, ok := manager.GetClient(scheme)
+				if !ok {
+					t.Error("client not found")
+					return
+				}
+				_, _ = rc.GetContentLength(&Request{})
+				rc, _ = manager.GetClient("fakeerr")
+				_, _ = rc.GetContentLength(&Request{})
+			}
+		}()
+		// the stats are read while the requests are recorded and the plugins are loaded
+		go func() {
+			defer wg.Done()
+			for j := 0; j < requests; j++ {
+				_ = manager.Stats()
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < requests; j++ {
+				_, _ = manager.GetClient(fmt.Sprintf("notfound%d", j%4))
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(map[string]SchemeStats{
+		"fake":    {Registered: true, Requests: workers * requests},
+		"fakeerr": {Registered: true, Requests: workers * requests, Errors: workers * requests},
+	}, manager.Stats())
+
+	// the stats are kept after the scheme is unregistered or replaced
+	manager.UnRegister("fakeerr")
+	manager.RegisterOrReplace("fake", &fakeClient{contentLength: 1}, noopAdapter)
+	rc, ok := manager.GetClient("fake")
+	assert.True(ok)
+	_, err := rc.GetContentLength(&Request{})
+	assert.NoError(err)
+	assert.Equal(map[string]SchemeStats{
+		"fake":    {Registered: true, Requests: workers*requests + 1},
+		"fakeerr": {Registered: false, Requests: workers * requests, Errors: workers * requests},
+	}, manager.Stats())
+}
+
+func TestClientManager_PluginLoadCooldown(t *testing.T) {
+	assert := testifyassert.New(t)
+	loads := atomic.NewInt64(0)
+	manager := NewManager(WithPluginLoadCooldown(time.Minute)).(*clientManager)
+	manager.loadPlugin = func(dir, scheme string) (ResourceClient, error) {
+		loads.Inc()
+		return nil, errors.New("plugin not found")
+	}
+	now := time.Now()
+	manager.now = func() time.Time { return now }
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, ok := manager.GetClient("fake")
+			assert.False(ok)
+		}()
+	}
+	wg.Wait()
+	// the failed plugin is loaded only once in cooldown
+	assert.Equal(int64(1), loads.Load())
+
+	// the plugin is loaded again after cooldown
+	now = now.Add(time.Minute)
+	_, ok := manager.GetClient("fake")
+	assert.False(ok)
+	assert.Equal(int64(2), loads.Load())
+
+	// the plugin is loaded after cooldown and registered
+	now = now.Add(time.Minute)
+	manager.loadPlugin = func(dir, scheme string) (ResourceClient, error) {
+		loads.Inc()
+		return &fakeClient{}, nil
+	}
+	_, ok = manager.GetClient("fake")
+	assert.True(ok)
+	assert.Equal(int64(3), loads.Load())
+	assert.Empty(manager.pluginLoadFailures)
+
+	// the plugin is always loaded without cooldown
+	manager = NewManager(WithPluginLoadCooldown(0)).(*clientManager)
+	manager.loadPlugin = func(dir, scheme string) (ResourceClient, error) {
+		loads.Inc()
+		return nil, errors.New("plugin not found")
+	}
+	for i := 0; i < 2; i++ {
+		_, ok = manager.GetClient("fake")
+		assert.False(ok)
+	}
+	assert.Equal(int64(5), loads.Load())
+}
+
+func BenchmarkClientManager_GetClientNotFound(b *testing.B) {
+	for _, cooldown := range []time.Duration{0, time.Minute} {
+		b.Run(fmt.Sprintf("cooldown %s", cooldown), func(b *testing.B) {
+			manager := NewManager(WithPluginLoadCooldown(cooldown)).(*clientManager)
+			manager.loadPlugin = func(dir, scheme string) (ResourceClient, error) {
+				// simulate the cost of trying to open the plugin file
+				time.Sleep(10 * time.Microsecond)
+				return nil, errors.New("plugin not found")
+			}
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					manager.GetClient("unsupported")
+				}
+			})
+		})
+	}
+}
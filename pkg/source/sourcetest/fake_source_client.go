@@ -0,0 +1,257 @@
+/*
+ *     Copyright 2020 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package sourcetest provides an in-memory fake source client for testing.
+package sourcetest
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/atomic"
+
+	"d7y.io/dragonfly/v2/pkg/source"
+	"d7y.io/dragonfly/v2/pkg/util/rangeutils"
+)
+
+var (
+	_ source.ResourceClient = (*FakeSourceClient)(nil)
+	_ source.ResourceLister = (*FakeSourceClient)(nil)
+	_ source.RangeReader    = (*FakeSourceClient)(nil)
+)
+
+// DefaultLastModified is the last modified time of objects which are not set explicitly
+var DefaultLastModified = time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// FakeSourceClient is an in-memory source client serving byte slices by url,
+// it supports ranges, list and can be programmed to fail on the Nth call.
+type FakeSourceClient struct {
+	mu      sync.RWMutex
+	objects map[string]*fakeObject
+
+	// calls is the count of all method calls
+	calls *atomic.Int64
+
+	// failures is the error returned on the Nth call
+	failures map[int64]error
+}
+
+type fakeObject struct {
+	data         []byte
+	lastModified time.Time
+	etag         string
+}
+
+// Option is a functional option for configuring the FakeSourceClient
+type Option func(c *FakeSourceClient)
+
+// WithObject serves data for url
+func WithObject(rawURL string, data []byte) Option {
+	return func(c *FakeSourceClient) {
+		c.PutObject(rawURL, data, DefaultLastModified, "")
+	}
+}
+
+// WithFailOnCall makes the Nth call returns err, n starts from 1 and counts all method calls
+func WithFailOnCall(n int64, err error) Option {
+	return func(c *FakeSourceClient) {
+		c.failures[n] = err
+	}
+}
+
+// NewFakeSourceClient returns a new FakeSourceClient
+func NewFakeSourceClient(options ...Option) *FakeSourceClient {
+	c := &FakeSourceClient{
+		objects:  map[string]*fakeObject{},
+		calls:    atomic.NewInt64(0),
+		failures: map[int64]error{},
+	}
+
+	for _, opt := range options {
+		opt(c)
+	}
+	return c
+}
+
+// PutObject serves data for url with last modified time and etag
+func (c *FakeSourceClient) PutObject(rawURL string, data []byte, lastModified time.Time, etag string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.objects[rawURL] = &fakeObject{
+		data:         data,
+		lastModified: lastModified,
+		etag:         etag,
+	}
+}
+
+// DeleteObject stops serving url
+func (c *FakeSourceClient) DeleteObject(rawURL string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.objects, rawURL)
+}
+
+// Calls returns the count of all method calls
+func (c *FakeSourceClient) Calls() int64 {
+	return c.calls.Load()
+}
+
+func (c *FakeSourceClient) GetContentLength(request *source.Request) (int64, error) {
+	object, err := c.getObject(request)
+	if err != nil {
+		return source.UnknownSourceFileLen, err
+	}
+
+	if rangeStr := request.Header.Get(source.Range); rangeStr != "" {
+		rg, err := rangeutils.ParseRange(rangeStr, uint64(len(object.data)))
+		if err != nil {
+			return source.UnknownSourceFileLen, err
+		}
+		return int64(rg.Length()), nil
+	}
+	return int64(len(object.data)), nil
+}
+
+func (c *FakeSourceClient) IsSupportRange(request *source.Request) (bool, error) {
+	if _, err := c.getObject(request); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (c *FakeSourceClient) IsExpired(request *source.Request, info *source.ExpireInfo) (bool, error) {
+	object, err := c.getObject(request)
+	if err != nil {
+		return false, err
+	}
+	return !(object.etag == info.ETag || object.lastModified.Format(source.LastModifiedLayout) == info.LastModified), nil
+}
+
+func (c *FakeSourceClient) Download(request *source.Request) (*source.Response, error) {
+	object, err := c.getObject(request)
+	if err != nil {
+		return nil, err
+	}
+
+	if rangeStr := request.Header.Get(source.Range); rangeStr != "" {
+		rg, err := rangeutils.ParseRange(rangeStr, uint64(len(object.data)))
+		if err != nil {
+			return nil, err
+		}
+		return newRangeResponse(object, *rg), nil
+	}
+
+	return source.NewResponse(
+		io.NopCloser(bytes.NewReader(object.data)),
+		source.WithContentLength(int64(len(object.data))),
+		source.WithExpireInfo(object.expireInfo())), nil
+}
+
+func (c *FakeSourceClient) GetLastModified(request *source.Request) (int64, error) {
+	object, err := c.getObject(request)
+	if err != nil {
+		return -1, err
+	}
+	return object.lastModified.UnixNano() / int64(time.Millisecond), nil
+}
+
+// List returns the urls of objects prefixed with request url in lexical order
+func (c *FakeSourceClient) List(request *source.Request) ([]*url.URL, error) {
+	if err := c.call(); err != nil {
+		return nil, err
+	}
+
+	prefix := strings.TrimSuffix(request.URL.String(), "/") + "/"
+	c.mu.RLock()
+	var rawURLs []string
+	for rawURL := range c.objects {
+		if strings.HasPrefix(rawURL, prefix) {
+			rawURLs = append(rawURLs, rawURL)
+		}
+	}
+	c.mu.RUnlock()
+
+	sort.Strings(rawURLs)
+	var urls []*url.URL
+	for _, rawURL := range rawURLs {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			return nil, err
+		}
+		urls = append(urls, u)
+	}
+	return urls, nil
+}
+
+func (c *FakeSourceClient) DownloadRange(request *source.Request, ranges []rangeutils.Range) ([]*source.Response, error) {
+	object, err := c.getObject(request)
+	if err != nil {
+		return nil, err
+	}
+
+	var responses []*source.Response
+	for _, rg := range ranges {
+		if rg.EndIndex >= uint64(len(object.data)) || rg.StartIndex > rg.EndIndex {
+			return nil, errors.Errorf("range %s is out of length %d", rg.String(), len(object.data))
+		}
+		responses = append(responses, newRangeResponse(object, rg))
+	}
+	return responses, nil
+}
+
+// call counts the call and returns the programmed error
+func (c *FakeSourceClient) call() error {
+	n := c.calls.Inc()
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.failures[n]
+}
+
+func (c *FakeSourceClient) getObject(request *source.Request) (*fakeObject, error) {
+	if err := c.call(); err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	object, ok := c.objects[request.URL.String()]
+	if !ok {
+		return nil, errors.Wrapf(source.ErrResourceNotReachable, "url: %s", request.URL)
+	}
+	return object, nil
+}
+
+func (o *fakeObject) expireInfo() source.ExpireInfo {
+	return source.ExpireInfo{
+		LastModified: o.lastModified.Format(source.LastModifiedLayout),
+		ETag:         o.etag,
+	}
+}
+
+func newRangeResponse(object *fakeObject, rg rangeutils.Range) *source.Response {
+	return source.NewResponse(
+		io.NopCloser(bytes.NewReader(object.data[rg.StartIndex:rg.EndIndex+1])),
+		source.WithStatus(http.StatusPartialContent, http.StatusText(http.StatusPartialContent)),
+		source.WithContentLength(int64(rg.Length())),
+		source.WithExpireInfo(object.expireInfo()))
+}
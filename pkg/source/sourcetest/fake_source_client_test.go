@@ -0,0 +1,143 @@
+/*
+ *     Copyright 2020 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sourcetest
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"d7y.io/dragonfly/v2/pkg/source"
+	"d7y.io/dragonfly/v2/pkg/util/rangeutils"
+)
+
+const (
+	testURL  = "fake://bucket/dir/object"
+	testData = "hello dragonfly"
+)
+
+func newRequest(t *testing.T, rawURL string, header map[string]string) *source.Request {
+	request, err := source.NewRequestWithHeader(rawURL, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return request
+}
+
+func TestFakeSourceClient_Download(t *testing.T) {
+	tests := []struct {
+		name   string
+		rawURL string
+		header map[string]string
+		expect func(t *testing.T, response *source.Response, err error)
+	}{
+		{
+			name:   "download object",
+			rawURL: testURL,
+			expect: func(t *testing.T, response *source.Response, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				data, err := io.ReadAll(response.Body)
+				assert.NoError(err)
+				assert.Equal(string(data), testData)
+				assert.Equal(response.ContentLength, int64(len(testData)))
+			},
+		},
+		{
+			name:   "download object with range",
+			rawURL: testURL,
+			header: map[string]string{source.Range: "6-14"},
+			expect: func(t *testing.T, response *source.Response, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				data, err := io.ReadAll(response.Body)
+				assert.NoError(err)
+				assert.Equal(string(data), "dragonfly")
+			},
+		},
+		{
+			name:   "object does not exist",
+			rawURL: "fake://bucket/notfound",
+			expect: func(t *testing.T, response *source.Response, err error) {
+				assert := assert.New(t)
+				assert.True(source.IsResourceNotReachableError(err))
+				assert.Nil(response)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			client := NewFakeSourceClient(WithObject(testURL, []byte(testData)))
+			response, err := client.Download(newRequest(t, tc.rawURL, tc.header))
+			tc.expect(t, response, err)
+		})
+	}
+}
+
+func TestFakeSourceClient_FailOnCall(t *testing.T) {
+	assert := assert.New(t)
+	client := NewFakeSourceClient(WithObject(testURL, []byte(testData)), WithFailOnCall(2, errors.New("foo")))
+
+	length, err := client.GetContentLength(newRequest(t, testURL, nil))
+	assert.NoError(err)
+	assert.Equal(length, int64(len(testData)))
+
+	_, err = client.Download(newRequest(t, testURL, nil))
+	assert.EqualError(err, "foo")
+
+	_, err = client.Download(newRequest(t, testURL, nil))
+	assert.NoError(err)
+	assert.Equal(client.Calls(), int64(3))
+}
+
+func TestFakeSourceClient_List(t *testing.T) {
+	assert := assert.New(t)
+	client := NewFakeSourceClient(
+		WithObject("fake://bucket/dir/b", []byte(testData)),
+		WithObject("fake://bucket/dir/a", []byte(testData)),
+		WithObject("fake://bucket/other/c", []byte(testData)),
+	)
+
+	urls, err := client.List(newRequest(t, "fake://bucket/dir", nil))
+	assert.NoError(err)
+	assert.Len(urls, 2)
+	assert.Equal(urls[0].String(), "fake://bucket/dir/a")
+	assert.Equal(urls[1].String(), "fake://bucket/dir/b")
+}
+
+func TestFakeSourceClient_DownloadRange(t *testing.T) {
+	assert := assert.New(t)
+	client := NewFakeSourceClient(WithObject(testURL, []byte(testData)))
+
+	responses, err := client.DownloadRange(newRequest(t, testURL, nil), []rangeutils.Range{
+		{StartIndex: 0, EndIndex: 4},
+		{StartIndex: 6, EndIndex: 14},
+	})
+	assert.NoError(err)
+	assert.Len(responses, 2)
+	for i, expected := range []string{"hello", "dragonfly"} {
+		data, err := io.ReadAll(responses[i].Body)
+		assert.NoError(err)
+		assert.Equal(string(data), expected)
+	}
+
+	_, err = client.DownloadRange(newRequest(t, testURL, nil), []rangeutils.Range{{StartIndex: 0, EndIndex: 15}})
+	assert.Error(err)
+}
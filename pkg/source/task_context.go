@@ -0,0 +1,53 @@
+/*
+ *     Copyright 2022 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package source
+
+import (
+	"context"
+
+	logger "d7y.io/dragonfly/v2/internal/dflog"
+)
+
+type taskContextKey struct{}
+
+type taskContext struct {
+	taskID string
+	peerID string
+}
+
+// WithTaskContext returns a copy of ctx with the task id and peer id, the source clients
+// log them for the requests created with the returned context
+func WithTaskContext(ctx context.Context, taskID, peerID string) context.Context {
+	return context.WithValue(ctx, taskContextKey{}, taskContext{taskID: taskID, peerID: peerID})
+}
+
+// TaskContextFrom returns the task id and peer id set by WithTaskContext
+func TaskContextFrom(ctx context.Context) (taskID, peerID string, ok bool) {
+	tc, ok := ctx.Value(taskContextKey{}).(taskContext)
+	if !ok {
+		return "", "", false
+	}
+	return tc.taskID, tc.peerID, true
+}
+
+// Logger returns a logger with the task id and peer id in the context of request
+func (r *Request) Logger() *logger.SugaredLoggerOnWith {
+	if taskID, peerID, ok := TaskContextFrom(r.Context()); ok {
+		return logger.WithTaskAndPeerID(taskID, peerID)
+	}
+	return logger.With()
+}
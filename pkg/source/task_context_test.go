@@ -0,0 +1,69 @@
+/*
+ *     Copyright 2022 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package source
+
+import (
+	"context"
+	"testing"
+
+	testifyassert "github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	logger "d7y.io/dragonfly/v2/internal/dflog"
+)
+
+func TestWithTaskContext(t *testing.T) {
+	assert := testifyassert.New(t)
+	_, _, ok := TaskContextFrom(context.Background())
+	assert.False(ok)
+
+	ctx := WithTaskContext(context.Background(), "task-1", "peer-1")
+	taskID, peerID, ok := TaskContextFrom(ctx)
+	assert.True(ok)
+	assert.Equal("task-1", taskID)
+	assert.Equal("peer-1", peerID)
+
+	// the task context is kept when the request is cloned
+	request, err := NewRequestWithContext(ctx, "http://example.com/file", nil)
+	assert.NoError(err)
+	taskID, peerID, ok = TaskContextFrom(request.Clone(request.Context()).Context())
+	assert.True(ok)
+	assert.Equal("task-1", taskID)
+	assert.Equal("peer-1", peerID)
+}
+
+func TestRequest_Logger(t *testing.T) {
+	assert := testifyassert.New(t)
+	core, logs := observer.New(zapcore.InfoLevel)
+	coreLogger := logger.CoreLogger
+	logger.SetCoreLogger(zap.New(core).Sugar())
+	defer logger.SetCoreLogger(coreLogger)
+
+	request, err := NewRequestWithContext(WithTaskContext(context.Background(), "task-1", "peer-1"), "http://example.com/file", nil)
+	assert.NoError(err)
+	request.Logger().Infof("with task context")
+	request, err = NewRequest("http://example.com/file")
+	assert.NoError(err)
+	request.Logger().Infof("without task context")
+
+	entries := logs.All()
+	assert.Len(entries, 2)
+	assert.Equal(map[string]interface{}{"taskID": "task-1", "peerID": "peer-1"}, entries[0].ContextMap())
+	assert.Empty(entries[1].ContextMap())
+}
@@ -61,4 +61,15 @@ func main() {
 		fmt.Printf("close error: %s\n", err)
 		os.Exit(1)
 	}
+
+	schemes, err := source.LoadAllPlugins("./testdata")
+	if err != nil {
+		fmt.Printf("load all plugins error: %s\n", err)
+		os.Exit(1)
+	}
+
+	if len(schemes) != 1 || schemes[0] != "dfs" {
+		fmt.Printf("loaded schemes mismatch: %v\n", schemes)
+		os.Exit(1)
+	}
 }
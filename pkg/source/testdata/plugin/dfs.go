@@ -56,6 +56,10 @@ func (c *client) GetLastModified(request *source.Request) (int64, error) {
 	panic("implement me")
 }
 
+func (c *client) Adapter(request *source.Request) *source.Request {
+	return request
+}
+
 func DragonflyPluginInit(option map[string]string) (interface{}, map[string]string, error) {
 	return &client{}, map[string]string{
 		"type":        "resource",
@@ -20,6 +20,7 @@ import (
 	"bufio"
 	"crypto/md5"
 	"crypto/sha256"
+	"crypto/sha512"
 	"encoding/hex"
 	"hash"
 	"io"
@@ -34,6 +35,7 @@ import (
 
 const (
 	Sha256Hash digest.Algorithm = "sha256"
+	Sha512Hash digest.Algorithm = "sha512"
 	Md5Hash    digest.Algorithm = "md5"
 )
 
@@ -43,6 +45,7 @@ var (
 	// Please don't use digest.Algorithm() to convert a string to digest.Algorithm.
 	Algorithms = map[string]digest.Algorithm{
 		Sha256Hash.String(): Sha256Hash,
+		Sha512Hash.String(): Sha512Hash,
 		Md5Hash.String():    Md5Hash,
 	}
 )
@@ -78,7 +81,7 @@ func Md5Bytes(bytes []byte) string {
 }
 
 // HashFile computes hash value corresponding to hashType,
-// hashType is from digestutils.Md5Hash and digestutils.Sha256Hash.
+// hashType is from digestutils.Md5Hash, digestutils.Sha256Hash and digestutils.Sha512Hash.
 func HashFile(file string, hashType digest.Algorithm) string {
 	if !fileutils.IsRegular(file) {
 		return ""
@@ -91,12 +94,8 @@ func HashFile(file string, hashType digest.Algorithm) string {
 
 	defer f.Close()
 
-	var h hash.Hash
-	if hashType == Md5Hash {
-		h = md5.New()
-	} else if hashType == Sha256Hash {
-		h = sha256.New()
-	} else {
+	h := CreateHash(hashType.String())
+	if h == nil {
 		return ""
 	}
 
@@ -119,11 +118,23 @@ func Parse(digest string) []string {
 	return strings.Split(digest, ":")
 }
 
+// ParseDigest splits digest like sha256:xxx into algorithm and encoded value,
+// algorithm is empty when digest has no algorithm prefix.
+func ParseDigest(value string) (string, string) {
+	value = strings.Trim(value, " ")
+	if i := strings.Index(value, ":"); i >= 0 {
+		return value[:i], value[i+1:]
+	}
+	return "", value
+}
+
 func CreateHash(hashType string) hash.Hash {
 	algo := Algorithms[hashType]
 	switch algo {
 	case Sha256Hash:
 		return sha256.New()
+	case Sha512Hash:
+		return sha512.New()
 	case Md5Hash:
 		return md5.New()
 	default:
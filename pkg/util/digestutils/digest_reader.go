@@ -17,8 +17,8 @@
 package digestutils
 
 import (
-	"crypto/md5"
 	"encoding/hex"
+	"fmt"
 	"hash"
 	"io"
 
@@ -31,11 +31,34 @@ var (
 	ErrDigestNotMatch = errors.New("digest not match")
 )
 
+// DigestMismatchError is returned by the digest reader when the digest of contents does not match,
+// PieceNum is -1 when the contents are not a piece. errors.Is(err, ErrDigestNotMatch) is true for it.
+type DigestMismatchError struct {
+	PieceNum int32
+	Expected string
+	Got      string
+}
+
+func (e *DigestMismatchError) Error() string {
+	if e.PieceNum < 0 {
+		return fmt.Sprintf("%s, expected: %s, got: %s", ErrDigestNotMatch, e.Expected, e.Got)
+	}
+	return fmt.Sprintf("piece %d %s, expected: %s, got: %s", e.PieceNum, ErrDigestNotMatch, e.Expected, e.Got)
+}
+
+func (e *DigestMismatchError) Is(target error) bool {
+	return target == ErrDigestNotMatch
+}
+
 // digestReader reads stream with RateLimiter.
 type digestReader struct {
 	r      io.Reader
 	hash   hash.Hash
 	digest string
+	// pieceNum is the piece number of contents, -1 when the contents are not a piece
+	pieceNum int32
+	// err is returned when the digest algorithm is not supported
+	err error
 	*logger.SugaredLoggerOnWith
 }
 
@@ -51,16 +74,46 @@ func NewDigestReader(log *logger.SugaredLoggerOnWith, reader io.Reader, digest .
 	if len(digest) > 0 {
 		d = digest[0]
 	}
-	return &digestReader{
+	return NewDigestReaderWithAlgorithm(log, reader, "", d)
+}
+
+// NewDigestReaderWithAlgorithm returns a reader which verifies digest with algorithm md5, sha256 or sha512.
+// The algorithm prefix of digest like sha256:xxx takes precedence, md5 is used when neither is set.
+func NewDigestReaderWithAlgorithm(log *logger.SugaredLoggerOnWith, reader io.Reader, algorithm string, digest string) io.Reader {
+	return newDigestReader(log, reader, -1, algorithm, digest)
+}
+
+// NewPieceDigestReader is like NewDigestReaderWithAlgorithm, the DigestMismatchError carries pieceNum.
+func NewPieceDigestReader(log *logger.SugaredLoggerOnWith, reader io.Reader, pieceNum int32, algorithm string, digest string) io.Reader {
+	return newDigestReader(log, reader, pieceNum, algorithm, digest)
+}
+
+func newDigestReader(log *logger.SugaredLoggerOnWith, reader io.Reader, pieceNum int32, algorithm string, digest string) io.Reader {
+	prefix, encoded := ParseDigest(digest)
+	if prefix != "" {
+		algorithm = prefix
+	}
+	if algorithm == "" {
+		algorithm = Md5Hash.String()
+	}
+
+	dr := &digestReader{
 		SugaredLoggerOnWith: log,
-		digest:              d,
-		// TODO support more digest method like sha1, sha256
-		hash: md5.New(),
-		r:    reader,
+		digest:              encoded,
+		pieceNum:            pieceNum,
+		hash:                CreateHash(algorithm),
+		r:                   reader,
+	}
+	if dr.hash == nil {
+		dr.err = errors.Errorf("unsupported digest algorithm %s", algorithm)
 	}
+	return dr
 }
 
 func (dr *digestReader) Read(p []byte) (int, error) {
+	if dr.err != nil {
+		return 0, dr.err
+	}
 	n, err := dr.r.Read(p)
 	if err != nil && err != io.EOF {
 		return n, err
@@ -72,14 +125,17 @@ func (dr *digestReader) Read(p []byte) (int, error) {
 		digest := dr.Digest()
 		if digest != dr.digest {
 			dr.Warnf("digest not match, desired: %s, actual: %s", dr.digest, digest)
-			return n, ErrDigestNotMatch
+			return n, &DigestMismatchError{PieceNum: dr.pieceNum, Expected: dr.digest, Got: digest}
 		}
 		dr.Debugf("digest match: %s", digest)
 	}
 	return n, err
 }
 
-// Digest returns the digest of contents.
+// Digest returns the hex encoded digest of contents without algorithm prefix.
 func (dr *digestReader) Digest() string {
-	return hex.EncodeToString(dr.hash.Sum(nil)[:16])
+	if dr.hash == nil {
+		return ""
+	}
+	return hex.EncodeToString(dr.hash.Sum(nil))
 }
@@ -19,7 +19,10 @@ package digestutils
 import (
 	"bytes"
 	"crypto/md5"
+	"crypto/sha256"
+	"crypto/sha512"
 	"encoding/hex"
+	"errors"
 	"io"
 	"os"
 	"testing"
@@ -48,3 +51,87 @@ func TestNewDigestReader(t *testing.T) {
 	assert.Nil(err)
 	assert.Equal(testBytes, data)
 }
+
+func TestNewDigestReaderWithAlgorithm(t *testing.T) {
+	testBytes := []byte("hello world")
+	md5Sum := md5.Sum(testBytes)
+	sha256Sum := sha256.Sum256(testBytes)
+	sha512Sum := sha512.Sum512(testBytes)
+
+	tests := []struct {
+		name      string
+		algorithm string
+		digest    string
+		expectErr error
+	}{
+		{
+			name:   "default md5",
+			digest: hex.EncodeToString(md5Sum[:]),
+		},
+		{
+			name:      "md5",
+			algorithm: "md5",
+			digest:    hex.EncodeToString(md5Sum[:]),
+		},
+		{
+			name:      "sha256",
+			algorithm: "sha256",
+			digest:    hex.EncodeToString(sha256Sum[:]),
+		},
+		{
+			name:      "sha512",
+			algorithm: "sha512",
+			digest:    hex.EncodeToString(sha512Sum[:]),
+		},
+		{
+			name:   "sha256 prefix",
+			digest: "sha256:" + hex.EncodeToString(sha256Sum[:]),
+		},
+		{
+			name:      "sha512 prefix takes precedence",
+			algorithm: "md5",
+			digest:    "sha512:" + hex.EncodeToString(sha512Sum[:]),
+		},
+		{
+			name:      "sha256 not match",
+			algorithm: "sha256",
+			digest:    hex.EncodeToString(md5Sum[:]),
+			expectErr: ErrDigestNotMatch,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := testifyassert.New(t)
+			reader := NewDigestReaderWithAlgorithm(logger.With("test", "test"), bytes.NewBuffer(testBytes), tc.algorithm, tc.digest)
+			data, err := io.ReadAll(reader)
+			if tc.expectErr == nil {
+				assert.Nil(err)
+				assert.Equal(testBytes, data)
+				return
+			}
+			assert.ErrorIs(err, tc.expectErr)
+		})
+	}
+}
+
+func TestNewDigestReaderWithAlgorithm_Unsupported(t *testing.T) {
+	assert := testifyassert.New(t)
+	reader := NewDigestReaderWithAlgorithm(logger.With("test", "test"), bytes.NewBufferString("hello world"), "sha1", "")
+	_, err := io.ReadAll(reader)
+	assert.EqualError(err, "unsupported digest algorithm sha1")
+}
+
+func TestNewPieceDigestReader_Mismatch(t *testing.T) {
+	assert := testifyassert.New(t)
+	md5Sum := md5.Sum([]byte("hello world"))
+	reader := NewPieceDigestReader(logger.With("test", "test"), bytes.NewBufferString("hello dragonfly"), 3, "", hex.EncodeToString(md5Sum[:]))
+	_, err := io.ReadAll(reader)
+	assert.ErrorIs(err, ErrDigestNotMatch)
+
+	var mismatch *DigestMismatchError
+	assert.True(errors.As(err, &mismatch))
+	assert.Equal(int32(3), mismatch.PieceNum)
+	assert.Equal(hex.EncodeToString(md5Sum[:]), mismatch.Expected)
+	assert.Equal(Md5Bytes([]byte("hello dragonfly")), mismatch.Got)
+}
@@ -34,6 +34,16 @@ func TestSha256(t *testing.T) {
 	assert.Equal(t, expected, Sha256("hello"))
 }
 
+func TestParseDigest(t *testing.T) {
+	algorithm, encoded := ParseDigest("sha256:2cf24dba")
+	assert.Equal(t, "sha256", algorithm)
+	assert.Equal(t, "2cf24dba", encoded)
+
+	algorithm, encoded = ParseDigest("5d41402a")
+	assert.Equal(t, "", algorithm)
+	assert.Equal(t, "5d41402a", encoded)
+}
+
 func TestMd5Bytes(t *testing.T) {
 	var expected = "5d41402abc4b2a76b9719d911017c592"
 	assert.Equal(t, expected, Md5Bytes([]byte("hello")))
@@ -16,7 +16,10 @@
 
 package httputils
 
-import "net/http"
+import (
+	"net/http"
+	"strings"
+)
 
 // HeaderToMap coverts request headers to map[string]string.
 func HeaderToMap(header http.Header) map[string]string {
@@ -46,3 +49,19 @@ func PickHeader(header http.Header, key, defaultValue string) string {
 
 	return defaultValue
 }
+
+// PickHeaderValues picks all values of header with key, every value is split by sep,
+// the empty values are dropped.
+func PickHeaderValues(header http.Header, key, sep string) []string {
+	var values []string
+	for _, v := range header.Values(key) {
+		for _, s := range strings.Split(v, sep) {
+			if s = strings.TrimSpace(s); s != "" {
+				values = append(values, s)
+			}
+		}
+	}
+
+	header.Del(key)
+	return values
+}
@@ -155,3 +155,51 @@ func TestPickHeader(t *testing.T) {
 		})
 	}
 }
+
+func TestPickHeaderValues(t *testing.T) {
+	tests := []struct {
+		name   string
+		header http.Header
+		key    string
+		expect []string
+	}{
+		{
+			name: "Pick single value",
+			header: http.Header{
+				"Foo": {"foo"},
+			},
+			key:    "Foo",
+			expect: []string{"foo"},
+		},
+		{
+			name: "Pick comma-separated values",
+			header: http.Header{
+				"Foo": {"foo, bar,,baz"},
+			},
+			key:    "Foo",
+			expect: []string{"foo", "bar", "baz"},
+		},
+		{
+			name: "Pick multiple header values",
+			header: http.Header{
+				"Foo": {"foo,bar", "baz"},
+			},
+			key:    "Foo",
+			expect: []string{"foo", "bar", "baz"},
+		},
+		{
+			name:   "Pick the non-existent key",
+			header: http.Header{},
+			key:    "Foo",
+			expect: nil,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := testifyassert.New(t)
+			assert.Equal(tc.expect, PickHeaderValues(tc.header, tc.key, ","))
+			assert.Equal("", tc.header.Get(tc.key))
+		})
+	}
+}
@@ -18,6 +18,7 @@ package urlutils
 
 import (
 	"net/url"
+	"strings"
 
 	"d7y.io/dragonfly/v2/pkg/util/stringutils"
 )
@@ -44,6 +45,19 @@ func FilterURLParam(str string, filters []string) string {
 	return u.String()
 }
 
+// SplitFilter splits filter separated by & or comma character, the empty filters are dropped.
+func SplitFilter(filter string) []string {
+	var filters []string
+	for _, f := range strings.FieldsFunc(filter, func(r rune) bool {
+		return r == '&' || r == ','
+	}) {
+		if f = strings.TrimSpace(f); f != "" {
+			filters = append(filters, f)
+		}
+	}
+	return filters
+}
+
 // IsValidURL returns whether the string url is a valid URL.
 func IsValidURL(str string) bool {
 	u, err := url.Parse(str)
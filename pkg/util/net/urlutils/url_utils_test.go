@@ -32,3 +32,9 @@ func TestFilterURLParam(t *testing.T) {
 	url := "http://www.xx.yy/path?u=f&x=y&m=z&x=s#size"
 	assert.Equal(t, "http://www.xx.yy/path?u=f#size", FilterURLParam(url, []string{"x", "m"}))
 }
+
+func TestSplitFilter(t *testing.T) {
+	assert.Nil(t, SplitFilter(""))
+	assert.Equal(t, []string{"x", "m"}, SplitFilter("x&m"))
+	assert.Equal(t, []string{"x", "m", "u"}, SplitFilter("x, m&&u"))
+}
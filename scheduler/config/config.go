@@ -64,8 +64,8 @@ func New() *Config {
 		Scheduler: &SchedulerConfig{
 			Algorithm:            "default",
 			BackSourceCount:      3,
-			RetryBackSourceLimit: 5,
-			RetryLimit:           20,
+			RetryBackSourceLimit: DefaultRetryBackSourceLimit,
+			RetryLimit:           DefaultRetryLimit,
 			RetryInterval:        200 * time.Millisecond,
 			GC: &GCConfig{
 				PeerGCInterval: 10 * time.Minute,
@@ -75,6 +75,13 @@ func New() *Config {
 				HostGCInterval: 30 * time.Minute,
 				HostTTL:        48 * time.Hour,
 			},
+			Score: &ScoreConfig{
+				FreeUploadLoadWeight: 0.5,
+				RecencyWeight:        0.2,
+				AffinityWeight:       0.2,
+				CDNBoost:             0.1,
+				RecencyWindow:        10 * time.Minute,
+			},
 		},
 		DynConfig: &DynConfig{
 			RefreshInterval: 1 * time.Minute,
@@ -246,6 +253,30 @@ type SchedulerConfig struct {
 
 	// Task and peer gc configuration
 	GC *GCConfig `yaml:"gc" mapstructure:"gc"`
+
+	// Host score configuration
+	Score *ScoreConfig `yaml:"score" mapstructure:"score"`
+
+	// PeerCountUploadLoad counts host upload load by peers instead of in-flight piece uploads,
+	// it keeps the legacy behavior during migration
+	PeerCountUploadLoad bool `yaml:"peerCountUploadLoad" mapstructure:"peerCountUploadLoad"`
+}
+
+type ScoreConfig struct {
+	// Weight of free upload load
+	FreeUploadLoadWeight float64 `yaml:"freeUploadLoadWeight" mapstructure:"freeUploadLoadWeight"`
+
+	// Weight of the recency of host update time
+	RecencyWeight float64 `yaml:"recencyWeight" mapstructure:"recencyWeight"`
+
+	// Weight of idc and location affinity
+	AffinityWeight float64 `yaml:"affinityWeight" mapstructure:"affinityWeight"`
+
+	// Weight boosted for cdn host
+	CDNBoost float64 `yaml:"cdnBoost" mapstructure:"cdnBoost"`
+
+	// Host which is not updated within recency window gets minimum recency score
+	RecencyWindow time.Duration `yaml:"recencyWindow" mapstructure:"recencyWindow"`
 }
 
 type GCConfig struct {
@@ -266,6 +297,10 @@ type GCConfig struct {
 
 	// Host time to live
 	HostTTL time.Duration `yaml:"hostTTL" mapstructure:"hostTTL"`
+
+	// HostGCIncludeCDN indicates whether to reclaim cdn hosts,
+	// cdn hosts are exempt from host gc by default
+	HostGCIncludeCDN bool `yaml:"hostGCIncludeCDN" mapstructure:"hostGCIncludeCDN"`
 }
 
 type DynConfig struct {
@@ -22,6 +22,8 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sync"
 	"time"
 
 	logger "d7y.io/dragonfly/v2/internal/dflog"
@@ -39,6 +41,17 @@ var (
 	watchInterval = 10 * time.Second
 )
 
+const (
+	// DefaultRetryLimit is the default limit times of scheduling
+	DefaultRetryLimit = 20
+
+	// DefaultRetryBackSourceLimit is the default limit times of scheduling before back-to-source
+	DefaultRetryBackSourceLimit = 5
+
+	// DefaultCandidateParentLimit is the default limit of candidate parents
+	DefaultCandidateParentLimit = 4
+)
+
 type DynconfigData struct {
 	CDNs             []*CDN            `yaml:"cdns" mapstructure:"cdns" json:"cdns"`
 	SchedulerCluster *SchedulerCluster `yaml:"schedulerCluster" mapstructure:"schedulerCluster" json:"scheduler_cluster"`
@@ -64,6 +77,38 @@ type SchedulerCluster struct {
 	ClientConfig []byte `yaml:"clientConfig" mapstructure:"clientConfig" json:"client_config"`
 }
 
+// Interest is a set of dynconfig data fields observed by observer
+type Interest uint8
+
+const (
+	// InterestCDNs observes the cdns
+	InterestCDNs Interest = 1 << iota
+
+	// InterestSchedulerCluster observes the scheduler cluster
+	InterestSchedulerCluster
+
+	// InterestAll observes all fields
+	InterestAll = InterestCDNs | InterestSchedulerCluster
+)
+
+// diffDynconfigData returns the fields changed from last to current
+func diffDynconfigData(last, current *DynconfigData) Interest {
+	if last == nil {
+		return InterestAll
+	}
+
+	var changed Interest
+	if !reflect.DeepEqual(last.CDNs, current.CDNs) {
+		changed |= InterestCDNs
+	}
+
+	if !reflect.DeepEqual(last.SchedulerCluster, current.SchedulerCluster) {
+		changed |= InterestSchedulerCluster
+	}
+
+	return changed
+}
+
 func (c *CDN) GetCDNClusterConfig() (types.CDNClusterConfig, bool) {
 	if c.CDNCluster == nil {
 		return types.CDNClusterConfig{}, false
@@ -84,19 +129,34 @@ type DynconfigInterface interface {
 	// Get the client config.
 	GetSchedulerClusterClientConfig() (types.SchedulerClusterClientConfig, bool)
 
+	// Get the retry limit of scheduler cluster, returns DefaultRetryLimit and false when it is unset.
+	GetRetryLimit() (int, bool)
+
+	// Get the retry back-to-source limit of scheduler cluster,
+	// returns DefaultRetryBackSourceLimit and false when it is unset.
+	GetRetryBackSourceLimit() (int, bool)
+
+	// Get the candidate parent limit of scheduler cluster,
+	// returns DefaultCandidateParentLimit and false when it is unset.
+	GetCandidateParentLimit() (int, bool)
+
 	// Get the cdn cluster config.
 	GetCDNClusterConfig(uint) (types.CDNClusterConfig, bool)
 
 	// Get the dynamic config from manager.
 	Get() (*DynconfigData, error)
 
+	// GetWithRevision refreshes the dynamic config and returns it with the revision,
+	// the revision is increased only when the dynamic config is changed.
+	GetWithRevision() (*DynconfigData, uint64, error)
+
 	// Register allows an instance to register itself to listen/observe events.
 	Register(Observer)
 
 	// Deregister allows an instance to remove itself from the collection of observers/listeners.
 	Deregister(Observer)
 
-	// Notify publishes new events to listeners.
+	// Notify publishes new events to listeners whose interests are changed.
 	Notify() error
 
 	// Serve the dynconfig listening service.
@@ -111,12 +171,26 @@ type Observer interface {
 	OnNotify(*DynconfigData)
 }
 
+// InterestedObserver is notified only when its interests are changed,
+// observer which does not implement it is notified when any field is changed.
+type InterestedObserver interface {
+	Observer
+
+	// Interests returns the fields of dynconfig data observed.
+	Interests() Interest
+}
+
 type dynconfig struct {
 	*dc.Dynconfig
 	observers map[Observer]struct{}
 	done      chan bool
 	cdnDir    string
 	cachePath string
+
+	// mu guards data and revision
+	mu       sync.Mutex
+	data     *DynconfigData
+	revision uint64
 }
 
 // TODO(Gaius) Rely on manager to delete cdnDirPath
@@ -152,7 +226,7 @@ func (d *dynconfig) GetSchedulerClusterConfig() (types.SchedulerClusterConfig, b
 		return types.SchedulerClusterConfig{}, false
 	}
 
-	if data.SchedulerCluster != nil {
+	if data.SchedulerCluster == nil {
 		return types.SchedulerClusterConfig{}, false
 	}
 
@@ -182,6 +256,33 @@ func (d *dynconfig) GetSchedulerClusterClientConfig() (types.SchedulerClusterCli
 	return config, true
 }
 
+func (d *dynconfig) GetRetryLimit() (int, bool) {
+	config, ok := d.GetSchedulerClusterConfig()
+	if !ok || config.RetryLimit == 0 {
+		return DefaultRetryLimit, false
+	}
+
+	return int(config.RetryLimit), true
+}
+
+func (d *dynconfig) GetRetryBackSourceLimit() (int, bool) {
+	config, ok := d.GetSchedulerClusterConfig()
+	if !ok || config.RetryBackSourceLimit == 0 {
+		return DefaultRetryBackSourceLimit, false
+	}
+
+	return int(config.RetryBackSourceLimit), true
+}
+
+func (d *dynconfig) GetCandidateParentLimit() (int, bool) {
+	config, ok := d.GetSchedulerClusterConfig()
+	if !ok || config.CandidateParentLimit == 0 {
+		return DefaultCandidateParentLimit, false
+	}
+
+	return int(config.CandidateParentLimit), true
+}
+
 func (d *dynconfig) GetCDNClusterConfig(id uint) (types.CDNClusterConfig, bool) {
 	data, err := d.Get()
 	if err != nil {
@@ -266,17 +367,45 @@ func (d *dynconfig) Deregister(l Observer) {
 	delete(d.observers, l)
 }
 
+func (d *dynconfig) GetWithRevision() (*DynconfigData, uint64, error) {
+	return d.refresh()
+}
+
 func (d *dynconfig) Notify() error {
+	_, _, err := d.refresh()
+	return err
+}
+
+// refresh diffs the dynamic config against the last one,
+// then increases the revision and notifies observers when it is changed.
+func (d *dynconfig) refresh() (*DynconfigData, uint64, error) {
 	config, err := d.Get()
 	if err != nil {
-		return err
+		return nil, 0, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	changed := diffDynconfigData(d.data, config)
+	if changed == 0 {
+		return d.data, d.revision, nil
 	}
 
+	d.data = config
+	d.revision++
 	for o := range d.observers {
-		o.OnNotify(config)
+		interests := InterestAll
+		if io, ok := o.(InterestedObserver); ok {
+			interests = io.Interests()
+		}
+
+		if interests&changed != 0 {
+			o.OnNotify(config)
+		}
 	}
 
-	return nil
+	return config, d.revision, nil
 }
 
 func (d *dynconfig) Serve() error {
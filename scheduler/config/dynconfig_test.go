@@ -185,3 +185,212 @@ func TestDynconfig_GetCDNFromDirPath(t *testing.T) {
 		})
 	}
 }
+
+type recordObserver struct {
+	interests Interest
+	notified  int
+}
+
+func (o *recordObserver) OnNotify(*DynconfigData) {
+	o.notified++
+}
+
+type recordInterestedObserver struct {
+	recordObserver
+}
+
+func (o *recordInterestedObserver) Interests() Interest {
+	return o.interests
+}
+
+func TestDynconfig_Notify(t *testing.T) {
+	assert := assert.New(t)
+	cdnDir := t.TempDir()
+	writeCDN := func(name string, data []byte) {
+		if err := os.WriteFile(filepath.Join(cdnDir, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeCDN("cdn_1.json", []byte(`{"id": 1, "host_name": "foo", "ip": "127.0.0.1", "port": 8001}`))
+
+	d, err := NewDynconfig(nil, t.TempDir(), &Config{
+		DynConfig: &DynConfig{
+			CDNDir: cdnDir,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	observer := &recordObserver{}
+	cdnObserver := &recordInterestedObserver{recordObserver{interests: InterestCDNs}}
+	schedulerClusterObserver := &recordInterestedObserver{recordObserver{interests: InterestSchedulerCluster}}
+	d.Register(observer)
+	d.Register(cdnObserver)
+	d.Register(schedulerClusterObserver)
+
+	// The first refresh notifies all observers
+	data, revision, err := d.GetWithRevision()
+	assert.NoError(err)
+	assert.Len(data.CDNs, 1)
+	assert.Equal(revision, uint64(1))
+	assert.Equal(observer.notified, 1)
+	assert.Equal(cdnObserver.notified, 1)
+	assert.Equal(schedulerClusterObserver.notified, 1)
+
+	// Unchanged config does not increase the revision nor notify observers
+	assert.NoError(d.Notify())
+	_, revision, err = d.GetWithRevision()
+	assert.NoError(err)
+	assert.Equal(revision, uint64(1))
+	assert.Equal(observer.notified, 1)
+	assert.Equal(cdnObserver.notified, 1)
+
+	// Changed cdns only notify observers interested in cdns
+	writeCDN("cdn_2.json", []byte(`{"id": 2, "host_name": "bar", "ip": "127.0.0.1", "port": 8001}`))
+	assert.NoError(d.Notify())
+	data, revision, err = d.GetWithRevision()
+	assert.NoError(err)
+	assert.Len(data.CDNs, 2)
+	assert.Equal(revision, uint64(2))
+	assert.Equal(observer.notified, 2)
+	assert.Equal(cdnObserver.notified, 2)
+	assert.Equal(schedulerClusterObserver.notified, 1)
+}
+
+func TestDiffDynconfigData(t *testing.T) {
+	tests := []struct {
+		name    string
+		last    *DynconfigData
+		current *DynconfigData
+		expect  Interest
+	}{
+		{
+			name:    "last is nil",
+			current: &DynconfigData{},
+			expect:  InterestAll,
+		},
+		{
+			name:    "nothing changed",
+			last:    &DynconfigData{CDNs: []*CDN{{ID: 1}}},
+			current: &DynconfigData{CDNs: []*CDN{{ID: 1}}},
+			expect:  0,
+		},
+		{
+			name:    "cdns changed",
+			last:    &DynconfigData{CDNs: []*CDN{{ID: 1}}},
+			current: &DynconfigData{CDNs: []*CDN{{ID: 2}}},
+			expect:  InterestCDNs,
+		},
+		{
+			name:    "scheduler cluster changed",
+			last:    &DynconfigData{},
+			current: &DynconfigData{SchedulerCluster: &SchedulerCluster{Config: []byte("{}")}},
+			expect:  InterestSchedulerCluster,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			assert.Equal(diffDynconfigData(tc.last, tc.current), tc.expect)
+		})
+	}
+}
+
+func TestDynconfig_GetSchedulerClusterConfigFields(t *testing.T) {
+	mockConfig := &Config{
+		DynConfig: &DynConfig{
+			RefreshInterval: 10 * time.Second,
+		},
+		Server: &ServerConfig{
+			Host: "localhost",
+		},
+		Manager: &ManagerConfig{
+			SchedulerClusterID: 1,
+		},
+	}
+
+	tests := []struct {
+		name   string
+		mock   func(m *mocks.MockClientMockRecorder)
+		expect func(t *testing.T, d DynconfigInterface)
+	}{
+		{
+			name: "get fields from scheduler cluster config",
+			mock: func(m *mocks.MockClientMockRecorder) {
+				m.GetScheduler(gomock.Any()).Return(&manager.Scheduler{
+					SchedulerCluster: &manager.SchedulerCluster{
+						Config: []byte(`{"retry_limit": 10, "retry_back_source_limit": 3, "candidate_parent_limit": 8}`),
+					},
+				}, nil).Times(1)
+			},
+			expect: func(t *testing.T, d DynconfigInterface) {
+				assert := assert.New(t)
+				retryLimit, ok := d.GetRetryLimit()
+				assert.True(ok)
+				assert.Equal(retryLimit, 10)
+
+				retryBackSourceLimit, ok := d.GetRetryBackSourceLimit()
+				assert.True(ok)
+				assert.Equal(retryBackSourceLimit, 3)
+
+				candidateParentLimit, ok := d.GetCandidateParentLimit()
+				assert.True(ok)
+				assert.Equal(candidateParentLimit, 8)
+			},
+		},
+		{
+			name: "fields are unset",
+			mock: func(m *mocks.MockClientMockRecorder) {
+				m.GetScheduler(gomock.Any()).Return(&manager.Scheduler{
+					SchedulerCluster: &manager.SchedulerCluster{
+						Config: []byte(`{"retry_limit": 10}`),
+					},
+				}, nil).Times(1)
+			},
+			expect: func(t *testing.T, d DynconfigInterface) {
+				assert := assert.New(t)
+				retryLimit, ok := d.GetRetryLimit()
+				assert.True(ok)
+				assert.Equal(retryLimit, 10)
+
+				retryBackSourceLimit, ok := d.GetRetryBackSourceLimit()
+				assert.False(ok)
+				assert.Equal(retryBackSourceLimit, DefaultRetryBackSourceLimit)
+
+				candidateParentLimit, ok := d.GetCandidateParentLimit()
+				assert.False(ok)
+				assert.Equal(candidateParentLimit, DefaultCandidateParentLimit)
+			},
+		},
+		{
+			name: "scheduler cluster does not exist",
+			mock: func(m *mocks.MockClientMockRecorder) {
+				m.GetScheduler(gomock.Any()).Return(&manager.Scheduler{}, nil).Times(1)
+			},
+			expect: func(t *testing.T, d DynconfigInterface) {
+				assert := assert.New(t)
+				retryLimit, ok := d.GetRetryLimit()
+				assert.False(ok)
+				assert.Equal(retryLimit, DefaultRetryLimit)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctl := gomock.NewController(t)
+			defer ctl.Finish()
+			mockManagerClient := mocks.NewMockClient(ctl)
+			tc.mock(mockManagerClient.EXPECT())
+
+			d, err := NewDynconfig(mockManagerClient, t.TempDir(), mockConfig)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tc.expect(t, d)
+		})
+	}
+}
@@ -77,6 +77,51 @@ func (mr *MockDynconfigInterfaceMockRecorder) GetCDNClusterConfig(arg0 interface
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCDNClusterConfig", reflect.TypeOf((*MockDynconfigInterface)(nil).GetCDNClusterConfig), arg0)
 }
 
+// GetCandidateParentLimit mocks base method.
+func (m *MockDynconfigInterface) GetCandidateParentLimit() (int, bool) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCandidateParentLimit")
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(bool)
+	return ret0, ret1
+}
+
+// GetCandidateParentLimit indicates an expected call of GetCandidateParentLimit.
+func (mr *MockDynconfigInterfaceMockRecorder) GetCandidateParentLimit() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCandidateParentLimit", reflect.TypeOf((*MockDynconfigInterface)(nil).GetCandidateParentLimit))
+}
+
+// GetRetryBackSourceLimit mocks base method.
+func (m *MockDynconfigInterface) GetRetryBackSourceLimit() (int, bool) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRetryBackSourceLimit")
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(bool)
+	return ret0, ret1
+}
+
+// GetRetryBackSourceLimit indicates an expected call of GetRetryBackSourceLimit.
+func (mr *MockDynconfigInterfaceMockRecorder) GetRetryBackSourceLimit() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRetryBackSourceLimit", reflect.TypeOf((*MockDynconfigInterface)(nil).GetRetryBackSourceLimit))
+}
+
+// GetRetryLimit mocks base method.
+func (m *MockDynconfigInterface) GetRetryLimit() (int, bool) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRetryLimit")
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(bool)
+	return ret0, ret1
+}
+
+// GetRetryLimit indicates an expected call of GetRetryLimit.
+func (mr *MockDynconfigInterfaceMockRecorder) GetRetryLimit() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRetryLimit", reflect.TypeOf((*MockDynconfigInterface)(nil).GetRetryLimit))
+}
+
 // GetSchedulerClusterClientConfig mocks base method.
 func (m *MockDynconfigInterface) GetSchedulerClusterClientConfig() (types.SchedulerClusterClientConfig, bool) {
 	m.ctrl.T.Helper()
@@ -107,6 +152,22 @@ func (mr *MockDynconfigInterfaceMockRecorder) GetSchedulerClusterConfig() *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSchedulerClusterConfig", reflect.TypeOf((*MockDynconfigInterface)(nil).GetSchedulerClusterConfig))
 }
 
+// GetWithRevision mocks base method.
+func (m *MockDynconfigInterface) GetWithRevision() (*config.DynconfigData, uint64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWithRevision")
+	ret0, _ := ret[0].(*config.DynconfigData)
+	ret1, _ := ret[1].(uint64)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetWithRevision indicates an expected call of GetWithRevision.
+func (mr *MockDynconfigInterfaceMockRecorder) GetWithRevision() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWithRevision", reflect.TypeOf((*MockDynconfigInterface)(nil).GetWithRevision))
+}
+
 // Notify mocks base method.
 func (m *MockDynconfigInterface) Notify() error {
 	m.ctrl.T.Helper()
@@ -195,3 +256,52 @@ func (mr *MockObserverMockRecorder) OnNotify(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnNotify", reflect.TypeOf((*MockObserver)(nil).OnNotify), arg0)
 }
+
+// MockInterestedObserver is a mock of InterestedObserver interface.
+type MockInterestedObserver struct {
+	ctrl     *gomock.Controller
+	recorder *MockInterestedObserverMockRecorder
+}
+
+// MockInterestedObserverMockRecorder is the mock recorder for MockInterestedObserver.
+type MockInterestedObserverMockRecorder struct {
+	mock *MockInterestedObserver
+}
+
+// NewMockInterestedObserver creates a new mock instance.
+func NewMockInterestedObserver(ctrl *gomock.Controller) *MockInterestedObserver {
+	mock := &MockInterestedObserver{ctrl: ctrl}
+	mock.recorder = &MockInterestedObserverMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockInterestedObserver) EXPECT() *MockInterestedObserverMockRecorder {
+	return m.recorder
+}
+
+// Interests mocks base method.
+func (m *MockInterestedObserver) Interests() config.Interest {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Interests")
+	ret0, _ := ret[0].(config.Interest)
+	return ret0
+}
+
+// Interests indicates an expected call of Interests.
+func (mr *MockInterestedObserverMockRecorder) Interests() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Interests", reflect.TypeOf((*MockInterestedObserver)(nil).Interests))
+}
+
+// OnNotify mocks base method.
+func (m *MockInterestedObserver) OnNotify(arg0 *config.DynconfigData) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnNotify", arg0)
+}
+
+// OnNotify indicates an expected call of OnNotify.
+func (mr *MockInterestedObserverMockRecorder) OnNotify(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnNotify", reflect.TypeOf((*MockInterestedObserver)(nil).OnNotify), arg0)
+}
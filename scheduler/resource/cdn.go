@@ -20,8 +20,9 @@ package resource
 
 import (
 	"context"
-	"fmt"
+	"net"
 	"reflect"
+	"strconv"
 
 	"github.com/pkg/errors"
 	"google.golang.org/grpc"
@@ -203,6 +204,11 @@ func newCDNClient(dynconfig config.DynconfigInterface, hostManager HostManager,
 	return dc, nil
 }
 
+// Interests returns the dynconfig fields observed by cdn client
+func (c *cdnClient) Interests() config.Interest {
+	return config.InterestCDNs
+}
+
 // Dynamic config notify function
 func (c *cdnClient) OnNotify(data *config.DynconfigData) {
 	ips := getCDNIPs(data.CDNs)
@@ -239,14 +245,14 @@ func cdnsToHosts(cdns []*config.CDN) map[string]*Host {
 	hosts := map[string]*Host{}
 	for _, cdn := range cdns {
 		var netTopology string
-		options := []HostOption{WithIsCDN(true)}
+		var options []HostOption
 		if config, ok := cdn.GetCDNClusterConfig(); ok {
 			options = append(options, WithUploadLoadLimit(int32(config.LoadLimit)))
 			netTopology = config.NetTopology
 		}
 
 		id := idgen.CDNHostID(cdn.Hostname, cdn.Port)
-		hosts[id] = NewHost(&rpcscheduler.PeerHost{
+		hosts[id] = NewCDNHost(&rpcscheduler.PeerHost{
 			Uuid:        id,
 			Ip:          cdn.IP,
 			RpcPort:     cdn.Port,
@@ -266,7 +272,7 @@ func cdnsToNetAddrs(cdns []*config.CDN) []dfnet.NetAddr {
 	for _, cdn := range cdns {
 		netAddrs = append(netAddrs, dfnet.NetAddr{
 			Type: dfnet.TCP,
-			Addr: fmt.Sprintf("%s:%d", cdn.IP, cdn.Port),
+			Addr: net.JoinHostPort(cdn.IP, strconv.Itoa(int(cdn.Port))),
 		})
 	}
 
@@ -94,6 +94,34 @@ func (m *MockCDNClient) EXPECT() *MockCDNClientMockRecorder {
 	return m.recorder
 }
 
+// CheckHealth mocks base method.
+func (m *MockCDNClient) CheckHealth(ctx context.Context, addr dfnet.NetAddr) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckHealth", ctx, addr)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CheckHealth indicates an expected call of CheckHealth.
+func (mr *MockCDNClientMockRecorder) CheckHealth(ctx, addr interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckHealth", reflect.TypeOf((*MockCDNClient)(nil).CheckHealth), ctx, addr)
+}
+
+// CircuitState mocks base method.
+func (m *MockCDNClient) CircuitState(addr dfnet.NetAddr) client.CircuitState {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CircuitState", addr)
+	ret0, _ := ret[0].(client.CircuitState)
+	return ret0
+}
+
+// CircuitState indicates an expected call of CircuitState.
+func (mr *MockCDNClientMockRecorder) CircuitState(addr interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CircuitState", reflect.TypeOf((*MockCDNClient)(nil).CircuitState), addr)
+}
+
 // Close mocks base method.
 func (m *MockCDNClient) Close() error {
 	m.ctrl.T.Helper()
@@ -108,6 +136,20 @@ func (mr *MockCDNClientMockRecorder) Close() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockCDNClient)(nil).Close))
 }
 
+// CloseGracefully mocks base method.
+func (m *MockCDNClient) CloseGracefully(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CloseGracefully", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CloseGracefully indicates an expected call of CloseGracefully.
+func (mr *MockCDNClientMockRecorder) CloseGracefully(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CloseGracefully", reflect.TypeOf((*MockCDNClient)(nil).CloseGracefully), ctx)
+}
+
 // GetPieceTasks mocks base method.
 func (m *MockCDNClient) GetPieceTasks(ctx context.Context, addr dfnet.NetAddr, req *base.PieceTaskRequest, opts ...grpc.CallOption) (*base.PiecePacket, error) {
 	m.ctrl.T.Helper()
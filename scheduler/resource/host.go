@@ -17,6 +17,9 @@
 package resource
 
 import (
+	"net"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -24,13 +27,57 @@ import (
 
 	logger "d7y.io/dragonfly/v2/internal/dflog"
 	"d7y.io/dragonfly/v2/pkg/rpc/scheduler"
+	"d7y.io/dragonfly/v2/scheduler/config"
 )
 
 const (
 	// Host default upload load limit
 	defaultUploadLoadLimit = 100
+
+	// CDN host default upload load limit
+	defaultCDNUploadLoadLimit = 1000
+
+	// Maximum distance of location and net topology, it's the same as the max element length of
+	// multi-element affinity in evaluator, which only scores the first 5 levels. The difference of
+	// deeper levels is not used in scheduling, and unknown topology is as far as the maximum
+	maxTopologyDistance = 5
+
+	// Minimum factor of effective upload load limit when upload load limit is degraded
+	minUploadLimitFactor = 0.1
+
+	// Weight of the latest measurement when averaging bandwidth
+	bandwidthSmoothingFactor = 0.25
+
+	// Separator of location and net topology levels
+	topologySeparator = "|"
+
+	// Separator of label key and value in net topology levels
+	labelSeparator = "="
 )
 
+var (
+	// Host default score weights
+	defaultScoreWeights = &config.ScoreConfig{
+		FreeUploadLoadWeight: 0.5,
+		RecencyWeight:        0.2,
+		AffinityWeight:       0.2,
+		CDNBoost:             0.1,
+		RecencyWindow:        10 * time.Minute,
+	}
+)
+
+// ScoreConfig is the configuration for calculating host score
+type ScoreConfig struct {
+	// Score weights
+	*config.ScoreConfig
+
+	// IDC which hosts are affine to, usually is the idc of scheduler
+	IDC string
+
+	// Location which hosts are affine to, usually is the location of scheduler
+	Location string
+}
+
 // HostOption is a functional option for configuring the host
 type HostOption func(h *Host) *Host
 
@@ -42,6 +89,35 @@ func WithUploadLoadLimit(limit int32) HostOption {
 	}
 }
 
+// WithBandwidth sets host's UploadBandwidth and DownloadBandwidth
+func WithBandwidth(up, down int64) HostOption {
+	return func(h *Host) *Host {
+		h.UploadBandwidth.Store(up)
+		h.DownloadBandwidth.Store(down)
+		return h
+	}
+}
+
+// WithScoreConfig sets host's ScoreConfig
+func WithScoreConfig(cfg ScoreConfig) HostOption {
+	return func(h *Host) *Host {
+		if cfg.ScoreConfig == nil {
+			cfg.ScoreConfig = defaultScoreWeights
+		}
+		h.ScoreConfig = cfg
+		return h
+	}
+}
+
+// WithPeerCountUploadLoad sets whether host's upload load is counted by peers,
+// it keeps the behavior that every peer of host takes a upload slot
+func WithPeerCountUploadLoad(enable bool) HostOption {
+	return func(h *Host) *Host {
+		h.peerCountUploadLoad = enable
+		return h
+	}
+}
+
 // WithIsCDN sets host's IsCDN
 func WithIsCDN(isCDN bool) HostOption {
 	return func(h *Host) *Host {
@@ -50,6 +126,16 @@ func WithIsCDN(isCDN bool) HostOption {
 	}
 }
 
+// WithLabels sets host's Labels, labels parsed from net topology are overridden
+func WithLabels(labels map[string]string) HostOption {
+	return func(h *Host) *Host {
+		for k, v := range labels {
+			h.SetLabel(k, v)
+		}
+		return h
+	}
+}
+
 type Host struct {
 	// ID is host id
 	ID string
@@ -83,12 +169,56 @@ type Host struct {
 	// UploadLoadLimit is upload load limit count
 	UploadLoadLimit *atomic.Int32
 
+	// UploadCount is count of in-flight piece uploads, an upload starts when a peer stores
+	// the peer of host as parent and finishes when the peer stops downloading pieces from it
+	UploadCount *atomic.Int32
+
+	// UploadBandwidth is upload bandwidth of host, bytes per second
+	UploadBandwidth *atomic.Int64
+
+	// DownloadBandwidth is download bandwidth of host, bytes per second
+	DownloadBandwidth *atomic.Int64
+
 	// Peer sync map
 	Peers *sync.Map
 
+	// peerCount is count of peers in sync map
+	peerCount *atomic.Int32
+
+	// peersLock serializes writes of peers to keep peerCount correct
+	peersLock sync.Mutex
+
 	// IsCDN is used as tag cdn
 	IsCDN bool
 
+	// Labels is free-form labels of host, it's parsed from the key=value levels of net topology
+	// Example: zone=a|gpu=true
+	Labels map[string]string
+
+	// labelsLock protects Labels
+	labelsLock sync.RWMutex
+
+	// ScoreConfig is used to calculate host score
+	ScoreConfig ScoreConfig
+
+	// peerCountUploadLoad indicates upload load is counted by peers instead of in-flight uploads
+	peerCountUploadLoad bool
+
+	// uploadLimitFactor is the factor of effective upload load limit when it is degraded
+	uploadLimitFactor float64
+
+	// uploadLimitDegradeAt is the time when upload load limit is degraded
+	uploadLimitDegradeAt time.Time
+
+	// uploadLimitRecoverAfter is the duration to recover upload load limit linearly
+	uploadLimitRecoverAfter time.Duration
+
+	// uploadLimitLock protects the degradation of upload load limit
+	uploadLimitLock sync.Mutex
+
+	// Current time, replaced in tests
+	now func() time.Time
+
 	// CreateAt is host create time
 	CreateAt *atomic.Time
 
@@ -102,21 +232,29 @@ type Host struct {
 // New host instance
 func NewHost(rawHost *scheduler.PeerHost, options ...HostOption) *Host {
 	h := &Host{
-		ID:              rawHost.Uuid,
-		IP:              rawHost.Ip,
-		Hostname:        rawHost.HostName,
-		Port:            rawHost.RpcPort,
-		DownloadPort:    rawHost.DownPort,
-		SecurityDomain:  rawHost.SecurityDomain,
-		IDC:             rawHost.Idc,
-		NetTopology:     rawHost.NetTopology,
-		Location:        rawHost.Location,
-		UploadLoadLimit: atomic.NewInt32(defaultUploadLoadLimit),
-		Peers:           &sync.Map{},
-		IsCDN:           false,
-		CreateAt:        atomic.NewTime(time.Now()),
-		UpdateAt:        atomic.NewTime(time.Now()),
-		Log:             logger.WithHostID(rawHost.Uuid),
+		ID:                rawHost.Uuid,
+		IP:                rawHost.Ip,
+		Hostname:          rawHost.HostName,
+		Port:              rawHost.RpcPort,
+		DownloadPort:      rawHost.DownPort,
+		SecurityDomain:    rawHost.SecurityDomain,
+		IDC:               rawHost.Idc,
+		NetTopology:       rawHost.NetTopology,
+		Location:          rawHost.Location,
+		UploadLoadLimit:   atomic.NewInt32(defaultUploadLoadLimit),
+		UploadCount:       atomic.NewInt32(0),
+		UploadBandwidth:   atomic.NewInt64(0),
+		DownloadBandwidth: atomic.NewInt64(0),
+		Peers:             &sync.Map{},
+		peerCount:         atomic.NewInt32(0),
+		IsCDN:             false,
+		Labels:            parseLabels(rawHost.NetTopology),
+		uploadLimitFactor: 1,
+		now:               time.Now,
+		ScoreConfig:       ScoreConfig{ScoreConfig: defaultScoreWeights},
+		CreateAt:          atomic.NewTime(time.Now()),
+		UpdateAt:          atomic.NewTime(time.Now()),
+		Log:               logger.WithHostID(rawHost.Uuid),
 	}
 
 	for _, opt := range options {
@@ -126,6 +264,24 @@ func NewHost(rawHost *scheduler.PeerHost, options ...HostOption) *Host {
 	return h
 }
 
+// New cdn host instance, the default upload load limit of cdn host is defaultCDNUploadLoadLimit,
+// and it can be overridden by WithUploadLoadLimit
+func NewCDNHost(rawHost *scheduler.PeerHost, options ...HostOption) *Host {
+	h := NewHost(rawHost, append([]HostOption{WithIsCDN(true), WithUploadLoadLimit(defaultCDNUploadLoadLimit)}, options...)...)
+	h.Log = logger.With("hostID", rawHost.Uuid, "isCDN", true)
+	return h
+}
+
+// Addr returns the grpc service address of host, ipv6 ip is enclosed in square brackets
+func (h *Host) Addr() string {
+	return net.JoinHostPort(h.IP, strconv.Itoa(int(h.Port)))
+}
+
+// DownloadAddr returns the piece downloading address of host, ipv6 ip is enclosed in square brackets
+func (h *Host) DownloadAddr() string {
+	return net.JoinHostPort(h.IP, strconv.Itoa(int(h.DownloadPort)))
+}
+
 // LoadPeer return peer for a key
 func (h *Host) LoadPeer(key string) (*Peer, bool) {
 	rawPeer, ok := h.Peers.Load(key)
@@ -138,31 +294,69 @@ func (h *Host) LoadPeer(key string) (*Peer, bool) {
 
 // StorePeer set peer
 func (h *Host) StorePeer(peer *Peer) {
-	h.Peers.Store(peer.ID, peer)
+	h.peersLock.Lock()
+	defer h.peersLock.Unlock()
+
+	if _, loaded := h.Peers.LoadOrStore(peer.ID, peer); loaded {
+		h.Peers.Store(peer.ID, peer)
+		return
+	}
+	h.peerCount.Inc()
 }
 
 // LoadOrStorePeer returns peer the key if present.
 // Otherwise, it stores and returns the given peer.
 // The loaded result is true if the peer was loaded, false if stored.
 func (h *Host) LoadOrStorePeer(peer *Peer) (*Peer, bool) {
+	h.peersLock.Lock()
+	defer h.peersLock.Unlock()
+
 	rawPeer, loaded := h.Peers.LoadOrStore(peer.ID, peer)
+	if !loaded {
+		h.peerCount.Inc()
+	}
 	return rawPeer.(*Peer), loaded
 }
 
 // DeletePeer deletes peer for a key
 func (h *Host) DeletePeer(key string) {
-	h.Peers.Delete(key)
+	h.peersLock.Lock()
+	defer h.peersLock.Unlock()
+
+	if _, loaded := h.Peers.LoadAndDelete(key); loaded {
+		h.peerCount.Dec()
+	}
 }
 
 // LenPeers return length of peers sync map
 func (h *Host) LenPeers() int {
-	var len int
-	h.Peers.Range(func(_, _ interface{}) bool {
-		len++
+	return int(h.peerCount.Load())
+}
+
+// SnapshotPeers returns peers of host by ranging the sync map once
+func (h *Host) SnapshotPeers() []*Peer {
+	peers := make([]*Peer, 0, h.LenPeers())
+	h.Peers.Range(func(_, value interface{}) bool {
+		if peer, ok := value.(*Peer); ok {
+			peers = append(peers, peer)
+		}
+		return true
+	})
+
+	return peers
+}
+
+// CountPeersByState return count of peers in host by the current state of peer fsm
+func (h *Host) CountPeersByState() map[string]int {
+	counts := make(map[string]int)
+	h.Peers.Range(func(_, value interface{}) bool {
+		if peer, ok := value.(*Peer); ok {
+			counts[peer.FSM.Current()]++
+		}
 		return true
 	})
 
-	return len
+	return counts
 }
 
 // LeavePeers set peer state to PeerStateLeave
@@ -186,7 +380,270 @@ func (h *Host) LeavePeers() {
 	})
 }
 
+// IncUploadCount increases count of in-flight piece uploads
+func (h *Host) IncUploadCount() {
+	h.UploadCount.Inc()
+}
+
+// DecUploadCount decreases count of in-flight piece uploads
+func (h *Host) DecUploadCount() {
+	for {
+		count := h.UploadCount.Load()
+		if count <= 0 {
+			return
+		}
+
+		if h.UploadCount.CAS(count, count-1) {
+			return
+		}
+	}
+}
+
 // FreeUploadLoad return free upload load of host
 func (h *Host) FreeUploadLoad() int32 {
-	return h.UploadLoadLimit.Load() - int32(h.LenPeers())
+	if h.peerCountUploadLoad {
+		return h.EffectiveUploadLoadLimit() - int32(h.LenPeers())
+	}
+
+	return h.EffectiveUploadLoadLimit() - h.UploadCount.Load()
+}
+
+// EffectiveUploadLoadLimit return upload load limit of host after degradation,
+// UploadLoadLimit is the base limit and it's not changed by degradation
+func (h *Host) EffectiveUploadLoadLimit() int32 {
+	h.uploadLimitLock.Lock()
+	defer h.uploadLimitLock.Unlock()
+
+	return int32(float64(h.UploadLoadLimit.Load()) * h.currentUploadLimitFactor(h.now()))
+}
+
+// DegradeUploadLimit multiplies the effective upload load limit by factor when host fails to upload pieces,
+// the effective limit ramps back to UploadLoadLimit linearly in recoverAfter
+func (h *Host) DegradeUploadLimit(factor float64, recoverAfter time.Duration) {
+	if factor <= 0 || factor >= 1 || recoverAfter <= 0 {
+		return
+	}
+
+	h.uploadLimitLock.Lock()
+	defer h.uploadLimitLock.Unlock()
+
+	now := h.now()
+	h.uploadLimitFactor = h.currentUploadLimitFactor(now) * factor
+	if h.uploadLimitFactor < minUploadLimitFactor {
+		h.uploadLimitFactor = minUploadLimitFactor
+	}
+	h.uploadLimitDegradeAt = now
+	h.uploadLimitRecoverAfter = recoverAfter
+	h.Log.Infof("upload load limit is degraded by factor %.2f and recovers after %s", h.uploadLimitFactor, recoverAfter)
+}
+
+// currentUploadLimitFactor return factor of effective upload load limit at now
+func (h *Host) currentUploadLimitFactor(now time.Time) float64 {
+	elapsed := now.Sub(h.uploadLimitDegradeAt)
+	if h.uploadLimitRecoverAfter <= 0 || elapsed >= h.uploadLimitRecoverAfter {
+		return 1
+	}
+
+	if elapsed < 0 {
+		elapsed = 0
+	}
+
+	return h.uploadLimitFactor + (1-h.uploadLimitFactor)*float64(elapsed)/float64(h.uploadLimitRecoverAfter)
+}
+
+// UpdateBandwidth updates bandwidth of host with the measured values by exponentially weighted moving average,
+// so the bandwidth follows the recent measurements. The value which is not positive is ignored
+func (h *Host) UpdateBandwidth(up, down int64) {
+	storeAverage := func(bandwidth *atomic.Int64, value int64) {
+		if value <= 0 {
+			return
+		}
+
+		for {
+			old := bandwidth.Load()
+			average := value
+			if old > 0 {
+				average = old + int64(float64(value-old)*bandwidthSmoothingFactor)
+			}
+			if bandwidth.CAS(old, average) {
+				return
+			}
+		}
+	}
+
+	storeAverage(h.UploadBandwidth, up)
+	storeAverage(h.DownloadBandwidth, down)
+}
+
+// FreeUploadBandwidth return free upload bandwidth of host,
+// every committed upload load takes an equal share of upload bandwidth
+func (h *Host) FreeUploadBandwidth() int64 {
+	limit := h.UploadLoadLimit.Load()
+	free := h.FreeUploadLoad()
+	if limit <= 0 || free <= 0 {
+		return 0
+	}
+
+	return h.UploadBandwidth.Load() * int64(free) / int64(limit)
+}
+
+// Score return the normalized score of host for scheduling, 0.0~1.0 larger and better.
+// It combines free upload load, recency of update time, idc and location affinity
+// and cdn boost by the weights of ScoreConfig
+func (h *Host) Score() float64 {
+	cfg := h.ScoreConfig
+	totalWeight := cfg.FreeUploadLoadWeight + cfg.RecencyWeight + cfg.AffinityWeight + cfg.CDNBoost
+	if totalWeight <= 0 {
+		return 0
+	}
+
+	var freeUploadLoadScore float64
+	if limit := h.UploadLoadLimit.Load(); limit > 0 {
+		freeUploadLoadScore = clampScore(float64(h.FreeUploadLoad()) / float64(limit))
+	}
+
+	var recencyScore float64
+	if cfg.RecencyWindow > 0 {
+		elapsed := time.Since(h.UpdateAt.Load())
+		recencyScore = clampScore(1 - float64(elapsed)/float64(cfg.RecencyWindow))
+	}
+
+	var affinityScore float64
+	if cfg.IDC != "" && h.IDC == cfg.IDC {
+		affinityScore += 0.5
+	}
+	affinityScore += 0.5 * locationAffinityScore(h.Location, cfg.Location)
+
+	var cdnScore float64
+	if h.IsCDN {
+		cdnScore = 1
+	}
+
+	return (cfg.FreeUploadLoadWeight*freeUploadLoadScore +
+		cfg.RecencyWeight*recencyScore +
+		cfg.AffinityWeight*affinityScore +
+		cfg.CDNBoost*cdnScore) / totalWeight
+}
+
+// SetLabel sets label of host
+func (h *Host) SetLabel(key, value string) {
+	h.labelsLock.Lock()
+	defer h.labelsLock.Unlock()
+
+	h.Labels[key] = value
+}
+
+// GetLabel returns label of host for a key
+func (h *Host) GetLabel(key string) (string, bool) {
+	h.labelsLock.RLock()
+	defer h.labelsLock.RUnlock()
+
+	value, ok := h.Labels[key]
+	return value, ok
+}
+
+// MatchLabels returns whether host labels match all labels of selector,
+// an empty value in selector matches any value, and a missing label never matches
+func (h *Host) MatchLabels(selector map[string]string) bool {
+	h.labelsLock.RLock()
+	defer h.labelsLock.RUnlock()
+
+	for k, v := range selector {
+		value, ok := h.Labels[k]
+		if !ok {
+			return false
+		}
+
+		if v != "" && v != value {
+			return false
+		}
+	}
+
+	return true
+}
+
+// parseLabels parses labels from the key=value levels of net topology
+func parseLabels(netTopology string) map[string]string {
+	labels := make(map[string]string)
+	if netTopology == "" {
+		return labels
+	}
+
+	for _, level := range strings.Split(netTopology, topologySeparator) {
+		kv := strings.SplitN(level, labelSeparator, 2)
+		if len(kv) != 2 || kv[0] == "" {
+			continue
+		}
+		labels[kv[0]] = kv[1]
+	}
+
+	return labels
+}
+
+// LocationDistance return the number of differing location levels between hosts,
+// closer is smaller and unknown location is the maximum distance
+func (h *Host) LocationDistance(other *Host) int {
+	return topologyDistance(h.Location, other.Location)
+}
+
+// NetTopologyDistance return the number of differing net topology levels between hosts,
+// closer is smaller and unknown net topology is the maximum distance
+func (h *Host) NetTopologyDistance(other *Host) int {
+	return topologyDistance(h.NetTopology, other.NetTopology)
+}
+
+// topologyDistance return the number of differing levels of pipe-delimited hierarchies
+func topologyDistance(dst, src string) int {
+	if dst == "" || src == "" {
+		return maxTopologyDistance
+	}
+
+	matched, maxLen := matchTopologyLevels(dst, src)
+	if distance := maxLen - matched; distance < maxTopologyDistance {
+		return distance
+	}
+
+	return maxTopologyDistance
+}
+
+// locationAffinityScore return the ratio of matched leading elements divided by "|", 0.0~1.0
+func locationAffinityScore(dst, src string) float64 {
+	if dst == "" || src == "" {
+		return 0
+	}
+
+	matched, maxLen := matchTopologyLevels(dst, src)
+	return float64(matched) / float64(maxLen)
+}
+
+// matchTopologyLevels return the number of matched leading levels of pipe-delimited hierarchies
+// and the number of levels of the deeper one
+func matchTopologyLevels(dst, src string) (matched int, maxLen int) {
+	dstElements := strings.Split(dst, topologySeparator)
+	srcElements := strings.Split(src, topologySeparator)
+	maxLen = len(dstElements)
+	if len(srcElements) > maxLen {
+		maxLen = len(srcElements)
+	}
+
+	for i := 0; i < len(dstElements) && i < len(srcElements); i++ {
+		if dstElements[i] != srcElements[i] {
+			break
+		}
+		matched++
+	}
+
+	return matched, maxLen
+}
+
+func clampScore(score float64) float64 {
+	if score < 0 {
+		return 0
+	}
+
+	if score > 1 {
+		return 1
+	}
+
+	return score
 }
@@ -56,13 +56,21 @@ type hostManager struct {
 
 	// Host time to live
 	ttl time.Duration
+
+	// Whether to reclaim cdn hosts
+	includeCDN bool
+
+	// Current time, replaced in tests
+	now func() time.Time
 }
 
 // New host manager interface
 func newHostManager(cfg *config.GCConfig, gc pkggc.GC) (HostManager, error) {
 	h := &hostManager{
-		Map: &sync.Map{},
-		ttl: cfg.HostTTL,
+		Map:        &sync.Map{},
+		ttl:        cfg.HostTTL,
+		includeCDN: cfg.HostGCIncludeCDN,
+		now:        time.Now,
 	}
 
 	if err := gc.Add(pkggc.Task{
@@ -102,10 +110,13 @@ func (h *hostManager) Delete(key string) {
 func (h *hostManager) RunGC() error {
 	h.Map.Range(func(_, value interface{}) bool {
 		host := value.(*Host)
-		elapsed := time.Since(host.UpdateAt.Load())
+		if host.IsCDN && !h.includeCDN {
+			return true
+		}
 
+		elapsed := h.now().Sub(host.UpdateAt.Load())
 		if elapsed > h.ttl && host.LenPeers() == 0 {
-			host.Log.Info("host has been reclaimed")
+			host.Log.Infof("host has been reclaimed, last update at %s", host.UpdateAt.Load())
 			h.Delete(host.ID)
 		}
 
@@ -357,3 +357,68 @@ func TestHostManager_RunGC(t *testing.T) {
 		})
 	}
 }
+
+func TestHostManager_RunGCWithFakeClock(t *testing.T) {
+	tests := []struct {
+		name       string
+		isCDN      bool
+		includeCDN bool
+		elapsed    time.Duration
+		reclaimed  bool
+	}{
+		{
+			name:      "host is not expired",
+			elapsed:   30 * time.Minute,
+			reclaimed: false,
+		},
+		{
+			name:      "host is expired",
+			elapsed:   2 * time.Hour,
+			reclaimed: true,
+		},
+		{
+			name:      "cdn host is exempt",
+			isCDN:     true,
+			elapsed:   2 * time.Hour,
+			reclaimed: false,
+		},
+		{
+			name:       "cdn host is reclaimed when cdn is included",
+			isCDN:      true,
+			includeCDN: true,
+			elapsed:    2 * time.Hour,
+			reclaimed:  true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctl := gomock.NewController(t)
+			defer ctl.Finish()
+			gc := gc.NewMockGC(ctl)
+			gc.EXPECT().Add(gomock.Any()).Return(nil).Times(1)
+
+			manager, err := newHostManager(&config.GCConfig{
+				HostGCInterval:   1 * time.Second,
+				HostTTL:          1 * time.Hour,
+				HostGCIncludeCDN: tc.includeCDN,
+			}, gc)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			mockHost := NewHost(mockRawHost, WithIsCDN(tc.isCDN))
+			manager.Store(mockHost)
+
+			now := mockHost.UpdateAt.Load()
+			manager.(*hostManager).now = func() time.Time {
+				return now.Add(tc.elapsed)
+			}
+
+			assert := assert.New(t)
+			assert.NoError(manager.RunGC())
+			_, ok := manager.Load(mockHost.ID)
+			assert.Equal(ok, !tc.reclaimed)
+		})
+	}
+}
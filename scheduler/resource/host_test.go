@@ -17,12 +17,16 @@
 package resource
 
 import (
+	"fmt"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
 	"d7y.io/dragonfly/v2/pkg/idgen"
 	"d7y.io/dragonfly/v2/pkg/rpc/scheduler"
+	"d7y.io/dragonfly/v2/scheduler/config"
 )
 
 var (
@@ -126,6 +130,19 @@ func TestHost_NewHost(t *testing.T) {
 				assert.NotNil(host.Log)
 			},
 		},
+		{
+			name:    "new host and set bandwidth",
+			rawHost: mockRawHost,
+			options: []HostOption{WithBandwidth(1024, 2048)},
+			expect: func(t *testing.T, host *Host) {
+				assert := assert.New(t)
+				assert.Equal(host.ID, mockRawHost.Uuid)
+				assert.Equal(host.UploadBandwidth.Load(), int64(1024))
+				assert.Equal(host.DownloadBandwidth.Load(), int64(2048))
+				assert.Equal(host.UploadLoadLimit.Load(), int32(defaultUploadLoadLimit))
+				assert.Equal(host.IsCDN, false)
+			},
+		},
 	}
 
 	for _, tc := range tests {
@@ -135,6 +152,94 @@ func TestHost_NewHost(t *testing.T) {
 	}
 }
 
+func TestHost_NewCDNHost(t *testing.T) {
+	tests := []struct {
+		name    string
+		rawHost *scheduler.PeerHost
+		options []HostOption
+		expect  func(t *testing.T, host *Host)
+	}{
+		{
+			name:    "new cdn host",
+			rawHost: mockRawCDNHost,
+			expect: func(t *testing.T, host *Host) {
+				assert := assert.New(t)
+				assert.Equal(host.ID, mockRawCDNHost.Uuid)
+				assert.Equal(host.IP, mockRawCDNHost.Ip)
+				assert.Equal(host.UploadLoadLimit.Load(), int32(defaultCDNUploadLoadLimit))
+				assert.Equal(host.FreeUploadLoad(), int32(defaultCDNUploadLoadLimit))
+				assert.Equal(host.IsCDN, true)
+				assert.NotNil(host.Log)
+			},
+		},
+		{
+			name:    "new cdn host and set upload loadlimit",
+			rawHost: mockRawCDNHost,
+			options: []HostOption{WithUploadLoadLimit(200)},
+			expect: func(t *testing.T, host *Host) {
+				assert := assert.New(t)
+				assert.Equal(host.UploadLoadLimit.Load(), int32(200))
+				assert.Equal(host.IsCDN, true)
+			},
+		},
+		{
+			name:    "cdn host free upload load reflects the higher limit",
+			rawHost: mockRawCDNHost,
+			options: []HostOption{WithPeerCountUploadLoad(true)},
+			expect: func(t *testing.T, host *Host) {
+				assert := assert.New(t)
+				mockTask := NewTask(mockTaskID, mockTaskURL, mockTaskBackToSourceLimit, mockTaskURLMeta)
+				host.StorePeer(NewPeer(mockPeerID, mockTask, host))
+				assert.Equal(host.FreeUploadLoad(), int32(defaultCDNUploadLoadLimit-1))
+				assert.Greater(host.FreeUploadLoad(), NewHost(mockRawHost).FreeUploadLoad())
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.expect(t, NewCDNHost(tc.rawHost, tc.options...))
+		})
+	}
+}
+
+func TestHost_Addr(t *testing.T) {
+	tests := []struct {
+		name                 string
+		ip                   string
+		expectedAddr         string
+		expectedDownloadAddr string
+	}{
+		{
+			name:                 "ipv4",
+			ip:                   "127.0.0.1",
+			expectedAddr:         "127.0.0.1:8003",
+			expectedDownloadAddr: "127.0.0.1:8001",
+		},
+		{
+			name:                 "ipv6",
+			ip:                   "fe80::1",
+			expectedAddr:         "[fe80::1]:8003",
+			expectedDownloadAddr: "[fe80::1]:8001",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			host := NewHost(&scheduler.PeerHost{
+				Uuid:     mockRawHost.Uuid,
+				Ip:       tc.ip,
+				RpcPort:  mockRawHost.RpcPort,
+				DownPort: mockRawHost.DownPort,
+				HostName: mockRawHost.HostName,
+			})
+			assert.Equal(tc.expectedAddr, host.Addr())
+			assert.Equal(tc.expectedDownloadAddr, host.DownloadAddr())
+		})
+	}
+}
+
 func TestHost_LoadPeer(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -361,6 +466,131 @@ func TestHost_LenPeers(t *testing.T) {
 	}
 }
 
+func TestHost_SnapshotPeers(t *testing.T) {
+	tests := []struct {
+		name    string
+		rawHost *scheduler.PeerHost
+		options []HostOption
+		expect  func(t *testing.T, host *Host, mockPeer *Peer)
+	}{
+		{
+			name:    "snapshot peers",
+			rawHost: mockRawHost,
+			expect: func(t *testing.T, host *Host, mockPeer *Peer) {
+				assert := assert.New(t)
+				host.StorePeer(mockPeer)
+				peers := host.SnapshotPeers()
+				assert.Equal(len(peers), 1)
+				assert.Equal(peers[0].ID, mockPeerID)
+			},
+		},
+		{
+			name:    "peer does not exist",
+			rawHost: mockRawHost,
+			expect: func(t *testing.T, host *Host, mockPeer *Peer) {
+				assert := assert.New(t)
+				assert.Equal(len(host.SnapshotPeers()), 0)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			host := NewHost(tc.rawHost, tc.options...)
+			mockTask := NewTask(mockTaskID, mockTaskURL, mockTaskBackToSourceLimit, mockTaskURLMeta)
+			mockPeer := NewPeer(mockPeerID, mockTask, host)
+
+			tc.expect(t, host, mockPeer)
+		})
+	}
+}
+
+func TestHost_PeersConcurrency(t *testing.T) {
+	assert := assert.New(t)
+	host := NewHost(mockRawHost)
+	mockTask := NewTask(mockTaskID, mockTaskURL, mockTaskBackToSourceLimit, mockTaskURLMeta)
+
+	var mockPeers []*Peer
+	for i := 0; i < 16; i++ {
+		mockPeers = append(mockPeers, NewPeer(fmt.Sprintf("peer-%d", i), mockTask, host))
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				peer := mockPeers[(i+j)%len(mockPeers)]
+				switch j % 3 {
+				case 0:
+					host.StorePeer(peer)
+				case 1:
+					host.LoadOrStorePeer(peer)
+				case 2:
+					host.DeletePeer(peer.ID)
+				}
+				host.SnapshotPeers()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(host.LenPeers(), len(host.SnapshotPeers()))
+	for _, peer := range mockPeers {
+		host.DeletePeer(peer.ID)
+	}
+	assert.Equal(host.LenPeers(), 0)
+}
+
+func TestHost_CountPeersByState(t *testing.T) {
+	tests := []struct {
+		name    string
+		rawHost *scheduler.PeerHost
+		options []HostOption
+		expect  func(t *testing.T, host *Host, mockPeer *Peer)
+	}{
+		{
+			name:    "count peers by state",
+			rawHost: mockRawHost,
+			expect: func(t *testing.T, host *Host, mockPeer *Peer) {
+				assert := assert.New(t)
+				host.StorePeer(mockPeer)
+				runningPeer := NewPeer(idgen.PeerID("0.0.0.0"), mockPeer.Task, host)
+				runningPeer.FSM.SetState(PeerStateRunning)
+				host.StorePeer(runningPeer)
+				failedPeer := NewPeer(idgen.PeerID("0.0.0.1"), mockPeer.Task, host)
+				failedPeer.FSM.SetState(PeerStateFailed)
+				host.StorePeer(failedPeer)
+
+				assert.Equal(host.CountPeersByState(), map[string]int{
+					PeerStatePending: 1,
+					PeerStateRunning: 1,
+					PeerStateFailed:  1,
+				})
+			},
+		},
+		{
+			name:    "peer does not exist",
+			rawHost: mockRawHost,
+			expect: func(t *testing.T, host *Host, mockPeer *Peer) {
+				assert := assert.New(t)
+				assert.Equal(len(host.CountPeersByState()), 0)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			host := NewHost(tc.rawHost, tc.options...)
+			mockTask := NewTask(mockTaskID, mockTaskURL, mockTaskBackToSourceLimit, mockTaskURLMeta)
+			mockPeer := NewPeer(mockPeerID, mockTask, host)
+
+			tc.expect(t, host, mockPeer)
+		})
+	}
+}
+
 func TestHost_LeavePeers(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -419,6 +649,38 @@ func TestHost_FreeUploadLoad(t *testing.T) {
 		{
 			name:    "get free upload load",
 			rawHost: mockRawHost,
+			expect: func(t *testing.T, host *Host, mockPeer *Peer) {
+				assert := assert.New(t)
+				host.IncUploadCount()
+				host.IncUploadCount()
+				assert.Equal(host.FreeUploadLoad(), int32(defaultUploadLoadLimit-2))
+				host.DecUploadCount()
+				assert.Equal(host.FreeUploadLoad(), int32(defaultUploadLoadLimit-1))
+			},
+		},
+		{
+			name:    "idle peers do not take upload load",
+			rawHost: mockRawHost,
+			expect: func(t *testing.T, host *Host, mockPeer *Peer) {
+				assert := assert.New(t)
+				host.StorePeer(mockPeer)
+				assert.Equal(host.FreeUploadLoad(), int32(defaultUploadLoadLimit))
+			},
+		},
+		{
+			name:    "upload count does not decrease below zero",
+			rawHost: mockRawHost,
+			expect: func(t *testing.T, host *Host, mockPeer *Peer) {
+				assert := assert.New(t)
+				host.DecUploadCount()
+				assert.Equal(host.UploadCount.Load(), int32(0))
+				assert.Equal(host.FreeUploadLoad(), int32(defaultUploadLoadLimit))
+			},
+		},
+		{
+			name:    "get free upload load counted by peers",
+			rawHost: mockRawHost,
+			options: []HostOption{WithPeerCountUploadLoad(true)},
 			expect: func(t *testing.T, host *Host, mockPeer *Peer) {
 				assert := assert.New(t)
 				host.StorePeer(mockPeer)
@@ -447,3 +709,446 @@ func TestHost_FreeUploadLoad(t *testing.T) {
 		})
 	}
 }
+
+func TestHost_UpdateBandwidth(t *testing.T) {
+	tests := []struct {
+		name    string
+		rawHost *scheduler.PeerHost
+		options []HostOption
+		expect  func(t *testing.T, host *Host)
+	}{
+		{
+			name:    "update bandwidth",
+			rawHost: mockRawHost,
+			expect: func(t *testing.T, host *Host) {
+				assert := assert.New(t)
+				host.UpdateBandwidth(1024, 2048)
+				assert.Equal(host.UploadBandwidth.Load(), int64(1024))
+				assert.Equal(host.DownloadBandwidth.Load(), int64(2048))
+			},
+		},
+		{
+			name:    "average bandwidth",
+			rawHost: mockRawHost,
+			options: []HostOption{WithBandwidth(1024, 2048)},
+			expect: func(t *testing.T, host *Host) {
+				assert := assert.New(t)
+				host.UpdateBandwidth(512, 4096)
+				assert.Equal(host.UploadBandwidth.Load(), int64(896))
+				assert.Equal(host.DownloadBandwidth.Load(), int64(2560))
+			},
+		},
+		{
+			name:    "bandwidth goes down after lower measurements",
+			rawHost: mockRawHost,
+			options: []HostOption{WithBandwidth(1024, 1024)},
+			expect: func(t *testing.T, host *Host) {
+				assert := assert.New(t)
+				last := host.UploadBandwidth.Load()
+				for i := 0; i < 10; i++ {
+					host.UpdateBandwidth(256, 0)
+					assert.Less(host.UploadBandwidth.Load(), last)
+					last = host.UploadBandwidth.Load()
+				}
+				assert.Less(last, int64(320))
+				assert.Greater(last, int64(256))
+				assert.Equal(host.DownloadBandwidth.Load(), int64(1024))
+			},
+		},
+		{
+			name:    "ignore bandwidth which is not positive",
+			rawHost: mockRawHost,
+			options: []HostOption{WithBandwidth(1024, 2048)},
+			expect: func(t *testing.T, host *Host) {
+				assert := assert.New(t)
+				host.UpdateBandwidth(0, -1)
+				assert.Equal(host.UploadBandwidth.Load(), int64(1024))
+				assert.Equal(host.DownloadBandwidth.Load(), int64(2048))
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.expect(t, NewHost(tc.rawHost, tc.options...))
+		})
+	}
+}
+
+func TestHost_DegradeUploadLimit(t *testing.T) {
+	tests := []struct {
+		name   string
+		expect func(t *testing.T, host *Host, now *time.Time)
+	}{
+		{
+			name: "degrade then recover",
+			expect: func(t *testing.T, host *Host, now *time.Time) {
+				assert := assert.New(t)
+				host.DegradeUploadLimit(0.5, 10*time.Second)
+				assert.Equal(host.UploadLoadLimit.Load(), int32(defaultUploadLoadLimit))
+				assert.Equal(host.EffectiveUploadLoadLimit(), int32(50))
+				assert.Equal(host.FreeUploadLoad(), int32(50))
+
+				*now = now.Add(5 * time.Second)
+				assert.Equal(host.EffectiveUploadLoadLimit(), int32(75))
+
+				*now = now.Add(5 * time.Second)
+				assert.Equal(host.EffectiveUploadLoadLimit(), int32(defaultUploadLoadLimit))
+				assert.Equal(host.FreeUploadLoad(), int32(defaultUploadLoadLimit))
+			},
+		},
+		{
+			name: "degrade repeatedly",
+			expect: func(t *testing.T, host *Host, now *time.Time) {
+				assert := assert.New(t)
+				host.DegradeUploadLimit(0.5, 10*time.Second)
+				host.DegradeUploadLimit(0.5, 10*time.Second)
+				assert.Equal(host.EffectiveUploadLoadLimit(), int32(25))
+
+				for i := 0; i < 10; i++ {
+					host.DegradeUploadLimit(0.5, 10*time.Second)
+				}
+				assert.Equal(host.EffectiveUploadLoadLimit(), int32(defaultUploadLoadLimit*minUploadLimitFactor))
+
+				*now = now.Add(10 * time.Second)
+				assert.Equal(host.EffectiveUploadLoadLimit(), int32(defaultUploadLoadLimit))
+			},
+		},
+		{
+			name: "invalid degradation",
+			expect: func(t *testing.T, host *Host, now *time.Time) {
+				assert := assert.New(t)
+				host.DegradeUploadLimit(0, 10*time.Second)
+				host.DegradeUploadLimit(1.5, 10*time.Second)
+				host.DegradeUploadLimit(0.5, 0)
+				assert.Equal(host.EffectiveUploadLoadLimit(), int32(defaultUploadLoadLimit))
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			host := NewHost(mockRawHost)
+			now := time.Now()
+			host.now = func() time.Time {
+				return now
+			}
+
+			tc.expect(t, host, &now)
+		})
+	}
+}
+
+func TestHost_FreeUploadBandwidth(t *testing.T) {
+	tests := []struct {
+		name    string
+		rawHost *scheduler.PeerHost
+		options []HostOption
+		expect  func(t *testing.T, host *Host, mockPeer *Peer)
+	}{
+		{
+			name:    "get free upload bandwidth",
+			rawHost: mockRawHost,
+			options: []HostOption{WithUploadLoadLimit(4), WithBandwidth(1024, 0)},
+			expect: func(t *testing.T, host *Host, mockPeer *Peer) {
+				assert := assert.New(t)
+				host.IncUploadCount()
+				assert.Equal(host.FreeUploadBandwidth(), int64(768))
+			},
+		},
+		{
+			name:    "upload peer does not exist",
+			rawHost: mockRawHost,
+			options: []HostOption{WithUploadLoadLimit(4), WithBandwidth(1024, 0)},
+			expect: func(t *testing.T, host *Host, mockPeer *Peer) {
+				assert := assert.New(t)
+				assert.Equal(host.FreeUploadBandwidth(), int64(1024))
+			},
+		},
+		{
+			name:    "upload load is full",
+			rawHost: mockRawHost,
+			options: []HostOption{WithUploadLoadLimit(1), WithBandwidth(1024, 0)},
+			expect: func(t *testing.T, host *Host, mockPeer *Peer) {
+				assert := assert.New(t)
+				host.IncUploadCount()
+				host.IncUploadCount()
+				assert.Equal(host.FreeUploadBandwidth(), int64(0))
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			host := NewHost(tc.rawHost, tc.options...)
+			mockTask := NewTask(mockTaskID, mockTaskURL, mockTaskBackToSourceLimit, mockTaskURLMeta)
+			mockPeer := NewPeer(mockPeerID, mockTask, host)
+
+			tc.expect(t, host, mockPeer)
+		})
+	}
+}
+
+func TestHost_Score(t *testing.T) {
+	mockScoreConfig := ScoreConfig{
+		ScoreConfig: &config.ScoreConfig{
+			FreeUploadLoadWeight: 0.5,
+			RecencyWeight:        0.2,
+			AffinityWeight:       0.2,
+			CDNBoost:             0.1,
+			RecencyWindow:        10 * time.Minute,
+		},
+		IDC:      "idc",
+		Location: "location",
+	}
+
+	tests := []struct {
+		name   string
+		expect func(t *testing.T)
+	}{
+		{
+			name: "nearly full host scores below idle host",
+			expect: func(t *testing.T) {
+				assert := assert.New(t)
+				idleHost := NewHost(mockRawHost, WithUploadLoadLimit(4), WithScoreConfig(mockScoreConfig))
+				busyHost := NewHost(mockRawHost, WithUploadLoadLimit(4), WithScoreConfig(mockScoreConfig))
+				for i := 0; i < 3; i++ {
+					busyHost.IncUploadCount()
+				}
+
+				assert.Less(busyHost.Score(), idleHost.Score())
+				assert.InDelta(idleHost.Score(), 0.9, 0.01)
+			},
+		},
+		{
+			name: "stale host scores below recently updated host",
+			expect: func(t *testing.T) {
+				assert := assert.New(t)
+				host := NewHost(mockRawHost, WithUploadLoadLimit(4), WithScoreConfig(mockScoreConfig))
+				staleHost := NewHost(mockRawHost, WithUploadLoadLimit(4), WithScoreConfig(mockScoreConfig))
+				staleHost.UpdateAt.Store(time.Now().Add(-time.Hour))
+
+				assert.Less(staleHost.Score(), host.Score())
+			},
+		},
+		{
+			name: "host in other idc and location scores below affine host",
+			expect: func(t *testing.T) {
+				assert := assert.New(t)
+				host := NewHost(mockRawHost, WithUploadLoadLimit(4), WithScoreConfig(mockScoreConfig))
+				otherHost := NewHost(&scheduler.PeerHost{
+					Uuid:     idgen.HostID("other", 8003),
+					Ip:       "127.0.0.2",
+					HostName: "other",
+					Location: "other",
+					Idc:      "other",
+				}, WithUploadLoadLimit(4), WithScoreConfig(mockScoreConfig))
+
+				assert.Less(otherHost.Score(), host.Score())
+			},
+		},
+		{
+			name: "cdn host is boosted",
+			expect: func(t *testing.T) {
+				assert := assert.New(t)
+				host := NewHost(mockRawHost, WithUploadLoadLimit(4), WithScoreConfig(mockScoreConfig))
+				cdnHost := NewHost(mockRawCDNHost, WithIsCDN(true), WithUploadLoadLimit(4), WithScoreConfig(mockScoreConfig))
+
+				assert.Less(host.Score(), cdnHost.Score())
+			},
+		},
+		{
+			name: "weights are zero",
+			expect: func(t *testing.T) {
+				assert := assert.New(t)
+				host := NewHost(mockRawHost, WithScoreConfig(ScoreConfig{ScoreConfig: &config.ScoreConfig{}}))
+				assert.Equal(host.Score(), float64(0))
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.expect(t)
+		})
+	}
+}
+
+func TestHost_Labels(t *testing.T) {
+	rawHost := &scheduler.PeerHost{
+		Uuid:        idgen.HostID("hostname", 8003),
+		NetTopology: "switch|zone=a|gpu=true|=invalid",
+	}
+
+	tests := []struct {
+		name     string
+		options  []HostOption
+		selector map[string]string
+		expect   bool
+	}{
+		{
+			name:     "empty selector",
+			selector: map[string]string{},
+			expect:   true,
+		},
+		{
+			name:     "match labels parsed from net topology",
+			selector: map[string]string{"zone": "a", "gpu": "true"},
+			expect:   true,
+		},
+		{
+			name:     "label value mismatched",
+			selector: map[string]string{"zone": "b"},
+			expect:   false,
+		},
+		{
+			name:     "empty value matches any value",
+			selector: map[string]string{"zone": ""},
+			expect:   true,
+		},
+		{
+			name:     "missing label",
+			selector: map[string]string{"spot": ""},
+			expect:   false,
+		},
+		{
+			name:     "match labels set by option",
+			options:  []HostOption{WithLabels(map[string]string{"spot": "true", "zone": "b"})},
+			selector: map[string]string{"spot": "true", "zone": "b", "gpu": "true"},
+			expect:   true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			host := NewHost(rawHost, tc.options...)
+			assert.Equal(host.MatchLabels(tc.selector), tc.expect)
+		})
+	}
+}
+
+func TestHost_SetLabel(t *testing.T) {
+	assert := assert.New(t)
+	host := NewHost(mockRawHost)
+	_, ok := host.GetLabel("gpu")
+	assert.False(ok)
+
+	host.SetLabel("gpu", "true")
+	value, ok := host.GetLabel("gpu")
+	assert.True(ok)
+	assert.Equal(value, "true")
+	assert.True(host.MatchLabels(map[string]string{"gpu": "true"}))
+}
+
+func TestHost_LocationDistance(t *testing.T) {
+	tests := []struct {
+		name     string
+		location string
+		other    string
+		expect   int
+	}{
+		{
+			name:     "same location",
+			location: "china|beijing|chaoyang",
+			other:    "china|beijing|chaoyang",
+			expect:   0,
+		},
+		{
+			name:     "same country and different province",
+			location: "china|beijing",
+			other:    "china|shanghai",
+			expect:   1,
+		},
+		{
+			name:     "same province and different city",
+			location: "china|beijing|chaoyang",
+			other:    "china|beijing|haidian",
+			expect:   1,
+		},
+		{
+			name:     "different levels",
+			location: "china|beijing|chaoyang",
+			other:    "china",
+			expect:   2,
+		},
+		{
+			name:     "different country",
+			location: "china|beijing",
+			other:    "usa|california",
+			expect:   2,
+		},
+		{
+			name:     "distance exceeds maximum",
+			location: "a|b|c|d|e|f|g",
+			other:    "h|i|j|k|l|m|n",
+			expect:   maxTopologyDistance,
+		},
+		{
+			name:     "location is empty",
+			location: "",
+			other:    "china|beijing",
+			expect:   maxTopologyDistance,
+		},
+		{
+			name:     "both locations are empty",
+			location: "",
+			other:    "",
+			expect:   maxTopologyDistance,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			host := NewHost(&scheduler.PeerHost{Uuid: "foo", Location: tc.location})
+			other := NewHost(&scheduler.PeerHost{Uuid: "bar", Location: tc.other})
+			assert.Equal(host.LocationDistance(other), tc.expect)
+			assert.Equal(other.LocationDistance(host), tc.expect)
+		})
+	}
+}
+
+func TestHost_NetTopologyDistance(t *testing.T) {
+	tests := []struct {
+		name        string
+		netTopology string
+		other       string
+		expect      int
+	}{
+		{
+			name:        "same rack",
+			netTopology: "switch|router",
+			other:       "switch|router",
+			expect:      0,
+		},
+		{
+			name:        "same switch and different router",
+			netTopology: "switch|router-a",
+			other:       "switch|router-b",
+			expect:      1,
+		},
+		{
+			name:        "different switch",
+			netTopology: "switch-a|router",
+			other:       "switch-b|router",
+			expect:      2,
+		},
+		{
+			name:        "net topology is empty",
+			netTopology: "switch|router",
+			other:       "",
+			expect:      maxTopologyDistance,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			host := NewHost(&scheduler.PeerHost{Uuid: "foo", NetTopology: tc.netTopology})
+			other := NewHost(&scheduler.PeerHost{Uuid: "bar", NetTopology: tc.other})
+			assert.Equal(host.NetTopologyDistance(other), tc.expect)
+			assert.Equal(other.NetTopologyDistance(host), tc.expect)
+		})
+	}
+}
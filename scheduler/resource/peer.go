@@ -131,6 +131,9 @@ type Peer struct {
 	// UpdateAt is peer update time
 	UpdateAt *atomic.Time
 
+	// uploadHost is the parent host whose upload to peer is in flight, it's protected by mu
+	uploadHost *Host
+
 	// Peer mutex
 	mu *sync.RWMutex
 
@@ -190,11 +193,13 @@ func NewPeer(id string, task *Task, host *Host) *Peer {
 				p.Log.Infof("peer state is %s", e.FSM.Current())
 			},
 			PeerEventDownloadFromBackToSource: func(e *fsm.Event) {
+				p.FinishUpload()
 				p.Task.BackToSourcePeers.Add(p)
 				p.UpdateAt.Store(time.Now())
 				p.Log.Infof("peer state is %s", e.FSM.Current())
 			},
 			PeerEventDownloadSucceeded: func(e *fsm.Event) {
+				p.FinishUpload()
 				if e.Src == PeerStateBackToSource {
 					p.Task.BackToSourcePeers.Delete(p)
 				}
@@ -203,6 +208,7 @@ func NewPeer(id string, task *Task, host *Host) *Peer {
 				p.Log.Infof("peer state is %s", e.FSM.Current())
 			},
 			PeerEventDownloadFailed: func(e *fsm.Event) {
+				p.FinishUpload()
 				if e.Src == PeerStateBackToSource {
 					p.Task.BackToSourcePeers.Delete(p)
 				}
@@ -235,25 +241,24 @@ func (p *Peer) LoadChild(key string) (*Peer, bool) {
 
 // StoreChild set peer child
 func (p *Peer) StoreChild(child *Peer) {
-	p.mu.Lock()
-	defer p.mu.Unlock()
-
-	p.Children.Store(child.ID, child)
-	child.Parent.Store(p)
+	child.StoreParent(p)
 }
 
 // DeleteChild deletes peer child for a key
 func (p *Peer) DeleteChild(key string) {
-	p.mu.Lock()
-	defer p.mu.Unlock()
-
 	child, ok := p.LoadChild(key)
 	if !ok {
 		return
 	}
 
-	p.Children.Delete(child.ID)
-	child.Parent = &atomic.Value{}
+	child.mu.Lock()
+	defer child.mu.Unlock()
+
+	if parent, ok := child.LoadParent(); ok && parent == p {
+		child.deleteParent()
+		return
+	}
+	p.Children.Delete(key)
 }
 
 // LenChildren return length of children sync map
@@ -277,20 +282,34 @@ func (p *Peer) LoadParent() (*Peer, bool) {
 	return rawParent.(*Peer), true
 }
 
-// StoreParent set peer parent
+// StoreParent set peer parent, the upload of parent host to peer starts
 func (p *Peer) StoreParent(parent *Peer) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
+	if old, ok := p.LoadParent(); ok && old != parent {
+		old.Children.Delete(p.ID)
+	}
+
 	p.Parent.Store(parent)
 	parent.Children.Store(p.ID, p)
+	if p.uploadHost != parent.Host {
+		p.finishUpload()
+		p.uploadHost = parent.Host
+		p.uploadHost.IncUploadCount()
+	}
 }
 
-// DeleteParent deletes peer parent
+// DeleteParent deletes peer parent, the upload of parent host to peer finishes
 func (p *Peer) DeleteParent() {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
+	p.deleteParent()
+}
+
+func (p *Peer) deleteParent() {
+	p.finishUpload()
 	parent, ok := p.LoadParent()
 	if !ok {
 		return
@@ -300,6 +319,24 @@ func (p *Peer) DeleteParent() {
 	parent.Children.Delete(p.ID)
 }
 
+// FinishUpload finishes the upload of parent host to peer when peer stops downloading pieces from parent,
+// the parent is kept in the tree
+func (p *Peer) FinishUpload() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.finishUpload()
+}
+
+func (p *Peer) finishUpload() {
+	if p.uploadHost == nil {
+		return
+	}
+
+	p.uploadHost.DecUploadCount()
+	p.uploadHost = nil
+}
+
 // ReplaceParent replaces peer parent
 func (p *Peer) ReplaceParent(parent *Peer) {
 	p.DeleteParent()
@@ -393,7 +430,7 @@ func (p *Peer) DownloadTinyFile() ([]byte, error) {
 	// Download url: http://${host}:${port}/download/${taskIndex}/${taskID}?peerId=${peerID}
 	targetURL := url.URL{
 		Scheme:   "http",
-		Host:     fmt.Sprintf("%s:%d", p.Host.IP, p.Host.DownloadPort),
+		Host:     p.Host.DownloadAddr(),
 		Path:     fmt.Sprintf("download/%s/%s", p.Task.ID[:3], p.Task.ID),
 		RawQuery: fmt.Sprintf("peerId=%s", p.ID),
 	}
@@ -195,14 +195,16 @@ func TestPeer_DeleteChild(t *testing.T) {
 			name:    "delete child",
 			childID: idgen.PeerID("127.0.0.1"),
 			expect: func(t *testing.T, peer *Peer, mockChildPeer *Peer) {
-				peer.DeleteChild(mockChildPeer.ID)
 				assert := assert.New(t)
+				assert.Equal(peer.Host.UploadCount.Load(), int32(1))
+				peer.DeleteChild(mockChildPeer.ID)
 
 				var ok bool
 				_, ok = peer.LoadChild(mockChildPeer.ID)
 				assert.Equal(ok, false)
 				_, ok = mockChildPeer.LoadParent()
 				assert.Equal(ok, false)
+				assert.Equal(peer.Host.UploadCount.Load(), int32(0))
 			},
 		},
 		{
@@ -355,6 +357,29 @@ func TestPeer_StoreParent(t *testing.T) {
 				child, ok = parent.LoadChild(peer.ID)
 				assert.Equal(ok, true)
 				assert.Equal(child.ID, peer.ID)
+				assert.Equal(parent.Host.UploadCount.Load(), int32(1))
+
+				// store the same parent again
+				peer.StoreParent(parent)
+				assert.Equal(parent.Host.UploadCount.Load(), int32(1))
+			},
+		},
+		{
+			name:     "store parent of other host",
+			parentID: idgen.PeerID("127.0.0.1"),
+			expect: func(t *testing.T, peer *Peer, parentID string) {
+				assert := assert.New(t)
+
+				oldParent, ok := peer.LoadParent()
+				assert.Equal(ok, true)
+				parent := NewPeer(idgen.PeerID("127.0.0.2"), peer.Task, NewHost(mockRawCDNHost))
+				peer.StoreParent(parent)
+				_, ok = oldParent.LoadChild(peer.ID)
+				assert.Equal(ok, false)
+				_, ok = parent.LoadChild(peer.ID)
+				assert.Equal(ok, true)
+				assert.Equal(oldParent.Host.UploadCount.Load(), int32(0))
+				assert.Equal(parent.Host.UploadCount.Load(), int32(1))
 			},
 		},
 		{
@@ -410,6 +435,7 @@ func TestPeer_DeleteParent(t *testing.T) {
 				assert.Equal(ok, false)
 				_, ok = mockParentPeer.LoadChild(peer.ID)
 				assert.Equal(ok, false)
+				assert.Equal(mockParentPeer.Host.UploadCount.Load(), int32(0))
 			},
 		},
 		{
@@ -440,6 +466,61 @@ func TestPeer_DeleteParent(t *testing.T) {
 	}
 }
 
+func TestPeer_FinishUpload(t *testing.T) {
+	tests := []struct {
+		name   string
+		event  string
+		expect func(t *testing.T, peer *Peer, mockParentPeer *Peer)
+	}{
+		{
+			name:  "peer download succeeded",
+			event: PeerEventDownloadSucceeded,
+			expect: func(t *testing.T, peer *Peer, mockParentPeer *Peer) {
+				assert := assert.New(t)
+				parent, ok := peer.LoadParent()
+				assert.Equal(ok, true)
+				assert.Equal(parent.ID, mockParentPeer.ID)
+				assert.Equal(mockParentPeer.Host.UploadCount.Load(), int32(0))
+
+				// the finished upload is not counted again
+				peer.DeleteParent()
+				assert.Equal(mockParentPeer.Host.UploadCount.Load(), int32(0))
+			},
+		},
+		{
+			name:  "peer download failed",
+			event: PeerEventDownloadFailed,
+			expect: func(t *testing.T, peer *Peer, mockParentPeer *Peer) {
+				assert := assert.New(t)
+				assert.Equal(mockParentPeer.Host.UploadCount.Load(), int32(0))
+			},
+		},
+		{
+			name:  "peer download from back-to-source",
+			event: PeerEventDownloadFromBackToSource,
+			expect: func(t *testing.T, peer *Peer, mockParentPeer *Peer) {
+				assert := assert.New(t)
+				assert.Equal(mockParentPeer.Host.UploadCount.Load(), int32(0))
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockHost := NewHost(mockRawHost)
+			mockTask := NewTask(mockTaskID, mockTaskURL, mockTaskBackToSourceLimit, mockTaskURLMeta)
+			mockParentPeer := NewPeer(idgen.PeerID("127.0.0.1"), mockTask, mockHost)
+			peer := NewPeer(mockPeerID, mockTask, mockHost)
+
+			peer.FSM.SetState(PeerStateRunning)
+			peer.StoreParent(mockParentPeer)
+			assert.Equal(t, mockParentPeer.Host.UploadCount.Load(), int32(1))
+			assert.NoError(t, peer.FSM.Event(tc.event))
+			tc.expect(t, peer, mockParentPeer)
+		})
+	}
+}
+
 func TestPeer_ReplaceParent(t *testing.T) {
 	tests := []struct {
 		name        string
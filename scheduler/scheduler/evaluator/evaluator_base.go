@@ -31,8 +31,8 @@ const (
 	// Finished piece weight
 	finishedPieceWeight float64 = 0.3
 
-	// Free load weight
-	freeLoadWeight = 0.2
+	// Host score weight, see resource.Host.Score
+	hostScoreWeight = 0.2
 
 	// host type affinity weight
 	hostTypeAffinityWeight = 0.2
@@ -85,7 +85,7 @@ func (eb *evaluatorBase) Evaluate(parent *resource.Peer, child *resource.Peer, t
 	}
 
 	return finishedPieceWeight*calculatePieceScore(parent, child, totalPieceCount) +
-		freeLoadWeight*calculateFreeLoadScore(parent.Host) +
+		hostScoreWeight*parent.Host.Score() +
 		hostTypeAffinityWeight*calculateHostTypeAffinityScore(parent) +
 		idcAffinityWeight*calculateIDCAffinityScore(parent.Host, child.Host) +
 		netTopologyAffinityWeight*calculateMultiElementAffinityScore(parent.Host.NetTopology, child.Host.NetTopology) +
@@ -108,13 +108,6 @@ func calculatePieceScore(parent *resource.Peer, child *resource.Peer, totalPiece
 	return float64(parentFinishedPieceCount) - float64(childFinishedPieceCount)
 }
 
-// calculateFreeLoadScore 0.0~1.0 larger and better
-func calculateFreeLoadScore(host *resource.Host) float64 {
-	load := host.LenPeers()
-	totalLoad := host.UploadLoadLimit.Load()
-	return float64(totalLoad-int32(load)) / float64(totalLoad)
-}
-
 // calculateHostTypeAffinityScore 0.0~1.0 larger and better
 func calculateHostTypeAffinityScore(peer *resource.Peer) float64 {
 	// When the task is downloaded for the first time,
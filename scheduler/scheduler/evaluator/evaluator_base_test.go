@@ -116,7 +116,7 @@ func TestEvaluatorBase_Evaluate(t *testing.T) {
 			},
 			expect: func(t *testing.T, score float64) {
 				assert := assert.New(t)
-				assert.Equal(score, float64(0.9))
+				assert.InDelta(score, float64(0.84), 0.01)
 			},
 		},
 		{
@@ -131,7 +131,7 @@ func TestEvaluatorBase_Evaluate(t *testing.T) {
 			},
 			expect: func(t *testing.T, score float64) {
 				assert := assert.New(t)
-				assert.Equal(score, float64(0.9))
+				assert.InDelta(score, float64(0.84), 0.01)
 			},
 		},
 		{
@@ -146,7 +146,23 @@ func TestEvaluatorBase_Evaluate(t *testing.T) {
 			},
 			expect: func(t *testing.T, score float64) {
 				assert := assert.New(t)
-				assert.Equal(score, float64(0.9))
+				assert.InDelta(score, float64(0.84), 0.01)
+			},
+		},
+		{
+			name:            "parent host is affine to scheduler",
+			parent:          resource.NewPeer(idgen.PeerID("127.0.0.1"), parentMockTask, parentMockHost),
+			child:           resource.NewPeer(idgen.PeerID("127.0.0.1"), childMockTask, childMockHost),
+			totalPieceCount: 1,
+			mock: func(parent *resource.Peer, child *resource.Peer) {
+				parent.Host.SecurityDomain = ""
+				child.Host.SecurityDomain = ""
+				parent.Pieces.Set(0)
+				resource.WithScoreConfig(resource.ScoreConfig{IDC: "idc", Location: "location"})(parent.Host)
+			},
+			expect: func(t *testing.T, score float64) {
+				assert := assert.New(t)
+				assert.InDelta(score, float64(0.88), 0.01)
 			},
 		},
 	}
@@ -277,43 +293,6 @@ func TestEvaluatorBase_calculatePieceScore(t *testing.T) {
 	}
 }
 
-func TestEvaluatorBase_calculateFreeLoadScore(t *testing.T) {
-	tests := []struct {
-		name   string
-		mock   func(host *resource.Host, mockPeer *resource.Peer)
-		expect func(t *testing.T, score float64)
-	}{
-		{
-			name: "host peers is not empty",
-			mock: func(host *resource.Host, mockPeer *resource.Peer) {
-				host.StorePeer(mockPeer)
-			},
-			expect: func(t *testing.T, score float64) {
-				assert := assert.New(t)
-				assert.Equal(score, float64(0.99))
-			},
-		},
-		{
-			name: "host peers is empty",
-			mock: func(host *resource.Host, mockPeer *resource.Peer) {},
-			expect: func(t *testing.T, score float64) {
-				assert := assert.New(t)
-				assert.Equal(score, float64(1))
-			},
-		},
-	}
-
-	for _, tc := range tests {
-		t.Run(tc.name, func(t *testing.T) {
-			host := resource.NewHost(mockRawHost)
-			mockTask := resource.NewTask(mockTaskID, mockTaskURL, mockTaskBackToSourceLimit, mockTaskURLMeta)
-			mockPeer := resource.NewPeer(mockPeerID, mockTask, host)
-			tc.mock(host, mockPeer)
-			tc.expect(t, calculateFreeLoadScore(host))
-		})
-	}
-}
-
 func TestEvaluatorBase_calculateHostTypeAffinityScore(t *testing.T) {
 	tests := []struct {
 		name   string
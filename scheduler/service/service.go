@@ -18,7 +18,6 @@ package service
 
 import (
 	"context"
-	"fmt"
 	"io"
 	"time"
 
@@ -35,6 +34,14 @@ import (
 	"d7y.io/dragonfly/v2/scheduler/scheduler"
 )
 
+const (
+	// Factor to degrade upload load limit of parent host when parent fails to upload piece
+	uploadFailedDegradeFactor = 0.5
+
+	// Duration to recover upload load limit of parent host after degradation
+	uploadFailedRecoverAfter = 5 * time.Minute
+)
+
 type Service struct {
 	// Resource interface
 	resource resource.Resource
@@ -153,7 +160,7 @@ func (s *Service) RegisterPeerTask(ctx context.Context, req *rpcscheduler.PeerTa
 
 			singlePiece := &rpcscheduler.SinglePiece{
 				DstPid:  parent.ID,
-				DstAddr: fmt.Sprintf("%s:%d", parent.Host.IP, parent.Host.DownloadPort),
+				DstAddr: parent.Host.DownloadAddr(),
 				PieceInfo: &base.PieceInfo{
 					PieceNum:    firstPiece.PieceNum,
 					RangeStart:  firstPiece.RangeStart,
@@ -243,50 +250,63 @@ func (s *Service) ReportPieceResult(stream rpcscheduler.Scheduler_ReportPieceRes
 			defer peer.DeleteStream()
 		}
 
-		peer.Log.Infof("receive piece: %#v %#v", piece, piece.PieceInfo)
-
-		if piece.PieceInfo != nil {
-			// Handle begin of piece
-			if piece.PieceInfo.PieceNum == common.BeginOfPiece {
-				s.handleBeginOfPiece(ctx, peer)
-				continue
+		// Handle the successful piece results reported in batch
+		if batch := piece.GetBatch(); batch != nil {
+			for _, pr := range batch.PieceResults {
+				s.handlePieceResult(ctx, peer, pr)
 			}
+			continue
+		}
 
-			// Handle end of piece
-			if piece.PieceInfo.PieceNum == common.EndOfPiece {
-				s.handleEndOfPiece(ctx, peer)
-				continue
-			}
+		s.handlePieceResult(ctx, peer, piece)
+	}
+}
+
+// handlePieceResult handles the piece result of the peer
+func (s *Service) handlePieceResult(ctx context.Context, peer *resource.Peer, piece *rpcscheduler.PieceResult) {
+	peer.Log.Infof("receive piece: %#v %#v", piece, piece.PieceInfo)
+
+	if piece.PieceInfo != nil {
+		// Handle begin of piece
+		if piece.PieceInfo.PieceNum == common.BeginOfPiece {
+			s.handleBeginOfPiece(ctx, peer)
+			return
 		}
 
-		// Handle piece download successfully
-		if piece.Success {
-			s.handlePieceSuccess(ctx, peer, piece)
-
-			// Collect peer host traffic metrics
-			if s.config.Metrics != nil && s.config.Metrics.EnablePeerHost {
-				metrics.PeerHostTraffic.WithLabelValues("download", peer.Host.ID, peer.Host.IP).Add(float64(piece.PieceInfo.RangeSize))
-				if parent, ok := s.resource.PeerManager().Load(piece.DstPid); ok {
-					metrics.PeerHostTraffic.WithLabelValues("upload", parent.Host.ID, parent.Host.IP).Add(float64(piece.PieceInfo.RangeSize))
-				} else {
-					peer.Log.Warnf("dst peer %s not found for piece %#v %#v", piece.DstPid, piece, piece.PieceInfo)
-				}
-			}
-			continue
+		// Handle end of piece
+		if piece.PieceInfo.PieceNum == common.EndOfPiece {
+			s.handleEndOfPiece(ctx, peer)
+			return
 		}
+	}
 
-		// Handle piece download code
-		if piece.Code != base.Code_Success {
-			// FIXME(244372610) When dfdaemon download peer return empty, retry later.
-			if piece.Code == base.Code_ClientWaitPieceReady {
-				peer.Log.Infof("receive piece code %d and wait for dfdaemon piece ready", piece.Code)
-				continue
+	// Handle piece download successfully
+	if piece.Success {
+		s.handlePieceSuccess(ctx, peer, piece)
+
+		// Collect peer host traffic metrics
+		if s.config.Metrics != nil && s.config.Metrics.EnablePeerHost {
+			metrics.PeerHostTraffic.WithLabelValues("download", peer.Host.ID, peer.Host.IP).Add(float64(piece.PieceInfo.RangeSize))
+			if parent, ok := s.resource.PeerManager().Load(piece.DstPid); ok {
+				metrics.PeerHostTraffic.WithLabelValues("upload", parent.Host.ID, parent.Host.IP).Add(float64(piece.PieceInfo.RangeSize))
+			} else {
+				peer.Log.Warnf("dst peer %s not found for piece %#v %#v", piece.DstPid, piece, piece.PieceInfo)
 			}
+		}
+		return
+	}
 
-			// Handle piece download failed
-			peer.Log.Errorf("receive failed piece: %#v %#v", piece, piece.PieceInfo)
-			s.handlePieceFail(ctx, peer, piece)
+	// Handle piece download code
+	if piece.Code != base.Code_Success {
+		// FIXME(244372610) When dfdaemon download peer return empty, retry later.
+		if piece.Code == base.Code_ClientWaitPieceReady {
+			peer.Log.Infof("receive piece code %d and wait for dfdaemon piece ready", piece.Code)
+			return
 		}
+
+		// Handle piece download failed
+		peer.Log.Errorf("receive failed piece: %#v %#v", piece, piece.PieceInfo)
+		s.handlePieceFail(ctx, peer, piece)
 	}
 }
 
@@ -392,6 +412,20 @@ func (s *Service) registerHost(ctx context.Context, req *rpcscheduler.PeerTaskRe
 			options = append(options, resource.WithUploadLoadLimit(int32(clientConfig.LoadLimit)))
 		}
 
+		// Score hosts with the weights of scheduler config and prefer hosts close to scheduler
+		if s.config.Scheduler.Score != nil {
+			scoreConfig := resource.ScoreConfig{ScoreConfig: s.config.Scheduler.Score}
+			if s.config.Host != nil {
+				scoreConfig.IDC = s.config.Host.IDC
+				scoreConfig.Location = s.config.Host.Location
+			}
+			options = append(options, resource.WithScoreConfig(scoreConfig))
+		}
+
+		if s.config.Scheduler.PeerCountUploadLoad {
+			options = append(options, resource.WithPeerCountUploadLoad(true))
+		}
+
 		host = resource.NewHost(rawHost, options...)
 		s.resource.HostManager().Store(host)
 		host.Log.Info("create new host")
@@ -462,6 +496,17 @@ func (s *Service) handlePieceSuccess(ctx context.Context, peer *resource.Peer, p
 	peer.Pieces.Set(uint(piece.PieceInfo.PieceNum))
 	peer.AppendPieceCost(int64(piece.EndTime - piece.BeginTime))
 
+	// Update bandwidth of peer host and parent host measured by the piece
+	if piece.EndTime > piece.BeginTime && piece.PieceInfo.RangeSize > 0 {
+		bandwidth := int64(piece.PieceInfo.RangeSize) * int64(time.Second) / int64(piece.EndTime-piece.BeginTime)
+		peer.Host.UpdateBandwidth(0, bandwidth)
+		if piece.DstPid != "" {
+			if parent, ok := s.resource.PeerManager().Load(piece.DstPid); ok {
+				parent.Host.UpdateBandwidth(bandwidth, 0)
+			}
+		}
+	}
+
 	// When the peer downloads back-to-source,
 	// piece downloads successfully updates the task piece info
 	if peer.FSM.Is(resource.PeerStateBackToSource) {
@@ -489,6 +534,11 @@ func (s *Service) handlePieceFail(ctx context.Context, peer *resource.Peer, piec
 	// to help peer to reschedule the parent node
 	switch piece.Code {
 	case base.Code_ClientPieceDownloadFail, base.Code_PeerTaskNotFound, base.Code_CDNError, base.Code_CDNTaskDownloadFail:
+		if piece.Code == base.Code_ClientPieceDownloadFail {
+			// Parent host fails to upload piece, lower its upload load limit temporarily
+			parent.Host.DegradeUploadLimit(uploadFailedDegradeFactor, uploadFailedRecoverAfter)
+		}
+
 		if err := parent.FSM.Event(resource.PeerEventDownloadFailed); err != nil {
 			peer.Log.Errorf("peer fsm event failed: %v", err)
 			break
@@ -762,6 +762,50 @@ func TestService_ReportPieceResult(t *testing.T) {
 				assert.False(ok)
 			},
 		},
+		{
+			name: "revice batched successful pieces",
+			mock: func(
+				mockPeer *resource.Peer,
+				res resource.Resource, peerManager resource.PeerManager,
+				mr *resource.MockResourceMockRecorder, mp *resource.MockPeerManagerMockRecorder, ms *rpcschedulermocks.MockScheduler_ReportPieceResultServerMockRecorder,
+
+			) {
+				gomock.InOrder(
+					ms.Context().Return(context.Background()).Times(1),
+					ms.Recv().Return(&rpcscheduler.PieceResult{
+						SrcPid: mockPeerID,
+						Batch: &rpcscheduler.PieceResults{
+							PieceResults: []*rpcscheduler.PieceResult{
+								{
+									SrcPid:  mockPeerID,
+									Success: true,
+									PieceInfo: &base.PieceInfo{
+										PieceNum: 1,
+									},
+								},
+								{
+									SrcPid:  mockPeerID,
+									Success: true,
+									PieceInfo: &base.PieceInfo{
+										PieceNum: 2,
+									},
+								},
+							},
+						},
+					}, nil).Times(1),
+					mr.PeerManager().Return(peerManager).Times(1),
+					mp.Load(gomock.Eq(mockPeerID)).Return(mockPeer, true).Times(1),
+					ms.Recv().Return(nil, io.EOF).Times(1),
+				)
+			},
+			expect: func(t *testing.T, peer *resource.Peer, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.True(peer.Pieces.Test(1))
+				assert.True(peer.Pieces.Test(2))
+				assert.Equal(uint(2), peer.Pieces.Count())
+			},
+		},
 		{
 			name: "revice Code_ClientWaitPieceReady code",
 			mock: func(
@@ -1671,6 +1715,28 @@ func TestService_handlePieceSuccess(t *testing.T) {
 				assert.Equal(peer.PieceCosts(), []int64{1})
 			},
 		},
+		{
+			name: "piece success and update bandwidth",
+			piece: &rpcscheduler.PieceResult{
+				PieceInfo: &base.PieceInfo{
+					PieceNum:  0,
+					PieceMd5:  "ac32345ef819f03710e2105c81106fdd",
+					RangeSize: 1024,
+				},
+				BeginTime: uint64(time.Second),
+				EndTime:   uint64(2 * time.Second),
+			},
+			peer: resource.NewPeer(mockPeerID, mockTask, resource.NewHost(mockRawHost)),
+			mock: func(peer *resource.Peer) {
+				peer.FSM.SetState(resource.PeerStateRunning)
+			},
+			expect: func(t *testing.T, peer *resource.Peer) {
+				assert := assert.New(t)
+				assert.Equal(peer.Pieces.Count(), uint(1))
+				assert.Equal(peer.Host.DownloadBandwidth.Load(), int64(1024))
+				assert.Equal(peer.Host.UploadBandwidth.Load(), int64(0))
+			},
+		},
 		{
 			name: "piece state is PeerStateBackToSource",
 			piece: &rpcscheduler.PieceResult{